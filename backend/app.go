@@ -2,48 +2,87 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/instance"
+	"devtools/backend/internal/logging"
+	"devtools/backend/internal/migrate"
+	appsettings "devtools/backend/internal/settings"
 	"devtools/backend/internal/sshmanager"
 	"devtools/backend/internal/syncconfig"
+	"devtools/backend/internal/syncer"
 	"devtools/backend/internal/types"
+	"devtools/backend/internal/workspace"
 	"devtools/backend/pkg/platform"
 	"devtools/backend/service/filesyncer"
+	"devtools/backend/service/hotkeys"
+	"devtools/backend/service/logstream"
+	"devtools/backend/service/notifications"
+	"devtools/backend/service/settings"
+	"devtools/backend/service/snippets"
 	"devtools/backend/service/sshgate"
 	"devtools/backend/service/terminal"
+	"devtools/backend/service/updater"
 
 	"github.com/wailsapp/wails/v2/pkg/menu"
 	"github.com/wailsapp/wails/v2/pkg/menu/keys"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+var (
+	logger         = logging.For("app")
+	frontendLogger = logging.For("frontend")
+)
+
 // App struct
 type App struct {
 	ctx context.Context
 
 	// 服务层
-	SSHGateService  *sshgate.Service
-	TerminalService *terminal.Service
-	FileSyncService *filesyncer.Service
+	SSHGateService   *sshgate.Service
+	TerminalService  *terminal.Service
+	FileSyncService  *filesyncer.Service
+	SettingsService  *settings.Service
+	LogStreamService *logstream.Service
+	SnippetService   *snippets.Service
+	HotkeyService    *hotkeys.Service
+	UpdateService    *updater.Service
+	NotifyService    *notifications.Service
 
 	isQuitting   bool       // 内部状态标志
 	backendReady bool       // 新增：标记后端服务是否全部成功启动
 	mu           sync.Mutex // 新增：保护 backendReady
 	isDebug      bool
 	isMacOS      bool
+	version      string // 编译时嵌入的版本号
+
+	instanceGuard *instance.Guard // 单实例锁
+
+	migrationReport *migrate.Report // 启动时配置文件迁移的结果
+
+	// 工作区（见 workspaces.go），切换时需要重新指向 SSH 配置与钥匙串命名空间
+	workspaces  *workspace.Manager
+	sshManager  *sshmanager.Manager
+	workspaceMu sync.Mutex
+
+	// 应用菜单中的快捷操作（见 quick_actions.go）
+	quickMenu *menu.Menu
+	quickMu   sync.Mutex
 }
 
 // NewApp creates a new App application struct
-func NewApp(isDebug, isMacOS bool) *App {
+func NewApp(isDebug, isMacOS bool, version string) *App {
 	return &App{
 		isDebug: isDebug,
 		isMacOS: isMacOS,
+		version: version,
 		// backendReady is false by default
 	}
 }
@@ -65,56 +104,115 @@ func (a *App) Bootstrap() {
 	// 日志初始化
 	logDir := a.initLogger()
 
-	// 初始化基础管理器
-	configPath := filepath.Join(logDir, "config.json")
+	// 单实例检查：如果已有实例在运行，请求它显示窗口后直接退出，
+	// 避免两个实例同时监听文件、启动终端服务并争抢同一份配置文件
+	guard, err := instance.Acquire(logDir)
+	switch {
+	case errors.Is(err, instance.ErrAlreadyRunning):
+		logger.Println("DevTools is already running, asked the existing instance to show its window. Exiting.")
+		os.Exit(0)
+	case err != nil:
+		logger.Printf("Warning: single-instance guard is unavailable: %v", err)
+	default:
+		a.instanceGuard = guard
+		guard.OnFocus(a.focusWindow)
+	}
+
+	// 在任何服务读取配置之前，把旧格式的配置文件升级到当前版本（原文件会被备份）
+	a.migrationReport = migrate.Run(logDir)
+	for _, f := range a.migrationReport.Files {
+		if f.Error != "" {
+			logger.Printf("Warning: config migration failed for %s: %s", f.File, f.Error)
+		}
+	}
+
+	// 加载工作区列表；引入工作区之前的配置原样作为默认工作区
+	a.workspaces = workspace.NewManager(logDir)
+	if err := a.workspaces.Load(); err != nil {
+		logger.Printf("Warning: Failed to load workspaces file: %v", err)
+	}
+	active := a.workspaces.Active()
+
+	// 初始化基础管理器，同步配置属于当前工作区
+	configPath := filepath.Join(a.workspaces.Dir(active.Name), "config.json")
 	cfgManager := syncconfig.NewConfigManager(configPath)
 	if err := cfgManager.Load(); err != nil {
-		log.Printf("Warning: Failed to load config file: %v", err)
+		logger.Printf("Warning: Failed to load config file: %v", err)
+	}
+
+	settingsMgr := appsettings.NewManager(filepath.Join(logDir, "settings.json"))
+	if err := settingsMgr.Load(); err != nil {
+		logger.Printf("Warning: Failed to load settings file: %v", err)
 	}
 
-	sshMgr, err := sshmanager.NewManager("")
+	sshMgr, err := sshmanager.NewManager(active.SSHConfigPath)
 	if err != nil {
-		log.Fatalf("关键错误: 初始化 SSH 配置管理器失败: %v", err)
+		logger.Fatalf("关键错误: 初始化 SSH 配置管理器失败: %v", err)
 	}
+	sshMgr.SetKeyringNamespace(workspace.KeyringNamespace(active.Name))
+	a.sshManager = sshMgr
 
 	// 创建并注入服务实例到 app 中
+	// SFTP 连接与 SSH Gate 共用全局的超时与重试策略
+	syncer.SetConnectionPolicySource(sshMgr.DialPolicyForHost)
 	a.FileSyncService = filesyncer.NewService(cfgManager)
 	a.SSHGateService = sshgate.NewService(sshMgr)
 	a.TerminalService = terminal.NewService(sshMgr)
+	a.SettingsService = settings.NewService(settingsMgr)
+	a.LogStreamService = logstream.NewService()
+	a.SnippetService = snippets.NewService()
+	a.HotkeyService = hotkeys.NewService(a.handleHotkey)
+	a.UpdateService = updater.NewService(a.version)
+	a.NotifyService = notifications.NewService()
+
+	// 隧道断开、同步出错与主机密钥变化时发送桌面通知
+	a.SSHGateService.SetTunnelDisconnectHandler(a.NotifyService.TunnelDisconnected)
+	a.SSHGateService.SetLogDir(logDir)
+	a.FileSyncService.SetErrorHandler(a.NotifyService.SyncError)
+	sshMgr.SetHostKeyChangedHandler(a.NotifyService.HostKeyChanged)
+
+	// 删除主机前列出使用它的终端会话与同步配置
+	a.SSHGateService.SetTerminalSessionSource(func(alias string) []sshgate.HostDependency {
+		var deps []sshgate.HostDependency
+		for _, session := range a.TerminalService.SessionsForHost(alias) {
+			deps = append(deps, sshgate.HostDependency{ID: session.ID, Name: session.Title})
+		}
+		return deps
+	})
+	a.SSHGateService.SetSyncConfigSource(cfgManager.GetAllSSHConfigs)
+	// 主机改名或删除时，终端服务中按别名保存的登录命令随之移动或删除
+	a.SSHGateService.SetLoginCommandHandlers(a.TerminalService.RenameLoginCommand, a.TerminalService.DeleteLoginCommand)
+
+	// 各服务订阅设置变化；Subscribe 会立即以当前设置调用一次
+	settingsMgr.Subscribe(func(s appsettings.Settings) {
+		if err := logging.SetLevels(s.LogLevel, s.SubsystemLogLevels); err != nil {
+			logger.Printf("Warning: invalid log levels in settings: %v", err)
+		}
+		i18n.SetLocale(s.Locale)
+	})
+	settingsMgr.Subscribe(sshMgr.ApplySettings)
+	settingsMgr.Subscribe(a.SSHGateService.ApplySettings)
+	settingsMgr.Subscribe(a.TerminalService.ApplySettings)
+	settingsMgr.Subscribe(a.HotkeyService.ApplySettings)
+	settingsMgr.Subscribe(a.UpdateService.ApplySettings)
+	settingsMgr.Subscribe(a.NotifyService.ApplySettings)
 }
 
 func (a *App) initLogger() string {
 	userConfigDir, err := os.UserConfigDir()
 	if err != nil {
-		log.Fatalf("无法获取用户配置目录: %v", err)
+		logger.Fatalf("无法获取用户配置目录: %v", err)
 	}
 	logDir := filepath.Join(userConfigDir, "DevTools")
 
 	// --- 日志文件初始化 ---
-	if err := os.MkdirAll(logDir, 0o750); err != nil {
-		// 如果创建目录失败，也别让程序崩溃，只是打印出来
-		log.Printf("警告: 创建日志目录失败: %v", err)
+	// app.log 按大小轮转；在开发模式下，日志同时输出到终端和文件
+	logFilePath, err := logging.Init(logDir, a.IsDebug())
+	if err != nil {
+		// 如果初始化失败，也别让程序崩溃，日志会继续输出到标准错误
+		logger.Printf("警告: 初始化日志文件失败: %v", err)
 	} else {
-		logFilePath := filepath.Join(logDir, "app.log")
-		// O_CREATE: 如果文件不存在则创建
-		// O_WRONLY: 以只写模式打开
-		// O_APPEND: 在文件末尾追加内容
-		logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o660)
-		if err != nil {
-			log.Printf("警告: 打开日志文件失败: %v", err)
-		} else {
-			fmt.Printf("运行模式: debug=%t, 日志文件路径: %s\n", a.isDebug, logFilePath)
-			// 将日志输出重定向到文件
-			// 在开发模式下，我们希望日志同时输出到终端和文件
-			// 在生产模式下，只输出到文件
-			if a.IsDebug() {
-				// 同时写入文件和标准错误输出(即终端)
-				mw := io.MultiWriter(os.Stderr, logFile)
-				log.SetOutput(mw)
-			} else {
-				log.SetOutput(logFile)
-			}
-		}
+		fmt.Printf("运行模式: debug=%t, 日志文件路径: %s\n", a.isDebug, logFilePath)
 	}
 	return logDir
 }
@@ -134,19 +232,25 @@ func (a *App) Startup(ctx context.Context) {
 		Name    string
 		StartFn func(context.Context) error
 	}{
+		{"SettingsService", a.SettingsService.Startup},
+		{"LogStreamService", a.LogStreamService.Startup},
 		{"FileSyncService", a.FileSyncService.Startup},
 		{"SSHGateService", a.SSHGateService.Startup},
 		{"TerminalService", a.TerminalService.Startup},
+		{"SnippetService", a.SnippetService.Startup},
+		{"HotkeyService", a.HotkeyService.Startup},
+		{"UpdateService", a.UpdateService.Startup},
+		{"NotifyService", a.NotifyService.Startup},
 	}
 
-	log.Println("App startup initiated...")
+	logger.Println("App startup initiated...")
 
 	// 依次启动每个服务，并在失败时处理错误
 	for _, task := range startupTasks {
-		log.Printf("Starting service: %s", task.Name)
+		logger.Printf("Starting service: %s", task.Name)
 		if err := task.StartFn(ctx); err != nil {
 			// 记录致命错误
-			log.Printf("FATAL: Failed to start service '%s': %v", task.Name, err)
+			logger.Printf("FATAL: Failed to start service '%s': %v", task.Name, err)
 			// 向用户显示一个原生错误对话框
 			runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
 				Type:    runtime.ErrorDialog,
@@ -161,10 +265,12 @@ func (a *App) Startup(ctx context.Context) {
 	}
 
 	// 所有服务都成功启动
-	log.Println("All backend services started successfully. App is ready and waiting for frontend.")
+	logger.Println("All backend services started successfully. App is ready and waiting for frontend.")
 	a.mu.Lock()
 	a.backendReady = true // 设置成功状态
 	a.mu.Unlock()
+
+	a.startQuickActions()
 }
 
 // DomReady is called by the frontend when it's ready to receive events.
@@ -174,33 +280,69 @@ func (a *App) DomReady() {
 	a.mu.Unlock()
 
 	if isReady {
-		log.Println("Frontend is ready and backend was ready. Emitting 'app:ready' event.")
+		logger.Println("Frontend is ready and backend was ready. Emitting 'app:ready' event.")
 		runtime.EventsEmit(a.ctx, "app:ready")
 	} else {
 		// 如果后端没有准备好，可能是因为它在 Startup 期间遇到了错误并正在退出。
 		// 在这种情况下，我们不发送 app:ready 事件，前端会继续显示 loading 界面，
 		// 直到应用进程被 Startup 中的 ForceQuit() 终止。
 		// 这是一个正确的行为，因为用户应该已经看到了一个错误对话框。
-		log.Println("Frontend signaled ready, but backend is not. Startup may have failed. Not emitting 'app:ready'.")
+		logger.Println("Frontend signaled ready, but backend is not. Startup may have failed. Not emitting 'app:ready'.")
 	}
 }
 
 // Shutdown is called when the app terminates.
 func (a *App) Shutdown(ctx context.Context) {
-	log.Println("App shutdown initiated...")
+	logger.Println("App shutdown initiated...")
+	if a.SettingsService != nil {
+		logger.Println("Shutting down SettingsService...")
+		a.SettingsService.Shutdown()
+	}
 	if a.FileSyncService != nil {
-		log.Println("Shutting down FileSyncService...")
+		logger.Println("Shutting down FileSyncService...")
 		a.FileSyncService.Shutdown()
 	}
 	if a.SSHGateService != nil {
-		log.Println("Shutting down SSHGateService...")
+		logger.Println("Shutting down SSHGateService...")
 		a.SSHGateService.Shutdown()
 	}
 	if a.TerminalService != nil {
-		log.Println("Shutting down TerminalService...")
+		logger.Println("Shutting down TerminalService...")
 		a.TerminalService.Shutdown()
 	}
-	log.Println("App shutdown completed.")
+	if a.LogStreamService != nil {
+		logger.Println("Shutting down LogStreamService...")
+		a.LogStreamService.Shutdown()
+	}
+	if a.SnippetService != nil {
+		logger.Println("Shutting down SnippetService...")
+		a.SnippetService.Shutdown()
+	}
+	if a.HotkeyService != nil {
+		logger.Println("Shutting down HotkeyService...")
+		a.HotkeyService.Shutdown()
+	}
+	if a.UpdateService != nil {
+		logger.Println("Shutting down UpdateService...")
+		a.UpdateService.Shutdown()
+	}
+	if a.NotifyService != nil {
+		logger.Println("Shutting down NotifyService...")
+		a.NotifyService.Shutdown()
+	}
+	if a.instanceGuard != nil {
+		a.instanceGuard.Release()
+	}
+	logger.Println("App shutdown completed.")
+}
+
+// focusWindow 将主窗口切到前台（由再次启动的实例触发）
+func (a *App) focusWindow() {
+	if a.ctx == nil {
+		return
+	}
+	runtime.WindowUnminimise(a.ctx)
+	runtime.WindowShow(a.ctx)
 }
 
 // OnBeforeClose is called when the user attempts to close the window.
@@ -274,6 +416,8 @@ func (a *App) Menu(appMenu *menu.Menu) {
 	viewMenu.AddText(resetZoomLabel, resetZoomAccelerator, func(_ *menu.CallbackData) {
 		runtime.EventsEmit(a.ctx, "zoom_change", "default")
 	})
+
+	a.addQuickActionsMenu(appMenu)
 }
 
 // SelectFile 处理文件选择
@@ -341,14 +485,33 @@ func (a *App) LogFromFrontend(entry types.LogEntry) {
 		timestamp = time.Now().Format("15:04:05")
 	}
 
-	// 使用我们已经配置好的、会写入到文件的 log 包
-	log.Printf("[FRONTEND] [%s] [%s] %s", timestamp, entry.Level, entry.Message)
+	// 前端日志作为独立的 "frontend" 子系统写入 app.log，并保留前端给出的级别
+	switch strings.ToLower(entry.Level) {
+	case "error":
+		frontendLogger.Errorf("[%s] %s", timestamp, entry.Message)
+	case "warn", "warning":
+		frontendLogger.Warnf("[%s] %s", timestamp, entry.Message)
+	case "debug":
+		frontendLogger.Debugf("[%s] %s", timestamp, entry.Message)
+	default:
+		frontendLogger.Infof("[%s] %s", timestamp, entry.Message)
+	}
 }
 
 // ForceQuit 强制退出应用程序
 func (a *App) ForceQuit() {
-	log.Println("ForceQuit called from frontend. Setting quit flag and exiting.")
+	logger.Println("ForceQuit called from frontend. Setting quit flag and exiting.")
 	// 在调用 Quit 之前，先设置状态标志
 	a.isQuitting = true
 	runtime.Quit(a.ctx)
 }
+
+// GetMigrationReport 返回本次启动时配置迁移的结果，前端据此展示首次使用引导或升级提示
+func (a *App) GetMigrationReport() *migrate.Report {
+	return a.migrationReport
+}
+
+// GetRecentLogs 返回内存中最近的日志，供前端日志查看器按级别和子系统过滤
+func (a *App) GetRecentLogs(level string, subsystem string, limit int) []logging.Entry {
+	return logging.GetRecentLogs(level, subsystem, limit)
+}
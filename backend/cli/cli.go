@@ -0,0 +1,324 @@
+// Package cli 实现无界面的命令行模式（devtools cli ...），
+// 直接复用 sshmanager / sshtunnel / syncer 等后端模块，不创建 Wails 窗口。
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/pkg/sftp"
+
+	"devtools/backend/internal/logging"
+	appsettings "devtools/backend/internal/settings"
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/syncconfig"
+	"devtools/backend/internal/syncer"
+	"devtools/backend/internal/types"
+	"devtools/backend/internal/workspace"
+	"devtools/backend/pkg/utils"
+	"devtools/backend/service/sshgate"
+)
+
+const usage = `Usage: devtools cli [--verbose] <command> [arguments]
+
+Commands:
+  hosts                       List hosts from ~/.ssh/config
+  tunnels                     List saved tunnels
+  tunnel up <name|id>...      Start saved tunnels and keep them running until Ctrl+C
+  sync pairs                  List sync configurations and their sync pairs
+  sync plan <pair-id>         Show what a reconcile would do without changing anything (dry run)
+  sync run <pair-id>          Run a one-off reconcile for a sync pair
+`
+
+// env 是 CLI 命令共用的依赖
+type env struct {
+	out       io.Writer
+	configDir string // 当前工作区的配置目录
+	sshMgr    *sshmanager.Manager
+}
+
+// Run 执行 CLI 命令并返回进程退出码
+func Run(args []string) int {
+	fs := flag.NewFlagSet("devtools cli", flag.ContinueOnError)
+	verbose := fs.Bool("verbose", false, "print backend logs to stderr")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	// 默认只输出警告和错误，避免日志淹没命令输出
+	if !*verbose {
+		_ = logging.SetLevels("warn", nil)
+	}
+
+	e, err := newEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "hosts":
+		err = e.listHosts()
+	case "tunnels":
+		err = e.listTunnels()
+	case "tunnel":
+		if len(rest) < 2 || rest[0] != "up" {
+			fs.Usage()
+			return 2
+		}
+		err = e.tunnelsUp(rest[1:])
+	case "sync":
+		switch {
+		case len(rest) == 1 && rest[0] == "pairs":
+			err = e.listSyncPairs()
+		case len(rest) == 2 && rest[0] == "plan":
+			err = e.planSync(rest[1])
+		case len(rest) == 2 && rest[0] == "run":
+			err = e.runSync(rest[1])
+		default:
+			fs.Usage()
+			return 2
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command '%s'\n\n", cmd)
+		fs.Usage()
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func newEnv() (*env, error) {
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	configDir := filepath.Join(userConfigDir, "DevTools")
+
+	// 与 GUI 使用同一个工作区的 ssh_config、同步配置与钥匙串命名空间
+	workspaces := workspace.NewManager(configDir)
+	if err := workspaces.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to load workspaces: %v\n", err)
+	}
+	active := workspaces.Active()
+
+	sshMgr, err := sshmanager.NewManager(active.SSHConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ssh config: %w", err)
+	}
+	sshMgr.SetKeyringNamespace(workspace.KeyringNamespace(active.Name))
+
+	// 与 GUI 使用相同的设置（保活、主机密钥策略等）
+	settingsMgr := appsettings.NewManager(filepath.Join(configDir, "settings.json"))
+	if err := settingsMgr.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to load settings: %v\n", err)
+	}
+	sshMgr.ApplySettings(settingsMgr.Get())
+	syncer.SetConnectionPolicySource(sshMgr.DialPolicyForHost)
+
+	return &env{out: os.Stdout, configDir: workspaces.Dir(active.Name), sshMgr: sshMgr}, nil
+}
+
+func (e *env) listHosts() error {
+	hosts, err := e.sshMgr.GetSSHHosts()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ALIAS\tUSER\tHOST\tPORT")
+	for _, h := range hosts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", h.Alias, h.User, h.HostName, h.Port)
+	}
+	return w.Flush()
+}
+
+// newGate 创建并启动一个不依赖 Wails 窗口的 SSH Gate 服务，服务发出的事件被忽略
+func (e *env) newGate(ctx context.Context) (*sshgate.Service, error) {
+	gate := sshgate.NewService(e.sshMgr)
+	if err := gate.Startup(utils.WithoutEvents(ctx)); err != nil {
+		return nil, err
+	}
+	return gate, nil
+}
+
+func (e *env) listTunnels() error {
+	gate, err := e.newGate(context.Background())
+	if err != nil {
+		return err
+	}
+	defer gate.Shutdown()
+
+	tunnels, err := gate.GetSavedTunnels()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tLOCAL PORT\tHOST\tREMOTE\tID")
+	for _, t := range tunnels {
+		host := t.HostAlias
+		if t.HostSource == "manual" && t.ManualHost != nil {
+			host = fmt.Sprintf("%s@%s", t.ManualHost.User, t.ManualHost.HostName)
+		}
+		remote := "-"
+		if t.TunnelType == "local" {
+			remote = fmt.Sprintf("%s:%d", t.RemoteHost, t.RemotePort)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", t.Name, t.TunnelType, t.LocalPort, host, remote, t.ID)
+	}
+	return w.Flush()
+}
+
+// tunnelsUp 启动指定的隧道，并在收到 Ctrl+C / SIGTERM 前一直保持运行
+func (e *env) tunnelsUp(names []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	gate, err := e.newGate(ctx)
+	if err != nil {
+		return err
+	}
+	defer gate.Shutdown()
+
+	saved, err := gate.GetSavedTunnels()
+	if err != nil {
+		return err
+	}
+
+	started := 0
+	for _, name := range names {
+		var configID string
+		for _, t := range saved {
+			if t.ID == name || strings.EqualFold(t.Name, name) {
+				configID = t.ID
+				break
+			}
+		}
+		if configID == "" {
+			fmt.Fprintf(os.Stderr, "tunnel '%s' not found\n", name)
+			continue
+		}
+		// 密码从系统钥匙串读取；需要交互输入密码的隧道请先在 GUI 中保存密码
+		if _, err := gate.StartTunnelFromConfig(configID, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start tunnel '%s': %v\n", name, err)
+			continue
+		}
+		started++
+	}
+	if started == 0 {
+		return fmt.Errorf("no tunnels were started")
+	}
+
+	for _, t := range gate.GetActiveTunnels() {
+		fmt.Fprintf(e.out, "%s tunnel via %s: %s -> %s\n", t.Type, t.Alias, t.LocalAddr, t.RemoteAddr)
+	}
+	fmt.Fprintln(e.out, "Tunnels are running. Press Ctrl+C to stop.")
+	<-ctx.Done()
+	fmt.Fprintln(e.out, "Stopping tunnels...")
+	return nil
+}
+
+func (e *env) loadSyncConfig() (*syncconfig.ConfigManager, error) {
+	cfgManager := syncconfig.NewConfigManager(filepath.Join(e.configDir, "config.json"))
+	if err := cfgManager.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load sync config: %w", err)
+	}
+	return cfgManager, nil
+}
+
+func (e *env) listSyncPairs() error {
+	cfgManager, err := e.loadSyncConfig()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CONFIG\tPAIR ID\tLOCAL\tREMOTE")
+	for _, cfg := range cfgManager.GetAllSSHConfigs() {
+		for _, pair := range cfgManager.GetSyncPairsByConfigID(cfg.ID) {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", cfg.Name, pair.ID, pair.LocalPath, pair.RemotePath)
+		}
+	}
+	return w.Flush()
+}
+
+// openSyncPair 查找同步对并建立到其服务器的 SFTP 连接
+func (e *env) openSyncPair(pairID string) (types.SyncPair, types.SSHConfig, *sftp.Client, error) {
+	cfgManager, err := e.loadSyncConfig()
+	if err != nil {
+		return types.SyncPair{}, types.SSHConfig{}, nil, err
+	}
+	pair, ok := cfgManager.GetSyncPairByID(pairID)
+	if !ok {
+		return types.SyncPair{}, types.SSHConfig{}, nil, fmt.Errorf("sync pair '%s' not found", pairID)
+	}
+	cfg, ok := cfgManager.GetSSHConfigByID(pair.ConfigID)
+	if !ok {
+		return types.SyncPair{}, types.SSHConfig{}, nil, fmt.Errorf("sync configuration '%s' for pair '%s' not found", pair.ConfigID, pairID)
+	}
+
+	client, err := syncer.NewSFTPClient(cfg)
+	if err != nil {
+		return types.SyncPair{}, types.SSHConfig{}, nil, err
+	}
+	return pair, cfg, client, nil
+}
+
+func (e *env) planSync(pairID string) error {
+	pair, _, client, err := e.openSyncPair(pairID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	plan, err := syncer.PlanDirectory(client, pair)
+	if err != nil {
+		return err
+	}
+	if len(plan.Items) == 0 {
+		fmt.Fprintf(e.out, "%s is up to date with %s\n", pair.LocalPath, pair.RemotePath)
+		return nil
+	}
+	w := tabwriter.NewWriter(e.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tPATH\tREASON")
+	for _, item := range plan.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", item.Action, item.LocalPath, item.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "\n%d actions, %d bytes to upload\n", len(plan.Items), plan.UploadBytes)
+	return nil
+}
+
+func (e *env) runSync(pairID string) error {
+	pair, cfg, client, err := e.openSyncPair(pairID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	run := syncer.SyncWithHooks(client, cfg, pair, func(level, message string) {
+		fmt.Fprintf(e.out, "[%s] %s\n", level, message)
+	})
+	if run.Failed > 0 || run.Error != "" {
+		return fmt.Errorf("reconcile finished with errors")
+	}
+	return nil
+}
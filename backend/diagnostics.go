@@ -0,0 +1,173 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"time"
+)
+
+// 自诊断：检查各后端服务与运行环境的状态，用于诊断面板，导出为 JSON 附在问题反馈中
+
+// 自诊断检查项的结果
+const (
+	DiagnosticOK      = "ok"
+	DiagnosticWarning = "warning" // 功能可用，但可能出问题（例如配置文件权限过宽）
+	DiagnosticError   = "error"   // 对应的功能不可用
+)
+
+// DiagnosticCheck 是自诊断中的一项检查
+type DiagnosticCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // ok | warning | error
+	Detail string `json:"detail,omitempty"`
+}
+
+// RuntimeStats 是自诊断时后端的运行统计
+type RuntimeStats struct {
+	Goroutines       int    `json:"goroutines"`
+	HeapAllocBytes   uint64 `json:"heapAllocBytes"`
+	ActiveTunnels    int    `json:"activeTunnels"`
+	LocalTerminals   int    `json:"localTerminals"`
+	RemoteTerminals  int    `json:"remoteTerminals"`
+	Watchers         int    `json:"watchers"` // 正在监控的同步根目录数
+	DegradedWatchers int    `json:"degradedWatchers"`
+}
+
+// SelfDiagnostics 是 DiagnoseSelf 的结果
+type SelfDiagnostics struct {
+	GeneratedAt string            `json:"generatedAt"` // RFC3339
+	Version     string            `json:"version"`
+	OS          string            `json:"os"`
+	Arch        string            `json:"arch"`
+	GoVersion   string            `json:"goVersion"`
+	Workspace   string            `json:"workspace"`
+	Healthy     bool              `json:"healthy"` // 没有 error 级别的检查项
+	Checks      []DiagnosticCheck `json:"checks"`
+	Stats       RuntimeStats      `json:"stats"`
+}
+
+// DiagnoseSelf 检查终端 WebSocket 服务、系统钥匙串、ssh 命令、SSH 配置文件的权限与文件监控，
+// 并返回当前的协程、隧道与终端统计
+func (a *App) DiagnoseSelf() *SelfDiagnostics {
+	report := &SelfDiagnostics{
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		Version:     a.version,
+		OS:          goruntime.GOOS,
+		Arch:        goruntime.GOARCH,
+		GoVersion:   goruntime.Version(),
+		Workspace:   a.workspaces.Active().Name,
+		Healthy:     true,
+	}
+	add := func(name, status, detail string) {
+		report.Checks = append(report.Checks, DiagnosticCheck{Name: name, Status: status, Detail: detail})
+		if status == DiagnosticError {
+			report.Healthy = false
+		}
+	}
+
+	// 终端 WebSocket 服务器
+	if addr := a.TerminalService.ServerAddress(); addr == "" {
+		add("terminal_server", DiagnosticError, "the terminal WebSocket server is not running")
+	} else if conn, err := net.DialTimeout("tcp", addr, 2*time.Second); err != nil {
+		add("terminal_server", DiagnosticError, fmt.Sprintf("cannot connect to %s: %v", addr, err))
+	} else {
+		conn.Close()
+		add("terminal_server", DiagnosticOK, "listening on "+addr)
+	}
+
+	// 系统钥匙串
+	if err := a.sshManager.CheckKeychain(); err != nil {
+		add("keychain", DiagnosticError, err.Error())
+	} else {
+		add("keychain", DiagnosticOK, "")
+	}
+
+	// ssh 命令，在外部终端中打开主机时使用
+	add(checkSSHBinary())
+
+	// SSH 配置文件与目录
+	configPath := a.sshManager.ConfigPath()
+	add(checkPermissions("ssh_dir", filepath.Dir(configPath), true))
+	name, status, detail := checkPermissions("ssh_config", configPath, false)
+	if ro := a.sshManager.ReadOnlyStatus(); ro.ReadOnly {
+		detail = strings.TrimPrefix(detail+"; read-only mode ("+ro.Reason+")", "; ")
+	}
+	add(name, status, detail)
+	add(checkPermissions("known_hosts", filepath.Join(filepath.Dir(configPath), "known_hosts"), false))
+
+	// 文件同步监控
+	health := a.FileSyncService.GetWatcherHealth()
+	report.Stats.Watchers = len(health)
+	var degraded []string
+	for _, h := range health {
+		if !h.Healthy {
+			degraded = append(degraded, fmt.Sprintf("%s (%s)", h.Path, h.Reason))
+		}
+	}
+	report.Stats.DegradedWatchers = len(degraded)
+	if len(degraded) > 0 {
+		add("file_watchers", DiagnosticWarning, "degraded: "+strings.Join(degraded, ", "))
+	} else {
+		add("file_watchers", DiagnosticOK, fmt.Sprintf("%d watched roots", len(health)))
+	}
+
+	var mem goruntime.MemStats
+	goruntime.ReadMemStats(&mem)
+	report.Stats.Goroutines = goruntime.NumGoroutine()
+	report.Stats.HeapAllocBytes = mem.HeapAlloc
+	report.Stats.ActiveTunnels = len(a.SSHGateService.GetActiveTunnels())
+	report.Stats.LocalTerminals, report.Stats.RemoteTerminals = a.TerminalService.SessionCount()
+	return report
+}
+
+// ExportSelfDiagnostics 以 JSON 文本返回自诊断的结果，用于附在问题反馈中
+func (a *App) ExportSelfDiagnostics() (string, error) {
+	data, err := json.MarshalIndent(a.DiagnoseSelf(), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal diagnostics: %w", err)
+	}
+	return string(data), nil
+}
+
+// checkSSHBinary 检查 PATH 中的 ssh 命令及其版本
+func checkSSHBinary() (name, status, detail string) {
+	path, err := exec.LookPath("ssh")
+	if err != nil {
+		return "ssh_binary", DiagnosticWarning, "ssh was not found in PATH, opening hosts in an external terminal will fail"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// ssh -V 把版本写到 stderr
+	out, err := exec.CommandContext(ctx, path, "-V").CombinedOutput()
+	if err != nil {
+		return "ssh_binary", DiagnosticWarning, fmt.Sprintf("%s -V failed: %v", path, err)
+	}
+	return "ssh_binary", DiagnosticOK, path + ": " + strings.TrimSpace(string(out))
+}
+
+// checkPermissions 检查文件或目录是否存在，以及权限是否过宽。
+// OpenSSH 会拒绝使用组或其他用户可写的配置文件（"Bad owner or permissions"）。
+func checkPermissions(name, path string, dir bool) (string, string, string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return name, DiagnosticWarning, path + " does not exist"
+		}
+		return name, DiagnosticError, err.Error()
+	}
+	if info.IsDir() != dir {
+		return name, DiagnosticError, path + " has the wrong file type"
+	}
+	detail := fmt.Sprintf("%s (%s)", path, info.Mode().Perm())
+	if goruntime.GOOS != "windows" && info.Mode().Perm()&0o022 != 0 {
+		return name, DiagnosticWarning, detail + " is writable by group or others"
+	}
+	return name, DiagnosticOK, detail
+}
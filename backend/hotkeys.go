@@ -0,0 +1,17 @@
+package backend
+
+import (
+	"devtools/backend/internal/settings"
+)
+
+// handleHotkey 执行全局快捷键中不依赖前端的部分；打开快速连接面板等界面操作由前端处理 "hotkey:triggered" 事件完成
+func (a *App) handleHotkey(b settings.GlobalHotkey) {
+	switch b.Action {
+	case settings.HotkeyActionQuickConnect, settings.HotkeyActionShowWindow:
+		a.focusWindow()
+	case settings.HotkeyActionToggleTunnel:
+		if err := a.SSHGateService.ToggleQuickTunnel(b.Target); err != nil {
+			logger.Printf("Global shortcut: failed to toggle tunnel %s: %v", b.Target, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package i18n 是后端返回给前端的用户可见文本（错误信息、连接结果等）的消息目录。
+// 消息通过 ID 查找，当前语言缺少某条消息时回退到英文，英文也没有时直接使用 ID。
+// 日志不经过这里，始终保持原样。
+package i18n
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+)
+
+// 支持的语言
+const (
+	LocaleEnglish = "en"
+	LocaleChinese = "zh-CN"
+
+	DefaultLocale = LocaleEnglish
+)
+
+var current atomic.Value // string
+
+// SetLocale 切换当前语言，不支持的语言回退到默认语言
+func SetLocale(locale string) {
+	if !IsSupported(locale) {
+		locale = DefaultLocale
+	}
+	current.Store(locale)
+}
+
+// Locale 返回当前语言
+func Locale() string {
+	if locale, ok := current.Load().(string); ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// SupportedLocales 返回所有支持的语言
+func SupportedLocales() []string {
+	return []string{LocaleEnglish, LocaleChinese}
+}
+
+// IsSupported 判断 locale 是否是支持的语言
+func IsSupported(locale string) bool {
+	return slices.Contains(SupportedLocales(), locale)
+}
+
+// T 返回消息 id 在当前语言下的文本，args 按 fmt 的格式化规则填入
+func T(id string, args ...any) string {
+	format := lookup(id)
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Errorf 与 fmt.Errorf 相同，但格式串取自消息目录，因此同样支持 %w
+func Errorf(id string, args ...any) error {
+	return fmt.Errorf(lookup(id), args...)
+}
+
+func lookup(id string) string {
+	if msg, ok := catalog[Locale()][id]; ok {
+		return msg
+	}
+	if msg, ok := catalog[DefaultLocale][id]; ok {
+		return msg
+	}
+	return id
+}
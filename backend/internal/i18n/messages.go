@@ -0,0 +1,121 @@
+package i18n
+
+// catalog 按语言保存消息，同一个 ID 在各语言中的格式化参数（顺序与动词）必须一致
+var catalog = map[string]map[string]string{
+	LocaleEnglish: {
+		// --- SSH 连接 ---
+		"ssh.password_required":       "password is required for '%s'",
+		"ssh.timeout":                 "connection to '%s' timed out, the server may be offline or firewalled",
+		"ssh.dns_failed":              "could not resolve hostname for '%s': %s, check the hostname and your DNS settings",
+		"ssh.connection_refused":      "connection refused by '%s', check the server's IP/port and firewall",
+		"ssh.no_route":                "no route to host '%s', check your network/VPN and the server's IP",
+		"ssh.network_unreachable":     "network is unreachable for '%s', check your network connection and VPN",
+		"ssh.offline":                 "cannot connect to '%s' while offline, it will be possible again once the network is back",
+		"ssh.local_port_in_use":       "the local port is already in use, please choose another port",
+		"ssh.auth_failed":             "authentication failed for '%s', please check your password or SSH key",
+		"ssh.auth_retry":              "Authentication failed. Please try again.",
+		"ssh.unexpected_error":        "an unexpected error occurred for '%s': %v",
+		"ssh.host_not_found":          "Host not found",
+		"ssh.host_key_strict":         "Host key for '%s' is not in known_hosts (strict host key checking is enabled)",
+		"ssh.host_key_capture_failed": "Failed to capture remote host key",
+		"ssh.host_key_unknown":        "the host key for '%s' is not in known_hosts",
+		"ssh.host_key_mismatch":       "the host key for '%s' does not match known_hosts, the server may have been reinstalled or someone may be intercepting the connection",
+		"ssh.passphrase_required":     "the private key %s for '%s' is protected by a passphrase, add it to your ssh-agent or enter the server password",
+
+		// --- 隧道 ---
+		"tunnel.config_not_found":     "tunnel configuration with ID %s not found",
+		"tunnel.manual_host_missing":  "manual host info is missing",
+		"tunnel.unknown_host_source":  "unknown host source",
+		"tunnel.invalid_type":         "tunnel type must be local or dynamic",
+		"tunnel.invalid_host_source":  "host source must be ssh_config or manual",
+		"tunnel.invalid_port":         "port must be between 1 and 65535",
+		"tunnel.remote_host_required": "remote host is required for local forwarding",
+		"tunnel.remote_host_invalid":  "remote host must not contain spaces",
+		"tunnel.alias_required":       "choose a host from the SSH config",
+		"tunnel.alias_not_found":      "host '%s' is not in the SSH config",
+		"tunnel.hostname_required":    "host name is required",
+		"tunnel.duplicate_forward":    "the same forward is already saved as '%s'",
+		"tunnel.port_shared":          "local port %d is also used by '%s', the two tunnels cannot run at the same time",
+		"tunnel.invalid_config":       "invalid tunnel configuration: %s",
+
+		// --- 文件同步 ---
+		"sync.invalid_symlink_mode":      "invalid symlink sync mode: %s",
+		"sync.invalid_max_file_size":     "invalid max file size: %d",
+		"sync.invalid_trash_retention":   "trash retention must be between 0 and %d days",
+		"sync.invalid_clipboard_history": "clipboard history must keep between 0 and %d pushes",
+		"sync.config_not_found":          "configuration with ID '%s' not found",
+		"sync.pair_not_found":            "sync pair with ID '%s' not found",
+		"sync.read_key_failed":           "cannot read private key file: %w",
+		"sync.parse_key_failed":          "cannot parse private key: %w",
+		"sync.dial_failed":               "SSH dial failed: %w",
+		"sync.sftp_failed":               "failed to create SFTP client: %w",
+		"sync.connect_failed":            "connection failed: %w",
+		"sync.connect_ok":                "Connection successful!",
+
+		// --- 桌面通知 ---
+		"notify.tunnel_disconnected":      "Tunnel disconnected",
+		"notify.tunnel_disconnected_body": "The tunnel via '%s' lost its connection: %s",
+		"notify.sync_error":               "File sync error",
+		"notify.host_key_changed":         "Host key changed",
+		"notify.test":                     "DevTools",
+		"notify.test_body":                "Desktop notifications are working.",
+	},
+	LocaleChinese: {
+		// --- SSH 连接 ---
+		"ssh.password_required":       "连接 '%s' 需要密码",
+		"ssh.timeout":                 "连接 '%s' 超时，服务器可能已离线或被防火墙拦截",
+		"ssh.dns_failed":              "无法解析 '%s' 的主机名：%s，请检查主机名和 DNS 设置",
+		"ssh.connection_refused":      "'%s' 拒绝了连接，请检查服务器的 IP/端口和防火墙",
+		"ssh.no_route":                "没有到主机 '%s' 的路由，请检查网络/VPN 和服务器 IP",
+		"ssh.network_unreachable":     "'%s' 网络不可达，请检查网络连接和 VPN",
+		"ssh.offline":                 "当前处于离线状态，无法连接 '%s'，网络恢复后即可重新连接",
+		"ssh.local_port_in_use":       "本地端口已被占用，请选择其他端口",
+		"ssh.auth_failed":             "'%s' 认证失败，请检查密码或 SSH 密钥",
+		"ssh.auth_retry":              "认证失败，请重试。",
+		"ssh.unexpected_error":        "连接 '%s' 时发生意外错误：%v",
+		"ssh.host_not_found":          "未找到主机",
+		"ssh.host_key_strict":         "'%s' 的主机密钥不在 known_hosts 中（已启用严格主机密钥检查）",
+		"ssh.host_key_capture_failed": "获取远程主机密钥失败",
+		"ssh.host_key_unknown":        "'%s' 的主机密钥不在 known_hosts 中",
+		"ssh.host_key_mismatch":       "'%s' 的主机密钥与 known_hosts 中的不一致，服务器可能已重装，也可能有人在拦截连接",
+		"ssh.passphrase_required":     "私钥 %s（'%s'）受密码短语保护，请将其添加到 ssh-agent 或输入服务器密码",
+
+		// --- 隧道 ---
+		"tunnel.config_not_found":     "未找到ID为 %s 的隧道配置",
+		"tunnel.manual_host_missing":  "缺少手动填写的主机信息",
+		"tunnel.unknown_host_source":  "未知的主机来源",
+		"tunnel.invalid_type":         "隧道类型必须是 local 或 dynamic",
+		"tunnel.invalid_host_source":  "主机来源必须是 ssh_config 或 manual",
+		"tunnel.invalid_port":         "端口必须在 1 到 65535 之间",
+		"tunnel.remote_host_required": "本地转发需要填写远程主机",
+		"tunnel.remote_host_invalid":  "远程主机不能包含空格",
+		"tunnel.alias_required":       "请从 SSH 配置中选择主机",
+		"tunnel.alias_not_found":      "SSH 配置中没有主机 '%s'",
+		"tunnel.hostname_required":    "请填写主机地址",
+		"tunnel.duplicate_forward":    "相同的转发已保存为 '%s'",
+		"tunnel.port_shared":          "本地端口 %d 也被 '%s' 使用，两个隧道不能同时运行",
+		"tunnel.invalid_config":       "隧道配置无效：%s",
+
+		// --- 文件同步 ---
+		"sync.invalid_symlink_mode":      "无效的符号链接同步方式: %s",
+		"sync.invalid_max_file_size":     "无效的文件大小上限: %d",
+		"sync.invalid_trash_retention":   "回收站保留天数必须在 0 到 %d 之间",
+		"sync.invalid_clipboard_history": "剪贴板历史保留份数必须在 0 到 %d 之间",
+		"sync.config_not_found":          "未找到ID为 '%s' 的配置",
+		"sync.pair_not_found":            "未找到ID为 '%s' 的同步对",
+		"sync.read_key_failed":           "无法读取私钥文件: %w",
+		"sync.parse_key_failed":          "无法解析私钥: %w",
+		"sync.dial_failed":               "SSH拨号失败: %w",
+		"sync.sftp_failed":               "SFTP客户端创建失败: %w",
+		"sync.connect_failed":            "连接失败: %w",
+		"sync.connect_ok":                "连接成功!",
+
+		// --- 桌面通知 ---
+		"notify.tunnel_disconnected":      "隧道已断开",
+		"notify.tunnel_disconnected_body": "经由 '%s' 的隧道连接已断开：%s",
+		"notify.sync_error":               "文件同步出错",
+		"notify.host_key_changed":         "主机密钥已变化",
+		"notify.test":                     "DevTools",
+		"notify.test_body":                "桌面通知工作正常。",
+	},
+}
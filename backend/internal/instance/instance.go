@@ -0,0 +1,206 @@
+// Package instance 保证同一用户只运行一个 DevTools 实例。
+// 第一个实例对锁文件加上排他锁（Unix 为 flock，Windows 为 LockFileEx），在 127.0.0.1 上监听一个随机端口，
+// 并把端口与 PID 写入锁文件；后续启动的实例无法加锁，读取锁文件，通过该端口请求已有实例把窗口切到前台，然后退出。
+// 锁由操作系统在进程退出（包括崩溃）时释放，因此两个同时启动的实例不会都成为唯一实例，也不需要处理残留的锁文件。
+package instance
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"devtools/backend/internal/logging"
+)
+
+var logger = logging.For("app")
+
+const (
+	lockFileName = "instance.lock"
+
+	cmdFocus = "devtools:focus"
+	replyOK  = "devtools:ok"
+
+	dialTimeout = 2 * time.Second
+	// focusRetries 与 focusRetryDelay 控制在已有实例写入地址之前（刚启动时）重试聚焦请求的次数与间隔
+	focusRetries    = 10
+	focusRetryDelay = 200 * time.Millisecond
+)
+
+// ErrAlreadyRunning 表示已有实例在运行，并且已经请求它切到前台
+var ErrAlreadyRunning = errors.New("another DevTools instance is already running")
+
+// errLocked 表示锁文件已被其他进程锁定，由各平台的 lockFile 返回
+var errLocked = errors.New("instance lock is held by another process")
+
+// Guard 持有单实例锁，直到 Release 被调用
+type Guard struct {
+	lockFile *os.File
+	listener net.Listener
+
+	mu      sync.Mutex
+	onFocus func()
+}
+
+// Acquire 尝试成为唯一的实例。
+// 如果已有实例在运行，会请求它聚焦窗口并返回 ErrAlreadyRunning。
+func Acquire(dir string) (*Guard, error) {
+	lockPath := filepath.Join(dir, lockFileName)
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open instance lock file: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, errLocked) {
+			focusRunningInstance(lockPath)
+			return nil, ErrAlreadyRunning
+		}
+		return nil, fmt.Errorf("failed to lock instance lock file: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		unlockFile(file)
+		file.Close()
+		return nil, fmt.Errorf("failed to listen for instance requests: %w", err)
+	}
+
+	content := fmt.Sprintf("%d\n%s\n", os.Getpid(), listener.Addr().String())
+	if err := writeLockFile(file, content); err != nil {
+		listener.Close()
+		unlockFile(file)
+		file.Close()
+		return nil, fmt.Errorf("failed to write instance lock file: %w", err)
+	}
+
+	g := &Guard{lockFile: file, listener: listener}
+	go g.serve()
+	return g, nil
+}
+
+// focusRunningInstance 请求持有锁的实例聚焦窗口。该实例可能刚加锁、还没有写入地址，因此会重试一段时间；
+// 始终没有应答时只记录警告，已有实例仍持有锁，当前实例照样退出。
+func focusRunningInstance(lockPath string) {
+	var err error
+	for i := 0; i < focusRetries; i++ {
+		if i > 0 {
+			time.Sleep(focusRetryDelay)
+		}
+		addr, ok := readLockFile(lockPath)
+		if !ok {
+			err = fmt.Errorf("instance lock file %s has no address yet", lockPath)
+			continue
+		}
+		if err = requestFocus(addr); err == nil {
+			return
+		}
+	}
+	logger.Printf("Warning: the running instance did not respond to the focus request: %v", err)
+}
+
+// writeLockFile 用 content 替换锁文件的内容
+func writeLockFile(file *os.File, content string) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt([]byte(content), 0); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// OnFocus 设置收到其他实例的聚焦请求时要执行的回调
+func (g *Guard) OnFocus(fn func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onFocus = fn
+}
+
+// Release 释放单实例锁，应在应用退出时调用。
+// 锁文件只清空不删除：删除后，正在等待同一个文件的进程与新建文件的进程可能会同时拿到锁。
+func (g *Guard) Release() {
+	g.listener.Close()
+	_ = g.lockFile.Truncate(0)
+	unlockFile(g.lockFile)
+	g.lockFile.Close()
+}
+
+func (g *Guard) serve() {
+	for {
+		conn, err := g.listener.Accept()
+		if err != nil {
+			return // listener closed
+		}
+		go g.handle(conn)
+	}
+}
+
+func (g *Guard) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != cmdFocus {
+		return
+	}
+	_, _ = fmt.Fprintln(conn, replyOK)
+
+	logger.Println("Another instance was launched, bringing the window to the front.")
+	g.mu.Lock()
+	fn := g.onFocus
+	g.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+// requestFocus 连接已有实例并请求其聚焦；实例不存在或没有正确应答时返回错误
+func requestFocus(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	if _, err := fmt.Fprintln(conn, cmdFocus); err != nil {
+		return err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(reply) != replyOK {
+		return fmt.Errorf("unexpected reply from %s", addr)
+	}
+	return nil
+}
+
+func readLockFile(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	_, addr, ok := parseLockFile(string(data))
+	return addr, ok
+}
+
+// parseLockFile 解析锁文件：第一行是 PID，第二行是监听地址
+func parseLockFile(content string) (int, string, bool) {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) < 2 {
+		return 0, "", false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return 0, "", false
+	}
+	return pid, strings.TrimSpace(lines[1]), true
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package instance
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffsetHigh 是锁定区域的偏移（高 32 位）。Windows 的文件锁是强制锁，锁定的字节无法被其他进程读取，
+// 因此锁定文件末尾之外的一个字节，其他实例仍然可以读取锁文件中的地址。
+const lockOffsetHigh = 1
+
+// lockFile 对锁文件加非阻塞的排他锁，已被其他进程锁定时返回 errLocked
+func lockFile(file *os.File) error {
+	overlapped := &windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) {
+	overlapped := &windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	_ = windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, overlapped)
+}
@@ -0,0 +1,358 @@
+// Package logging 提供基于 slog 的分级、分子系统日志。
+// 所有子系统共用一个输出（可轮转的 app.log），并在内存中保留最近的日志，
+// 供前端日志查看器按级别与子系统过滤，而无需读取日志文件。
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// RecentCapacity 是内存中保留的最近日志条数（GetRecentLogs 的 limit 上限）
+	RecentCapacity = 2000
+	// defaultRecentLimit 是 GetRecentLogs 未指定 limit 时返回的条数
+	defaultRecentLimit = 200
+
+	defaultMaxFileSize = 5 * 1024 * 1024 // 5MB
+	defaultMaxBackups  = 3
+)
+
+// Entry 是一条结构化的日志记录
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Subsystem string    `json:"subsystem"`
+	Message   string    `json:"message"`
+}
+
+// core 是所有子系统 handler 共享的状态：输出、级别配置与最近日志
+type core struct {
+	mu       sync.Mutex
+	out      io.Writer
+	recent   []Entry
+	next     int
+	full     bool
+	levelsMu sync.RWMutex
+	defLevel slog.Level
+	levels   map[string]slog.Level
+
+	subsMu sync.RWMutex
+	subs   map[int]func(Entry)
+	nextID int
+}
+
+var std = &core{
+	out:      os.Stderr,
+	recent:   make([]Entry, RecentCapacity),
+	defLevel: slog.LevelInfo,
+	levels:   make(map[string]slog.Level),
+	subs:     make(map[int]func(Entry)),
+}
+
+func (c *core) levelFor(subsystem string) slog.Level {
+	c.levelsMu.RLock()
+	defer c.levelsMu.RUnlock()
+	if l, ok := c.levels[subsystem]; ok {
+		return l
+	}
+	return c.defLevel
+}
+
+func (c *core) write(e Entry, line string) {
+	c.mu.Lock()
+	_, _ = io.WriteString(c.out, line)
+	c.recent[c.next] = e
+	c.next = (c.next + 1) % len(c.recent)
+	if c.next == 0 {
+		c.full = true
+	}
+	c.mu.Unlock()
+
+	c.subsMu.RLock()
+	defer c.subsMu.RUnlock()
+	for _, fn := range c.subs {
+		fn(e)
+	}
+}
+
+// snapshot 按时间顺序返回内存中的日志
+func (c *core) snapshot() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.full {
+		out := make([]Entry, c.next)
+		copy(out, c.recent[:c.next])
+		return out
+	}
+	out := make([]Entry, 0, len(c.recent))
+	out = append(out, c.recent[c.next:]...)
+	out = append(out, c.recent[:c.next]...)
+	return out
+}
+
+// handler 是一个子系统的 slog.Handler
+type handler struct {
+	subsystem string
+	attrs     []slog.Attr
+	core      *core
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.core.levelFor(h.subsystem)
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Message)
+	appendAttr := func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range h.attrs {
+		appendAttr(a)
+	}
+	r.Attrs(appendAttr)
+
+	msg := b.String()
+	e := Entry{Time: r.Time, Level: levelName(r.Level), Subsystem: h.subsystem, Message: msg}
+	line := fmt.Sprintf("%s %-5s [%s] %s\n", r.Time.Format("2006/01/02 15:04:05.000"), e.Level, h.subsystem, msg)
+	h.core.write(e, line)
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	merged := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	merged = append(merged, h.attrs...)
+	merged = append(merged, attrs...)
+	return &handler{subsystem: h.subsystem, attrs: merged, core: h.core}
+}
+
+func (h *handler) WithGroup(string) slog.Handler {
+	return h // groups are flattened
+}
+
+// Logger 是某个子系统的日志器，提供 printf 风格的分级方法
+type Logger struct {
+	subsystem string
+	sl        *slog.Logger
+}
+
+var (
+	loggersMu sync.Mutex
+	loggers   = make(map[string]*Logger)
+)
+
+// For 返回指定子系统的日志器，例如 "sshgate"、"tunnel"、"terminal"、"syncer"
+func For(subsystem string) *Logger {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+	if l, ok := loggers[subsystem]; ok {
+		return l
+	}
+	l := &Logger{subsystem: subsystem, sl: slog.New(&handler{subsystem: subsystem, core: std})}
+	loggers[subsystem] = l
+	return l
+}
+
+func (l *Logger) logf(level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	if !l.sl.Enabled(ctx, level) {
+		return
+	}
+	l.sl.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Debugf(format string, args ...any) { l.logf(slog.LevelDebug, format, args...) }
+func (l *Logger) Infof(format string, args ...any)  { l.logf(slog.LevelInfo, format, args...) }
+func (l *Logger) Warnf(format string, args ...any)  { l.logf(slog.LevelWarn, format, args...) }
+func (l *Logger) Errorf(format string, args ...any) { l.logf(slog.LevelError, format, args...) }
+
+// Printf 与 log.Printf 兼容，级别根据消息前缀（如 "Warning:"、"ERROR:"）推断
+func (l *Logger) Printf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	l.logf(inferLevel(msg), "%s", msg)
+}
+
+// Println 与 log.Println 兼容，级别根据消息前缀推断
+func (l *Logger) Println(args ...any) {
+	msg := strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+	l.logf(inferLevel(msg), "%s", msg)
+}
+
+// Fatalf 记录一条错误日志后退出进程
+func (l *Logger) Fatalf(format string, args ...any) {
+	l.logf(slog.LevelError, format, args...)
+	os.Exit(1)
+}
+
+// Slog 返回底层的 *slog.Logger，用于结构化字段
+func (l *Logger) Slog() *slog.Logger {
+	return l.sl
+}
+
+// StdLogger 返回一个写入该子系统的 *log.Logger，用于只接受标准库 logger 的接口
+func (l *Logger) StdLogger() *log.Logger {
+	return slog.NewLogLogger(l.sl.Handler(), slog.LevelError)
+}
+
+// inferLevel 根据旧式日志消息的前缀推断级别
+func inferLevel(msg string) slog.Level {
+	lower := strings.ToLower(strings.TrimSpace(msg))
+	switch {
+	case strings.HasPrefix(lower, "fatal"), strings.HasPrefix(lower, "error"),
+		strings.HasPrefix(lower, "critical"), strings.HasPrefix(lower, "关键错误"):
+		return slog.LevelError
+	case strings.HasPrefix(lower, "warn"), strings.HasPrefix(lower, "警告"):
+		return slog.LevelWarn
+	case strings.HasPrefix(lower, "debug"):
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func levelName(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError:
+		return "ERROR"
+	case l >= slog.LevelWarn:
+		return "WARN"
+	case l >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// ParseLevel 将 "debug"、"info"、"warn"、"error" 转换为 slog.Level
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level '%s'", s)
+	}
+}
+
+// SetLevels 设置默认级别与各子系统的级别，未列出的子系统使用默认级别
+func SetLevels(defaultLevel string, perSubsystem map[string]string) error {
+	def, err := ParseLevel(defaultLevel)
+	if err != nil {
+		return err
+	}
+	levels := make(map[string]slog.Level, len(perSubsystem))
+	for subsystem, s := range perSubsystem {
+		l, err := ParseLevel(s)
+		if err != nil {
+			return fmt.Errorf("subsystem %s: %w", subsystem, err)
+		}
+		levels[subsystem] = l
+	}
+
+	std.levelsMu.Lock()
+	defer std.levelsMu.Unlock()
+	std.defLevel = def
+	std.levels = levels
+	return nil
+}
+
+// Init 将日志输出到 dir/app.log（按大小轮转）。debug 模式下同时输出到终端。
+// 标准库 log 的输出也会被接管，以便尚未迁移的调用同样进入 app.log 和内存缓冲。
+func Init(dir string, debug bool) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+	logFilePath := filepath.Join(dir, "app.log")
+	w, err := newRotatingWriter(logFilePath, defaultMaxFileSize, defaultMaxBackups)
+	if err != nil {
+		return "", err
+	}
+
+	var out io.Writer = w
+	if debug {
+		out = io.MultiWriter(os.Stderr, w)
+	}
+	std.mu.Lock()
+	std.out = out
+	std.mu.Unlock()
+
+	// 接管标准库 log：每一行都作为 "app" 子系统的日志处理
+	log.SetFlags(0)
+	log.SetOutput(stdLogBridge{logger: For("app")})
+	return logFilePath, nil
+}
+
+// stdLogBridge 将标准库 log 的输出转为结构化日志
+type stdLogBridge struct {
+	logger *Logger
+}
+
+func (b stdLogBridge) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		b.logger.Printf("%s", line)
+	}
+	return len(p), nil
+}
+
+// Subscribe 注册一个回调，每写入一条日志都会被调用一次，返回取消订阅的函数。
+// 回调在写日志的 goroutine 中同步执行，必须快速返回，且不能再写日志。
+func Subscribe(fn func(Entry)) (unsubscribe func()) {
+	std.subsMu.Lock()
+	id := std.nextID
+	std.nextID++
+	std.subs[id] = fn
+	std.subsMu.Unlock()
+
+	return func() {
+		std.subsMu.Lock()
+		delete(std.subs, id)
+		std.subsMu.Unlock()
+	}
+}
+
+// GetRecentLogs 返回内存中最近的日志。
+// level 为最低级别（空字符串表示全部），subsystem 为空表示全部子系统，limit <= 0 时使用默认值。
+func GetRecentLogs(level, subsystem string, limit int) []Entry {
+	minLevel := slog.LevelDebug
+	if level != "" {
+		if l, err := ParseLevel(level); err == nil {
+			minLevel = l
+		}
+	}
+	if limit <= 0 {
+		limit = defaultRecentLimit
+	}
+
+	all := std.snapshot()
+	out := make([]Entry, 0, limit)
+	for i := len(all) - 1; i >= 0 && len(out) < limit; i-- {
+		e := all[i]
+		if subsystem != "" && e.Subsystem != subsystem {
+			continue
+		}
+		if l, _ := ParseLevel(e.Level); l < minLevel {
+			continue
+		}
+		out = append(out, e)
+	}
+	// 恢复为时间正序
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingWriter 是一个按大小轮转的文件 writer：
+// 当前文件超过 maxSize 时，app.log 依次重命名为 app.log.1、app.log.2 …，最多保留 maxBackups 个。
+type rotatingWriter struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newRotatingWriter(path string, maxSize int64, maxBackups int) (*rotatingWriter, error) {
+	w := &rotatingWriter{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := w.open(w.path); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open 以追加方式打开 path 作为当前文件
+func (w *rotatingWriter) open(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o660)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		// 上次轮转后没能打开任何文件，每次写入时重试
+		if err := w.open(w.path); err != nil {
+			return 0, err
+		}
+	}
+	if w.size+int64(len(p)) > w.maxSize && w.size > 0 {
+		if err := w.rotate(); err != nil {
+			// 轮转失败时继续写入当前文件，避免丢失日志
+			fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+		}
+		if w.file == nil {
+			return 0, fmt.Errorf("no log file is open")
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate 关闭当前文件，移动备份并重新打开一个空文件。调用方需持有锁。
+// 当前文件必须先关闭（Windows 上无法重命名打开的文件）；新文件打不开时重新打开刚才的文件继续追加，
+// 两者都失败时 w.file 为 nil，之后的写入会重试打开。
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		if openErr := w.open(w.path); openErr != nil {
+			w.file = nil
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+	current := w.path
+	for i := w.maxBackups - 1; i >= 1; i-- {
+		src := fmt.Sprintf("%s.%d", w.path, i)
+		if _, err := os.Stat(src); err == nil {
+			_ = os.Rename(src, fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+	}
+	if w.maxBackups > 0 {
+		if err := os.Rename(w.path, w.path+".1"); err == nil {
+			current = w.path + ".1"
+		}
+	} else {
+		_ = os.Remove(w.path)
+	}
+
+	err := w.open(w.path)
+	if err == nil {
+		return nil
+	}
+	if reopenErr := w.open(current); reopenErr != nil {
+		w.file = nil
+		return errors.Join(err, reopenErr)
+	}
+	// 再写入 maxSize 之后才重试轮转，避免每次写入都移动一遍备份
+	w.size = 0
+	return err
+}
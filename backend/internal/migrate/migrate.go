@@ -0,0 +1,208 @@
+// Package migrate 在应用启动时升级应用配置目录中的旧版配置文件。
+//
+// 每个配置文件的根对象带有 schemaVersion 字段，缺失时视为 0（引入版本号之前的格式）。
+// 启动时按顺序执行从文件当前版本到最新版本的迁移，并返回每个文件迁移了什么，便于在界面上展示。
+// 只有迁移实际修改了内容时才写入文件，写入前将原文件备份为 "<name>.v<版本>.bak"；
+// 没有修改的旧版本文件保持原样，由各服务下次保存时写入当前版本号。
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"devtools/backend/internal/logging"
+)
+
+var logger = logging.For("migrate")
+
+const (
+	// ConfigSchemaVersion 是 config.json（同步配置）的当前版本
+	ConfigSchemaVersion = 1
+	// TunnelsSchemaVersion 是 tunnels.json（隧道配置）的当前版本
+	TunnelsSchemaVersion = 1
+)
+
+// document 是配置文件解析后的根对象，迁移直接修改其中的字段
+type document map[string]any
+
+// step 是把某个文件从 From 版本升级到 From+1 的迁移
+type step struct {
+	From        int
+	Description string
+	// Apply 修改 doc 并返回具体做了哪些改动，没有改动时返回空
+	Apply func(doc document) []string
+}
+
+// fileSpec 描述一个需要迁移的配置文件
+type fileSpec struct {
+	Name    string
+	Version int
+	Steps   []step
+}
+
+// FileReport 是一个配置文件的迁移结果
+type FileReport struct {
+	File        string   `json:"file"`
+	FromVersion int      `json:"fromVersion"`
+	ToVersion   int      `json:"toVersion"`
+	Changes     []string `json:"changes"`
+	BackupPath  string   `json:"backupPath,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// Report 是一次启动时迁移的汇总
+type Report struct {
+	FirstRun bool         `json:"firstRun"` // 应用配置目录中还没有任何配置文件，可以展示首次使用引导
+	Files    []FileReport `json:"files"`    // 只包含实际迁移过或迁移失败的文件
+}
+
+// Migrated 判断是否有文件被升级
+func (r *Report) Migrated() bool {
+	for _, f := range r.Files {
+		if f.Error == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// specs 列出所有受版本管理的配置文件
+var specs = []fileSpec{
+	{Name: "config.json", Version: ConfigSchemaVersion, Steps: syncConfigSteps},
+	{Name: "tunnels.json", Version: TunnelsSchemaVersion, Steps: tunnelsSteps},
+}
+
+// Run 迁移 dir（应用配置目录）中的所有配置文件。单个文件失败不会影响其它文件，
+// 失败的文件保持原样，由各服务按原来的方式加载。
+func Run(dir string) *Report {
+	report := &Report{FirstRun: true, Files: []FileReport{}}
+	for _, spec := range specs {
+		path := filepath.Join(dir, spec.Name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				report.FirstRun = false
+				report.Files = append(report.Files, FileReport{File: path, Error: err.Error()})
+			}
+			continue
+		}
+		report.FirstRun = false
+
+		fr, err := migrateFile(path, data, spec)
+		if err != nil {
+			fr.Error = err.Error()
+			logger.Printf("ERROR: failed to migrate %s: %v", path, err)
+		}
+		if fr.Error != "" || len(fr.Changes) > 0 {
+			report.Files = append(report.Files, fr)
+		}
+	}
+	return report
+}
+
+// migrateFile 升级单个文件；已经是最新版本或迁移没有改动任何内容时不写入文件
+func migrateFile(path string, data []byte, spec fileSpec) (FileReport, error) {
+	fr := FileReport{File: path, ToVersion: spec.Version, Changes: []string{}}
+	if len(bytes.TrimSpace(data)) == 0 {
+		fr.FromVersion = spec.Version
+		return fr, nil // 空文件由各服务当作空配置处理
+	}
+
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fr, fmt.Errorf("invalid JSON: %w", err)
+	}
+	obj, ok := raw.(map[string]any)
+	if !ok {
+		return fr, fmt.Errorf("unexpected root type %T", raw)
+	}
+	doc := document(obj)
+
+	fr.FromVersion = doc.version()
+	if fr.FromVersion > spec.Version {
+		// 由更新版本的应用写入，不做降级
+		fr.ToVersion = fr.FromVersion
+		return fr, fmt.Errorf("schema version %d is newer than supported version %d", fr.FromVersion, spec.Version)
+	}
+	if fr.FromVersion == spec.Version {
+		return fr, nil
+	}
+
+	for _, st := range spec.Steps {
+		if st.From < fr.FromVersion {
+			continue
+		}
+		logger.Printf("Applying %s migration v%d→v%d: %s", spec.Name, st.From, st.From+1, st.Description)
+		for _, change := range st.Apply(doc) {
+			fr.Changes = append(fr.Changes, fmt.Sprintf("v%d→v%d: %s", st.From, st.From+1, change))
+		}
+	}
+	if len(fr.Changes) == 0 {
+		// 旧版本的格式与当前版本兼容，不必为了写入版本号而改写文件
+		fr.ToVersion = fr.FromVersion
+		return fr, nil
+	}
+	doc["schemaVersion"] = spec.Version
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fr, fmt.Errorf("failed to marshal migrated file: %w", err)
+	}
+	fr.BackupPath = fmt.Sprintf("%s.v%d.bak", path, fr.FromVersion)
+	if err := os.WriteFile(fr.BackupPath, data, 0o600); err != nil {
+		fr.BackupPath = ""
+		return fr, fmt.Errorf("failed to back up original file: %w", err)
+	}
+	if err := writeFileAtomic(path, out); err != nil {
+		return fr, err
+	}
+	logger.Printf("Migrated %s from schema v%d to v%d (%d changes, backup at %s).",
+		path, fr.FromVersion, fr.ToVersion, len(fr.Changes), fr.BackupPath)
+	return fr, nil
+}
+
+// writeFileAtomic 先写入临时文件再改名，避免迁移中途退出留下不完整的配置
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp := path + ".migrating"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return fmt.Errorf("failed to write migrated file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace original file: %w", err)
+	}
+	return nil
+}
+
+// version 返回根对象中的 schemaVersion，缺失或无效时为 0
+func (d document) version() int {
+	if v, ok := d["schemaVersion"].(float64); ok && v >= 0 {
+		return int(v)
+	}
+	return 0
+}
+
+// list 返回 key 对应的对象数组，缺失时为空
+func (d document) list(key string) []map[string]any {
+	items, _ := d[key].([]any)
+	var out []map[string]any
+	for _, item := range items {
+		if obj, ok := item.(map[string]any); ok {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+// stringField 返回对象中的字符串字段
+func stringField(obj map[string]any, key string) string {
+	s, _ := obj[key].(string)
+	return s
+}
@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// 版本 0 是引入 schemaVersion 之前发布的格式，字段名与版本 1 相同：
+// config.json 为 {"sshConfigs": [...], "syncPairs": [...]}，tunnels.json 为 {"tunnels": [...], "tunnelsOrder": [...]}。
+// 版本 0 的应用不校验这些字段，迁移只补全版本 1 要求非空的字段。
+
+// --- config.json ---
+
+var syncConfigSteps = []step{
+	{
+		From:        0,
+		Description: "fill in missing IDs and connection defaults",
+		Apply: func(doc document) []string {
+			var changes []string
+			configIDs := map[string]bool{}
+			for _, cfg := range doc.list("sshConfigs") {
+				name := stringField(cfg, "name")
+				if stringField(cfg, "id") == "" {
+					cfg["id"] = uuid.NewString()
+					changes = append(changes, fmt.Sprintf("SSH config %q: assigned missing ID", name))
+				}
+				configIDs[stringField(cfg, "id")] = true
+				if port, ok := cfg["port"].(float64); !ok || port <= 0 {
+					cfg["port"] = 22
+					changes = append(changes, fmt.Sprintf("SSH config %q: set missing port to 22", name))
+				}
+				if stringField(cfg, "authMethod") == "" {
+					method := "password"
+					if stringField(cfg, "keyPath") != "" {
+						method = "key"
+					}
+					cfg["authMethod"] = method
+					changes = append(changes, fmt.Sprintf("SSH config %q: set missing auth method to %s", name, method))
+				}
+			}
+
+			for _, pair := range doc.list("syncPairs") {
+				local := stringField(pair, "localPath")
+				if stringField(pair, "id") == "" {
+					pair["id"] = uuid.NewString()
+					changes = append(changes, fmt.Sprintf("sync pair %q: assigned missing ID", local))
+				}
+				if !configIDs[stringField(pair, "configId")] {
+					// 保留数据，只记录日志：同步对在界面上不会出现在任何配置下
+					logger.Printf("Warning: sync pair %q references unknown SSH config %q", local, stringField(pair, "configId"))
+				}
+			}
+			return changes
+		},
+	},
+}
+
+// --- tunnels.json ---
+
+var tunnelsSteps = []step{
+	{
+		From:        0,
+		Description: "fill in missing IDs, tunnel types and host sources",
+		Apply: func(doc document) []string {
+			var changes []string
+			for _, tunnel := range doc.list("tunnels") {
+				name := stringField(tunnel, "name")
+				if stringField(tunnel, "id") == "" {
+					tunnel["id"] = uuid.NewString()
+					changes = append(changes, fmt.Sprintf("tunnel %q: assigned missing ID", name))
+				}
+				if stringField(tunnel, "tunnelType") == "" {
+					tunnelType := "dynamic"
+					if stringField(tunnel, "remoteHost") != "" {
+						tunnelType = "local"
+					}
+					tunnel["tunnelType"] = tunnelType
+					changes = append(changes, fmt.Sprintf("tunnel %q: set missing type to %s", name, tunnelType))
+				}
+				if stringField(tunnel, "hostSource") == "" {
+					source := "ssh_config"
+					if _, ok := tunnel["manualHost"].(map[string]any); ok {
+						source = "manual"
+					}
+					tunnel["hostSource"] = source
+					changes = append(changes, fmt.Sprintf("tunnel %q: set missing host source to %s", name, source))
+				}
+			}
+			return changes
+		},
+	},
+}
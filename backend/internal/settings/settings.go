@@ -0,0 +1,481 @@
+package settings
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/logging"
+	"devtools/backend/pkg/hotkey"
+)
+
+var logger = logging.For("settings")
+
+// currentVersion 是设置文件的 schema 版本，结构发生不兼容变化时递增
+const currentVersion = 1
+
+// 主机密钥策略，对应 OpenSSH 的 StrictHostKeyChecking
+const (
+	HostKeyPolicyAsk       = "ask"        // 未知主机密钥时询问用户（默认）
+	HostKeyPolicyAcceptNew = "accept-new" // 自动信任未知主机，但拒绝已变化的密钥
+	HostKeyPolicyStrict    = "strict"     // 只信任 known_hosts 中已有的密钥
+)
+
+// 本地 API 的默认端口与 token 最小长度
+const (
+	DefaultLocalAPIPort    = 47321
+	minLocalAPITokenLength = 16
+)
+
+// 终端粘贴大小：默认超过 1 MiB 需要确认，设置值不能超过 64 MiB
+const (
+	DefaultTerminalMaxPasteBytes = 1 << 20
+	maxTerminalPasteBytes        = 64 << 20
+)
+
+// 停止隧道时默认最多等待 10 秒让活动连接结束
+const DefaultTunnelDrainTimeoutSeconds = 10
+
+// 隧道的 SSH 往返延迟默认超过 500 毫秒时提示变慢
+const DefaultTunnelSlowLatencyMs = 500
+
+// 隧道日志文件默认保留 14 天，最长一年
+const (
+	DefaultTunnelLogRetentionDays = 14
+	maxTunnelLogRetentionDays     = 365
+)
+
+// 更新检查默认每天一次，最长间隔一周
+const (
+	DefaultUpdateCheckIntervalHours = 24
+	maxUpdateCheckIntervalHours     = 7 * 24
+)
+
+// 全局快捷键触发的动作
+const (
+	HotkeyActionQuickConnect = "quick_connect" // 显示窗口并打开快速连接面板
+	HotkeyActionShowWindow   = "show_window"   // 显示窗口
+	HotkeyActionToggleTunnel = "toggle_tunnel" // 启动或停止 Target 指定的隧道
+)
+
+// 界面主题提示
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// Settings 是应用级偏好设置的完整 schema
+type Settings struct {
+	Version int `json:"version"`
+
+	// --- 界面 ---
+	Theme  string `json:"theme"`  // system | light | dark
+	Locale string `json:"locale"` // 后端返回的错误信息等文本使用的语言：en | zh-CN
+
+	// --- 事件 ---
+	TunnelEventDebounceMs int `json:"tunnelEventDebounceMs"` // tunnels:changed / saved_tunnels_changed 的防抖时间
+
+	// --- 隧道 ---
+	TunnelDrainTimeoutSeconds int  `json:"tunnelDrainTimeoutSeconds"` // 停止隧道时等待活动连接结束的最长时间，0 表示立即关闭
+	MigrateTunnelCredentials  bool `json:"migrateTunnelCredentials"`  // 复制隧道时复制钥匙串中的密码，主机来源变化时迁移密码
+	TunnelSlowLatencyMs       int  `json:"tunnelSlowLatencyMs"`       // 平均往返延迟超过该值时发送 tunnel:slow 事件，0 表示不提示
+	TunnelFileLogs            bool `json:"tunnelFileLogs"`            // 把每个隧道的启停与连接事件写入日志目录下的 tunnels/<名称>-<日期>.log
+	TunnelLogRetentionDays    int  `json:"tunnelLogRetentionDays"`    // 隧道日志文件保留的天数
+
+	// --- 终端 ---
+	DefaultTerminal          string             `json:"defaultTerminal"`          // 外部终端程序，空字符串表示使用平台默认值
+	TerminalMaxPasteBytes    int                `json:"terminalMaxPasteBytes"`    // 超过该大小的粘贴需要用户确认，0 表示不限制
+	TerminalBracketedPaste   bool               `json:"terminalBracketedPaste"`   // 远程程序开启 bracketed paste 模式时包裹粘贴内容
+	TerminalShellIntegration bool               `json:"terminalShellIntegration"` // 启动会话时注入 OSC 133 标记脚本（bash/zsh），用于按命令跳转与计时
+	TerminalIdle             TerminalIdlePolicy `json:"terminalIdle"`             // 远程会话长时间没有输入时的处理（警告、执行命令或关闭）
+
+	// --- SSH ---
+	KeepAliveIntervalSeconds int              `json:"keepAliveIntervalSeconds"` // 0 表示使用 ssh_config 或内置默认值
+	KeepAliveCountMax        int              `json:"keepAliveCountMax"`        // 0 表示使用 ssh_config 或内置默认值
+	HostKeyPolicy            string           `json:"hostKeyPolicy"`            // ask | accept-new | strict
+	NewHostDefaults          NewHostDefaults  `json:"newHostDefaults"`          // 新建主机时为空字段填入的默认值
+	TeamConfig               TeamConfigSource `json:"teamConfig"`               // 团队共享的只读 ssh_config 片段
+	LockedHosts              []string         `json:"lockedHosts"`              // 在界面中只读的主机别名，与 ssh_config 中的 "# @locked" 注释效果相同
+	ReadOnly                 bool             `json:"readOnly"`                 // 只读模式：禁止修改 ssh_config；配置文件不可写时会自动进入只读模式
+
+	// --- 通知 ---
+	Notifications NotificationSettings `json:"notifications"` // 隧道断开、同步出错、主机密钥变化时发送系统桌面通知
+
+	// --- 全局快捷键 ---
+	GlobalHotkeys []GlobalHotkey `json:"globalHotkeys"` // 应用不在前台时也能触发的系统级快捷键
+
+	// --- 本地 API ---
+	LocalAPIEnabled bool   `json:"localApiEnabled"` // 是否启动供外部工具使用的本地 HTTP API（默认关闭）
+	LocalAPIPort    int    `json:"localApiPort"`    // 仅监听 127.0.0.1
+	LocalAPIToken   string `json:"localApiToken"`   // 请求需携带 "Authorization: Bearer <token>"
+
+	// --- 更新 ---
+	UpdateCheckEnabled       bool `json:"updateCheckEnabled"`       // 是否定期检查新版本（默认关闭）
+	UpdateCheckIntervalHours int  `json:"updateCheckIntervalHours"` // 检查间隔
+
+	// --- 日志 ---
+	LogLevel           string            `json:"logLevel"`                     // debug | info | warn | error
+	SubsystemLogLevels map[string]string `json:"subsystemLogLevels,omitempty"` // 例如 {"tunnel": "debug"}
+}
+
+// NewHostDefaults 是新建 SSH 主机时使用的默认参数，空值（或 0）表示不设置
+type NewHostDefaults struct {
+	User                string `json:"user"`
+	Port                string `json:"port"`
+	IdentityFile        string `json:"identityFile"`
+	ServerAliveInterval int    `json:"serverAliveInterval"`
+}
+
+// Validate 检查新建主机默认参数是否合法
+func (d NewHostDefaults) Validate() error {
+	if strings.ContainsAny(d.User, " \t") {
+		return fmt.Errorf("default user cannot contain spaces")
+	}
+	if d.Port != "" {
+		if port, err := strconv.Atoi(d.Port); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("default port must be between 1 and 65535")
+		}
+	}
+	if d.ServerAliveInterval < 0 || d.ServerAliveInterval > 3600 {
+		return fmt.Errorf("default ServerAliveInterval must be between 0 and 3600 seconds")
+	}
+	return nil
+}
+
+// GlobalHotkey 把一个系统级快捷键绑定到一个动作
+type GlobalHotkey struct {
+	Action      string `json:"action"`           // quick_connect | show_window | toggle_tunnel
+	Accelerator string `json:"accelerator"`      // 例如 "CmdOrCtrl+Shift+Space"
+	Target      string `json:"target,omitempty"` // toggle_tunnel 时为隧道配置 ID
+}
+
+// validateGlobalHotkeys 检查动作、快捷键格式，并拒绝重复的快捷键
+func validateGlobalHotkeys(bindings []GlobalHotkey) error {
+	seen := make(map[string]bool)
+	for _, b := range bindings {
+		switch b.Action {
+		case HotkeyActionQuickConnect, HotkeyActionShowWindow:
+		case HotkeyActionToggleTunnel:
+			if b.Target == "" {
+				return fmt.Errorf("shortcut %s must select a tunnel to toggle", b.Accelerator)
+			}
+		default:
+			return fmt.Errorf("invalid shortcut action '%s'", b.Action)
+		}
+		h, err := hotkey.Parse(b.Accelerator)
+		if err != nil {
+			return err
+		}
+		if seen[h.String()] {
+			return fmt.Errorf("shortcut %s is assigned more than once", b.Accelerator)
+		}
+		seen[h.String()] = true
+	}
+	return nil
+}
+
+// 远程终端会话空闲时的动作
+const (
+	IdleActionWarn    = "warn"    // 只发送 terminal:idle 事件，由前端提示用户
+	IdleActionCommand = "command" // 向会话写入 Command，例如 "clear" 或 "exit"
+	IdleActionClose   = "close"   // 关闭会话
+)
+
+// 终端空闲超时最长一天
+const maxTerminalIdleMinutes = 24 * 60
+
+// TerminalIdlePolicy 定义远程终端会话多久没有输入算作空闲，以及空闲后的动作
+type TerminalIdlePolicy struct {
+	TimeoutMinutes int    `json:"timeoutMinutes"`    // 0 表示不检测空闲
+	Action         string `json:"action"`            // warn | command | close
+	Command        string `json:"command,omitempty"` // Action 为 command 时写入会话的命令
+}
+
+// Validate 检查空闲策略是否合法
+func (p TerminalIdlePolicy) Validate() error {
+	if p.TimeoutMinutes < 0 || p.TimeoutMinutes > maxTerminalIdleMinutes {
+		return fmt.Errorf("terminal idle timeout must be between 0 and %d minutes", maxTerminalIdleMinutes)
+	}
+	switch p.Action {
+	case IdleActionWarn, IdleActionClose:
+	case IdleActionCommand:
+		if strings.TrimSpace(p.Command) == "" {
+			return fmt.Errorf("terminal idle command cannot be empty")
+		}
+	default:
+		return fmt.Errorf("invalid terminal idle action '%s'", p.Action)
+	}
+	return nil
+}
+
+// NotificationSettings 控制哪些事件会发送系统桌面通知
+type NotificationSettings struct {
+	Enabled            bool `json:"enabled"`            // 总开关
+	TunnelDisconnected bool `json:"tunnelDisconnected"` // 隧道的 SSH 连接意外断开
+	SyncError          bool `json:"syncError"`          // 文件同步出错
+	HostKeyChanged     bool `json:"hostKeyChanged"`     // 主机密钥与 known_hosts 不一致
+}
+
+// 团队配置的刷新间隔：默认 30 分钟，最长一天
+const (
+	DefaultTeamConfigRefreshMinutes = 30
+	maxTeamConfigRefreshMinutes     = 24 * 60
+)
+
+// TeamConfigSource 是团队共享 ssh_config 片段的来源，其中的主机以只读方式合并到主机列表
+type TeamConfigSource struct {
+	Location       string `json:"location"`       // http(s) URL 或本地文件路径（位于 Git 仓库中时刷新前会先 git pull），空字符串表示不使用
+	RefreshMinutes int    `json:"refreshMinutes"` // 定期刷新的间隔，0 表示只在启动和手动刷新时读取
+}
+
+// Validate 检查团队配置来源是否合法
+func (t TeamConfigSource) Validate() error {
+	if t.RefreshMinutes < 0 || t.RefreshMinutes > maxTeamConfigRefreshMinutes {
+		return fmt.Errorf("team config refresh interval must be between 0 and %d minutes", maxTeamConfigRefreshMinutes)
+	}
+	location := strings.TrimSpace(t.Location)
+	if strings.Contains(location, "://") && !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return fmt.Errorf("team config URL must use http or https")
+	}
+	return nil
+}
+
+// Defaults 返回默认设置
+func Defaults() Settings {
+	return Settings{
+		Version:                   currentVersion,
+		Theme:                     ThemeSystem,
+		Locale:                    i18n.DefaultLocale,
+		TunnelEventDebounceMs:     200,
+		TunnelDrainTimeoutSeconds: DefaultTunnelDrainTimeoutSeconds,
+		TunnelSlowLatencyMs:       DefaultTunnelSlowLatencyMs,
+		TunnelFileLogs:            false,
+		TunnelLogRetentionDays:    DefaultTunnelLogRetentionDays,
+		MigrateTunnelCredentials:  true,
+		DefaultTerminal:           "",
+		TerminalMaxPasteBytes:     DefaultTerminalMaxPasteBytes,
+		TerminalBracketedPaste:    true,
+		TerminalShellIntegration:  false,
+		TerminalIdle:              TerminalIdlePolicy{Action: IdleActionWarn},
+		KeepAliveIntervalSeconds:  0,
+		KeepAliveCountMax:         0,
+		HostKeyPolicy:             HostKeyPolicyAsk,
+		TeamConfig:                TeamConfigSource{RefreshMinutes: DefaultTeamConfigRefreshMinutes},
+		LockedHosts:               []string{},
+		Notifications:             NotificationSettings{Enabled: true, TunnelDisconnected: true, SyncError: true, HostKeyChanged: true},
+		GlobalHotkeys:             []GlobalHotkey{},
+		LocalAPIEnabled:           false,
+		LocalAPIPort:              DefaultLocalAPIPort,
+		UpdateCheckEnabled:        false,
+		UpdateCheckIntervalHours:  DefaultUpdateCheckIntervalHours,
+		LogLevel:                  "info",
+	}
+}
+
+// Validate 检查设置是否合法
+func (s Settings) Validate() error {
+	switch s.Theme {
+	case ThemeSystem, ThemeLight, ThemeDark:
+	default:
+		return fmt.Errorf("invalid theme '%s'", s.Theme)
+	}
+	if !i18n.IsSupported(s.Locale) {
+		return fmt.Errorf("unsupported locale '%s'", s.Locale)
+	}
+	if s.TunnelEventDebounceMs < 0 || s.TunnelEventDebounceMs > 5000 {
+		return fmt.Errorf("tunnel event debounce must be between 0 and 5000 ms")
+	}
+	if s.TunnelDrainTimeoutSeconds < 0 || s.TunnelDrainTimeoutSeconds > 600 {
+		return fmt.Errorf("tunnel drain timeout must be between 0 and 600 seconds")
+	}
+	if s.TunnelSlowLatencyMs < 0 || s.TunnelSlowLatencyMs > 60000 {
+		return fmt.Errorf("tunnel slow latency threshold must be between 0 and 60000 ms")
+	}
+	if s.TunnelLogRetentionDays < 1 || s.TunnelLogRetentionDays > maxTunnelLogRetentionDays {
+		return fmt.Errorf("tunnel log retention must be between 1 and %d days", maxTunnelLogRetentionDays)
+	}
+	if s.TerminalMaxPasteBytes < 0 || s.TerminalMaxPasteBytes > maxTerminalPasteBytes {
+		return fmt.Errorf("terminal max paste size must be between 0 and %d bytes", maxTerminalPasteBytes)
+	}
+	if err := s.TerminalIdle.Validate(); err != nil {
+		return err
+	}
+	if s.KeepAliveIntervalSeconds < 0 || s.KeepAliveIntervalSeconds > 3600 {
+		return fmt.Errorf("keep-alive interval must be between 0 and 3600 seconds")
+	}
+	if s.KeepAliveCountMax < 0 || s.KeepAliveCountMax > 100 {
+		return fmt.Errorf("keep-alive count max must be between 0 and 100")
+	}
+	switch s.HostKeyPolicy {
+	case HostKeyPolicyAsk, HostKeyPolicyAcceptNew, HostKeyPolicyStrict:
+	default:
+		return fmt.Errorf("invalid host key policy '%s'", s.HostKeyPolicy)
+	}
+	if err := s.NewHostDefaults.Validate(); err != nil {
+		return err
+	}
+	if err := s.TeamConfig.Validate(); err != nil {
+		return err
+	}
+	for _, alias := range s.LockedHosts {
+		if alias == "" || strings.ContainsAny(alias, " \t") {
+			return fmt.Errorf("invalid locked host alias '%s'", alias)
+		}
+	}
+	if err := validateGlobalHotkeys(s.GlobalHotkeys); err != nil {
+		return err
+	}
+	if s.LocalAPIPort < 1024 || s.LocalAPIPort > 65535 {
+		return fmt.Errorf("local API port must be between 1024 and 65535")
+	}
+	if s.LocalAPIEnabled && len(s.LocalAPIToken) < minLocalAPITokenLength {
+		return fmt.Errorf("local API token must be at least %d characters", minLocalAPITokenLength)
+	}
+	if s.UpdateCheckIntervalHours < 1 || s.UpdateCheckIntervalHours > maxUpdateCheckIntervalHours {
+		return fmt.Errorf("update check interval must be between 1 and %d hours", maxUpdateCheckIntervalHours)
+	}
+	if _, err := logging.ParseLevel(s.LogLevel); err != nil {
+		return err
+	}
+	for subsystem, level := range s.SubsystemLogLevels {
+		if _, err := logging.ParseLevel(level); err != nil {
+			return fmt.Errorf("subsystem %s: %w", subsystem, err)
+		}
+	}
+	return nil
+}
+
+// GenerateLocalAPIToken 生成一个随机的本地 API token
+func GenerateLocalAPIToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate local API token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Subscriber 在设置变化时被调用，参数为新的设置
+type Subscriber func(Settings)
+
+// Manager 负责设置的加载、校验、持久化与变更通知
+type Manager struct {
+	path        string
+	current     Settings
+	mu          sync.RWMutex
+	subscribers []Subscriber
+	subMu       sync.Mutex
+}
+
+// NewManager 创建一个设置管理器，path 为 settings.json 的路径
+func NewManager(path string) *Manager {
+	return &Manager{
+		path:    path,
+		current: Defaults(),
+	}
+}
+
+// Load 从文件加载设置。文件不存在时使用默认值；缺失的字段会保留默认值。
+func (m *Manager) Load() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Println("Settings file not found, using defaults.")
+			m.current = Defaults()
+			return nil
+		}
+		return fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	loaded := Defaults()
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	loaded.Version = currentVersion
+	if err := loaded.Validate(); err != nil {
+		logger.Printf("Warning: invalid settings in %s, falling back to defaults: %v", m.path, err)
+		loaded = Defaults()
+	}
+	m.current = loaded
+	return nil
+}
+
+// Get 返回当前设置的副本
+func (m *Manager) Get() Settings {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current
+}
+
+// Set 校验并保存新的设置，然后通知所有订阅者
+func (m *Manager) Set(s Settings) error {
+	s.Version = currentVersion
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	if err := m.save_nolock(s); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.current = s
+	m.mu.Unlock()
+
+	m.notify(s)
+	return nil
+}
+
+// Update 在当前设置的基础上应用修改函数并保存
+func (m *Manager) Update(fn func(*Settings)) (Settings, error) {
+	s := m.Get()
+	fn(&s)
+	if err := m.Set(s); err != nil {
+		return Settings{}, err
+	}
+	return s, nil
+}
+
+// Subscribe 注册一个变更订阅者，并立即以当前设置调用一次，方便订阅者完成初始化
+func (m *Manager) Subscribe(fn Subscriber) {
+	m.subMu.Lock()
+	m.subscribers = append(m.subscribers, fn)
+	m.subMu.Unlock()
+	fn(m.Get())
+}
+
+func (m *Manager) notify(s Settings) {
+	m.subMu.Lock()
+	subscribers := make([]Subscriber, len(m.subscribers))
+	copy(subscribers, m.subscribers)
+	m.subMu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(s)
+	}
+}
+
+// save_nolock 将设置写入文件，调用方需持有写锁
+func (m *Manager) save_nolock(s Settings) error {
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	if err := os.WriteFile(m.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write settings file: %w", err)
+	}
+	return nil
+}
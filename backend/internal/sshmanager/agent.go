@@ -0,0 +1,148 @@
+package sshmanager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ssh-agent 的后端，用于日志与错误信息
+const (
+	AgentBackendSocket      = "ssh-agent"    // Unix 套接字（SSH_AUTH_SOCK）
+	AgentBackendOpenSSHPipe = "openssh-pipe" // Windows OpenSSH 代理的命名管道
+	AgentBackendPageant     = "pageant"      // PuTTY Pageant（共享内存协议）
+)
+
+// DialAgent 连接本地 ssh-agent。socket 为空时自动选择：Unix 上使用 SSH_AUTH_SOCK；
+// Windows 上依次尝试 SSH_AUTH_SOCK、OpenSSH 代理的命名管道与 Pageant。
+func DialAgent(socket string) (io.ReadWriteCloser, error) {
+	conn, _, err := dialAgent(socket)
+	return conn, err
+}
+
+// agentSigners 返回 ssh-agent 中的所有密钥，没有可用的代理时返回 nil。socket 为空时自动选择代理。
+// 列出密钥后立即断开，签名时再重新连接，避免连接在整个 SSH 会话期间保持打开。
+func agentSigners(socket string) []ssh.Signer {
+	conn, backend, err := dialAgent(socket)
+	if err != nil {
+		return nil // 没有运行代理是常见情况
+	}
+	defer conn.Close()
+
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		logger.Printf("Warning: failed to list keys from %s: %v", backend, err)
+		return nil
+	}
+	signers := make([]ssh.Signer, 0, len(keys))
+	for _, key := range keys {
+		signers = append(signers, &agentSigner{key: key, socket: socket})
+	}
+	if len(signers) > 0 {
+		logger.Printf("Found %d keys in %s.", len(signers), backend)
+	}
+	return signers
+}
+
+// appendAgentSigners 在 signers 后追加主机的 IdentityAgent 中的密钥，跳过已有的公钥（例如 IdentityFile 已加入的密钥）。
+// IdentityAgent 为 none 时不追加；allow 不为 nil 时只追加它接受的密钥（IdentitiesOnly）。签名时会记录到 trace。
+func appendAgentSigners(signers []ssh.Signer, identityAgent IdentityAgentSettings, allow func(ssh.PublicKey) bool, trace *authTrace) []ssh.Signer {
+	if identityAgent.Disabled {
+		return signers
+	}
+	skipped := 0
+	for _, s := range agentSigners(identityAgent.Socket) {
+		if allow != nil && !allow(s.PublicKey()) {
+			skipped++
+			continue
+		}
+		blob := s.PublicKey().Marshal()
+		duplicate := false
+		for _, existing := range signers {
+			if bytes.Equal(existing.PublicKey().Marshal(), blob) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			signers = append(signers, &tracedSigner{Signer: s, label: "agent: " + s.(*agentSigner).key.Comment, trace: trace})
+		}
+	}
+	if skipped > 0 {
+		logger.Printf("IdentitiesOnly is set, skipped %d agent keys that match no IdentityFile.", skipped)
+	}
+	return signers
+}
+
+// identityKeyFilter 返回只接受 files 中密钥的过滤器，用于 IdentitiesOnly。
+// 与 OpenSSH 一样优先读取密钥旁的 .pub 文件，因此受密码短语保护的私钥也能匹配到代理中的密钥；
+// 没有 .pub 文件时从未加密的私钥中取得公钥。无法读取的文件被跳过。
+func identityKeyFilter(files []string) func(ssh.PublicKey) bool {
+	var blobs [][]byte
+	for _, file := range files {
+		if key := identityPublicKey(file); key != nil {
+			blobs = append(blobs, key.Marshal())
+		}
+	}
+	return func(key ssh.PublicKey) bool {
+		blob := key.Marshal()
+		return slices.ContainsFunc(blobs, func(b []byte) bool { return bytes.Equal(b, blob) })
+	}
+}
+
+// identityPublicKey 读取密钥文件对应的公钥，读取失败时返回 nil
+func identityPublicKey(file string) ssh.PublicKey {
+	if data, err := readKeyFile(file + ".pub"); err == nil {
+		if key, _, _, _, err := ssh.ParseAuthorizedKey(data); err == nil {
+			return key
+		}
+	}
+	data, err := readKeyFile(file)
+	if err != nil {
+		return nil
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil
+	}
+	return signer.PublicKey()
+}
+
+// agentSigner 是 ssh-agent 中的一个密钥，每次签名时单独连接代理
+type agentSigner struct {
+	key    *agent.Key
+	socket string // 列出该密钥的代理，为空表示自动选择
+}
+
+func (s *agentSigner) PublicKey() ssh.PublicKey {
+	return s.key
+}
+
+func (s *agentSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.SignWithAlgorithm(rand, data, "")
+}
+
+// SignWithAlgorithm 支持 RSA 密钥的 rsa-sha2-256/512 签名，现代服务器通常不再接受 ssh-rsa (SHA-1)
+func (s *agentSigner) SignWithAlgorithm(_ io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	var flags agent.SignatureFlags
+	switch algorithm {
+	case ssh.KeyAlgoRSASHA256:
+		flags = agent.SignatureFlagRsaSha256
+	case ssh.KeyAlgoRSASHA512:
+		flags = agent.SignatureFlagRsaSha512
+	}
+	conn, backend, err := dialAgent(s.socket)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to ssh-agent: %w", err)
+	}
+	defer conn.Close()
+	sig, err := agent.NewClient(conn).SignWithFlags(s.key, data, flags)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to sign with %s: %w", backend, s.key.Comment, err)
+	}
+	return sig, nil
+}
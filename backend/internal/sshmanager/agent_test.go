@@ -0,0 +1,91 @@
+package sshmanager
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// TestIdentitiesOnlyAgentKeys 测试 IdentitiesOnly 时只使用代理中与 IdentityFile 对应的密钥：
+// 受密码短语保护的密钥通过 .pub 文件匹配，未加密的密钥直接从私钥取得公钥
+func TestIdentitiesOnlyAgentKeys(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test agent listens on a Unix socket")
+	}
+	dir := t.TempDir()
+	keyring := agent.NewKeyring()
+	var keys []ssh.PublicKey
+	for i := 0; i < 3; i++ {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := keyring.Add(agent.AddedKey{PrivateKey: priv, Comment: fmt.Sprintf("key%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+		signer, err := ssh.NewSignerFromKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, signer.PublicKey())
+
+		switch i {
+		case 0: // 只有 .pub 文件可读（私钥受密码短语保护）
+			if err := os.WriteFile(filepath.Join(dir, "key0.pub"), ssh.MarshalAuthorizedKey(signer.PublicKey()), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		case 1: // 未加密的私钥，没有 .pub 文件
+			block, err := ssh.MarshalPrivateKey(priv, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "key1"), pem.EncodeToMemory(block), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("cannot listen on a Unix socket: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+
+	identityAgent := IdentityAgentSettings{Socket: socket}
+	if signers := appendAgentSigners(nil, identityAgent, nil, nil); len(signers) != 3 {
+		t.Fatalf("without IdentitiesOnly all agent keys should be offered, got %d", len(signers))
+	}
+
+	allow := identityKeyFilter([]string{filepath.Join(dir, "key0"), filepath.Join(dir, "key1"), filepath.Join(dir, "missing")})
+	signers := appendAgentSigners(nil, identityAgent, allow, nil)
+	if len(signers) != 2 {
+		t.Fatalf("only the agent keys of the IdentityFile entries should be offered, got %d", len(signers))
+	}
+	for i, s := range signers {
+		if string(s.PublicKey().Marshal()) != string(keys[i].Marshal()) {
+			t.Errorf("signer %d is not the key of its IdentityFile", i)
+		}
+	}
+}
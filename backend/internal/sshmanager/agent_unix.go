@@ -0,0 +1,25 @@
+//go:build !windows
+
+package sshmanager
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+// dialAgent 连接 Unix 套接字上的 ssh-agent，socket 为空时使用 SSH_AUTH_SOCK
+func dialAgent(socket string) (io.ReadWriteCloser, string, error) {
+	if socket == "" {
+		socket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socket == "" {
+		return nil, "", fmt.Errorf("SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, "", err
+	}
+	return conn, AgentBackendSocket, nil
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package sshmanager
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// openSSHAgentPipe 是 Windows 自带 OpenSSH 代理（ssh-agent 服务）的命名管道
+const openSSHAgentPipe = `\\.\pipe\openssh-ssh-agent`
+
+// dialAgent 连接 Windows 上的 ssh-agent。socket 为空时依次尝试 SSH_AUTH_SOCK 指定的命名管道、
+// OpenSSH 代理的命名管道，最后是 Pageant（PuTTY 用户最常见的配置）。
+func dialAgent(socket string) (io.ReadWriteCloser, string, error) {
+	if socket == "" {
+		socket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socket != "" {
+		conn, err := os.OpenFile(socket, os.O_RDWR, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		return conn, AgentBackendOpenSSHPipe, nil
+	}
+
+	conn, pipeErr := os.OpenFile(openSSHAgentPipe, os.O_RDWR, 0)
+	if pipeErr == nil {
+		return conn, AgentBackendOpenSSHPipe, nil
+	}
+	if pageantAvailable() {
+		return &pageantConn{}, AgentBackendPageant, nil
+	}
+	return nil, "", fmt.Errorf("no ssh-agent found: the OpenSSH Authentication Agent service is not running (%v) and Pageant is not open", pipeErr)
+}
@@ -0,0 +1,56 @@
+package sshmanager
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"devtools/backend/pkg/sshconfig"
+)
+
+// 分析主机时解析域名的超时与并发数
+const (
+	analysisResolveTimeout = 3 * time.Second
+	analysisResolveWorkers = 8
+)
+
+type resolveResult struct {
+	ips []string
+	err error
+}
+
+// resolveHostNames 并发解析 targets 中直连主机的域名，返回域名到解析结果的映射
+func resolveHostNames(targets []sshconfig.HostTarget) map[string]resolveResult {
+	var names []string
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		if t.Kind == sshconfig.HostNameDNS && t.ProxyJump == "" && !seen[t.HostName] {
+			seen[t.HostName] = true
+			names = append(names, t.HostName)
+		}
+	}
+
+	results := make(map[string]resolveResult, len(names))
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	sem := make(chan struct{}, analysisResolveWorkers)
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ctx, cancel := context.WithTimeout(context.Background(), analysisResolveTimeout)
+			defer cancel()
+			ips, err := net.DefaultResolver.LookupHost(ctx, name)
+			mu.Lock()
+			results[name] = resolveResult{ips: ips, err: err}
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return results
+}
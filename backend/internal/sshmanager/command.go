@@ -0,0 +1,51 @@
+package sshmanager
+
+// GetCommandConfig 返回在 OpenSSH 命令行中连接 alias 所需的参数：HostName、Port、User、IdentityFile，
+// 以及 ProxyJump 的各跳或 ProxyCommand。与 GetConnectionConfig 不同，它不构建认证方式，
+// 不需要密码，返回值的 ClientConfig 为 nil。
+// ProxyJump 中属于 ssh_config 的别名会展开为其 HostName、User 与 Port，
+// 使命令在没有这份 ssh_config 的机器（例如服务器）上也能使用。
+func (m *Manager) GetCommandConfig(alias string) (*ConnectionConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	host, err := m.GetSSHHostByAlias(alias)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &ConnectionConfig{
+		Name:         alias,
+		HostName:     host.HostName,
+		Port:         host.Port,
+		User:         host.User,
+		IdentityFile: host.IdentityFile,
+	}
+	if cfg.HostName == "" {
+		cfg.HostName = alias
+	}
+
+	value := m.proxyJumpFor(alias, host)
+	if value == "" {
+		cfg.ProxyCommand = m.proxyCommandFor(alias, host)
+		return cfg, nil
+	}
+	for _, spec := range parseProxyJump(value) {
+		hop := &ConnectionConfig{Name: spec.host, HostName: spec.host, Port: "22"}
+		if m.manager.HasHost(spec.host) || m.IsTeamHost(spec.host) {
+			if h, err := m.GetSSHHostByAlias(spec.host); err == nil {
+				hop.User, hop.Port, hop.IdentityFile = h.User, h.Port, h.IdentityFile
+				if h.HostName != "" {
+					hop.HostName = h.HostName
+				}
+			}
+		}
+		if spec.user != "" {
+			hop.User = spec.user
+		}
+		if spec.port != "" {
+			hop.Port = spec.port
+		}
+		cfg.JumpHosts = append(cfg.JumpHosts, hop)
+	}
+	return cfg, nil
+}
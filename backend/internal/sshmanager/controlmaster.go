@@ -0,0 +1,82 @@
+package sshmanager
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// controlSocketDialTimeout 是检测 ControlMaster 套接字是否可用时的连接超时
+const controlSocketDialTimeout = time.Second
+
+// ControlMasterStatus 描述一个主机的 OpenSSH 连接复用（ControlMaster）状态
+type ControlMasterStatus struct {
+	Alias          string `json:"alias"`
+	ControlMaster  string `json:"controlMaster,omitempty"`  // 生效配置中的 ControlMaster
+	ControlPath    string `json:"controlPath,omitempty"`    // 展开 token 后的套接字路径，未配置时为空
+	ControlPersist string `json:"controlPersist,omitempty"` // 生效配置中的 ControlPersist
+	Active         bool   `json:"active"`                   // 套接字存在且有主连接在监听，可以立即复用
+}
+
+// ControlMasterStatus 根据 alias 生效配置中的 ControlPath 检测是否已有可复用的主连接。
+// 只有 ssh 客户端建立的主连接才会创建套接字；本应用内置的 SSH 连接不参与复用。
+func (m *Manager) ControlMasterStatus(alias string) ControlMasterStatus {
+	m.mu.RLock()
+	cfg := m.manager.ResolveHost(alias)
+	status := ControlMasterStatus{
+		Alias:          alias,
+		ControlMaster:  cfg.Get("ControlMaster"),
+		ControlPersist: cfg.Get("ControlPersist"),
+	}
+	controlPath := cfg.Get("ControlPath")
+	if controlPath != "" && !strings.EqualFold(controlPath, "none") {
+		status.ControlPath = expandUserPath(m.manager.ExpandTokens(alias, controlPath))
+	}
+	m.mu.RUnlock()
+
+	status.Active = controlSocketActive(status.ControlPath)
+	return status
+}
+
+// controlSocketActive 检查套接字文件存在并且可以连接（主连接退出后可能留下失效的套接字文件）
+func controlSocketActive(path string) bool {
+	if path == "" || runtime.GOOS == "windows" {
+		return false // Windows 版 OpenSSH 不支持 ControlMaster
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return false
+	}
+	conn, err := net.DialTimeout("unix", path, controlSocketDialTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// expandUserPath 展开路径开头的 ~
+func expandUserPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// ConnectInTerminalViaControlMaster 在外部终端中通过已有的主连接（ssh -S）打开会话，无需重新认证
+func (m *Manager) ConnectInTerminalViaControlMaster(alias string) error {
+	status := m.ControlMasterStatus(alias)
+	if !status.Active {
+		return fmt.Errorf("no active ControlMaster connection for '%s'", alias)
+	}
+	// 路径中可能有空格（例如 macOS 的 ~/Library/Application Support），由终端中的 shell 解析，需要加引号
+	sshCmd := fmt.Sprintf("ssh -S %s %s", shellQuote(status.ControlPath), shellQuote(alias))
+	logger.Printf("Debug: SSH command to be executed: %s", sshCmd)
+	return sshExec(sshCmd, m.getExternalTerminal())
+}
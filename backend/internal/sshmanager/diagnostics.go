@@ -0,0 +1,422 @@
+package sshmanager
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// maxKeepAliveSamples 是每个连接保留的最近保活结果数量（默认 15 秒一次，约 15 分钟）
+	maxKeepAliveSamples = 60
+	// maxClosedConnections 是保留统计的已断开连接数量，用于排查"隧道总是断开"一类的问题
+	maxClosedConnections = 20
+)
+
+// 丢包模式
+const (
+	LossPatternNone      = "none"      // 没有未应答的保活
+	LossPatternScattered = "scattered" // 零散的单次丢失，常见于信号较差的 Wi-Fi 或拥塞的链路
+	LossPatternBursts    = "bursts"    // 连续多次丢失，表示网络中断了一段时间（漫游、休眠、VPN 重连或服务器卡住）
+)
+
+// 丢包的可能原因，根据同一时间其他主机的连接是否也没有应答推断
+const (
+	LossCauseNone         = "none"          // 没有丢失
+	LossCauseLocalNetwork = "local_network" // 其他主机的连接同时也没有应答，问题多半在本机网络（Wi-Fi、VPN）
+	LossCauseRemote       = "remote"        // 只有这个主机没有应答，问题多半在服务器或到它的线路上
+	LossCauseUnknown      = "unknown"       // 当时没有其他连接可以比较
+)
+
+// KeepAliveSample 是一次保活请求的结果
+type KeepAliveSample struct {
+	Time   string  `json:"time"`            // RFC 3339
+	RTTMs  float64 `json:"rttMs,omitempty"` // 得到应答时的往返时间
+	Missed bool    `json:"missed,omitempty"`
+}
+
+// ConnectionStats 是一个连接的保活与流量统计
+type ConnectionStats struct {
+	RemoteAddr      string            `json:"remoteAddr"`
+	ConnectedAt     string            `json:"connectedAt"`        // RFC 3339
+	ClosedAt        string            `json:"closedAt,omitempty"` // 已断开的连接才有
+	KeepAlivesSent  int               `json:"keepAlivesSent"`
+	KeepAlivesLost  int               `json:"keepAlivesLost"` // 超时没有应答的保活
+	MaxLostInARow   int               `json:"maxLostInARow"`  // 最长的连续丢失次数
+	PausedOffline   int               `json:"pausedOffline"`  // 本机离线而跳过的保活
+	AvgRTTMs        float64           `json:"avgRttMs"`       // 以下均为得到应答的保活
+	MinRTTMs        float64           `json:"minRttMs"`
+	MaxRTTMs        float64           `json:"maxRttMs"`
+	JitterMs        float64           `json:"jitterMs"`  // 相邻两次往返时间之差的平均值
+	BytesSent       int64             `json:"bytesSent"` // 传输层（加密后）的字节数
+	BytesReceived   int64             `json:"bytesReceived"`
+	EstimatedRekeys int64             `json:"estimatedRekeys"` // 按流量推算的客户端发起的重新协商次数，服务器发起的无法观察到
+	Recent          []KeepAliveSample `json:"recent"`          // 最近的保活结果，按时间排列
+}
+
+// ConnectionDiagnostics 汇总一个主机的活动连接与最近断开的连接，帮助判断断线是本机网络还是服务器的问题
+type ConnectionDiagnostics struct {
+	Alias          string            `json:"alias"`
+	Connections    []ConnectionStats `json:"connections"` // 活动连接在前，随后是最近断开的连接
+	KeepAlivesSent int               `json:"keepAlivesSent"`
+	KeepAlivesLost int               `json:"keepAlivesLost"`
+	LossPercent    float64           `json:"lossPercent"`
+	Pattern        string            `json:"pattern"`      // none | scattered | bursts
+	LikelyCause    string            `json:"likelyCause"`  // none | local_network | remote | unknown
+	SharedLosses   int               `json:"sharedLosses"` // 同一时间其他主机的连接也没有应答的丢失次数
+}
+
+// connectionStats 记录一个连接的统计，由保活循环与计数连接更新
+type connectionStats struct {
+	conn        *countingConn
+	connectedAt time.Time
+	remoteAddr  string
+	rekeyBytes  int64 // 客户端发起重新协商的流量阈值
+
+	mu        sync.Mutex
+	alias     string
+	interval  time.Duration
+	closedAt  time.Time
+	sent      int
+	lost      int
+	lostInRow int
+	maxInRow  int
+	paused    int
+	rttCount  int
+	rttSum    time.Duration
+	rttMin    time.Duration
+	rttMax    time.Duration
+	lastRTT   time.Duration
+	jitterSum time.Duration
+	samples   []keepAliveSample
+}
+
+type keepAliveSample struct {
+	at     time.Time
+	rtt    time.Duration
+	missed bool
+}
+
+var (
+	// connections 保存活动连接的统计
+	connections sync.Map // *ssh.Client → *connectionStats
+	// closedConnections 保存最近断开的连接的统计，最旧的在前
+	closedConnections   []*connectionStats
+	closedConnectionsMu sync.Mutex
+)
+
+// countingConn 统计传输层读写的字节数
+type countingConn struct {
+	net.Conn
+	read, written atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+// trackConnection 开始记录新连接的统计，连接关闭后移入最近断开的列表
+func trackConnection(client *ssh.Client, conn *countingConn, clientConfig *ssh.ClientConfig) {
+	stats := &connectionStats{
+		conn:        conn,
+		connectedAt: time.Now(),
+		remoteAddr:  client.RemoteAddr().String(),
+	}
+	info, _ := HandshakeInfoFor(client)
+	stats.rekeyBytes = rekeyThreshold(clientConfig, info.Cipher)
+	connections.Store(client, stats)
+	go func() {
+		_ = client.Wait()
+		connections.Delete(client)
+		stats.mu.Lock()
+		stats.closedAt = time.Now()
+		stats.mu.Unlock()
+
+		closedConnectionsMu.Lock()
+		defer closedConnectionsMu.Unlock()
+		closedConnections = append(closedConnections, stats)
+		if len(closedConnections) > maxClosedConnections {
+			closedConnections = closedConnections[len(closedConnections)-maxClosedConnections:]
+		}
+	}()
+}
+
+// rekeyThreshold 返回 x/crypto/ssh 在一个方向上传输多少字节后发起重新协商
+func rekeyThreshold(clientConfig *ssh.ClientConfig, cipher string) int64 {
+	if clientConfig != nil && clientConfig.RekeyThreshold > 0 {
+		return int64(min(clientConfig.RekeyThreshold, 1<<62))
+	}
+	// AES 按 RFC 4344 在 2^32 个分组后重新协商，其他算法按 RFC 4253 的建议在 1 GiB 后
+	if strings.HasPrefix(cipher, "aes") {
+		return 16 << 32
+	}
+	return 1 << 30
+}
+
+// setConnectionAlias 记录连接所属的主机别名
+func setConnectionAlias(client *ssh.Client, alias string) {
+	if stats := statsFor(client); stats != nil {
+		stats.mu.Lock()
+		stats.alias = alias
+		stats.mu.Unlock()
+	}
+}
+
+// statsFor 返回连接的统计，不是通过本包建立的连接返回 nil
+func statsFor(client *ssh.Client) *connectionStats {
+	if stats, ok := connections.Load(client); ok {
+		return stats.(*connectionStats)
+	}
+	return nil
+}
+
+func (s *connectionStats) setInterval(interval time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interval = interval
+}
+
+// recordRTT 记录一次得到应答的保活
+func (s *connectionStats) recordRTT(rtt time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent++
+	s.lostInRow = 0
+	if s.rttCount > 0 {
+		s.jitterSum += (rtt - s.lastRTT).Abs()
+	}
+	if s.rttCount == 0 || rtt < s.rttMin {
+		s.rttMin = rtt
+	}
+	s.rttMax = max(s.rttMax, rtt)
+	s.rttCount++
+	s.rttSum += rtt
+	s.lastRTT = rtt
+	s.addSample(keepAliveSample{at: time.Now(), rtt: rtt})
+}
+
+// recordMiss 记录一次超时没有应答的保活
+func (s *connectionStats) recordMiss() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent++
+	s.lost++
+	s.lostInRow++
+	s.maxInRow = max(s.maxInRow, s.lostInRow)
+	s.addSample(keepAliveSample{at: time.Now(), missed: true})
+}
+
+// recordPaused 记录一次因本机离线而跳过的保活
+func (s *connectionStats) recordPaused() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused++
+	s.lostInRow = 0
+}
+
+// addSample 追加一个保活结果，调用方需持有 s.mu
+func (s *connectionStats) addSample(sample keepAliveSample) {
+	s.samples = append(s.samples, sample)
+	if len(s.samples) > maxKeepAliveSamples {
+		s.samples = s.samples[len(s.samples)-maxKeepAliveSamples:]
+	}
+}
+
+// snapshot 返回统计的副本
+func (s *connectionStats) snapshot() ConnectionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := ConnectionStats{
+		RemoteAddr:     s.remoteAddr,
+		ConnectedAt:    s.connectedAt.Format(time.RFC3339),
+		KeepAlivesSent: s.sent,
+		KeepAlivesLost: s.lost,
+		MaxLostInARow:  s.maxInRow,
+		PausedOffline:  s.paused,
+		BytesSent:      s.conn.written.Load(),
+		BytesReceived:  s.conn.read.Load(),
+		Recent:         make([]KeepAliveSample, 0, len(s.samples)),
+	}
+	if !s.closedAt.IsZero() {
+		result.ClosedAt = s.closedAt.Format(time.RFC3339)
+	}
+	if s.rttCount > 0 {
+		result.AvgRTTMs = millis(s.rttSum / time.Duration(s.rttCount))
+		result.MinRTTMs = millis(s.rttMin)
+		result.MaxRTTMs = millis(s.rttMax)
+	}
+	if s.rttCount > 1 {
+		result.JitterMs = millis(s.jitterSum / time.Duration(s.rttCount-1))
+	}
+	if s.rekeyBytes > 0 {
+		result.EstimatedRekeys = max(result.BytesSent, result.BytesReceived) / s.rekeyBytes
+	}
+	for _, sample := range s.samples {
+		result.Recent = append(result.Recent, KeepAliveSample{
+			Time:   sample.at.Format(time.RFC3339),
+			RTTMs:  millis(sample.rtt),
+			Missed: sample.missed,
+		})
+	}
+	return result
+}
+
+// lossTimes 返回最近的丢失时间与保活间隔
+func (s *connectionStats) lossTimes() ([]time.Time, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var times []time.Time
+	for _, sample := range s.samples {
+		if sample.missed {
+			times = append(times, sample.at)
+		}
+	}
+	return times, s.interval
+}
+
+// window 返回连接的统计覆盖的时间段
+func (s *connectionStats) window() (from, to time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	to = s.closedAt
+	if to.IsZero() {
+		to = time.Now()
+	}
+	return s.connectedAt, to
+}
+
+func (s *connectionStats) getAlias() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.alias
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// GetConnectionDiagnostics 返回主机的活动连接与最近断开的连接的保活统计，并根据丢失的分布
+// 以及同一时间其他主机的连接是否也没有应答，推断丢包的模式与可能的原因
+func GetConnectionDiagnostics(alias string) ConnectionDiagnostics {
+	var own, others []*connectionStats
+	connections.Range(func(_, value any) bool {
+		stats := value.(*connectionStats)
+		if stats.getAlias() == alias {
+			own = append(own, stats)
+		} else {
+			others = append(others, stats)
+		}
+		return true
+	})
+	closedConnectionsMu.Lock()
+	for i := len(closedConnections) - 1; i >= 0; i-- {
+		if stats := closedConnections[i]; stats.getAlias() == alias {
+			own = append(own, stats)
+		} else {
+			others = append(others, stats)
+		}
+	}
+	closedConnectionsMu.Unlock()
+
+	diag := ConnectionDiagnostics{Alias: alias, Connections: []ConnectionStats{}, Pattern: LossPatternNone, LikelyCause: LossCauseNone}
+	inBursts := 0
+	compared := 0
+	for _, stats := range own {
+		snapshot := stats.snapshot()
+		diag.Connections = append(diag.Connections, snapshot)
+		diag.KeepAlivesSent += snapshot.KeepAlivesSent
+		diag.KeepAlivesLost += snapshot.KeepAlivesLost
+		inBursts += lossesInBursts(snapshot.Recent)
+
+		times, interval := stats.lossTimes()
+		for _, at := range times {
+			shared, comparable := lostElsewhere(at, interval, others)
+			if shared {
+				diag.SharedLosses++
+			}
+			if comparable {
+				compared++
+			}
+		}
+	}
+	if diag.KeepAlivesLost == 0 {
+		return diag
+	}
+	diag.LossPercent = float64(diag.KeepAlivesLost) * 100 / float64(diag.KeepAlivesSent)
+
+	diag.Pattern = LossPatternScattered
+	if inBursts*2 >= diag.KeepAlivesLost {
+		diag.Pattern = LossPatternBursts
+	}
+	switch {
+	case compared == 0:
+		diag.LikelyCause = LossCauseUnknown
+	case diag.SharedLosses*2 >= compared:
+		diag.LikelyCause = LossCauseLocalNetwork
+	default:
+		diag.LikelyCause = LossCauseRemote
+	}
+	return diag
+}
+
+// lossesInBursts 返回属于连续丢失（两次及以上）的丢失次数
+func lossesInBursts(samples []KeepAliveSample) int {
+	count, run := 0, 0
+	for i := 0; i <= len(samples); i++ {
+		if i < len(samples) && samples[i].Missed {
+			run++
+			continue
+		}
+		if run >= 2 {
+			count += run
+		}
+		run = 0
+	}
+	return count
+}
+
+// lostElsewhere 判断在 at 前后一个保活间隔内，其他连接是否也有丢失。
+// comparable 表示当时至少有一个其他连接在运行，可以用来比较。
+func lostElsewhere(at time.Time, interval time.Duration, others []*connectionStats) (shared, comparable bool) {
+	if interval <= 0 {
+		interval = SSHKeepAliveInterval
+	}
+	for _, other := range others {
+		from, to := other.window()
+		if at.Before(from) || at.After(to) {
+			continue
+		}
+		comparable = true
+		times, _ := other.lossTimes()
+		for _, t := range times {
+			if t.Sub(at).Abs() <= interval {
+				return true, true
+			}
+		}
+	}
+	return false, comparable
+}
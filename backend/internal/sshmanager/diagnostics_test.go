@@ -0,0 +1,63 @@
+package sshmanager
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestStats 注册一个不对应真实连接的统计，测试结束时删除
+func newTestStats(t *testing.T, alias string) *connectionStats {
+	t.Helper()
+	client := &ssh.Client{}
+	stats := &connectionStats{
+		conn:        &countingConn{},
+		connectedAt: time.Now().Add(-time.Hour),
+		alias:       alias,
+		interval:    time.Minute,
+		rekeyBytes:  1 << 30,
+	}
+	connections.Store(client, stats)
+	t.Cleanup(func() { connections.Delete(client) })
+	return stats
+}
+
+// TestGetConnectionDiagnostics 测试往返时间统计、丢包模式，以及根据其他主机是否同时丢失推断原因
+func TestGetConnectionDiagnostics(t *testing.T) {
+	web := newTestStats(t, "diag-web")
+	web.recordRTT(20 * time.Millisecond)
+	web.recordRTT(40 * time.Millisecond)
+	web.recordMiss()
+	web.recordRTT(30 * time.Millisecond)
+	web.conn.written.Store(3 << 30)
+
+	diag := GetConnectionDiagnostics("diag-web")
+	if len(diag.Connections) != 1 || diag.KeepAlivesSent != 4 || diag.KeepAlivesLost != 1 || diag.LossPercent != 25 {
+		t.Fatalf("unexpected totals: %+v", diag)
+	}
+	stats := diag.Connections[0]
+	if stats.AvgRTTMs != 30 || stats.MinRTTMs != 20 || stats.MaxRTTMs != 40 || stats.JitterMs != 15 {
+		t.Errorf("unexpected round trips: %+v", stats)
+	}
+	if stats.EstimatedRekeys != 3 || len(stats.Recent) != 4 || !stats.Recent[2].Missed {
+		t.Errorf("unexpected rekeys or samples: %+v", stats)
+	}
+	if diag.Pattern != LossPatternScattered || diag.LikelyCause != LossCauseUnknown {
+		t.Errorf("a single loss without other connections should be scattered/unknown, got %s/%s", diag.Pattern, diag.LikelyCause)
+	}
+
+	// 另一个主机的连接同时也在丢失：多半是本机网络的问题
+	db := newTestStats(t, "diag-db")
+	db.recordMiss()
+	web.recordMiss()
+	web.recordMiss()
+	diag = GetConnectionDiagnostics("diag-web")
+	if diag.Pattern != LossPatternBursts || diag.LikelyCause != LossCauseLocalNetwork || diag.SharedLosses != 3 {
+		t.Errorf("losses shared with another host should point at the local network, got %s/%s (%d shared)", diag.Pattern, diag.LikelyCause, diag.SharedLosses)
+	}
+
+	if diag := GetConnectionDiagnostics("diag-none"); len(diag.Connections) != 0 || diag.Pattern != LossPatternNone || diag.LikelyCause != LossCauseNone {
+		t.Errorf("a host without connections should have empty diagnostics: %+v", diag)
+	}
+}
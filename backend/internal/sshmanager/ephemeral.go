@@ -0,0 +1,71 @@
+package sshmanager
+
+import (
+	"fmt"
+	"sort"
+
+	"devtools/backend/internal/types"
+)
+
+// AddEphemeralHost 注册一个只存在于本次运行中的临时主机。临时主机不会写入 ssh_config，
+// 但和配置文件中的主机一样可以通过别名打开终端、建立隧道。
+func (m *Manager) AddEphemeralHost(host types.SSHHost) error {
+	if host.Alias == "" {
+		return fmt.Errorf("alias is required")
+	}
+	if m.HasHostExact(host.Alias) {
+		return fmt.Errorf("host with alias '%s' already exists", host.Alias)
+	}
+
+	m.ephemeralMu.Lock()
+	defer m.ephemeralMu.Unlock()
+	if _, ok := m.ephemeral[host.Alias]; ok {
+		return fmt.Errorf("temporary host with alias '%s' already exists", host.Alias)
+	}
+	if m.ephemeral == nil {
+		m.ephemeral = make(map[string]types.SSHHost)
+	}
+	host.Ephemeral = true
+	m.ephemeral[host.Alias] = host
+	return nil
+}
+
+// RemoveEphemeralHost 删除一个临时主机，返回它是否存在
+func (m *Manager) RemoveEphemeralHost(alias string) bool {
+	m.ephemeralMu.Lock()
+	defer m.ephemeralMu.Unlock()
+	if _, ok := m.ephemeral[alias]; !ok {
+		return false
+	}
+	delete(m.ephemeral, alias)
+	return true
+}
+
+// IsEphemeralHost 判断 alias 是否是一个临时主机
+func (m *Manager) IsEphemeralHost(alias string) bool {
+	_, ok := m.ephemeralHost(alias)
+	return ok
+}
+
+// GetEphemeralHosts 返回所有临时主机，按别名排序
+func (m *Manager) GetEphemeralHosts() []types.SSHHost {
+	m.ephemeralMu.RLock()
+	defer m.ephemeralMu.RUnlock()
+	hosts := make([]types.SSHHost, 0, len(m.ephemeral))
+	for _, host := range m.ephemeral {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Alias < hosts[j].Alias })
+	return hosts
+}
+
+// ephemeralHost 返回 alias 对应的临时主机副本
+func (m *Manager) ephemeralHost(alias string) (*types.SSHHost, bool) {
+	m.ephemeralMu.RLock()
+	defer m.ephemeralMu.RUnlock()
+	host, ok := m.ephemeral[alias]
+	if !ok {
+		return nil, false
+	}
+	return &host, true
+}
@@ -0,0 +1,209 @@
+package sshmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/sshconfig"
+
+	"golang.org/x/crypto/ssh"
+	xknownhosts "golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrorKind 是连接错误的分类。它以字符串形式通过 Wails 传给前端，前端据此决定提示方式（例如弹出密码框），
+// 不再需要匹配错误信息文本
+type ErrorKind string
+
+const (
+	KindPasswordRequired   ErrorKind = "password_required"   // 没有可用的认证方式，需要用户输入密码
+	KindPassphraseRequired ErrorKind = "passphrase_required" // 私钥有密码保护，且没有其他可用的认证方式
+	KindAuthFailed         ErrorKind = "auth_failed"         // 服务器拒绝了所有认证方式
+	KindTimeout            ErrorKind = "timeout"
+	KindDNSFailed          ErrorKind = "dns_failed"
+	KindConnectionRefused  ErrorKind = "connection_refused"
+	KindHostUnreachable    ErrorKind = "host_unreachable"    // 没有到主机的路由
+	KindNetworkUnreachable ErrorKind = "network_unreachable" // 本机网络不可用
+	KindOffline            ErrorKind = "offline"             // 本机没有可用的网卡，重试已暂停
+	KindPortInUse          ErrorKind = "port_in_use"         // 本地监听端口已被占用
+	KindHostKeyUnknown     ErrorKind = "host_key_unknown"    // known_hosts 中没有该主机
+	KindHostKeyMismatch    ErrorKind = "host_key_mismatch"   // 主机密钥与 known_hosts 中的不一致
+	KindHostNotFound       ErrorKind = "host_not_found"      // ssh_config 中没有该别名
+	KindUnknown            ErrorKind = "unknown"
+)
+
+// 每种分类对应的哨兵错误，可以用 errors.Is(err, ErrAuthFailed) 判断 *ConnectError 的分类
+var (
+	ErrPasswordRequired   = errors.New("password required")
+	ErrPassphraseRequired = errors.New("private key passphrase required")
+	ErrAuthFailed         = errors.New("authentication failed")
+	ErrTimeout            = errors.New("connection timed out")
+	ErrDNSFailed          = errors.New("hostname could not be resolved")
+	ErrConnectionRefused  = errors.New("connection refused")
+	ErrHostUnreachable    = errors.New("no route to host")
+	ErrNetworkUnreachable = errors.New("network is unreachable")
+	ErrOffline            = errors.New("network is offline")
+	ErrPortInUse          = errors.New("local port already in use")
+	ErrHostKeyUnknown     = errors.New("host key is not known")
+	ErrHostKeyMismatch    = errors.New("host key mismatch")
+	ErrHostNotFound       = errors.New("host not found")
+)
+
+var sentinelErrors = map[ErrorKind]error{
+	KindPasswordRequired:   ErrPasswordRequired,
+	KindPassphraseRequired: ErrPassphraseRequired,
+	KindAuthFailed:         ErrAuthFailed,
+	KindTimeout:            ErrTimeout,
+	KindDNSFailed:          ErrDNSFailed,
+	KindConnectionRefused:  ErrConnectionRefused,
+	KindHostUnreachable:    ErrHostUnreachable,
+	KindNetworkUnreachable: ErrNetworkUnreachable,
+	KindOffline:            ErrOffline,
+	KindPortInUse:          ErrPortInUse,
+	KindHostKeyUnknown:     ErrHostKeyUnknown,
+	KindHostKeyMismatch:    ErrHostKeyMismatch,
+	KindHostNotFound:       ErrHostNotFound,
+}
+
+// ConnectError 是一个已分类的连接错误。Error() 返回本地化的提示，Unwrap() 返回原始错误。
+type ConnectError struct {
+	Kind   ErrorKind
+	Alias  string
+	Detail string // 附加信息，例如无法解析的主机名、需要密码的私钥文件
+	Err    error
+}
+
+func (e *ConnectError) Error() string {
+	switch e.Kind {
+	case KindPasswordRequired:
+		return i18n.T("ssh.password_required", e.Alias)
+	case KindPassphraseRequired:
+		return i18n.T("ssh.passphrase_required", e.Detail, e.Alias)
+	case KindAuthFailed:
+		return i18n.T("ssh.auth_failed", e.Alias)
+	case KindTimeout:
+		return i18n.T("ssh.timeout", e.Alias)
+	case KindDNSFailed:
+		return i18n.T("ssh.dns_failed", e.Alias, e.Detail)
+	case KindConnectionRefused:
+		return i18n.T("ssh.connection_refused", e.Alias)
+	case KindHostUnreachable:
+		return i18n.T("ssh.no_route", e.Alias)
+	case KindNetworkUnreachable:
+		return i18n.T("ssh.network_unreachable", e.Alias)
+	case KindOffline:
+		return i18n.T("ssh.offline", e.Alias)
+	case KindPortInUse:
+		return i18n.T("ssh.local_port_in_use")
+	case KindHostKeyUnknown:
+		return i18n.T("ssh.host_key_unknown", e.Alias)
+	case KindHostKeyMismatch:
+		return i18n.T("ssh.host_key_mismatch", e.Alias)
+	case KindHostNotFound:
+		return i18n.T("ssh.host_not_found")
+	}
+	return i18n.T("ssh.unexpected_error", e.Alias, e.Err)
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Is 让 errors.Is 可以用哨兵错误判断分类
+func (e *ConnectError) Is(target error) bool {
+	return target != nil && sentinelErrors[e.Kind] == target
+}
+
+// MarshalJSON 让错误在事件或结构体中以 {kind, alias, message} 的形式传给前端。
+// Wails 把绑定方法返回的 error 转成 Error() 字符串，需要分类的接口通过 ConnectionResult.ErrorKind 返回。
+func (e *ConnectError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind    ErrorKind `json:"kind"`
+		Alias   string    `json:"alias,omitempty"`
+		Message string    `json:"message"`
+	}{e.Kind, e.Alias, e.Error()})
+}
+
+// NewConnectError 对 err 分类并包装为 *ConnectError。err 已经是 *ConnectError 时直接返回（补上缺少的别名）。
+func NewConnectError(err error, alias string) *ConnectError {
+	if err == nil {
+		return nil
+	}
+	var ce *ConnectError
+	if errors.As(err, &ce) {
+		if ce.Alias == "" {
+			copied := *ce
+			copied.Alias = alias
+			return &copied
+		}
+		return ce
+	}
+	kind, detail := classify(err)
+	return &ConnectError{Kind: kind, Alias: alias, Detail: detail, Err: err}
+}
+
+// Classify 返回 err 的分类
+func Classify(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	var ce *ConnectError
+	if errors.As(err, &ce) {
+		return ce.Kind
+	}
+	kind, _ := classify(err)
+	return kind
+}
+
+// classify 是错误分类的唯一实现：优先判断结构化的错误类型，最后才匹配 x/crypto/ssh 没有导出类型的错误信息
+func classify(err error) (ErrorKind, string) {
+	var passwordRequired *types.PasswordRequiredError
+	var authFailed *types.AuthenticationFailedError
+	var hostNotFound *sshconfig.HostNotFoundError
+	var passphraseMissing *ssh.PassphraseMissingError
+	var keyErr *xknownhosts.KeyError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, ErrOffline):
+		return KindOffline, ""
+	case errors.As(err, &passwordRequired):
+		return KindPasswordRequired, ""
+	case errors.As(err, &passphraseMissing):
+		return KindPassphraseRequired, ""
+	case errors.As(err, &authFailed):
+		return KindAuthFailed, ""
+	case errors.As(err, &hostNotFound):
+		return KindHostNotFound, ""
+	case errors.As(err, &keyErr):
+		if len(keyErr.Want) > 0 {
+			return KindHostKeyMismatch, ""
+		}
+		return KindHostKeyUnknown, ""
+	case errors.As(err, &dnsErr):
+		return KindDNSFailed, dnsErr.Name
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return KindTimeout, ""
+	}
+	var syscallErr *os.SyscallError
+	if errors.As(err, &syscallErr) {
+		if kind := classifySyscallError(syscallErr); kind != "" {
+			return kind, ""
+		}
+	}
+
+	// x/crypto/ssh 的客户端认证失败只有错误信息，没有导出的类型
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "unable to authenticate"), strings.Contains(msg, "permission denied"), strings.Contains(msg, "authentication failed"):
+		return KindAuthFailed, ""
+	case strings.Contains(msg, "address already in use"):
+		return KindPortInUse, ""
+	}
+	return KindUnknown, ""
+}
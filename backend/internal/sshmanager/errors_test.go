@@ -0,0 +1,140 @@
+package sshmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/sshconfig"
+
+	"golang.org/x/crypto/ssh"
+	xknownhosts "golang.org/x/crypto/ssh/knownhosts"
+)
+
+// listenTwice 在同一个端口上监听两次，返回第二次的（端口占用）错误
+func listenTwice(t *testing.T) error {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer l.Close()
+	l2, err := net.Listen("tcp", l.Addr().String())
+	if err == nil {
+		l2.Close()
+		t.Fatal("second Listen on the same port succeeded")
+	}
+	return err
+}
+
+// dialClosedPort 连接一个刚关闭的本地端口，返回连接被拒绝的错误
+func dialClosedPort(t *testing.T) error {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	conn, err := net.Dial("tcp", addr)
+	if err == nil {
+		conn.Close()
+		t.Skip("closed port unexpectedly accepted a connection")
+	}
+	return err
+}
+
+// TestClassify 覆盖每种分类：尽量使用真实产生的错误，并确认经过 %w 包装后分类不变
+func TestClassify(t *testing.T) {
+	mismatch := &xknownhosts.KeyError{Want: []xknownhosts.KnownKey{{Filename: "known_hosts", Line: 1}}}
+
+	tests := []struct {
+		name     string
+		err      error
+		want     ErrorKind
+		sentinel error
+	}{
+		{"password required", &types.PasswordRequiredError{Alias: "web"}, KindPasswordRequired, ErrPasswordRequired},
+		{"passphrase missing", &ssh.PassphraseMissingError{}, KindPassphraseRequired, ErrPassphraseRequired},
+		{"authentication failed type", &types.AuthenticationFailedError{Alias: "web"}, KindAuthFailed, ErrAuthFailed},
+		{"authentication failed message", errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password], no supported methods remain"), KindAuthFailed, ErrAuthFailed},
+		{"permission denied", errors.New("Permission denied (publickey)"), KindAuthFailed, ErrAuthFailed},
+		{"host not found", &sshconfig.HostNotFoundError{Alias: "missing"}, KindHostNotFound, ErrHostNotFound},
+		{"host key unknown", &xknownhosts.KeyError{}, KindHostKeyUnknown, ErrHostKeyUnknown},
+		{"host key mismatch", mismatch, KindHostKeyMismatch, ErrHostKeyMismatch},
+		{"timeout", &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, KindTimeout, ErrTimeout},
+		{"dns", &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}}, KindDNSFailed, ErrDNSFailed},
+		{"connection refused", dialClosedPort(t), KindConnectionRefused, ErrConnectionRefused},
+		{"port in use", listenTwice(t), KindPortInUse, ErrPortInUse},
+		{"port in use message", errors.New("listen tcp 127.0.0.1:8080: bind: address already in use"), KindPortInUse, ErrPortInUse},
+		{"unknown", errors.New("something else"), KindUnknown, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, err := range []error{tt.err, fmt.Errorf("wrapped: %w", tt.err)} {
+				if got := Classify(err); got != tt.want {
+					t.Errorf("Classify(%v) = %q, want %q", err, got, tt.want)
+				}
+				ce := NewConnectError(err, "web")
+				if ce.Kind != tt.want {
+					t.Errorf("NewConnectError(%v).Kind = %q, want %q", err, ce.Kind, tt.want)
+				}
+				if tt.sentinel != nil && !errors.Is(ce, tt.sentinel) {
+					t.Errorf("errors.Is(%v, %v) = false", ce, tt.sentinel)
+				}
+				if !errors.Is(ce, err) {
+					t.Errorf("ConnectError does not unwrap to the original error %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestClassify_Nil nil 错误没有分类
+func TestClassify_Nil(t *testing.T) {
+	if got := Classify(nil); got != "" {
+		t.Errorf("Classify(nil) = %q, want empty", got)
+	}
+	if ce := NewConnectError(nil, "web"); ce != nil {
+		t.Errorf("NewConnectError(nil) = %v, want nil", ce)
+	}
+}
+
+// TestNewConnectError_KeepsExistingKind 已分类的错误不会被重新分类，只补上别名
+func TestNewConnectError_KeepsExistingKind(t *testing.T) {
+	inner := &ConnectError{Kind: KindPassphraseRequired, Detail: "~/.ssh/id_ed25519", Err: &types.PasswordRequiredError{}}
+	ce := NewConnectError(fmt.Errorf("connect: %w", inner), "web")
+	if ce.Kind != KindPassphraseRequired || ce.Alias != "web" || ce.Detail != inner.Detail {
+		t.Errorf("NewConnectError = %+v, want kind %q with alias 'web'", ce, KindPassphraseRequired)
+	}
+	if inner.Alias != "" {
+		t.Error("NewConnectError modified the wrapped error")
+	}
+
+	// 包装的 PasswordRequiredError 仍然可以取出，前端据此弹出密码框
+	var passwordRequired *types.PasswordRequiredError
+	if !errors.As(ce, &passwordRequired) {
+		t.Error("errors.As(PasswordRequiredError) = false")
+	}
+}
+
+// TestConnectError_MarshalJSON 错误序列化为 {kind, alias, message}
+func TestConnectError_MarshalJSON(t *testing.T) {
+	ce := NewConnectError(&net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, "web")
+	data, err := json.Marshal(ce)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got["kind"] != string(KindTimeout) || got["alias"] != "web" || got["message"] != ce.Error() {
+		t.Errorf("MarshalJSON = %s", data)
+	}
+}
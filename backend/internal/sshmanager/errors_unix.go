@@ -0,0 +1,23 @@
+//go:build !windows
+
+package sshmanager
+
+import (
+	"os"
+	"syscall"
+)
+
+// classifySyscallError 对 Unix 上的网络系统调用错误分类，无法识别时返回空字符串
+func classifySyscallError(syscallErr *os.SyscallError) ErrorKind {
+	switch syscallErr.Err {
+	case syscall.ECONNREFUSED:
+		return KindConnectionRefused
+	case syscall.EHOSTUNREACH:
+		return KindHostUnreachable
+	case syscall.ENETUNREACH:
+		return KindNetworkUnreachable
+	case syscall.EADDRINUSE:
+		return KindPortInUse
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package sshmanager
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// classifySyscallError 对 Windows 上的网络系统调用错误（WSA*）分类，无法识别时返回空字符串
+func classifySyscallError(syscallErr *os.SyscallError) ErrorKind {
+	switch {
+	case errors.Is(syscallErr.Err, windows.WSAECONNREFUSED):
+		return KindConnectionRefused
+	case errors.Is(syscallErr.Err, windows.WSAEHOSTUNREACH):
+		return KindHostUnreachable
+	case errors.Is(syscallErr.Err, windows.WSAENETUNREACH):
+		return KindNetworkUnreachable
+	case errors.Is(syscallErr.Err, windows.WSAEADDRINUSE):
+		return KindPortInUse
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package sshmanager
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// maxCommandOutputBytes 是 RunCommand 保留的输出上限，超出部分被丢弃
+const maxCommandOutputBytes = 1 << 20
+
+// cappedBuffer 只保留前 limit 个字节，写入永远不会失败，避免远程命令因输出过多而被中断
+type cappedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// RunCommand 新建一个到 alias 的连接，执行一条非交互命令并返回合并后的标准输出与标准错误。
+// password 为空时使用钥匙串中保存的密码；命令以非零状态退出时返回 *ssh.ExitError 与已有的输出。
+// 超过 timeout 时关闭连接，远程进程会随 SSH 会话结束收到 SIGHUP。
+func (m *Manager) RunCommand(alias, password, command string, timeout time.Duration) ([]byte, error) {
+	config, _, err := m.GetConnectionConfig(alias, password)
+	if err != nil {
+		return nil, err
+	}
+	client, err := Dial(config)
+	if err != nil {
+		return nil, NewConnectError(err, alias)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session on %s: %w", alias, err)
+	}
+	defer session.Close()
+
+	output := &cappedBuffer{limit: maxCommandOutputBytes}
+	session.Stdout = output
+	session.Stderr = output
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		client.Close()
+		<-done
+		return output.buf.Bytes(), fmt.Errorf("command on %s timed out after %s", alias, timeout)
+	}
+	return output.buf.Bytes(), err
+}
@@ -0,0 +1,127 @@
+package sshmanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"devtools/backend/pkg/sshconfig"
+)
+
+// ForwardingSettings 是主机的代理转发与 X11 转发设置，来自 ssh_config 的 ForwardAgent 与 ForwardX11，
+// 因此外部终端中的 ssh 也会使用相同的设置
+type ForwardingSettings struct {
+	Agent       bool   `json:"agent"`
+	AgentSocket string `json:"agentSocket,omitempty"` // ForwardAgent 指定的套接字路径，未指定时为 IdentityAgent 的套接字，都为空时使用 SSH_AUTH_SOCK
+	X11         bool   `json:"x11"`
+}
+
+// ForwardingFor 返回 alias 生效配置中的转发设置
+func (m *Manager) ForwardingFor(alias string) ForwardingSettings {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	effective := m.manager.ResolveHost(alias)
+
+	var fwd ForwardingSettings
+	fwd.Agent, fwd.AgentSocket = parseForwardAgent(effective.Get("ForwardAgent"))
+	if fwd.Agent && fwd.AgentSocket == "" {
+		// 与 OpenSSH 一致：ForwardAgent yes 转发的是 IdentityAgent 指定的代理
+		if identityAgent := m.identityAgentFor(alias); !identityAgent.Disabled {
+			fwd.AgentSocket = identityAgent.Socket
+		}
+	}
+	fwd.X11 = strings.EqualFold(strings.TrimSpace(effective.Get("ForwardX11")), "yes")
+	return fwd
+}
+
+// SetForwarding 将 alias 的代理转发与 X11 转发开关写入 ssh_config。
+// 关闭时如果该选项是从其他块（例如 Host *）继承的，会显式写入 "no"。
+func (m *Manager) SetForwarding(alias string, agent, x11 bool) (*HostUpdateResult, error) {
+	if m.IsEphemeralHost(alias) {
+		return nil, fmt.Errorf("forwarding cannot be saved for temporary host '%s'", alias)
+	}
+	if !m.HasHost(alias) {
+		return nil, fmt.Errorf("host '%s' not found", alias)
+	}
+
+	current := m.ForwardingFor(alias)
+	params := map[string]string{}
+	switch {
+	case agent && current.Agent:
+		// 保留已有的设置（可能是套接字路径）
+	case agent:
+		params["ForwardAgent"] = "yes"
+	case current.Agent:
+		params["ForwardAgent"] = "no"
+	}
+	switch {
+	case x11 == current.X11:
+	case x11:
+		params["ForwardX11"] = "yes"
+	default:
+		params["ForwardX11"] = "no"
+	}
+	if len(params) == 0 {
+		return newHostUpdateResult(), nil
+	}
+	return m.UpdateHost(HostUpdateRequest{Name: alias, Params: params})
+}
+
+// parseForwardAgent 解析 ForwardAgent 的值：yes、no，或者代理套接字的路径 / 环境变量（例如 $SSH_AUTH_SOCK）
+func parseForwardAgent(value string) (enabled bool, socket string) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "", "no":
+		return false, ""
+	case "yes":
+		return true, ""
+	}
+	if strings.HasPrefix(value, "$") {
+		return true, os.Getenv(strings.Trim(value[1:], "{}"))
+	}
+	if strings.HasPrefix(value, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			value = filepath.Join(home, value[1:])
+		}
+	}
+	return true, value
+}
+
+// ConfigForwards 返回 alias 的 Host 块中直接声明的 LocalForward、RemoteForward 与 DynamicForward，按文件中的顺序排列
+func (m *Manager) ConfigForwards(alias string) ([]sshconfig.Param, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hostConfig, err := m.manager.GetHost(alias)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host %s: %w", alias, err)
+	}
+	var forwards []sshconfig.Param
+	for _, param := range hostConfig.OrderedParams() {
+		if sshconfig.IsForwardKeyword(param.Key) {
+			forwards = append(forwards, param)
+		}
+	}
+	return forwards, nil
+}
+
+// ReplaceConfigForward 把 alias 中值为 oldValue 的转发指令改为 newValue 并保存
+func (m *Manager) ReplaceConfigForward(alias, keyword, oldValue, newValue string) error {
+	if m.IsEphemeralHost(alias) {
+		return fmt.Errorf("forwards cannot be saved for temporary host '%s'", alias)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := m.manager.ReplaceParamValue(alias, keyword, oldValue, newValue); err != nil {
+		return err
+	}
+	if err := m.save(); err != nil {
+		return fmt.Errorf("failed to save config after updating %s: %w", keyword, err)
+	}
+	return nil
+}
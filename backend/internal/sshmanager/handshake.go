@@ -0,0 +1,225 @@
+package sshmanager
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// HandshakeInfo 是一次 SSH 握手协商出的算法。x/crypto/ssh 不公开这些信息，
+// 这里根据服务器发送的（明文）KEXINIT 与客户端的偏好，按 RFC 4253 的规则推算出结果。
+type HandshakeInfo struct {
+	KeyExchange string `json:"keyExchange,omitempty"`
+	Cipher      string `json:"cipher,omitempty"`
+	MAC         string `json:"mac,omitempty"` // AEAD 加密算法（GCM、ChaCha20-Poly1305）不使用单独的 MAC，此时为空
+}
+
+// handshakes 保存已建立连接的握手信息，连接关闭后删除
+var handshakes sync.Map // *ssh.Client → HandshakeInfo
+
+// HandshakeInfoFor 返回连接握手时协商出的算法
+func HandshakeInfoFor(client *ssh.Client) (HandshakeInfo, bool) {
+	info, ok := handshakes.Load(client)
+	if !ok {
+		return HandshakeInfo{}, false
+	}
+	return info.(HandshakeInfo), true
+}
+
+// recordHandshake 根据 sniffer 捕获的 KEXINIT 记录连接的握手信息
+func recordHandshake(client *ssh.Client, sniffer *kexinitSniffer, clientConfig *ssh.ClientConfig) {
+	payload := sniffer.kexinit()
+	if payload == nil {
+		return
+	}
+	info, ok := negotiate(payload, clientConfig)
+	if !ok {
+		return
+	}
+	handshakes.Store(client, info)
+	go func() {
+		_ = client.Wait()
+		handshakes.Delete(client)
+	}()
+}
+
+// negotiate 计算客户端会选择的算法：客户端偏好列表中第一个服务器也支持的算法
+func negotiate(payload []byte, clientConfig *ssh.ClientConfig) (HandshakeInfo, bool) {
+	// KEXINIT: byte 20, 16 字节 cookie, 然后是 kex、hostkey、c2s/s2c cipher、c2s/s2c MAC 等 name-list
+	if len(payload) < 17 || payload[0] != 20 {
+		return HandshakeInfo{}, false
+	}
+	rest := payload[17:]
+	lists := make([][]string, 0, 6)
+	for range 6 {
+		if len(rest) < 4 {
+			return HandshakeInfo{}, false
+		}
+		n := binary.BigEndian.Uint32(rest)
+		if uint64(len(rest)-4) < uint64(n) {
+			return HandshakeInfo{}, false
+		}
+		lists = append(lists, strings.Split(string(rest[4:4+n]), ","))
+		rest = rest[4+n:]
+	}
+
+	config := clientConfig.Config
+	config.SetDefaults()
+	info := HandshakeInfo{
+		KeyExchange: firstCommon(config.KeyExchanges, lists[0]),
+		Cipher:      firstCommon(config.Ciphers, lists[2]),
+	}
+	if !isAEADCipher(info.Cipher) {
+		info.MAC = firstCommon(config.MACs, lists[4])
+	}
+	return info, true
+}
+
+func firstCommon(client, server []string) string {
+	for _, algo := range client {
+		if slices.Contains(server, algo) {
+			return algo
+		}
+	}
+	return ""
+}
+
+func isAEADCipher(cipher string) bool {
+	return strings.HasSuffix(cipher, "-gcm@openssh.com") || strings.HasPrefix(cipher, "chacha20-poly1305")
+}
+
+// maxKexinitSniff 是查找 KEXINIT 时最多缓存的字节数，超过后放弃
+const maxKexinitSniff = 64 * 1024
+
+// kexinitSniffer 在读取时旁路捕获服务器的版本行之后的第一个数据包（KEXINIT），不改变读到的数据
+type kexinitSniffer struct {
+	net.Conn
+	done atomic.Bool
+
+	mu      sync.Mutex
+	buf     []byte
+	version bool   // 已经读到服务器的版本行
+	payload []byte // 捕获的 KEXINIT
+}
+
+func (s *kexinitSniffer) Read(p []byte) (int, error) {
+	n, err := s.Conn.Read(p)
+	if n > 0 && !s.done.Load() {
+		s.observe(p[:n])
+	}
+	return n, err
+}
+
+func (s *kexinitSniffer) observe(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, data...)
+
+	// 版本行之前服务器可以发送其他文本行
+	for !s.version {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			s.giveUpIfTooLarge()
+			return
+		}
+		s.version = bytes.HasPrefix(s.buf, []byte("SSH-"))
+		s.buf = s.buf[i+1:]
+	}
+	if len(s.buf) < 5 {
+		return
+	}
+	length := int(binary.BigEndian.Uint32(s.buf))
+	padding := int(s.buf[4])
+	if length > maxKexinitSniff || padding+1 > length {
+		s.finish(nil)
+		return
+	}
+	if len(s.buf) < 4+length {
+		return
+	}
+	s.finish(append([]byte(nil), s.buf[5:4+length-padding]...))
+}
+
+func (s *kexinitSniffer) giveUpIfTooLarge() {
+	if len(s.buf) > maxKexinitSniff {
+		s.finish(nil)
+	}
+}
+
+// finish 保存结果并停止捕获，调用方需持有 s.mu
+func (s *kexinitSniffer) finish(payload []byte) {
+	s.payload = payload
+	s.buf = nil
+	s.done.Store(true)
+}
+
+// kexinit 返回捕获的 KEXINIT，没有捕获到时为 nil
+func (s *kexinitSniffer) kexinit() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.payload
+}
+
+// authTrace 记录握手期间最后一次尝试的认证方式；握手成功时它就是实际使用的方式
+type authTrace struct {
+	mu     sync.Mutex
+	method string
+}
+
+func (t *authTrace) set(method string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.method = method
+	t.mu.Unlock()
+}
+
+func (t *authTrace) get() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.method
+}
+
+// AuthMethodUsed 返回最近一次用该配置建立连接时使用的认证方式，例如 "password" 或 "publickey (agent: me@laptop)"
+func (c *ConnectionConfig) AuthMethodUsed() string {
+	return c.auth.get()
+}
+
+// tracedPassword 返回一个在被尝试时记录到 trace 的密码认证方式
+func tracedPassword(trace *authTrace, label, password string) ssh.AuthMethod {
+	return ssh.PasswordCallback(func() (string, error) {
+		trace.set(label)
+		return password, nil
+	})
+}
+
+// tracedSigner 在签名时记录使用的密钥。服务器只会要求对它接受的公钥签名，因此最后一次签名的密钥就是认证成功的密钥。
+type tracedSigner struct {
+	ssh.Signer
+	label string
+	trace *authTrace
+}
+
+func (s *tracedSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	s.trace.set("publickey (" + s.label + ")")
+	return s.Signer.Sign(rand, data)
+}
+
+func (s *tracedSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	s.trace.set("publickey (" + s.label + ")")
+	if as, ok := s.Signer.(ssh.AlgorithmSigner); ok {
+		return as.SignWithAlgorithm(rand, data, algorithm)
+	}
+	return s.Signer.Sign(rand, data)
+}
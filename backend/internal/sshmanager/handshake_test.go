@@ -0,0 +1,99 @@
+package sshmanager
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startTestServer 启动一个只接受密码 "secret" 的 SSH 服务器，服务器只支持 aes128-ctr + hmac-sha2-256
+func startTestServer(t *testing.T) string {
+	t.Helper()
+	return startAuthTestServer(t, &ssh.ServerConfig{
+		Config: ssh.Config{Ciphers: []string{"aes128-ctr"}, MACs: []string{"hmac-sha2-256"}},
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	})
+}
+
+// startAuthTestServer 启动一个使用 config 认证、拒绝所有通道的 SSH 服务器
+func startAuthTestServer(t *testing.T, config *ssh.ServerConfig) string {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				sc, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "")
+				}
+				sc.Close()
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// TestDial_RecordsHandshakeAndAuthMethod 握手后可以取得协商出的算法与实际使用的认证方式
+func TestDial_RecordsHandshakeAndAuthMethod(t *testing.T) {
+	addr := startTestServer(t)
+	trace := &authTrace{}
+	clientConfig := &ssh.ClientConfig{
+		User: "test",
+		Auth: []ssh.AuthMethod{
+			tracedPassword(trace, "password (keychain)", "wrong"),
+			ssh.KeyboardInteractive(func(string, string, []string, []bool) ([]string, error) { return nil, nil }),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	if _, err := DialWithPolicy(addr, clientConfig, DefaultConnectionPolicy()); err == nil {
+		t.Fatal("dial with a wrong password should fail")
+	}
+
+	clientConfig.Auth = []ssh.AuthMethod{tracedPassword(trace, "password", "secret")}
+	client, err := DialWithPolicy(addr, clientConfig, DefaultConnectionPolicy())
+	if err != nil {
+		t.Fatalf("DialWithPolicy failed: %v", err)
+	}
+	defer client.Close()
+
+	info, ok := HandshakeInfoFor(client)
+	if !ok {
+		t.Fatal("HandshakeInfoFor found no handshake")
+	}
+	if info.Cipher != "aes128-ctr" || info.MAC != "hmac-sha2-256" || info.KeyExchange == "" {
+		t.Errorf("HandshakeInfo = %+v, want aes128-ctr/hmac-sha2-256", info)
+	}
+	if got := (&ConnectionConfig{auth: trace}).AuthMethodUsed(); got != "password" {
+		t.Errorf("AuthMethodUsed() = %q, want password", got)
+	}
+}
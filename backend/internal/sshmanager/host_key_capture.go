@@ -0,0 +1,217 @@
+package sshmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"devtools/backend/internal/types"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// captureAddressTimeout 是捕获主机密钥时每个地址的建连与握手超时，
+	// 保证某个地址不可达时能尽快尝试下一个
+	captureAddressTimeout = 5 * time.Second
+	// capturedHostKeyTTL 是捕获结果的有效期，在此期间信任主机时直接使用已展示给用户的密钥
+	capturedHostKeyTTL = 5 * time.Minute
+)
+
+// CapturedHostKey 是一次主机密钥捕获的结果
+type CapturedHostKey struct {
+	Key         ssh.PublicKey `json:"-"`
+	Address     string        `json:"address"`     // 实际提供密钥的地址（ip:port），经由跳板机或 ProxyCommand 时为 HostName:Port
+	KeyType     string        `json:"keyType"`     // 例如 "ssh-ed25519"
+	Fingerprint string        `json:"fingerprint"` // SHA256 指纹
+	CapturedAt  time.Time     `json:"-"`
+}
+
+// captureHostKeyError a special error type to capture the host key
+type captureHostKeyError struct {
+	key ssh.PublicKey
+}
+
+func (e *captureHostKeyError) Error() string {
+	return "host key captured"
+}
+
+// CaptureHostKey 捕获服务器的公钥。直连时会解析 HostName 的所有 A/AAAA 记录并依次以较短的超时尝试，
+// 返回第一个提供了密钥的地址；经由 ProxyJump / ProxyCommand 时与正常连接走相同的传输路径。
+// 捕获结果会被记住一段时间，供随后的信任操作复用，避免用户确认的密钥与写入的密钥不一致。
+func (m *Manager) CaptureHostKey(host *types.SSHHost) (*CapturedHostKey, error) {
+	// 创建一个只用于捕获的、不进行任何认证的配置
+	captureConfig := &ssh.ClientConfig{
+		User: host.User,
+		// 关键：这个回调函数在拿到公钥后，会立即返回一个特殊错误来中断连接
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return &captureHostKeyError{key: key}
+		},
+	}
+
+	// 捕获公钥只需要一次握手，因此不做重试
+	policy := m.ConnectionPolicyFor(host.Alias)
+	if policy.Validate() != nil {
+		policy = DefaultConnectionPolicy()
+	}
+	policy.RetryCount = 0
+
+	captureConn := &ConnectionConfig{
+		Name:         host.Alias,
+		HostName:     host.HostName,
+		Port:         host.Port,
+		ClientConfig: captureConfig,
+		Policy:       policy,
+	}
+	m.mu.RLock()
+	policy = m.withConfigDialOptions(host.Alias, policy)
+	captureConn.Policy = policy
+	// 只允许旧算法的设备需要在握手阶段就使用配置的算法
+	m.transportOptionsFor(host.Alias).applyAlgorithms(captureConfig)
+	err := m.applyTransport(captureConn, host.Alias, host)
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture host key: %w", err)
+	}
+
+	var captured *CapturedHostKey
+	if len(captureConn.JumpHosts) > 0 || captureConn.ProxyCommand != "" {
+		key, err := capturedKeyFrom(Dial(captureConn))
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture host key: %w", err)
+		}
+		captured = newCapturedHostKey(net.JoinHostPort(host.HostName, host.Port), key)
+	} else {
+		captured, err = captureFromAddresses(host.HostName, host.Port, captureConfig, policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture host key: %w", err)
+		}
+	}
+
+	m.capturedMu.Lock()
+	if m.captured == nil {
+		m.captured = make(map[string]*CapturedHostKey)
+	}
+	m.captured[capturedHostKeyID(host)] = captured
+	m.capturedMu.Unlock()
+
+	logger.Printf("Captured %s host key of %s from %s", captured.KeyType, host.Alias, captured.Address)
+	return captured, nil
+}
+
+// RecentHostKey 返回最近一次为 host 捕获、仍在有效期内的主机密钥，没有时返回 nil
+func (m *Manager) RecentHostKey(host *types.SSHHost) *CapturedHostKey {
+	m.capturedMu.Lock()
+	defer m.capturedMu.Unlock()
+	captured, ok := m.captured[capturedHostKeyID(host)]
+	if !ok {
+		return nil
+	}
+	if time.Since(captured.CapturedAt) > capturedHostKeyTTL {
+		delete(m.captured, capturedHostKeyID(host))
+		return nil
+	}
+	return captured
+}
+
+// forgetCapturedHostKey 删除 host 的捕获结果，在密钥写入 known_hosts 后调用
+func (m *Manager) forgetCapturedHostKey(host *types.SSHHost) {
+	m.capturedMu.Lock()
+	defer m.capturedMu.Unlock()
+	delete(m.captured, capturedHostKeyID(host))
+}
+
+// capturedHostKeyID 是捕获结果的索引，别名相同但地址变化后不会复用旧结果
+func capturedHostKeyID(host *types.SSHHost) string {
+	return host.Alias + "|" + net.JoinHostPort(host.HostName, host.Port)
+}
+
+// captureFromAddresses 解析 hostName 的所有地址并依次尝试，返回第一个提供了密钥的地址
+func captureFromAddresses(hostName, port string, clientConfig *ssh.ClientConfig, policy ConnectionPolicy) (*CapturedHostKey, error) {
+	addrs, err := resolveHostAddresses(hostName, policy.dialTimeout(), policy.AddressFamily)
+	if err != nil {
+		return nil, err
+	}
+
+	policy.DialTimeoutSeconds = min(policy.DialTimeoutSeconds, int(captureAddressTimeout/time.Second))
+	policy.AuthTimeoutSeconds = min(policy.AuthTimeoutSeconds, int(captureAddressTimeout/time.Second))
+
+	var errs []error
+	for _, ip := range addrs {
+		addr := net.JoinHostPort(ip, port)
+		key, err := capturedKeyFrom(dialOnce(addr, clientConfig, policy))
+		if err == nil {
+			return newCapturedHostKey(addr, key), nil
+		}
+		logger.Printf("Could not capture host key of %s from %s: %v", hostName, addr, err)
+		errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+	}
+	if len(errs) == 1 {
+		return nil, errors.Unwrap(errs[0])
+	}
+	return nil, fmt.Errorf("no address of %s returned a host key: %w", hostName, errors.Join(errs...))
+}
+
+// resolveHostAddresses 返回 hostName 的所有 IP 地址（IPv4 在前），hostName 本身是 IP 时直接返回。
+// family 为 inet 或 inet6 时只返回对应地址族的地址。
+func resolveHostAddresses(hostName string, timeout time.Duration, family string) ([]string, error) {
+	hostName = strings.Trim(hostName, "[]")
+	if net.ParseIP(hostName) != nil {
+		return []string{hostName}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ipAddrs, err := net.DefaultResolver.LookupIPAddr(ctx, hostName)
+	if err != nil {
+		return nil, err
+	}
+
+	var v4, v6 []string
+	for _, ip := range ipAddrs {
+		if ip.IP.To4() != nil {
+			v4 = append(v4, ip.String())
+		} else {
+			v6 = append(v6, ip.String())
+		}
+	}
+	switch family {
+	case AddressFamilyInet:
+		v6 = nil
+	case AddressFamilyInet6:
+		v4 = nil
+	}
+	if len(v4)+len(v6) == 0 {
+		return nil, fmt.Errorf("%s has no %s address", hostName, family)
+	}
+	return append(v4, v6...), nil
+}
+
+// capturedKeyFrom 从一次捕获用的拨号结果中取出主机密钥
+func capturedKeyFrom(client *ssh.Client, err error) (ssh.PublicKey, error) {
+	if client != nil {
+		client.Close()
+	}
+	var capturedKeyErr *captureHostKeyError
+	if errors.As(err, &capturedKeyErr) {
+		return capturedKeyErr.key, nil
+	}
+	if err == nil {
+		// 握手不经过主机密钥校验就成功了，不应发生
+		err = errors.New("server did not present a host key")
+	}
+	return nil, err
+}
+
+func newCapturedHostKey(addr string, key ssh.PublicKey) *CapturedHostKey {
+	return &CapturedHostKey{
+		Key:         key,
+		Address:     addr,
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		CapturedAt:  time.Now(),
+	}
+}
@@ -0,0 +1,74 @@
+package sshmanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"devtools/backend/pkg/sshconfig"
+)
+
+// IdentityAgentSettings 是主机认证时使用的 ssh-agent，来自 ssh_config 的 IdentityAgent。
+// 密钥保存在第三方代理（例如 1Password、Secretive、KeePassXC）中时，通常只为部分主机指定该代理的套接字。
+type IdentityAgentSettings struct {
+	Value    string `json:"value"`            // ssh_config 中的原始值，为空表示未设置
+	Disabled bool   `json:"disabled"`         // IdentityAgent none：不使用任何代理中的密钥
+	Socket   string `json:"socket,omitempty"` // 展开后的套接字路径（Windows 上为命名管道），为空表示自动选择（SSH_AUTH_SOCK 等）
+}
+
+// IdentityAgentFor 返回 alias 生效配置中的 IdentityAgent
+func (m *Manager) IdentityAgentFor(alias string) IdentityAgentSettings {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.identityAgentFor(alias)
+}
+
+// identityAgentFor 解析 alias 的 IdentityAgent，调用方需持有 m.mu
+func (m *Manager) identityAgentFor(alias string) IdentityAgentSettings {
+	value := strings.TrimSpace(m.manager.ResolveHost(alias).Get("IdentityAgent"))
+	agent := parseIdentityAgent(value, m.manager.TokensFor(alias))
+	if agent.Disabled && !strings.EqualFold(value, "none") {
+		logger.Printf("Warning: IdentityAgent %s of %s is not set in the environment, not using an agent", value, alias)
+	}
+	return agent
+}
+
+// SetIdentityAgent 将 alias 的 IdentityAgent 写入 ssh_config，value 为空时删除该选项（使用 SSH_AUTH_SOCK）
+func (m *Manager) SetIdentityAgent(alias, value string) (*HostUpdateResult, error) {
+	if m.IsEphemeralHost(alias) {
+		return nil, fmt.Errorf("IdentityAgent cannot be saved for temporary host '%s'", alias)
+	}
+	if !m.HasHost(alias) {
+		return nil, fmt.Errorf("host '%s' not found", alias)
+	}
+	value = strings.TrimSpace(value)
+	if strings.ContainsAny(value, "\r\n") {
+		return nil, fmt.Errorf("IdentityAgent cannot contain line breaks")
+	}
+	return m.UpdateHost(HostUpdateRequest{Name: alias, Params: map[string]string{"IdentityAgent": value}})
+}
+
+// parseIdentityAgent 按 OpenSSH 的规则解析 IdentityAgent 的值：none 表示不使用代理，
+// SSH_AUTH_SOCK 或 $VAR 表示从环境变量读取套接字路径（未设置时不使用代理），
+// 其余值是套接字路径，支持 ~ 与 TOKENS（%d %h %u 等）。
+func parseIdentityAgent(value string, tokens sshconfig.Tokens) IdentityAgentSettings {
+	agent := IdentityAgentSettings{Value: value}
+	switch {
+	case value == "":
+	case strings.EqualFold(value, "none"):
+		agent.Disabled = true
+	case value == "SSH_AUTH_SOCK" || strings.HasPrefix(value, "$"):
+		agent.Socket = os.Getenv(strings.Trim(strings.TrimPrefix(value, "$"), "{}"))
+		agent.Disabled = agent.Socket == ""
+	default:
+		socket := sshconfig.ExpandTokensWith(value, tokens)
+		if strings.HasPrefix(socket, "~") {
+			if home, err := os.UserHomeDir(); err == nil {
+				socket = filepath.Join(home, socket[1:])
+			}
+		}
+		agent.Socket = socket
+	}
+	return agent
+}
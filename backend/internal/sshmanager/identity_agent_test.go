@@ -0,0 +1,59 @@
+package sshmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestIdentityAgentFor 测试 IdentityAgent 的 none、环境变量与套接字路径（含 ~ 与 TOKENS），
+// 以及 ForwardAgent yes 转发 IdentityAgent 指定的代理
+func TestIdentityAgentFor(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	t.Setenv("DEVTOOLS_TEST_AGENT", "/tmp/test-agent.sock")
+	t.Setenv("DEVTOOLS_TEST_UNSET_AGENT", "")
+
+	configPath := filepath.Join(t.TempDir(), "config")
+	content := "Host op\n    IdentityAgent \"~/Library/Group Containers/op/agent.sock\"\n    ForwardAgent yes\n\n" +
+		"Host none\n    IdentityAgent none\n    ForwardAgent yes\n\n" +
+		"Host env\n    IdentityAgent $DEVTOOLS_TEST_AGENT\n\n" +
+		"Host unset\n    IdentityAgent ${DEVTOOLS_TEST_UNSET_AGENT}\n\n" +
+		"Host token\n    HostName 10.0.0.1\n    IdentityAgent /run/agents/%h.sock\n\n" +
+		"Host plain\n    HostName 10.0.0.2\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		alias    string
+		disabled bool
+		socket   string
+	}{
+		{"op", false, filepath.Join(home, "Library/Group Containers/op/agent.sock")},
+		{"none", true, ""},
+		{"env", false, "/tmp/test-agent.sock"},
+		{"unset", true, ""},
+		{"token", false, "/run/agents/10.0.0.1.sock"},
+		{"plain", false, ""},
+	}
+	for _, tt := range tests {
+		got := m.IdentityAgentFor(tt.alias)
+		if got.Disabled != tt.disabled || got.Socket != tt.socket {
+			t.Errorf("IdentityAgentFor(%q) = %+v, want disabled=%v socket=%q", tt.alias, got, tt.disabled, tt.socket)
+		}
+	}
+
+	if fwd := m.ForwardingFor("op"); !fwd.Agent || fwd.AgentSocket != filepath.Join(home, "Library/Group Containers/op/agent.sock") {
+		t.Errorf("ForwardAgent yes should forward the IdentityAgent socket, got %+v", fwd)
+	}
+	if fwd := m.ForwardingFor("none"); !fwd.Agent || fwd.AgentSocket != "" {
+		t.Errorf("IdentityAgent none should fall back to the default agent for forwarding, got %+v", fwd)
+	}
+}
@@ -0,0 +1,219 @@
+package sshmanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"devtools/backend/internal/types"
+)
+
+// HostSourceInclude 是 Include 文件中的主机在 types.SSHHost.Source 中的取值
+const HostSourceInclude = "include"
+
+// adjacentConfigDirs 是 ssh_config 旁边常见的配置目录（例如公司统一下发的配置），需要 Include 后才会生效
+var adjacentConfigDirs = []string{"config.d", "conf.d"}
+
+// AdjacentConfig 是配置目录中的一个文件
+type AdjacentConfig struct {
+	Path     string   `json:"path"`
+	Hosts    []string `json:"hosts"`           // 文件中定义的具体主机
+	Included bool     `json:"included"`        // 已经被 ssh_config 的 Include 引入
+	Error    string   `json:"error,omitempty"` // 文件无法读取或解析
+}
+
+// AdjacentConfigScan 是 ScanForAdjacentConfigs 的结果
+type AdjacentConfigScan struct {
+	HasInclude bool             `json:"hasInclude"` // ssh_config 中已经有 Include 指令
+	Suggested  []string         `json:"suggested"`  // 建议添加的 Include 参数，例如 "config.d/*"，可直接传给 AdoptInclude
+	Files      []AdjacentConfig `json:"files"`
+}
+
+// ScanForAdjacentConfigs 列出 ssh_config 所在目录下 config.d 等目录中的配置文件，
+// 以及它们是否已经被 Include。没有被引入的文件中的主机不会出现在主机列表中，也不会被 ssh 使用。
+func (m *Manager) ScanForAdjacentConfigs() (*AdjacentConfigScan, error) {
+	m.mu.RLock()
+	hasInclude := len(m.manager.GetIncludes()) > 0
+	includedFiles := m.manager.IncludedFiles()
+	baseDir := filepath.Dir(m.configPath)
+	m.mu.RUnlock()
+
+	included := make(map[string]bool, len(includedFiles))
+	for _, path := range includedFiles {
+		included[path] = true
+	}
+
+	scan := &AdjacentConfigScan{HasInclude: hasInclude, Suggested: []string{}, Files: []AdjacentConfig{}}
+	for _, name := range adjacentConfigDirs {
+		dir := filepath.Join(baseDir, name)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logger.Printf("Warning: failed to read %s: %v", dir, err)
+			}
+			continue
+		}
+		suggest := false
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || !isConfigFileName(entry.Name()) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			file := AdjacentConfig{Path: path, Hosts: []string{}, Included: included[path]}
+			if content, err := os.ReadFile(path); err != nil {
+				file.Error = err.Error()
+			} else if hosts, err := parseHosts(string(content), HostSourceInclude); err != nil {
+				file.Error = err.Error()
+			} else {
+				for _, host := range hosts {
+					file.Hosts = append(file.Hosts, host.Alias)
+				}
+			}
+			if !file.Included {
+				suggest = true
+			}
+			scan.Files = append(scan.Files, file)
+		}
+		if suggest {
+			scan.Suggested = append(scan.Suggested, name+"/*")
+		}
+	}
+	return scan, nil
+}
+
+// isConfigFileName 排除隐藏文件与编辑器、备份工具留下的文件
+func isConfigFileName(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".bak", ".orig", ".swp", ".tmp", ".rpmnew", ".rpmsave", ".dpkg-old", ".dpkg-new":
+		return false
+	}
+	return true
+}
+
+// AdoptInclude 在 ssh_config 开头添加 Include path 并重新加载配置。path 可以是文件或通配符，
+// 相对路径相对于 ssh_config 所在目录（与 OpenSSH 一致）。返回写入的 Include 参数；已经引入时不做修改。
+func (m *Manager) AdoptInclude(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || strings.ContainsAny(path, "\r\n\"") {
+		return "", fmt.Errorf("invalid include path '%s'", path)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return "", err
+	}
+
+	baseDir := filepath.Dir(m.configPath)
+	pattern := path
+	if strings.HasPrefix(pattern, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		pattern = filepath.Join(home, pattern[1:])
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid include path '%s': %w", path, err)
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no files match '%s'", path)
+	}
+
+	// ssh_config 目录内的路径写成相对路径，与手写的 "Include config.d/*" 保持一致
+	arg := path
+	if rel, err := filepath.Rel(baseDir, pattern); err == nil && !strings.HasPrefix(rel, "..") {
+		arg = filepath.ToSlash(rel)
+	}
+
+	included := make(map[string]bool)
+	for _, file := range m.manager.IncludedFiles() {
+		included[file] = true
+	}
+	pending := false
+	for _, match := range matches {
+		if !included[filepath.Clean(match)] {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		return arg, nil
+	}
+
+	if strings.ContainsAny(arg, " \t") {
+		arg = `"` + arg + `"`
+	}
+	m.manager.AddInclude(arg)
+	if err := m.save(); err != nil {
+		return "", fmt.Errorf("failed to save config after adding Include: %w", err)
+	}
+	if err := m.reload(); err != nil {
+		return "", err
+	}
+	logger.Printf("Added 'Include %s' to %s.", arg, m.configPath)
+	return arg, nil
+}
+
+// GetIncludedHosts 返回 Include 文件中定义的具体主机，按别名排序。
+// 这些主机只读（需要编辑对应的文件）；主配置中的同名主机优先，同名时只保留先引入的文件中的主机。
+func (m *Manager) GetIncludedHosts() []types.SSHHost {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.includedHosts_nolock()
+}
+
+// includedHosts_nolock 读取并解析所有 Include 文件中的主机，调用方需持有 m.mu
+func (m *Manager) includedHosts_nolock() []types.SSHHost {
+	hosts := []types.SSHHost{}
+	seen := make(map[string]bool)
+	for _, path := range m.manager.IncludedFiles() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		fileHosts, err := parseHosts(string(content), HostSourceInclude)
+		if err != nil {
+			logger.Printf("Warning: failed to parse included file %s: %v", path, err)
+			continue
+		}
+		for _, host := range fileHosts {
+			if seen[host.Alias] || m.HasHost(host.Alias) {
+				continue
+			}
+			seen[host.Alias] = true
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Alias < hosts[j].Alias })
+	return hosts
+}
+
+// IsIncludedHost 判断 alias 是否是 Include 文件中的主机（主配置中没有同名主机）
+func (m *Manager) IsIncludedHost(alias string) bool {
+	_, ok := m.includedHost(alias)
+	return ok
+}
+
+// includedHost 返回 alias 对应的 Include 文件中的主机副本。与 GetSSHHost 一样不加锁，
+// GetConnectionConfig 等调用方已经持有 m.mu。
+func (m *Manager) includedHost(alias string) (*types.SSHHost, bool) {
+	if m.HasHost(alias) {
+		return nil, false
+	}
+	for _, host := range m.includedHosts_nolock() {
+		if host.Alias == alias {
+			return &host, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,207 @@
+package sshmanager
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"devtools/backend/internal/types"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// HopChainSeparator 用于展示多跳连接路径，例如 "bastion01 → db-prod-3"
+const HopChainSeparator = " → "
+
+// HopChain 返回连接经过的所有主机（依次为各跳板机和目标主机）
+func (c *ConnectionConfig) HopChain() []string {
+	chain := make([]string, 0, len(c.JumpHosts)+1)
+	for _, hop := range c.JumpHosts {
+		chain = append(chain, hop.Name)
+	}
+	name := c.Name
+	if name == "" {
+		name = c.HostName
+	}
+	return append(chain, name)
+}
+
+// parseProxyJump 解析 ProxyJump 的值：逗号分隔的 [user@]host[:port] 列表
+func parseProxyJump(value string) []jumpSpec {
+	var specs []jumpSpec
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var spec jumpSpec
+		if at := strings.LastIndex(part, "@"); at >= 0 {
+			spec.user, part = part[:at], part[at+1:]
+		}
+		spec.host = part
+		if h, p, err := net.SplitHostPort(part); err == nil {
+			spec.host, spec.port = h, p
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// jumpSpec 是 ProxyJump 中的一跳
+type jumpSpec struct {
+	user string
+	host string
+	port string
+}
+
+// proxyJumpFor 返回 alias 生效的 ProxyJump。团队主机使用团队配置中的值，其他主机读取 ssh_config；
+// 未配置或配置为 "none" 时返回空字符串。调用方需持有 m.mu。
+func (m *Manager) proxyJumpFor(alias string, host *types.SSHHost) string {
+	if alias == "" {
+		return ""
+	}
+	value := strings.TrimSpace(host.ProxyJump)
+	if value == "" {
+		value = strings.TrimSpace(m.manager.ResolveHost(alias).Get("ProxyJump"))
+	}
+	if strings.EqualFold(value, "none") {
+		return ""
+	}
+	return value
+}
+
+// jumpHostsFor 根据 alias 的 ProxyJump 构建各跳板机的连接配置。
+// 跳板机如果是 ssh_config 中的别名或团队主机，会使用该别名的 HostName、User、IdentityFile 等设置，
+// 其密码只能来自系统钥匙串。跳板机自身的 ProxyJump 不会被继续展开。调用方需持有 m.mu。
+func (m *Manager) jumpHostsFor(alias string, host *types.SSHHost) ([]*ConnectionConfig, error) {
+	value := m.proxyJumpFor(alias, host)
+	if value == "" {
+		return nil, nil
+	}
+
+	var hops []*ConnectionConfig
+	for _, spec := range parseProxyJump(value) {
+		hop, err := m.buildJumpHost(spec)
+		if err != nil {
+			return nil, fmt.Errorf("jump host '%s': %w", spec.host, err)
+		}
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+// buildJumpHost 构建一跳的连接配置，调用方需持有 m.mu
+func (m *Manager) buildJumpHost(spec jumpSpec) (*ConnectionConfig, error) {
+	host := &types.SSHHost{Alias: spec.host, HostName: spec.host, Port: "22"}
+	fromConfig := m.manager.HasHost(spec.host)
+	if fromConfig || m.IsTeamHost(spec.host) {
+		h, err := m.GetSSHHostByAlias(spec.host)
+		if err != nil {
+			return nil, err
+		}
+		host = h
+		if host.HostName == "" {
+			host.HostName = spec.host
+		}
+	}
+	if spec.user != "" {
+		host.User = spec.user
+	}
+	if spec.port != "" {
+		host.Port = spec.port
+	}
+
+	if !fromConfig {
+		// 团队主机作为跳板机时只使用其连接字段，它自己的跳板机不会被继续展开
+		host.ProxyJump, host.ProxyCommand = "", ""
+	}
+
+	var opts transportOptions
+	if fromConfig {
+		opts = m.transportOptionsFor(spec.host)
+	}
+	cfg, err := m.buildClientConfig(host, "", host.Alias, opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Name = spec.host
+	if fromConfig {
+		cfg.KeepAlive = m.keepAliveForHost(spec.host)
+		cfg.Policy = m.withConfigDialOptions(spec.host, m.dialPolicyFor(spec.host))
+		cfg.ProxyCommand = m.proxyCommandFor(spec.host, host)
+		cfg.PreConnect = m.preConnectFor(spec.host, host)
+	}
+	return cfg, nil
+}
+
+// applyTransport 为 alias 设置 ProxyJump 或 ProxyCommand。两者同时配置时 ProxyJump 优先。
+// 调用方需持有 m.mu。
+func (m *Manager) applyTransport(cfg *ConnectionConfig, alias string, host *types.SSHHost) error {
+	jumps, err := m.jumpHostsFor(alias, host)
+	if err != nil {
+		return err
+	}
+	if len(jumps) > 0 {
+		cfg.JumpHosts = jumps
+		cfg.ProxyCommand = ""
+		return nil
+	}
+	cfg.ProxyCommand = m.proxyCommandFor(alias, host)
+	return nil
+}
+
+// dialViaJumpHosts 依次连接各跳板机，并通过最后一跳建立到 addr 的 SSH 连接。
+// 返回的客户端关闭后，所有中间连接也会随之关闭。
+func dialViaJumpHosts(hops []*ConnectionConfig, addr string, clientConfig *ssh.ClientConfig, policy ConnectionPolicy) (*ssh.Client, error) {
+	if policy.Validate() != nil {
+		policy = DefaultConnectionPolicy()
+	}
+
+	var clients []*ssh.Client
+	closeAll := func() {
+		for i := len(clients) - 1; i >= 0; i-- {
+			clients[i].Close()
+		}
+	}
+
+	first := hops[0]
+	logger.Printf("Dialing jump host %s", first.Name)
+	client, err := Dial(first)
+	if err != nil {
+		return nil, fmt.Errorf("jump host '%s': %w", first.Name, err)
+	}
+	clients = append(clients, client)
+
+	// 依次通过上一跳建立到下一跳的连接，最后一个目标是 addr 本身
+	for i := 1; i <= len(hops); i++ {
+		nextAddr, nextConfig, nextPolicy, nextName := addr, clientConfig, policy, addr
+		if i < len(hops) {
+			hop := hops[i]
+			nextAddr = net.JoinHostPort(hop.HostName, hop.Port)
+			nextConfig, nextPolicy, nextName = hop.ClientConfig, hop.Policy, hop.Name
+		}
+		logger.Printf("Dialing %s via jump host %s", nextName, hops[i-1].Name)
+
+		conn, err := clients[len(clients)-1].Dial("tcp", nextAddr)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to reach %s via jump host '%s': %w", nextName, hops[i-1].Name, err)
+		}
+		next, err := newClientConn(conn, nextAddr, nextConfig, nextPolicy)
+		if err != nil {
+			closeAll()
+			if i < len(hops) {
+				return nil, fmt.Errorf("jump host '%s': %w", nextName, err)
+			}
+			return nil, err
+		}
+		clients = append(clients, next)
+	}
+
+	target := clients[len(clients)-1]
+	go func() {
+		_ = target.Wait()
+		closeAll()
+	}()
+	return target, nil
+}
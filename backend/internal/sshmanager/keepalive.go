@@ -2,38 +2,166 @@ package sshmanager
 
 import (
 	"context"
-	"log"
+	"strconv"
+	"strings"
 	"time"
 
+	"devtools/backend/pkg/sshconfig"
+
 	"golang.org/x/crypto/ssh"
 )
 
 const (
-	// SSHKeepAliveInterval is the interval for sending SSH keep-alive messages.
+	// SSHKeepAliveInterval is the default interval for sending SSH keep-alive messages.
 	SSHKeepAliveInterval = 15 * time.Second
-	// keepAliveRequestTimeout is the timeout for the keep-alive request itself.
-	// It must be shorter than SSHKeepAliveInterval.
+	// DefaultKeepAliveCountMax is the default number of consecutive unanswered
+	// keep-alives after which the connection is considered dead (same as OpenSSH).
+	DefaultKeepAliveCountMax = 3
+	// keepAliveRequestTimeout is the upper bound for the keep-alive request itself.
+	// The effective timeout is never longer than the keep-alive interval.
 	keepAliveRequestTimeout = 10 * time.Second
 )
 
+// KeepAliveSettings describes the keep-alive policy of a connection. It mirrors
+// ServerAliveInterval and ServerAliveCountMax from ssh_config.
+// A zero Interval means "unset"; Disabled is an explicit "ServerAliveInterval 0".
+type KeepAliveSettings struct {
+	Interval time.Duration `json:"interval"`
+	CountMax int           `json:"countMax"`
+	Disabled bool          `json:"disabled,omitempty"`
+}
+
+// DefaultKeepAliveSettings returns the keep-alive policy used when neither
+// ssh_config nor the app settings provide one.
+func DefaultKeepAliveSettings() KeepAliveSettings {
+	return KeepAliveSettings{Interval: SSHKeepAliveInterval, CountMax: DefaultKeepAliveCountMax}
+}
+
+// withDefaults fills zero or invalid fields with the defaults. A disabled policy keeps a zero interval.
+func (s KeepAliveSettings) withDefaults() KeepAliveSettings {
+	if s.Disabled {
+		s.Interval = 0
+	} else if s.Interval <= 0 {
+		s.Interval = SSHKeepAliveInterval
+	}
+	if s.CountMax <= 0 {
+		s.CountMax = DefaultKeepAliveCountMax
+	}
+	return s
+}
+
+// merge returns s with every set field of override applied on top. An override that
+// disables keep-alives or sets an interval replaces both the interval and the disabled state of s.
+func (s KeepAliveSettings) merge(override KeepAliveSettings) KeepAliveSettings {
+	if override.Disabled {
+		s.Interval, s.Disabled = 0, true
+	} else if override.Interval > 0 {
+		s.Interval, s.Disabled = override.Interval, false
+	}
+	if override.CountMax > 0 {
+		s.CountMax = override.CountMax
+	}
+	return s
+}
+
+// keepAliveFromParams parses ServerAliveInterval (an OpenSSH time value such as 30 or 1m30s)
+// and ServerAliveCountMax. As in OpenSSH, "ServerAliveInterval 0" disables keep-alives.
+// Missing or malformed values are left as zero so that defaults apply.
+func keepAliveFromParams(interval, countMax string) KeepAliveSettings {
+	var s KeepAliveSettings
+	if strings.TrimSpace(interval) != "" {
+		if d, err := sshconfig.ParseTimeValue(interval); err != nil {
+			logger.Printf("Warning: ignoring invalid ServerAliveInterval %q: %v", interval, err)
+		} else if d == 0 {
+			s.Disabled = true
+		} else {
+			s.Interval = d
+		}
+	}
+	if count, err := strconv.Atoi(strings.TrimSpace(countMax)); err == nil && count > 0 {
+		s.CountMax = count
+	}
+	return s
+}
+
+// SetKeepAliveOverride sets a global keep-alive policy (from the app settings)
+// that takes precedence over ssh_config. Zero fields mean "no override".
+func (m *Manager) SetKeepAliveOverride(settings KeepAliveSettings) {
+	m.overrideMu.Lock()
+	defer m.overrideMu.Unlock()
+	m.keepAliveOverride = settings
+}
+
+// KeepAliveForHost returns the effective keep-alive policy for a host alias.
+func (m *Manager) KeepAliveForHost(alias string) KeepAliveSettings {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keepAliveForHost(alias)
+}
+
+// keepAliveForHost is the lock-free version of KeepAliveForHost.
+// The caller must hold m.mu. An empty alias yields the defaults plus the global override.
+func (m *Manager) keepAliveForHost(alias string) KeepAliveSettings {
+	var fromConfig KeepAliveSettings
+	if alias != "" {
+		effective := m.manager.ResolveHost(alias)
+		fromConfig = keepAliveFromParams(effective.Get("ServerAliveInterval"), effective.Get("ServerAliveCountMax"))
+	}
+
+	m.overrideMu.RLock()
+	override := m.keepAliveOverride
+	m.overrideMu.RUnlock()
+
+	return fromConfig.merge(override).withDefaults()
+}
+
 // StartKeepAlive periodically sends keep-alive requests to the SSH server
-// to actively detect dead connections. If a request fails or times out, it closes the client.
+// to actively detect dead connections. The connection is closed when a request
+// fails, or when settings.CountMax consecutive requests time out.
 // This should be run in its own goroutine.
 // The original implementation was vulnerable to the SendRequest call blocking indefinitely
 // in certain network failure scenarios (e.g., a "half-open" connection), which would
 // prevent the keep-alive from detecting the dead connection. This version adds a timeout
 // to the request itself.
-func StartKeepAlive(client *ssh.Client, ctx context.Context) {
-	ticker := time.NewTicker(SSHKeepAliveInterval)
+// While the machine is offline (see IsOffline) keep-alives are paused instead of counted
+// as missed, so a short outage does not tear down every connection at once.
+func StartKeepAlive(client *ssh.Client, ctx context.Context, settings KeepAliveSettings) {
+	StartKeepAliveWithLatency(client, ctx, settings, nil)
+}
+
+// StartKeepAliveWithLatency 与 StartKeepAlive 相同，并在每次 keep-alive 得到应答后
+// 以请求的往返时间调用 onRTT（可以为 nil），用于展示连接的延迟
+func StartKeepAliveWithLatency(client *ssh.Client, ctx context.Context, settings KeepAliveSettings, onRTT func(time.Duration)) {
+	settings = settings.withDefaults()
+	if settings.Disabled {
+		// ServerAliveInterval 0: like ssh, rely on TCP to notice a dead connection
+		return
+	}
+	requestTimeout := keepAliveRequestTimeout
+	if settings.Interval < requestTimeout {
+		requestTimeout = settings.Interval
+	}
+
+	stats := statsFor(client)
+	stats.setInterval(settings.Interval)
+
+	ticker := time.NewTicker(settings.Interval)
 	defer ticker.Stop()
 
+	missed := 0
 	for {
 		select {
 		case <-ticker.C:
+			if IsOffline() {
+				missed = 0
+				stats.recordPaused()
+				continue
+			}
 			// We run the SendRequest in a separate goroutine so we can time it out.
 			// If SendRequest blocks, the original implementation would block this
 			// whole keep-alive goroutine, defeating its purpose.
 			errC := make(chan error, 1)
+			sentAt := time.Now()
 			go func() {
 				_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
 				errC <- err
@@ -42,15 +170,26 @@ func StartKeepAlive(client *ssh.Client, ctx context.Context) {
 			select {
 			case err := <-errC:
 				if err != nil {
-					log.Printf("SSH keep-alive for client %s failed: %v. Closing connection.", client.RemoteAddr(), err)
+					logger.Printf("SSH keep-alive for client %s failed: %v. Closing connection.", client.RemoteAddr(), err)
 					client.Close()
 					return
 				}
-				// Keep-alive successful, continue the loop.
-			case <-time.After(keepAliveRequestTimeout):
-				log.Printf("SSH keep-alive for client %s timed out after %s. Closing connection.", client.RemoteAddr(), keepAliveRequestTimeout)
-				client.Close()
-				return
+				// Keep-alive successful, reset the counter and continue the loop.
+				missed = 0
+				rtt := time.Since(sentAt)
+				stats.recordRTT(rtt)
+				if onRTT != nil {
+					onRTT(rtt)
+				}
+			case <-time.After(requestTimeout):
+				missed++
+				stats.recordMiss()
+				if missed >= settings.CountMax {
+					logger.Printf("SSH keep-alive for client %s timed out %d times in a row. Closing connection.", client.RemoteAddr(), missed)
+					client.Close()
+					return
+				}
+				logger.Printf("SSH keep-alive for client %s timed out after %s (%d/%d).", client.RemoteAddr(), requestTimeout, missed, settings.CountMax)
 			case <-ctx.Done():
 				// The parent context was cancelled (e.g., tunnel is shutting down).
 				return
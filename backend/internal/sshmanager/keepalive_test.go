@@ -0,0 +1,73 @@
+package sshmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestKeepAliveFromParams 测试按 OpenSSH 的时间格式解析 ServerAliveInterval，显式的 0 表示关闭保活
+func TestKeepAliveFromParams(t *testing.T) {
+	tests := []struct {
+		interval, countMax string
+		want               KeepAliveSettings
+	}{
+		{"", "", KeepAliveSettings{}},
+		{"30", "5", KeepAliveSettings{Interval: 30 * time.Second, CountMax: 5}},
+		{"1m", "", KeepAliveSettings{Interval: time.Minute}},
+		{"1m30s", "", KeepAliveSettings{Interval: 90 * time.Second}},
+		{"0", "", KeepAliveSettings{Disabled: true}},
+		{"abc", "x", KeepAliveSettings{}},
+		{"", "0", KeepAliveSettings{}},
+	}
+	for _, tt := range tests {
+		if got := keepAliveFromParams(tt.interval, tt.countMax); got != tt.want {
+			t.Errorf("keepAliveFromParams(%q, %q) = %+v, want %+v", tt.interval, tt.countMax, got, tt.want)
+		}
+	}
+}
+
+// TestKeepAliveSettingsMerge 测试应用设置覆盖 ssh_config 时区分“关闭”与“未设置”，并补上默认值
+func TestKeepAliveSettingsMerge(t *testing.T) {
+	tests := []struct {
+		name             string
+		config, override KeepAliveSettings
+		want             KeepAliveSettings
+	}{
+		{"defaults", KeepAliveSettings{}, KeepAliveSettings{}, DefaultKeepAliveSettings()},
+		{"config interval", KeepAliveSettings{Interval: time.Minute}, KeepAliveSettings{}, KeepAliveSettings{Interval: time.Minute, CountMax: DefaultKeepAliveCountMax}},
+		{"config disabled", KeepAliveSettings{Disabled: true}, KeepAliveSettings{}, KeepAliveSettings{CountMax: DefaultKeepAliveCountMax, Disabled: true}},
+		{"override enables", KeepAliveSettings{Disabled: true}, KeepAliveSettings{Interval: 20 * time.Second}, KeepAliveSettings{Interval: 20 * time.Second, CountMax: DefaultKeepAliveCountMax}},
+		{"override disables", KeepAliveSettings{Interval: time.Minute, CountMax: 5}, KeepAliveSettings{Disabled: true}, KeepAliveSettings{CountMax: 5, Disabled: true}},
+		{"override count only", KeepAliveSettings{Disabled: true}, KeepAliveSettings{CountMax: 7}, KeepAliveSettings{CountMax: 7, Disabled: true}},
+	}
+	for _, tt := range tests {
+		if got := tt.config.merge(tt.override).withDefaults(); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestKeepAliveForHost 测试主机的保活策略取自 ssh_config 的生效配置
+func TestKeepAliveForHost(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	content := "Host quiet\n    ServerAliveInterval 0\n\nHost slow\n    ServerAliveInterval 2m\n    ServerAliveCountMax 2\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := m.KeepAliveForHost("quiet"); !got.Disabled || got.Interval != 0 {
+		t.Errorf("ServerAliveInterval 0 should disable keep-alives, got %+v", got)
+	}
+	if got := m.KeepAliveForHost("slow"); got.Interval != 2*time.Minute || got.CountMax != 2 {
+		t.Errorf("ServerAliveInterval 2m should be honoured, got %+v", got)
+	}
+	if got := m.KeepAliveForHost("other"); got != DefaultKeepAliveSettings() {
+		t.Errorf("hosts without ServerAliveInterval should use the defaults, got %+v", got)
+	}
+}
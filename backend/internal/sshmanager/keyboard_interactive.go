@@ -0,0 +1,99 @@
+package sshmanager
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"devtools/backend/internal/types"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyboardInteractiveTimeout 是等待用户回答一轮 keyboard-interactive 问题的最长时间
+const KeyboardInteractiveTimeout = 2 * time.Minute
+
+// ErrNoKeyboardInteractiveHandler 表示服务器要求交互式输入，但当前没有可以向用户提问的处理器
+var ErrNoKeyboardInteractiveHandler = errors.New("server requires keyboard-interactive input, but no prompt handler is available")
+
+// KeyboardInteractivePrompt 是服务器在 keyboard-interactive 认证中提出的一个问题
+type KeyboardInteractivePrompt struct {
+	Text string `json:"text"`
+	Echo bool   `json:"echo"` // false 表示输入需要隐藏（密码、OTP 等）
+}
+
+// KeyboardInteractiveChallenge 是服务器发来的一轮问题
+type KeyboardInteractiveChallenge struct {
+	Alias       string
+	User        string
+	Name        string
+	Instruction string
+	Prompts     []KeyboardInteractivePrompt
+}
+
+// KeyboardInteractiveHandler 将一轮问题转交给用户，并按顺序返回每个问题的答案
+type KeyboardInteractiveHandler func(challenge KeyboardInteractiveChallenge) ([]string, error)
+
+// SetKeyboardInteractiveHandler 设置向用户转发 keyboard-interactive 问题（如 2FA/OTP）的处理器。
+// 之后构建的连接配置都会提供 keyboard-interactive 认证。
+func (m *Manager) SetKeyboardInteractiveHandler(handler KeyboardInteractiveHandler) {
+	m.overrideMu.Lock()
+	defer m.overrideMu.Unlock()
+	m.kbdInteractiveHandler = handler
+}
+
+func (m *Manager) getKeyboardInteractiveHandler() KeyboardInteractiveHandler {
+	m.overrideMu.RLock()
+	defer m.overrideMu.RUnlock()
+	return m.kbdInteractiveHandler
+}
+
+// keyboardInteractiveAuth 构建 keyboard-interactive 认证方法。
+// 只有一个隐藏输入的密码问题时，直接使用已知的密码作答（很多服务器只开放这种方式的密码认证）；
+// 其余问题（验证码、OTP 等）转交给处理器。没有密码也没有处理器时返回 nil。
+func (m *Manager) keyboardInteractiveAuth(host *types.SSHHost, passwords []string, trace *authTrace) ssh.AuthMethod {
+	handler := m.getKeyboardInteractiveHandler()
+	if handler == nil && len(passwords) == 0 {
+		return nil
+	}
+
+	return ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		trace.set("keyboard-interactive")
+		// 服务器可能发送不含问题的一轮（只有提示信息），直接回复空答案
+		if len(questions) == 0 {
+			return []string{}, nil
+		}
+		if len(questions) == 1 && !echos[0] && len(passwords) > 0 &&
+			strings.Contains(strings.ToLower(questions[0]), "password") {
+			return []string{passwords[0]}, nil
+		}
+		if handler == nil {
+			return nil, ErrNoKeyboardInteractiveHandler
+		}
+
+		challenge := KeyboardInteractiveChallenge{
+			Alias:       host.Alias,
+			User:        host.User,
+			Name:        name,
+			Instruction: instruction,
+			Prompts:     make([]KeyboardInteractivePrompt, len(questions)),
+		}
+		for i, q := range questions {
+			challenge.Prompts[i] = KeyboardInteractivePrompt{Text: q, Echo: echos[i]}
+		}
+		logger.Printf("Host %s requested keyboard-interactive input (%d prompts), forwarding to user", host.Alias, len(questions))
+		return handler(challenge)
+	})
+}
+
+// dialPolicyFor 返回建立连接时使用的策略。
+// 可以向用户提问时，认证超时需要覆盖用户作答的时间，否则握手会在用户输入验证码前超时。
+func (m *Manager) dialPolicyFor(alias string) ConnectionPolicy {
+	policy := m.ConnectionPolicyFor(alias)
+	if m.getKeyboardInteractiveHandler() == nil || policy.AuthTimeoutSeconds == 0 {
+		return policy
+	}
+	// 不超过 Validate 允许的上限，否则 DialWithPolicy 会回退到默认策略
+	policy.AuthTimeoutSeconds = min(policy.AuthTimeoutSeconds+int(KeyboardInteractiveTimeout/time.Second), 600)
+	return policy
+}
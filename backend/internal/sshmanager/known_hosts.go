@@ -0,0 +1,172 @@
+package sshmanager
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skeema/knownhosts"
+	"golang.org/x/crypto/ssh"
+	xknownhosts "golang.org/x/crypto/ssh/knownhosts"
+)
+
+// hashedHostPrefix 是 OpenSSH 哈希主机名（HashKnownHosts yes）的前缀，格式为 |1|base64(salt)|base64(hmac)
+const hashedHostPrefix = "|1|"
+
+// knownHostsPath 返回与 ssh_config 同目录的 known_hosts 文件路径。
+// 调用方可能持有也可能不持有 m.mu，因此读取 configPath 的副本。
+func (m *Manager) knownHostsPath() string {
+	return filepath.Join(filepath.Dir(m.currentConfigPath()), "known_hosts")
+}
+
+// hashKnownHostsFor 判断 alias 的生效配置中是否设置了 HashKnownHosts yes
+func (m *Manager) hashKnownHostsFor(alias string) bool {
+	if alias == "" {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return strings.EqualFold(m.manager.ResolveHost(alias).Get("HashKnownHosts"), "yes")
+}
+
+// knownHostsLine 生成一行 known_hosts 记录，hashed 为 true 时主机名以 HMAC-SHA1 哈希形式写入
+func knownHostsLine(address string, key ssh.PublicKey, hashed bool) string {
+	if !hashed {
+		return knownhosts.Line([]string{address}, key)
+	}
+	return xknownhosts.HashHostname(knownhosts.Normalize(address)) + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+// hostPatternMatches 判断 known_hosts 中的一个主机字段是否就是 address（已规范化），支持哈希形式
+func hostPatternMatches(pattern, address string) bool {
+	if !strings.HasPrefix(pattern, hashedHostPrefix) {
+		return pattern == address
+	}
+	parts := strings.Split(pattern[len(hashedHostPrefix):], "|")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(address))
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// knownHostsHasKey 扫描 known_hosts，判断 address 是否已经记录了 key（明文与哈希记录都会匹配）
+func knownHostsHasKey(path, address string, key ssh.PublicKey) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	address = knownhosts.Normalize(address)
+	want := key.Marshal()
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// 空行、注释和无法解析的行都会返回错误，跳过即可
+		marker, hosts, pubKey, _, _, err := ssh.ParseKnownHosts(scanner.Bytes())
+		if err != nil || marker != "" || !bytes.Equal(pubKey.Marshal(), want) {
+			continue
+		}
+		for _, pattern := range hosts {
+			if hostPatternMatches(pattern, address) {
+				return true, nil
+			}
+		}
+	}
+	return false, scanner.Err()
+}
+
+// HashKnownHosts 将 known_hosts 中的明文主机名改写为哈希形式（相当于 ssh-keygen -H），
+// 返回被哈希的主机名数量。原文件会先备份为 known_hosts.old。
+// 带通配符或否定模式（*、?、!）的行无法哈希，保持原样。
+func (m *Manager) HashKnownHosts() (int, error) {
+	path := m.knownHostsPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	var out strings.Builder
+	hashed := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		lines := hashKnownHostsLine(line)
+		if len(lines) != 1 || lines[0] != line {
+			hashed += len(lines)
+		}
+		for _, l := range lines {
+			out.WriteString(l)
+			out.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+	if hashed == 0 {
+		return 0, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat known_hosts: %w", err)
+	}
+	if err := os.WriteFile(path+".old", data, info.Mode().Perm()); err != nil {
+		return 0, fmt.Errorf("failed to back up known_hosts: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(out.String()), info.Mode().Perm()); err != nil {
+		return 0, fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+
+	logger.Printf("Hashed %d host names in %s (backup: %s.old)", hashed, path, path)
+	return hashed, nil
+}
+
+// hashKnownHostsLine 返回 line 哈希后的结果：每个明文主机名各占一行。
+// 注释、空行、@cert-authority/@revoked 行、已哈希的行以及带通配符的行原样返回。
+func hashKnownHostsLine(line string) []string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "@") {
+		return []string{line}
+	}
+	sep := strings.IndexAny(trimmed, " \t")
+	if sep < 0 {
+		return []string{line}
+	}
+	hostsField, rest := trimmed[:sep], trimmed[sep+1:]
+	if strings.HasPrefix(hostsField, hashedHostPrefix) || strings.ContainsAny(hostsField, "*?!") {
+		return []string{line}
+	}
+
+	hosts := strings.Split(hostsField, ",")
+	lines := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+		lines = append(lines, xknownhosts.HashHostname(host)+" "+strings.TrimSpace(rest))
+	}
+	return lines
+}
@@ -0,0 +1,193 @@
+package sshmanager
+
+import (
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NetworkPollInterval 是检查网络状态的默认间隔
+const NetworkPollInterval = 3 * time.Second
+
+// networkOffline 由 NetworkMonitor 维护；没有运行监视器时总是在线
+var networkOffline atomic.Bool
+
+// IsOffline 报告本机当前是否离线。保活与建连重试在离线期间暂停，避免产生大量错误。
+func IsOffline() bool {
+	return networkOffline.Load()
+}
+
+// NetworkState 是一次网络状态检查的结果
+type NetworkState struct {
+	Online       bool     `json:"online"`
+	Interfaces   []string `json:"interfaces"`             // 已启用且有地址的非回环网卡
+	DefaultRoute string   `json:"defaultRoute,omitempty"` // 访问外网时使用的本地地址，为空表示没有默认路由
+	Since        string   `json:"since"`                  // RFC3339，状态开始的时间
+}
+
+// equal 比较两个状态是否相同，忽略 Since
+func (s NetworkState) equal(other NetworkState) bool {
+	return s.Online == other.Online && s.DefaultRoute == other.DefaultRoute && slices.Equal(s.Interfaces, other.Interfaces)
+}
+
+// NetworkMonitor 定期检查网卡与默认路由，在状态变化时更新 IsOffline 并调用回调。
+// 只有没有任何可用网卡时才视为离线：没有默认路由的局域网仍然可以连接局域网内的主机。
+type NetworkMonitor struct {
+	interval time.Duration
+	probe    func() NetworkState
+	onChange func(previous, current NetworkState)
+
+	mu     sync.Mutex
+	state  NetworkState
+	stopCh chan struct{}
+}
+
+// NewNetworkMonitor 创建一个网络状态监视器，onChange 在每次状态变化时被调用（不包括第一次检查）
+func NewNetworkMonitor(onChange func(previous, current NetworkState)) *NetworkMonitor {
+	return &NetworkMonitor{
+		interval: NetworkPollInterval,
+		probe:    probeNetwork,
+		onChange: onChange,
+		state:    NetworkState{Online: true},
+	}
+}
+
+// Start 立即检查一次网络状态，之后定期检查，直到 Stop 被调用
+func (m *NetworkMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopCh != nil {
+		return
+	}
+	m.state = m.probe()
+	m.state.Since = time.Now().Format(time.RFC3339)
+	networkOffline.Store(!m.state.Online)
+	if !m.state.Online {
+		logger.Printf("Warning: no network interface is available, keep-alives and connection retries are paused.")
+	}
+
+	m.stopCh = make(chan struct{})
+	go m.loop(m.stopCh)
+}
+
+// Stop 停止检查，并恢复为在线状态
+func (m *NetworkMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopCh != nil {
+		close(m.stopCh)
+		m.stopCh = nil
+	}
+	networkOffline.Store(false)
+}
+
+// State 返回最近一次检查的网络状态
+func (m *NetworkMonitor) State() NetworkState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state := m.state
+	state.Interfaces = slices.Clone(state.Interfaces)
+	return state
+}
+
+func (m *NetworkMonitor) loop(stopCh chan struct{}) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.check(stopCh)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// check 检查一次网络状态，变化时更新离线标记并调用回调
+func (m *NetworkMonitor) check(stopCh chan struct{}) {
+	current := m.probe()
+
+	m.mu.Lock()
+	if m.stopCh != stopCh {
+		m.mu.Unlock()
+		return // 检查期间监视器已停止
+	}
+	previous := m.state
+	if current.equal(previous) {
+		m.mu.Unlock()
+		return
+	}
+	current.Since = previous.Since
+	if current.Online != previous.Online {
+		current.Since = time.Now().Format(time.RFC3339)
+	}
+	m.state = current
+	networkOffline.Store(!current.Online)
+	m.mu.Unlock()
+
+	switch {
+	case previous.Online && !current.Online:
+		logger.Printf("Warning: network went offline, keep-alives and connection retries are paused.")
+	case !previous.Online && current.Online:
+		logger.Printf("Network is back online (interfaces: %v, default route via %q).", current.Interfaces, current.DefaultRoute)
+	default:
+		logger.Printf("Network changed (interfaces: %v, default route via %q).", current.Interfaces, current.DefaultRoute)
+	}
+	if m.onChange != nil {
+		m.onChange(previous, current)
+	}
+}
+
+// probeNetwork 列出已启用且有地址的非回环网卡，并查找默认路由使用的本地地址
+func probeNetwork() NetworkState {
+	var state NetworkState
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		logger.Printf("Warning: could not list network interfaces: %v", err)
+		// 无法判断时视为在线，避免误报
+		return NetworkState{Online: true}
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil || !hasUsableAddr(addrs) {
+			continue
+		}
+		state.Interfaces = append(state.Interfaces, iface.Name)
+	}
+	slices.Sort(state.Interfaces)
+	state.Online = len(state.Interfaces) > 0
+	if state.Online {
+		state.DefaultRoute = defaultRouteAddr()
+	}
+	return state
+}
+
+// hasUsableAddr 判断地址列表中是否有可以用来通信的地址（排除链路本地地址）
+func hasUsableAddr(addrs []net.Addr) bool {
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && ipNet.IP.IsGlobalUnicast() {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRouteAddr 返回访问外网时使用的本地地址。UDP 的 "连接" 只查询路由表，不会发送任何数据。
+func defaultRouteAddr() string {
+	for _, target := range []string{"192.0.2.1:9", "[2001:db8::1]:9"} {
+		conn, err := net.Dial("udp", target)
+		if err != nil {
+			continue
+		}
+		addr := conn.LocalAddr().(*net.UDPAddr).IP.String()
+		conn.Close()
+		return addr
+	}
+	return ""
+}
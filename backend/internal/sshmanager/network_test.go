@@ -0,0 +1,51 @@
+package sshmanager
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestNetworkMonitor 测试网络状态变化时更新离线标记并调用回调，离线期间建连不再重试
+func TestNetworkMonitor(t *testing.T) {
+	state := NetworkState{Online: true, Interfaces: []string{"en0"}, DefaultRoute: "192.168.1.2"}
+	var changes []NetworkState
+	m := NewNetworkMonitor(func(previous, current NetworkState) { changes = append(changes, current) })
+	m.interval = time.Hour
+	m.probe = func() NetworkState { return state }
+	m.Start()
+	defer m.Stop()
+	stopCh := m.stopCh
+
+	m.check(stopCh)
+	if len(changes) != 0 || IsOffline() {
+		t.Fatalf("an unchanged network should not be reported: %+v", changes)
+	}
+
+	state = NetworkState{}
+	m.check(stopCh)
+	if len(changes) != 1 || changes[0].Online || !IsOffline() || m.State().Online {
+		t.Fatalf("going offline should be reported: %+v", changes)
+	}
+
+	// 离线时第一次尝试仍然进行，失败后不再按策略重试
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	policy := DefaultConnectionPolicy()
+	policy.RetryCount, policy.RetryBackoffMillis = 5, 60000
+	_, err = DialWithPolicy(addr, nil, policy)
+	if !errors.Is(err, ErrOffline) || Classify(err) != KindOffline {
+		t.Errorf("dial retries should stop while offline, got %v", err)
+	}
+
+	state = NetworkState{Online: true, Interfaces: []string{"wlan0"}, DefaultRoute: "10.0.0.5"}
+	m.check(stopCh)
+	if len(changes) != 2 || !changes[1].Online || IsOffline() {
+		t.Errorf("coming back online should be reported: %+v", changes)
+	}
+}
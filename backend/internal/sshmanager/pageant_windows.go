@@ -0,0 +1,133 @@
+//go:build windows
+
+package sshmanager
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Pageant 的共享内存协议：请求写入一个命名的文件映射，然后通过 WM_COPYDATA 把映射的名称发给 Pageant 窗口，
+// Pageant 处理后把响应写回同一个映射。
+const (
+	pageantMaxMessageLength = 8192
+	pageantCopyDataID       = 0x804e50ba
+	wmCopyData              = 0x004A
+)
+
+var (
+	user32              = windows.NewLazySystemDLL("user32.dll")
+	procFindWindowW     = user32.NewProc("FindWindowW")
+	procSendMessageW    = user32.NewProc("SendMessageW")
+	pageantMappingCount atomic.Uint32
+)
+
+// copyDataStruct 对应 Win32 的 COPYDATASTRUCT
+type copyDataStruct struct {
+	dwData uintptr
+	cbData uint32
+	lpData uintptr
+}
+
+// pageantWindow 返回 Pageant 的窗口句柄，没有运行时为 0
+func pageantWindow() uintptr {
+	name, err := windows.UTF16PtrFromString("Pageant")
+	if err != nil {
+		return 0
+	}
+	hwnd, _, _ := procFindWindowW.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(name)))
+	return hwnd
+}
+
+// pageantAvailable 判断 Pageant 是否正在运行
+func pageantAvailable() bool {
+	return procFindWindowW.Find() == nil && pageantWindow() != 0
+}
+
+// pageantQuery 把一个完整的代理请求（含长度前缀）发给 Pageant 并返回响应
+func pageantQuery(request []byte) ([]byte, error) {
+	if len(request) > pageantMaxMessageLength {
+		return nil, fmt.Errorf("pageant: request of %d bytes is too large", len(request))
+	}
+	hwnd := pageantWindow()
+	if hwnd == 0 {
+		return nil, fmt.Errorf("pageant is not running")
+	}
+
+	mapName := fmt.Sprintf("PageantRequest%08x%04x", windows.GetCurrentProcessId(), pageantMappingCount.Add(1)&0xffff)
+	mapNameUTF16, err := windows.UTF16PtrFromString(mapName)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := windows.CreateFileMapping(windows.InvalidHandle, nil, windows.PAGE_READWRITE, 0, pageantMaxMessageLength, mapNameUTF16)
+	if err != nil {
+		return nil, fmt.Errorf("pageant: CreateFileMapping failed: %w", err)
+	}
+	defer windows.CloseHandle(mapping)
+	view, err := windows.MapViewOfFile(mapping, windows.FILE_MAP_WRITE, 0, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("pageant: MapViewOfFile failed: %w", err)
+	}
+	defer windows.UnmapViewOfFile(view)
+	buf := unsafe.Slice((*byte)(unsafe.Add(nil, view)), pageantMaxMessageLength)
+	copy(buf, request)
+
+	// Pageant 按 ANSI 字符串读取映射名
+	name := append([]byte(mapName), 0)
+	cds := copyDataStruct{
+		dwData: pageantCopyDataID,
+		cbData: uint32(len(name)),
+		lpData: uintptr(unsafe.Pointer(&name[0])),
+	}
+	ret, _, _ := procSendMessageW.Call(hwnd, wmCopyData, 0, uintptr(unsafe.Pointer(&cds)))
+	runtime.KeepAlive(name)
+	if ret == 0 {
+		return nil, fmt.Errorf("pageant refused the request")
+	}
+
+	length := int(binary.BigEndian.Uint32(buf[:4])) + 4
+	if length > pageantMaxMessageLength {
+		return nil, fmt.Errorf("pageant: response of %d bytes is too large", length)
+	}
+	return append([]byte(nil), buf[:length]...), nil
+}
+
+// pageantConn 把 Pageant 的请求/响应协议包装成 agent.NewClient 使用的连接：
+// 写入的请求凑齐一条完整消息后发送给 Pageant，响应留给后续的 Read
+type pageantConn struct {
+	request  bytes.Buffer
+	response bytes.Reader
+}
+
+func (c *pageantConn) Write(p []byte) (int, error) {
+	c.request.Write(p)
+	data := c.request.Bytes()
+	if len(data) < 4 || len(data) < int(binary.BigEndian.Uint32(data[:4]))+4 {
+		return len(p), nil
+	}
+	response, err := pageantQuery(data)
+	c.request.Reset()
+	if err != nil {
+		return 0, err
+	}
+	c.response.Reset(response)
+	return len(p), nil
+}
+
+func (c *pageantConn) Read(p []byte) (int, error) {
+	if c.response.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.response.Read(p)
+}
+
+func (c *pageantConn) Close() error {
+	return nil
+}
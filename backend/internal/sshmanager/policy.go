@@ -0,0 +1,287 @@
+package sshmanager
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"devtools/backend/pkg/sshconfig"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 建连使用的地址族，取值与 ssh_config 的 AddressFamily 一致
+const (
+	AddressFamilyAny   = "any"
+	AddressFamilyInet  = "inet"  // 只使用 IPv4
+	AddressFamilyInet6 = "inet6" // 只使用 IPv6
+)
+
+// maxDialTimeoutSeconds 是建连超时的上限，ssh_config 中更大的 ConnectTimeout 会被截断
+const maxDialTimeoutSeconds = 300
+
+// ConnectionPolicy 定义了建立 SSH 连接时的超时与重试策略
+type ConnectionPolicy struct {
+	DialTimeoutSeconds int    `json:"dialTimeoutSeconds"`      // TCP 建连超时
+	AuthTimeoutSeconds int    `json:"authTimeoutSeconds"`      // SSH 握手与认证的超时
+	RetryCount         int    `json:"retryCount"`              // 网络类错误的重试次数，0 表示不重试
+	RetryBackoffMillis int    `json:"retryBackoffMs"`          // 首次重试前的等待时间，之后每次翻倍
+	AddressFamily      string `json:"addressFamily,omitempty"` // any | inet | inet6，为空时由系统选择
+}
+
+// DialOverrides 是单次连接对策略的覆盖（例如同步配置中单独设置的超时），零值字段不覆盖
+type DialOverrides struct {
+	ConnectTimeoutSeconds int    `json:"connectTimeoutSeconds,omitempty"`
+	AddressFamily         string `json:"addressFamily,omitempty"`
+}
+
+// Validate 检查覆盖值是否合法
+func (o DialOverrides) Validate() error {
+	if o.ConnectTimeoutSeconds < 0 || o.ConnectTimeoutSeconds > maxDialTimeoutSeconds {
+		return fmt.Errorf("connect timeout must be between 0 and %d seconds", maxDialTimeoutSeconds)
+	}
+	return validateAddressFamily(o.AddressFamily)
+}
+
+// WithOverrides 返回应用了 o 中非零字段的策略
+func (p ConnectionPolicy) WithOverrides(o DialOverrides) ConnectionPolicy {
+	if o.ConnectTimeoutSeconds > 0 {
+		p.DialTimeoutSeconds = min(o.ConnectTimeoutSeconds, maxDialTimeoutSeconds)
+	}
+	if o.AddressFamily != "" && validateAddressFamily(o.AddressFamily) == nil {
+		p.AddressFamily = o.AddressFamily
+	}
+	return p
+}
+
+func validateAddressFamily(family string) error {
+	switch family {
+	case "", AddressFamilyAny, AddressFamilyInet, AddressFamilyInet6:
+		return nil
+	}
+	return fmt.Errorf("invalid address family '%s', expected any, inet or inet6", family)
+}
+
+// DefaultConnectionPolicy 返回内置的默认连接策略
+func DefaultConnectionPolicy() ConnectionPolicy {
+	return ConnectionPolicy{
+		DialTimeoutSeconds: 10,
+		AuthTimeoutSeconds: 30,
+		RetryCount:         0,
+		RetryBackoffMillis: 500,
+	}
+}
+
+// Validate 检查策略中的值是否合法
+func (p ConnectionPolicy) Validate() error {
+	if p.DialTimeoutSeconds <= 0 || p.DialTimeoutSeconds > maxDialTimeoutSeconds {
+		return fmt.Errorf("dial timeout must be between 1 and %d seconds", maxDialTimeoutSeconds)
+	}
+	if p.AuthTimeoutSeconds < 0 || p.AuthTimeoutSeconds > 600 {
+		return fmt.Errorf("auth timeout must be between 0 and 600 seconds")
+	}
+	if p.RetryCount < 0 || p.RetryCount > 10 {
+		return fmt.Errorf("retry count must be between 0 and 10")
+	}
+	if p.RetryBackoffMillis < 0 || p.RetryBackoffMillis > 60000 {
+		return fmt.Errorf("retry backoff must be between 0 and 60000 milliseconds")
+	}
+	return validateAddressFamily(p.AddressFamily)
+}
+
+func (p ConnectionPolicy) dialTimeout() time.Duration {
+	return time.Duration(p.DialTimeoutSeconds) * time.Second
+}
+
+// network 返回按地址族建连时使用的网络名
+func (p ConnectionPolicy) network() string {
+	switch p.AddressFamily {
+	case AddressFamilyInet:
+		return "tcp4"
+	case AddressFamilyInet6:
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+func (p ConnectionPolicy) authTimeout() time.Duration {
+	return time.Duration(p.AuthTimeoutSeconds) * time.Second
+}
+
+func (p ConnectionPolicy) retryBackoff(attempt int) time.Duration {
+	return time.Duration(p.RetryBackoffMillis) * time.Millisecond * time.Duration(1<<(attempt-1))
+}
+
+// ConnectionPolicies 是全局策略与按主机别名覆盖的策略的集合。
+// 主机策略一旦设置，会完整替换全局策略。
+type ConnectionPolicies struct {
+	Global ConnectionPolicy            `json:"global"`
+	Hosts  map[string]ConnectionPolicy `json:"hosts,omitempty"`
+}
+
+// SetConnectionPolicies 替换当前的连接策略（通常在加载持久化配置后调用）
+func (m *Manager) SetConnectionPolicies(policies ConnectionPolicies) {
+	m.overrideMu.Lock()
+	defer m.overrideMu.Unlock()
+	hosts := make(map[string]ConnectionPolicy, len(policies.Hosts))
+	for alias, p := range policies.Hosts {
+		hosts[alias] = p
+	}
+	m.policies = ConnectionPolicies{Global: policies.Global, Hosts: hosts}
+}
+
+// GetConnectionPolicies 返回当前连接策略的副本
+func (m *Manager) GetConnectionPolicies() ConnectionPolicies {
+	m.overrideMu.RLock()
+	defer m.overrideMu.RUnlock()
+	hosts := make(map[string]ConnectionPolicy, len(m.policies.Hosts))
+	for alias, p := range m.policies.Hosts {
+		hosts[alias] = p
+	}
+	return ConnectionPolicies{Global: m.policies.Global, Hosts: hosts}
+}
+
+// ConnectionPolicyFor 返回某个别名生效的连接策略；alias 为空时返回全局策略
+func (m *Manager) ConnectionPolicyFor(alias string) ConnectionPolicy {
+	m.overrideMu.RLock()
+	defer m.overrideMu.RUnlock()
+	if p, ok := m.policies.Hosts[alias]; ok && alias != "" {
+		return p
+	}
+	return m.policies.Global
+}
+
+// withConfigDialOptions 把 alias 生效配置中的 ConnectTimeout 与 AddressFamily 应用到 policy。
+// 在应用内为该主机单独设置的策略优先于 ssh_config 的 ConnectTimeout。调用方需持有 m.mu。
+func (m *Manager) withConfigDialOptions(alias string, policy ConnectionPolicy) ConnectionPolicy {
+	if alias == "" {
+		return policy
+	}
+	effective := m.manager.ResolveHost(alias)
+
+	m.overrideMu.RLock()
+	_, hasHostPolicy := m.policies.Hosts[alias]
+	m.overrideMu.RUnlock()
+
+	var o DialOverrides
+	// ConnectTimeout 是 OpenSSH 的时间格式（如 30 或 1m），0 与未设置相同
+	if d, err := sshconfig.ParseTimeValue(effective.Get("ConnectTimeout")); err == nil && d > 0 && !hasHostPolicy {
+		o.ConnectTimeoutSeconds = int(d / time.Second)
+	}
+	if policy.AddressFamily == "" {
+		o.AddressFamily = strings.ToLower(strings.TrimSpace(effective.Get("AddressFamily")))
+	}
+	return policy.WithOverrides(o)
+}
+
+// DialPolicyForHost 返回连接一个不在 ssh_config 中定义别名的主机（例如同步配置中的主机）时使用的策略：
+// 全局策略加上 ssh_config 中匹配 hostName 的块（如 "Host *"）的 ConnectTimeout 与 AddressFamily
+func (m *Manager) DialPolicyForHost(hostName string) ConnectionPolicy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.withConfigDialOptions(hostName, m.ConnectionPolicyFor(""))
+}
+
+// Dial 按照连接配置中的策略建立 SSH 连接。
+// 配置了连接前命令时先在本地执行该命令，连接失败时其输出会附加到错误中。
+// 配置了跳板机时依次经由各跳板机连接；配置了 ProxyCommand 时通过该命令建立传输。
+func Dial(config *ConnectionConfig) (*ssh.Client, error) {
+	var output string
+	if config.PreConnect != nil {
+		var err error
+		if output, err = runPreConnect(config.Name, config.PreConnect); err != nil {
+			return nil, err
+		}
+	}
+	client, err := dialTransport(config)
+	if err != nil {
+		if output != "" {
+			return nil, fmt.Errorf("%w\npre-connect command output:\n%s", err, output)
+		}
+		return nil, err
+	}
+	// 按主机别名汇总保活统计，见 GetConnectionDiagnostics
+	setConnectionAlias(client, config.Name)
+	return client, nil
+}
+
+// dialTransport 选择直连、跳板机或 ProxyCommand 建立连接
+func dialTransport(config *ConnectionConfig) (*ssh.Client, error) {
+	addr := net.JoinHostPort(config.HostName, config.Port)
+	if len(config.JumpHosts) > 0 {
+		return dialViaJumpHosts(config.JumpHosts, addr, config.ClientConfig, config.Policy)
+	}
+	if config.ProxyCommand != "" {
+		return dialProxyCommand(config.ProxyCommand, addr, config.ClientConfig, config.Policy)
+	}
+	return DialWithPolicy(addr, config.ClientConfig, config.Policy)
+}
+
+// DialWithPolicy 建立一个 SSH 连接，应用策略中的建连超时、认证超时，
+// 并在遇到网络类错误时按指数退避重试。认证失败、主机密钥错误等不会重试；本机离线时也不再重试。
+func DialWithPolicy(addr string, clientConfig *ssh.ClientConfig, policy ConnectionPolicy) (*ssh.Client, error) {
+	if policy.Validate() != nil {
+		policy = DefaultConnectionPolicy()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= policy.RetryCount; attempt++ {
+		if attempt > 0 {
+			if IsOffline() {
+				logger.Printf("Not retrying SSH dial to %s while offline: %v", addr, lastErr)
+				return nil, fmt.Errorf("%w: %w", ErrOffline, lastErr)
+			}
+			backoff := policy.retryBackoff(attempt)
+			logger.Printf("Retrying SSH dial to %s in %s (attempt %d/%d): %v", addr, backoff, attempt, policy.RetryCount, lastErr)
+			time.Sleep(backoff)
+		}
+
+		client, err := dialOnce(addr, clientConfig, policy)
+		if err == nil {
+			return client, nil
+		}
+		lastErr = err
+		if !isRetryableDialError(err) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// dialOnce 执行一次建连和 SSH 握手
+func dialOnce(addr string, clientConfig *ssh.ClientConfig, policy ConnectionPolicy) (*ssh.Client, error) {
+	dialer := net.Dialer{Timeout: policy.dialTimeout()}
+	conn, err := dialer.Dial(policy.network(), addr)
+	if err != nil {
+		return nil, err
+	}
+	return newClientConn(conn, addr, clientConfig, policy)
+}
+
+// newClientConn 在一个已建立的传输连接上完成 SSH 握手，握手期间应用认证超时
+func newClientConn(conn net.Conn, addr string, clientConfig *ssh.ClientConfig, policy ConnectionPolicy) (*ssh.Client, error) {
+	if timeout := policy.authTimeout(); timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+	}
+	counted := &countingConn{Conn: conn}
+	sniffer := &kexinitSniffer{Conn: counted}
+	c, chans, reqs, err := ssh.NewClientConn(sniffer, addr, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// 握手完成后清除超时，避免影响长连接
+	_ = conn.SetDeadline(time.Time{})
+	client := ssh.NewClient(c, chans, reqs)
+	recordHandshake(client, sniffer, clientConfig)
+	trackConnection(client, counted, clientConfig)
+	return client, nil
+}
+
+// isRetryableDialError 判断一个错误是否为值得重试的网络错误
+func isRetryableDialError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,53 @@
+package sshmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWithConfigDialOptions 测试 ssh_config 中的 ConnectTimeout 与 AddressFamily 应用到建连策略，
+// 以及应用内的主机策略与单次覆盖的优先级
+func TestWithConfigDialOptions(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	content := "Host web\n    HostName 10.0.0.1\n    ConnectTimeout 3\n    AddressFamily inet6\n\nHost db\n    HostName 10.0.0.2\n    ConnectTimeout 3\n\nHost slow\n    HostName 10.0.0.3\n    ConnectTimeout 1m30s\n\nHost *\n    ConnectTimeout 1000\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostPolicy := DefaultConnectionPolicy()
+	hostPolicy.DialTimeoutSeconds = 20
+	m.SetConnectionPolicies(ConnectionPolicies{
+		Global: DefaultConnectionPolicy(),
+		Hosts:  map[string]ConnectionPolicy{"db": hostPolicy},
+	})
+
+	m.mu.RLock()
+	web := m.withConfigDialOptions("web", m.dialPolicyFor("web"))
+	db := m.withConfigDialOptions("db", m.dialPolicyFor("db"))
+	m.mu.RUnlock()
+
+	if web.DialTimeoutSeconds != 3 || web.AddressFamily != AddressFamilyInet6 || web.network() != "tcp6" {
+		t.Errorf("web should use ConnectTimeout 3 over IPv6, got %+v", web)
+	}
+	if db.DialTimeoutSeconds != 20 || db.network() != "tcp" {
+		t.Errorf("the app's host policy should take precedence over ConnectTimeout, got %+v", db)
+	}
+	if slow := m.DialPolicyForHost("slow"); slow.DialTimeoutSeconds != 90 {
+		t.Errorf("ConnectTimeout 1m30s should be 90 seconds, got %d", slow.DialTimeoutSeconds)
+	}
+	if other := m.DialPolicyForHost("example.com"); other.DialTimeoutSeconds != maxDialTimeoutSeconds {
+		t.Errorf("a ConnectTimeout above the limit should be capped, got %d", other.DialTimeoutSeconds)
+	}
+
+	overridden := web.WithOverrides(DialOverrides{ConnectTimeoutSeconds: 7, AddressFamily: AddressFamilyInet})
+	if overridden.DialTimeoutSeconds != 7 || overridden.network() != "tcp4" {
+		t.Errorf("per-call overrides should win, got %+v", overridden)
+	}
+	if err := (DialOverrides{AddressFamily: "ipx"}).Validate(); err == nil {
+		t.Error("an unknown address family should be rejected")
+	}
+}
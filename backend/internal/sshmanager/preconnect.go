@@ -0,0 +1,150 @@
+package sshmanager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"devtools/backend/internal/types"
+)
+
+const (
+	// defaultPreConnectTimeout 是未配置超时时本地命令的最长执行时间
+	defaultPreConnectTimeout = 10 * time.Second
+	// maxPreConnectOutput 是附加到连接错误中的命令输出的最大长度
+	maxPreConnectOutput = 4 * 1024
+)
+
+// PreConnectCommand 是连接某个主机之前在本地执行的命令，例如端口敲门（port knock）
+// 或检查 VPN 是否已连接。命令通过 shell 执行，支持 %h/%p/%r/%n 展开。
+type PreConnectCommand struct {
+	Command        string `json:"command"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty"` // 0 表示使用默认的 10 秒
+	// 成功条件：退出码为 0，且 ExpectOutput 非空时输出中包含该字符串
+	ExpectOutput string `json:"expectOutput,omitempty"`
+	// 为 true 时命令失败也继续连接（例如敲门工具总是返回非 0），输出仍会附加到连接错误中
+	IgnoreFailure bool `json:"ignoreFailure,omitempty"`
+	// 命令成功后等待多久再建立连接，给防火墙规则生效留出时间
+	DelayMillis int `json:"delayMs,omitempty"`
+}
+
+// Validate 检查命令配置是否合法
+func (c PreConnectCommand) Validate() error {
+	if strings.TrimSpace(c.Command) == "" {
+		return fmt.Errorf("pre-connect command cannot be empty")
+	}
+	if c.TimeoutSeconds < 0 || c.TimeoutSeconds > 300 {
+		return fmt.Errorf("pre-connect timeout must be between 0 and 300 seconds")
+	}
+	if c.DelayMillis < 0 || c.DelayMillis > 60000 {
+		return fmt.Errorf("pre-connect delay must be between 0 and 60000 milliseconds")
+	}
+	return nil
+}
+
+func (c PreConnectCommand) timeout() time.Duration {
+	if c.TimeoutSeconds <= 0 {
+		return defaultPreConnectTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// PreConnectError 表示连接前的本地命令失败，连接没有进行
+type PreConnectError struct {
+	Alias   string
+	Command string
+	Output  string
+	Err     error
+}
+
+func (e *PreConnectError) Error() string {
+	msg := fmt.Sprintf("pre-connect command for '%s' failed: %v", e.Alias, e.Err)
+	if e.Output != "" {
+		msg += "\ncommand output:\n" + e.Output
+	}
+	return msg
+}
+
+func (e *PreConnectError) Unwrap() error { return e.Err }
+
+// SetPreConnectCommands 替换按主机别名配置的连接前命令（通常在加载持久化配置后调用）
+func (m *Manager) SetPreConnectCommands(commands map[string]PreConnectCommand) {
+	m.overrideMu.Lock()
+	defer m.overrideMu.Unlock()
+	m.preConnect = make(map[string]PreConnectCommand, len(commands))
+	for alias, c := range commands {
+		m.preConnect[alias] = c
+	}
+}
+
+// GetPreConnectCommands 返回所有连接前命令的副本
+func (m *Manager) GetPreConnectCommands() map[string]PreConnectCommand {
+	m.overrideMu.RLock()
+	defer m.overrideMu.RUnlock()
+	commands := make(map[string]PreConnectCommand, len(m.preConnect))
+	for alias, c := range m.preConnect {
+		commands[alias] = c
+	}
+	return commands
+}
+
+// preConnectFor 返回 alias 的连接前命令（已展开 token），未配置时返回 nil
+func (m *Manager) preConnectFor(alias string, host *types.SSHHost) *PreConnectCommand {
+	m.overrideMu.RLock()
+	c, ok := m.preConnect[alias]
+	m.overrideMu.RUnlock()
+	if !ok || alias == "" {
+		return nil
+	}
+	c.Command = expandProxyCommand(c.Command, alias, host)
+	return &c
+}
+
+// runPreConnect 执行连接前命令，返回命令输出。命令失败且未设置 IgnoreFailure 时返回 *PreConnectError。
+func runPreConnect(name string, c *PreConnectCommand) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout())
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/c", c.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", c.Command)
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	logger.Printf("Running pre-connect command for %s: %s", name, c.Command)
+	start := time.Now()
+	err := cmd.Run()
+	output := strings.TrimSpace(out.String())
+	if len(output) > maxPreConnectOutput {
+		output = output[len(output)-maxPreConnectOutput:]
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("timed out after %s", c.timeout())
+	case err == nil && c.ExpectOutput != "" && !strings.Contains(out.String(), c.ExpectOutput):
+		err = fmt.Errorf("output does not contain %q", c.ExpectOutput)
+	}
+	if err != nil {
+		if !c.IgnoreFailure {
+			return output, &PreConnectError{Alias: name, Command: c.Command, Output: output, Err: err}
+		}
+		logger.Printf("Warning: pre-connect command for %s failed, continuing as configured: %v", name, err)
+	} else {
+		logger.Printf("Pre-connect command for %s succeeded in %s.", name, time.Since(start).Round(time.Millisecond))
+	}
+
+	if c.DelayMillis > 0 {
+		time.Sleep(time.Duration(c.DelayMillis) * time.Millisecond)
+	}
+	return output, nil
+}
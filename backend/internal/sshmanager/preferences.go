@@ -0,0 +1,78 @@
+package sshmanager
+
+import (
+	"net"
+	"time"
+
+	"devtools/backend/internal/settings"
+	"devtools/backend/internal/types"
+
+	"github.com/skeema/knownhosts"
+	"golang.org/x/crypto/ssh"
+)
+
+// ApplySettings 应用来自设置服务的偏好：保活策略、主机密钥策略、外部终端、只读主机与只读模式
+func (m *Manager) ApplySettings(s settings.Settings) {
+	m.SetLockedHosts(s.LockedHosts)
+	m.SetReadOnly(s.ReadOnly)
+	m.SetKeepAliveOverride(KeepAliveSettings{
+		Interval: time.Duration(s.KeepAliveIntervalSeconds) * time.Second,
+		CountMax: s.KeepAliveCountMax,
+	})
+
+	m.overrideMu.Lock()
+	defer m.overrideMu.Unlock()
+	m.hostKeyPolicy = s.HostKeyPolicy
+	m.externalTerminal = s.DefaultTerminal
+}
+
+// HostKeyPolicy 返回当前的主机密钥策略
+func (m *Manager) HostKeyPolicy() string {
+	m.overrideMu.RLock()
+	defer m.overrideMu.RUnlock()
+	if m.hostKeyPolicy == "" {
+		return settings.HostKeyPolicyAsk
+	}
+	return m.hostKeyPolicy
+}
+
+func (m *Manager) getExternalTerminal() string {
+	m.overrideMu.RLock()
+	defer m.overrideMu.RUnlock()
+	return m.externalTerminal
+}
+
+// SetHostKeyChangedHandler 设置主机密钥与 known_hosts 不一致（连接被拒绝）时的回调，
+// address 是 known_hosts 中记录的主机地址
+func (m *Manager) SetHostKeyChangedHandler(handler func(alias, address string)) {
+	m.overrideMu.Lock()
+	defer m.overrideMu.Unlock()
+	m.hostKeyChangedHandler = handler
+}
+
+func (m *Manager) getHostKeyChangedHandler() func(alias, address string) {
+	m.overrideMu.RLock()
+	defer m.overrideMu.RUnlock()
+	return m.hostKeyChangedHandler
+}
+
+// wrapHostKeyCallback 根据主机密钥策略包装 known_hosts 回调。
+// accept-new 时，未知主机的密钥会被自动写入 known_hosts；已变化的密钥在任何策略下都会被拒绝，并通知回调。
+func (m *Manager) wrapHostKeyCallback(host *types.SSHHost, cb ssh.HostKeyCallback) ssh.HostKeyCallback {
+	acceptNew := m.HostKeyPolicy() == settings.HostKeyPolicyAcceptNew
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := cb(hostname, remote, key)
+		switch {
+		case err == nil:
+		case knownhosts.IsHostKeyChanged(err):
+			logger.Printf("Warning: host key for %s (%s) does not match known_hosts", host.Alias, hostname)
+			if handler := m.getHostKeyChangedHandler(); handler != nil {
+				handler(host.Alias, hostname)
+			}
+		case acceptNew && knownhosts.IsHostUnknown(err):
+			logger.Printf("Host key policy is accept-new, trusting new host key for %s", host.Alias)
+			return m.AddHostKeyToKnownHosts(host, key)
+		}
+		return err
+	}
+}
@@ -0,0 +1,207 @@
+package sshmanager
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"devtools/backend/pkg/sshconfig"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// maxBannerLines 是读取服务器版本标识时最多读取的行数
+const maxBannerLines = 10
+
+// errProbeAborted 用于在探测认证方式时中止握手，不会真正提交任何凭据
+var errProbeAborted = errors.New("auth probe finished")
+
+// HostProbe 是对一个主机的连通性探测结果（不完成认证）
+type HostProbe struct {
+	Alias       string   `json:"alias"`
+	HostName    string   `json:"hostName"`
+	Port        string   `json:"port"`
+	User        string   `json:"user"`
+	Via         string   `json:"via,omitempty"` // 经由 ProxyJump/ProxyCommand 连接时为对应的值，此时不做直接探测
+	Reachable   bool     `json:"reachable"`
+	LatencyMs   int64    `json:"latencyMs,omitempty"` // TCP 建连耗时
+	Banner      string   `json:"banner,omitempty"`    // 服务器的版本标识，例如 "SSH-2.0-OpenSSH_9.6"
+	HostKey     string   `json:"hostKey,omitempty"`   // 主机密钥的类型与 SHA256 指纹
+	AuthMethods []string `json:"authMethods"`         // 服务器允许的认证方式（publickey / password / keyboard-interactive）
+	Error       string   `json:"error,omitempty"`
+}
+
+// ProbeHost 探测 alias：TCP 是否可达、SSH 版本标识、主机密钥以及服务器允许的认证方式。
+// 探测不会提交任何密码或签名。经由跳板机或 ProxyCommand 连接的主机只返回其配置，不做直接探测。
+func (m *Manager) ProbeHost(alias string) *HostProbe {
+	probe := &HostProbe{Alias: alias, AuthMethods: []string{}}
+
+	m.mu.RLock()
+	host, err := m.GetSSHHostByAlias(alias)
+	if err != nil {
+		m.mu.RUnlock()
+		probe.Error = err.Error()
+		return probe
+	}
+	if jump := m.proxyJumpFor(alias, host); jump != "" {
+		probe.Via = "ProxyJump " + jump
+	} else if command := m.proxyCommandFor(alias, host); command != "" {
+		probe.Via = "ProxyCommand " + command
+	}
+	opts := m.transportOptionsFor(alias)
+	policy := m.withConfigDialOptions(alias, m.ConnectionPolicyFor(alias))
+	m.mu.RUnlock()
+
+	probe.HostName, probe.Port, probe.User = host.HostName, host.Port, host.User
+	if probe.User == "" {
+		// 与 ssh 一致，未配置 User 时使用当前系统用户；经由跳板机的主机同样展示
+		probe.User = sshconfig.LocalTokens().LocalUser
+	}
+	if probe.Via != "" {
+		return probe
+	}
+	m.probeAddress(probe, policy, opts)
+	return probe
+}
+
+// ProbeAddress 与 ProbeHost 相同，但探测尚未写入 ssh_config 的地址（例如添加主机向导中填写的地址），
+// 使用全局连接策略与默认算法。userName 为空时使用当前系统用户，port 为空时使用 22。
+func (m *Manager) ProbeAddress(hostName, port, userName string) *HostProbe {
+	if port == "" {
+		port = "22"
+	}
+	if userName == "" {
+		userName = sshconfig.LocalTokens().LocalUser
+	}
+	probe := &HostProbe{HostName: hostName, Port: port, User: userName, AuthMethods: []string{}}
+	m.probeAddress(probe, m.ConnectionPolicyFor(""), transportOptions{})
+	return probe
+}
+
+// ProbeTCP 只检查 hostName:port 是否可达以及对方是否是 SSH 服务器（读取版本标识），不进行 SSH 握手
+func (m *Manager) ProbeTCP(hostName, port string) *HostProbe {
+	probe := &HostProbe{HostName: hostName, Port: port, AuthMethods: []string{}}
+	policy := m.ConnectionPolicyFor("")
+	if policy.Validate() != nil {
+		policy = DefaultConnectionPolicy()
+	}
+	probeBanner(probe, policy)
+	return probe
+}
+
+// probeAddress 探测 probe.HostName:probe.Port 的可达性、版本标识、主机密钥与认证方式，结果写入 probe
+func (m *Manager) probeAddress(probe *HostProbe, policy ConnectionPolicy, opts transportOptions) {
+	if policy.Validate() != nil {
+		policy = DefaultConnectionPolicy()
+	}
+
+	// 1. TCP 可达性与版本标识
+	if !probeBanner(probe, policy) {
+		return
+	}
+
+	// 2. 认证方式：为每种方式提供只做记录的回调，服务器允许时回调才会被调用。
+	// password 与 keyboard-interactive 的回调只能以错误中止握手，因此分两次握手探测。
+	addr := net.JoinHostPort(probe.HostName, probe.Port)
+	for _, methods := range [][]string{{"publickey", "password"}, {"keyboard-interactive"}} {
+		if err := m.probeAuthMethods(addr, probe, methods, policy, opts); err != nil {
+			probe.Error = err.Error()
+			break
+		}
+	}
+}
+
+// probeBanner 连接 probe.HostName:probe.Port 并读取服务器的版本标识（服务器在连接建立后首先发送标识行）。
+// 返回 false 表示不可达或对方不是 SSH 服务器，原因写入 probe.Error。
+func probeBanner(probe *HostProbe, policy ConnectionPolicy) bool {
+	start := time.Now()
+	conn, err := net.DialTimeout(policy.network(), net.JoinHostPort(probe.HostName, probe.Port), policy.dialTimeout())
+	if err != nil {
+		probe.Error = err.Error()
+		return false
+	}
+	probe.Reachable = true
+	probe.LatencyMs = time.Since(start).Milliseconds()
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(policy.dialTimeout()))
+
+	// 服务器可以在标识行之前发送其他文本行（RFC 4253 4.2）
+	reader := bufio.NewReader(conn)
+	var first string
+	for i := 0; i < maxBannerLines; i++ {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "SSH-") {
+			probe.Banner = line
+			return true
+		}
+		if i == 0 {
+			first = line
+		}
+		if err != nil {
+			if first == "" {
+				probe.Error = "no SSH banner: " + err.Error()
+				return false
+			}
+			break
+		}
+	}
+	probe.Banner = first
+	probe.Error = "not an SSH server: " + first
+	return false
+}
+
+// probeAuthMethods 进行一次不提交凭据的握手，把服务器允许的方式记录到 probe.AuthMethods
+func (m *Manager) probeAuthMethods(addr string, probe *HostProbe, methods []string, policy ConnectionPolicy, opts transportOptions) error {
+	record := func(method string) {
+		if !slices.Contains(probe.AuthMethods, method) {
+			probe.AuthMethods = append(probe.AuthMethods, method)
+		}
+	}
+
+	var auth []ssh.AuthMethod
+	for _, method := range methods {
+		switch method {
+		case "publickey":
+			auth = append(auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				record("publickey")
+				return nil, nil // 不提供任何密钥，继续尝试下一种方式
+			}))
+		case "password":
+			auth = append(auth, ssh.PasswordCallback(func() (string, error) {
+				record("password")
+				return "", errProbeAborted
+			}))
+		case "keyboard-interactive":
+			auth = append(auth, ssh.KeyboardInteractive(func(_, _ string, _ []string, _ []bool) ([]string, error) {
+				record("keyboard-interactive")
+				return nil, errProbeAborted
+			}))
+		}
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User: probe.User,
+		Auth: auth,
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			probe.HostKey = key.Type() + " " + ssh.FingerprintSHA256(key)
+			return nil // 只读取指纹，不会认证成功，因此无需校验 known_hosts
+		},
+		Timeout: policy.dialTimeout(),
+	}
+	opts.applyAlgorithms(clientConfig)
+	client, err := dialOnce(addr, clientConfig, policy)
+	if err == nil {
+		// 服务器接受了 none 认证
+		client.Close()
+		return nil
+	}
+	if errors.Is(err, errProbeAborted) || probe.HostKey != "" {
+		// 密钥交换已经完成，之后的错误来自被中止或被拒绝的认证，不影响探测结果
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package sshmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"devtools/backend/pkg/sshconfig"
+)
+
+// TestProbeHost_ViaJumpHostDefaultsUser 测试经由跳板机的主机不做直接探测，但未配置 User 时同样展示当前系统用户
+func TestProbeHost_ViaJumpHostDefaultsUser(t *testing.T) {
+	current := sshconfig.LocalTokens().LocalUser
+	configPath := filepath.Join(t.TempDir(), "config")
+	content := "Host db\n    HostName 10.0.0.9\n    ProxyJump bastion\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probe := m.ProbeHost("db")
+	if probe.Via != "ProxyJump bastion" {
+		t.Fatalf("Via = %q, want the ProxyJump", probe.Via)
+	}
+	if probe.User != current {
+		t.Errorf("User = %q, want the current user %q", probe.User, current)
+	}
+	if probe.Reachable {
+		t.Error("a host behind a jump host should not be probed directly")
+	}
+}
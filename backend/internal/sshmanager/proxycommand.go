@@ -0,0 +1,204 @@
+package sshmanager
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"devtools/backend/internal/types"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// proxyCommandFor 返回 alias 在 ssh_config 中生效的 ProxyCommand，并完成 %h/%p/%r/%n 展开。
+// 未配置或配置为 "none" 时返回空字符串。调用方需持有 m.mu。
+func (m *Manager) proxyCommandFor(alias string, host *types.SSHHost) string {
+	if alias == "" {
+		return ""
+	}
+	command := strings.TrimSpace(host.ProxyCommand)
+	if command == "" {
+		command = strings.TrimSpace(m.manager.ResolveHost(alias).Get("ProxyCommand"))
+	}
+	if command == "" || strings.EqualFold(command, "none") {
+		return ""
+	}
+	return expandProxyCommand(command, alias, host)
+}
+
+// expandProxyCommand 展开 ProxyCommand 中的 token：
+// %h 目标主机名，%p 端口，%r 远程用户名，%n 原始别名，%% 字面量 %
+func expandProxyCommand(command, alias string, host *types.SSHHost) string {
+	hostName := host.HostName
+	if hostName == "" {
+		hostName = alias
+	}
+	port := host.Port
+	if port == "" {
+		port = "22"
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] != '%' || i+1 == len(command) {
+			b.WriteByte(command[i])
+			continue
+		}
+		i++
+		switch command[i] {
+		case 'h':
+			b.WriteString(hostName)
+		case 'p':
+			b.WriteString(port)
+		case 'r':
+			b.WriteString(host.User)
+		case 'n':
+			b.WriteString(alias)
+		case '%':
+			b.WriteByte('%')
+		default:
+			// 未知 token 原样保留
+			b.WriteByte('%')
+			b.WriteByte(command[i])
+		}
+	}
+	return b.String()
+}
+
+// dialProxyCommand 执行 ProxyCommand，并以其标准输入/输出作为 SSH 传输层完成握手。
+// 与 OpenSSH 一致，命令通过 shell 执行，因此可以使用管道、环境变量等。
+func dialProxyCommand(command, addr string, clientConfig *ssh.ClientConfig, policy ConnectionPolicy) (*ssh.Client, error) {
+	if policy.Validate() != nil {
+		policy = DefaultConnectionPolicy()
+	}
+	conn, err := startProxyCommand(command, addr)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newClientConn(conn, addr, clientConfig, policy)
+	if err != nil {
+		return nil, fmt.Errorf("ssh handshake over ProxyCommand failed: %w", err)
+	}
+	return client, nil
+}
+
+// startProxyCommand 启动 ProxyCommand 进程并返回一个包装其 stdio 的 net.Conn
+func startProxyCommand(command, addr string) (net.Conn, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd.exe", "/c", command)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", command)
+	}
+
+	// 使用 os.Pipe 而不是 StdinPipe/StdoutPipe，以便在支持的平台上设置读写超时
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ProxyCommand pipe: %w", err)
+	}
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		stdoutR.Close()
+		stdoutW.Close()
+		return nil, fmt.Errorf("failed to create ProxyCommand pipe: %w", err)
+	}
+	cmd.Stdin = stdinR
+	cmd.Stdout = stdoutW
+	cmd.Stderr = &proxyCommandStderr{addr: addr}
+
+	logger.Printf("Starting ProxyCommand for %s: %s", addr, command)
+	if err := cmd.Start(); err != nil {
+		stdoutR.Close()
+		stdoutW.Close()
+		stdinR.Close()
+		stdinW.Close()
+		return nil, fmt.Errorf("failed to start ProxyCommand '%s': %w", command, err)
+	}
+	// 子进程已经持有这两端，父进程中需要关闭，否则读端收不到 EOF
+	stdinR.Close()
+	stdoutW.Close()
+
+	c := &proxyCommandConn{cmd: cmd, stdin: stdinW, stdout: stdoutR, remote: proxyCommandAddr(addr)}
+	go c.wait()
+	return c, nil
+}
+
+// proxyCommandStderr 将 ProxyCommand 的 stderr 逐行写入日志，便于排查代理命令本身的错误
+type proxyCommandStderr struct {
+	addr string
+}
+
+func (w *proxyCommandStderr) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			logger.Printf("ProxyCommand (%s): %s", w.addr, line)
+		}
+	}
+	return len(p), nil
+}
+
+// proxyCommandConn 将 ProxyCommand 子进程的 stdio 适配为 net.Conn
+type proxyCommandConn struct {
+	cmd    *exec.Cmd
+	stdin  *os.File
+	stdout *os.File
+	remote proxyCommandAddr
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+}
+
+func (c *proxyCommandConn) Read(p []byte) (int, error)  { return c.stdout.Read(p) }
+func (c *proxyCommandConn) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+// Close 关闭管道并结束子进程
+func (c *proxyCommandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		c.stdin.Close()
+		c.stdout.Close()
+		if c.cmd.Process != nil {
+			_ = c.cmd.Process.Kill()
+		}
+	})
+	return nil
+}
+
+// wait 回收子进程，并记录其意外退出（由 Close 结束的不记录）
+func (c *proxyCommandConn) wait() {
+	if err := c.cmd.Wait(); err != nil && !c.closed.Load() {
+		logger.Printf("ProxyCommand for %s exited: %v", c.remote, err)
+	}
+}
+
+func (c *proxyCommandConn) LocalAddr() net.Addr  { return proxyCommandAddr("proxycommand") }
+func (c *proxyCommandConn) RemoteAddr() net.Addr { return c.remote }
+
+// 管道在部分平台（如 Windows）上不支持超时，此时忽略错误
+func (c *proxyCommandConn) SetDeadline(t time.Time) error {
+	_ = c.stdout.SetReadDeadline(t)
+	_ = c.stdin.SetWriteDeadline(t)
+	return nil
+}
+
+func (c *proxyCommandConn) SetReadDeadline(t time.Time) error {
+	_ = c.stdout.SetReadDeadline(t)
+	return nil
+}
+
+func (c *proxyCommandConn) SetWriteDeadline(t time.Time) error {
+	_ = c.stdin.SetWriteDeadline(t)
+	return nil
+}
+
+// proxyCommandAddr 是 ProxyCommand 连接的地址，网络类型为 "proxycommand"
+type proxyCommandAddr string
+
+func (a proxyCommandAddr) Network() string { return "proxycommand" }
+func (a proxyCommandAddr) String() string  { return string(a) }
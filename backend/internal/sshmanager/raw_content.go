@@ -0,0 +1,161 @@
+package sshmanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"devtools/backend/pkg/sshconfig"
+)
+
+const (
+	// maxRawContentSize 是在编辑器中一次保存的配置内容上限
+	maxRawContentSize = 4 << 20
+	// diffContext 是差异中每处修改前后保留的未变化行数
+	diffContext = 3
+	// maxDiffCells 限制逐行比较的计算量（两边行数的乘积），超过时把中间部分整体视为删除加新增
+	maxDiffCells = 4_000_000
+)
+
+// RawConfig 是配置文件的原始内容及其校验和。保存时传回校验和，文件在此之后被修改时保存会被拒绝。
+type RawConfig struct {
+	Content  string `json:"content"`
+	Checksum string `json:"checksum"`
+}
+
+// RawSaveResult 是 SaveRawContent 的结果
+type RawSaveResult struct {
+	Saved       bool                   `json:"saved"`
+	Checksum    string                 `json:"checksum,omitempty"` // 保存后文件的校验和，用于下一次保存
+	Diagnostics []sshconfig.Diagnostic `json:"diagnostics"`
+	Conflict    *RawContentConflict    `json:"conflict,omitempty"` // 文件在加载之后被修改，没有保存
+}
+
+// RawContentConflict 描述文件在编辑器加载之后被其他程序（或应用的其他功能）修改的情况，
+// 供前端展示差异并让用户合并后重新保存
+type RawContentConflict struct {
+	CurrentContent  string     `json:"currentContent"`
+	CurrentChecksum string     `json:"currentChecksum"`
+	Diff            []DiffLine `json:"diff"` // 从当前文件到待保存内容的逐行差异，只包含修改处及其上下文
+}
+
+// DiffLine 是逐行差异中的一行
+type DiffLine struct {
+	Op      string `json:"op"` // " " 未变化 | "-" 只在当前文件中 | "+" 只在待保存的内容中
+	Text    string `json:"text"`
+	OldLine int    `json:"oldLine,omitempty"` // 在当前文件中的行号（从 1 开始）
+	NewLine int    `json:"newLine,omitempty"` // 在待保存内容中的行号（从 1 开始）
+}
+
+// newRawContentConflict 返回文件当前内容 current 与待保存内容 content 的冲突信息
+func newRawContentConflict(current, content string) *RawContentConflict {
+	return &RawContentConflict{
+		CurrentContent:  current,
+		CurrentChecksum: contentChecksum(current),
+		Diff:            diffLines(current, content),
+	}
+}
+
+// contentChecksum 返回配置内容的 SHA-256 校验和（十六进制）
+func contentChecksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// diffLines 逐行比较 oldText 与 newText，返回带有上下文的差异
+func diffLines(oldText, newText string) []DiffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	// 配置通常只改动少数几行，先去掉相同的开头与结尾，只比较中间部分
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, DiffLine{Op: " ", Text: a[i], OldLine: i + 1, NewLine: i + 1})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+	for i := 0; i < suffix; i++ {
+		oldIdx, newIdx := len(a)-suffix+i, len(b)-suffix+i
+		lines = append(lines, DiffLine{Op: " ", Text: a[oldIdx], OldLine: oldIdx + 1, NewLine: newIdx + 1})
+	}
+	return withContext(lines, diffContext)
+}
+
+// diffMiddle 用最长公共子序列比较 a 与 b，offset 是两者在完整内容中的起始行
+func diffMiddle(a, b []string, offset int) []DiffLine {
+	n, m := len(a), len(b)
+	var lines []DiffLine
+	if n*m > maxDiffCells {
+		for i, text := range a {
+			lines = append(lines, DiffLine{Op: "-", Text: text, OldLine: offset + i + 1})
+		}
+		for j, text := range b {
+			lines = append(lines, DiffLine{Op: "+", Text: text, NewLine: offset + j + 1})
+		}
+		return lines
+	}
+
+	// lcs[i*(m+1)+j] 是 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([]int, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: " ", Text: a[i], OldLine: offset + i + 1, NewLine: offset + j + 1})
+			i++
+			j++
+		case j == m || (i < n && lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]):
+			lines = append(lines, DiffLine{Op: "-", Text: a[i], OldLine: offset + i + 1})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: "+", Text: b[j], NewLine: offset + j + 1})
+			j++
+		}
+	}
+	return lines
+}
+
+// withContext 只保留修改的行及其前后 context 行未变化的内容
+func withContext(lines []DiffLine, context int) []DiffLine {
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Op == " " {
+			continue
+		}
+		for k := max(0, i-context); k <= min(len(lines)-1, i+context); k++ {
+			keep[k] = true
+		}
+	}
+	result := []DiffLine{}
+	for i, line := range lines {
+		if keep[i] {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// splitLines 按行拆分内容，忽略末尾的换行
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
@@ -0,0 +1,87 @@
+package sshmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDiffLines 测试差异只包含修改处及其上下文，行号对应两边的内容
+func TestDiffLines(t *testing.T) {
+	oldText := "Host a\n    HostName 1\n    User root\n    Port 22\n\nHost b\n    HostName 2\n    User b\n    Port 22\n"
+	newText := "Host a\n    HostName 1\n    User deploy\n    Port 22\n\nHost b\n    HostName 2\n    User b\n    Port 22\n"
+
+	diff := diffLines(oldText, newText)
+	want := []DiffLine{
+		{Op: " ", Text: "Host a", OldLine: 1, NewLine: 1},
+		{Op: " ", Text: "    HostName 1", OldLine: 2, NewLine: 2},
+		{Op: "-", Text: "    User root", OldLine: 3},
+		{Op: "+", Text: "    User deploy", NewLine: 3},
+		{Op: " ", Text: "    Port 22", OldLine: 4, NewLine: 4},
+		{Op: " ", Text: "", OldLine: 5, NewLine: 5},
+		{Op: " ", Text: "Host b", OldLine: 6, NewLine: 6},
+	}
+	if len(diff) != len(want) {
+		t.Fatalf("diff = %+v, want %+v", diff, want)
+	}
+	for i := range want {
+		if diff[i] != want[i] {
+			t.Errorf("diff[%d] = %+v, want %+v", i, diff[i], want[i])
+		}
+	}
+
+	if diff := diffLines(oldText, oldText); len(diff) != 0 {
+		t.Errorf("identical content should have no diff, got %+v", diff)
+	}
+	if diff := diffLines("", "Host a\n"); len(diff) != 1 || diff[0].Op != "+" || diff[0].NewLine != 1 {
+		t.Errorf("unexpected diff for a new file: %+v", diff)
+	}
+}
+
+// TestSaveRawContentConflict 测试文件在加载之后被修改时拒绝保存并返回冲突，使用最新的校验和则可以保存
+func TestSaveRawContentConflict(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte("Host web\n    HostName 10.0.0.1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := m.GetRawConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 编辑器打开之后，文件被其他程序修改
+	external := "Host web\n    HostName 10.0.0.1\n    User admin\n"
+	if err := os.WriteFile(configPath, []byte(external), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := "Host web\n    HostName 10.0.0.2\n"
+	result, err := m.SaveRawContent(edited, loaded.Checksum)
+	if err != nil {
+		t.Fatalf("a conflict should not be an error: %v", err)
+	}
+	if result.Saved || result.Conflict == nil {
+		t.Fatalf("save should be rejected with a conflict, got %+v", result)
+	}
+	if result.Conflict.CurrentContent != external || len(result.Conflict.Diff) == 0 {
+		t.Errorf("conflict should carry the current content and a diff, got %+v", result.Conflict)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != external {
+		t.Errorf("the file should not be overwritten, got %q", data)
+	}
+
+	result, err = m.SaveRawContent(edited, result.Conflict.CurrentChecksum)
+	if err != nil || !result.Saved {
+		t.Fatalf("save with the current checksum should succeed, got %+v, %v", result, err)
+	}
+	if result.Checksum != contentChecksum(edited) {
+		t.Errorf("result checksum should match the saved content")
+	}
+	if value, _ := m.manager.GetParam("web", "HostName"); value != "10.0.0.2" {
+		t.Errorf("manager should be reloaded after saving, HostName = %q", value)
+	}
+}
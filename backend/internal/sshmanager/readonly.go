@@ -0,0 +1,96 @@
+package sshmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrReadOnly 表示应用处于只读模式，修改 ssh_config 的操作被拒绝。
+// Wails 只把错误信息传给前端，前端通过这个固定的前缀识别，并可以用 ReadOnlyStatus 提前禁用编辑控件。
+var ErrReadOnly = errors.New("read-only mode: the SSH config cannot be modified")
+
+// 进入只读模式的原因
+const (
+	ReadOnlyReasonSetting     = "setting"     // 在设置中开启
+	ReadOnlyReasonPermissions = "permissions" // 配置文件（或它所在的目录）不可写，例如受管理的公司电脑
+)
+
+// ReadOnlyStatus 描述 ssh_config 当前是否只读
+type ReadOnlyStatus struct {
+	ReadOnly   bool   `json:"readOnly"`
+	Reason     string `json:"reason,omitempty"` // setting | permissions
+	ConfigPath string `json:"configPath"`
+}
+
+// SetReadOnly 开启或关闭设置中的只读模式
+func (m *Manager) SetReadOnly(enabled bool) {
+	m.readOnly.Store(enabled)
+}
+
+// ReadOnlyStatus 返回 ssh_config 是否只读。配置文件的权限在每次调用时重新检查，
+// 因此在应用运行期间修改权限后，结果会随之变化。
+func (m *Manager) ReadOnlyStatus() ReadOnlyStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.readOnlyStatus()
+}
+
+// readOnlyStatus 是 ReadOnlyStatus 的实现，调用方需持有 m.mu
+func (m *Manager) readOnlyStatus() ReadOnlyStatus {
+	status := ReadOnlyStatus{ConfigPath: m.configPath}
+	switch {
+	case m.readOnly.Load():
+		status.ReadOnly, status.Reason = true, ReadOnlyReasonSetting
+	case !isWritable(m.configPath):
+		status.ReadOnly, status.Reason = true, ReadOnlyReasonPermissions
+	}
+	return status
+}
+
+// checkWritable 在修改 ssh_config 之前调用，只读时返回 ErrReadOnly，避免改了内存中的配置却保存失败。
+// 调用方需持有 m.mu。
+func (m *Manager) checkWritable() error {
+	status := m.readOnlyStatus()
+	if !status.ReadOnly {
+		return nil
+	}
+	if status.Reason == ReadOnlyReasonPermissions {
+		return fmt.Errorf("%w: %s is not writable", ErrReadOnly, status.ConfigPath)
+	}
+	return ErrReadOnly
+}
+
+// isWritable 判断 path 能否写入：文件存在时以写方式打开（不截断）来检查，这样 ACL 等权限也会被考虑；
+// 文件不存在时检查最近的已存在的上级目录中能否创建文件
+func isWritable(path string) bool {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err == nil {
+		f.Close()
+		return true
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+	tmp, err := os.CreateTemp(dir, ".devtools-write-check-*")
+	if err != nil {
+		return false
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	return true
+}
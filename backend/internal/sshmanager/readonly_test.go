@@ -0,0 +1,58 @@
+package sshmanager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestReadOnly 测试设置中开启只读模式，以及配置文件不可写时，修改操作返回 ErrReadOnly 且不改动内存中的配置
+func TestReadOnly(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte("Host web\n    HostName 10.0.0.1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := m.ReadOnlyStatus(); status.ReadOnly {
+		t.Fatalf("a writable config should not be read-only: %+v", status)
+	}
+
+	m.SetReadOnly(true)
+	if status := m.ReadOnlyStatus(); !status.ReadOnly || status.Reason != ReadOnlyReasonSetting {
+		t.Errorf("unexpected status with the setting on: %+v", status)
+	}
+	if err := m.AddHost("db"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("AddHost should fail with ErrReadOnly, got %v", err)
+	}
+	if err := m.RenameHost("web", "www"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("RenameHost should fail with ErrReadOnly, got %v", err)
+	}
+	if _, err := m.SaveRawContent("Host other\n", ""); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("SaveRawContent should fail with ErrReadOnly, got %v", err)
+	}
+	if !m.HasHost("web") || m.HasHost("db") || m.HasHost("www") {
+		t.Error("a rejected change should not modify the config in memory")
+	}
+	m.SetReadOnly(false)
+	if err := m.AddHost("db"); err != nil {
+		t.Fatalf("AddHost failed after leaving read-only mode: %v", err)
+	}
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("file permissions are not enforced")
+	}
+	if err := os.Chmod(configPath, 0o400); err != nil {
+		t.Fatal(err)
+	}
+	if status := m.ReadOnlyStatus(); !status.ReadOnly || status.Reason != ReadOnlyReasonPermissions {
+		t.Errorf("an unwritable config should be read-only: %+v", status)
+	}
+	if err := m.DeleteHost("db"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("DeleteHost should fail with ErrReadOnly, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package sshmanager
+
+import (
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"devtools/backend/pkg/sshconfig"
+)
+
+// 会话环境变量的来源
+const (
+	EnvSourceSendEnv = "SendEnv" // 取自本地环境
+	EnvSourceSetEnv  = "SetEnv"  // ssh_config 中写明的值
+)
+
+// SessionEnvVar 是根据 ssh_config 要在远程会话中设置的一个环境变量
+type SessionEnvVar struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"` // SendEnv | SetEnv
+}
+
+// SessionEnvFor 返回 alias 的生效配置中 SendEnv 与 SetEnv 指定的环境变量，顺序与 ssh 发送的顺序一致：
+// 先是名称匹配 SendEnv 模式的本地环境变量（按名称排序），然后是 SetEnv 的变量，同名时只保留 SetEnv 的值。
+// 本地的 TERM 不会发送，远程会话的 TERM 由 PTY 请求决定。
+func (m *Manager) SessionEnvFor(alias string) []SessionEnvVar {
+	m.mu.RLock()
+	effective := m.manager.ResolveHost(alias)
+	m.mu.RUnlock()
+
+	setEnv := parseSetEnv(alias, effective.Get("SetEnv"))
+	patterns := sendEnvPatterns(effective.GetAll("SendEnv"))
+
+	var vars []SessionEnvVar
+	for _, entry := range os.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || name == "TERM" || !envPatternsMatch(patterns, name) {
+			continue
+		}
+		if slices.ContainsFunc(setEnv, func(v SessionEnvVar) bool { return v.Name == name }) {
+			continue
+		}
+		vars = append(vars, SessionEnvVar{Name: name, Value: value, Source: EnvSourceSendEnv})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return append(vars, setEnv...)
+}
+
+// sendEnvPatterns 按出现顺序合并所有 SendEnv 的模式。
+// 与 OpenSSH 一致，以 "-" 开头的模式会删除之前加入的、与之匹配的模式。
+func sendEnvPatterns(values []string) []string {
+	var patterns []string
+	for _, value := range values {
+		args, err := sshconfig.SplitArgs(value)
+		if err != nil {
+			args = strings.Fields(value)
+		}
+		for _, arg := range args {
+			if removed, ok := strings.CutPrefix(arg, "-"); ok {
+				patterns = slices.DeleteFunc(patterns, func(p string) bool { return envPatternMatches(removed, p) })
+				continue
+			}
+			patterns = append(patterns, arg)
+		}
+	}
+	return patterns
+}
+
+// parseSetEnv 解析 SetEnv 的 NAME=VALUE 列表，同名变量以第一个为准。
+// 只有一个参数的值在读取配置时已经去掉了引号（如 SetEnv GREETING="hello world"），
+// 因此拆分后出现不含 "=" 的部分时，把整个值当作一个变量。
+func parseSetEnv(alias, value string) []SessionEnvVar {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	args, err := sshconfig.SplitArgs(value)
+	if err != nil || slices.ContainsFunc(args, func(arg string) bool { return !strings.Contains(arg, "=") }) {
+		args = []string{strings.TrimSpace(value)}
+	}
+
+	var vars []SessionEnvVar
+	for _, arg := range args {
+		name, val, ok := strings.Cut(arg, "=")
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			logger.Printf("Warning: ignoring invalid SetEnv entry %q for %s", arg, alias)
+			continue
+		}
+		if slices.ContainsFunc(vars, func(v SessionEnvVar) bool { return v.Name == name }) {
+			continue
+		}
+		vars = append(vars, SessionEnvVar{Name: name, Value: val, Source: EnvSourceSetEnv})
+	}
+	return vars
+}
+
+// envPatternsMatch 判断环境变量名是否匹配任一 SendEnv 模式
+func envPatternsMatch(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool { return envPatternMatches(p, name) })
+}
+
+// envPatternMatches 判断名称是否匹配 SendEnv 模式（支持 * 与 ?）
+func envPatternMatches(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
@@ -0,0 +1,59 @@
+package sshmanager
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestSessionEnvFor 测试 SendEnv 模式的合并与删除、SetEnv 的解析以及两者同名时的优先级
+func TestSessionEnvFor(t *testing.T) {
+	t.Setenv("LANG", "en_US.UTF-8")
+	t.Setenv("LC_ALL", "C")
+	t.Setenv("DEVTOOLS_TEST_TOKEN", "secret")
+	t.Setenv("DEVTOOLS_TEST_MODE", "local")
+	t.Setenv("TERM", "dumb")
+
+	configPath := filepath.Join(t.TempDir(), "config")
+	content := "Host app\n" +
+		"    SendEnv LANG LC_* DEVTOOLS_TEST_*\n" +
+		"    SendEnv -DEVTOOLS_* TERM\n" +
+		"    SetEnv DEVTOOLS_TEST_MODE=remote EDITOR=vim EDITOR=nano\n\n" +
+		"Host quoted\n" +
+		"    SetEnv GREETING=\"hello world\"\n\n" +
+		"Host plain\n    HostName 10.0.0.1\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := m.SessionEnvFor("app")
+	want := []SessionEnvVar{
+		{Name: "LANG", Value: "en_US.UTF-8", Source: EnvSourceSendEnv},
+		{Name: "LC_ALL", Value: "C", Source: EnvSourceSendEnv},
+		{Name: "DEVTOOLS_TEST_MODE", Value: "remote", Source: EnvSourceSetEnv},
+		{Name: "EDITOR", Value: "vim", Source: EnvSourceSetEnv},
+	}
+	// 本地环境中可能还有其他 LC_* 变量，只检查测试设置的变量
+	var filtered []SessionEnvVar
+	for _, v := range got {
+		switch v.Name {
+		case "LANG", "LC_ALL", "DEVTOOLS_TEST_MODE", "DEVTOOLS_TEST_TOKEN", "EDITOR", "TERM":
+			filtered = append(filtered, v)
+		}
+	}
+	if !reflect.DeepEqual(filtered, want) {
+		t.Errorf("SessionEnvFor(app) = %+v, want %+v", filtered, want)
+	}
+
+	if got := m.SessionEnvFor("quoted"); !reflect.DeepEqual(got, []SessionEnvVar{{Name: "GREETING", Value: "hello world", Source: EnvSourceSetEnv}}) {
+		t.Errorf("SessionEnvFor(quoted) = %+v", got)
+	}
+	if got := m.SessionEnvFor("plain"); len(got) != 0 {
+		t.Errorf("SessionEnvFor(plain) = %+v, want none", got)
+	}
+}
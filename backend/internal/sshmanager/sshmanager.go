@@ -3,16 +3,20 @@ package sshmanager
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/logging"
 	"devtools/backend/internal/types"
 	"devtools/backend/pkg/sshconfig"
 
@@ -21,16 +25,26 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
+var logger = logging.For("ssh")
+
 // 定义钥匙串服务的名称
 const keyringService = "DevTools-SSH-Gate"
 
 // ConnectionConfig 结构体，用于封装一个完整的SSH客户端配置
 type ConnectionConfig struct {
+	Name         string // 用于展示的名称，通常是 ssh_config 中的别名
 	HostName     string
 	Port         string
 	User         string
 	IdentityFile string // 添加此字段存储密钥文件路径
 	ClientConfig *ssh.ClientConfig
+	KeepAlive    KeepAliveSettings   // 保活策略，来自 ssh_config 或应用设置
+	Policy       ConnectionPolicy    // 超时与重试策略
+	ProxyCommand string              // 已展开的 ProxyCommand，非空时通过该命令的 stdio 建立连接
+	JumpHosts    []*ConnectionConfig // ProxyJump 中的跳板机，按连接顺序排列
+	PreConnect   *PreConnectCommand  // 连接前在本地执行的命令（端口敲门、VPN 检查等）
+
+	auth *authTrace // 记录实际使用的认证方式，见 AuthMethodUsed
 }
 
 // Manager 封装了对 SSH 配置的高级操作
@@ -39,8 +53,48 @@ type Manager struct {
 	manager *sshconfig.SSHConfigManager
 	// 保护 manager 的并发访问
 	mu sync.RWMutex
-	// 配置文件路径
+	// 配置文件路径，受 mu 保护
 	configPath string
+	// configPath 的副本，供不持有 mu 的调用方读取（例如建立连接时查找 known_hosts，此时可能已持有读锁）
+	configPathCopy atomic.Pointer[string]
+	// 来自应用设置的只读主机别名，重新加载 manager 后需要重新应用，受 mu 保护
+	lockedHosts []string
+	// 当前工作区在钥匙串中的命名空间，为空时使用 keyringService 本身，受 overrideMu 保护
+	keyringNamespace string
+
+	// 来自应用设置的全局保活策略覆盖
+	keepAliveOverride KeepAliveSettings
+	// 全局及按主机的连接超时与重试策略
+	policies ConnectionPolicies
+	// 来自应用设置的主机密钥策略与外部终端程序
+	hostKeyPolicy    string
+	externalTerminal string
+	// 向用户转发 keyboard-interactive 问题的处理器，为 nil 时只能自动回答密码问题
+	kbdInteractiveHandler KeyboardInteractiveHandler
+	// 主机密钥与 known_hosts 不一致时的回调，用于发送桌面通知
+	hostKeyChangedHandler func(alias, address string)
+	// 按主机别名配置的连接前命令
+	preConnect map[string]PreConnectCommand
+	overrideMu sync.RWMutex
+
+	// 本次运行中创建的临时主机（不写入 ssh_config），按别名索引
+	ephemeral   map[string]types.SSHHost
+	ephemeralMu sync.RWMutex
+
+	// 最近捕获的主机密钥，供信任主机时复用
+	captured   map[string]*CapturedHostKey
+	capturedMu sync.Mutex
+
+	// 团队共享配置中的只读主机，按别名索引；个人配置中的同名主机优先
+	team   map[string]types.SSHHost
+	teamMu sync.RWMutex
+
+	// 编辑配置文件时额外执行的校验规则（团队策略等）
+	validationRules []sshconfig.ValidationRule
+	rulesMu         sync.RWMutex
+
+	// 在设置中开启的只读模式，开启后修改 ssh_config 的操作返回 ErrReadOnly
+	readOnly atomic.Bool
 }
 
 // ConfigSnapshot 代表一个配置快照，用于返回配置信息，避免直接暴露内部结构
@@ -56,6 +110,13 @@ type HostConfigSnapshot struct {
 	IsGlobal    bool
 	Description string
 	Params      map[string][]string // 简化参数表示，只取值
+	Ordered     []ParamEntry        // 按文件顺序排列的参数，用于界面展示和稳定的输出
+}
+
+// ParamEntry 是快照中的单个参数
+type ParamEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 // HostUpdateRequest 定义更新主机配置的请求
@@ -82,10 +143,29 @@ func NewManager(configPath string) (*Manager, error) {
 		return nil, fmt.Errorf("failed to create pkg manager: %w", err)
 	}
 
-	return &Manager{
-		manager:    manager,
-		configPath: configPath,
-	}, nil
+	m := &Manager{
+		manager:  manager,
+		policies: ConnectionPolicies{Global: DefaultConnectionPolicy()},
+		// 内置规则只产生警告，在编辑器与保存结果中提示
+		validationRules: sshconfig.BuiltinRules(),
+	}
+	m.setConfigPath(configPath)
+	manager.SetValidationRules(m.validationRules)
+	return m, nil
+}
+
+// setConfigPath 设置配置文件路径，调用方需持有 m.mu（创建 Manager 时除外）
+func (m *Manager) setConfigPath(configPath string) {
+	m.configPath = configPath
+	m.configPathCopy.Store(&configPath)
+}
+
+// currentConfigPath 返回配置文件路径，不需要持有 m.mu
+func (m *Manager) currentConfigPath() string {
+	if p := m.configPathCopy.Load(); p != nil {
+		return *p
+	}
+	return ""
 }
 
 // GetConfigSnapshot 获取当前配置的快照
@@ -115,6 +195,9 @@ func (m *Manager) GetConfigSnapshot() (*ConfigSnapshot, error) {
 			}
 			snapshot.Params[key] = values
 		}
+		for _, param := range hostConfig.Ordered {
+			snapshot.Ordered = append(snapshot.Ordered, ParamEntry{Key: param.Key, Value: param.Value})
+		}
 		snapshots = append(snapshots, snapshot)
 	}
 
@@ -149,49 +232,84 @@ func (m *Manager) GetHostParams(hostname string) (map[string][]string, error) {
 	return params, nil
 }
 
+// HostUpdateResult 记录一次主机保存实际修改了哪些参数，供前端提示
+type HostUpdateResult struct {
+	Added   []string `json:"added"`
+	Updated []string `json:"updated"`
+	Removed []string `json:"removed"`
+}
+
+func newHostUpdateResult() *HostUpdateResult {
+	return &HostUpdateResult{Added: []string{}, Updated: []string{}, Removed: []string{}}
+}
+
+// sortedParamKeys 按字母顺序返回参数名，保证写入顺序与结果稳定
+func sortedParamKeys(params map[string]string) []string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // UpdateHost 批量更新或删除主机参数
-// 如果 req.Params 中某个 key 的 value 为空字符串，则删除该参数
-func (m *Manager) UpdateHost(req HostUpdateRequest) error {
+// 如果 req.Params 中某个 key 的 value 为空（或只有空白），则删除该参数，不会写入空值
+func (m *Manager) UpdateHost(req HostUpdateRequest) (*HostUpdateResult, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return nil, err
+	}
 
 	hostname := req.Name
-	for key, value := range req.Params {
-		var err error
-		if value == "" {
-			// 删除参数
-			err = m.manager.RemoveParam(hostname, key)
-			if err != nil {
-				// 如果参数不存在，RemoveParam 可能会返回错误，这里可以视情况处理
-				// 为了简化，我们记录警告而不是失败整个操作
-				// log.Printf("Warning: Failed to remove param %s for host %s: %v", key, hostname, err)
-				// 或者严格处理：
-				// return fmt.Errorf("failed to remove param %s for host %s: %w", key, hostname, err)
+	result := newHostUpdateResult()
+	for _, key := range sortedParamKeys(req.Params) {
+		value := strings.TrimSpace(req.Params[key])
+		existing, getErr := m.manager.GetParam(hostname, key)
+		exists := getErr == nil
+
+		switch {
+		case value == "":
+			if !exists {
+				continue
+			}
+			if err := m.manager.RemoveParam(hostname, key); err != nil {
+				return nil, fmt.Errorf("failed to remove param %s for host %s: %w", key, hostname, err)
+			}
+			result.Removed = append(result.Removed, key)
+		case exists && existing == value:
+			continue
+		default:
+			if err := m.manager.SetParam(hostname, key, value); err != nil {
+				return nil, fmt.Errorf("failed to process param %s for host %s: %w", key, hostname, err)
+			}
+			if exists {
+				result.Updated = append(result.Updated, key)
+			} else {
+				result.Added = append(result.Added, key)
 			}
-		} else {
-			// 设置/更新参数
-			err = m.manager.SetParam(hostname, key, value)
-		}
-		if err != nil {
-			return fmt.Errorf("failed to process param %s for host %s: %w", key, hostname, err)
 		}
 	}
 
-	// 保存更改
-	if err := m.manager.Save(); err != nil {
-		return fmt.Errorf("failed to save config after update: %w", err)
+	// 保存更改（重命名等内存中的修改也依赖这里落盘）
+	if err := m.save(); err != nil {
+		return nil, fmt.Errorf("failed to save config after update: %w", err)
 	}
 
-	return nil
+	return result, nil
 }
 
 // AddHost 添加一个新主机
 func (m *Manager) AddHost(hostname string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
 
-	// 检查主机是否已存在
-	if m.manager.HasHost(hostname) {
+	// 检查别名是否已被占用。被通配符块匹配的名称（例如 Host *.corp 下的 web.corp）可以添加
+	if m.manager.HasHostExact(hostname) {
 		return fmt.Errorf("host %s already exists", hostname)
 	}
 
@@ -199,7 +317,7 @@ func (m *Manager) AddHost(hostname string) error {
 	m.manager.AddHost(hostname)
 
 	// 保存到文件
-	if err := m.manager.Save(); err != nil {
+	if err := m.save(); err != nil {
 		return fmt.Errorf("failed to save config after adding host: %w", err)
 	}
 
@@ -210,41 +328,62 @@ func (m *Manager) HasHost(hostname string) bool {
 	return m.manager.HasHost(hostname)
 }
 
+// HasHostExact 检查个人 ssh_config 中是否有以 alias 为别名的 Host 块（不做通配符匹配）
+func (m *Manager) HasHostExact(alias string) bool {
+	return m.manager.HasHostExact(alias)
+}
+
+// UniqueAlias 返回以 baseName 为基础、既不是 ssh_config 中的别名也不是临时主机的别名（baseName、baseName-2……）
+func (m *Manager) UniqueAlias(baseName string) string {
+	alias := baseName
+	for i := 2; m.manager.HasHostExact(alias) || m.IsEphemeralHost(alias); i++ {
+		alias = fmt.Sprintf("%s-%d", baseName, i)
+	}
+	return alias
+}
+
 func (m *Manager) GetHostNames() ([]string, error) {
 	return m.manager.GetHostNames()
 }
 
-// AddHostWithParams 添加一个带参数的新主机
-func (m *Manager) AddHostWithParams(req HostUpdateRequest) error {
+// AddHostWithParams 添加一个带参数的新主机，值为空的参数会被跳过
+func (m *Manager) AddHostWithParams(req HostUpdateRequest) (*HostUpdateResult, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return nil, err
+	}
 
-	// 检查主机是否已存在
-	if m.manager.HasHost(req.Name) {
-		return fmt.Errorf("host %s already exists", req.Name)
+	// 检查别名是否已被占用
+	if m.manager.HasHostExact(req.Name) {
+		return nil, fmt.Errorf("host %s already exists", req.Name)
 	}
 
 	// 添加主机
 	m.manager.AddHost(req.Name)
 
-	// 设置参数
-	for key, value := range req.Params {
-		// 直接使用 value，因为 req.Params 是 map[string]string
+	// 设置参数。新参数总是插入在 Host 行之后，因此倒序写入，文件中按字母顺序排列
+	result := newHostUpdateResult()
+	keys := sortedParamKeys(req.Params)
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := keys[i]
+		value := strings.TrimSpace(req.Params[key])
 		if value == "" {
 			continue
 		}
 		err := m.manager.SetParam(req.Name, key, value)
 		if err != nil {
-			return fmt.Errorf("failed to set param %s for host %s: %w", key, req.Name, err)
+			return nil, fmt.Errorf("failed to set param %s for host %s: %w", key, req.Name, err)
 		}
+		result.Added = append([]string{key}, result.Added...)
 	}
 
 	// 保存到文件
-	if err := m.manager.Save(); err != nil {
-		return fmt.Errorf("failed to save config after adding host: %w", err)
+	if err := m.save(); err != nil {
+		return nil, fmt.Errorf("failed to save config after adding host: %w", err)
 	}
 
-	return nil
+	return result, nil
 }
 
 // RenameHost renames a host alias in the config file.
@@ -253,22 +392,36 @@ func (m *Manager) AddHostWithParams(req HostUpdateRequest) error {
 func (m *Manager) RenameHost(oldName, newName string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
 
 	if !m.manager.HasHost(oldName) {
 		return fmt.Errorf("host '%s' not found", oldName)
 	}
 	// It's crucial to check for new name collision before renaming.
-	if oldName != newName && m.manager.HasHost(newName) {
+	if oldName != newName && m.manager.HasHostExact(newName) {
 		return fmt.Errorf("host with new alias '%s' already exists", newName)
 	}
 
 	return m.manager.RenameHost(oldName, newName)
 }
 
+// RenameJumpReferences points ProxyJump/ProxyCommand references to oldName at newName.
+// Like RenameHost it only changes the configuration in memory; Include files are left untouched.
+func (m *Manager) RenameJumpReferences(oldName, newName string) []sshconfig.Reference {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.manager.RenameJumpReferences(oldName, newName)
+}
+
 // DeleteHost 删除一个主机
 func (m *Manager) DeleteHost(hostname string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
 
 	// 检查主机是否存在
 	if !m.manager.HasHost(hostname) {
@@ -281,7 +434,7 @@ func (m *Manager) DeleteHost(hostname string) error {
 	}
 
 	// 保存更改
-	if err := m.manager.Save(); err != nil {
+	if err := m.save(); err != nil {
 		return fmt.Errorf("failed to save config after deleting host: %w", err)
 	}
 
@@ -292,7 +445,22 @@ func (m *Manager) DeleteHost(hostname string) error {
 func (m *Manager) GetRawContent() (string, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	return m.readRawContent()
+}
 
+// GetRawConfig 返回配置文件的原始内容及其校验和，保存时把校验和传给 SaveRawContent
+func (m *Manager) GetRawConfig() (*RawConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	content, err := m.readRawContent()
+	if err != nil {
+		return nil, err
+	}
+	return &RawConfig{Content: content, Checksum: contentChecksum(content)}, nil
+}
+
+// readRawContent 读取配置文件，文件不存在时返回空字符串。调用方需持有 m.mu。
+func (m *Manager) readRawContent() (string, error) {
 	data, err := os.ReadFile(m.configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -303,25 +471,97 @@ func (m *Manager) GetRawContent() (string, error) {
 	return string(data), nil
 }
 
-// SaveRawContent 校验并保存完整的配置文件内容
-func (m *Manager) SaveRawContent(content string) error {
+// SaveRawContent 校验并保存完整的配置文件内容，无论是否保存都返回完整的诊断列表。
+// 只有错误级别的诊断会阻止保存，警告（例如未知参数）只返回给调用方提示。
+// expectedChecksum 是加载内容时 GetRawConfig 返回的校验和：文件在此之后被修改时不会保存，
+// 结果的 Conflict 中包含文件的当前内容与差异，不返回错误，以便调用方拿到冲突信息。为空时不检查。
+func (m *Manager) SaveRawContent(content, expectedChecksum string) (*RawSaveResult, error) {
+	if len(content) > maxRawContentSize {
+		return nil, fmt.Errorf("ssh config content is too large (%d bytes, the limit is %d)", len(content), maxRawContentSize)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return nil, err
+	}
 
-	// 在保存前，先进行一次基本的语法校验
-	validator := sshconfig.NewConfigValidator(strings.Split(content, "\n"))
-	if err := validator.Validate(); err != nil {
-		return fmt.Errorf("SSH config validation failed: %w", err)
+	// 在保存前，先进行一次语法校验，并执行已注册的校验规则
+	result := &RawSaveResult{}
+	diagnostics, err := m.newValidator(content).Check(false)
+	result.Diagnostics = diagnostics
+	if err != nil {
+		return result, fmt.Errorf("SSH config validation failed: %w", err)
+	}
+	// 只读的 Host 块不能在原始文本中被修改或删除
+	if err := m.manager.CheckLockedBlocks(strings.Split(content, "\n")); err != nil {
+		return result, err
+	}
+
+	// 文件在编辑器加载之后被修改时，整体覆写会丢失那些修改
+	current, err := m.readRawContent()
+	if err != nil {
+		return result, err
+	}
+	if currentChecksum := contentChecksum(current); expectedChecksum != "" && currentChecksum != expectedChecksum {
+		logger.Printf("Warning: SSH config file %s changed since it was loaded, not overwriting it.", m.configPath)
+		result.Conflict = newRawContentConflict(current, content)
+		return result, nil
 	}
 
 	// 覆写文件
 	if err := os.WriteFile(m.configPath, []byte(content), 0o600); err != nil {
-		return fmt.Errorf("failed to write raw ssh config: %w", err)
+		return result, fmt.Errorf("failed to write raw ssh config: %w", err)
 	}
-	log.Printf("SSH config file %s has been updated.", m.configPath)
+	logger.Printf("SSH config file %s has been updated.", m.configPath)
+	result.Saved, result.Checksum = true, contentChecksum(content)
 
 	// 写回成功后，必须重新加载内存中的 manager，以保证数据同步
-	return m.reload()
+	return result, m.reload()
+}
+
+// AddValidationRule 注册编辑配置文件时额外执行的校验规则（内置规则在创建 Manager 时已注册）。
+// 错误级别的诊断会阻止 SaveRawContent 以及添加、修改主机等所有写入 ssh_config 的操作。
+func (m *Manager) AddValidationRule(rule sshconfig.ValidationRule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rulesMu.Lock()
+	m.validationRules = append(m.validationRules, rule)
+	m.rulesMu.Unlock()
+	m.manager.SetValidationRules(m.rules())
+}
+
+// rules 返回已注册的校验规则的副本
+func (m *Manager) rules() []sshconfig.ValidationRule {
+	m.rulesMu.RLock()
+	defer m.rulesMu.RUnlock()
+	return slices.Clone(m.validationRules)
+}
+
+// ValidateContent 返回配置文件内容的所有诊断（语法错误与已注册规则的结果），不修改文件
+func (m *Manager) ValidateContent(content string) []sshconfig.Diagnostic {
+	return m.newValidator(content).Diagnostics()
+}
+
+// newValidator 创建带有已注册规则的校验器
+func (m *Manager) newValidator(content string) *sshconfig.ConfigValidator {
+	validator := sshconfig.NewConfigValidator(strings.Split(content, "\n"))
+	for _, rule := range m.rules() {
+		validator.AddRule(rule)
+	}
+	return validator
+}
+
+// save 校验并保存内存中的配置，调用方需持有 m.mu。
+// 被校验规则拒绝或写入失败时重新加载文件，丢弃这次修改，避免它随之后的保存一起写入。
+func (m *Manager) save() error {
+	if err := m.manager.Save(); err != nil {
+		if reloadErr := m.reload(); reloadErr != nil {
+			logger.Printf("Warning: failed to discard a rejected change to %s: %v", m.configPath, reloadErr)
+		}
+		return err
+	}
+	return nil
 }
 
 // reload 是一个内部方法，用于在不释放锁的情况下重新加载配置
@@ -330,6 +570,8 @@ func (m *Manager) reload() error {
 	if err != nil {
 		return fmt.Errorf("failed to reload config from %s: %w", m.configPath, err)
 	}
+	newManager.SetLockedHosts(m.lockedHosts)
+	newManager.SetValidationRules(m.rules())
 	m.manager = newManager
 	return nil
 }
@@ -344,10 +586,48 @@ func (m *Manager) Reload() error {
 		return fmt.Errorf("failed to reload config from %s: %w", m.configPath, err)
 	}
 
+	newManager.SetLockedHosts(m.lockedHosts)
+	newManager.SetValidationRules(m.rules())
 	m.manager = newManager
 	return nil
 }
 
+// SetConfigPath 切换到另一个 SSH 配置文件并重新加载，为空时使用默认路径 ~/.ssh/config
+func (m *Manager) SetConfigPath(configPath string) error {
+	if configPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home dir: %w", err)
+		}
+		configPath = filepath.Join(homeDir, ".ssh", "config")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldPath := m.configPath
+	m.setConfigPath(configPath)
+	if err := m.reload(); err != nil {
+		m.setConfigPath(oldPath)
+		return err
+	}
+	return nil
+}
+
+// SetLockedHosts 设置来自应用设置的只读主机别名，这些主机所在的 Host 块无法被修改
+func (m *Manager) SetLockedHosts(aliases []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lockedHosts = slices.Clone(aliases)
+	m.manager.SetLockedHosts(m.lockedHosts)
+}
+
+// IsHostLocked 判断主机所在的 Host 块是否只读（带有 @locked 注释或在设置的锁定列表中）
+func (m *Manager) IsHostLocked(alias string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.manager.IsHostLocked(alias)
+}
+
 // Validate 检查配置文件语法
 func (m *Manager) Validate() error {
 	m.mu.RLock()
@@ -382,7 +662,8 @@ func (m *Manager) UpdateGlobal(params map[string]string) error {
 		Name:   "*",
 		Params: params,
 	}
-	return m.UpdateHost(req)
+	_, err := m.UpdateHost(req)
+	return err
 }
 
 // ReorderHosts rewrites the SSH config file with hosts in the specified order.
@@ -391,6 +672,9 @@ func (m *Manager) UpdateGlobal(params map[string]string) error {
 func (m *Manager) ReorderHosts(orderedAliases []string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
 
 	// The underlying sshconfig manager will handle the reordering of raw lines
 	// while preserving comments and file structure.
@@ -399,19 +683,129 @@ func (m *Manager) ReorderHosts(orderedAliases []string) error {
 	}
 
 	// Save the modified configuration.
-	if err := m.manager.Save(); err != nil {
+	if err := m.save(); err != nil {
 		return fmt.Errorf("failed to save reordered hosts: %w", err)
 	}
 
 	// Optionally, create a backup.
 	if _, err := m.manager.Backup(); err != nil {
 		// Log as a warning since the main operation succeeded.
-		log.Printf("Warning: failed to create backup after reordering hosts: %v", err)
+		logger.Printf("Warning: failed to create backup after reordering hosts: %v", err)
 	}
 
 	return nil
 }
 
+// SortHosts 按 mode（sshconfig.SortByAlias 或 sshconfig.SortByTag）整理 Host 块的顺序，
+// Host *、Match 与 Include 块保持原来的位置
+func (m *Manager) SortHosts(mode string) error {
+	return m.sortHosts(func(cm *sshconfig.SSHConfigManager) error {
+		return cm.SortHosts(mode)
+	})
+}
+
+// SortHostsBy 与 SortHosts 相同，但按 group 返回的分组排序，分组为空的主机排在最后
+func (m *Manager) SortHostsBy(group func(aliases []string) string) error {
+	return m.sortHosts(func(cm *sshconfig.SSHConfigManager) error {
+		cm.SortHostsBy(group)
+		return nil
+	})
+}
+
+// sortHosts 在锁内执行排序并保存，与 ReorderHosts 一样在保存后创建备份
+func (m *Manager) sortHosts(sortFn func(*sshconfig.SSHConfigManager) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := sortFn(m.manager); err != nil {
+		return fmt.Errorf("failed to sort hosts in config: %w", err)
+	}
+	if err := m.save(); err != nil {
+		return fmt.Errorf("failed to save sorted hosts: %w", err)
+	}
+	if _, err := m.manager.Backup(); err != nil {
+		logger.Printf("Warning: failed to create backup after sorting hosts: %v", err)
+	}
+	return nil
+}
+
+// MergeHosts combines the Host blocks of sourceAliases into the block of targetAlias.
+// A backup of the config is taken first because the source blocks are deleted.
+func (m *Manager) MergeHosts(targetAlias string, sourceAliases []string) (*sshconfig.MergeResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if _, err := m.manager.Backup(); err != nil {
+		return nil, fmt.Errorf("failed to back up config before merging hosts: %w", err)
+	}
+
+	result, err := m.manager.MergeHosts(targetAlias, sourceAliases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge hosts into '%s': %w", targetAlias, err)
+	}
+
+	if err := m.save(); err != nil {
+		_ = m.reload() // Discard the in-memory merge.
+		return nil, fmt.Errorf("failed to save merged hosts: %w", err)
+	}
+
+	return result, nil
+}
+
+// DuplicateHost copies the Host block of sourceAlias (comments included) under newAlias,
+// applies overrides (an empty value removes the parameter), validates and saves in one step.
+func (m *Manager) DuplicateHost(sourceAlias, newAlias string, overrides map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := m.manager.DuplicateHost(sourceAlias, newAlias); err != nil {
+		return fmt.Errorf("failed to duplicate host '%s': %w", sourceAlias, err)
+	}
+
+	hostConfig, err := m.manager.GetHost(newAlias)
+	if err != nil {
+		_ = m.reload()
+		return err
+	}
+	for key, value := range overrides {
+		// 沿用副本中已有参数的大小写（如 Hostname），否则会新增一行而不是覆盖
+		for existing := range hostConfig.Params {
+			if strings.EqualFold(existing, key) {
+				key = existing
+				break
+			}
+		}
+		if value == "" {
+			err = m.manager.RemoveParam(newAlias, key)
+		} else {
+			err = m.manager.SetParam(newAlias, key, value)
+		}
+		if err != nil {
+			_ = m.reload() // Discard the in-memory copy.
+			return fmt.Errorf("failed to process param %s for host %s: %w", key, newAlias, err)
+		}
+	}
+
+	if err := m.manager.Validate(); err != nil {
+		_ = m.reload()
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	if err := m.save(); err != nil {
+		_ = m.reload()
+		return fmt.Errorf("failed to save duplicated host: %w", err)
+	}
+	return nil
+}
+
 // convertToSSHHost 将 HostConfig 转换为 types.SSHHost
 func convertToSSHHost(hostConfig *sshconfig.HostConfig) types.SSHHost {
 	// 从 Params 中提取信息
@@ -433,11 +827,17 @@ func convertToSSHHost(hostConfig *sshconfig.HostConfig) types.SSHHost {
 	}
 }
 
+// GetSSHHostByAlias 返回用于连接的主机（隧道、终端等）。与 GetSSHHost 返回的原始块不同，
+// 个人 ssh_config 中的主机使用生效配置，即同时应用匹配的通配符块（例如 Host *.corp 或 Host *）
+// 中的 HostName、User、Port 与 IdentityFile，与 OpenSSH 一致按首个值生效。
 func (m *Manager) GetSSHHostByAlias(alias string) (*types.SSHHost, error) {
 	host, err := m.GetSSHHost(alias)
 	if err != nil {
 		return nil, err
 	}
+	if _, ephemeral := m.ephemeralHost(alias); !ephemeral && m.manager.HasHost(alias) {
+		applyEffectiveConfig(host, m.manager.ResolveHost(alias))
+	}
 	// 在这里，我们集中处理所有默认值逻辑
 	if host.Port == "" {
 		host.Port = "22"
@@ -446,9 +846,36 @@ func (m *Manager) GetSSHHostByAlias(alias string) (*types.SSHHost, error) {
 	return host, nil
 }
 
+// applyEffectiveConfig 用生效配置中的值覆盖主机的连接字段，生效配置中没有的字段保持原值
+func applyEffectiveConfig(host *types.SSHHost, effective *sshconfig.EffectiveConfig) {
+	for _, field := range []struct {
+		key   string
+		value *string
+	}{
+		{"HostName", &host.HostName},
+		{"User", &host.User},
+		{"Port", &host.Port},
+		{"IdentityFile", &host.IdentityFile},
+	} {
+		if v := effective.Get(field.key); v != "" {
+			*field.value = v
+		}
+	}
+}
+
+// GetSSHHost 返回主机在配置中的原始块（编辑主机时使用），不应用通配符块中的默认值
 func (m *Manager) GetSSHHost(alias string) (*types.SSHHost, error) {
+	if host, ok := m.ephemeralHost(alias); ok {
+		return host, nil
+	}
 	hostConfig, err := m.manager.GetHost(alias)
 	if err != nil {
+		if host, ok := m.includedHost(alias); ok {
+			return host, nil
+		}
+		if host, ok := m.teamHost(alias); ok {
+			return host, nil
+		}
 		return nil, err
 	}
 	newHost := convertToSSHHost(hostConfig)
@@ -457,6 +884,9 @@ func (m *Manager) GetSSHHost(alias string) (*types.SSHHost, error) {
 
 // GetSSHHosts 解析用户的 SSH 配置文件并返回所有主机配置
 func (m *Manager) GetSSHHosts() ([]types.SSHHost, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	// Get the modification time of the config file.
 	var modTimeStr string
 	fileInfo, err := os.Stat(m.configPath)
@@ -479,56 +909,32 @@ func (m *Manager) GetSSHHosts() ([]types.SSHHost, error) {
 		}
 		newHost := convertToSSHHost(hostConfig)
 		newHost.LastModified = modTimeStr
+		newHost.Locked = m.manager.IsHostLocked(hostConfig.Name)
 		hosts = append(hosts, newHost)
 	}
 
-	log.Printf("Successfully parsed %d SSH hosts.", len(hosts)) // 如果需要日志
+	logger.Printf("Successfully parsed %d SSH hosts.", len(hosts)) // 如果需要日志
 	return hosts, nil
 }
 
-// a special error type to capture the host key
-type captureHostKeyError struct {
-	key ssh.PublicKey
-}
-
-func (e *captureHostKeyError) Error() string {
-	return "host key captured"
-}
+// AddHostKeyToKnownHosts 将一个新的主机公钥添加到用户的 known_hosts 文件中。
+// 生效配置中设置了 HashKnownHosts yes 时写入哈希形式的主机名；已存在相同记录时不重复写入。
+func (m *Manager) AddHostKeyToKnownHosts(host *types.SSHHost, key ssh.PublicKey) error {
+	knownHostsPath := m.knownHostsPath()
 
-// captureHostKey 是一个特殊的函数，用于捕获服务器的公钥
-func (m *Manager) CaptureHostKey(host *types.SSHHost) (ssh.PublicKey, error) {
-	// 创建一个只用于捕获的、不进行任何认证的配置
-	captureConfig := &ssh.ClientConfig{
-		User: host.User,
-		// 关键：这个回调函数在拿到公钥后，会立即返回一个特殊错误来中断连接
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return &captureHostKeyError{key: key}
-		},
-		Timeout: 5 * time.Second,
-	}
+	// 将主机名和端口组合成 known_hosts 中的地址，端口为 22 时会被规范化为不带端口的形式
+	address := knownhosts.Normalize(fmt.Sprintf("[%s]:%s", host.HostName, host.Port))
 
-	// 使用处理过的 port
-	serverAddr := fmt.Sprintf("%s:%s", host.HostName, host.Port)
-	client, err := ssh.Dial("tcp", serverAddr, captureConfig)
-	if client != nil {
-		client.Close()
+	exists, err := knownHostsHasKey(knownHostsPath, address, key)
+	if err != nil {
+		return fmt.Errorf("failed to read known_hosts file: %w", err)
 	}
-
-	// 检查返回的错误
-	var capturedKeyErr *captureHostKeyError
-	if errors.As(err, &capturedKeyErr) {
-		// 成功捕获！返回公钥
-		return capturedKeyErr.key, nil
+	if exists {
+		m.forgetCapturedHostKey(host)
+		logger.Printf("Host key for %s is already in %s", host.Alias, knownHostsPath)
+		return nil
 	}
 
-	// 其他错误
-	return nil, fmt.Errorf("failed to capture host key: %w", err)
-}
-
-// AddHostKeyToKnownHosts 将一个新的主机公钥添加到用户的 known_hosts 文件中
-func (m *Manager) AddHostKeyToKnownHosts(host *types.SSHHost, key ssh.PublicKey) error {
-	knownHostsPath := filepath.Join(filepath.Dir(m.configPath), "known_hosts")
-
 	// 以“追加”模式打开文件，如果文件不存在则创建
 	f, err := os.OpenFile(knownHostsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
 	if err != nil {
@@ -536,13 +942,7 @@ func (m *Manager) AddHostKeyToKnownHosts(host *types.SSHHost, key ssh.PublicKey)
 	}
 	defer f.Close()
 
-	// 构建要写入的地址列表
-	// 一个主机别名可能对应多个地址（例如，域名和IP地址）
-	// 我们在这里将主机名和端口组合起来
-	addresses := []string{fmt.Sprintf("[%s]:%s", host.HostName, host.Port)}
-
-	// 使用 knownhosts.Line() 这个标准函数来生成格式正确的行
-	newLine := knownhosts.Line(addresses, key)
+	newLine := knownHostsLine(address, key, m.hashKnownHostsFor(host.Alias))
 
 	// 检查文件是否为空，如果是，则不加换行符
 	stat, err := f.Stat()
@@ -559,7 +959,8 @@ func (m *Manager) AddHostKeyToKnownHosts(host *types.SSHHost, key ssh.PublicKey)
 		return fmt.Errorf("failed to write to known_hosts file: %w", err)
 	}
 
-	log.Printf("Added new host key for %s to %s", host.Alias, knownHostsPath)
+	m.forgetCapturedHostKey(host)
+	logger.Printf("Added new host key for %s to %s", host.Alias, knownHostsPath)
 	return nil
 }
 
@@ -575,24 +976,42 @@ func readKeyFile(path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
 
+// SetKeyringNamespace 设置工作区在钥匙串中的命名空间，之后保存和读取的密码都属于该命名空间。
+// 为空时使用引入工作区之前的条目。
+func (m *Manager) SetKeyringNamespace(namespace string) {
+	m.overrideMu.Lock()
+	defer m.overrideMu.Unlock()
+	m.keyringNamespace = namespace
+}
+
+// keyringServiceName 返回当前工作区在钥匙串中使用的服务名
+func (m *Manager) keyringServiceName() string {
+	m.overrideMu.RLock()
+	defer m.overrideMu.RUnlock()
+	if m.keyringNamespace == "" {
+		return keyringService
+	}
+	return keyringService + "/" + m.keyringNamespace
+}
+
 // SavePassword 将密码安全地存入系统钥匙串
 func (m *Manager) SavePassword(key string, password string) error {
-	return keyring.Set(keyringService, key, password)
+	return keyring.Set(m.keyringServiceName(), key, password)
 }
 
 // DeletePassword 从系统钥匙串中删除密码
 func (m *Manager) DeletePassword(key string) error {
 	// 在删除前检查是否存在，避免keyring库在某些平台因找不到而报错
-	_, err := keyring.Get(keyringService, key)
+	_, err := keyring.Get(m.keyringServiceName(), key)
 	if err == nil {
-		return keyring.Delete(keyringService, key)
+		return keyring.Delete(m.keyringServiceName(), key)
 	}
 	return nil // 如果本来就不存在，也算成功
 }
 
 // RenamePassword renames a password entry in the keychain.
 func (m *Manager) RenamePassword(oldKey, newKey string) error {
-	password, err := keyring.Get(keyringService, oldKey)
+	password, err := keyring.Get(m.keyringServiceName(), oldKey)
 	if err != nil {
 		if errors.Is(err, keyring.ErrNotFound) {
 			return nil // Old key doesn't exist, nothing to do.
@@ -600,52 +1019,142 @@ func (m *Manager) RenamePassword(oldKey, newKey string) error {
 		return fmt.Errorf("failed to get password for key %s: %w", oldKey, err)
 	}
 
-	if err := keyring.Set(keyringService, newKey, password); err != nil {
+	if err := keyring.Set(m.keyringServiceName(), newKey, password); err != nil {
 		return fmt.Errorf("failed to set new password for key %s: %w", newKey, err)
 	}
 
-	return keyring.Delete(keyringService, oldKey)
+	return keyring.Delete(m.keyringServiceName(), oldKey)
+}
+
+// CheckKeychain 读取一个不存在的条目来检查系统钥匙串是否可用，可用时返回 nil
+func (m *Manager) CheckKeychain() error {
+	_, err := keyring.Get(m.keyringServiceName(), "devtools-keychain-probe")
+	if err == nil || errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// HasPassword 判断钥匙串中是否保存了 key 的密码
+func (m *Manager) HasPassword(key string) bool {
+	_, err := keyring.Get(m.keyringServiceName(), key)
+	return err == nil
+}
+
+// CopyPassword 把 fromKey 的密码复制到 toKey。fromKey 没有密码，或 toKey 已经有密码（不会被覆盖）时返回 false。
+func (m *Manager) CopyPassword(fromKey, toKey string) (bool, error) {
+	password, err := keyring.Get(m.keyringServiceName(), fromKey)
+	if err != nil {
+		if errors.Is(err, keyring.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get password for key %s: %w", fromKey, err)
+	}
+	if _, err := keyring.Get(m.keyringServiceName(), toKey); err == nil {
+		return false, nil
+	}
+	if err := keyring.Set(m.keyringServiceName(), toKey, password); err != nil {
+		return false, fmt.Errorf("failed to set password for key %s: %w", toKey, err)
+	}
+	return true, nil
 }
 
-// _getAuthMethods 智能地构建认证方法列表
-func (m *Manager) _getAuthMethods(host *types.SSHHost, password string, keychainKey string) ([]ssh.AuthMethod, error) {
-	var authMethods []ssh.AuthMethod
+// _getAuthMethods 智能地构建认证方法列表。opts.PreferredAuthentications 非空时按其排序并过滤，
+// opts.IdentityAgent 决定使用哪个 ssh-agent 中的密钥，opts.IdentitiesOnly 时只使用代理中与 IdentityFile 对应的密钥。
+// trace 记录握手时实际尝试的认证方式，可以为 nil。
+func (m *Manager) _getAuthMethods(host *types.SSHHost, password string, keychainKey string, opts transportOptions, trace *authTrace) ([]ssh.AuthMethod, error) {
+	var authMethods []namedAuthMethod
+	var passwords []string
 
 	// 认证优先级 1: 用户本次在UI上输入的临时密码
 	if password != "" {
-		authMethods = append(authMethods, ssh.Password(password))
+		authMethods = append(authMethods, namedAuthMethod{authPassword, tracedPassword(trace, "password", password)})
+		passwords = append(passwords, password)
 	}
 
 	// 认证优先级 2: 从系统钥匙串中获取已保存的密码
 	// The keychainKey can be either a host alias or a tunnel ID.
 	if keychainKey != "" {
-		savedPassword, err := keyring.Get(keyringService, keychainKey)
+		savedPassword, err := keyring.Get(m.keyringServiceName(), keychainKey)
 		if err == nil && savedPassword != "" {
-			authMethods = append(authMethods, ssh.Password(savedPassword))
+			authMethods = append(authMethods, namedAuthMethod{authPassword, tracedPassword(trace, "password (keychain)", savedPassword)})
+			passwords = append(passwords, savedPassword)
 		}
 	}
 
-	// 认证优先级 3: ~/.ssh/config 中配置的 IdentityFile (密钥文件)
+	// 认证优先级 3: ~/.ssh/config 中配置的 IdentityFile (密钥文件)，然后是 IdentityAgent（默认为 ssh-agent）中的密钥。
+	// 两者必须合并为同一个 publickey 认证方式，x/crypto/ssh 不会重复尝试同名的认证方式。
+	var passphraseErr error
+	var signers []ssh.Signer
 	if host.IdentityFile != "" {
 		key, err := readKeyFile(host.IdentityFile)
 		if err == nil {
 			signer, err := ssh.ParsePrivateKey(key)
 			if err == nil {
-				authMethods = append(authMethods, ssh.PublicKeys(signer))
+				signers = append(signers, &tracedSigner{Signer: signer, label: host.IdentityFile, trace: trace})
 			} else {
-				log.Printf("Warning: Failed to parse private key %s: %v", host.IdentityFile, err)
+				var missing *ssh.PassphraseMissingError
+				if errors.As(err, &missing) {
+					passphraseErr = err
+				}
+				logger.Printf("Warning: Failed to parse private key %s: %v", host.IdentityFile, err)
 			}
 		} else {
-			log.Printf("Warning: Failed to read private key file %s: %v", host.IdentityFile, err)
+			logger.Printf("Warning: Failed to read private key file %s: %v", host.IdentityFile, err)
 		}
 	}
-
-	// 如果一个有效的认证方法都没有，就返回需要密码的特定错误
+	// 受密码短语保护的私钥通常已经加入了代理。IdentitiesOnly 时代理中的其他密钥不会被尝试，
+	// 避免代理中密钥很多时在轮到配置的密钥之前就超过服务器的 MaxAuthTries
+	var allow func(ssh.PublicKey) bool
+	if opts.IdentitiesOnly {
+		files := opts.IdentityFiles
+		if len(files) == 0 && host.IdentityFile != "" {
+			files = []string{host.IdentityFile}
+		}
+		allow = identityKeyFilter(files)
+	}
+	signers = appendAgentSigners(signers, opts.IdentityAgent, allow, trace)
+	if len(signers) > 0 {
+		authMethods = append(authMethods, namedAuthMethod{authPublicKey, ssh.PublicKeys(signers...)})
+	}
+
+	// 认证优先级 4: keyboard-interactive，用于 2FA/OTP 以及只开放这种方式的密码认证
+	if kbdAuth := m.keyboardInteractiveAuth(host, passwords, trace); kbdAuth != nil {
+		authMethods = append(authMethods, namedAuthMethod{authKeyboardInteractive, kbdAuth})
+	}
+
+	// 需要密码时返回的特定错误
+	var passwordRequired error = &types.PasswordRequiredError{Alias: host.Alias}
+	if passphraseErr != nil {
+		// 私钥受密码短语保护：仍然包装 PasswordRequiredError，用户可以改用服务器密码登录
+		passwordRequired = &ConnectError{
+			Kind:   KindPassphraseRequired,
+			Alias:  host.Alias,
+			Detail: host.IdentityFile,
+			Err: &types.PasswordRequiredError{
+				Alias:   host.Alias,
+				Message: i18n.T("ssh.passphrase_required", host.IdentityFile, host.Alias),
+			},
+		}
+	}
+	// 如果一个有效的认证方法都没有，就直接返回需要密码的错误
 	if len(authMethods) == 0 {
-		return nil, &types.PasswordRequiredError{Alias: host.Alias}
+		return nil, passwordRequired
+	}
+	// 没有密码时最后加入一个 password 方式，它在被尝试时以需要密码的错误结束握手：
+	// 只有其他方式（密钥、keyboard-interactive）都不被服务器接受时才会轮到它
+	if len(passwords) == 0 {
+		authMethods = append(authMethods, namedAuthMethod{authPassword, ssh.PasswordCallback(func() (string, error) {
+			return "", passwordRequired
+		})})
 	}
 
-	return authMethods, nil
+	ordered := orderAuthMethods(authMethods, opts.PreferredAuthentications)
+	if len(ordered) == 0 {
+		// PreferredAuthentications 排除了所有可用的方式，只能让用户提供密码
+		return nil, &types.PasswordRequiredError{Alias: host.Alias}
+	}
+	return ordered, nil
 }
 
 // VerifyConnection 执行一次真正的连接“预检”
@@ -656,8 +1165,7 @@ func (m *Manager) VerifyConnection(alias string, password string) (*types.SSHHos
 	}
 
 	// 尝试真正地拨号连接
-	serverAddr := fmt.Sprintf("%s:%s", config.HostName, config.Port)
-	client, err := ssh.Dial("tcp", serverAddr, config.ClientConfig)
+	client, err := Dial(config)
 	if err != nil {
 
 		dialErrStr := strings.ToLower(err.Error())
@@ -706,27 +1214,33 @@ func (m *Manager) VerifyConnection(alias string, password string) (*types.SSHHos
 // BuildSSHClientConfig builds a complete SSH client configuration from a host object and a password.
 // This is the core logic, decoupled from ~/.ssh/config aliases.
 func (m *Manager) BuildSSHClientConfig(host *types.SSHHost, password string, keychainKey string) (*ConnectionConfig, error) {
-	authMethods, err := m._getAuthMethods(host, password, keychainKey)
+	return m.buildClientConfig(host, password, keychainKey, transportOptions{})
+}
+
+// buildClientConfig 与 BuildSSHClientConfig 相同，另外应用 ssh_config 中的算法与认证方式偏好
+func (m *Manager) buildClientConfig(host *types.SSHHost, password string, keychainKey string, opts transportOptions) (*ConnectionConfig, error) {
+	trace := &authTrace{}
+	authMethods, err := m._getAuthMethods(host, password, keychainKey, opts, trace)
 	if err != nil {
 		return nil, err
 	}
 
 	var hostKeyCallback ssh.HostKeyCallback
 
-	knownHostsPath := filepath.Join(filepath.Dir(m.configPath), "known_hosts")
+	knownHostsPath := m.knownHostsPath()
 	var hkcb knownhosts.HostKeyCallback
 	hkcb, err = knownhosts.New(knownHostsPath)
 	if err != nil {
 		return nil, fmt.Errorf("could not create known_hosts callback: %w", err)
 	}
-	hostKeyCallback = hkcb.HostKeyCallback()
+	hostKeyCallback = m.wrapHostKeyCallback(host, hkcb.HostKeyCallback())
 
 	clientConfig := &ssh.ClientConfig{
 		User:            host.User,
 		Auth:            authMethods,
 		HostKeyCallback: hostKeyCallback,
-		Timeout:         10 * time.Second,
 	}
+	opts.applyAlgorithms(clientConfig)
 
 	return &ConnectionConfig{
 		HostName:     host.HostName,
@@ -734,6 +1248,9 @@ func (m *Manager) BuildSSHClientConfig(host *types.SSHHost, password string, key
 		User:         host.User,
 		IdentityFile: host.IdentityFile,
 		ClientConfig: clientConfig,
+		KeepAlive:    m.keepAliveForHost(""),
+		Policy:       m.dialPolicyFor(""),
+		auth:         trace,
 	}, nil
 }
 
@@ -747,28 +1264,65 @@ func (m *Manager) GetConnectionConfig(alias string, password string) (*Connectio
 		return nil, nil, err
 	}
 
-	connConfig, err := m.BuildSSHClientConfig(host, password, host.Alias)
+	connConfig, err := m.buildClientConfig(host, password, host.Alias, m.transportOptionsFor(alias))
 	if err != nil {
 		// The host object is still useful for the caller (e.g., for error handling UI)
 		return nil, host, err
 	}
+	// Hosts from ssh_config may define their own ServerAliveInterval/ServerAliveCountMax.
+	connConfig.KeepAlive = m.keepAliveForHost(alias)
+	connConfig.Name = alias
+	connConfig.Policy = m.withConfigDialOptions(alias, m.dialPolicyFor(alias))
+	connConfig.PreConnect = m.preConnectFor(alias, host)
+	if err := m.applyTransport(connConfig, alias, host); err != nil {
+		return nil, host, err
+	}
 
 	return connConfig, host, nil
 }
 
-func sshExec(sshCmd string) error {
+// shellQuote 用单引号包裹参数，使其在终端的 POSIX Shell 中作为一个参数传递
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// appleScriptEscape 转义放入 AppleScript 双引号字符串中的反斜杠与双引号
+func appleScriptEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// sshExec 在外部终端中执行 sshCmd。terminal 为空时使用平台默认终端。
+func sshExec(sshCmd string, terminal string) error {
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
 	case "darwin":
-		// macOS 的命令
-		script := fmt.Sprintf(`tell app "Terminal" to do script "%s"`, sshCmd)
+		// macOS 的命令，sshCmd 与终端名称都放在 AppleScript 的字符串中
+		sshCmd = appleScriptEscape(sshCmd)
+		var script string
+		switch strings.ToLower(terminal) {
+		case "iterm", "iterm2":
+			script = fmt.Sprintf(`tell application "iTerm" to create window with default profile command "%s"`, sshCmd)
+		case "":
+			script = fmt.Sprintf(`tell app "Terminal" to do script "%s"`, sshCmd)
+		default:
+			script = fmt.Sprintf(`tell app "%s" to do script "%s"`, appleScriptEscape(terminal), sshCmd)
+		}
 		cmd = exec.Command("osascript", "-e", script)
 	case "windows":
 		// Windows 的命令
 		// start 命令会打开一个新的终端窗口
-		cmd = exec.Command("cmd.exe", "/c", "start", "wt.exe", sshCmd)
+		if terminal == "" {
+			terminal = "wt.exe"
+		}
+		cmd = exec.Command("cmd.exe", "/c", "start", terminal, sshCmd)
 	default: // Linux
-		cmd = exec.Command("gnome-terminal", "--", "bash", "-c", sshCmd+"; exec bash")
+		switch terminal {
+		case "", "gnome-terminal":
+			cmd = exec.Command("gnome-terminal", "--", "bash", "-c", sshCmd+"; exec bash")
+		default:
+			// konsole、xterm、x-terminal-emulator 等都支持 -e
+			cmd = exec.Command(terminal, "-e", "bash", "-c", sshCmd+"; exec bash")
+		}
 	}
 
 	// Start() 启动命令，不等待它完成
@@ -778,23 +1332,82 @@ func sshExec(sshCmd string) error {
 	return nil
 }
 
+// ExpandTokens 展开 value 中的 OpenSSH token（%h、%p、%r、%u、%d、%L、%l）与 ${ENV}，
+// 值取自 alias 的生效配置
+func (m *Manager) ExpandTokens(alias, value string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.manager.ExpandTokens(alias, value)
+}
+
+// ConfigPath 返回主配置文件的路径，与 sshconfig.Reference.File 中主配置文件的写法一致
+func (m *Manager) ConfigPath() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.manager.Filename()
+}
+
+// WhereUsed 返回所有引用了 value 的参数（IdentityFile 路径、ProxyJump 跳板机等），包括 Include 文件中的主机
+func (m *Manager) WhereUsed(paramKey, value string) ([]sshconfig.Reference, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.manager.WhereUsed(paramKey, value)
+}
+
+// AnalyzeHosts 返回每个主机的连接目标，以及指向同一 HostName:Port 的别名分组。
+// resolveDNS 为 true 时先在不持有锁的情况下并发解析所有域名，解析到同一地址的别名也会被归为一组。
+func (m *Manager) AnalyzeHosts(resolveDNS bool) *sshconfig.HostAnalysis {
+	if !resolveDNS {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return m.manager.AnalyzeHosts(nil)
+	}
+
+	m.mu.RLock()
+	targets := m.manager.AnalyzeHosts(nil).Targets
+	m.mu.RUnlock()
+	resolved := resolveHostNames(targets)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.manager.AnalyzeHosts(func(host string) ([]string, error) {
+		r, ok := resolved[host]
+		if !ok {
+			return nil, fmt.Errorf("host %s was not resolved", host)
+		}
+		return r.ips, r.err
+	})
+}
+
 // ConnectInTerminal 在系统默认终端中打开一个 SSH 连接
 func (m *Manager) ConnectInTerminal(alias string, dryRun bool) error {
 	if dryRun {
 		return nil
 	}
+	// 临时主机与团队主机不在 ssh_config 中，需要把连接参数（包括跳板机与 ProxyCommand）直接交给 ssh
+	if m.IsEphemeralHost(alias) || m.IsTeamHost(alias) {
+		cfg, err := m.GetCommandConfig(alias)
+		if err != nil {
+			return err
+		}
+		return m.ConnectInTerminalWithConfig("", cfg)
+	}
 	// ssh 客户端非常智能，我们只需要告诉它要连接的别名 (alias) 即可。
 	// 它会自动从 ~/.ssh/config 文件中读取 HostName, User, Port, IdentityFile 等所有配置。
 	sshCmd := fmt.Sprintf("ssh %s", alias)
-	log.Printf("Debug: SSH command to be executed: %s", sshCmd)
+	logger.Printf("Debug: SSH command to be executed: %s", sshCmd)
 
-	return sshExec(sshCmd)
+	return sshExec(sshCmd, m.getExternalTerminal())
 }
 
 // ConnectInTerminalWithConfig 接收一个完整的配置，并在系统终端中打开连接
 func (m *Manager) ConnectInTerminalWithConfig(alias string, config *ConnectionConfig) error {
 	// 处理密钥文件路径（展开~并验证）
 	identityFile := config.IdentityFile
+	if identityFile != "" && alias != "" {
+		// IdentityFile 中可以使用 %d、%u、%h 等 token
+		identityFile = m.ExpandTokens(alias, identityFile)
+	}
 	if identityFile != "" {
 		// 展开路径中的~符号
 		if strings.HasPrefix(identityFile, "~") {
@@ -806,7 +1419,7 @@ func (m *Manager) ConnectInTerminalWithConfig(alias string, config *ConnectionCo
 		}
 		// 验证文件是否存在
 		if _, err := os.Stat(identityFile); err != nil {
-			log.Printf("Warning: Identity file %s not found", identityFile)
+			logger.Printf("Warning: Identity file %s not found", identityFile)
 			identityFile = "" // 文件不存在时不使用该参数
 		}
 	}
@@ -822,6 +1435,22 @@ func (m *Manager) ConnectInTerminalWithConfig(alias string, config *ConnectionCo
 	if identityFile != "" {
 		sshArgs = append(sshArgs, "-i", identityFile)
 	}
+	// 跳板机与 ProxyCommand（-F /dev/null 时 ssh 无法从配置中读取它们）
+	if len(config.JumpHosts) > 0 {
+		hops := make([]string, len(config.JumpHosts))
+		for i, hop := range config.JumpHosts {
+			hops[i] = hop.HostName
+			if hop.Port != "" && hop.Port != "22" {
+				hops[i] = net.JoinHostPort(hop.HostName, hop.Port)
+			}
+			if hop.User != "" {
+				hops[i] = hop.User + "@" + hops[i]
+			}
+		}
+		sshArgs = append(sshArgs, "-J", strings.Join(hops, ","))
+	} else if config.ProxyCommand != "" {
+		sshArgs = append(sshArgs, "-o", shellQuote("ProxyCommand="+config.ProxyCommand))
+	}
 
 	// 添加目标用户@主机
 	sshArgs = append(sshArgs, fmt.Sprintf("%s@%s", config.User, config.HostName))
@@ -829,7 +1458,7 @@ func (m *Manager) ConnectInTerminalWithConfig(alias string, config *ConnectionCo
 	// 拼接完整命令字符串
 	sshCmd := "ssh " + strings.Join(sshArgs, " ")
 
-	log.Printf("Debug: SSH command to be executed: %s", sshCmd)
+	logger.Printf("Debug: SSH command to be executed: %s", sshCmd)
 
-	return sshExec(sshCmd)
+	return sshExec(sshCmd, m.getExternalTerminal())
 }
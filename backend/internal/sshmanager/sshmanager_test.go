@@ -0,0 +1,205 @@
+package sshmanager
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/sshconfig"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// TestGetSSHHostByAlias_WildcardDefaults 测试连接时使用的主机应用通配符块中的默认值，
+// 而编辑时使用的原始块不受影响
+func TestGetSSHHostByAlias_WildcardDefaults(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	content := "Host web.corp\n    HostName 10.0.0.1\n\n" +
+		"Host db.corp\n    HostName 10.0.0.2\n    User postgres\n\n" +
+		"Host *.corp\n    User deploy\n    Port 2222\n    IdentityFile ~/.ssh/corp\n\n" +
+		"Host plain\n    HostName 10.0.0.3\n\n" +
+		"Host *\n    User fallback\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		alias, hostName, user, port, identityFile string
+	}{
+		{"web.corp", "10.0.0.1", "deploy", "2222", "~/.ssh/corp"},
+		{"db.corp", "10.0.0.2", "postgres", "2222", "~/.ssh/corp"},
+		{"plain", "10.0.0.3", "fallback", "22", ""},
+	}
+	for _, tt := range tests {
+		host, err := m.GetSSHHostByAlias(tt.alias)
+		if err != nil {
+			t.Fatalf("GetSSHHostByAlias(%q): %v", tt.alias, err)
+		}
+		if host.HostName != tt.hostName || host.User != tt.user || host.Port != tt.port || host.IdentityFile != tt.identityFile {
+			t.Errorf("GetSSHHostByAlias(%q) = %+v, want %s@%s:%s identity %q", tt.alias, host, tt.user, tt.hostName, tt.port, tt.identityFile)
+		}
+	}
+
+	raw, err := m.GetSSHHost("web.corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.User != "" || raw.Port != "" || raw.IdentityFile != "" {
+		t.Errorf("GetSSHHost should return the raw block, got %+v", raw)
+	}
+}
+
+// TestValidationRulesOnSave 测试注册的规则在添加、修改主机时同样生效：被拒绝的修改不写入文件，也不留在内存中
+func TestValidationRulesOnSave(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	original := "Host prod-web\n    HostName 10.0.0.1\n    IdentityFile ~/.ssh/prod\n"
+	if err := os.WriteFile(configPath, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 内置规则在创建时已注册
+	diagnostics := m.ValidateContent("Host *\n    ForwardAgent yes\n")
+	if len(diagnostics) != 1 || diagnostics[0].Rule != sshconfig.GlobalForwardAgentRuleID {
+		t.Errorf("built-in rules should be registered, got %+v", diagnostics)
+	}
+
+	m.AddValidationRule(sshconfig.RequireParamRule("prod-identity", "", "prod-*", "IdentityFile"))
+	if _, err := m.UpdateHost(HostUpdateRequest{Name: "prod-web", Params: map[string]string{"IdentityFile": ""}}); err == nil {
+		t.Fatal("UpdateHost should be rejected by the rule")
+	}
+	if err := m.AddHost("prod-db"); err == nil {
+		t.Fatal("AddHost should be rejected by the rule")
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != original {
+		t.Errorf("rejected changes should not be written, got %q", data)
+	}
+	if m.HasHostExact("prod-db") {
+		t.Error("a rejected change should not stay in memory")
+	}
+	if value, err := m.manager.GetParam("prod-web", "IdentityFile"); err != nil || value != "~/.ssh/prod" {
+		t.Errorf("a rejected change should not stay in memory, IdentityFile = %q, %v", value, err)
+	}
+
+	// 规则在重新加载后仍然生效
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddHost("prod-api"); err == nil {
+		t.Error("rules should still apply after reloading")
+	}
+	if err := m.AddHost("dev"); err != nil {
+		t.Errorf("hosts not matched by the rule can be added: %v", err)
+	}
+}
+
+// TestShellQuote 测试参数经 POSIX Shell 解析后保持原样，包括空格与单引号
+func TestShellQuote(t *testing.T) {
+	for _, arg := range []string{"/tmp/ctl/web", "/Users/me/Library/Application Support/ssh/cm-web", "it's", ""} {
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(arg)).Output()
+		if err != nil {
+			t.Skipf("no POSIX shell: %v", err)
+		}
+		if string(out) != arg {
+			t.Errorf("shellQuote(%q) was parsed as %q", arg, out)
+		}
+	}
+}
+
+// TestAppleScriptEscape 测试放入 AppleScript 字符串的终端名称与命令不能提前结束字符串
+func TestAppleScriptEscape(t *testing.T) {
+	tests := map[string]string{
+		"Terminal":          "Terminal",
+		`My "Term"`:         `My \"Term\"`,
+		`C:\path`:           `C:\\path`,
+		`x" to quit --\`:    `x\" to quit --\\`,
+		`ssh -J "a" 'b\"c'`: `ssh -J \"a\" 'b\\\"c'`,
+	}
+	for in, want := range tests {
+		if got := appleScriptEscape(in); got != want {
+			t.Errorf("appleScriptEscape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestGetAuthMethods_KeyboardInteractiveWithoutPassword 测试没有密码与密钥时仍然提供 keyboard-interactive，
+// 只开放 keyboard-interactive 的服务器可以收到问题；只接受密码的服务器以需要密码的错误结束握手
+func TestGetAuthMethods_KeyboardInteractiveWithoutPassword(t *testing.T) {
+	m := &Manager{}
+	host := &types.SSHHost{Alias: "otp", User: "test"}
+	opts := transportOptions{IdentityAgent: IdentityAgentSettings{Disabled: true}}
+
+	if _, err := m._getAuthMethods(host, "", "", opts, nil); !errors.As(err, new(*types.PasswordRequiredError)) {
+		t.Fatalf("without any method the password should be required up front, got %v", err)
+	}
+
+	var asked []string
+	m.SetKeyboardInteractiveHandler(func(challenge KeyboardInteractiveChallenge) ([]string, error) {
+		for _, p := range challenge.Prompts {
+			asked = append(asked, p.Text)
+		}
+		return []string{"123456"}, nil
+	})
+	methods, err := m._getAuthMethods(host, "", "", opts, nil)
+	if err != nil {
+		t.Fatalf("keyboard-interactive should be offered without a password: %v", err)
+	}
+
+	otpServer := startAuthTestServer(t, &ssh.ServerConfig{
+		KeyboardInteractiveCallback: func(_ ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := client("", "", []string{"Verification code: "}, []bool{false})
+			if err != nil || len(answers) != 1 || answers[0] != "123456" {
+				return nil, errors.New("wrong code")
+			}
+			return nil, nil
+		},
+	})
+	client, err := ssh.Dial("tcp", otpServer, &ssh.ClientConfig{User: "test", Auth: methods, HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+	if err != nil {
+		t.Fatalf("a keyboard-interactive only server should prompt the user: %v", err)
+	}
+	client.Close()
+	if len(asked) != 1 || asked[0] != "Verification code: " {
+		t.Errorf("the prompt should reach the handler, got %q", asked)
+	}
+
+	passwordServer := startAuthTestServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	})
+	_, err = ssh.Dial("tcp", passwordServer, &ssh.ClientConfig{User: "test", Auth: methods, HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+	if !errors.As(err, new(*types.PasswordRequiredError)) {
+		t.Errorf("a password only server should end with PasswordRequiredError, got %v", err)
+	}
+}
+
+// TestAddEphemeralHost_WildcardBlock 测试通配符块（如 Host tmp-*）不会阻止添加 UniqueAlias 生成的临时主机，
+// 同名的具体主机仍然会被拒绝
+func TestAddEphemeralHost_WildcardBlock(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	content := "Host tmp-*\n    User deploy\n\nHost tmp-web\n    HostName 10.0.0.1\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alias := m.UniqueAlias("tmp-db")
+	if err := m.AddEphemeralHost(types.SSHHost{Alias: alias, HostName: "10.0.0.2"}); err != nil {
+		t.Errorf("an alias only matched by a wildcard block should be accepted: %v", err)
+	}
+	if err := m.AddEphemeralHost(types.SSHHost{Alias: "tmp-web", HostName: "10.0.0.3"}); err == nil {
+		t.Error("an alias of a concrete Host block should be rejected")
+	}
+}
@@ -0,0 +1,130 @@
+package sshmanager
+
+import (
+	"sort"
+	"strings"
+
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/sshconfig"
+)
+
+// HostSourceTeam 是团队共享配置中的主机在 types.SSHHost.Source 中的取值
+const HostSourceTeam = "team"
+
+// TeamHostConflict 描述团队配置中与个人 ssh_config 同名的主机。个人配置优先，团队中的主机被隐藏。
+type TeamHostConflict struct {
+	Alias  string   `json:"alias"`
+	Fields []string `json:"fields"` // 取值不同的字段（HostName、User、Port、IdentityFile），为空表示两边相同
+}
+
+// ParseTeamConfig 解析团队共享的 ssh_config 片段，返回其中的具体主机（跳过 Host * 与通配符模式），
+// 片段中 Host * 等块设置的 User、Port、ProxyJump 等会被应用到各主机上
+func ParseTeamConfig(content string) ([]types.SSHHost, error) {
+	return parseHosts(content, HostSourceTeam)
+}
+
+// parseHosts 解析一段 ssh_config 内容中的具体主机，并标记来源
+func parseHosts(content, source string) ([]types.SSHHost, error) {
+	cfg, err := sshconfig.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	hostConfigs, err := cfg.GetAllHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []types.SSHHost
+	seen := make(map[string]bool)
+	for _, hostConfig := range hostConfigs {
+		if hostConfig.IsGlobal || strings.ContainsAny(hostConfig.Name, "*?!") {
+			continue
+		}
+		for _, alias := range strings.Fields(hostConfig.Name) {
+			if seen[alias] {
+				continue
+			}
+			seen[alias] = true
+			effective := cfg.ResolveHost(alias)
+			hosts = append(hosts, types.SSHHost{
+				Alias:        alias,
+				HostName:     effective.Get("HostName"),
+				User:         effective.Get("User"),
+				Port:         effective.Get("Port"),
+				IdentityFile: effective.Get("IdentityFile"),
+				ProxyJump:    effective.Get("ProxyJump"),
+				ProxyCommand: effective.Get("ProxyCommand"),
+				Source:       source,
+			})
+		}
+	}
+	return hosts, nil
+}
+
+// SetTeamHosts 替换团队共享的主机列表。团队主机只读，不会写入 ssh_config。
+func (m *Manager) SetTeamHosts(hosts []types.SSHHost) {
+	team := make(map[string]types.SSHHost, len(hosts))
+	for _, host := range hosts {
+		host.Source = HostSourceTeam
+		team[host.Alias] = host
+	}
+	m.teamMu.Lock()
+	defer m.teamMu.Unlock()
+	m.team = team
+}
+
+// GetTeamHosts 返回所有团队主机（包括与个人配置同名而被隐藏的），按别名排序
+func (m *Manager) GetTeamHosts() []types.SSHHost {
+	m.teamMu.RLock()
+	defer m.teamMu.RUnlock()
+	hosts := make([]types.SSHHost, 0, len(m.team))
+	for _, host := range m.team {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Alias < hosts[j].Alias })
+	return hosts
+}
+
+// IsTeamHost 判断 alias 是否是一个生效的团队主机（个人配置中没有同名主机）
+func (m *Manager) IsTeamHost(alias string) bool {
+	_, ok := m.teamHost(alias)
+	return ok
+}
+
+// TeamConflicts 返回团队配置中与个人配置同名的主机，按别名排序
+func (m *Manager) TeamConflicts() []TeamHostConflict {
+	conflicts := []TeamHostConflict{}
+	for _, teamHost := range m.GetTeamHosts() {
+		if !m.HasHost(teamHost.Alias) {
+			continue
+		}
+		personal, err := m.GetSSHHost(teamHost.Alias)
+		if err != nil {
+			continue
+		}
+		conflict := TeamHostConflict{Alias: teamHost.Alias, Fields: []string{}}
+		for _, f := range []struct{ name, personal, team string }{
+			{"HostName", personal.HostName, teamHost.HostName},
+			{"User", personal.User, teamHost.User},
+			{"Port", personal.Port, teamHost.Port},
+			{"IdentityFile", personal.IdentityFile, teamHost.IdentityFile},
+		} {
+			if f.personal != f.team {
+				conflict.Fields = append(conflict.Fields, f.name)
+			}
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts
+}
+
+// teamHost 返回 alias 对应的团队主机副本；个人配置中有同名主机时返回 false
+func (m *Manager) teamHost(alias string) (*types.SSHHost, bool) {
+	m.teamMu.RLock()
+	host, ok := m.team[alias]
+	m.teamMu.RUnlock()
+	if !ok || m.HasHost(alias) {
+		return nil, false
+	}
+	return &host, true
+}
@@ -0,0 +1,59 @@
+package sshmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTeamHostTransport 测试团队主机保留 ProxyJump / ProxyCommand，跳板机同样可以是团队主机
+func TestTeamHostTransport(t *testing.T) {
+	hosts, err := ParseTeamConfig(`Host bastion
+    HostName 203.0.113.10
+    User jump
+
+Host db
+    HostName 10.0.0.5
+    ProxyJump bastion
+
+Host legacy
+    HostName 10.0.0.6
+    ProxyCommand nc -X connect -x proxy:3128 %h %p
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAlias := make(map[string]string)
+	for _, host := range hosts {
+		byAlias[host.Alias] = host.ProxyJump + "|" + host.ProxyCommand
+	}
+	if byAlias["db"] != "bastion|" || byAlias["legacy"] != "|nc -X connect -x proxy:3128 %h %p" {
+		t.Fatalf("team hosts should carry ProxyJump and ProxyCommand, got %v", byAlias)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte("Host web\n    HostName 10.0.0.1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetTeamHosts(hosts)
+
+	cfg, err := m.GetCommandConfig("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.JumpHosts) != 1 || cfg.JumpHosts[0].HostName != "203.0.113.10" || cfg.JumpHosts[0].User != "jump" {
+		t.Errorf("the team jump host should be resolved from the team config, got %+v", cfg.JumpHosts)
+	}
+
+	cfg, err = m.GetCommandConfig("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "nc -X connect -x proxy:3128 10.0.0.6 22"; cfg.ProxyCommand != want {
+		t.Errorf("ProxyCommand = %q, want %q", cfg.ProxyCommand, want)
+	}
+}
@@ -0,0 +1,217 @@
+package sshmanager
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 以下默认与支持的算法列表与 golang.org/x/crypto/ssh 保持一致，用于展开 ssh_config 中
+// "+"（追加）、"-"（移除）、"^"（前置）形式的算法列表，并在配置了不支持的算法时给出提示。
+var (
+	defaultCiphers = []string{
+		"aes128-gcm@openssh.com", "aes256-gcm@openssh.com",
+		"chacha20-poly1305@openssh.com",
+		"aes128-ctr", "aes192-ctr", "aes256-ctr",
+	}
+	supportedCiphers = []string{
+		"aes128-ctr", "aes192-ctr", "aes256-ctr",
+		"aes128-gcm@openssh.com", "aes256-gcm@openssh.com",
+		"chacha20-poly1305@openssh.com",
+		"arcfour256", "arcfour128", "arcfour",
+		"aes128-cbc", "3des-cbc",
+	}
+
+	defaultMACs = []string{
+		"hmac-sha2-256-etm@openssh.com", "hmac-sha2-512-etm@openssh.com",
+		"hmac-sha2-256", "hmac-sha2-512", "hmac-sha1", "hmac-sha1-96",
+	}
+	supportedMACs = defaultMACs
+
+	defaultKexAlgorithms = []string{
+		"mlkem768x25519-sha256",
+		"curve25519-sha256", "curve25519-sha256@libssh.org",
+		"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+		"diffie-hellman-group14-sha256", "diffie-hellman-group14-sha1",
+	}
+	supportedKexAlgorithms = []string{
+		"mlkem768x25519-sha256",
+		"curve25519-sha256", "curve25519-sha256@libssh.org",
+		"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+		"diffie-hellman-group14-sha256", "diffie-hellman-group16-sha512",
+		"diffie-hellman-group14-sha1", "diffie-hellman-group1-sha1",
+		"diffie-hellman-group-exchange-sha1", "diffie-hellman-group-exchange-sha256",
+	}
+
+	defaultHostKeyAlgorithms = []string{
+		ssh.CertAlgoRSASHA256v01, ssh.CertAlgoRSASHA512v01,
+		ssh.CertAlgoRSAv01, ssh.CertAlgoDSAv01, ssh.CertAlgoECDSA256v01,
+		ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01, ssh.CertAlgoED25519v01,
+		ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+		ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSASHA512,
+		ssh.KeyAlgoRSA, ssh.KeyAlgoDSA,
+		ssh.KeyAlgoED25519,
+	}
+	supportedHostKeyAlgorithms = defaultHostKeyAlgorithms
+)
+
+// 认证方式名称，与 ssh_config 中 PreferredAuthentications 的取值一致
+const (
+	authPublicKey           = "publickey"
+	authPassword            = "password"
+	authKeyboardInteractive = "keyboard-interactive"
+)
+
+// transportOptions 是从 ssh_config 读取的传输层选项，空列表表示使用默认值
+type transportOptions struct {
+	Ciphers                  []string
+	MACs                     []string
+	KexAlgorithms            []string
+	HostKeyAlgorithms        []string
+	PreferredAuthentications []string
+	Compression              bool
+	IdentityAgent            IdentityAgentSettings
+	IdentitiesOnly           bool
+	IdentityFiles            []string // 所有 IdentityFile（已展开 token），IdentitiesOnly 时只使用代理中与之对应的密钥
+}
+
+// transportOptionsFor 读取 alias 生效配置中的 Ciphers、MACs、KexAlgorithms、HostKeyAlgorithms、
+// PreferredAuthentications、Compression、IdentityAgent 与 IdentitiesOnly。调用方需持有 m.mu。
+func (m *Manager) transportOptionsFor(alias string) transportOptions {
+	var opts transportOptions
+	if alias == "" {
+		return opts
+	}
+	effective := m.manager.ResolveHost(alias)
+	opts.Ciphers = resolveAlgorithms(alias, "Ciphers", effective.Get("Ciphers"), defaultCiphers, supportedCiphers)
+	opts.MACs = resolveAlgorithms(alias, "MACs", effective.Get("MACs"), defaultMACs, supportedMACs)
+	opts.KexAlgorithms = resolveAlgorithms(alias, "KexAlgorithms", effective.Get("KexAlgorithms"), defaultKexAlgorithms, supportedKexAlgorithms)
+	opts.HostKeyAlgorithms = resolveAlgorithms(alias, "HostKeyAlgorithms", effective.Get("HostKeyAlgorithms"), defaultHostKeyAlgorithms, supportedHostKeyAlgorithms)
+	opts.PreferredAuthentications = splitList(effective.Get("PreferredAuthentications"))
+	opts.Compression = strings.EqualFold(strings.TrimSpace(effective.Get("Compression")), "yes")
+	opts.IdentityAgent = m.identityAgentFor(alias)
+	opts.IdentitiesOnly = strings.EqualFold(strings.TrimSpace(effective.Get("IdentitiesOnly")), "yes")
+	for _, file := range effective.GetAll("IdentityFile") {
+		opts.IdentityFiles = append(opts.IdentityFiles, m.manager.ExpandTokens(alias, file))
+	}
+	if opts.Compression {
+		// golang.org/x/crypto/ssh 只实现了 "none" 压缩，连接仍可建立，只是不压缩
+		logger.Printf("Warning: Compression is not supported for in-app connections to %s, continuing without it", alias)
+	}
+	return opts
+}
+
+// applyAlgorithms 将配置的算法列表写入 clientConfig，未配置的项保持库的默认值
+func (o transportOptions) applyAlgorithms(clientConfig *ssh.ClientConfig) {
+	if len(o.Ciphers) > 0 {
+		clientConfig.Ciphers = o.Ciphers
+	}
+	if len(o.MACs) > 0 {
+		clientConfig.MACs = o.MACs
+	}
+	if len(o.KexAlgorithms) > 0 {
+		clientConfig.KeyExchanges = o.KexAlgorithms
+	}
+	if len(o.HostKeyAlgorithms) > 0 {
+		clientConfig.HostKeyAlgorithms = o.HostKeyAlgorithms
+	}
+}
+
+// resolveAlgorithms 按 OpenSSH 的规则展开算法列表：
+// "+a,b" 追加到默认列表末尾，"-a,b*" 从默认列表中移除（支持通配符），"^a,b" 放到默认列表最前，
+// 其余情况直接使用给定的列表。值为空时返回 nil。不支持的算法会被丢弃并记录警告。
+func resolveAlgorithms(alias, key, value string, defaults, supported []string) []string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	var result []string
+	switch value[0] {
+	case '+':
+		result = slices.Clone(defaults)
+		for _, alg := range splitList(value[1:]) {
+			if !slices.Contains(result, alg) {
+				result = append(result, alg)
+			}
+		}
+	case '-':
+		patterns := splitList(value[1:])
+		for _, alg := range defaults {
+			if !slices.ContainsFunc(patterns, func(p string) bool { return algorithmMatches(p, alg) }) {
+				result = append(result, alg)
+			}
+		}
+	case '^':
+		result = splitList(value[1:])
+		for _, alg := range defaults {
+			if !slices.Contains(result, alg) {
+				result = append(result, alg)
+			}
+		}
+	default:
+		result = splitList(value)
+	}
+
+	var usable, unsupported []string
+	for _, alg := range result {
+		if slices.Contains(supported, alg) {
+			usable = append(usable, alg)
+		} else {
+			unsupported = append(unsupported, alg)
+		}
+	}
+	if len(unsupported) > 0 {
+		logger.Printf("Warning: %s for %s contains unsupported algorithms, ignoring: %s", key, alias, strings.Join(unsupported, ","))
+	}
+	if len(usable) == 0 {
+		logger.Printf("Warning: %s for %s leaves no supported algorithm, using defaults", key, alias)
+		return nil
+	}
+	return usable
+}
+
+// algorithmMatches 判断算法名是否匹配 ssh_config 中的模式（支持 * 与 ?）
+func algorithmMatches(pattern, alg string) bool {
+	ok, err := path.Match(pattern, alg)
+	return err == nil && ok
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// namedAuthMethod 是带有 ssh_config 名称的认证方式，用于按 PreferredAuthentications 排序
+type namedAuthMethod struct {
+	name   string
+	method ssh.AuthMethod
+}
+
+// orderAuthMethods 按 preferred 中的顺序排列认证方式；preferred 为空时保持原顺序。
+// 未在 preferred 中列出的方式不会被使用，与 OpenSSH 的行为一致。
+func orderAuthMethods(methods []namedAuthMethod, preferred []string) []ssh.AuthMethod {
+	var ordered []ssh.AuthMethod
+	if len(preferred) == 0 {
+		for _, m := range methods {
+			ordered = append(ordered, m.method)
+		}
+		return ordered
+	}
+	for _, name := range preferred {
+		for _, m := range methods {
+			if strings.EqualFold(m.name, name) {
+				ordered = append(ordered, m.method)
+			}
+		}
+	}
+	return ordered
+}
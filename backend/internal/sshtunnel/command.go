@@ -0,0 +1,85 @@
+package sshtunnel
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"devtools/backend/internal/sshmanager"
+)
+
+// --- Export of a saved tunnel as an OpenSSH command line ---
+
+// BuildCommand returns the ssh command line that sets up the same forward as config, connecting
+// with the options of target (see sshmanager.GetCommandConfig). Only the primary target of a
+// load-balanced tunnel is exported, and the DNS forwarder, SOCKS rules and hooks have no ssh
+// equivalent. Jump hosts are passed with -J, so their identity files must be in the ssh-agent.
+func BuildCommand(config SavedTunnelConfig, target *sshmanager.ConnectionConfig) (string, error) {
+	bind := ""
+	if config.GatewayPorts {
+		bind = "0.0.0.0:"
+	}
+
+	// ExitOnForwardFailure makes ssh fail instead of staying connected without the forward,
+	// which is what the app does when the local port is taken.
+	args := []string{"ssh", "-N", "-o", "ExitOnForwardFailure=yes"}
+	switch config.TunnelType {
+	case "local":
+		remoteHost := config.RemoteHost
+		if strings.Contains(remoteHost, ":") {
+			remoteHost = "[" + remoteHost + "]" // IPv6 address
+		}
+		args = append(args, "-L", fmt.Sprintf("%s%d:%s:%d", bind, config.LocalPort, remoteHost, config.RemotePort))
+	case "dynamic":
+		args = append(args, "-D", bind+strconv.Itoa(config.LocalPort))
+	default:
+		return "", fmt.Errorf("unknown tunnel type '%s'", config.TunnelType)
+	}
+
+	if target.Port != "" && target.Port != "22" {
+		args = append(args, "-p", target.Port)
+	}
+	if target.IdentityFile != "" {
+		args = append(args, "-i", target.IdentityFile)
+	}
+	if len(target.JumpHosts) > 0 {
+		hops := make([]string, len(target.JumpHosts))
+		for i, hop := range target.JumpHosts {
+			hops[i] = userHost(hop.User, hop.HostName)
+			if hop.Port != "" && hop.Port != "22" {
+				hops[i] = userHost(hop.User, net.JoinHostPort(hop.HostName, hop.Port))
+			}
+		}
+		args = append(args, "-J", strings.Join(hops, ","))
+	} else if target.ProxyCommand != "" {
+		args = append(args, "-o", "ProxyCommand="+target.ProxyCommand)
+	}
+	args = append(args, userHost(target.User, target.HostName))
+
+	for i, arg := range args {
+		args[i] = quoteShellArg(arg)
+	}
+	return strings.Join(args, " "), nil
+}
+
+func userHost(user, host string) string {
+	if user == "" {
+		return host
+	}
+	return user + "@" + host
+}
+
+// quoteShellArg wraps arg in single quotes when a POSIX shell would otherwise split or expand it.
+// A leading ~/ is left unquoted so the path is expanded on the machine the command runs on.
+func quoteShellArg(arg string) string {
+	if rest, ok := strings.CutPrefix(arg, "~/"); ok && rest != "" {
+		return "~/" + quoteShellArg(rest)
+	}
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./-_", r))
+	}) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,207 @@
+package sshtunnel
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// ConnectionEventType identifies a step in the lifecycle of a forwarded connection.
+type ConnectionEventType string
+
+const (
+	// EventAccepted means a local client connection was accepted by the listener.
+	EventAccepted ConnectionEventType = "accepted"
+	// EventDialed means the remote side was successfully dialed through the SSH client.
+	EventDialed ConnectionEventType = "dialed"
+	// EventClosed means the connection finished and both directions were closed.
+	EventClosed ConnectionEventType = "closed"
+	// EventDenied means a SOCKS request was refused by the tunnel's destination rules.
+	EventDenied ConnectionEventType = "denied"
+	// EventError means the connection failed at some step (see Error and Stage).
+	EventError ConnectionEventType = "error"
+)
+
+// defaultConnectionLogSize is the number of events kept per tunnel.
+const defaultConnectionLogSize = 200
+
+// ConnectionEvent is a single structured entry in a tunnel's connection log.
+type ConnectionEvent struct {
+	Time       string              `json:"time"` // RFC3339Nano, for easy parsing in JavaScript
+	ConnID     uint64              `json:"connId"`
+	Type       ConnectionEventType `json:"type"`
+	Stage      string              `json:"stage,omitempty"` // e.g. "socks-handshake", "dial-remote"
+	ClientAddr string              `json:"clientAddr,omitempty"`
+	Target     string              `json:"target,omitempty"`
+	BytesSent  int64               `json:"bytesSent,omitempty"`     // local -> remote
+	BytesRecv  int64               `json:"bytesReceived,omitempty"` // remote -> local
+	DurationMs int64               `json:"durationMs,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	PID        int                 `json:"pid,omitempty"`     // Local process that opened the connection, if known
+	Process    string              `json:"process,omitempty"` // Name of that process
+}
+
+// connectionLog is a bounded, concurrency-safe ring buffer of connection events.
+type connectionLog struct {
+	mu     sync.Mutex
+	events []ConnectionEvent
+	next   int
+	full   bool
+	nextID uint64
+
+	procs     map[uint64]*ProcessInfo  // Process of each open connection, filled into its events
+	openConns map[uint64]bool          // Connections that were accepted and have not ended yet
+	usage     map[string]*ProcessUsage // Per-process totals over the tunnel's lifetime
+
+	onEvent func(ConnectionEvent) // Optional; called with every event after it is added, e.g. to write the tunnel's log file
+}
+
+func newConnectionLog(size int) *connectionLog {
+	if size <= 0 {
+		size = defaultConnectionLogSize
+	}
+	return &connectionLog{
+		events:    make([]ConnectionEvent, size),
+		procs:     make(map[uint64]*ProcessInfo),
+		openConns: make(map[uint64]bool),
+		usage:     make(map[string]*ProcessUsage),
+	}
+}
+
+// setProcess records the local process of a connection once the lookup that runs after the accepted
+// event finishes. The connection moves from the unknown process to its process in the usage totals and
+// the events added from then on carry the process. Connections that already ended stay unknown.
+func (l *connectionLog) setProcess(connID uint64, proc *ProcessInfo) {
+	if proc == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, known := l.procs[connID]; known || !l.openConns[connID] {
+		return
+	}
+	l.procs[connID] = proc
+
+	unknown := l.usageFor_nolock("")
+	unknown.Connections--
+	unknown.Active--
+	if unknown.Connections == 0 {
+		delete(l.usage, unknownProcess)
+	}
+	u := l.usageFor_nolock(proc.Name)
+	u.Connections++
+	u.Active++
+	if proc.PID != 0 && !slices.Contains(u.PIDs, proc.PID) {
+		u.PIDs = append(u.PIDs, proc.PID)
+	}
+}
+
+// newConnID allocates a tunnel-local identifier used to correlate events of one connection.
+func (l *connectionLog) newConnID() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.nextID++
+	return l.nextID
+}
+
+// add appends an event, overwriting the oldest one when the buffer is full.
+func (l *connectionLog) add(event ConnectionEvent) {
+	if event.Time == "" {
+		event.Time = time.Now().Format(time.RFC3339Nano)
+	}
+
+	l.mu.Lock()
+	if proc, ok := l.procs[event.ConnID]; ok {
+		event.PID, event.Process = proc.PID, proc.Name
+	}
+	l.recordUsage_nolock(event)
+	l.events[l.next] = event
+	l.next = (l.next + 1) % len(l.events)
+	if l.next == 0 {
+		l.full = true
+	}
+	onEvent := l.onEvent
+	l.mu.Unlock()
+
+	if onEvent != nil {
+		onEvent(event)
+	}
+}
+
+// snapshot returns a copy of the events in chronological order.
+func (l *connectionLog) snapshot() []ConnectionEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.full {
+		out := make([]ConnectionEvent, l.next)
+		copy(out, l.events[:l.next])
+		return out
+	}
+	out := make([]ConnectionEvent, 0, len(l.events))
+	out = append(out, l.events[l.next:]...)
+	out = append(out, l.events[:l.next]...)
+	return out
+}
+
+// recordUsage_nolock updates the per-process totals; a connection ends with its first closed or error event.
+func (l *connectionLog) recordUsage_nolock(event ConnectionEvent) {
+	switch event.Type {
+	case EventAccepted:
+		l.openConns[event.ConnID] = true
+		u := l.usageFor_nolock(event.Process)
+		u.Connections++
+		u.Active++
+		if event.PID != 0 && !slices.Contains(u.PIDs, event.PID) {
+			u.PIDs = append(u.PIDs, event.PID)
+		}
+	case EventClosed, EventError:
+		if l.endConn_nolock(event.ConnID, event.Process) {
+			u := l.usageFor_nolock(event.Process)
+			u.BytesSent += event.BytesSent
+			u.BytesRecv += event.BytesRecv
+		}
+	}
+}
+
+// finish marks a connection as ended if its handler returned without a closed or error event.
+func (l *connectionLog) finish(connID uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var name string
+	if proc, ok := l.procs[connID]; ok {
+		name = proc.Name
+	}
+	l.endConn_nolock(connID, name)
+}
+
+// endConn_nolock removes an open connection and reports whether it was still open.
+func (l *connectionLog) endConn_nolock(connID uint64, process string) bool {
+	if !l.openConns[connID] {
+		return false
+	}
+	delete(l.openConns, connID)
+	delete(l.procs, connID)
+	l.usageFor_nolock(process).Active--
+	return true
+}
+
+// usageFor_nolock returns the usage entry of a process name, creating it if needed.
+func (l *connectionLog) usageFor_nolock(process string) *ProcessUsage {
+	if process == "" {
+		process = unknownProcess
+	}
+	u, ok := l.usage[process]
+	if !ok {
+		u = &ProcessUsage{Name: process, PIDs: []int{}}
+		l.usage[process] = u
+	}
+	return u
+}
+
+// processUsage returns the per-process totals, most active first.
+func (l *connectionLog) processUsage() []ProcessUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return sortedUsage(l.usage)
+}
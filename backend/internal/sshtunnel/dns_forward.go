@@ -0,0 +1,230 @@
+package sshtunnel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"devtools/backend/pkg/utils"
+)
+
+// DefaultDNSResolver is the remote resolver used when a DNS forwarder has none configured.
+const DefaultDNSResolver = "1.1.1.1:53"
+
+// dnsQueryTimeout bounds a single UDP query relayed over the tunnel.
+const dnsQueryTimeout = 10 * time.Second
+
+// DNSForwardConfig configures the optional DNS forwarder of a dynamic (SOCKS) tunnel.
+//
+// Domain-type SOCKS requests are always resolved on the remote side. The forwarder covers
+// applications that resolve names themselves before connecting: it listens next to the
+// SOCKS port and relays every query over the SSH connection to a resolver reachable from
+// the remote host, so no lookups leak to the local network's DNS server.
+type DNSForwardConfig struct {
+	Enabled   bool   `json:"enabled"`
+	LocalPort int    `json:"localPort,omitempty"` // 0 means the SOCKS port + 1
+	Resolver  string `json:"resolver,omitempty"`  // host[:port] as seen from the remote host, default 1.1.1.1:53
+}
+
+// Normalize fills in defaults for the given SOCKS port and validates the configuration.
+func (c DNSForwardConfig) Normalize(socksPort int) (DNSForwardConfig, error) {
+	if c.LocalPort == 0 {
+		c.LocalPort = socksPort + 1
+	}
+	if c.LocalPort < 1 || c.LocalPort > 65535 {
+		return c, fmt.Errorf("invalid DNS forwarder port %d", c.LocalPort)
+	}
+	if c.LocalPort == socksPort {
+		return c, fmt.Errorf("DNS forwarder port must differ from the SOCKS port %d", socksPort)
+	}
+
+	resolver := strings.TrimSpace(c.Resolver)
+	if resolver == "" {
+		resolver = DefaultDNSResolver
+	}
+	if _, _, err := net.SplitHostPort(resolver); err != nil {
+		// No port given (IPv6 literals may be written with or without brackets).
+		resolver = net.JoinHostPort(strings.Trim(resolver, "[]"), "53")
+	}
+	host, port, err := net.SplitHostPort(resolver)
+	if err != nil || host == "" {
+		return c, fmt.Errorf("invalid DNS resolver '%s'", c.Resolver)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return c, fmt.Errorf("invalid DNS resolver port in '%s'", c.Resolver)
+	}
+	c.Resolver = resolver
+	return c, nil
+}
+
+// dnsForwarder relays DNS queries received on a local UDP and TCP port to a remote resolver.
+// SSH can only forward TCP, so UDP queries are converted to DNS-over-TCP (RFC 1035 4.2.2).
+type dnsForwarder struct {
+	addr     string
+	resolver string
+	tcp      net.Listener
+	udp      net.PacketConn
+}
+
+// StartDNSForwarder starts the DNS forwarder of a running dynamic tunnel.
+// It binds to the same interface as the SOCKS listener.
+func (m *Manager) StartDNSForwarder(tunnelID string, cfg DNSForwardConfig) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tunnel, ok := m.activeTunnels[tunnelID]
+	if !ok {
+		return "", fmt.Errorf("tunnel with ID %s not found", tunnelID)
+	}
+	if tunnel.Status != StatusActive {
+		return "", fmt.Errorf("tunnel %s is %s", tunnelID, tunnel.Status)
+	}
+	if tunnel.Type != "dynamic" {
+		return "", fmt.Errorf("DNS forwarding is only available for dynamic tunnels")
+	}
+	if tunnel.dnsForwarder != nil {
+		return tunnel.dnsForwarder.addr, nil
+	}
+
+	bindHost, socksPortStr, err := net.SplitHostPort(tunnel.LocalAddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid tunnel address %s: %w", tunnel.LocalAddr, err)
+	}
+	socksPort, _ := strconv.Atoi(socksPortStr)
+	cfg, err = cfg.Normalize(socksPort)
+	if err != nil {
+		return "", err
+	}
+
+	addr := net.JoinHostPort(bindHost, strconv.Itoa(cfg.LocalPort))
+	tcpListener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	udpConn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		tcpListener.Close()
+		return "", err
+	}
+
+	f := &dnsForwarder{addr: addr, resolver: cfg.Resolver, tcp: tcpListener, udp: udpConn}
+	tunnel.dnsForwarder = f
+
+	utils.SafeGo(logger.StdLogger(), func() { m.serveDNSOverTCP(tunnel, f) })
+	utils.SafeGo(logger.StdLogger(), func() { m.serveDNSOverUDP(tunnel, f) })
+
+	logger.Printf("Tunnel %s: DNS forwarder listening on %s (tcp+udp) -> %s", tunnel.ID, addr, cfg.Resolver)
+	m.debounceChangeEvent()
+	return addr, nil
+}
+
+// close stops both listeners. It is safe to call more than once.
+func (f *dnsForwarder) close() {
+	f.tcp.Close()
+	f.udp.Close()
+}
+
+// serveDNSOverTCP pipes each local DNS-over-TCP connection straight to the resolver.
+func (m *Manager) serveDNSOverTCP(tunnel *Tunnel, f *dnsForwarder) {
+	for {
+		localConn, err := f.tcp.Accept()
+		if err != nil {
+			return // Listener closed during cleanup.
+		}
+		go func() {
+			defer localConn.Close()
+			connID := tunnel.connLog.newConnID()
+			clientAddr := localConn.RemoteAddr().String()
+
+			remoteConn, err := tunnel.sshClient.Dial("tcp", f.resolver)
+			if err != nil {
+				tunnel.connLog.add(ConnectionEvent{
+					ConnID:     connID,
+					Type:       EventError,
+					Stage:      "dns-tcp",
+					ClientAddr: clientAddr,
+					Target:     f.resolver,
+					Error:      err.Error(),
+				})
+				return
+			}
+			defer remoteConn.Close()
+
+			start := time.Now()
+			sent, recv := m.proxyData(localConn, remoteConn)
+			tunnel.connLog.add(ConnectionEvent{
+				ConnID:     connID,
+				Type:       EventClosed,
+				Stage:      "dns-tcp",
+				ClientAddr: clientAddr,
+				Target:     f.resolver,
+				BytesSent:  sent,
+				BytesRecv:  recv,
+				DurationMs: time.Since(start).Milliseconds(),
+			})
+		}()
+	}
+}
+
+// serveDNSOverUDP relays each UDP query to the resolver over a new TCP channel through the tunnel.
+func (m *Manager) serveDNSOverUDP(tunnel *Tunnel, f *dnsForwarder) {
+	buf := make([]byte, 65535)
+	for {
+		n, clientAddr, err := f.udp.ReadFrom(buf)
+		if err != nil {
+			return // Socket closed during cleanup.
+		}
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			reply, err := queryDNSOverTCP(tunnel, f.resolver, query)
+			if err != nil {
+				tunnel.connLog.add(ConnectionEvent{
+					ConnID:     tunnel.connLog.newConnID(),
+					Type:       EventError,
+					Stage:      "dns-udp",
+					ClientAddr: clientAddr.String(),
+					Target:     f.resolver,
+					Error:      err.Error(),
+				})
+				return
+			}
+			if _, err := f.udp.WriteTo(reply, clientAddr); err != nil {
+				logger.Printf("Tunnel %s: failed to send DNS reply to %s: %v", tunnel.ID, clientAddr, err)
+			}
+		}()
+	}
+}
+
+// queryDNSOverTCP sends a single DNS message to resolver using the 2-byte length framing of DNS over TCP.
+func queryDNSOverTCP(tunnel *Tunnel, resolver string, query []byte) ([]byte, error) {
+	conn, err := tunnel.sshClient.Dial("tcp", resolver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// SSH channels don't support deadlines, so bound the exchange with a timer instead.
+	timer := time.AfterFunc(dnsQueryTimeout, func() { conn.Close() })
+	defer timer.Stop()
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, fmt.Errorf("failed to send query: %w", err)
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, fmt.Errorf("failed to read reply length: %w", err)
+	}
+	reply := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, fmt.Errorf("failed to read reply: %w", err)
+	}
+	return reply, nil
+}
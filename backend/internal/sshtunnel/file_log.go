@@ -0,0 +1,255 @@
+package sshtunnel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// --- Persistent per-tunnel logs ---
+
+// TunnelLogDirName is the directory under the app's log directory that holds the per-tunnel log files.
+const TunnelLogDirName = "tunnels"
+
+const (
+	tunnelLogDateLayout = "2006-01-02"
+	tunnelLogExt        = ".log"
+)
+
+// fileLogConfig controls the per-tunnel log files; guarded by Manager.fileLogMu.
+type fileLogConfig struct {
+	dir           string
+	enabled       bool
+	retentionDays int
+}
+
+// tunnelFileLog appends the lifecycle and connection events of one tunnel to
+// <dir>/<configID>_<name>-<date>.log, starting a new file every day. Unlike the in-memory connection log it
+// survives restarts, so a tunnel that kept dropping overnight can be investigated the next morning.
+// A nil *tunnelFileLog discards everything, which is what tunnels get when file logging is off.
+type tunnelFileLog struct {
+	mu            sync.Mutex
+	dir           string
+	name          string
+	retentionDays int
+	date          string   // Date of the open file; a write on another day opens a new file
+	file          *os.File // nil if the file of the current date could not be opened
+	closed        bool
+}
+
+// printf writes one timestamped line. Failures to write are logged once per file and otherwise
+// ignored: the tunnel must keep working when the disk is full.
+func (l *tunnelFileLog) printf(format string, args ...any) {
+	if l == nil {
+		return
+	}
+	now := time.Now()
+	date := now.Format(tunnelLogDateLayout)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	if date != l.date {
+		l.open_nolock(date, now)
+	}
+	if l.file == nil {
+		return
+	}
+	line := fmt.Sprintf("%s %s\n", now.Format("2006/01/02 15:04:05.000"), fmt.Sprintf(format, args...))
+	if _, err := l.file.WriteString(line); err != nil {
+		logger.Printf("Warning: failed to write tunnel log %s: %v", l.file.Name(), err)
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+// open_nolock closes the current file and opens the one for date, purging expired files of all
+// tunnels on the way. The caller must hold l.mu.
+func (l *tunnelFileLog) open_nolock(date string, now time.Time) {
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+	l.date = date
+	if err := os.MkdirAll(l.dir, 0o750); err != nil {
+		logger.Printf("Warning: failed to create tunnel log directory %s: %v", l.dir, err)
+		return
+	}
+	purgeTunnelLogs(l.dir, l.retentionDays, now)
+	path := filepath.Join(l.dir, l.name+"-"+date+tunnelLogExt)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		logger.Printf("Warning: failed to open tunnel log %s: %v", path, err)
+		return
+	}
+	l.file = f
+}
+
+// close closes the file; later writes, e.g. of connections that outlive the tunnel, are dropped.
+func (l *tunnelFileLog) close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+// logEvent writes a connection event as a single key=value line.
+func (l *tunnelFileLog) logEvent(event ConnectionEvent) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "conn=%d %s", event.ConnID, event.Type)
+	field := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, " %s=%q", key, value)
+		}
+	}
+	field("stage", event.Stage)
+	field("client", event.ClientAddr)
+	field("target", event.Target)
+	if event.Process != "" {
+		field("process", fmt.Sprintf("%s (%d)", event.Process, event.PID))
+	}
+	if event.Type == EventClosed {
+		fmt.Fprintf(&b, " sent=%d received=%d duration=%dms", event.BytesSent, event.BytesRecv, event.DurationMs)
+	}
+	field("error", event.Error)
+	l.printf("%s", b.String())
+}
+
+// SetFileLogging turns the per-tunnel log files in dir on or off and sets how many days of files
+// are kept. It applies to tunnels started afterwards; expired files are purged right away.
+func (m *Manager) SetFileLogging(dir string, enabled bool, retentionDays int) {
+	m.fileLogMu.Lock()
+	m.fileLog = fileLogConfig{dir: dir, enabled: enabled, retentionDays: retentionDays}
+	m.fileLogMu.Unlock()
+	if dir != "" {
+		purgeTunnelLogs(dir, retentionDays, time.Now())
+	}
+}
+
+// openFileLog returns the file log of a tunnel named name, or nil when file logging is off.
+func (m *Manager) openFileLog(name, configID string) *tunnelFileLog {
+	m.fileLogMu.RLock()
+	defer m.fileLogMu.RUnlock()
+	if !m.fileLog.enabled || m.fileLog.dir == "" {
+		return nil
+	}
+	return &tunnelFileLog{
+		dir:           m.fileLog.dir,
+		name:          tunnelLogName(name, configID),
+		retentionDays: m.fileLog.retentionDays,
+	}
+}
+
+// TunnelLogPath returns the most recent log file of the tunnel configID. Files are found by the
+// config ID alone, so the history of a renamed tunnel is still found; name is only used in errors.
+func (m *Manager) TunnelLogPath(name, configID string) (string, error) {
+	m.fileLogMu.RLock()
+	dir := m.fileLog.dir
+	m.fileLogMu.RUnlock()
+	if dir == "" {
+		return "", fmt.Errorf("tunnel log directory is not configured")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read tunnel log directory: %w", err)
+	}
+	var latest, latestDate string
+	var latestMod time.Time
+	for _, entry := range entries {
+		date, ok := tunnelLogDate(entry.Name(), configID)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		// A tunnel renamed during the day has a file under each name for that day
+		if date > latestDate || date == latestDate && info.ModTime().After(latestMod) {
+			latest, latestDate, latestMod = entry.Name(), date, info.ModTime()
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no log file found for tunnel '%s'", name)
+	}
+	return filepath.Join(dir, latest), nil
+}
+
+// tunnelLogDate reports whether file is a log file of the tunnel configID and returns its date.
+// The name part between the config ID and the date is ignored, it changes when the tunnel is renamed.
+func tunnelLogDate(file, configID string) (string, bool) {
+	rest, ok := strings.CutPrefix(file, configID)
+	if !ok || configID == "" {
+		return "", false
+	}
+	base, ok := strings.CutSuffix(rest, tunnelLogExt)
+	// "abc-2024-05-01.log" or "abc_db-2024-05-01.log" for "abc", but nothing of a config "abcd"
+	if !ok || len(base) <= len(tunnelLogDateLayout) || base[0] != '_' && base[0] != '-' {
+		return "", false
+	}
+	date := base[len(base)-len(tunnelLogDateLayout):]
+	if base[len(base)-len(tunnelLogDateLayout)-1] != '-' {
+		return "", false
+	}
+	if _, err := time.Parse(tunnelLogDateLayout, date); err != nil {
+		return "", false
+	}
+	return date, true
+}
+
+// tunnelLogName returns the file name prefix of a tunnel: the config ID, which keeps files of
+// tunnels with similar names apart and survives renames, followed by the name for readability.
+// Letters and digits of any script are kept so that non-English names stay readable; names with
+// nothing left use the config ID alone.
+func tunnelLogName(name, configID string) string {
+	cleaned := strings.Trim(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(name)), "._")
+	if cleaned == "" {
+		return configID
+	}
+	return configID + "_" + cleaned
+}
+
+// purgeTunnelLogs deletes the log files in dir that are older than retentionDays days.
+// retentionDays <= 0 keeps all files.
+func purgeTunnelLogs(dir string, retentionDays int, now time.Time) {
+	if retentionDays <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	year, month, day := now.Date()
+	cutoff := time.Date(year, month, day-retentionDays, 0, 0, 0, 0, time.Local)
+	for _, entry := range entries {
+		base, ok := strings.CutSuffix(entry.Name(), tunnelLogExt)
+		if !ok || entry.IsDir() || len(base) < len(tunnelLogDateLayout) {
+			continue
+		}
+		date, err := time.ParseInLocation(tunnelLogDateLayout, base[len(base)-len(tunnelLogDateLayout):], time.Local)
+		if err != nil || !date.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			logger.Printf("Warning: failed to delete expired tunnel log %s: %v", entry.Name(), err)
+		}
+	}
+}
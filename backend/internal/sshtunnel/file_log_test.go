@@ -0,0 +1,107 @@
+package sshtunnel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestTunnelLogName checks that file names start with the config ID and keep a readable name.
+func TestTunnelLogName(t *testing.T) {
+	tests := []struct {
+		name, configID, want string
+	}{
+		{"db-prod", "id1", "id1_db-prod"},
+		{"db/prod", "id1", "id1_db_prod"},
+		{"  数据库 生产 ", "id1", "id1_数据库_生产"},
+		{"../etc", "id1", "id1_etc"},
+		{"???", "id1", "id1"},
+		{"", "id1", "id1"},
+	}
+	for _, tt := range tests {
+		if got := tunnelLogName(tt.name, tt.configID); got != tt.want {
+			t.Errorf("tunnelLogName(%q, %q) = %q, want %q", tt.name, tt.configID, got, tt.want)
+		}
+	}
+	// Names that sanitize to the same text stay apart through their config IDs
+	if tunnelLogName("db/prod", "a") == tunnelLogName("db prod", "b") {
+		t.Error("tunnels with similar names must not share a log file")
+	}
+}
+
+// TestTunnelLogPath checks that the latest file is found by config ID, across renames, without
+// matching configs whose ID merely starts with the same text.
+func TestTunnelLogPath(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manager{}
+	if _, err := m.TunnelLogPath("db", "abc"); err == nil {
+		t.Fatal("an error is expected while the log directory is not configured")
+	}
+	m.SetFileLogging(dir, true, 0)
+
+	touch := func(name string, mod time.Time) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	touch("abc_db-2024-05-01.log", now.Add(-48*time.Hour))
+	touch("abc_db-2024-05-02.log", now.Add(-2*time.Hour))
+	touch("abc_db-renamed-2024-05-02.log", now.Add(-time.Hour)) // renamed during the day
+	touch("abcd_db-2024-05-09.log", now)                        // another config whose ID starts with "abc"
+	touch("abc_db-notes.log", now)                              // no date
+	touch("abc_db-2024-05-03.txt", now)
+
+	path, err := m.TunnelLogPath("db-renamed", "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "abc_db-renamed-2024-05-02.log"); path != want {
+		t.Errorf("TunnelLogPath = %q, want %q", path, want)
+	}
+
+	touch("xyz-2024-05-04.log", now) // a name with nothing left after sanitizing
+	if path, err := m.TunnelLogPath("???", "xyz"); err != nil || filepath.Base(path) != "xyz-2024-05-04.log" {
+		t.Errorf("TunnelLogPath for a config without a readable name = %q, %v", path, err)
+	}
+	if _, err := m.TunnelLogPath("web", "missing"); err == nil {
+		t.Error("an error is expected for a tunnel without log files")
+	}
+}
+
+// TestPurgeTunnelLogs checks that only dated log files older than the retention are deleted.
+func TestPurgeTunnelLogs(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"abc_db-2024-04-20.log", // older than the retention
+		"abc_db-2024-04-24.log", // first day kept
+		"abc_db-2024-05-01.log",
+		"notes.log",
+		"abc_db-2024-04-01.txt",
+	}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+
+	purgeTunnelLogs(dir, 0, now)
+	if entries, _ := os.ReadDir(dir); len(entries) != len(files) {
+		t.Fatalf("retention 0 must keep all files, %d left", len(entries))
+	}
+
+	purgeTunnelLogs(dir, 7, now)
+	for _, name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if deleted := os.IsNotExist(err); deleted != (name == "abc_db-2024-04-20.log") {
+			t.Errorf("%s deleted = %v", name, deleted)
+		}
+	}
+}
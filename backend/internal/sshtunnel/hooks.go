@@ -0,0 +1,162 @@
+package sshtunnel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"devtools/backend/pkg/utils"
+)
+
+const (
+	// defaultHookTimeout is used when TunnelHooks.TimeoutSeconds is 0.
+	defaultHookTimeout = 30 * time.Second
+	// maxHookOutput is how much of a hook's output is kept in the "tunnel:hook" event.
+	maxHookOutput = 4 * 1024
+)
+
+// Lifecycle events a hook runs on.
+const (
+	HookEventUp   = "up"
+	HookEventDown = "down"
+)
+
+// Reasons passed to the down hook in DEVTOOLS_TUNNEL_REASON.
+const (
+	HookReasonStopped      = "stopped"      // stopped by the user, or the app is quitting
+	HookReasonDisconnected = "disconnected" // the SSH connection was lost
+)
+
+// TunnelHooks are local shell commands run when a tunnel comes up and when it goes down, e.g. to
+// re-point a local service at the forwarded port or to send a notification when a DB forward drops.
+// Hooks run in the background and never affect the tunnel itself. They get the tunnel details in
+// DEVTOOLS_TUNNEL_* environment variables: ID, CONFIG_ID, ALIAS, TYPE, EVENT, LOCAL_ADDR,
+// LOCAL_PORT, REMOTE_ADDR, and for down hooks REASON and MESSAGE.
+type TunnelHooks struct {
+	OnUp           string `json:"onUp,omitempty"`
+	OnDown         string `json:"onDown,omitempty"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty"` // 0 means 30 seconds
+}
+
+// Normalize trims the commands and checks the timeout.
+func (h TunnelHooks) Normalize() (TunnelHooks, error) {
+	h.OnUp = strings.TrimSpace(h.OnUp)
+	h.OnDown = strings.TrimSpace(h.OnDown)
+	if h.TimeoutSeconds < 0 || h.TimeoutSeconds > 600 {
+		return h, fmt.Errorf("hook timeout must be between 0 and 600 seconds")
+	}
+	return h, nil
+}
+
+// IsEmpty reports whether no hook command is set.
+func (h TunnelHooks) IsEmpty() bool {
+	return h.OnUp == "" && h.OnDown == ""
+}
+
+func (h TunnelHooks) timeout() time.Duration {
+	if h.TimeoutSeconds <= 0 {
+		return defaultHookTimeout
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+// TunnelHookResult is the payload of the "tunnel:hook" event, emitted after every hook run.
+type TunnelHookResult struct {
+	TunnelID   string `json:"tunnelId"`
+	ConfigID   string `json:"configId"`
+	Event      string `json:"event"` // up | down
+	Command    string `json:"command"`
+	Output     string `json:"output,omitempty"`
+	ExitCode   int    `json:"exitCode"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// runHook runs the tunnel's hook for event in the background. message is the status message
+// of the tunnel, passed to down hooks together with reason.
+func (m *Manager) runHook(tunnel *Tunnel, event, reason, message string) {
+	if tunnel.hooks == nil {
+		return
+	}
+	command := tunnel.hooks.OnUp
+	if event == HookEventDown {
+		command = tunnel.hooks.OnDown
+	}
+	if command == "" {
+		return
+	}
+
+	env := []string{
+		"DEVTOOLS_TUNNEL_ID=" + tunnel.ID,
+		"DEVTOOLS_TUNNEL_CONFIG_ID=" + tunnel.ConfigID,
+		"DEVTOOLS_TUNNEL_ALIAS=" + tunnel.Alias,
+		"DEVTOOLS_TUNNEL_TYPE=" + tunnel.Type,
+		"DEVTOOLS_TUNNEL_EVENT=" + event,
+		"DEVTOOLS_TUNNEL_LOCAL_ADDR=" + tunnel.LocalAddr,
+		"DEVTOOLS_TUNNEL_REMOTE_ADDR=" + tunnel.RemoteAddr,
+	}
+	if _, port, err := net.SplitHostPort(tunnel.LocalAddr); err == nil {
+		env = append(env, "DEVTOOLS_TUNNEL_LOCAL_PORT="+port)
+	}
+	if event == HookEventDown {
+		env = append(env, "DEVTOOLS_TUNNEL_REASON="+reason, "DEVTOOLS_TUNNEL_MESSAGE="+message)
+	}
+	timeout := tunnel.hooks.timeout()
+
+	utils.SafeGo(logger.StdLogger(), func() {
+		result := runHookCommand(command, env, timeout)
+		result.TunnelID, result.ConfigID, result.Event = tunnel.ID, tunnel.ConfigID, event
+		if result.Error != "" {
+			logger.Printf("Warning: %s hook of tunnel %s failed: %s", event, tunnel.ID, result.Error)
+		} else {
+			logger.Printf("%s hook of tunnel %s finished in %dms.", event, tunnel.ID, result.DurationMs)
+		}
+		utils.EmitEvent(m.appCtx, "tunnel:hook", result)
+	})
+}
+
+// runHookCommand runs command with the system shell, adding env to the app's environment.
+func runHookCommand(command string, env []string, timeout time.Duration) TunnelHookResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/c", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	start := time.Now()
+	err := cmd.Run()
+	result := TunnelHookResult{Command: command, DurationMs: time.Since(start).Milliseconds()}
+	result.Output = strings.TrimSpace(out.String())
+	if len(result.Output) > maxHookOutput {
+		result.Output = result.Output[len(result.Output)-maxHookOutput:]
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = fmt.Sprintf("exit status %d", result.ExitCode)
+	case err != nil:
+		result.ExitCode = -1
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+package sshtunnel
+
+import (
+	"sync"
+	"time"
+
+	"devtools/backend/pkg/utils"
+)
+
+// latencyWindowSize is the number of recent samples the rolling averages are computed over.
+const latencyWindowSize = 10
+
+// TunnelLatency describes how responsive a tunnel is. All durations are in milliseconds.
+type TunnelLatency struct {
+	ConnectMs  float64 `json:"connectMs"`            // Time it took to establish the SSH connection
+	RTTMs      float64 `json:"rttMs,omitempty"`      // Round-trip time of the most recent keep-alive
+	AvgRTTMs   float64 `json:"avgRttMs,omitempty"`   // Rolling average of the keep-alive round-trip time
+	RTTSamples int     `json:"rttSamples,omitempty"` // Number of samples in AvgRTTMs
+	AvgDialMs  float64 `json:"avgDialMs,omitempty"`  // Rolling average of the time to open a channel to the remote target
+	Slow       bool    `json:"slow,omitempty"`       // AvgRTTMs is above the slow latency threshold
+}
+
+// TunnelSlowEvent is the payload of the "tunnel:slow" event.
+type TunnelSlowEvent struct {
+	TunnelID    string  `json:"tunnelId"`
+	ConfigID    string  `json:"configId"`
+	Alias       string  `json:"alias"`
+	AvgRTTMs    float64 `json:"avgRttMs"`
+	ThresholdMs float64 `json:"thresholdMs"`
+}
+
+// latencyWindow keeps the most recent samples of a duration.
+type latencyWindow struct {
+	samples [latencyWindowSize]time.Duration
+	next    int
+	count   int
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+	if w.count < len(w.samples) {
+		w.count++
+	}
+}
+
+func (w *latencyWindow) last() time.Duration {
+	if w.count == 0 {
+		return 0
+	}
+	return w.samples[(w.next+len(w.samples)-1)%len(w.samples)]
+}
+
+func (w *latencyWindow) average() time.Duration {
+	if w.count == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range w.samples[:w.count] {
+		total += d
+	}
+	return total / time.Duration(w.count)
+}
+
+// latencyTracker collects the latency measurements of one tunnel; it is safe for concurrent use.
+type latencyTracker struct {
+	mu      sync.Mutex
+	connect time.Duration
+	rtt     latencyWindow
+	dial    latencyWindow
+	slow    bool
+}
+
+func newLatencyTracker(connect time.Duration) *latencyTracker {
+	return &latencyTracker{connect: connect}
+}
+
+// addRTT records a keep-alive round trip and returns the new rolling average.
+func (t *latencyTracker) addRTT(d time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rtt.add(d)
+	return t.rtt.average()
+}
+
+// addDial records how long it took to open a channel to the remote target.
+func (t *latencyTracker) addDial(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dial.add(d)
+}
+
+// setSlow updates the slow flag and reports whether it changed.
+func (t *latencyTracker) setSlow(slow bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	changed := t.slow != slow
+	t.slow = slow
+	return changed
+}
+
+func (t *latencyTracker) snapshot() *TunnelLatency {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &TunnelLatency{
+		ConnectMs:  durationMs(t.connect),
+		RTTMs:      durationMs(t.rtt.last()),
+		AvgRTTMs:   durationMs(t.rtt.average()),
+		RTTSamples: t.rtt.count,
+		AvgDialMs:  durationMs(t.dial.average()),
+		Slow:       t.slow,
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// SetSlowLatencyThreshold sets the average round-trip time above which a tunnel is reported as slow; 0 disables it.
+func (m *Manager) SetSlowLatencyThreshold(d time.Duration) {
+	m.slowLatency.Store(int64(d))
+}
+
+// recordRTT adds a keep-alive round trip to the tunnel's statistics. When the rolling average
+// crosses the slow latency threshold a "tunnel:slow" event is emitted, so users can tell why
+// the forwarded service feels slow; the tunnel's Slow flag is cleared again once it recovers.
+func (m *Manager) recordRTT(tunnel *Tunnel, d time.Duration) {
+	avg := tunnel.latency.addRTT(d)
+	threshold := time.Duration(m.slowLatency.Load())
+	slow := threshold > 0 && avg > threshold
+	if !tunnel.latency.setSlow(slow) {
+		return
+	}
+	if slow {
+		logger.Printf("Tunnel %s (alias: %s) is slow: average round-trip time %s exceeds %s.", tunnel.ID, tunnel.Alias, avg, threshold)
+		tunnel.fileLog.printf("Slow: average round-trip time %s exceeds %s", avg.Round(time.Millisecond), threshold)
+		utils.EmitEvent(m.appCtx, "tunnel:slow", TunnelSlowEvent{
+			TunnelID:    tunnel.ID,
+			ConfigID:    tunnel.ConfigID,
+			Alias:       tunnel.Alias,
+			AvgRTTMs:    durationMs(avg),
+			ThresholdMs: durationMs(threshold),
+		})
+	} else {
+		tunnel.fileLog.printf("Latency recovered: average round-trip time %s", avg.Round(time.Millisecond))
+	}
+	m.debounceChangeEvent()
+}
@@ -0,0 +1,253 @@
+package sshtunnel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"devtools/backend/pkg/utils"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Load balancing modes for local forwards with several remote targets.
+const (
+	// BalanceRoundRobin spreads new connections evenly across all healthy targets.
+	BalanceRoundRobin = "round-robin"
+	// BalanceFailover sends every connection to the first healthy target in the list.
+	BalanceFailover = "failover"
+)
+
+const (
+	// healthCheckInterval is how often every target is dialed through the SSH connection.
+	healthCheckInterval = 15 * time.Second
+	// healthCheckTimeout bounds a single health check dial.
+	healthCheckTimeout = 5 * time.Second
+)
+
+// LoadBalanceConfig lets a local forward distribute connections across several remote targets.
+// RemoteHost:RemotePort of the tunnel is always the first target.
+type LoadBalanceConfig struct {
+	Targets []string `json:"targets"`        // Additional targets as "host:port"
+	Mode    string   `json:"mode,omitempty"` // "round-robin" (default) or "failover"
+}
+
+// Normalize validates the config and returns a copy whose Targets start with primary
+// (the tunnel's RemoteHost:RemotePort), without duplicates, and with the default mode filled in.
+func (c LoadBalanceConfig) Normalize(primary string) (LoadBalanceConfig, error) {
+	switch c.Mode {
+	case "":
+		c.Mode = BalanceRoundRobin
+	case BalanceRoundRobin, BalanceFailover:
+	default:
+		return c, fmt.Errorf("unsupported load balancing mode '%s'", c.Mode)
+	}
+
+	targets := []string{primary}
+	for _, target := range c.Targets {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(target)
+		if err != nil || host == "" {
+			return c, fmt.Errorf("invalid remote target '%s': expected host:port", target)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return c, fmt.Errorf("invalid port in remote target '%s'", target)
+		}
+		target = net.JoinHostPort(host, port)
+		if !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	c.Targets = targets
+	return c, nil
+}
+
+// TargetStatus is the health of one remote target, shown next to the active tunnel.
+type TargetStatus struct {
+	Addr        string `json:"addr"`
+	Healthy     bool   `json:"healthy"`
+	LastError   string `json:"lastError,omitempty"`
+	LastChecked string `json:"lastChecked,omitempty"` // RFC3339
+	Connections int64  `json:"connections"`           // Connections routed to this target so far
+}
+
+// targetPool picks the remote target for each new connection of a local forward
+// and tracks which targets are dead. Dead targets are skipped until the health checker
+// (or a later successful dial) marks them healthy again.
+type targetPool struct {
+	mode     string
+	mu       sync.Mutex
+	targets  []*TargetStatus
+	next     int
+	onChange func()
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newTargetPool(cfg LoadBalanceConfig, onChange func()) *targetPool {
+	p := &targetPool{mode: cfg.Mode, onChange: onChange, stop: make(chan struct{})}
+	for _, addr := range cfg.Targets {
+		p.targets = append(p.targets, &TargetStatus{Addr: addr, Healthy: true})
+	}
+	return p
+}
+
+// candidates returns the targets in the order they should be tried for a new connection:
+// healthy targets first (rotated for round-robin), dead ones last as a fallback.
+func (p *targetPool) candidates() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	start := 0
+	if p.mode == BalanceRoundRobin {
+		start = p.next
+		p.next = (p.next + 1) % len(p.targets)
+	}
+	var healthy, dead []string
+	for i := range p.targets {
+		t := p.targets[(start+i)%len(p.targets)]
+		if t.Healthy {
+			healthy = append(healthy, t.Addr)
+		} else {
+			dead = append(dead, t.Addr)
+		}
+	}
+	return append(healthy, dead...)
+}
+
+// dial connects to the first reachable candidate through client.
+func (p *targetPool) dial(client *ssh.Client) (net.Conn, string, error) {
+	var lastErr error
+	for _, addr := range p.candidates() {
+		conn, err := client.Dial("tcp", addr)
+		p.mark(addr, err)
+		if err == nil {
+			p.mu.Lock()
+			p.find(addr).Connections++
+			p.mu.Unlock()
+			return conn, addr, nil
+		}
+		lastErr = err
+		logger.Printf("Remote target %s failed: %v. Trying the next target.", addr, err)
+	}
+	return nil, "", fmt.Errorf("all %d remote targets failed, last error: %w", len(p.targets), lastErr)
+}
+
+// mark records the outcome of a dial to addr and reports health changes.
+func (p *targetPool) mark(addr string, err error) {
+	p.mu.Lock()
+	t := p.find(addr)
+	if t == nil {
+		p.mu.Unlock()
+		return
+	}
+	wasHealthy := t.Healthy
+	t.Healthy = err == nil
+	t.LastChecked = time.Now().Format(time.RFC3339)
+	if err != nil {
+		t.LastError = err.Error()
+	} else {
+		t.LastError = ""
+	}
+	p.mu.Unlock()
+
+	if wasHealthy != (err == nil) {
+		if err != nil {
+			logger.Printf("Remote target %s marked as dead: %v", addr, err)
+		} else {
+			logger.Printf("Remote target %s is healthy again.", addr)
+		}
+		if p.onChange != nil {
+			p.onChange()
+		}
+	}
+}
+
+// find returns the target with addr; the caller must hold p.mu.
+func (p *targetPool) find(addr string) *TargetStatus {
+	for _, t := range p.targets {
+		if t.Addr == addr {
+			return t
+		}
+	}
+	return nil
+}
+
+// statuses returns a copy of the target states.
+func (p *targetPool) statuses() []TargetStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]TargetStatus, len(p.targets))
+	for i, t := range p.targets {
+		out[i] = *t
+	}
+	return out
+}
+
+// runHealthChecks periodically dials every target through client until the pool is closed.
+func (p *targetPool) runHealthChecks(client *ssh.Client) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			for _, t := range p.statuses() {
+				ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+				conn, err := client.DialContext(ctx, "tcp", t.Addr)
+				cancel()
+				if conn != nil {
+					conn.Close()
+				}
+				p.mark(t.Addr, err)
+			}
+		}
+	}
+}
+
+// close stops the health checker. It is safe to call more than once.
+func (p *targetPool) close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// StartLoadBalancer makes a running local-forward tunnel distribute new connections across
+// the targets of cfg (in addition to its own remote address) and starts the health checker.
+func (m *Manager) StartLoadBalancer(tunnelID string, cfg LoadBalanceConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tunnel, ok := m.activeTunnels[tunnelID]
+	if !ok {
+		return fmt.Errorf("tunnel with ID %s not found", tunnelID)
+	}
+	if tunnel.Status != StatusActive {
+		return fmt.Errorf("tunnel %s is %s", tunnelID, tunnel.Status)
+	}
+	if tunnel.Type != "local" {
+		return fmt.Errorf("multiple remote targets are only available for local tunnels")
+	}
+	if tunnel.targets.Load() != nil {
+		return nil
+	}
+
+	cfg, err := cfg.Normalize(tunnel.RemoteAddr)
+	if err != nil {
+		return err
+	}
+	pool := newTargetPool(cfg, m.debounceChangeEvent)
+	tunnel.targets.Store(pool)
+	utils.SafeGo(logger.StdLogger(), func() { pool.runHealthChecks(tunnel.sshClient) })
+
+	logger.Printf("Tunnel %s: balancing connections (%s) across %s", tunnel.ID, cfg.Mode, strings.Join(cfg.Targets, ", "))
+	m.debounceChangeEvent()
+	return nil
+}
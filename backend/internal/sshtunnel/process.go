@@ -0,0 +1,101 @@
+package sshtunnel
+
+import (
+	"net"
+	"sort"
+)
+
+// ProcessInfo identifies the local process that opened a connection to a tunnel.
+type ProcessInfo struct {
+	PID  int    `json:"pid"`
+	Name string `json:"name"`
+}
+
+// ProcessUsage aggregates the connections of one local process to a tunnel,
+// e.g. to show "Chrome is using my SOCKS proxy" at a glance.
+type ProcessUsage struct {
+	Name        string `json:"name"`
+	PIDs        []int  `json:"pids"`
+	Connections int    `json:"connections"`
+	Active      int    `json:"active"`                  // Connections that are still open
+	BytesSent   int64  `json:"bytesSent,omitempty"`     // local -> remote, closed connections only
+	BytesRecv   int64  `json:"bytesReceived,omitempty"` // remote -> local, closed connections only
+}
+
+// unknownProcess is the usage key for connections whose process could not be resolved.
+const unknownProcess = "unknown"
+
+// maxProcessLookups bounds the process lookups running at the same time across all tunnels.
+// A lookup may start lsof or scan /proc, so a burst of connections (e.g. a browser behind a SOCKS
+// tunnel) would otherwise start one per connection.
+const maxProcessLookups = 4
+
+// processLookups holds a slot for each running lookup.
+var processLookups = make(chan struct{}, maxProcessLookups)
+
+// resolveConnProcessAsync looks up the local process on the client side of conn, an accepted
+// connection of a tunnel listener, in the background and calls done with the result. done is not
+// called for connections from other machines (gateway ports), on platforms without a lookup
+// mechanism, on failure, or when maxProcessLookups lookups are already running; such connections
+// are reported as unknown.
+func resolveConnProcessAsync(conn net.Conn, done func(*ProcessInfo)) {
+	client, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return
+	}
+	server, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return
+	}
+	select {
+	case processLookups <- struct{}{}:
+	default:
+		return
+	}
+	go func() {
+		defer func() { <-processLookups }()
+		if !isLocalIP(client.IP) {
+			return
+		}
+		info, err := lookupProcess(client, server)
+		if err != nil {
+			logger.Printf("Could not resolve process for connection from %s: %v", client, err)
+			return
+		}
+		done(info)
+	}()
+}
+
+// isLocalIP reports whether ip belongs to this machine, i.e. the connecting process runs locally.
+func isLocalIP(ip net.IP) bool {
+	if ip.IsLoopback() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedUsage returns the usage entries ordered by connection count, most active first.
+func sortedUsage(usage map[string]*ProcessUsage) []ProcessUsage {
+	out := make([]ProcessUsage, 0, len(usage))
+	for _, u := range usage {
+		item := *u
+		item.PIDs = append([]int(nil), u.PIDs...)
+		out = append(out, item)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Connections != out[j].Connections {
+			return out[i].Connections > out[j].Connections
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
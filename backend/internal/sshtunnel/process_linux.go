@@ -0,0 +1,107 @@
+package sshtunnel
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// lookupProcess finds the socket client -> server in /proc/net/tcp{,6} and the process
+// holding its inode among the file descriptors in /proc/<pid>/fd.
+func lookupProcess(client, server *net.TCPAddr) (*ProcessInfo, error) {
+	inode := ""
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		var err error
+		if inode, err = findSocketInode(table, client, server); err != nil {
+			return nil, err
+		}
+		if inode != "" {
+			break
+		}
+	}
+	if inode == "" {
+		return nil, fmt.Errorf("socket not found")
+	}
+
+	target := "socket:[" + inode + "]"
+	fdDirs, _ := filepath.Glob("/proc/[0-9]*/fd")
+	self := os.Getpid()
+	for _, dir := range fdDirs {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(dir)))
+		if err != nil || pid == self {
+			continue
+		}
+		fds, err := os.ReadDir(dir)
+		if err != nil {
+			continue // Processes of other users are not readable
+		}
+		for _, fd := range fds {
+			if link, err := os.Readlink(filepath.Join(dir, fd.Name())); err == nil && link == target {
+				name, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+				return &ProcessInfo{PID: pid, Name: strings.TrimSpace(string(name))}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no accessible process owns socket inode %s", inode)
+}
+
+// findSocketInode returns the inode of the socket with local address client and
+// remote address server, or "" when the table has no such socket.
+func findSocketInode(table string, client, server *net.TCPAddr) (string, error) {
+	f, err := os.Open(table)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil // IPv6 disabled
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		local, err1 := parseProcNetAddr(fields[1])
+		remote, err2 := parseProcNetAddr(fields[2])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if sameTCPAddr(local, client) && sameTCPAddr(remote, server) {
+			return fields[9], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// parseProcNetAddr parses "0100007F:1F90": the IP is hex in host byte order per 32-bit word.
+func parseProcNetAddr(s string) (*net.TCPAddr, error) {
+	ipHex, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	raw, err := hex.DecodeString(ipHex)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return nil, err
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+func sameTCPAddr(a, b *net.TCPAddr) bool {
+	return a.Port == b.Port && a.IP.Equal(b.IP)
+}
@@ -0,0 +1,62 @@
+//go:build !linux && !windows
+
+package sshtunnel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lsofTimeout bounds a single lsof invocation.
+const lsofTimeout = 2 * time.Second
+
+// lookupProcess asks lsof which processes have a TCP socket with the client address.
+// Both ends of the connection match, so this process (the tunnel listener) is skipped.
+func lookupProcess(client, _ *net.TCPAddr) (*ProcessInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lsofTimeout)
+	defer cancel()
+
+	host := client.IP.String()
+	if client.IP.To4() == nil {
+		host = "[" + host + "]"
+	}
+	spec := fmt.Sprintf("TCP@%s:%d", host, client.Port)
+	out, err := exec.CommandContext(ctx, "lsof", "-nP", "-i", spec, "-F", "pc").Output()
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("lsof failed: %w", err)
+	}
+
+	// Output consists of "p<pid>" lines, each followed by a "c<command>" line.
+	self := os.Getpid()
+	var current *ProcessInfo
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case 'p':
+			pid, err := strconv.Atoi(line[1:])
+			if err != nil || pid == self {
+				current = nil
+				continue
+			}
+			current = &ProcessInfo{PID: pid}
+		case 'c':
+			if current != nil {
+				current.Name = strings.TrimSpace(line[1:])
+				return current, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no process found for %s", spec)
+}
@@ -0,0 +1,109 @@
+//go:build windows
+
+package sshtunnel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	afInet              = 2
+	afInet6             = 23
+	tcpTableOwnerPIDAll = 5
+)
+
+var procGetExtendedTcpTable = windows.NewLazySystemDLL("iphlpapi.dll").NewProc("GetExtendedTcpTable")
+
+// lookupProcess finds the owning process of the socket client -> server with GetExtendedTcpTable.
+func lookupProcess(client, server *net.TCPAddr) (*ProcessInfo, error) {
+	family, rowSize := uint32(afInet), 24 // MIB_TCPROW_OWNER_PID
+	if client.IP.To4() == nil {
+		family, rowSize = afInet6, 56 // MIB_TCP6ROW_OWNER_PID
+	}
+	table, err := extendedTCPTable(family)
+	if err != nil {
+		return nil, err
+	}
+
+	self := os.Getpid()
+	count := int(binary.LittleEndian.Uint32(table))
+	for i := 0; i < count; i++ {
+		offset := 4 + i*rowSize
+		if offset+rowSize > len(table) {
+			break
+		}
+		row := table[offset : offset+rowSize]
+
+		var local, remote *net.TCPAddr
+		var pid uint32
+		if family == afInet {
+			local = &net.TCPAddr{IP: net.IP(row[4:8]), Port: tcpTablePort(row[8:12])}
+			remote = &net.TCPAddr{IP: net.IP(row[12:16]), Port: tcpTablePort(row[16:20])}
+			pid = binary.LittleEndian.Uint32(row[20:24])
+		} else {
+			local = &net.TCPAddr{IP: net.IP(row[0:16]), Port: tcpTablePort(row[20:24])}
+			remote = &net.TCPAddr{IP: net.IP(row[24:40]), Port: tcpTablePort(row[44:48])}
+			pid = binary.LittleEndian.Uint32(row[52:56])
+		}
+		if int(pid) == self || local.Port != client.Port || remote.Port != server.Port ||
+			!local.IP.Equal(client.IP) || !remote.IP.Equal(server.IP) {
+			continue
+		}
+		return &ProcessInfo{PID: int(pid), Name: processName(pid)}, nil
+	}
+	return nil, fmt.Errorf("socket not found")
+}
+
+// extendedTCPTable returns the raw MIB_TCP(6)TABLE_OWNER_PID for the address family.
+func extendedTCPTable(family uint32) ([]byte, error) {
+	var size uint32
+	for attempt := 0; attempt < 3; attempt++ {
+		var buf []byte
+		var ptr uintptr
+		if size > 0 {
+			buf = make([]byte, size)
+			ptr = uintptr(unsafe.Pointer(&buf[0]))
+		}
+		ret, _, _ := procGetExtendedTcpTable.Call(ptr, uintptr(unsafe.Pointer(&size)), 0, uintptr(family), tcpTableOwnerPIDAll, 0)
+		switch windows.Errno(ret) {
+		case windows.ERROR_SUCCESS:
+			if buf == nil {
+				continue
+			}
+			return buf, nil
+		case windows.ERROR_INSUFFICIENT_BUFFER:
+			continue // size was updated, retry with a bigger buffer
+		default:
+			return nil, fmt.Errorf("GetExtendedTcpTable failed: %w", windows.Errno(ret))
+		}
+	}
+	return nil, fmt.Errorf("GetExtendedTcpTable: table keeps growing")
+}
+
+// tcpTablePort decodes a port stored in network byte order in the low 16 bits of a DWORD.
+func tcpTablePort(b []byte) int {
+	return int(binary.BigEndian.Uint16(b[0:2]))
+}
+
+// processName returns the executable name of pid, or "" if the process can't be opened.
+func processName(pid uint32) string {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]uint16, windows.MAX_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(h, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return filepath.Base(windows.UTF16ToString(buf[:size]))
+}
@@ -0,0 +1,219 @@
+package sshtunnel
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SocksACLConfig restricts the destinations a dynamic (SOCKS) tunnel will connect to,
+// e.g. so a proxy opened for internal dashboards can't be used for general browsing.
+//
+// Each rule is "host[:port]". host is an exact name ("grafana.corp"), a wildcard suffix
+// ("*.corp.example.com"), an IP, a CIDR ("10.0.0.0/8") or "*"; port is a single port or a
+// range ("8000-8999"), and matches every port when omitted. IPv6 addresses and prefixes with
+// a port are written in brackets ("[fd00::/8]:443").
+//
+// Names are not resolved locally (that would leak the lookup and may differ from what the
+// remote host sees), so IP and CIDR rules only match requests by address and name rules only
+// match requests by name. Deny rules are checked first; when Allow is non-empty, destinations
+// matching no allow rule are refused.
+type SocksACLConfig struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// IsEmpty reports whether the config has no rules.
+func (c SocksACLConfig) IsEmpty() bool {
+	return len(c.Allow) == 0 && len(c.Deny) == 0
+}
+
+// Normalize validates every rule and returns a copy with whitespace and empty rules removed.
+func (c SocksACLConfig) Normalize() (SocksACLConfig, error) {
+	var err error
+	if c.Allow, err = normalizeACLRules(c.Allow); err != nil {
+		return c, err
+	}
+	if c.Deny, err = normalizeACLRules(c.Deny); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func normalizeACLRules(rules []string) ([]string, error) {
+	var normalized []string
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		if _, err := parseACLRule(rule); err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, rule)
+	}
+	return normalized, nil
+}
+
+// aclRule is a parsed SocksACLConfig rule.
+type aclRule struct {
+	raw     string
+	any     bool       // "*" matches every host
+	name    string     // exact host name, lower case
+	suffix  string     // ".corp.example.com" for "*.corp.example.com"
+	network *net.IPNet // IP or CIDR
+	minPort uint16
+	maxPort uint16
+}
+
+// parseACLRule parses "host[:port]" as described on SocksACLConfig.
+func parseACLRule(rule string) (*aclRule, error) {
+	r := &aclRule{raw: rule, minPort: 1, maxPort: 65535}
+	host, port := rule, ""
+	switch {
+	case strings.HasPrefix(rule, "["):
+		end := strings.Index(rule, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("invalid rule '%s': missing ']'", rule)
+		}
+		host = rule[1:end]
+		if rest := rule[end+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return nil, fmt.Errorf("invalid rule '%s'", rule)
+			}
+			port = rest[1:]
+		}
+	case strings.Count(rule, ":") == 1:
+		host, port, _ = strings.Cut(rule, ":")
+	}
+	if host == "" {
+		return nil, fmt.Errorf("invalid rule '%s': missing host", rule)
+	}
+
+	if port != "" {
+		lo, hi, isRange := strings.Cut(port, "-")
+		if !isRange {
+			hi = lo
+		}
+		first, err1 := strconv.ParseUint(lo, 10, 16)
+		last, err2 := strconv.ParseUint(hi, 10, 16)
+		if err1 != nil || err2 != nil || first == 0 || first > last {
+			return nil, fmt.Errorf("invalid port '%s' in rule '%s'", port, rule)
+		}
+		r.minPort, r.maxPort = uint16(first), uint16(last)
+	}
+
+	switch {
+	case host == "*":
+		r.any = true
+	case strings.Contains(host, "/"):
+		_, network, err := net.ParseCIDR(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR in rule '%s'", rule)
+		}
+		r.network = network
+	case net.ParseIP(host) != nil:
+		ip := net.ParseIP(host)
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		r.network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	case strings.HasPrefix(host, "*."):
+		r.suffix = strings.ToLower(host[1:])
+	case strings.ContainsAny(host, "*?/ "):
+		return nil, fmt.Errorf("invalid host '%s' in rule '%s'", host, rule)
+	default:
+		r.name = strings.ToLower(strings.TrimSuffix(host, "."))
+	}
+	return r, nil
+}
+
+// matches reports whether the SOCKS destination host:port matches the rule.
+func (r *aclRule) matches(host string, port uint16) bool {
+	if port < r.minPort || port > r.maxPort {
+		return false
+	}
+	if r.any {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return r.network != nil && r.network.Contains(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	switch {
+	case r.name != "":
+		return host == r.name
+	case r.suffix != "":
+		return strings.HasSuffix(host, r.suffix)
+	}
+	return false
+}
+
+// socksACL is the compiled form of a SocksACLConfig used by a running tunnel.
+type socksACL struct {
+	allow []*aclRule
+	deny  []*aclRule
+}
+
+func newSocksACL(cfg SocksACLConfig) (*socksACL, error) {
+	acl := &socksACL{}
+	for _, rule := range cfg.Allow {
+		r, err := parseACLRule(strings.TrimSpace(rule))
+		if err != nil {
+			return nil, err
+		}
+		acl.allow = append(acl.allow, r)
+	}
+	for _, rule := range cfg.Deny {
+		r, err := parseACLRule(strings.TrimSpace(rule))
+		if err != nil {
+			return nil, err
+		}
+		acl.deny = append(acl.deny, r)
+	}
+	return acl, nil
+}
+
+// check returns an empty reason if the destination is allowed, otherwise why it was refused.
+func (a *socksACL) check(host string, port uint16) (reason string) {
+	for _, r := range a.deny {
+		if r.matches(host, port) {
+			return fmt.Sprintf("denied by rule '%s'", r.raw)
+		}
+	}
+	if len(a.allow) == 0 {
+		return ""
+	}
+	for _, r := range a.allow {
+		if r.matches(host, port) {
+			return ""
+		}
+	}
+	return "not in the allow list"
+}
+
+// SetSocksACL replaces the destination rules of a running dynamic tunnel. An empty config removes them.
+func (m *Manager) SetSocksACL(tunnelID string, cfg SocksACLConfig) error {
+	m.mu.RLock()
+	tunnel, ok := m.activeTunnels[tunnelID]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("tunnel with ID %s not found", tunnelID)
+	}
+	if tunnel.Type != "dynamic" {
+		return fmt.Errorf("destination rules are only available for dynamic tunnels")
+	}
+	if cfg.IsEmpty() {
+		tunnel.acl.Store(nil)
+		return nil
+	}
+	acl, err := newSocksACL(cfg)
+	if err != nil {
+		return err
+	}
+	tunnel.acl.Store(acl)
+	logger.Printf("Tunnel %s: applied SOCKS destination rules (%d allow, %d deny)", tunnel.ID, len(acl.allow), len(acl.deny))
+	return nil
+}
@@ -5,19 +5,21 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"devtools/backend/internal/logging"
 	"devtools/backend/internal/sshmanager"
 	"devtools/backend/pkg/utils"
 
 	"github.com/google/uuid"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"golang.org/x/crypto/ssh"
 )
 
+var logger = logging.For("tunnel")
+
 // TunnelStatus represents the state of a tunnel.
 type TunnelStatus string
 
@@ -28,8 +30,14 @@ const (
 	StatusDisconnected TunnelStatus = "disconnected"
 	// StatusStopping means the tunnel is being shut down by the user.
 	StatusStopping TunnelStatus = "stopping"
+	// StatusDraining means the tunnel no longer accepts connections and is waiting
+	// for the active ones to finish before it stops.
+	StatusDraining TunnelStatus = "draining"
 )
 
+// drainPollInterval is how often a draining tunnel checks its active connections.
+const drainPollInterval = 100 * time.Millisecond
+
 // SOCKS5 protocol constants
 const (
 	socks5Version = 0x05
@@ -46,6 +54,7 @@ const (
 
 	// Reply codes
 	repSucceeded               = 0x00
+	repConnectionNotAllowed    = 0x02
 	repHostUnreachable         = 0x04
 	repCommandNotSupported     = 0x07
 	repAddressTypeNotSupported = 0x08
@@ -53,15 +62,22 @@ const (
 
 // SavedTunnelConfig represents a persistently stored tunnel configuration.
 type SavedTunnelConfig struct {
-	ID         string `json:"id"`         // Unique ID, e.g., UUID
-	Name       string `json:"name"`       // User-defined name, e.g., "Access Corp DB"
-	TunnelType string `json:"tunnelType"` // "local" or "dynamic"
-	LocalPort  int    `json:"localPort"`
-	GatewayPorts bool `json:"gatewayPorts"`
+	ID           string `json:"id"`         // Unique ID, e.g., UUID
+	Name         string `json:"name"`       // User-defined name, e.g., "Access Corp DB"
+	TunnelType   string `json:"tunnelType"` // "local" or "dynamic"
+	LocalPort    int    `json:"localPort"`
+	GatewayPorts bool   `json:"gatewayPorts"`
 
 	// --- Fields for Local Forwarding only ---
 	RemoteHost string `json:"remoteHost,omitempty"`
 	RemotePort int    `json:"remotePort,omitempty"`
+	// Optional extra targets; connections are then balanced across RemoteHost:RemotePort and these
+	LoadBalance *LoadBalanceConfig `json:"loadBalance,omitempty"`
+
+	// --- Fields for Dynamic Forwarding only ---
+	DNSForward *DNSForwardConfig `json:"dnsForward,omitempty"`
+	// Optional allow/deny rules for the destinations clients may connect to
+	SocksACL *SocksACLConfig `json:"socksAcl,omitempty"`
 
 	// --- Host Connection Information ---
 	HostSource string `json:"hostSource"` // "ssh_config" or "manual"
@@ -71,6 +87,13 @@ type SavedTunnelConfig struct {
 
 	// If HostSource is "manual"
 	ManualHost *ManualHostInfo `json:"manualHost,omitempty"`
+	// Set when the tunnel was imported from a LocalForward/DynamicForward line of the host's ssh_config
+	ConfigForward *ConfigForwardLink `json:"configForward,omitempty"`
+
+	Favorite bool `json:"favorite,omitempty"` // Shown in the quick actions menu for one-click start/stop
+
+	// Optional local commands run when the tunnel comes up and goes down
+	Hooks *TunnelHooks `json:"hooks,omitempty"`
 }
 
 // ManualHostInfo stores connection details for a manually entered host.
@@ -83,6 +106,14 @@ type ManualHostInfo struct {
 	// using the SavedTunnelConfig.ID as the service key.
 }
 
+// ConfigForwardLink links a saved tunnel to the ssh_config forward line it was imported from.
+type ConfigForwardLink struct {
+	Keyword string `json:"keyword"` // "LocalForward" or "DynamicForward"
+	Value   string `json:"value"`   // The line's value as currently written, e.g. "8080 db:5432"
+	// When true, saving the tunnel rewrites the linked line so the ssh CLI uses the same forward
+	WriteBack bool `json:"writeBack,omitempty"`
+}
+
 // Tunnel 代表一个活动的端口转发隧道
 type Tunnel struct {
 	ID         string
@@ -96,6 +127,23 @@ type Tunnel struct {
 	sshClient  *ssh.Client
 	listener   net.Listener
 	cancelFunc context.CancelFunc // 用于优雅地关闭隧道
+	connLog    *connectionLog     // Bounded per-connection event log for troubleshooting
+
+	dnsForwarder *dnsForwarder // Optional DNS forwarder next to the SOCKS port (dynamic tunnels only)
+
+	targets atomic.Pointer[targetPool] // Optional remote targets to balance across (local tunnels only)
+
+	acl         atomic.Pointer[socksACL] // Optional destination rules (dynamic tunnels only)
+	deniedConns atomic.Int64             // SOCKS requests refused by acl
+
+	activeConns   atomic.Int64 // Connections currently being served
+	drainDeadline time.Time    // Set while draining; active connections are force-closed at this time
+
+	latency *latencyTracker // SSH round-trip and dial times
+
+	hooks *TunnelHooks // Optional up/down commands; the down hook runs from cleanupTunnel
+
+	fileLog *tunnelFileLog // Persistent log file; nil when file logging is off
 }
 
 // ActiveTunnelInfo 是一个用于向前端展示的、简化的隧道信息结构
@@ -108,6 +156,18 @@ type ActiveTunnelInfo struct {
 	RemoteAddr string       `json:"remoteAddr"`
 	Status     TunnelStatus `json:"status"`
 	StatusMsg  string       `json:"statusMsg"`
+	DNSAddr    string       `json:"dnsAddr,omitempty"` // Address of the DNS forwarder, if running
+
+	BalanceMode string         `json:"balanceMode,omitempty"` // Set when connections are balanced across several targets
+	Targets     []TargetStatus `json:"targets,omitempty"`
+
+	HasDestinationRules bool  `json:"hasDestinationRules,omitempty"`
+	DeniedConnections   int64 `json:"deniedConnections,omitempty"` // SOCKS requests refused by the destination rules
+
+	ActiveConnections int64  `json:"activeConnections"`
+	DrainDeadline     string `json:"drainDeadline,omitempty"` // RFC 3339 time at which a draining tunnel force-closes its connections
+
+	Latency *TunnelLatency `json:"latency,omitempty"`
 }
 
 // Manager 负责管理所有活动的隧道
@@ -121,6 +181,18 @@ type Manager struct {
 	eventDebouncer        *time.Timer
 	eventDebounceDuration time.Duration
 	eventMu               sync.Mutex
+
+	// How long StopForward waits for active connections to finish; 0 closes them immediately
+	drainTimeout atomic.Int64
+	// Average round-trip time above which "tunnel:slow" is emitted; 0 disables it
+	slowLatency atomic.Int64
+
+	// Called after a tunnel's SSH connection is lost unexpectedly; guarded by mu
+	onDisconnect func(tunnelID, alias, message string)
+
+	// Where and whether tunnels write persistent log files
+	fileLog   fileLogConfig
+	fileLogMu sync.RWMutex
 }
 
 // NewManager 是隧道管理器的构造函数
@@ -148,29 +220,58 @@ func (m *Manager) Shutdown() {
 	}
 	m.eventMu.Unlock()
 
+	m.StopAll()
+}
+
+// SetDisconnectHandler 设置隧道的 SSH 连接意外断开时的回调（用户停止隧道时不会调用）
+func (m *Manager) SetDisconnectHandler(fn func(tunnelID, alias, message string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onDisconnect = fn
+}
+
+// StopAll 立即停止所有活动的隧道（不等待活动连接结束），例如在应用退出或切换工作区时
+func (m *Manager) StopAll() {
 	// 通过创建一个副本避免在迭代时修改 map
-	idsToStop := make([]string, 0, len(m.activeTunnels))
 	m.mu.RLock()
+	idsToStop := make([]string, 0, len(m.activeTunnels))
 	for id := range m.activeTunnels {
 		idsToStop = append(idsToStop, id)
 	}
 	m.mu.RUnlock()
 
 	for _, id := range idsToStop {
-		// StopForward 内部会处理锁和清理
-		if err := m.StopForward(id); err != nil {
-			log.Printf("Error stopping tunnel %s during shutdown: %v", id, err)
+		if err := m.StopForwardWithTimeout(id, 0); err != nil {
+			logger.Printf("Error stopping tunnel %s: %v", id, err)
 		}
 	}
-	log.Println("All active tunnels have been requested to stop.")
+	logger.Println("All active tunnels have been requested to stop.")
 }
 
 // CreateTunnelFromConfig is the core tunnel creation logic. It takes a pre-built connection configuration.
-func (m *Manager) CreateTunnelFromConfig(configID, alias string, localPort int, gatewayPorts bool, tunnelType, remoteAddr string, connConfig *sshmanager.ConnectionConfig) (string, error) {
+// name is the saved tunnel's name, used for its log file when file logging is on.
+// acl restricts the destinations of dynamic tunnels; it is applied before the first connection is accepted.
+// hooks, if set, run when the tunnel is up and again when it goes down.
+func (m *Manager) CreateTunnelFromConfig(configID, name, alias string, localPort int, gatewayPorts bool, tunnelType, remoteAddr string, connConfig *sshmanager.ConnectionConfig, acl *SocksACLConfig, hooks *TunnelHooks) (string, error) {
+	var compiledACL *socksACL
+	if tunnelType == "dynamic" && acl != nil && !acl.IsEmpty() {
+		var err error
+		if compiledACL, err = newSocksACL(*acl); err != nil {
+			return "", err
+		}
+	}
+
+	// Failed starts are logged too: they are often what an overnight investigation is after.
+	fileLog := m.openFileLog(name, configID)
+	fileLog.printf("Starting %s tunnel on port %d -> %s via %s", tunnelType, localPort, remoteAddr, alias)
+
 	// 1. Dial SSH server
-	serverAddr := fmt.Sprintf("%s:%s", connConfig.HostName, connConfig.Port)
-	sshClient, err := ssh.Dial("tcp", serverAddr, connConfig.ClientConfig)
+	dialStart := time.Now()
+	sshClient, err := sshmanager.Dial(connConfig)
+	connectTime := time.Since(dialStart)
 	if err != nil {
+		fileLog.printf("Failed to connect to %s: %v", alias, err)
+		fileLog.close()
 		return "", err // Return raw error for the service layer to inspect and translate.
 	}
 
@@ -183,6 +284,8 @@ func (m *Manager) CreateTunnelFromConfig(configID, alias string, localPort int,
 	listener, err := net.Listen("tcp", localAddr)
 	if err != nil {
 		sshClient.Close()
+		fileLog.printf("Failed to listen on %s: %v", localAddr, err)
+		fileLog.close()
 		return "", err // Return raw error for the service layer to inspect and translate.
 	}
 
@@ -201,24 +304,36 @@ func (m *Manager) CreateTunnelFromConfig(configID, alias string, localPort int,
 		cancelFunc: cancel,
 		Status:     StatusActive, // Tunnels start as active.
 		StatusMsg:  "Connection established.",
+		connLog:    newConnectionLog(defaultConnectionLogSize),
+		latency:    newLatencyTracker(connectTime),
+		hooks:      hooks,
+		fileLog:    fileLog,
+	}
+	if fileLog != nil {
+		tunnel.connLog.onEvent = fileLog.logEvent
 	}
+	tunnel.acl.Store(compiledACL)
 
 	m.mu.Lock()
 	m.activeTunnels[tunnelID] = tunnel
 	m.mu.Unlock()
 
-	log.Printf("Started %s forward tunnel %s: %s -> %s (via %s)", tunnelType, tunnelID, tunnel.LocalAddr, tunnel.RemoteAddr, alias)
+	logger.Printf("Started %s forward tunnel %s: %s -> %s (via %s)", tunnelType, tunnelID, tunnel.LocalAddr, tunnel.RemoteAddr, alias)
+	fileLog.printf("Started tunnel %s on %s, connected in %s", tunnelID, tunnel.LocalAddr, connectTime.Round(time.Millisecond))
 
 	// 4. Start background goroutines for the tunnel's lifecycle
 	//    - runTunnel: Accepts and forwards connections.
 	//    - monitorSSHConnection: Passively waits for the SSH connection to close.
-	//    - startKeepAlive: Actively probes the connection to detect failures.
+	//    - startKeepAlive: Actively probes the connection to detect failures and measures its round-trip time.
 	go m.runTunnel(tunnel, ctx)
 	go m.monitorSSHConnection(tunnel)
-	go sshmanager.StartKeepAlive(tunnel.sshClient, ctx)
+	go sshmanager.StartKeepAliveWithLatency(tunnel.sshClient, ctx, connConfig.KeepAlive, func(rtt time.Duration) {
+		m.recordRTT(tunnel, rtt)
+	})
 
 	// Notify frontend about the change
 	m.debounceChangeEvent()
+	m.runHook(tunnel, HookEventUp, "", "")
 
 	return tunnelID, nil
 }
@@ -229,44 +344,49 @@ func (m *Manager) CreateTunnelFromConfig(configID, alias string, localPort int,
 func (m *Manager) monitorSSHConnection(tunnel *Tunnel) {
 	// This blocks until the connection is closed for any reason.
 	waitErr := tunnel.sshClient.Wait()
-	log.Printf("SSH connection for tunnel %s (alias: %s) closed: %v.", tunnel.ID, tunnel.Alias, waitErr)
+	logger.Printf("SSH connection for tunnel %s (alias: %s) closed: %v.", tunnel.ID, tunnel.Alias, waitErr)
 
 	m.mu.Lock()
 	// Re-fetch the tunnel to get the most current state inside the lock.
 	currentTunnel, ok := m.activeTunnels[tunnel.ID]
-	if !ok || currentTunnel.Status == StatusStopping {
+	if !ok || currentTunnel.Status == StatusStopping || currentTunnel.Status == StatusDraining {
 		// If the tunnel is not found or is already being stopped by the user,
 		// the cleanup is being handled by StopForward. We don't need to do anything.
 		m.mu.Unlock()
-		log.Printf("Tunnel %s is already stopping or cleaned up, skipping disconnect logic.", tunnel.ID)
+		logger.Printf("Tunnel %s is already stopping or cleaned up, skipping disconnect logic.", tunnel.ID)
 		return
 	}
 
 	// This was an unexpected disconnection. Update the status.
 	currentTunnel.Status = StatusDisconnected
 	currentTunnel.StatusMsg = fmt.Sprintf("Connection lost: %v", waitErr)
+	statusMsg, onDisconnect := currentTunnel.StatusMsg, m.onDisconnect
 	m.mu.Unlock()
+	currentTunnel.fileLog.printf("%s", statusMsg)
 
 	// Close the listener to unblock the runTunnel goroutine, which will then call cleanup.
 	currentTunnel.listener.Close()
 	m.debounceChangeEvent() // Notify the frontend of the status change.
+	if onDisconnect != nil {
+		onDisconnect(currentTunnel.ID, currentTunnel.Alias, statusMsg)
+	}
 }
 
 func (m *Manager) runTunnel(tunnel *Tunnel, ctx context.Context) {
 	defer m.cleanupTunnel(tunnel.ID) // 确保隧道退出时被清理
-	log.Printf("Tunnel %s: runTunnel loop started.", tunnel.ID)
+	logger.Printf("Tunnel %s: runTunnel loop started.", tunnel.ID)
 
 	// 启动一个 goroutine，它的唯一作用是在 context 被取消时关闭 listener。
 	// 这样可以解除下面 listener.Accept() 的阻塞。
 	// go func() {
 	// 	<-ctx.Done()
-	// 	log.Printf("Tunnel %s: Context cancelled, closing listener to unblock Accept().", tunnel.ID)
+	// 	logger.Printf("Tunnel %s: Context cancelled, closing listener to unblock Accept().", tunnel.ID)
 	// 	tunnel.listener.Close()
 	// }()
 
-	utils.SafeGo(log.Default(), func() {
+	utils.SafeGo(logger.StdLogger(), func() {
 		<-ctx.Done()
-		log.Printf("Tunnel %s: Context cancelled, closing listener to unblock Accept().", tunnel.ID)
+		logger.Printf("Tunnel %s: Context cancelled, closing listener to unblock Accept().", tunnel.ID)
 		tunnel.listener.Close()
 	})
 
@@ -279,94 +399,177 @@ func (m *Manager) runTunnel(tunnel *Tunnel, ctx context.Context) {
 			select {
 			case <-ctx.Done():
 				// context 被取消，是预期的关闭流程。
-				log.Printf("Tunnel %s: Listener closed as part of graceful shutdown.", tunnel.ID)
+				logger.Printf("Tunnel %s: Listener closed as part of graceful shutdown.", tunnel.ID)
 				return
 			default:
+				if m.isDraining(tunnel) {
+					// 停止接受新连接，等待已有连接结束（或超时）后再清理
+					logger.Printf("Tunnel %s: Listener closed, draining %d active connections.", tunnel.ID, tunnel.activeConns.Load())
+					m.waitForDrain(tunnel)
+					tunnel.cancelFunc()
+					return
+				}
 				// context 没有被取消，这是一个意外的错误。
-				log.Printf("Tunnel %s: Error accepting connection: %v. Shutting down.", tunnel.ID, err)
+				logger.Printf("Tunnel %s: Error accepting connection: %v. Shutting down.", tunnel.ID, err)
 				return
 			}
 		}
 
-		log.Printf("Tunnel %s: Accepted new local connection from %s", tunnel.ID, localConn.RemoteAddr())
-		// 根据隧道类型，分派到不同的处理器
-		switch tunnel.Type {
-		case "local":
-			go m.forwardLocalConnection(localConn, tunnel)
-		case "dynamic":
-			go m.handleSocks5Connection(localConn, tunnel)
-		default:
-			log.Printf("Unknown tunnel type '%s' for tunnel ID %s. Closing connection.", tunnel.Type, tunnel.ID)
-			localConn.Close()
-		}
+		logger.Printf("Tunnel %s: Accepted new local connection from %s", tunnel.ID, localConn.RemoteAddr())
+		connID := tunnel.connLog.newConnID()
+		// 在 Accept 循环中计数，避免 StopForward 在 goroutine 启动前看到 0 个活动连接
+		tunnel.activeConns.Add(1)
+		go m.serveConnection(localConn, tunnel, connID)
+	}
+}
+
+// serveConnection 根据隧道类型把连接分派到不同的处理器。
+// 发起连接的本地进程在后台解析（可能需要调用 lsof），不会推迟转发的开始。
+func (m *Manager) serveConnection(localConn net.Conn, tunnel *Tunnel, connID uint64) {
+	defer tunnel.activeConns.Add(-1)
+
+	tunnel.connLog.add(ConnectionEvent{
+		ConnID:     connID,
+		Type:       EventAccepted,
+		ClientAddr: localConn.RemoteAddr().String(),
+	})
+	defer tunnel.connLog.finish(connID)
+	resolveConnProcessAsync(localConn, func(proc *ProcessInfo) {
+		tunnel.connLog.setProcess(connID, proc)
+	})
+
+	switch tunnel.Type {
+	case "local":
+		m.forwardLocalConnection(localConn, tunnel, connID)
+	case "dynamic":
+		m.handleSocks5Connection(localConn, tunnel, connID)
+	default:
+		logger.Printf("Unknown tunnel type '%s' for tunnel ID %s. Closing connection.", tunnel.Type, tunnel.ID)
+		tunnel.connLog.add(ConnectionEvent{
+			ConnID: connID,
+			Type:   EventError,
+			Stage:  "dispatch",
+			Error:  fmt.Sprintf("unknown tunnel type '%s'", tunnel.Type),
+		})
+		localConn.Close()
 	}
 }
 
 // forwardLocalConnection 在本地连接和远程SSH通道之间为本地转发(-L)双向复制数据
-func (m *Manager) forwardLocalConnection(localConn net.Conn, tunnel *Tunnel) {
+func (m *Manager) forwardLocalConnection(localConn net.Conn, tunnel *Tunnel, connID uint64) {
 	defer localConn.Close()
-	log.Printf("Tunnel %s: Starting forwardLocalConnection for %s", tunnel.ID, localConn.RemoteAddr())
-
-	// 通过已建立的 SSH 客户端，连接到最终的目标服务器
-	remoteConn, err := tunnel.sshClient.Dial("tcp", tunnel.RemoteAddr)
+	logger.Printf("Tunnel %s: Starting forwardLocalConnection for %s", tunnel.ID, localConn.RemoteAddr())
+	clientAddr := localConn.RemoteAddr().String()
+
+	// 通过已建立的 SSH 客户端，连接到最终的目标服务器（配置了多个目标时由 targetPool 选择）
+	var (
+		remoteConn net.Conn
+		err        error
+	)
+	target := tunnel.RemoteAddr
+	dialStart := time.Now()
+	if pool := tunnel.targets.Load(); pool != nil {
+		remoteConn, target, err = pool.dial(tunnel.sshClient)
+	} else {
+		remoteConn, err = tunnel.sshClient.Dial("tcp", target)
+	}
 	if err != nil {
-		log.Printf("Tunnel %s failed to dial remote addr %s: %v", tunnel.ID, tunnel.RemoteAddr, err)
+		logger.Printf("Tunnel %s failed to dial remote addr %s: %v", tunnel.ID, tunnel.RemoteAddr, err)
+		tunnel.connLog.add(ConnectionEvent{
+			ConnID:     connID,
+			Type:       EventError,
+			Stage:      "dial-remote",
+			ClientAddr: clientAddr,
+			Target:     target,
+			Error:      err.Error(),
+		})
 		return
 	}
 	defer remoteConn.Close()
-
-	log.Printf("Tunnel %s: Forwarding connection for %s", tunnel.ID, localConn.RemoteAddr())
-
-	m.proxyData(localConn, remoteConn)
+	tunnel.latency.addDial(time.Since(dialStart))
+
+	logger.Printf("Tunnel %s: Forwarding connection for %s to %s", tunnel.ID, localConn.RemoteAddr(), target)
+	tunnel.connLog.add(ConnectionEvent{ConnID: connID, Type: EventDialed, ClientAddr: clientAddr, Target: target})
+
+	start := time.Now()
+	sent, recv := m.proxyData(localConn, remoteConn)
+	tunnel.connLog.add(ConnectionEvent{
+		ConnID:     connID,
+		Type:       EventClosed,
+		ClientAddr: clientAddr,
+		Target:     target,
+		BytesSent:  sent,
+		BytesRecv:  recv,
+		DurationMs: time.Since(start).Milliseconds(),
+	})
 }
 
 // handleSocks5Connection 处理一个 SOCKS5 代理请求
-func (m *Manager) handleSocks5Connection(localConn net.Conn, tunnel *Tunnel) {
+func (m *Manager) handleSocks5Connection(localConn net.Conn, tunnel *Tunnel, connID uint64) {
 	defer localConn.Close()
-	log.Printf("Tunnel %s: Starting handleSocks5Connection for %s", tunnel.ID, localConn.RemoteAddr())
+	logger.Printf("Tunnel %s: Starting handleSocks5Connection for %s", tunnel.ID, localConn.RemoteAddr())
+	clientAddr := localConn.RemoteAddr().String()
+
+	// handshakeFailed records a failure during SOCKS negotiation in the tunnel's connection log.
+	handshakeFailed := func(err error) {
+		tunnel.connLog.add(ConnectionEvent{
+			ConnID:     connID,
+			Type:       EventError,
+			Stage:      "socks-handshake",
+			ClientAddr: clientAddr,
+			Error:      err.Error(),
+		})
+	}
 
 	// 1. SOCKS5 Greeting
 	buf := make([]byte, 256)
 	// Read VER, NMETHODS
 	if _, err := io.ReadFull(localConn, buf[:2]); err != nil {
-		log.Printf("SOCKS5: failed to read greeting: %v", err)
+		logger.Printf("SOCKS5: failed to read greeting: %v", err)
+		handshakeFailed(fmt.Errorf("failed to read greeting: %v", err))
 		return
 	}
 
 	ver, nMethods := buf[0], buf[1]
 	if ver != socks5Version {
-		log.Printf("SOCKS5: unsupported version: %d", ver)
+		logger.Printf("SOCKS5: unsupported version: %d", ver)
+		handshakeFailed(fmt.Errorf("unsupported version: %d", ver))
 		return
 	}
 
 	// Read METHODS
 	if _, err := io.ReadFull(localConn, buf[:nMethods]); err != nil {
-		log.Printf("SOCKS5: failed to read methods: %v", err)
+		logger.Printf("SOCKS5: failed to read methods: %v", err)
+		handshakeFailed(fmt.Errorf("failed to read methods: %v", err))
 		return
 	}
 
 	// 2. Server Choice - We only support NO AUTHENTICATION REQUIRED (0x00)
 	if _, err := localConn.Write([]byte{socks5Version, 0x00}); err != nil {
-		log.Printf("SOCKS5: failed to write server choice: %v", err)
+		logger.Printf("SOCKS5: failed to write server choice: %v", err)
+		handshakeFailed(fmt.Errorf("failed to write server choice: %v", err))
 		return
 	}
 
 	// 3. Client Request
 	// Read VER, CMD, RSV, ATYP
 	if _, err := io.ReadFull(localConn, buf[:4]); err != nil {
-		log.Printf("SOCKS5: failed to read request header: %v", err)
+		logger.Printf("SOCKS5: failed to read request header: %v", err)
+		handshakeFailed(fmt.Errorf("failed to read request header: %v", err))
 		return
 	}
 
 	ver, cmd := buf[0], buf[1]
 	if ver != socks5Version {
-		log.Printf("SOCKS5: unsupported version in request: %d", ver)
+		logger.Printf("SOCKS5: unsupported version in request: %d", ver)
+		handshakeFailed(fmt.Errorf("unsupported version in request: %d", ver))
 		return
 	}
 
 	// We only support the CONNECT command. For all others, we reply with "command not supported".
 	if cmd != cmdConnect {
-		log.Printf("SOCKS5: unsupported command received: %d. Only CONNECT is supported.", cmd)
+		logger.Printf("SOCKS5: unsupported command received: %d. Only CONNECT is supported.", cmd)
+		handshakeFailed(fmt.Errorf("unsupported command %d, only CONNECT is supported", cmd))
 		sendSocks5ErrorReply(localConn, repCommandNotSupported)
 		return
 	}
@@ -376,62 +579,108 @@ func (m *Manager) handleSocks5Connection(localConn net.Conn, tunnel *Tunnel) {
 	switch buf[3] { // ATYP
 	case atypIPv4:
 		if _, err := io.ReadFull(localConn, buf[:4]); err != nil {
-			log.Printf("SOCKS5: failed to read IPv4 address: %v", err)
+			logger.Printf("SOCKS5: failed to read IPv4 address: %v", err)
+			handshakeFailed(fmt.Errorf("failed to read IPv4 address: %v", err))
 			return
 		}
 		host = net.IP(buf[:4]).String()
 	case atypDomain:
 		if _, err := io.ReadFull(localConn, buf[:1]); err != nil {
-			log.Printf("SOCKS5: failed to read domain length: %v", err)
+			logger.Printf("SOCKS5: failed to read domain length: %v", err)
+			handshakeFailed(fmt.Errorf("failed to read domain length: %v", err))
 			return
 		}
 		domainLen := buf[0]
 		if _, err := io.ReadFull(localConn, buf[:domainLen]); err != nil {
-			log.Printf("SOCKS5: failed to read domain: %v", err)
+			logger.Printf("SOCKS5: failed to read domain: %v", err)
+			handshakeFailed(fmt.Errorf("failed to read domain: %v", err))
 			return
 		}
 		host = string(buf[:domainLen])
 	case atypIPv6:
 		if _, err := io.ReadFull(localConn, buf[:16]); err != nil {
-			log.Printf("SOCKS5: failed to read IPv6 address: %v", err)
+			logger.Printf("SOCKS5: failed to read IPv6 address: %v", err)
+			handshakeFailed(fmt.Errorf("failed to read IPv6 address: %v", err))
 			return
 		}
 		host = net.IP(buf[:16]).String()
 	default:
-		log.Printf("SOCKS5: unsupported address type: %d", buf[3])
+		logger.Printf("SOCKS5: unsupported address type: %d", buf[3])
+		handshakeFailed(fmt.Errorf("unsupported address type: %d", buf[3]))
 		sendSocks5ErrorReply(localConn, repAddressTypeNotSupported)
 		return
 	}
 
 	// Read port
 	if _, err := io.ReadFull(localConn, buf[:2]); err != nil {
-		log.Printf("SOCKS5: failed to read port: %v", err)
+		logger.Printf("SOCKS5: failed to read port: %v", err)
+		handshakeFailed(fmt.Errorf("failed to read port: %v", err))
 		return
 	}
 	port := binary.BigEndian.Uint16(buf[:2])
 	destAddr := fmt.Sprintf("%s:%d", host, port)
 
+	// Enforce the tunnel's destination rules before anything is dialed.
+	if acl := tunnel.acl.Load(); acl != nil {
+		if reason := acl.check(host, port); reason != "" {
+			tunnel.deniedConns.Add(1)
+			logger.Printf("Warning: tunnel %s refused SOCKS request from %s to %s: %s", tunnel.ID, clientAddr, destAddr, reason)
+			m.debounceChangeEvent() // Refresh the denied count shown with the active tunnel
+			tunnel.connLog.add(ConnectionEvent{
+				ConnID:     connID,
+				Type:       EventDenied,
+				Stage:      "acl",
+				ClientAddr: clientAddr,
+				Target:     destAddr,
+				Error:      reason,
+			})
+			sendSocks5ErrorReply(localConn, repConnectionNotAllowed)
+			return
+		}
+	}
+
 	// 4. Dial through SSH tunnel
+	dialStart := time.Now()
 	remoteConn, err := tunnel.sshClient.Dial("tcp", destAddr)
 	if err != nil {
-		log.Printf("SOCKS5: failed to dial remote addr %s via tunnel %s: %v", destAddr, tunnel.ID, err)
+		logger.Printf("SOCKS5: failed to dial remote addr %s via tunnel %s: %v", destAddr, tunnel.ID, err)
+		tunnel.connLog.add(ConnectionEvent{
+			ConnID:     connID,
+			Type:       EventError,
+			Stage:      "dial-remote",
+			ClientAddr: clientAddr,
+			Target:     destAddr,
+			Error:      err.Error(),
+		})
 		sendSocks5ErrorReply(localConn, repHostUnreachable)
 		return
 	}
 	defer remoteConn.Close()
+	tunnel.latency.addDial(time.Since(dialStart))
+	tunnel.connLog.add(ConnectionEvent{ConnID: connID, Type: EventDialed, ClientAddr: clientAddr, Target: destAddr})
 
 	// 5. Server Reply - Success
 	// The BND.ADDR and BND.PORT should be the address and port of the server-side of the connection.
 	// For simplicity, we send back 0.0.0.0:0 as many clients ignore this field on success.
 	if _, err := localConn.Write([]byte{socks5Version, repSucceeded, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0}); err != nil {
-		log.Printf("SOCKS5: failed to write success reply: %v", err)
+		logger.Printf("SOCKS5: failed to write success reply: %v", err)
 		return
 	}
 
-	log.Printf("Tunnel %s: SOCKS5 connection established for %s to %s", tunnel.ID, localConn.RemoteAddr(), destAddr)
+	logger.Printf("Tunnel %s: SOCKS5 connection established for %s to %s", tunnel.ID, localConn.RemoteAddr(), destAddr)
 
 	// 6. Forward data
-	m.proxyData(localConn, remoteConn)
+	start := time.Now()
+	sent, recv := m.proxyData(localConn, remoteConn)
+	tunnel.connLog.add(ConnectionEvent{
+		ConnID:     connID,
+		Type:       EventClosed,
+		ClientAddr: clientAddr,
+		Target:     destAddr,
+		BytesSent:  sent,
+		BytesRecv:  recv,
+		DurationMs: time.Since(start).Milliseconds(),
+	})
 }
 
 // sendSocks5ErrorReply sends a SOCKS5 error reply with a given reply code.
@@ -440,40 +689,51 @@ func sendSocks5ErrorReply(w io.Writer, rep byte) {
 	// For errors, BND.ADDR and BND.PORT can be zero.
 	_, err := w.Write([]byte{socks5Version, rep, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0})
 	if err != nil {
-		log.Printf("SOCKS5: failed to write error reply: %v", err)
+		logger.Printf("SOCKS5: failed to write error reply: %v", err)
 	}
 }
 
 // proxyData 在两个连接之间双向地、并发地复制数据
-func (m *Manager) proxyData(conn1, conn2 net.Conn) {
+// It returns the number of bytes copied from conn1 to conn2 and from conn2 to conn1.
+func (m *Manager) proxyData(conn1, conn2 net.Conn) (sent, received int64) {
 	var wg sync.WaitGroup
 	wg.Add(2)
-	log.Printf("Proxying data between %s and %s", conn1.RemoteAddr(), conn2.RemoteAddr())
+	logger.Printf("Proxying data between %s and %s", conn1.RemoteAddr(), conn2.RemoteAddr())
 
-	copier := func(dst net.Conn, src net.Conn) {
+	copier := func(dst net.Conn, src net.Conn, written *int64) {
 		defer wg.Done()
-		if _, err := io.Copy(dst, src); err != nil {
+		n, err := io.Copy(dst, src)
+		*written = n
+		if err != nil {
 			// io.EOF is an expected and normal condition when a connection is closed by the other side.
 			if err == io.EOF {
-				log.Printf("io.Copy completed: %s -> %s (EOF)", src.RemoteAddr(), dst.RemoteAddr())
+				logger.Printf("io.Copy completed: %s -> %s (EOF)", src.RemoteAddr(), dst.RemoteAddr())
 			} else {
-				log.Printf("io.Copy error on %s -> %s: %v", src.RemoteAddr(), dst.RemoteAddr(), err)
+				logger.Printf("io.Copy error on %s -> %s: %v", src.RemoteAddr(), dst.RemoteAddr(), err)
 			}
 		}
 	}
 
-	utils.SafeGo(log.Default(), func() {
-		copier(conn1, conn2)
+	utils.SafeGo(logger.StdLogger(), func() {
+		copier(conn1, conn2, &received)
 	})
-	utils.SafeGo(log.Default(), func() {
-		copier(conn2, conn1)
+	utils.SafeGo(logger.StdLogger(), func() {
+		copier(conn2, conn1, &sent)
 	})
 
 	wg.Wait()
+	return sent, received
 }
 
-// StopForward 停止一个正在运行的隧道
+// StopForward 停止一个正在运行的隧道。隧道还有活动连接时先进入 draining 状态：
+// 不再接受新连接，等待已有连接结束，最多等待 SetDrainTimeout 设置的时间后强制关闭。
 func (m *Manager) StopForward(tunnelID string) error {
+	return m.StopForwardWithTimeout(tunnelID, time.Duration(m.drainTimeout.Load()))
+}
+
+// StopForwardWithTimeout 与 StopForward 相同，但使用指定的等待时间；0 表示立即关闭所有连接。
+// 对正在 draining 的隧道再次调用会立即强制关闭。
+func (m *Manager) StopForwardWithTimeout(tunnelID string, timeout time.Duration) error {
 	m.mu.Lock()
 	tunnel, ok := m.activeTunnels[tunnelID]
 	if !ok {
@@ -483,28 +743,94 @@ func (m *Manager) StopForward(tunnelID string) error {
 
 	switch tunnel.Status {
 	case StatusActive:
+		if n := tunnel.activeConns.Load(); timeout > 0 && n > 0 {
+			// Stop accepting new connections; runTunnel waits for the active ones to finish.
+			logger.Printf("User requested stop for active tunnel %s. Draining %d connections (timeout %s).", tunnelID, n, timeout)
+			tunnel.Status = StatusDraining
+			tunnel.drainDeadline = time.Now().Add(timeout)
+			tunnel.StatusMsg = fmt.Sprintf("Waiting for %d active connections to finish.", n)
+			tunnel.fileLog.printf("Stop requested, draining %d connections (timeout %s)", n, timeout)
+			tunnel.listener.Close()
+			m.debounceChangeEvent()
+			break
+		}
 		// For active tunnels, initiate a graceful shutdown.
-		log.Printf("User requested stop for active tunnel %s. Changing status to 'stopping'.", tunnelID)
+		logger.Printf("User requested stop for active tunnel %s. Changing status to 'stopping'.", tunnelID)
 		tunnel.Status = StatusStopping
 		tunnel.StatusMsg = "User initiated stop."
+		tunnel.fileLog.printf("Stop requested")
 		// Calling cancelFunc triggers the cleanup cascade.
 		tunnel.cancelFunc()
+	case StatusDraining:
+		// A second stop while draining closes the remaining connections right away.
+		logger.Printf("User requested immediate stop for draining tunnel %s.", tunnelID)
+		tunnel.Status = StatusStopping
+		tunnel.StatusMsg = "User initiated stop."
+		tunnel.cancelFunc()
 	case StatusDisconnected:
 		// For disconnected tunnels, the user is just clearing it from the list.
 		// Resources are already closed, so we just remove it from the map.
-		log.Printf("User requested to clear disconnected tunnel %s.", tunnelID)
+		logger.Printf("User requested to clear disconnected tunnel %s.", tunnelID)
 		delete(m.activeTunnels, tunnelID)
 		// Manually trigger event as cleanupTunnel won't be called for this case.
 		m.debounceChangeEvent()
 	case StatusStopping:
 		// Already being stopped, do nothing.
-		log.Printf("Stop request for tunnel %s ignored, already in 'stopping' state.", tunnelID)
+		logger.Printf("Stop request for tunnel %s ignored, already in 'stopping' state.", tunnelID)
 	}
 
 	m.mu.Unlock()
 	return nil
 }
 
+// SetDrainTimeout changes how long StopForward waits for active connections to finish.
+func (m *Manager) SetDrainTimeout(d time.Duration) {
+	m.drainTimeout.Store(int64(d))
+}
+
+// isDraining reports whether the tunnel is waiting for its connections to finish.
+func (m *Manager) isDraining(tunnel *Tunnel) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return tunnel.Status == StatusDraining
+}
+
+// waitForDrain blocks until the draining tunnel has no active connections, its deadline passes,
+// or it is stopped immediately. The tunnel is then marked as stopping so cleanupTunnel removes it.
+func (m *Manager) waitForDrain(tunnel *Tunnel) {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	last := tunnel.activeConns.Load()
+	for {
+		n := tunnel.activeConns.Load()
+		m.mu.Lock()
+		switch {
+		case tunnel.Status != StatusDraining:
+			// Stopped immediately, or the SSH connection was lost.
+		case n == 0:
+			logger.Printf("Tunnel %s: all connections finished, stopping.", tunnel.ID)
+			tunnel.fileLog.printf("All connections finished")
+		case !time.Now().Before(tunnel.drainDeadline):
+			logger.Printf("Tunnel %s: drain timeout reached, force-closing %d connections.", tunnel.ID, n)
+			tunnel.fileLog.printf("Drain timeout reached, force-closing %d connections", n)
+		default:
+			if n != last {
+				tunnel.StatusMsg = fmt.Sprintf("Waiting for %d active connections to finish.", n)
+				last = n
+				m.debounceChangeEvent()
+			}
+			m.mu.Unlock()
+			<-ticker.C
+			continue
+		}
+		tunnel.Status = StatusStopping
+		tunnel.StatusMsg = "User initiated stop."
+		m.mu.Unlock()
+		return
+	}
+}
+
 // cleanupTunnel 关闭所有资源并从map中移除
 func (m *Manager) cleanupTunnel(tunnelID string) {
 	m.mu.Lock()
@@ -515,23 +841,37 @@ func (m *Manager) cleanupTunnel(tunnelID string) {
 		return // Already cleaned up or never existed
 	}
 
-	log.Printf("Starting resource cleanup for tunnel %s (status: %s)...", tunnelID, tunnel.Status)
+	logger.Printf("Starting resource cleanup for tunnel %s (status: %s)...", tunnelID, tunnel.Status)
 
 	// Resources like listener and sshClient are closed regardless of status.
 	// The listener might have already been closed by monitorSSHConnection, but closing again is safe.
 	if tunnel.listener != nil {
 		tunnel.listener.Close()
 	}
+	if tunnel.dnsForwarder != nil {
+		tunnel.dnsForwarder.close()
+	}
+	if pool := tunnel.targets.Load(); pool != nil {
+		pool.close()
+	}
 	if tunnel.sshClient != nil {
 		tunnel.sshClient.Close()
 	}
 
+	reason := HookReasonDisconnected
+	if tunnel.Status == StatusStopping {
+		reason = HookReasonStopped
+	}
+	m.runHook(tunnel, HookEventDown, reason, tunnel.StatusMsg)
+	tunnel.fileLog.printf("Tunnel closed (%s)", reason)
+	tunnel.fileLog.close()
+
 	// The crucial part: only remove the tunnel from the map if it was a user-initiated stop.
 	if tunnel.Status == StatusStopping {
 		delete(m.activeTunnels, tunnelID)
-		log.Printf("Completed cleanup and removed tunnel %s from active list.", tunnelID)
+		logger.Printf("Completed cleanup and removed tunnel %s from active list.", tunnelID)
 	} else {
-		log.Printf("Completed resource cleanup for tunnel %s. It remains in 'disconnected' state.", tunnelID)
+		logger.Printf("Completed resource cleanup for tunnel %s. It remains in 'disconnected' state.", tunnelID)
 	}
 
 	m.debounceChangeEvent()
@@ -550,14 +890,21 @@ func (m *Manager) debounceChangeEvent() {
 
 	// Set a new timer that will fire after the quiet period.
 	m.eventDebouncer = time.AfterFunc(m.eventDebounceDuration, func() {
-		log.Println("Debouncer fired: emitting 'tunnels:changed' event to frontend.")
+		logger.Println("Debouncer fired: emitting 'tunnels:changed' event to frontend.")
 		// This runs in a new goroutine, so we wrap it for safety.
-		utils.SafeGo(log.Default(), func() {
-			runtime.EventsEmit(m.appCtx, "tunnels:changed")
+		utils.SafeGo(logger.StdLogger(), func() {
+			utils.EmitEvent(m.appCtx, "tunnels:changed")
 		})
 	})
 }
 
+// SetEventDebounceDuration changes the quiet period used before emitting "tunnels:changed".
+func (m *Manager) SetEventDebounceDuration(d time.Duration) {
+	m.eventMu.Lock()
+	defer m.eventMu.Unlock()
+	m.eventDebounceDuration = d
+}
+
 // GetActiveTunnels 返回所有活动隧道的简化信息
 func (m *Manager) GetActiveTunnels() []ActiveTunnelInfo {
 	m.mu.RLock()
@@ -565,7 +912,11 @@ func (m *Manager) GetActiveTunnels() []ActiveTunnelInfo {
 
 	info := make([]ActiveTunnelInfo, 0, len(m.activeTunnels))
 	for _, tunnel := range m.activeTunnels {
-		info = append(info, ActiveTunnelInfo{
+		var dnsAddr string
+		if tunnel.dnsForwarder != nil {
+			dnsAddr = tunnel.dnsForwarder.addr
+		}
+		item := ActiveTunnelInfo{
 			ID:         tunnel.ID,
 			ConfigID:   tunnel.ConfigID,
 			Alias:      tunnel.Alias,
@@ -574,7 +925,44 @@ func (m *Manager) GetActiveTunnels() []ActiveTunnelInfo {
 			RemoteAddr: tunnel.RemoteAddr,
 			Status:     tunnel.Status,
 			StatusMsg:  tunnel.StatusMsg,
-		})
+			DNSAddr:    dnsAddr,
+		}
+		if pool := tunnel.targets.Load(); pool != nil {
+			item.BalanceMode = pool.mode
+			item.Targets = pool.statuses()
+		}
+		item.HasDestinationRules = tunnel.acl.Load() != nil
+		item.DeniedConnections = tunnel.deniedConns.Load()
+		item.ActiveConnections = tunnel.activeConns.Load()
+		item.Latency = tunnel.latency.snapshot()
+		if tunnel.Status == StatusDraining {
+			item.DrainDeadline = tunnel.drainDeadline.Format(time.RFC3339)
+		}
+		info = append(info, item)
 	}
 	return info
 }
+
+// GetTunnelConnectionLog returns the recent connection events of a tunnel in chronological order.
+func (m *Manager) GetTunnelConnectionLog(tunnelID string) ([]ConnectionEvent, error) {
+	m.mu.RLock()
+	tunnel, ok := m.activeTunnels[tunnelID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("tunnel with ID %s not found", tunnelID)
+	}
+	return tunnel.connLog.snapshot(), nil
+}
+
+// GetTunnelProcessUsage returns which local processes have used a tunnel, most active first.
+func (m *Manager) GetTunnelProcessUsage(tunnelID string) ([]ProcessUsage, error) {
+	m.mu.RLock()
+	tunnel, ok := m.activeTunnels[tunnelID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("tunnel with ID %s not found", tunnelID)
+	}
+	return tunnel.connLog.processUsage(), nil
+}
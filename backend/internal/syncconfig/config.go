@@ -9,12 +9,15 @@ import (
 
 	"github.com/google/uuid"
 
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/migrate"
 	"devtools/backend/internal/types"
 )
 
 type AppConfig struct {
-	SSHConfigs []types.SSHConfig `json:"sshConfigs"`
-	SyncPairs  []types.SyncPair  `json:"syncPairs"`
+	SchemaVersion int               `json:"schemaVersion"` // 见 migrate.ConfigSchemaVersion
+	SSHConfigs    []types.SSHConfig `json:"sshConfigs"`
+	SyncPairs     []types.SyncPair  `json:"syncPairs"`
 }
 
 // --- 错误类型 ---
@@ -23,7 +26,7 @@ type ConfigNotFoundError struct {
 }
 
 func (e *ConfigNotFoundError) Error() string {
-	return fmt.Sprintf("未找到ID为 '%s' 的配置", e.ConfigID)
+	return i18n.T("sync.config_not_found", e.ConfigID)
 }
 
 // --- 配置管理器 ---
@@ -59,7 +62,20 @@ func (cm *ConfigManager) Load() error {
 	return json.Unmarshal(data, &cm.config)
 }
 
+// SetPath 切换到另一个配置文件（例如切换工作区时）并重新加载，文件不存在时配置为空
+func (cm *ConfigManager) SetPath(path string) error {
+	cm.mu.Lock()
+	cm.path = path
+	cm.config = AppConfig{
+		SSHConfigs: make([]types.SSHConfig, 0),
+		SyncPairs:  make([]types.SyncPair, 0),
+	}
+	cm.mu.Unlock()
+	return cm.Load()
+}
+
 func (cm *ConfigManager) save() error {
+	cm.config.SchemaVersion = migrate.ConfigSchemaVersion
 	data, err := json.MarshalIndent(cm.config, "", "  ")
 	if err != nil {
 		return err
@@ -184,7 +200,7 @@ func (cm *ConfigManager) SaveSyncPair(pair types.SyncPair) error {
 			}
 		}
 		if !found {
-			return fmt.Errorf("未找到ID为 '%s' 的同步对", pair.ID)
+			return i18n.Errorf("sync.pair_not_found", pair.ID)
 		}
 	}
 	return cm.save()
@@ -204,7 +220,7 @@ func (cm *ConfigManager) DeleteSyncPair(id string) error {
 		newPairs = append(newPairs, p)
 	}
 	if !found {
-		return fmt.Errorf("未找到ID为 '%s' 的同步对", id)
+		return i18n.Errorf("sync.pair_not_found", id)
 	}
 	cm.config.SyncPairs = newPairs
 	return cm.save()
@@ -0,0 +1,160 @@
+package syncer
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"devtools/backend/internal/types"
+)
+
+// clipboardHistoryLayout 是剪贴板历史文件名中的时间戳格式，例如 clip.20261016-170405.html
+const clipboardHistoryLayout = "20060102-150405"
+
+// MaxClipboardHistory 是剪贴板推送历史最多保留的份数
+const MaxClipboardHistory = 100
+
+// clipboardHistoryPattern 匹配时间戳部分，同一秒内的多次推送会带上 -N 后缀
+var clipboardHistoryPattern = regexp.MustCompile(`^\d{8}-\d{6}(-\d+)?$`)
+
+// ClipboardPush 是一次保留在远程的剪贴板推送
+type ClipboardPush struct {
+	Path     string `json:"path"`
+	PushedAt string `json:"pushedAt"` // RFC3339
+	Size     int64  `json:"size"`
+}
+
+// clipboardHistoryName 返回 remotePath 在 t 时刻的历史文件路径：同目录下，时间戳插在扩展名之前
+func clipboardHistoryName(remotePath string, t time.Time, seq int) string {
+	dir, file := path.Split(remotePath)
+	ext := path.Ext(file)
+	stamp := t.Format(clipboardHistoryLayout)
+	if seq > 0 {
+		stamp = fmt.Sprintf("%s-%d", stamp, seq)
+	}
+	return dir + strings.TrimSuffix(file, ext) + "." + stamp + ext
+}
+
+// parseClipboardHistoryName 判断 name 是否是 remotePath 的历史文件，并返回其推送时间
+func parseClipboardHistoryName(remotePath, name string) (time.Time, bool) {
+	file := path.Base(remotePath)
+	ext := path.Ext(file)
+	prefix := strings.TrimSuffix(file, ext) + "."
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) || len(name) < len(prefix)+len(ext) {
+		return time.Time{}, false
+	}
+	stamp := name[len(prefix) : len(name)-len(ext)]
+	if !clipboardHistoryPattern.MatchString(stamp) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(clipboardHistoryLayout, stamp[:len(clipboardHistoryLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// listClipboardHistory 返回 remotePath 的所有历史文件，最新的在前
+func listClipboardHistory(client *sftp.Client, remotePath string) ([]ClipboardPush, error) {
+	entries, err := client.ReadDir(path.Dir(remotePath))
+	if err != nil {
+		return nil, fmt.Errorf("读取远程目录失败: %w", err)
+	}
+	pushes := []ClipboardPush{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		t, ok := parseClipboardHistoryName(remotePath, entry.Name())
+		if !ok {
+			continue
+		}
+		pushes = append(pushes, ClipboardPush{
+			Path:     path.Join(path.Dir(remotePath), entry.Name()),
+			PushedAt: t.Format(time.RFC3339),
+			Size:     entry.Size(),
+		})
+	}
+	// 文件名中的时间戳按字典序即为时间顺序
+	sort.Slice(pushes, func(i, j int) bool { return pushes[i].Path > pushes[j].Path })
+	return pushes, nil
+}
+
+// saveClipboardHistory 将本次推送的内容另存为带时间戳的历史文件，并删除超出 keep 份的旧文件
+func saveClipboardHistory(client *sftp.Client, remotePath string, content []byte, keep int) error {
+	now := time.Now()
+	historyPath := clipboardHistoryName(remotePath, now, 0)
+	for seq := 1; ; seq++ {
+		if _, err := client.Lstat(historyPath); err != nil {
+			break
+		}
+		historyPath = clipboardHistoryName(remotePath, now, seq)
+	}
+	if err := writeRemoteAtomic(client, historyPath, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	}, nil); err != nil {
+		return err
+	}
+
+	pushes, err := listClipboardHistory(client, remotePath)
+	if err != nil {
+		return err
+	}
+	for _, old := range pushes[min(keep, len(pushes)):] {
+		if err := client.Remove(old.Path); err != nil {
+			logger.Printf("Warning: failed to remove old clipboard push %s: %v", old.Path, err)
+		}
+	}
+	return nil
+}
+
+// GetClipboardHistory 返回同步配置的剪贴板文件在远程保留的历史推送，最新的在前
+func GetClipboardHistory(config types.SSHConfig) ([]ClipboardPush, error) {
+	if config.Clipboard.FilePath == "" {
+		return nil, fmt.Errorf("clipboard file path is not configured")
+	}
+	client, err := NewSFTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return listClipboardHistory(client, config.Clipboard.FilePath)
+}
+
+// ReadClipboardPush 读取一份历史推送的内容。historyPath 必须是剪贴板文件的历史文件。
+func ReadClipboardPush(config types.SSHConfig, historyPath string) (string, error) {
+	remotePath := config.Clipboard.FilePath
+	if remotePath == "" {
+		return "", fmt.Errorf("clipboard file path is not configured")
+	}
+	if path.Dir(historyPath) != path.Dir(remotePath) {
+		return "", fmt.Errorf("%s is not a clipboard push of %s", historyPath, remotePath)
+	}
+	if _, ok := parseClipboardHistoryName(remotePath, path.Base(historyPath)); !ok {
+		return "", fmt.Errorf("%s is not a clipboard push of %s", historyPath, remotePath)
+	}
+
+	client, err := NewSFTPClient(config)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	f, err := client.Open(historyPath)
+	if err != nil {
+		return "", fmt.Errorf("打开远程文件失败: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", fmt.Errorf("读取远程文件失败: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,162 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/text/unicode/norm"
+
+	"devtools/backend/internal/types"
+)
+
+// 文件名冲突的类型
+const (
+	CollisionCase    = "case"    // 文件名只有大小写不同，例如 README.md 与 readme.md
+	CollisionUnicode = "unicode" // 文件名只有 Unicode 规范化形式不同（macOS 常见的 NFD 与其他系统的 NFC）
+)
+
+// nameKey 返回比较文件名时使用的键：NFC 规范化后转为小写。
+// 两个不同的文件名键相同时，在不区分大小写（或会规范化文件名）的文件系统上指向同一个文件。
+// 文件系统只做简单的大小写映射，因此这里不使用完整的大小写折叠（ß 与 SS 是不同的文件名）。
+func nameKey(name string) string {
+	return strings.ToLower(norm.NFC.String(name))
+}
+
+// collisionKind 返回两个键相同的文件名的冲突类型
+func collisionKind(a, b string) string {
+	if norm.NFC.String(a) == norm.NFC.String(b) {
+		return CollisionUnicode
+	}
+	return CollisionCase
+}
+
+// nameChecker 检查本地文件名是否与同一目录下的其他本地文件，或远程已有的文件只有大小写或规范化形式不同。
+// 只有远程文件系统会把这样的两个文件名视为同一个文件时才算冲突：本地（例如 Linux）的两个文件会上传到
+// 同一个远程文件，后上传的覆盖先上传的；远程已有的文件会被只有大小写不同的本地文件覆盖。
+// 远程区分大小写时（例如 Linux 到 Linux）这些文件名互不影响，不会被标记。
+// 目录列表在第一次使用时读取并缓存，一个检查器用于一次同步（或一批文件变化）。
+type nameChecker struct {
+	client     *sftp.Client
+	local      fsFolding                      // 本地文件系统的行为
+	remote     fsFolding                      // 远程文件系统的行为
+	localDirs  map[string]map[string][]string // 本地目录 -> 键 -> 文件名
+	remoteDirs map[string]map[string][]string // 远程目录 -> 键 -> 文件名
+}
+
+// newNameChecker 创建同步对的检查器，两端文件系统的行为每个同步对只探测一次
+func newNameChecker(client *sftp.Client, pair types.SyncPair) *nameChecker {
+	local, remote := pairFolding(client, pair)
+	return &nameChecker{
+		client:     client,
+		local:      local,
+		remote:     remote,
+		localDirs:  make(map[string]map[string][]string),
+		remoteDirs: make(map[string]map[string][]string),
+	}
+}
+
+// check 返回与 localPath 冲突的路径及冲突类型，没有冲突时返回空字符串
+func (c *nameChecker) check(localPath, remotePath string) (conflict, kind string) {
+	if !c.remote.caseFold && !c.remote.normFold {
+		return "", "" // 远程区分所有文件名，不会有冲突
+	}
+	name := filepath.Base(localPath)
+	key := nameKey(name)
+
+	// 本地同样不区分时，同一目录下不可能有这样的两个文件
+	localDir := filepath.Dir(localPath)
+	if !c.local.caseFold || !c.local.normFold {
+		if _, ok := c.localDirs[localDir]; !ok {
+			var names []string
+			if entries, err := os.ReadDir(localDir); err == nil {
+				for _, e := range entries {
+					names = append(names, e.Name())
+				}
+			}
+			c.localDirs[localDir] = groupNames(names)
+		}
+		for _, other := range c.localDirs[localDir][key] {
+			if other != name && c.remote.folds(collisionKind(name, other)) {
+				return filepath.Join(localDir, other), collisionKind(name, other)
+			}
+		}
+	}
+
+	remoteDir, remoteName := path.Split(remotePath)
+	remoteDir = path.Clean(remoteDir)
+	if _, ok := c.remoteDirs[remoteDir]; !ok {
+		var names []string
+		// 远程目录不存在时没有冲突
+		if infos, err := c.client.ReadDir(remoteDir); err == nil {
+			for _, info := range infos {
+				names = append(names, info.Name())
+			}
+		}
+		c.remoteDirs[remoteDir] = groupNames(names)
+	}
+	candidates := c.remoteDirs[remoteDir][nameKey(remoteName)]
+	for _, other := range candidates {
+		if other == remoteName {
+			return "", "" // 远程已有同名文件，正常覆盖
+		}
+	}
+	for _, other := range candidates {
+		if kind := collisionKind(remoteName, other); c.remote.folds(kind) {
+			return path.Join(remoteDir, other), kind
+		}
+	}
+	return "", ""
+}
+
+// collisionItem 在 localPath 的文件名存在冲突时返回描述该冲突的计划项
+func (c *nameChecker) collisionItem(localPath, remotePath string) (PlanItem, bool) {
+	conflict, kind := c.check(localPath, remotePath)
+	if conflict == "" {
+		return PlanItem{}, false
+	}
+	item := PlanItem{
+		Action:       ActionCollision,
+		LocalPath:    localPath,
+		RemotePath:   remotePath,
+		Warning:      true,
+		Collision:    kind,
+		CollidesWith: conflict,
+	}
+	if kind == CollisionUnicode {
+		item.Reason = fmt.Sprintf("Name differs only in Unicode normalization from %s", conflict)
+	} else {
+		item.Reason = fmt.Sprintf("Name differs only in case from %s", conflict)
+	}
+	return item, true
+}
+
+// markCollision 把冲突信息附加到按配置仍会执行的计划项上（同步对允许冲突时）
+func markCollision(item PlanItem, collision PlanItem) PlanItem {
+	item.Warning = true
+	item.Collision, item.CollidesWith = collision.Collision, collision.CollidesWith
+	item.Reason += "; " + collision.Reason
+	return item
+}
+
+// groupNames 按 nameKey 对文件名分组
+func groupNames(names []string) map[string][]string {
+	groups := make(map[string][]string, len(names))
+	for _, name := range names {
+		key := nameKey(name)
+		groups[key] = append(groups[key], name)
+	}
+	return groups
+}
+
+// collisionBlocked 检查单个文件（例如监控到的变化）的文件名是否冲突。同步对不允许冲突且存在冲突时返回对应的计划项，
+// 调用方应改为执行该项（跳过并警告）。names 缓存目录列表，同一次同步的文件应共用一个检查器。
+func collisionBlocked(names *nameChecker, pair types.SyncPair, localPath, remotePath string) (PlanItem, bool) {
+	if pair.AllowNameCollisions {
+		return PlanItem{}, false
+	}
+	return names.collisionItem(localPath, remotePath)
+}
@@ -0,0 +1,216 @@
+package syncer
+
+import (
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+
+	"devtools/backend/internal/types"
+)
+
+// newTestSFTPClient 返回连接到内存 SFTP 服务器的客户端，内存文件系统区分大小写
+func newTestSFTPClient(t *testing.T) *sftp.Client {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, sftp.InMemHandler())
+	go server.Serve()
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client
+}
+
+func writeLocalFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeRemoteFiles(t *testing.T, client *sftp.Client, dir string, names ...string) {
+	t.Helper()
+	if err := client.MkdirAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		f, err := client.Create(path.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+}
+
+// TestNameCheckerCaseSensitiveRemote 测试远程区分大小写时（Linux 到 Linux），只有大小写不同的文件不算冲突
+func TestNameCheckerCaseSensitiveRemote(t *testing.T) {
+	client := newTestSFTPClient(t)
+	localDir := t.TempDir()
+	writeLocalFiles(t, localDir, "xt_CONNMARK.h", "xt_connmark.h")
+	writeRemoteFiles(t, client, "/srv/include", "xt_CONNMARK.h")
+	if folds := probeFolding(localProbeFS(), localDir); folds.caseFold {
+		t.Skip("local filesystem is case-insensitive")
+	}
+
+	pair := types.SyncPair{ID: "case-sensitive", LocalPath: localDir, RemotePath: "/srv/include"}
+	names := newNameChecker(client, pair)
+	if names.remote.caseFold {
+		t.Fatalf("in-memory remote should be detected as case-sensitive")
+	}
+	for _, name := range []string{"xt_CONNMARK.h", "xt_connmark.h"} {
+		if item, blocked := collisionBlocked(names, pair, filepath.Join(localDir, name), "/srv/include/"+name); blocked {
+			t.Errorf("%s should not be flagged on a case-sensitive remote, got %+v", name, item)
+		}
+	}
+}
+
+// TestNameCheckerCaseFoldingRemote 测试远程不区分大小写时，本地的两个文件以及与远程已有文件的冲突都会被标记
+func TestNameCheckerCaseFoldingRemote(t *testing.T) {
+	client := newTestSFTPClient(t)
+	localDir := t.TempDir()
+	writeLocalFiles(t, localDir, "xt_CONNMARK.h", "xt_connmark.h", "README.md")
+	writeRemoteFiles(t, client, "/srv/include", "readme.md", "notes.txt")
+
+	pair := types.SyncPair{ID: "case-folding", LocalPath: localDir, RemotePath: "/srv/include"}
+	names := newNameChecker(client, pair)
+	names.local = fsFolding{}
+	names.remote = fsFolding{caseFold: true, normFold: true}
+
+	item, blocked := collisionBlocked(names, pair, filepath.Join(localDir, "xt_connmark.h"), "/srv/include/xt_connmark.h")
+	if !blocked || item.Collision != CollisionCase || filepath.Base(item.CollidesWith) != "xt_CONNMARK.h" {
+		t.Errorf("local siblings differing in case should collide, got %+v, %v", item, blocked)
+	}
+	item, blocked = collisionBlocked(names, pair, filepath.Join(localDir, "README.md"), "/srv/include/README.md")
+	if !blocked || item.CollidesWith != "/srv/include/readme.md" {
+		t.Errorf("an existing remote file differing in case should collide, got %+v, %v", item, blocked)
+	}
+	if _, blocked := collisionBlocked(names, pair, filepath.Join(localDir, "notes.txt"), "/srv/include/notes.txt"); blocked {
+		t.Errorf("overwriting a file with the same name is not a collision")
+	}
+
+	// 允许冲突的同步对不拦截
+	pair.AllowNameCollisions = true
+	if _, blocked := collisionBlocked(names, pair, filepath.Join(localDir, "README.md"), "/srv/include/README.md"); blocked {
+		t.Errorf("pairs allowing collisions should not be blocked")
+	}
+}
+
+// TestNameCheckerCachesRemoteListing 测试同一目录的远程列表只读取一次
+func TestNameCheckerCachesRemoteListing(t *testing.T) {
+	client := newTestSFTPClient(t)
+	localDir := t.TempDir()
+	writeRemoteFiles(t, client, "/srv/data", "a.txt")
+
+	names := newNameChecker(client, types.SyncPair{ID: "cache", LocalPath: localDir, RemotePath: "/srv/data"})
+	names.remote = fsFolding{caseFold: true}
+	if conflict, _ := names.check(filepath.Join(localDir, "b.txt"), "/srv/data/b.txt"); conflict != "" {
+		t.Fatalf("unexpected conflict %q", conflict)
+	}
+	// 读取列表之后远程新增的文件不会被看到，说明列表来自缓存
+	writeRemoteFiles(t, client, "/srv/data", "B.txt")
+	if conflict, _ := names.check(filepath.Join(localDir, "b.txt"), "/srv/data/b.txt"); conflict != "" {
+		t.Errorf("the remote listing should be cached, got conflict %q", conflict)
+	}
+	if len(names.remoteDirs) != 1 {
+		t.Errorf("remoteDirs = %v, want one cached directory", names.remoteDirs)
+	}
+}
+
+// foldingFS 是按 fold 折叠文件名的内存文件系统，用于测试探测逻辑
+type foldingFS struct {
+	files map[string]string // 折叠后的路径 -> 实际文件名
+	fold  func(string) string
+	ro    bool
+}
+
+func (f *foldingFS) probeFS() probeFS {
+	return probeFS{
+		list: func(dir string) ([]string, error) {
+			var names []string
+			for key, name := range f.files {
+				if path.Dir(key) == f.fold(dir) {
+					names = append(names, name)
+				}
+			}
+			return names, nil
+		},
+		exists: func(p string) (bool, error) {
+			_, ok := f.files[f.fold(p)]
+			return ok, nil
+		},
+		create: func(p string) error {
+			if f.ro {
+				return os.ErrPermission
+			}
+			f.files[f.fold(p)] = path.Base(p)
+			return nil
+		},
+		remove: func(p string) error {
+			delete(f.files, f.fold(p))
+			return nil
+		},
+		join: path.Join,
+	}
+}
+
+// TestProbeFolding 测试根据已有文件或临时文件探测文件系统是否折叠大小写
+func TestProbeFolding(t *testing.T) {
+	identity := func(s string) string { return s }
+
+	tests := []struct {
+		name  string
+		fs    *foldingFS
+		want  fsFolding
+		empty bool // 探测后不应留下临时文件
+	}{
+		{
+			name: "case-insensitive with existing files",
+			fs:   &foldingFS{files: map[string]string{"/data/readme.md": "README.md"}, fold: strings.ToLower, ro: true},
+			want: fsFolding{caseFold: true, normFold: true},
+		},
+		{
+			name: "case-sensitive with existing files",
+			fs:   &foldingFS{files: map[string]string{"/data/README.md": "README.md"}, fold: identity},
+			want: fsFolding{},
+		},
+		{
+			name:  "empty case-sensitive directory uses a probe file",
+			fs:    &foldingFS{files: map[string]string{}, fold: identity},
+			want:  fsFolding{},
+			empty: true,
+		},
+		{
+			name:  "empty case-insensitive directory uses a probe file",
+			fs:    &foldingFS{files: map[string]string{}, fold: strings.ToLower},
+			want:  fsFolding{caseFold: true},
+			empty: true,
+		},
+		{
+			name: "unknown behaviour is assumed to fold",
+			fs:   &foldingFS{files: map[string]string{}, fold: identity, ro: true},
+			want: fsFolding{caseFold: true, normFold: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := probeFolding(tt.fs.probeFS(), "/data")
+			if got != tt.want {
+				t.Errorf("probeFolding = %+v, want %+v", got, tt.want)
+			}
+			if tt.empty && len(tt.fs.files) != 0 {
+				t.Errorf("probe files should be removed, left %v", tt.fs.files)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package syncer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/text/unicode/norm"
+
+	"devtools/backend/internal/types"
+)
+
+// fsFolding 描述文件系统是否把只有大小写（caseFold）或 Unicode 规范化形式（normFold）不同的文件名视为同一个文件，
+// 例如 macOS 的 APFS 两者都是，Windows 的 NTFS 只有前者，Linux 的 ext4 都不是
+type fsFolding struct {
+	caseFold bool
+	normFold bool
+}
+
+// folds 判断该类型的冲突在这个文件系统上是否会指向同一个文件
+func (f fsFolding) folds(kind string) bool {
+	if kind == CollisionUnicode {
+		return f.normFold
+	}
+	return f.caseFold
+}
+
+// foldingCache 缓存每个同步对两端的探测结果，key 为同步对 ID、SSH 配置与两端的根目录
+var foldingCache sync.Map // string -> [2]fsFolding
+
+// pairFolding 返回同步对本地与远程文件系统的行为，每个同步对只探测一次
+func pairFolding(client *sftp.Client, pair types.SyncPair) (local, remote fsFolding) {
+	key := strings.Join([]string{pair.ID, pair.ConfigID, pair.LocalPath, pair.RemotePath}, "\x00")
+	if cached, ok := foldingCache.Load(key); ok {
+		result := cached.([2]fsFolding)
+		return result[0], result[1]
+	}
+	local = probeFolding(localProbeFS(), pair.LocalPath)
+	remote = probeFolding(remoteProbeFS(client), pair.RemotePath)
+	foldingCache.Store(key, [2]fsFolding{local, remote})
+	logger.Printf("文件名大小写探测: %s (case-insensitive: %t, normalization-insensitive: %t) -> %s (case-insensitive: %t, normalization-insensitive: %t)",
+		pair.LocalPath, local.caseFold, local.normFold, pair.RemotePath, remote.caseFold, remote.normFold)
+	return local, remote
+}
+
+// probeFS 是探测文件系统行为所需的操作，本地与远程（SFTP）各有一个实现
+type probeFS struct {
+	list   func(dir string) ([]string, error)
+	exists func(path string) (bool, error)
+	create func(path string) error
+	remove func(path string) error
+	join   func(elem ...string) string
+}
+
+func localProbeFS() probeFS {
+	return probeFS{
+		list: func(dir string) ([]string, error) {
+			entries, err := os.ReadDir(dir)
+			names := make([]string, 0, len(entries))
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			return names, err
+		},
+		exists: func(p string) (bool, error) {
+			_, err := os.Lstat(p)
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return err == nil, err
+		},
+		create: func(p string) error {
+			f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+			if err == nil {
+				err = f.Close()
+			}
+			return err
+		},
+		remove: os.Remove,
+		join:   filepath.Join,
+	}
+}
+
+func remoteProbeFS(client *sftp.Client) probeFS {
+	return probeFS{
+		list: func(dir string) ([]string, error) {
+			infos, err := client.ReadDir(dir)
+			names := make([]string, 0, len(infos))
+			for _, info := range infos {
+				names = append(names, info.Name())
+			}
+			return names, err
+		},
+		exists: func(p string) (bool, error) {
+			_, err := client.Lstat(p)
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return err == nil, err
+		},
+		create: func(p string) error {
+			f, err := client.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY)
+			if err == nil {
+				err = f.Close()
+			}
+			return err
+		},
+		remove: client.Remove,
+		join:   path.Join,
+	}
+}
+
+// probeFolding 探测 root 所在文件系统的行为。优先只读地查找 root 下已有文件名的变体，
+// 没有合适的文件时创建一个临时文件再查找；都不行时（例如目录不存在或只读）按会折叠处理，宁可多报冲突。
+func probeFolding(fs probeFS, root string) fsFolding {
+	names, _ := fs.list(root)
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	tag := hex.EncodeToString(suffix)
+	return fsFolding{
+		caseFold: probeVariant(fs, root, names, swapCase, ".devtools-case-probe-"+tag),
+		normFold: probeVariant(fs, root, names, norm.NFD.String, ".devtools-norm-probe-é-"+tag),
+	}
+}
+
+// probeVariant 判断文件名的变体（variant(name)）是否指向同一个文件
+func probeVariant(fs probeFS, root string, names []string, variant func(string) string, probeName string) bool {
+	for _, name := range names {
+		other := variant(name)
+		if other == name || slices.Contains(names, other) {
+			continue
+		}
+		if found, err := fs.exists(fs.join(root, other)); err == nil {
+			return found
+		}
+	}
+
+	probe := fs.join(root, probeName)
+	if err := fs.create(probe); err != nil {
+		return true
+	}
+	defer fs.remove(probe)
+	found, err := fs.exists(fs.join(root, variant(probeName)))
+	return err != nil || found
+}
+
+// swapCase 交换文件名中字母的大小写
+func swapCase(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, name)
+}
@@ -0,0 +1,284 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxRunsPerPair 是每个同步对保留的历史记录条数，超出后优先丢弃最早的成功记录，失败记录保留到重试为止
+	maxRunsPerPair = 20
+	// maxTransfersPerRun 是每条记录保留的文件明细数；失败的文件始终保留，以便重试
+	maxTransfersPerRun = 1000
+	// historySaveDelay 是记录之后写入磁盘的延迟，期间的多条记录合并为一次写入
+	historySaveDelay = 2 * time.Second
+)
+
+// 同步记录的触发方式
+const (
+	TriggerFullSync = "full"  // 开始监控或修改同步对时的完整同步
+	TriggerWatch    = "watch" // 文件变化触发的同步
+	TriggerRetry    = "retry" // 重试上一次失败的文件
+)
+
+// 单个文件的传输结果
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+	ResultSkipped = "skipped"
+)
+
+// TransferRecord 是一次同步中单个文件（或目录）的传输明细
+type TransferRecord struct {
+	LocalPath  string `json:"localPath"`
+	RemotePath string `json:"remotePath"`
+	Direction  string `json:"direction"` // mkdir / upload / chmod / symlink / delete，规划失败时与 Result 一起为 error
+	Size       int64  `json:"size,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	Result     string `json:"result"`
+	Error      string `json:"error,omitempty"` // 失败或跳过的原因
+}
+
+// SyncRun 是同步对的一次同步记录
+type SyncRun struct {
+	ID         string           `json:"id"`
+	PairID     string           `json:"pairId"`
+	Trigger    string           `json:"trigger"`
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Succeeded  int              `json:"succeeded"`
+	Failed     int              `json:"failed"`
+	Skipped    int              `json:"skipped"`
+	Bytes      int64            `json:"bytes"`           // 成功上传的字节数
+	Omitted    int              `json:"omitted"`         // 超出明细上限而未保存的成功/跳过记录数
+	Error      string           `json:"error,omitempty"` // 整体失败的原因，例如无法连接或钩子失败
+	PreHook    *HookResult      `json:"preHook,omitempty"`
+	PostHook   *HookResult      `json:"postHook,omitempty"`
+	Transfers  []TransferRecord `json:"transfers"`
+}
+
+// newSyncRun 开始一条新的同步记录
+func newSyncRun(pairID, trigger string) *SyncRun {
+	return &SyncRun{
+		ID:        uuid.NewString(),
+		PairID:    pairID,
+		Trigger:   trigger,
+		StartedAt: time.Now(),
+		Transfers: []TransferRecord{},
+	}
+}
+
+// NewFailedRun 返回一条整体失败（例如无法连接远程）的同步记录
+func NewFailedRun(pairID, trigger string, err error) *SyncRun {
+	run := newSyncRun(pairID, trigger)
+	run.Error = err.Error()
+	run.FinishedAt = run.StartedAt
+	return run
+}
+
+// add 加入一条文件明细并更新统计
+func (r *SyncRun) add(rec TransferRecord) {
+	switch rec.Result {
+	case ResultSuccess:
+		r.Succeeded++
+		if rec.Direction == string(ActionUpload) {
+			r.Bytes += rec.Size
+		}
+	case ResultError:
+		r.Failed++
+	case ResultSkipped:
+		r.Skipped++
+	}
+	if len(r.Transfers) >= maxTransfersPerRun && rec.Result != ResultError {
+		r.Omitted++
+		return
+	}
+	r.Transfers = append(r.Transfers, rec)
+}
+
+// hasFailures 判断本次同步是否有失败的文件或整体失败
+func (r *SyncRun) hasFailures() bool {
+	return r.Failed > 0 || r.Error != ""
+}
+
+// FailedTransfers 返回本次同步中失败的文件明细
+func (r *SyncRun) FailedTransfers() []TransferRecord {
+	var failed []TransferRecord
+	for _, rec := range r.Transfers {
+		if rec.Result == ResultError {
+			failed = append(failed, rec)
+		}
+	}
+	return failed
+}
+
+// HistoryStore 按同步对保存有限条数的同步记录，持久化为一个 JSON 文件。
+// 记录按时间先后排列，最新的在最后。Record 不立即写入，historySaveDelay 之后合并写入一次，
+// 退出前需调用 Flush。
+type HistoryStore struct {
+	path string
+	runs map[string][]SyncRun
+	mu   sync.RWMutex
+
+	dirty     bool        // 有尚未写入磁盘的记录
+	saveTimer *time.Timer // 已安排的写入，受 mu 保护
+}
+
+// NewHistoryStore 创建保存在 path 的历史记录，需调用 Load 读取已有记录
+func NewHistoryStore(path string) *HistoryStore {
+	return &HistoryStore{path: path, runs: make(map[string][]SyncRun)}
+}
+
+// Load 从磁盘读取历史记录，文件不存在时为空
+func (h *HistoryStore) Load() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read sync history file: %w", err)
+	}
+	runs := make(map[string][]SyncRun)
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return fmt.Errorf("failed to unmarshal sync history: %w", err)
+	}
+	h.runs = runs
+	return nil
+}
+
+// save_nolock 将历史记录写入磁盘，调用方需持有 h.mu
+func (h *HistoryStore) save_nolock() error {
+	data, err := json.MarshalIndent(h.runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create sync history directory: %w", err)
+	}
+	if err := os.WriteFile(h.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write sync history file: %w", err)
+	}
+	return nil
+}
+
+// Record 保存一条完成的同步记录并安排写入磁盘；没有任何文件明细且没有错误的记录会被忽略
+func (h *HistoryStore) Record(run *SyncRun) {
+	if h == nil || run == nil {
+		return
+	}
+	if run.Succeeded+run.Failed+run.Skipped == 0 && run.Error == "" {
+		return // 已经同步，没有任何操作
+	}
+	if run.FinishedAt.IsZero() {
+		run.FinishedAt = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.runs[run.PairID] = trimRuns(append(h.runs[run.PairID], *run))
+	h.dirty = true
+	if h.saveTimer == nil {
+		h.saveTimer = time.AfterFunc(historySaveDelay, h.saveScheduled)
+	}
+}
+
+// trimRuns 把记录减少到 maxRunsPerPair 条：优先丢弃最早的成功记录，全部失败时丢弃最早的记录。
+// 频繁的监控同步不会把需要重试的失败记录挤出去。
+func trimRuns(runs []SyncRun) []SyncRun {
+	for len(runs) > maxRunsPerPair {
+		i := slices.IndexFunc(runs, func(r SyncRun) bool { return !r.hasFailures() })
+		if i < 0 {
+			i = 0
+		}
+		runs = slices.Delete(runs, i, i+1)
+	}
+	return runs
+}
+
+// saveScheduled 由 Record 安排的定时器调用，写入之前的所有记录
+func (h *HistoryStore) saveScheduled() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.saveTimer = nil
+	h.flush_nolock()
+}
+
+// Flush 立即写入尚未保存的记录，应在停止同步服务时调用
+func (h *HistoryStore) Flush() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.saveTimer != nil {
+		h.saveTimer.Stop()
+		h.saveTimer = nil
+	}
+	h.flush_nolock()
+}
+
+// flush_nolock 在有未保存的记录时写入磁盘，调用方需持有 h.mu
+func (h *HistoryStore) flush_nolock() {
+	if !h.dirty {
+		return
+	}
+	if err := h.save_nolock(); err != nil {
+		logger.Printf("Warning: failed to save sync history: %v", err)
+		return
+	}
+	h.dirty = false
+}
+
+// Runs 返回同步对最近的 limit 条记录，最新的在前；limit <= 0 时返回全部
+func (h *HistoryStore) Runs(pairID string, limit int) []SyncRun {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	runs := h.runs[pairID]
+	if limit <= 0 || limit > len(runs) {
+		limit = len(runs)
+	}
+	out := make([]SyncRun, 0, limit)
+	for i := len(runs) - 1; i >= len(runs)-limit; i-- {
+		out = append(out, runs[i])
+	}
+	return out
+}
+
+// LastRun 返回同步对最近的一条记录
+func (h *HistoryStore) LastRun(pairID string) (SyncRun, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	runs := h.runs[pairID]
+	if len(runs) == 0 {
+		return SyncRun{}, false
+	}
+	return runs[len(runs)-1], true
+}
+
+// DeletePair 删除同步对的所有记录
+func (h *HistoryStore) DeletePair(pairID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.runs[pairID]; !ok {
+		return nil
+	}
+	delete(h.runs, pairID)
+	if err := h.save_nolock(); err != nil {
+		return err
+	}
+	h.dirty = false
+	return nil
+}
@@ -0,0 +1,66 @@
+package syncer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testRun(pairID string, failed int) *SyncRun {
+	run := newSyncRun(pairID, TriggerWatch)
+	run.Succeeded = 1
+	run.Failed = failed
+	return run
+}
+
+// TestHistoryKeepsFailedRuns 测试超出条数上限时优先丢弃成功的记录，失败记录不会被频繁的同步挤出去
+func TestHistoryKeepsFailedRuns(t *testing.T) {
+	h := NewHistoryStore(filepath.Join(t.TempDir(), "sync_history.json"))
+	t.Cleanup(h.Flush)
+
+	failed := testRun("pair", 1)
+	h.Record(failed)
+	for i := 0; i < maxRunsPerPair*2; i++ {
+		h.Record(testRun("pair", 0))
+	}
+	runs := h.Runs("pair", 0)
+	if len(runs) != maxRunsPerPair {
+		t.Fatalf("len(runs) = %d, want %d", len(runs), maxRunsPerPair)
+	}
+	if last := runs[len(runs)-1]; last.ID != failed.ID {
+		t.Errorf("the failed run should be kept as the oldest run, got %+v", last)
+	}
+
+	// 全部是失败记录时丢弃最早的
+	h.DeletePair("pair")
+	first := testRun("pair", 1)
+	h.Record(first)
+	for i := 0; i < maxRunsPerPair; i++ {
+		h.Record(testRun("pair", 1))
+	}
+	for _, run := range h.Runs("pair", 0) {
+		if run.ID == first.ID {
+			t.Error("the oldest failed run should be dropped when every run failed")
+		}
+	}
+}
+
+// TestHistoryBatchesWrites 测试记录不会立即写入磁盘，Flush 之后写入并可以重新加载
+func TestHistoryBatchesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync_history.json")
+	h := NewHistoryStore(path)
+	h.Record(testRun("pair", 0))
+	h.Record(testRun("pair", 1))
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("history should not be written on every record, stat err = %v", err)
+	}
+
+	h.Flush()
+	loaded := NewHistoryStore(path)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if runs := loaded.Runs("pair", 0); len(runs) != 2 || runs[0].Failed != 1 {
+		t.Errorf("flushed history = %+v, want both runs with the newest first", runs)
+	}
+}
@@ -0,0 +1,202 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"devtools/backend/internal/types"
+)
+
+const (
+	// hookTimeout 是单个钩子命令的最长执行时间
+	hookTimeout = 2 * time.Minute
+	// maxHookOutput 是钩子输出保存到同步历史中的最大字节数
+	maxHookOutput = 16 * 1024
+)
+
+// HookResult 是一次钩子命令的执行结果
+type HookResult struct {
+	Command    string `json:"command"`
+	Remote     bool   `json:"remote"` // true 表示在远程服务器上执行
+	Output     string `json:"output,omitempty"`
+	ExitCode   int    `json:"exitCode"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SyncWithHooks 执行同步对的一次完整同步，并在前后运行同步对配置的钩子：
+//   - PreSyncCommand 在本地执行，失败时取消本次同步
+//   - PostSyncCommand 在远程执行，仅当本次同步有变更且没有失败时执行
+func SyncWithHooks(client *sftp.Client, cfg types.SSHConfig, pair types.SyncPair, emitLog func(level, message string)) *SyncRun {
+	run := newSyncRun(pair.ID, TriggerFullSync)
+	if runPreSyncHook(pair, run, emitLog) {
+		reconcileInto(client, pair, run, emitLog)
+		runPostSyncHook(cfg, pair, run, emitLog)
+	}
+	run.FinishedAt = time.Now()
+	return run
+}
+
+// runPreSyncHook 在本地执行同步前命令，返回是否继续同步
+func runPreSyncHook(pair types.SyncPair, run *SyncRun, emitLog func(level, message string)) bool {
+	command := strings.TrimSpace(pair.PreSyncCommand)
+	if command == "" {
+		return true
+	}
+
+	emitLog("INFO", fmt.Sprintf("Running pre-sync hook for %s: %s", pair.LocalPath, command))
+	result := runLocalHook(command, pair.LocalPath)
+	run.PreHook = result
+	reportHookOutput(result, emitLog)
+	if result.Error != "" {
+		emitLog("ERROR", fmt.Sprintf("HOOK FAILED (pre-sync, local): %s: %s. Sync of %s cancelled.", command, result.Error, pair.LocalPath))
+		run.Error = "pre-sync hook failed: " + result.Error
+		return false
+	}
+	emitLog("SUCCESS", fmt.Sprintf("Pre-sync hook finished: %s", command))
+	return true
+}
+
+// runPostSyncHook 在远程执行同步后命令；本次同步没有变更或有失败的文件时跳过
+func runPostSyncHook(cfg types.SSHConfig, pair types.SyncPair, run *SyncRun, emitLog func(level, message string)) {
+	command := strings.TrimSpace(pair.PostSyncCommand)
+	if command == "" || run.Succeeded == 0 {
+		return
+	}
+	if run.Failed > 0 || run.Error != "" {
+		emitLog("WARN", fmt.Sprintf("Skipped post-sync hook for %s: sync finished with errors", pair.LocalPath))
+		return
+	}
+
+	emitLog("INFO", fmt.Sprintf("Running post-sync hook on %s: %s", cfg.Host, command))
+	result := runRemoteHook(cfg, command, pair.RemotePath)
+	run.PostHook = result
+	reportHookOutput(result, emitLog)
+	if result.Error != "" {
+		emitLog("ERROR", fmt.Sprintf("HOOK FAILED (post-sync, remote): %s: %s", command, result.Error))
+		run.Error = "post-sync hook failed: " + result.Error
+		return
+	}
+	emitLog("SUCCESS", fmt.Sprintf("Post-sync hook finished: %s", command))
+}
+
+// runLocalHook 在 dir 目录下用系统 Shell 执行命令
+func runLocalHook(command, dir string) *HookResult {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/c", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	cmd.Dir = dir
+
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	result := &HookResult{Command: command, Output: truncateHookOutput(output), DurationMs: time.Since(start).Milliseconds()}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("timed out after %s", hookTimeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = fmt.Sprintf("exit status %d", result.ExitCode)
+	case err != nil:
+		result.ExitCode = -1
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// runRemoteHook 建立新的 SSH 连接，在 dir 目录下执行命令
+func runRemoteHook(cfg types.SSHConfig, command, dir string) *HookResult {
+	result := &HookResult{Command: command, Remote: true}
+	start := time.Now()
+	defer func() { result.DurationMs = time.Since(start).Milliseconds() }()
+
+	client, err := dialSSH(cfg)
+	if err != nil {
+		result.ExitCode = -1
+		result.Error = err.Error()
+		return result
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("failed to open session: %v", err)
+		return result
+	}
+	defer session.Close()
+
+	var output bytes.Buffer
+	session.Stdout = &output
+	session.Stderr = &output
+	done := make(chan error, 1)
+	go func() { done <- session.Run(fmt.Sprintf("cd %s && %s", shellQuote(dir), command)) }()
+
+	select {
+	case err = <-done:
+	case <-time.After(hookTimeout):
+		// 关闭连接使 Run 返回，远程进程会随 SSH 会话结束收到 SIGHUP
+		client.Close()
+		<-done
+		result.Output = truncateHookOutput(output.Bytes())
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("timed out after %s", hookTimeout)
+		return result
+	}
+
+	result.Output = truncateHookOutput(output.Bytes())
+	var exitErr *ssh.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitStatus()
+		result.Error = fmt.Sprintf("exit status %d", result.ExitCode)
+	case err != nil:
+		result.ExitCode = -1
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// reportHookOutput 将钩子的输出逐行写入同步日志
+func reportHookOutput(result *HookResult, emitLog func(level, message string)) {
+	if result.Output == "" {
+		return
+	}
+	where := "local"
+	if result.Remote {
+		where = "remote"
+	}
+	for _, line := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+		emitLog("INFO", fmt.Sprintf("[hook:%s] %s", where, line))
+	}
+}
+
+// truncateHookOutput 截断过长的输出，保留末尾部分（错误信息通常在最后）
+func truncateHookOutput(output []byte) string {
+	if len(output) > maxHookOutput {
+		output = append([]byte("...(truncated)\n"), output[len(output)-maxHookOutput:]...)
+	}
+	return strings.ToValidUTF8(string(output), "�")
+}
+
+// shellQuote 用单引号包裹参数，供远程 POSIX Shell 使用
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
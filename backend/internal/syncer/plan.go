@@ -0,0 +1,286 @@
+package syncer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"devtools/backend/internal/types"
+)
+
+// SyncAction 是同步计划中的一个操作
+type SyncAction string
+
+const (
+	ActionMkdir   SyncAction = "mkdir"   // 创建远程目录
+	ActionUpload  SyncAction = "upload"  // 上传文件
+	ActionChmod   SyncAction = "chmod"   // 内容一致，只更新权限位
+	ActionSymlink SyncAction = "symlink" // 在远程重建符号链接
+	ActionSkip    SyncAction = "skip"    // 按配置跳过（符号链接、超大文件等）
+	ActionError   SyncAction = "error"   // 无法比对（读取本地或远程信息失败）
+
+	ActionCollision SyncAction = "collision" // 文件名与其他文件只有大小写或 Unicode 规范化形式不同，可能互相覆盖，同步对允许冲突前不会同步
+)
+
+// PlanItem 是同步计划中的一项
+type PlanItem struct {
+	Action     SyncAction `json:"action"`
+	LocalPath  string     `json:"localPath"`
+	RemotePath string     `json:"remotePath"`
+	Reason     string     `json:"reason"`
+	Size       int64      `json:"size,omitempty"`
+	Warning    bool       `json:"warning,omitempty"` // 需要用户注意的跳过，例如超过大小上限的文件
+
+	Collision    string `json:"collision,omitempty"`    // 文件名冲突的类型：case | unicode
+	CollidesWith string `json:"collidesWith,omitempty"` // 与之冲突的本地或远程路径
+}
+
+// SyncPlan 是一次完整同步将要执行的操作（dry-run 的结果）。
+// 已经同步的文件不会出现在计划中。
+type SyncPlan struct {
+	PairID      string     `json:"pairId"`
+	LocalPath   string     `json:"localPath"`
+	RemotePath  string     `json:"remotePath"`
+	Items       []PlanItem `json:"items"`
+	UploadBytes int64      `json:"uploadBytes"` // 需要上传的字节数
+}
+
+// PlanDirectory 比对本地目录与远程目录，返回同步计划，不修改远程的任何内容。
+// 文件名只有大小写或 Unicode 规范化形式不同的文件会被标记为冲突；同步对不允许冲突时，冲突的文件（及目录下的所有内容）不会同步。
+func PlanDirectory(client *sftp.Client, pair types.SyncPair) (*SyncPlan, error) {
+	plan := &SyncPlan{PairID: pair.ID, LocalPath: pair.LocalPath, RemotePath: pair.RemotePath, Items: []PlanItem{}}
+	names := newNameChecker(client, pair)
+
+	// 使用 filepath.WalkDir 遍历本地目录 (Go 1.16+ 推荐)
+	err := filepath.WalkDir(pair.LocalPath, func(localPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err // 传递遍历过程中的错误
+		}
+
+		// 计算相对路径和远程路径
+		relativePath, err := filepath.Rel(pair.LocalPath, localPath)
+		if err != nil {
+			return err
+		}
+		remotePath := path.Join(pair.RemotePath, filepath.ToSlash(relativePath))
+
+		if relativePath != "." {
+			if collision, ok := names.collisionItem(localPath, remotePath); ok {
+				if !pair.AllowNameCollisions {
+					plan.Items = append(plan.Items, collision)
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if item, ok := planEntry(client, pair, localPath, remotePath, d); ok {
+					plan.Items = append(plan.Items, markCollision(item, collision))
+					if item.Action == ActionUpload {
+						plan.UploadBytes += item.Size
+					}
+				}
+				return nil
+			}
+		}
+
+		if item, ok := planEntry(client, pair, localPath, remotePath, d); ok {
+			plan.Items = append(plan.Items, item)
+			if item.Action == ActionUpload {
+				plan.UploadBytes += item.Size
+			}
+		}
+		return nil
+	})
+	return plan, err
+}
+
+// planEntry 决定单个本地路径需要执行的操作，已同步时返回 false
+func planEntry(client *sftp.Client, pair types.SyncPair, localPath, remotePath string, d fs.DirEntry) (PlanItem, bool) {
+	item := PlanItem{LocalPath: localPath, RemotePath: remotePath}
+	errorItem := func(format string, args ...any) (PlanItem, bool) {
+		item.Action = ActionError
+		item.Reason = fmt.Sprintf(format, args...)
+		return item, true
+	}
+
+	if d.IsDir() {
+		remoteInfo, err := client.Stat(remotePath)
+		switch {
+		case os.IsNotExist(err):
+			item.Action, item.Reason = ActionMkdir, "Remote missing"
+			return item, true
+		case err != nil:
+			return errorItem("Failed to get remote dir info for %s: %v", remotePath, err)
+		}
+		if pair.PreservePermissions {
+			if info, err := d.Info(); err == nil && info.Mode().Perm() != remoteInfo.Mode().Perm() {
+				item.Action, item.Reason = ActionChmod, "Mode differs"
+				return item, true
+			}
+		}
+		return item, false
+	}
+
+	// --- 符号链接：WalkDir 不会跟随链接，这里按同步对的配置处理 ---
+	if d.Type()&fs.ModeSymlink != 0 {
+		switch pair.EffectiveSymlinkMode() {
+		case types.SymlinkSkip:
+			item.Action, item.Reason = ActionSkip, "Symlink"
+			return item, true
+		case types.SymlinkRecreate:
+			target, err := os.Readlink(localPath)
+			if err != nil {
+				return errorItem("Failed to read symlink %s: %v", localPath, err)
+			}
+			if existing, err := client.ReadLink(remotePath); err == nil && existing == filepath.ToSlash(target) {
+				return item, false
+			}
+			item.Action, item.Reason = ActionSymlink, "Symlink differs"
+			return item, true
+		}
+		// SymlinkCopy: 按链接指向的文件继续比对
+	}
+
+	// --- 以下是文件比对逻辑 ---
+	localInfo, err := os.Stat(localPath)
+	if err != nil {
+		return errorItem("Failed to get local file info for %s: %v", localPath, err)
+	}
+	if localInfo.IsDir() {
+		item.Action, item.Reason = ActionSkip, "Symlink points to a directory"
+		return item, true
+	}
+	item.Size = localInfo.Size()
+	if exceedsMaxFileSize(pair, localInfo.Size()) {
+		item.Action, item.Warning = ActionSkip, true
+		item.Reason = fmt.Sprintf("File too large (%s > %s)", formatSize(localInfo.Size()), formatSize(pair.MaxFileSize))
+		return item, true
+	}
+
+	// 检查远程文件状态
+	remoteInfo, err := client.Stat(remotePath)
+
+	// 检查点1: 远程文件不存在
+	if os.IsNotExist(err) {
+		item.Action, item.Reason = ActionUpload, "Remote missing"
+		return item, true
+	}
+
+	// 其他获取远程文件信息的错误
+	if err != nil {
+		return errorItem("Failed to get remote file info for %s: %v", remotePath, err)
+	}
+
+	// 检查点2: 远程文件存在，但大小不一致
+	if localInfo.Size() != remoteInfo.Size() {
+		item.Action, item.Reason = ActionUpload, "Size differs"
+		return item, true
+	}
+
+	// 检查点3: 保留修改时间时，时间不一致说明内容可能已变化（SFTP 只精确到秒）
+	if pair.PreserveMtime && localInfo.ModTime().Unix() != remoteInfo.ModTime().Unix() {
+		item.Action, item.Reason = ActionUpload, "Mtime differs"
+		return item, true
+	}
+
+	// 检查点4: 内容一致但权限位不同，只更新权限
+	if pair.PreservePermissions && localInfo.Mode().Perm() != remoteInfo.Mode().Perm() {
+		item.Action, item.Reason = ActionChmod, "Mode differs"
+		return item, true
+	}
+
+	// 如果远程文件存在且大小一致，则认为它是同步的
+	return item, false
+}
+
+// applyPlanItem 执行同步计划中的一项，并通过 emitLog 报告结果，返回该项的传输明细
+func applyPlanItem(client *sftp.Client, pair types.SyncPair, item PlanItem, emitLog func(level, message string)) TransferRecord {
+	rec := TransferRecord{
+		LocalPath:  item.LocalPath,
+		RemotePath: item.RemotePath,
+		Direction:  string(item.Action),
+		Size:       item.Size,
+		Result:     ResultSuccess,
+	}
+	start := time.Now()
+	var err error
+	switch item.Action {
+	case ActionMkdir:
+		// 确保远程也创建对应的目录结构，即使是空目录
+		if err = client.MkdirAll(item.RemotePath); err != nil {
+			emitLog("ERROR", fmt.Sprintf("Failed to create remote dir %s: %v", item.RemotePath, err))
+			break
+		}
+		if pair.PreservePermissions {
+			err = applyLocalMode(client, item, emitLog)
+		}
+	case ActionChmod:
+		err = applyLocalMode(client, item, emitLog)
+	case ActionUpload, ActionSymlink:
+		// 修改日志格式，下同
+		emitLog("INFO", fmt.Sprintf("%s, syncing: %s -> %s", item.Reason, item.LocalPath, item.RemotePath))
+		rec = syncAndReport(client, item.LocalPath, item.RemotePath, pair, emitLog)
+		rec.Direction = string(item.Action)
+		return rec
+	case ActionSkip:
+		level := "INFO"
+		if item.Warning {
+			level = "WARN"
+		}
+		emitLog(level, fmt.Sprintf("Skipped: %s (%s)", item.LocalPath, item.Reason))
+		rec.Result, rec.Error = ResultSkipped, item.Reason
+	case ActionCollision:
+		emitLog("WARN", fmt.Sprintf("Skipped: %s (%s, allow name collisions on the sync pair to sync it anyway)", item.LocalPath, item.Reason))
+		rec.Result, rec.Error = ResultSkipped, item.Reason
+	case ActionError:
+		emitLog("ERROR", item.Reason)
+		rec.Result, rec.Error = ResultError, item.Reason
+	}
+	rec.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		rec.Result, rec.Error = ResultError, err.Error()
+	}
+	return rec
+}
+
+// applyLocalMode 将本地路径的权限位设置到远程路径
+func applyLocalMode(client *sftp.Client, item PlanItem, emitLog func(level, message string)) error {
+	info, err := os.Stat(item.LocalPath)
+	if err != nil {
+		emitLog("ERROR", fmt.Sprintf("Failed to get local file info for %s: %v", item.LocalPath, err))
+		return err
+	}
+	mode := info.Mode().Perm()
+	if err := client.Chmod(item.RemotePath, mode); err != nil {
+		emitLog("ERROR", fmt.Sprintf("Failed to set mode %#o on %s: %v", mode, item.RemotePath, err))
+		return err
+	}
+	if item.Action == ActionChmod {
+		emitLog("SUCCESS", fmt.Sprintf("Updated mode %#o: %s", mode, item.RemotePath))
+	}
+	return nil
+}
+
+// exceedsMaxFileSize 检查文件是否超过同步对设置的大小上限
+func exceedsMaxFileSize(pair types.SyncPair, size int64) bool {
+	return pair.MaxFileSize > 0 && size > pair.MaxFileSize
+}
+
+// formatSize 以易读的方式格式化字节数，例如 "1.5 GiB"
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
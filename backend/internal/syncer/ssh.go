@@ -2,40 +2,66 @@ package syncer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
-	"log"
 	"net"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/logging"
+	"devtools/backend/internal/sshmanager"
 	"devtools/backend/internal/types"
 )
 
+var logger = logging.For("syncer")
+
+// connectionPolicy 返回连接 host 的 SFTP 连接使用的超时与重试策略。
+// 应用启动时通过 SetConnectionPolicySource 注入，使其与 SSH Gate 的全局策略及 ssh_config 保持一致。
+var connectionPolicy = func(string) sshmanager.ConnectionPolicy { return sshmanager.DefaultConnectionPolicy() }
+
+// SetConnectionPolicySource 设置 SFTP 连接策略的来源，应在任何同步开始之前调用
+func SetConnectionPolicySource(source func(host string) sshmanager.ConnectionPolicy) {
+	if source != nil {
+		connectionPolicy = source
+	}
+}
+
+// dialPolicy 返回连接 cfg 使用的策略，同步配置中单独设置的超时与地址族优先
+func dialPolicy(cfg types.SSHConfig) sshmanager.ConnectionPolicy {
+	return connectionPolicy(cfg.Host).WithOverrides(sshmanager.DialOverrides{
+		ConnectTimeoutSeconds: cfg.ConnectTimeoutSeconds,
+		AddressFamily:         cfg.AddressFamily,
+	})
+}
+
 func getSSHAuthMethod(cfg types.SSHConfig) (ssh.AuthMethod, error) {
 	if cfg.AuthMethod == "password" {
 		return ssh.Password(cfg.Password), nil
 	}
 	key, err := os.ReadFile(cfg.KeyPath)
 	if err != nil {
-		return nil, fmt.Errorf("无法读取私钥文件: %w", err)
+		return nil, i18n.Errorf("sync.read_key_failed", err)
 	}
 	signer, err := ssh.ParsePrivateKey(key)
 	if err != nil {
-		return nil, fmt.Errorf("无法解析私钥: %w", err)
+		return nil, i18n.Errorf("sync.parse_key_failed", err)
 	}
 	return ssh.PublicKeys(signer), nil
 }
 
-func NewSFTPClient(cfg types.SSHConfig) (*sftp.Client, error) {
+// dialSSH 按同步配置建立 SSH 连接
+func dialSSH(cfg types.SSHConfig) (*ssh.Client, error) {
 	auth, err := getSSHAuthMethod(cfg)
 	if err != nil {
 		return nil, err
@@ -45,19 +71,26 @@ func NewSFTPClient(cfg types.SSHConfig) (*sftp.Client, error) {
 		User:            cfg.User,
 		Auth:            []ssh.AuthMethod{auth},
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // 生产环境建议替换
-		Timeout:         10 * time.Second,
 	}
 
-	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
-	conn, err := ssh.Dial("tcp", addr, sshConfig)
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	conn, err := sshmanager.DialWithPolicy(addr, sshConfig, dialPolicy(cfg))
 	if err != nil {
-		return nil, fmt.Errorf("SSH拨号失败: %w", err)
+		return nil, i18n.Errorf("sync.dial_failed", err)
+	}
+	return conn, nil
+}
+
+func NewSFTPClient(cfg types.SSHConfig) (*sftp.Client, error) {
+	conn, err := dialSSH(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	client, err := sftp.NewClient(conn)
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("SFTP客户端创建失败: %w", err)
+		return nil, i18n.Errorf("sync.sftp_failed", err)
 	}
 
 	return client, nil
@@ -73,16 +106,15 @@ func TestSSHConnection(cfg types.SSHConfig) (string, error) {
 		User:            cfg.User,
 		Auth:            []ssh.AuthMethod{auth},
 		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error { return nil }, // 允许任何host key
-		Timeout:         5 * time.Second,
 	}
 
-	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
-	client, err := ssh.Dial("tcp", addr, sshConfig)
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	client, err := sshmanager.DialWithPolicy(addr, sshConfig, dialPolicy(cfg))
 	if err != nil {
-		return "", fmt.Errorf("连接失败: %w", err)
+		return "", i18n.Errorf("sync.connect_failed", err)
 	}
 	defer client.Close()
-	return "连接成功!", nil
+	return i18n.T("sync.connect_ok"), nil
 }
 
 // defaultHTMLTemplate 包含了用于展示剪贴板内容的默认HTML模板。
@@ -152,17 +184,6 @@ func UpdateRemoteFile(config types.SSHConfig, remotePath string, content string,
 	}
 	defer client.Close()
 
-	remoteDir := path.Dir(remotePath)
-	if err := client.MkdirAll(remoteDir); err != nil {
-		return fmt.Errorf("创建远程目录失败: %w", err)
-	}
-
-	f, err := client.Create(remotePath)
-	if err != nil {
-		return fmt.Errorf("创建远程文件失败: %w", err)
-	}
-	defer f.Close()
-
 	var contentToWrite []byte
 	if asHTML {
 		// 优先使用用户在配置中定义的模板
@@ -183,47 +204,231 @@ func UpdateRemoteFile(config types.SSHConfig, remotePath string, content string,
 		contentToWrite = []byte(content)
 	}
 
-	if _, err := f.Write(contentToWrite); err != nil {
-		return fmt.Errorf("写入远程文件失败: %w", err)
+	if err := writeRemoteAtomic(client, remotePath, func(w io.Writer) error {
+		_, err := w.Write(contentToWrite)
+		return err
+	}, nil); err != nil {
+		return err
+	}
+
+	if keep := min(config.Clipboard.KeepHistory, MaxClipboardHistory); keep > 0 {
+		// 历史副本只是为了方便找回，保存失败不影响本次推送
+		if err := saveClipboardHistory(client, remotePath, contentToWrite, keep); err != nil {
+			logger.Printf("Warning: failed to keep clipboard push history for %s: %v", remotePath, err)
+		}
 	}
 	return nil
 }
 
+// errSymlinkSkipped 表示本地路径是符号链接，且按同步对的配置被跳过
+var errSymlinkSkipped = errors.New("symlink skipped")
+
+// errFileTooLarge 表示文件超过了同步对设置的 MaxFileSize
+var errFileTooLarge = errors.New("file too large")
+
 // syncFile handles uploading a single file.
-func syncFile(client *sftp.Client, localPath, remotePath string) error {
+// 符号链接按 pair.SymlinkMode 处理；根据 pair 的设置同步权限位与修改时间。
+func syncFile(client *sftp.Client, localPath, remotePath string, pair types.SyncPair) error {
+	info, err := os.Lstat(localPath)
+	if err != nil {
+		return fmt.Errorf("无法读取本地文件信息: %w", err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch pair.EffectiveSymlinkMode() {
+		case types.SymlinkRecreate:
+			return syncSymlink(client, localPath, remotePath)
+		case types.SymlinkCopy:
+			if info, err = os.Stat(localPath); err != nil {
+				return fmt.Errorf("无法解析符号链接: %w", err)
+			}
+			if info.IsDir() {
+				// 跟随指向目录的链接可能形成循环，copy 模式只处理指向文件的链接
+				return fmt.Errorf("link points to a directory: %w", errSymlinkSkipped)
+			}
+		default:
+			return errSymlinkSkipped
+		}
+	}
+
+	if exceedsMaxFileSize(pair, info.Size()) {
+		return fmt.Errorf("%w (%s > %s)", errFileTooLarge, formatSize(info.Size()), formatSize(pair.MaxFileSize))
+	}
+
 	srcFile, err := os.Open(localPath)
 	if err != nil {
 		return fmt.Errorf("无法打开本地文件: %w", err)
 	}
 	defer srcFile.Close()
 
+	var preserved string
+	err = writeRemoteAtomic(client, remotePath, func(dst io.Writer) error {
+		_, err := io.Copy(dst, srcFile)
+		return err
+	}, func(tmpPath string) {
+		// 在改名之前设置属性，使文件以最终的权限和修改时间出现
+		preserved = preserveAttributes(client, tmpPath, info, pair)
+	})
+	if err != nil {
+		return err
+	}
+
+	if preserved != "" {
+		logger.Printf("SYNCED: %s -> %s (preserved %s)", localPath, remotePath, preserved)
+	} else {
+		logger.Printf("SYNCED: %s -> %s", localPath, remotePath)
+	}
+	return nil
+}
+
+// remoteTempSuffix 是原子写入时临时文件的后缀
+const remoteTempSuffix = ".devtools-tmp"
+
+// writeRemoteAtomic 先把内容写入 "<name>.devtools-tmp"，再改名覆盖 remotePath，
+// 避免服务器上的读取方（例如监视配置文件的守护进程）看到写了一半的文件。
+// beforeRename 在临时文件写完、改名之前调用，可用于设置权限与修改时间。
+//
+// 优先使用 posix-rename@openssh.com 扩展原子地覆盖目标；服务器不支持时退回为先删除再改名，
+// 此时会有一个很短的窗口目标文件不存在，但仍不会出现不完整的内容。
+func writeRemoteAtomic(client *sftp.Client, remotePath string, write func(io.Writer) error, beforeRename func(tmpPath string)) error {
 	// 确保远程目录存在
 	remoteDir := path.Dir(remotePath)
 	if err := client.MkdirAll(remoteDir); err != nil {
 		return fmt.Errorf("创建远程目录失败: %w", err)
 	}
 
-	dstFile, err := client.Create(remotePath)
+	tmpPath := remotePath + remoteTempSuffix
+	tmpFile, err := client.Create(tmpPath)
 	if err != nil {
-		return fmt.Errorf("创建远程文件失败: %w", err)
+		return fmt.Errorf("创建远程临时文件失败: %w", err)
+	}
+	if err := write(tmpFile); err != nil {
+		tmpFile.Close()
+		_ = client.Remove(tmpPath)
+		return fmt.Errorf("复制文件内容失败: %w", err)
+	}
+	// 先关闭再设置 mtime，否则部分服务器会在关闭时刷新修改时间
+	if err := tmpFile.Close(); err != nil {
+		_ = client.Remove(tmpPath)
+		return fmt.Errorf("写入远程临时文件失败: %w", err)
+	}
+
+	// 新建的临时文件使用服务器默认权限，这里沿用目标文件原有的权限（例如可执行位）
+	if existing, err := client.Stat(remotePath); err == nil {
+		if err := client.Chmod(tmpPath, existing.Mode().Perm()); err != nil {
+			logger.Printf("Warning: failed to copy mode of %s to temp file: %v", remotePath, err)
+		}
+	}
+	if beforeRename != nil {
+		beforeRename(tmpPath)
+	}
+
+	if err := renameRemote(client, tmpPath, remotePath); err != nil {
+		_ = client.Remove(tmpPath)
+		return fmt.Errorf("替换远程文件失败: %w", err)
 	}
-	defer dstFile.Close()
+	return nil
+}
+
+// renameRemote 将 oldPath 改名为 newPath，newPath 已存在时覆盖它
+func renameRemote(client *sftp.Client, oldPath, newPath string) error {
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		err := client.PosixRename(oldPath, newPath)
+		if err == nil {
+			return nil
+		}
+		logger.Printf("Warning: posix-rename of %s failed, falling back to remove+rename: %v", newPath, err)
+	}
+	// 标准 SFTP 的 rename 在目标已存在时会失败
+	if err := client.Remove(newPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return client.Rename(oldPath, newPath)
+}
+
+// preserveAttributes 按同步对的配置设置远程文件的权限位和修改时间，返回已保留属性的描述。
+// 失败只记录警告：部分 SFTP 服务器（例如 Windows 上的）不支持这些操作。
+func preserveAttributes(client *sftp.Client, remotePath string, info os.FileInfo, pair types.SyncPair) string {
+	var preserved []string
+	if pair.PreservePermissions {
+		mode := info.Mode().Perm()
+		if err := client.Chmod(remotePath, mode); err != nil {
+			logger.Printf("Warning: failed to set mode %#o on %s: %v", mode, remotePath, err)
+		} else {
+			preserved = append(preserved, fmt.Sprintf("mode %#o", mode))
+		}
+	}
+	if pair.PreserveMtime {
+		mtime := info.ModTime()
+		if err := client.Chtimes(remotePath, mtime, mtime); err != nil {
+			logger.Printf("Warning: failed to set mtime on %s: %v", remotePath, err)
+		} else {
+			preserved = append(preserved, "mtime "+mtime.Format(time.RFC3339))
+		}
+	}
+	return strings.Join(preserved, ", ")
+}
 
-	_, err = io.Copy(dstFile, srcFile)
+// syncSymlink 在远程创建指向相同目标的符号链接，已存在且目标相同时不做任何操作
+func syncSymlink(client *sftp.Client, localPath, remotePath string) error {
+	target, err := os.Readlink(localPath)
 	if err != nil {
-		return fmt.Errorf("复制文件内容失败: %w", err)
+		return fmt.Errorf("无法读取符号链接: %w", err)
+	}
+	target = filepath.ToSlash(target)
+	if path.IsAbs(target) || filepath.IsAbs(target) {
+		logger.Printf("Warning: symlink %s has an absolute target %s, which may not exist on the remote host", localPath, target)
 	}
 
-	log.Printf("SYNCED: %s -> %s", localPath, remotePath)
+	if existing, err := client.ReadLink(remotePath); err == nil && existing == target {
+		return nil
+	}
+	if err := client.MkdirAll(path.Dir(remotePath)); err != nil {
+		return fmt.Errorf("创建远程目录失败: %w", err)
+	}
+	// 远程已有同名文件或旧链接时先删除，Symlink 不会覆盖
+	if _, err := client.Lstat(remotePath); err == nil {
+		if err := client.Remove(remotePath); err != nil {
+			return fmt.Errorf("删除远程旧文件失败: %w", err)
+		}
+	}
+	if err := client.Symlink(target, remotePath); err != nil {
+		return fmt.Errorf("创建远程符号链接失败: %w", err)
+	}
+	logger.Printf("SYMLINKED: %s -> %s (target %s)", localPath, remotePath, target)
 	return nil
 }
 
+// syncAndReport 同步单个文件并通过 emitLog 报告结果，返回该文件的传输明细
+func syncAndReport(client *sftp.Client, localPath, remotePath string, pair types.SyncPair, emitLog func(level, message string)) TransferRecord {
+	rec := TransferRecord{LocalPath: localPath, RemotePath: remotePath, Direction: string(ActionUpload), Result: ResultSuccess}
+	if info, err := os.Stat(localPath); err == nil {
+		rec.Size = info.Size()
+	}
+	start := time.Now()
+	err := syncFile(client, localPath, remotePath, pair)
+	rec.DurationMs = time.Since(start).Milliseconds()
+	switch {
+	case errors.Is(err, errSymlinkSkipped):
+		emitLog("INFO", fmt.Sprintf("Skipped symlink: %s (%v)", localPath, err))
+		rec.Result, rec.Error = ResultSkipped, err.Error()
+	case errors.Is(err, errFileTooLarge):
+		emitLog("WARN", fmt.Sprintf("Skipped: %s (%v)", localPath, err))
+		rec.Result, rec.Error = ResultSkipped, err.Error()
+	case err != nil:
+		emitLog("ERROR", fmt.Sprintf("Failed sync: %s -> %s (%v)", localPath, remotePath, err))
+		rec.Result, rec.Error = ResultError, err.Error()
+	default:
+		emitLog("SUCCESS", fmt.Sprintf("Synced: %s -> %s", localPath, remotePath))
+	}
+	return rec
+}
+
 // deleteRemote handles deleting a remote file or directory.
 func deleteRemote(client *sftp.Client, remotePath string) error {
 	// 尝试作为文件删除
 	err := client.Remove(remotePath)
 	if err == nil {
-		log.Printf("DELETED FILE: %s", remotePath)
+		logger.Printf("DELETED FILE: %s", remotePath)
 		return nil
 	}
 
@@ -232,7 +437,7 @@ func deleteRemote(client *sftp.Client, remotePath string) error {
 	// 对于递归删除，需要更复杂的逻辑，这里简化处理
 	err = client.RemoveDirectory(remotePath)
 	if err == nil {
-		log.Printf("DELETED DIR: %s", remotePath)
+		logger.Printf("DELETED DIR: %s", remotePath)
 		return nil
 	}
 
@@ -244,78 +449,87 @@ func deleteRemote(client *sftp.Client, remotePath string) error {
 	return fmt.Errorf("删除远程路径失败: %w", err)
 }
 
-// ReconcileDirectory 递归地比对和同步本地目录与远程目录
-func ReconcileDirectory(client *sftp.Client, pair types.SyncPair, emitLog func(level, message string)) {
-	emitLog("INFO", fmt.Sprintf("Starting full sync for: %s", pair.LocalPath))
-
-	// 使用 filepath.WalkDir 遍历本地目录 (Go 1.16+ 推荐)
-	walkErr := filepath.WalkDir(pair.LocalPath, func(localPath string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err // 传递遍历过程中的错误
-		}
-
-		// 计算相对路径和远程路径
-		relativePath, err := filepath.Rel(pair.LocalPath, localPath)
-		if err != nil {
-			return err
-		}
-		remotePath := path.Join(pair.RemotePath, filepath.ToSlash(relativePath))
+// ReconcileDirectory 递归地比对和同步本地目录与远程目录，返回本次完整同步的记录
+func ReconcileDirectory(client *sftp.Client, pair types.SyncPair, emitLog func(level, message string)) *SyncRun {
+	run := newSyncRun(pair.ID, TriggerFullSync)
+	reconcileInto(client, pair, run, emitLog)
+	run.FinishedAt = time.Now()
+	return run
+}
 
-		if d.IsDir() {
-			// 确保远程也创建对应的目录结构，即使是空目录
-			if err := client.MkdirAll(remotePath); err != nil {
-				emitLog("ERROR", fmt.Sprintf("Failed to create remote dir %s: %v", remotePath, err))
-				// Don't return the error, just log it and continue walking.
-			}
-			return nil
-		}
+// reconcileInto 执行完整同步，并把每一项的传输明细加入 run
+func reconcileInto(client *sftp.Client, pair types.SyncPair, run *SyncRun, emitLog func(level, message string)) {
+	emitLog("INFO", fmt.Sprintf("Starting full sync for: %s", pair.LocalPath))
 
-		// --- 以下是文件比对逻辑 ---
-		localInfo, err := d.Info()
-		if err != nil {
-			emitLog("ERROR", fmt.Sprintf("Failed to get local file info for %s: %v", localPath, err))
-			return nil // 跳过这个文件，继续下一个
-		}
+	plan, walkErr := PlanDirectory(client, pair)
+	for _, item := range plan.Items {
+		run.add(applyPlanItem(client, pair, item, emitLog))
+	}
 
-		// 检查远程文件状态
-		remoteInfo, err := client.Stat(remotePath)
+	if walkErr != nil {
+		emitLog("ERROR", fmt.Sprintf("Error during full sync for %s: %v", pair.LocalPath, walkErr))
+		run.Error = walkErr.Error()
+	} else {
+		emitLog("SUCCESS", fmt.Sprintf("Full sync completed for: %s", pair.LocalPath))
+	}
+}
 
-		// 检查点1: 远程文件不存在
-		if os.IsNotExist(err) {
-			// 修改日志格式，下同
-			emitLog("INFO", fmt.Sprintf("Remote missing, syncing: %s -> %s", localPath, remotePath))
-			if syncErr := syncFile(client, localPath, remotePath); syncErr != nil {
-				emitLog("ERROR", fmt.Sprintf("Failed sync: %s -> %s (%v)", localPath, remotePath, syncErr))
+// RetryTransfers 重新同步 failed 中的每个路径（通常是上一次同步失败的文件），返回重试的记录。
+// 每个路径会重新比对：已经一致的视为成功，本地已不存在的跳过，目录会完整同步一次。
+// 与完整同步一样，前后会运行同步对配置的钩子。
+func RetryTransfers(client *sftp.Client, cfg types.SSHConfig, pair types.SyncPair, failed []TransferRecord, emitLog func(level, message string)) *SyncRun {
+	run := newSyncRun(pair.ID, TriggerRetry)
+	if !runPreSyncHook(pair, run, emitLog) {
+		run.FinishedAt = time.Now()
+		return run
+	}
+	names := newNameChecker(client, pair)
+	for _, prev := range failed {
+		rec := TransferRecord{LocalPath: prev.LocalPath, RemotePath: prev.RemotePath, Direction: prev.Direction, Result: ResultSuccess}
+
+		if prev.Direction == "delete" {
+			start := time.Now()
+			if action, err := removeRemote(client, pair, prev.RemotePath); err != nil {
+				emitLog("ERROR", fmt.Sprintf("Failed to delete remote %s: %v", prev.RemotePath, err))
+				rec.Result, rec.Error = ResultError, err.Error()
 			} else {
-				emitLog("SUCCESS", fmt.Sprintf("Synced: %s -> %s", localPath, remotePath))
+				emitLog("SUCCESS", fmt.Sprintf("%s: %s -> %s", action, prev.LocalPath, prev.RemotePath))
 			}
-			return nil
+			rec.DurationMs = time.Since(start).Milliseconds()
+			run.add(rec)
+			continue
 		}
 
-		// 其他获取远程文件信息的错误
-		if err != nil {
-			emitLog("ERROR", fmt.Sprintf("Failed to get remote file info for %s: %v", remotePath, err))
-			return nil
+		info, err := os.Lstat(prev.LocalPath)
+		switch {
+		case os.IsNotExist(err):
+			emitLog("INFO", fmt.Sprintf("Skipped: %s (local file no longer exists)", prev.LocalPath))
+			rec.Result, rec.Error = ResultSkipped, "Local file no longer exists"
+			run.add(rec)
+			continue
+		case err != nil:
+			emitLog("ERROR", fmt.Sprintf("Cannot get file info for %s: %v", prev.LocalPath, err))
+			rec.Result, rec.Error = ResultError, err.Error()
+			run.add(rec)
+			continue
+		case info.IsDir():
+			subPair := pair
+			subPair.LocalPath = prev.LocalPath
+			subPair.RemotePath = prev.RemotePath
+			reconcileInto(client, subPair, run, emitLog)
+			continue
 		}
 
-		// 检查点2: 远程文件存在，但大小不一致
-		if localInfo.Size() != remoteInfo.Size() {
-			emitLog("INFO", fmt.Sprintf("Size differs, syncing: %s -> %s", localPath, remotePath))
-			if syncErr := syncFile(client, localPath, remotePath); syncErr != nil {
-				emitLog("ERROR", fmt.Sprintf("Failed sync: %s -> %s (%v)", localPath, remotePath, syncErr))
-			} else {
-				emitLog("SUCCESS", fmt.Sprintf("Synced: %s -> %s", localPath, remotePath))
-			}
-			return nil
+		if item, blocked := collisionBlocked(names, pair, prev.LocalPath, prev.RemotePath); blocked {
+			run.add(applyPlanItem(client, pair, item, emitLog))
+		} else if item, ok := planEntry(client, pair, prev.LocalPath, prev.RemotePath, fs.FileInfoToDirEntry(info)); ok {
+			run.add(applyPlanItem(client, pair, item, emitLog))
+		} else {
+			emitLog("SUCCESS", fmt.Sprintf("Already in sync: %s", prev.LocalPath))
+			run.add(rec)
 		}
-
-		// 如果远程文件存在且大小一致，则认为它是同步的
-		return nil
-	})
-
-	if walkErr != nil {
-		emitLog("ERROR", fmt.Sprintf("Error during full sync for %s: %v", pair.LocalPath, walkErr))
-	} else {
-		emitLog("SUCCESS", fmt.Sprintf("Full sync completed for: %s", pair.LocalPath))
 	}
+	runPostSyncHook(cfg, pair, run, emitLog)
+	run.FinishedAt = time.Now()
+	return run
 }
@@ -0,0 +1,289 @@
+package syncer
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"devtools/backend/internal/types"
+)
+
+// TrashDirName 是同步对远程目录下的回收站目录。SyncDeletes 开启时，本地删除的文件在远程被移到
+// .devtools-trash/<时间戳>/<相对路径> 下，超过保留时长后才真正删除，避免本地误删直接传到服务器。
+const TrashDirName = ".devtools-trash"
+
+// DeletedItem 是远程回收站中的一个文件或目录
+type DeletedItem struct {
+	Path      string `json:"path"`      // 被删除前相对于同步对远程目录的路径
+	TrashPath string `json:"trashPath"` // 在回收站中的远程路径，用于 RestoreDeleted
+	DeletedAt string `json:"deletedAt"` // RFC3339
+	IsDir     bool   `json:"isDir"`
+	Size      int64  `json:"size"`
+}
+
+// trashRoot 返回同步对的远程回收站目录
+func trashRoot(pair types.SyncPair) string {
+	return path.Join(pair.RemotePath, TrashDirName)
+}
+
+// removeRemote 按同步对的设置删除远程路径：移到回收站，或在 DeletePermanently 时直接删除。
+// 返回值描述执行的操作，用于日志。
+func removeRemote(client *sftp.Client, pair types.SyncPair, remotePath string) (string, error) {
+	if pair.DeletePermanently {
+		return "Deleted", deleteRemote(client, remotePath)
+	}
+	if err := moveToTrash(client, pair, remotePath, time.Now()); err != nil {
+		return "", err
+	}
+	purgeTrash(client, pair, time.Now())
+	return "Moved to trash", nil
+}
+
+// moveToTrash 把 remotePath 改名到回收站中本次删除的时间戳目录下。
+// 相对路径被转义为一个文件名，使回收站中的每一项都对应一次删除，目录也只需一次改名。
+func moveToTrash(client *sftp.Client, pair types.SyncPair, remotePath string, now time.Time) error {
+	if _, err := client.Lstat(remotePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取远程路径失败: %w", err)
+	}
+
+	rel := strings.TrimPrefix(remotePath, strings.TrimSuffix(pair.RemotePath, "/")+"/")
+	if rel == remotePath {
+		rel = path.Base(remotePath)
+	}
+	name := url.PathEscape(rel)
+
+	// 同一秒内删除同一路径时使用带序号的时间戳目录，与剪贴板历史的命名方式相同
+	stamp := now.Format(clipboardHistoryLayout)
+	target := path.Join(trashRoot(pair), stamp, name)
+	for seq := 1; ; seq++ {
+		if _, err := client.Lstat(target); err != nil {
+			break
+		}
+		target = path.Join(trashRoot(pair), fmt.Sprintf("%s-%d", stamp, seq), name)
+	}
+	if err := client.MkdirAll(path.Dir(target)); err != nil {
+		return fmt.Errorf("创建远程回收站目录失败: %w", err)
+	}
+	if err := client.Rename(remotePath, target); err != nil {
+		return fmt.Errorf("移动到远程回收站失败: %w", err)
+	}
+	logger.Printf("TRASHED: %s -> %s", remotePath, target)
+	return nil
+}
+
+// purgeTrash 删除回收站中超过保留时长的时间戳目录，失败只记录警告
+func purgeTrash(client *sftp.Client, pair types.SyncPair, now time.Time) {
+	entries, err := client.ReadDir(trashRoot(pair))
+	if err != nil {
+		return
+	}
+	cutoff := now.Add(-pair.EffectiveTrashRetention())
+	for _, entry := range entries {
+		deletedAt, ok := parseTrashStamp(entry.Name())
+		if !entry.IsDir() || !ok || deletedAt.After(cutoff) {
+			continue
+		}
+		dir := path.Join(trashRoot(pair), entry.Name())
+		if err := client.RemoveAll(dir); err != nil {
+			logger.Printf("Warning: failed to purge remote trash %s: %v", dir, err)
+		} else {
+			logger.Printf("PURGED: %s", dir)
+		}
+	}
+}
+
+// parseTrashStamp 解析回收站时间戳目录的名称
+func parseTrashStamp(name string) (time.Time, bool) {
+	if !clipboardHistoryPattern.MatchString(name) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(clipboardHistoryLayout, name[:len(clipboardHistoryLayout)], time.Local)
+	return t, err == nil
+}
+
+// ListDeleted 返回同步对远程回收站中的文件，最近删除的在前。回收站不存在时返回空列表。
+func ListDeleted(cfg types.SSHConfig, pair types.SyncPair) ([]DeletedItem, error) {
+	client, err := NewSFTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	items := []DeletedItem{}
+	stamps, err := client.ReadDir(trashRoot(pair))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return items, nil
+		}
+		return nil, fmt.Errorf("读取远程回收站失败: %w", err)
+	}
+	for _, stamp := range stamps {
+		deletedAt, ok := parseTrashStamp(stamp.Name())
+		if !stamp.IsDir() || !ok {
+			continue
+		}
+		dir := path.Join(trashRoot(pair), stamp.Name())
+		entries, err := client.ReadDir(dir)
+		if err != nil {
+			logger.Printf("Warning: failed to read remote trash %s: %v", dir, err)
+			continue
+		}
+		for _, entry := range entries {
+			rel, err := url.PathUnescape(entry.Name())
+			if err != nil {
+				continue
+			}
+			items = append(items, DeletedItem{
+				Path:      rel,
+				TrashPath: path.Join(dir, entry.Name()),
+				DeletedAt: deletedAt.Format(time.RFC3339),
+				IsDir:     entry.IsDir(),
+				Size:      entry.Size(),
+			})
+		}
+	}
+	// 时间戳目录按字典序即为时间顺序
+	sort.SliceStable(items, func(i, j int) bool { return items[i].TrashPath > items[j].TrashPath })
+	return items, nil
+}
+
+// RestoreDeleted 把回收站中的 trashPath 移回原来的远程路径，并在本地不存在该路径时下载回本地。
+// 原来的远程路径已存在时返回错误，不会覆盖。
+func RestoreDeleted(cfg types.SSHConfig, pair types.SyncPair, trashPath string) (*DeletedItem, error) {
+	// 先校验路径，不合法时不需要建立连接
+	if _, _, err := parseTrashEntry(pair, trashPath); err != nil {
+		return nil, err
+	}
+	client, err := NewSFTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return restoreDeleted(client, pair, trashPath)
+}
+
+// parseTrashEntry 校验 trashPath 是同步对回收站中某个时间戳目录下的一项，返回时间戳目录与该项原来的相对路径。
+// 转义后的相对路径不能指向同步对的远程目录之外。
+func parseTrashEntry(pair types.SyncPair, trashPath string) (stampDir, rel string, err error) {
+	stampDir, name := path.Split(path.Clean(trashPath))
+	stampDir = path.Clean(stampDir)
+	if path.Dir(stampDir) != trashRoot(pair) {
+		return "", "", fmt.Errorf("%s is not in the trash of %s", trashPath, pair.RemotePath)
+	}
+	if _, ok := parseTrashStamp(path.Base(stampDir)); !ok {
+		return "", "", fmt.Errorf("%s is not in the trash of %s", trashPath, pair.RemotePath)
+	}
+	rel, err = url.PathUnescape(name)
+	rel = path.Clean(rel)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+		return "", "", fmt.Errorf("invalid trash entry %s", trashPath)
+	}
+	return stampDir, rel, nil
+}
+
+// restoreDeleted 是 RestoreDeleted 的实现，使用已建立的 SFTP 连接
+func restoreDeleted(client *sftp.Client, pair types.SyncPair, trashPath string) (*DeletedItem, error) {
+	stampDir, rel, err := parseTrashEntry(pair, trashPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := client.Lstat(trashPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取回收站文件失败: %w", err)
+	}
+	remotePath := path.Join(pair.RemotePath, rel)
+	if _, err := client.Lstat(remotePath); err == nil {
+		return nil, fmt.Errorf("remote path %s already exists", remotePath)
+	}
+	if err := client.MkdirAll(path.Dir(remotePath)); err != nil {
+		return nil, fmt.Errorf("创建远程目录失败: %w", err)
+	}
+	if err := client.Rename(trashPath, remotePath); err != nil {
+		return nil, fmt.Errorf("从远程回收站恢复失败: %w", err)
+	}
+	logger.Printf("RESTORED: %s -> %s", trashPath, remotePath)
+	// 时间戳目录空了就删除，失败无影响
+	_ = client.RemoveDirectory(stampDir)
+
+	localPath := filepath.Join(pair.LocalPath, filepath.FromSlash(rel))
+	if _, err := os.Lstat(localPath); os.IsNotExist(err) {
+		if err := downloadRemote(client, remotePath, localPath); err != nil {
+			return nil, fmt.Errorf("restored %s on the remote, but downloading it failed: %w", remotePath, err)
+		}
+	}
+
+	deletedAt, _ := parseTrashStamp(path.Base(stampDir))
+	return &DeletedItem{
+		Path:      rel,
+		TrashPath: trashPath,
+		DeletedAt: deletedAt.Format(time.RFC3339),
+		IsDir:     info.IsDir(),
+		Size:      info.Size(),
+	}, nil
+}
+
+// downloadRemote 把远程文件或目录（递归）下载到 localPath，保留权限位。符号链接按原样重建。
+func downloadRemote(client *sftp.Client, remotePath, localPath string) error {
+	walker := client.Walk(remotePath)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), remotePath), "/")
+		target := filepath.Join(localPath, filepath.FromSlash(rel))
+		info := walker.Stat()
+
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()|0o700); err != nil {
+				return err
+			}
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := client.ReadLink(walker.Path())
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if err := downloadFile(client, walker.Path(), target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func downloadFile(client *sftp.Client, remotePath, localPath string, perm os.FileMode) error {
+	src, err := client.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
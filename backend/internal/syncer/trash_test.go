@@ -0,0 +1,123 @@
+package syncer
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"devtools/backend/internal/types"
+)
+
+// TestParseTrashStamp 测试回收站时间戳目录名称的解析，同一秒内的目录带有 -N 后缀
+func TestParseTrashStamp(t *testing.T) {
+	want := time.Date(2026, 10, 16, 17, 4, 5, 0, time.Local)
+	for _, name := range []string{"20261016-170405", "20261016-170405-2"} {
+		got, ok := parseTrashStamp(name)
+		if !ok || !got.Equal(want) {
+			t.Errorf("parseTrashStamp(%q) = %v, %v, want %v", name, got, ok, want)
+		}
+	}
+	for _, name := range []string{"", "notes.txt", "20261016", "20261016-170405-", "20261316-170405", "x20261016-170405"} {
+		if _, ok := parseTrashStamp(name); ok {
+			t.Errorf("parseTrashStamp(%q) should fail", name)
+		}
+	}
+}
+
+// TestMoveToTrashAndRestore 测试嵌套路径在回收站中被转义为一个文件名，恢复后回到原来的远程路径并下载到本地
+func TestMoveToTrashAndRestore(t *testing.T) {
+	client := newTestSFTPClient(t)
+	localDir := t.TempDir()
+	pair := types.SyncPair{ID: "trash", LocalPath: localDir, RemotePath: "/srv/app"}
+	writeRemoteFiles(t, client, "/srv/app/docs", "read me.txt")
+
+	now := time.Date(2026, 10, 16, 17, 4, 5, 0, time.Local)
+	if err := moveToTrash(client, pair, "/srv/app/docs/read me.txt", now); err != nil {
+		t.Fatal(err)
+	}
+	trashPath := "/srv/app/.devtools-trash/20261016-170405/docs%2Fread%20me.txt"
+	if _, err := client.Lstat(trashPath); err != nil {
+		t.Fatalf("expected %s in the trash: %v", trashPath, err)
+	}
+
+	// 同一秒内再次删除同一路径时放到带序号的目录
+	writeRemoteFiles(t, client, "/srv/app/docs", "read me.txt")
+	if err := moveToTrash(client, pair, "/srv/app/docs/read me.txt", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Lstat("/srv/app/.devtools-trash/20261016-170405-1/docs%2Fread%20me.txt"); err != nil {
+		t.Fatalf("a second delete in the same second should use a numbered directory: %v", err)
+	}
+
+	item, err := restoreDeleted(client, pair, trashPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Path != "docs/read me.txt" || item.IsDir {
+		t.Errorf("unexpected restored item %+v", item)
+	}
+	if _, err := client.Lstat("/srv/app/docs/read me.txt"); err != nil {
+		t.Errorf("file should be back at its remote path: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(localDir, "docs", "read me.txt")); err != nil {
+		t.Errorf("file should be downloaded to the local path: %v", err)
+	}
+
+	// 原来的远程路径已存在时不覆盖
+	if _, err := restoreDeleted(client, pair, "/srv/app/.devtools-trash/20261016-170405-1/docs%2Fread%20me.txt"); err == nil {
+		t.Error("restoring over an existing remote path should fail")
+	}
+}
+
+// TestRestoreDeletedRejectsInvalidPaths 测试回收站之外的路径与指向同步目录之外的转义路径都被拒绝
+func TestRestoreDeletedRejectsInvalidPaths(t *testing.T) {
+	pair := types.SyncPair{ID: "trash", LocalPath: t.TempDir(), RemotePath: "/srv/app"}
+	for _, trashPath := range []string{
+		"/srv/app/docs/readme.txt",                                 // 不在回收站中
+		"/srv/other/.devtools-trash/20261016-170405/readme.txt",    // 其他同步对的回收站
+		"/srv/app/.devtools-trash/readme.txt",                      // 不在时间戳目录中
+		"/srv/app/.devtools-trash/latest/readme.txt",               // 时间戳目录名称不合法
+		"/srv/app/.devtools-trash/20261016-170405/..%2F..%2Fetc",   // 转义的 ../ 指向同步目录之外
+		"/srv/app/.devtools-trash/20261016-170405/%2E%2E",          // 转义的 ..
+		"/srv/app/.devtools-trash/20261016-170405/%2Fetc%2Fpasswd", // 转义的绝对路径
+		"/srv/app/.devtools-trash/20261016-170405/%zz",             // 无效的转义
+		"/srv/app/.devtools-trash/20261016-170405/../../../etc",    // 未转义的 ..
+	} {
+		if _, _, err := parseTrashEntry(pair, trashPath); err == nil {
+			t.Errorf("parseTrashEntry(%q) should fail", trashPath)
+		}
+	}
+
+	stampDir, rel, err := parseTrashEntry(pair, "/srv/app/.devtools-trash/20261016-170405/docs%2Freadme.txt")
+	if err != nil || stampDir != "/srv/app/.devtools-trash/20261016-170405" || rel != "docs/readme.txt" {
+		t.Errorf("parseTrashEntry = %q, %q, %v", stampDir, rel, err)
+	}
+}
+
+// TestPurgeTrash 测试只删除超过保留时长的时间戳目录，其他内容保持不变
+func TestPurgeTrash(t *testing.T) {
+	client := newTestSFTPClient(t)
+	pair := types.SyncPair{ID: "trash", RemotePath: "/srv/app", TrashRetentionDays: 3}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+
+	stamps := map[string]bool{ // 时间戳目录 -> 是否应被删除
+		now.Add(-4 * 24 * time.Hour).Format(clipboardHistoryLayout):           true,
+		now.Add(-3*24*time.Hour - time.Minute).Format(clipboardHistoryLayout): true,
+		now.Add(-3*24*time.Hour + time.Minute).Format(clipboardHistoryLayout): false,
+		now.Add(-time.Hour).Format(clipboardHistoryLayout):                    false,
+		"keep-me": false, // 不是时间戳目录
+	}
+	for stamp := range stamps {
+		writeRemoteFiles(t, client, path.Join(trashRoot(pair), stamp), "file.txt")
+	}
+
+	purgeTrash(client, pair, now)
+	for stamp, purged := range stamps {
+		_, err := client.Lstat(path.Join(trashRoot(pair), stamp))
+		if exists := err == nil; exists == purged {
+			t.Errorf("%s: exists = %t, want purged = %t", stamp, exists, purged)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package syncer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/utils"
+)
+
+// watchdogInterval 是看门狗检查监控根目录的周期
+const watchdogInterval = 30 * time.Second
+
+// WatchHealth 描述一个被监控的根目录的健康状态
+type WatchHealth struct {
+	Path      string   `json:"path"`
+	ConfigIDs []string `json:"configIds"`
+	Healthy   bool     `json:"healthy"`
+	Reason    string   `json:"reason,omitempty"` // 不健康的原因，或最近一次恢复的原因
+	Since     string   `json:"since"`            // 进入当前状态的时间（RFC3339）
+}
+
+// rootState 是看门狗为每个根目录记录的状态
+type rootState struct {
+	info     os.FileInfo // 上次检查时根目录的信息，用于发现目录被删除后重建
+	degraded bool
+	reason   string
+	since    time.Time
+}
+
+// SetRecoveredHandler 设置监控恢复后的回调，通常用于执行一次完整同步以补上失效期间遗漏的变化
+func (s *WatcherService) SetRecoveredHandler(fn func(pairs []types.SyncPair, cfg types.SSHConfig)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRecovered = fn
+}
+
+// SetErrorHandler 设置文件变化触发的同步出错时的回调，例如用于发送桌面通知
+func (s *WatcherService) SetErrorHandler(fn func(message string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onError = fn
+}
+
+// reportError 把同步错误转交给错误回调
+func (s *WatcherService) reportError(message string) {
+	s.mu.RLock()
+	onError := s.onError
+	s.mu.RUnlock()
+	if onError != nil {
+		onError(message)
+	}
+}
+
+// Health 返回所有被监控根目录的健康状态，按路径排序
+func (s *WatcherService) Health() []WatchHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]WatchHealth, 0, len(s.watchedItems))
+	for path := range s.watchedItems {
+		result = append(result, s.healthFor_nolock(path))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+// healthFor_nolock 生成 path 的健康状态，调用方需持有 s.mu
+func (s *WatcherService) healthFor_nolock(path string) WatchHealth {
+	h := WatchHealth{Path: path, Healthy: true, ConfigIDs: []string{}}
+	for _, p := range s.watchedItems[path] {
+		if !slices.Contains(h.ConfigIDs, p.ConfigID) {
+			h.ConfigIDs = append(h.ConfigIDs, p.ConfigID)
+		}
+	}
+	if st, ok := s.roots[path]; ok {
+		h.Healthy = !st.degraded
+		h.Reason = st.reason
+		h.Since = st.since.Format(time.RFC3339)
+	}
+	return h
+}
+
+// runWatchdog 定期检查监控根目录。fsnotify 在目录被替换（编辑器原子保存整个目录）、
+// 卷重新挂载等情况下会静默丢失监控，看门狗发现后重新添加监控并通知前端。
+func (s *WatcherService) runWatchdog() {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkRoots()
+		}
+	}
+}
+
+// checkRoots 检查每个根目录：目录不存在时标记为失效，目录被重建或监控丢失时重新添加监控
+func (s *WatcherService) checkRoots() {
+	s.mu.RLock()
+	paths := make([]string, 0, len(s.watchedItems))
+	for path := range s.watchedItems {
+		paths = append(paths, path)
+	}
+	watched := s.watcher.WatchList()
+	s.mu.RUnlock()
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			s.markDegraded(path, "directory is missing")
+		case !info.IsDir():
+			s.markDegraded(path, "path is no longer a directory")
+		default:
+			s.checkRoot(path, info, slices.Contains(watched, path))
+		}
+	}
+}
+
+// checkRoot 处理一个存在的根目录，必要时重新添加监控
+func (s *WatcherService) checkRoot(path string, info os.FileInfo, stillWatched bool) {
+	s.mu.Lock()
+	st, ok := s.roots[path]
+	if !ok {
+		// 根目录已被移除监控
+		s.mu.Unlock()
+		return
+	}
+
+	var reason string
+	switch {
+	case st.degraded:
+		reason = "directory is back"
+	case st.info != nil && !os.SameFile(st.info, info):
+		reason = "directory was recreated"
+	case !stillWatched:
+		reason = "watch was lost"
+	default:
+		s.mu.Unlock()
+		return
+	}
+
+	s.addWatchTree_nolock(path)
+	st.info = info
+	st.degraded = false
+	st.reason = reason
+	st.since = time.Now()
+	pairs := slices.Clone(s.watchedItems[path])
+	cfg := s.watchedConfig[path]
+	health := s.healthFor_nolock(path)
+	onRecovered := s.onRecovered
+	s.mu.Unlock()
+
+	logger.Printf("监控已恢复: %s (%s)", path, reason)
+	utils.EmitEvent(s.ctx, "watcher:recovered", health)
+	if onRecovered != nil {
+		go onRecovered(pairs, cfg)
+	}
+}
+
+// markDegraded 将根目录标记为失效，只在状态变化时通知一次
+func (s *WatcherService) markDegraded(path, reason string) {
+	s.mu.Lock()
+	st, ok := s.roots[path]
+	if !ok || st.degraded {
+		s.mu.Unlock()
+		return
+	}
+	st.degraded = true
+	st.reason = reason
+	st.since = time.Now()
+	health := s.healthFor_nolock(path)
+	s.mu.Unlock()
+
+	logger.Printf("警告: 监控已失效: %s (%s)", path, reason)
+	utils.EmitEvent(s.ctx, "watcher:degraded", health)
+}
+
+// addWatchTree_nolock 将 root 及其所有子目录加入 fsnotify，调用方需持有 s.mu
+func (s *WatcherService) addWatchTree_nolock(root string) {
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if err := s.watcher.Add(path); err != nil {
+				logger.Printf("警告: 无法添加监控路径 %s: %v", path, err)
+			}
+		}
+		return nil
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,9 +11,10 @@ import (
 	"time"
 
 	"devtools/backend/internal/types"
+	"devtools/backend/pkg/utils"
 
 	"github.com/fsnotify/fsnotify"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/pkg/sftp"
 )
 
 // WatcherService 负责所有文件监控的逻辑
@@ -24,18 +24,29 @@ type WatcherService struct {
 	watcher       *fsnotify.Watcher
 	watchedItems  map[string][]types.SyncPair
 	watchedConfig map[string]types.SSHConfig
+	history       *HistoryStore // 记录文件变化触发的同步，可以为 nil
 	mu            sync.RWMutex
+
+	// 看门狗记录的根目录状态，以及监控恢复后的回调
+	roots       map[string]*rootState
+	onRecovered func(pairs []types.SyncPair, cfg types.SSHConfig)
+	// 文件变化触发的同步出错时的回调
+	onError func(message string)
+
+	// 每个同步对等待同步的文件变化，key 为同步对 ID
+	batches map[string]*pairBatch
+	batchMu sync.Mutex
 }
 
-// NewWatcherService 是 WatcherService 的构造函数
-func NewWatcherService(appCtx context.Context) *WatcherService {
+// NewWatcherService 是 WatcherService 的构造函数，history 为 nil 时不记录同步历史
+func NewWatcherService(appCtx context.Context, history *HistoryStore) *WatcherService {
 	// 创建一个可以被取消的子 context，用于优雅地关闭 goroutine
 	ctx, cancel := context.WithCancel(appCtx)
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		// 在真实应用中，这里可能需要更优雅的错误处理，而不是直接 panic
-		log.Fatalf("无法创建文件监控器: %v", err)
+		logger.Fatalf("无法创建文件监控器: %v", err)
 	}
 
 	return &WatcherService{
@@ -44,19 +55,23 @@ func NewWatcherService(appCtx context.Context) *WatcherService {
 		watcher:       watcher,
 		watchedItems:  make(map[string][]types.SyncPair),
 		watchedConfig: make(map[string]types.SSHConfig),
+		history:       history,
+		roots:         make(map[string]*rootState),
+		batches:       make(map[string]*pairBatch),
 	}
 }
 
 // Start 在一个新的 goroutine 中启动监控服务的主循环
 func (s *WatcherService) Start() {
 	defer s.watcher.Close()
-	log.Println("文件监控服务已启动")
+	logger.Println("文件监控服务已启动")
+	go s.runWatchdog()
 
 	for {
 		select {
 		// 如果 context 被取消 (通过调用 s.Stop())，则退出循环
 		case <-s.ctx.Done():
-			log.Println("文件监控服务正在关闭...")
+			logger.Println("文件监控服务正在关闭...")
 			return
 		// 处理文件系统事件
 		case event, ok := <-s.watcher.Events:
@@ -69,7 +84,7 @@ func (s *WatcherService) Start() {
 			if !ok {
 				return
 			}
-			log.Printf("监控器错误: %v", err)
+			logger.Printf("监控器错误: %v", err)
 		}
 	}
 }
@@ -95,7 +110,7 @@ func (s *WatcherService) AddWatch(pair types.SyncPair, cfg types.SSHConfig) erro
 			if err := s.watcher.Add(path); err != nil {
 				// 忽略某些系统产生的错误，例如在某些系统上监控一个不存在的符号链接。
 				// 打印警告而不是返回错误，以允许其他目录的监控继续进行。
-				log.Printf("警告: 无法添加监控路径 %s: %v", path, err)
+				logger.Printf("警告: 无法添加监控路径 %s: %v", path, err)
 			}
 		}
 		return nil
@@ -107,13 +122,18 @@ func (s *WatcherService) AddWatch(pair types.SyncPair, cfg types.SSHConfig) erro
 	// 将新的同步对追加到对应路径的切片中
 	s.watchedItems[pair.LocalPath] = append(s.watchedItems[pair.LocalPath], pair)
 	s.watchedConfig[pair.LocalPath] = cfg // SSH 配置可以覆盖，因为它们对于同一个本地路径总是相同的
+	if _, ok := s.roots[pair.LocalPath]; !ok {
+		info, _ := os.Stat(pair.LocalPath)
+		s.roots[pair.LocalPath] = &rootState{info: info, since: time.Now()}
+	}
 
-	log.Printf("已配置同步对: %s -> %s", pair.LocalPath, pair.RemotePath)
+	logger.Printf("已配置同步对: %s -> %s", pair.LocalPath, pair.RemotePath)
 	return nil
 }
 
 // RemoveWatch 移除一个正在监控的目录
 func (s *WatcherService) RemoveWatch(pairToRemove types.SyncPair) {
+	s.dropBatch(pairToRemove.ID)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -135,15 +155,16 @@ func (s *WatcherService) RemoveWatch(pairToRemove types.SyncPair) {
 	if len(newPairs) == 0 {
 		err := s.watcher.Remove(pairToRemove.LocalPath)
 		if err != nil {
-			log.Printf("从 fsnotify 移除监控失败: %v", err)
+			logger.Printf("从 fsnotify 移除监控失败: %v", err)
 		}
 		delete(s.watchedItems, pairToRemove.LocalPath)
 		delete(s.watchedConfig, pairToRemove.LocalPath)
-		log.Printf("已移除对路径 %s 的所有监控", pairToRemove.LocalPath)
+		delete(s.roots, pairToRemove.LocalPath)
+		logger.Printf("已移除对路径 %s 的所有监控", pairToRemove.LocalPath)
 	} else {
 		// 否则，只是更新列表
 		s.watchedItems[pairToRemove.LocalPath] = newPairs
-		log.Printf("已移除同步对: %s -> %s", pairToRemove.LocalPath, pairToRemove.RemotePath)
+		logger.Printf("已移除同步对: %s -> %s", pairToRemove.LocalPath, pairToRemove.RemotePath)
 	}
 }
 
@@ -188,71 +209,190 @@ func (s *WatcherService) handleEvent(event fsnotify.Event) {
 	config := s.watchedConfig[bestMatchPath]
 	s.mu.RUnlock()
 
-	// 为每一个匹配的同步对都执行一次同步操作
+	// 事件先按同步对合并，短时间内的一批变化只运行一次钩子、建立一次连接
 	for _, pair := range pairsToSync {
-		// 在 goroutine 中执行每个同步任务，避免互相阻塞
-		go func(p types.SyncPair, c types.SSHConfig) {
-			emitLog := func(level, message string) {
-				entry := types.LogEntry{Timestamp: time.Now().Format("15:04:05"), Level: level, Message: message}
-				runtime.EventsEmit(s.ctx, "log_event", entry)
-			}
+		isDelete := event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+		if isDelete && !pair.SyncDeletes {
+			continue
+		}
+		s.enqueueEvent(pair, config, bestMatchPath, event)
+	}
+}
 
-			relativePath, err := filepath.Rel(bestMatchPath, event.Name)
-			if err != nil {
-				emitLog("ERROR", fmt.Sprintf("Cannot calculate relative path: %v", err))
-				return
+// watchBatchDelay 是同步对最后一次文件变化后等待的时间，之后才同步这一批变化。
+// 例如构建输出一次写入很多文件时，只运行一次同步前后的钩子。
+const watchBatchDelay = 300 * time.Millisecond
+
+// pairBatch 是一个同步对等待同步的文件变化；同一同步对的批次依次执行，不会并发
+type pairBatch struct {
+	pair    types.SyncPair
+	config  types.SSHConfig
+	root    string
+	events  map[string]fsnotify.Event // 路径 -> 最后一次事件
+	order   []string                  // 路径第一次出现的顺序
+	timer   *time.Timer
+	running bool
+}
+
+// enqueueEvent 把事件加入同步对的批次，并重新开始等待
+func (s *WatcherService) enqueueEvent(pair types.SyncPair, cfg types.SSHConfig, root string, event fsnotify.Event) {
+	s.batchMu.Lock()
+	defer s.batchMu.Unlock()
+
+	b, ok := s.batches[pair.ID]
+	if !ok {
+		b = &pairBatch{events: make(map[string]fsnotify.Event)}
+		s.batches[pair.ID] = b
+	}
+	b.pair, b.config, b.root = pair, cfg, root
+	if _, seen := b.events[event.Name]; !seen {
+		b.order = append(b.order, event.Name)
+	}
+	b.events[event.Name] = event
+	if b.running {
+		return // 当前批次结束后会安排下一批
+	}
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	pairID := pair.ID
+	b.timer = time.AfterFunc(watchBatchDelay, func() { s.flushBatch(pairID) })
+}
+
+// flushBatch 取出同步对等待中的变化并同步；同步期间到达的变化在结束后作为下一批处理
+func (s *WatcherService) flushBatch(pairID string) {
+	s.batchMu.Lock()
+	b, ok := s.batches[pairID]
+	if !ok || b.running || len(b.order) == 0 || s.ctx.Err() != nil {
+		s.batchMu.Unlock()
+		return
+	}
+	events := make([]fsnotify.Event, 0, len(b.order))
+	for _, path := range b.order {
+		events = append(events, b.events[path])
+	}
+	pair, cfg, root := b.pair, b.config, b.root
+	b.events, b.order, b.timer = make(map[string]fsnotify.Event), nil, nil
+	b.running = true
+	s.batchMu.Unlock()
+
+	s.syncBatch(pair, cfg, root, events)
+
+	s.batchMu.Lock()
+	defer s.batchMu.Unlock()
+	b.running = false
+	if len(b.order) > 0 && s.batches[pairID] == b {
+		b.timer = time.AfterFunc(watchBatchDelay, func() { s.flushBatch(pairID) })
+	}
+}
+
+// dropBatch 丢弃同步对等待中的变化，用于移除监控时
+func (s *WatcherService) dropBatch(pairID string) {
+	s.batchMu.Lock()
+	defer s.batchMu.Unlock()
+	if b, ok := s.batches[pairID]; ok {
+		if b.timer != nil {
+			b.timer.Stop()
+		}
+		delete(s.batches, pairID)
+	}
+}
+
+// syncBatch 同步一批文件变化：运行一次同步前钩子，通过同一个连接处理所有变化，再运行一次同步后钩子
+func (s *WatcherService) syncBatch(p types.SyncPair, c types.SSHConfig, root string, events []fsnotify.Event) {
+	emitLog := func(level, message string) {
+		entry := types.LogEntry{Timestamp: time.Now().Format("15:04:05"), Level: level, Message: message}
+		utils.EmitEvent(s.ctx, "log_event", entry)
+		if level == "ERROR" {
+			s.reportError(message)
+		}
+	}
+
+	run := newSyncRun(p.ID, TriggerWatch)
+	defer s.history.Record(run)
+	if !runPreSyncHook(p, run, emitLog) {
+		return
+	}
+
+	client, err := NewSFTPClient(c)
+	if err != nil {
+		emitLog("ERROR", fmt.Sprintf("Cannot connect to %s for %s: %v", c.Host, p.LocalPath, err))
+		// 记录为失败的文件，连接恢复后可以通过重试补上这些变化
+		run.Error = err.Error()
+		for _, event := range events {
+			relativePath, relErr := filepath.Rel(root, event.Name)
+			if relErr != nil {
+				continue
+			}
+			direction := string(ActionUpload)
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				direction = "delete"
 			}
 			remotePath := filepath.ToSlash(filepath.Join(p.RemotePath, relativePath))
+			run.add(TransferRecord{LocalPath: event.Name, RemotePath: remotePath, Direction: direction, Result: ResultError, Error: err.Error()})
+		}
+		return
+	}
+	defer client.Close()
 
-			client, err := NewSFTPClient(c)
-			if err != nil {
-				emitLog("ERROR", fmt.Sprintf("Cannot connect to %s for %s: %v", c.Host, remotePath, err))
+	names := newNameChecker(client, p)
+	for _, event := range events {
+		s.syncEvent(client, names, p, root, event, run, emitLog)
+	}
+	runPostSyncHook(c, p, run, emitLog)
+}
+
+// syncEvent 把一个文件变化同步到远程，结果记录到 run
+func (s *WatcherService) syncEvent(client *sftp.Client, names *nameChecker, p types.SyncPair, root string, event fsnotify.Event, run *SyncRun, emitLog func(level, message string)) {
+	relativePath, err := filepath.Rel(root, event.Name)
+	if err != nil {
+		emitLog("ERROR", fmt.Sprintf("Cannot calculate relative path: %v", err))
+		return
+	}
+	remotePath := filepath.ToSlash(filepath.Join(p.RemotePath, relativePath))
+	isDelete := event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+
+	// 根据事件类型执行不同操作，并使用新的日志格式
+	if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+		info, err := os.Lstat(event.Name)
+		if err != nil {
+			if os.IsNotExist(err) {
 				return
 			}
-			defer client.Close()
-
-			// 根据事件类型执行不同操作，并使用新的日志格式
-			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
-				info, err := os.Stat(event.Name)
-				if err != nil {
-					if os.IsNotExist(err) {
-						return
-					}
-					emitLog("ERROR", fmt.Sprintf("Cannot get file info for %s: %v", event.Name, err))
-					return
-				}
-				if info.IsDir() {
-					// 关键修复点：当一个新目录被创建时，必须做两件事：
-					// 1. 立即将这个新目录及其所有子目录也加入到 fsnotify 的监控列表中，以便将来的修改能被捕捉到。
-					_ = filepath.WalkDir(event.Name, func(path string, d fs.DirEntry, err error) error {
-						if d.IsDir() {
-							_ = s.watcher.Add(path)
-						}
-						return nil
-					})
-
-					// 2. 立即对这个新目录进行一次完整的递归同步，以处理一次性复制进来的所有内容。
-					subPair := types.SyncPair{
-						LocalPath:  event.Name,
-						RemotePath: remotePath,
-					}
-					ReconcileDirectory(client, subPair, emitLog)
-				} else {
-					if err := syncFile(client, event.Name, remotePath); err != nil {
-						emitLog("ERROR", fmt.Sprintf("Failed to sync: %s -> %s (%v)", event.Name, remotePath, err))
-					} else {
-						emitLog("SUCCESS", fmt.Sprintf("Synced: %s -> %s", event.Name, remotePath))
-					}
-				}
-			} else if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
-				if pair.SyncDeletes {
-					if err := deleteRemote(client, remotePath); err != nil {
-						emitLog("ERROR", fmt.Sprintf("Failed to delete remote %s: %v", remotePath, err))
-					} else {
-						emitLog("SUCCESS", fmt.Sprintf("Deleted: %s -> %s", event.Name, remotePath))
-					}
+			emitLog("ERROR", fmt.Sprintf("Cannot get file info for %s: %v", event.Name, err))
+			return
+		}
+		if info.IsDir() {
+			// 关键修复点：当一个新目录被创建时，必须做两件事：
+			// 1. 立即将这个新目录及其所有子目录也加入到 fsnotify 的监控列表中，以便将来的修改能被捕捉到。
+			_ = filepath.WalkDir(event.Name, func(path string, d fs.DirEntry, err error) error {
+				if d.IsDir() {
+					_ = s.watcher.Add(path)
 				}
-			}
-		}(pair, config)
+				return nil
+			})
+
+			// 2. 立即对这个新目录进行一次完整的递归同步，以处理一次性复制进来的所有内容。
+			// 子目录沿用同步对的权限、修改时间和符号链接设置
+			subPair := p
+			subPair.LocalPath = event.Name
+			subPair.RemotePath = remotePath
+			reconcileInto(client, subPair, run, emitLog)
+		} else if item, blocked := collisionBlocked(names, p, event.Name, remotePath); blocked {
+			run.add(applyPlanItem(client, p, item, emitLog))
+		} else {
+			run.add(syncAndReport(client, event.Name, remotePath, p, emitLog))
+		}
+	} else if isDelete {
+		rec := TransferRecord{LocalPath: event.Name, RemotePath: remotePath, Direction: "delete", Result: ResultSuccess}
+		start := time.Now()
+		if action, err := removeRemote(client, p, remotePath); err != nil {
+			emitLog("ERROR", fmt.Sprintf("Failed to delete remote %s: %v", remotePath, err))
+			rec.Result, rec.Error = ResultError, err.Error()
+		} else {
+			emitLog("SUCCESS", fmt.Sprintf("%s: %s -> %s", action, event.Name, remotePath))
+		}
+		rec.DurationMs = time.Since(start).Milliseconds()
+		run.add(rec)
 	}
 }
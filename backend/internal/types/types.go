@@ -1,6 +1,9 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type LogEntry struct {
 	Timestamp string `json:"timestamp"`
@@ -12,6 +15,9 @@ type LogEntry struct {
 type ClipboardConfig struct {
 	FilePath     string `json:"filePath,omitempty"`
 	HTMLTemplate string `json:"htmlTemplate,omitempty"`
+	// KeepHistory 大于 0 时，每次推送另存一份带时间戳的副本（与 FilePath 同目录），只保留最近的 KeepHistory 份；
+	// 0 表示每次直接覆盖
+	KeepHistory int `json:"keepHistory,omitempty"`
 }
 
 type SSHConfig struct {
@@ -24,14 +30,62 @@ type SSHConfig struct {
 	Password   string          `json:"password,omitempty"`
 	KeyPath    string          `json:"keyPath,omitempty"`
 	Clipboard  ClipboardConfig `json:"clipboard"`
+
+	// 覆盖 ssh_config 与全局连接策略，零值表示不覆盖
+	ConnectTimeoutSeconds int    `json:"connectTimeoutSeconds,omitempty"`
+	AddressFamily         string `json:"addressFamily,omitempty"` // any | inet | inet6
 }
 
+// 符号链接的同步方式
+const (
+	SymlinkSkip     = "skip"     // 跳过符号链接（默认，避免把同步目录之外的文件传到远程）
+	SymlinkCopy     = "copy"     // 跟随链接，上传其指向的文件内容
+	SymlinkRecreate = "recreate" // 在远程创建指向相同目标的符号链接
+)
+
 type SyncPair struct {
 	ID          string `json:"id"`
 	ConfigID    string `json:"configId"`
 	LocalPath   string `json:"localPath"`
 	RemotePath  string `json:"remotePath"`
 	SyncDeletes bool   `json:"syncDeletes"`
+	// SyncDeletes 开启时，远程文件默认移到同步目录下的回收站（.devtools-trash），而不是直接删除
+	DeletePermanently  bool `json:"deletePermanently,omitempty"`  // 直接删除远程文件，不经过回收站
+	TrashRetentionDays int  `json:"trashRetentionDays,omitempty"` // 回收站中的文件保留的天数，0 表示使用默认的 DefaultTrashRetentionDays
+
+	PreservePermissions bool   `json:"preservePermissions,omitempty"` // 同步文件权限位（client.Chmod）
+	PreserveMtime       bool   `json:"preserveMtime,omitempty"`       // 同步修改时间（client.Chtimes）
+	SymlinkMode         string `json:"symlinkMode,omitempty"`         // skip / copy / recreate，空值等同于 skip
+	MaxFileSize         int64  `json:"maxFileSize,omitempty"`         // 超过该字节数的文件会被跳过并警告，0 表示不限制
+	AllowNameCollisions bool   `json:"allowNameCollisions,omitempty"` // 确认后仍同步只有大小写或 Unicode 规范化形式不同的文件名（可能覆盖远程文件）
+
+	PreSyncCommand  string `json:"preSyncCommand,omitempty"`  // 每次同步前在本地（LocalPath 目录下）执行的命令，失败时取消同步
+	PostSyncCommand string `json:"postSyncCommand,omitempty"` // 同步成功后在远程（RemotePath 目录下）执行的命令，例如 "systemctl reload nginx"
+}
+
+// 远程回收站的保留天数
+const (
+	DefaultTrashRetentionDays = 7
+	MaxTrashRetentionDays     = 365
+)
+
+// EffectiveTrashRetention 返回回收站中的文件保留的时长
+func (p SyncPair) EffectiveTrashRetention() time.Duration {
+	days := p.TrashRetentionDays
+	if days <= 0 {
+		days = DefaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// EffectiveSymlinkMode 返回符号链接的同步方式，未设置或无效时为 SymlinkSkip
+func (p SyncPair) EffectiveSymlinkMode() string {
+	switch p.SymlinkMode {
+	case SymlinkCopy, SymlinkRecreate:
+		return p.SymlinkMode
+	default:
+		return SymlinkSkip
+	}
 }
 
 // SSHHost 代表一个从 ~/.ssh/config 文件中解析出的主机配置
@@ -41,7 +95,13 @@ type SSHHost struct {
 	User         string `json:"user"`                   // User, e.g., "root"
 	Port         string `json:"port"`                   // Port, e.g., "22"
 	IdentityFile string `json:"identityFile"`           // IdentityFile, e.g., "~/.ssh/id_rsa"
+	ProxyJump    string `json:"proxyJump,omitempty"`    // 团队主机的 ProxyJump；个人 ssh_config 中的主机从生效配置读取，不填写此字段
+	ProxyCommand string `json:"proxyCommand,omitempty"` // 团队主机的 ProxyCommand（未展开 token），规则同 ProxyJump
 	LastModified string `json:"lastModified,omitempty"` // 使用 string (ISO 8601) 以便 JSON 传输
+	Favorite     bool   `json:"favorite,omitempty"`     // 是否被置顶收藏（保存在应用配置中，不写入 ssh_config）
+	Ephemeral    bool   `json:"ephemeral,omitempty"`    // 仅在本次运行中存在的临时主机，不写入 ssh_config
+	Source       string `json:"source,omitempty"`       // 主机来源，"team" 表示来自团队共享的只读配置，"include" 表示来自 Include 文件，空值为个人 ssh_config
+	Locked       bool   `json:"locked,omitempty"`       // 所在的 Host 块是否只读（带有 @locked 注释或在设置的锁定列表中）
 }
 
 // PasswordRequiredError 表示连接因为需要密码而失败
@@ -57,9 +117,11 @@ func (e *PasswordRequiredError) Error() string {
 
 // HostKeyVerificationRequiredError 表示需要用户确认一个新的主机指纹
 type HostKeyVerificationRequiredError struct {
-	Alias       string `json:"alias"`
-	Fingerprint string `json:"fingerprint"`
-	HostAddress string `json:"hostAddress"`
+	Alias           string `json:"alias"`
+	Fingerprint     string `json:"fingerprint"`
+	HostAddress     string `json:"hostAddress"`
+	KeyType         string `json:"keyType,omitempty"`         // 主机密钥类型，例如 ssh-ed25519
+	ResolvedAddress string `json:"resolvedAddress,omitempty"` // 实际提供密钥的地址，HostName 有多个地址时可能与 HostAddress 不同
 }
 
 func (e *HostKeyVerificationRequiredError) Error() string {
@@ -71,6 +133,7 @@ type ConnectionResult struct {
 	ErrorMessage                string                            `json:"errorMessage,omitempty"`
 	PasswordRequired            *PasswordRequiredError            `json:"passwordRequired,omitempty"`
 	HostKeyVerificationRequired *HostKeyVerificationRequiredError `json:"hostKeyVerificationRequired,omitempty"`
+	ErrorKind                   string                            `json:"errorKind,omitempty"` // 失败时的错误分类，见 sshmanager.ErrorKind
 }
 
 // AuthenticationFailedError 表示尝试连接但因凭据错误而失败
@@ -89,4 +152,12 @@ type TerminalSessionInfo struct {
 	Alias string `json:"alias"`
 	URL   string `json:"url"`
 	Type  string `json:"type" enums:"local,remote"`
+	// HopChain 是远程会话经过的连接路径，例如 "bastion01 → db-prod-3"；直连时为空
+	HopChain string `json:"hopChain,omitempty"`
+	// 远程会话中实际生效的 ssh-agent 转发与 X11 转发
+	AgentForwarding bool `json:"agentForwarding,omitempty"`
+	X11Forwarding   bool `json:"x11Forwarding,omitempty"`
+	// 按 ssh_config 的 SendEnv / SetEnv 发送后被服务器接受与拒绝（未在 AcceptEnv 中允许）的环境变量名
+	SentEnv     []string `json:"sentEnv,omitempty"`
+	RejectedEnv []string `json:"rejectedEnv,omitempty"`
 }
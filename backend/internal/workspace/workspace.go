@@ -0,0 +1,257 @@
+// Package workspace 管理多个相互独立的工作区（例如 "personal"、"work-client-A"）。
+//
+// 每个工作区有自己的 ssh_config 路径、配置目录（tunnels.json、同步配置、主机备注等）
+// 和钥匙串命名空间。工作区列表保存在应用配置目录的 workspaces.json 中。
+// 引入工作区之前的单一配置布局原样作为 "default" 工作区：它的配置目录就是应用配置目录本身，
+// 钥匙串命名空间也保持不变，因此升级后不需要移动任何文件或重新输入密码。
+// 设置、日志、代码片段等应用级配置不属于任何工作区。
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"devtools/backend/internal/logging"
+)
+
+var logger = logging.For("workspace")
+
+// DefaultName 是由原有单一配置布局迁移而来的工作区，不能被删除
+const DefaultName = "default"
+
+// workspacesDir 是非默认工作区的配置目录所在的子目录
+const workspacesDir = "workspaces"
+
+// validName 限制工作区名称，名称会直接用作目录名与钥匙串命名空间
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Workspace 是一个工作区
+type Workspace struct {
+	Name          string `json:"name"`
+	SSHConfigPath string `json:"sshConfigPath,omitempty"` // 为空时使用 ~/.ssh/config
+	CreatedAt     string `json:"createdAt,omitempty"`     // RFC 3339
+}
+
+// Registry 是 workspaces.json 的根对象
+type Registry struct {
+	Active     string      `json:"active"`
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// Manager 负责加载与保存工作区列表，并记录当前激活的工作区
+type Manager struct {
+	root string // 应用配置目录，即默认工作区的配置目录
+	path string
+	reg  Registry
+	mu   sync.RWMutex
+}
+
+// activeDir 是当前激活的工作区的配置目录，供各服务通过 ConfigDir 读取
+var (
+	activeDir string
+	activeMu  sync.RWMutex
+)
+
+// NewManager 创建工作区管理器，root 是应用配置目录
+func NewManager(root string) *Manager {
+	return &Manager{
+		root: root,
+		path: filepath.Join(root, "workspaces.json"),
+		reg:  defaultRegistry(),
+	}
+}
+
+func defaultRegistry() Registry {
+	return Registry{Active: DefaultName, Workspaces: []Workspace{{Name: DefaultName}}}
+}
+
+// Load 加载工作区列表并激活上次使用的工作区。文件不存在时（从单一配置布局升级）只有默认工作区。
+func (m *Manager) Load() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read workspaces file: %w", err)
+		}
+		m.reg = defaultRegistry()
+	} else {
+		reg := Registry{}
+		if err := json.Unmarshal(data, &reg); err != nil {
+			return fmt.Errorf("failed to unmarshal workspaces: %w", err)
+		}
+		if !slices.ContainsFunc(reg.Workspaces, func(w Workspace) bool { return w.Name == DefaultName }) {
+			reg.Workspaces = append([]Workspace{{Name: DefaultName}}, reg.Workspaces...)
+		}
+		if _, ok := find(reg.Workspaces, reg.Active); !ok {
+			logger.Printf("Warning: active workspace '%s' does not exist, falling back to '%s'.", reg.Active, DefaultName)
+			reg.Active = DefaultName
+		}
+		m.reg = reg
+	}
+	setActiveDir(m.dir(m.reg.Active))
+	logger.Printf("Loaded %d workspaces, active: %s", len(m.reg.Workspaces), m.reg.Active)
+	return nil
+}
+
+// save 保存工作区列表，调用方必须持有 m.mu
+func (m *Manager) save() error {
+	data, err := json.MarshalIndent(m.reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspaces: %w", err)
+	}
+	if err := os.MkdirAll(m.root, 0o755); err != nil {
+		return fmt.Errorf("failed to create app config directory: %w", err)
+	}
+	if err := os.WriteFile(m.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write workspaces file: %w", err)
+	}
+	return nil
+}
+
+// List 返回所有工作区，默认工作区在最前
+func (m *Manager) List() []Workspace {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.reg.Workspaces)
+}
+
+// Active 返回当前激活的工作区
+func (m *Manager) Active() Workspace {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ws, _ := find(m.reg.Workspaces, m.reg.Active)
+	return ws
+}
+
+// Get 按名称返回工作区
+func (m *Manager) Get(name string) (Workspace, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return find(m.reg.Workspaces, name)
+}
+
+// Create 新建一个工作区并创建它的配置目录
+func (m *Manager) Create(ws Workspace) error {
+	if !validName.MatchString(ws.Name) {
+		return fmt.Errorf("invalid workspace name '%s': use letters, digits, '.', '_' or '-'", ws.Name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := find(m.reg.Workspaces, ws.Name); ok {
+		return fmt.Errorf("workspace '%s' already exists", ws.Name)
+	}
+	if err := os.MkdirAll(m.dir(ws.Name), 0o755); err != nil {
+		return fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+	ws.CreatedAt = time.Now().Format(time.RFC3339)
+	m.reg.Workspaces = append(m.reg.Workspaces, ws)
+	return m.save()
+}
+
+// Update 修改工作区的 ssh_config 路径。修改激活的工作区后需要重新切换到它才会生效。
+func (m *Manager) Update(ws Workspace) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.reg.Workspaces, func(w Workspace) bool { return w.Name == ws.Name })
+	if i < 0 {
+		return fmt.Errorf("workspace '%s' not found", ws.Name)
+	}
+	m.reg.Workspaces[i].SSHConfigPath = ws.SSHConfigPath
+	return m.save()
+}
+
+// Delete 从列表中删除工作区。配置目录保留在磁盘上，以免误删隧道与同步配置；
+// 默认工作区与激活的工作区不能被删除。
+func (m *Manager) Delete(name string) error {
+	if name == DefaultName {
+		return fmt.Errorf("the default workspace cannot be deleted")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == m.reg.Active {
+		return fmt.Errorf("cannot delete the active workspace '%s', switch to another workspace first", name)
+	}
+	i := slices.IndexFunc(m.reg.Workspaces, func(w Workspace) bool { return w.Name == name })
+	if i < 0 {
+		return fmt.Errorf("workspace '%s' not found", name)
+	}
+	m.reg.Workspaces = slices.Delete(m.reg.Workspaces, i, i+1)
+	return m.save()
+}
+
+// SetActive 激活工作区并保存，之后 ConfigDir 返回它的配置目录。
+// 调用方负责在此之前关闭属于旧工作区的资源，并在之后重新加载各服务。
+func (m *Manager) SetActive(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := find(m.reg.Workspaces, name); !ok {
+		return fmt.Errorf("workspace '%s' not found", name)
+	}
+	m.reg.Active = name
+	setActiveDir(m.dir(name))
+	return m.save()
+}
+
+// Dir 返回工作区的配置目录
+func (m *Manager) Dir(name string) string {
+	return m.dir(name)
+}
+
+func (m *Manager) dir(name string) string {
+	if name == DefaultName {
+		return m.root
+	}
+	return filepath.Join(m.root, workspacesDir, name)
+}
+
+func find(workspaces []Workspace, name string) (Workspace, bool) {
+	i := slices.IndexFunc(workspaces, func(w Workspace) bool { return w.Name == name })
+	if i < 0 {
+		return Workspace{}, false
+	}
+	return workspaces[i], true
+}
+
+// KeyringNamespace 返回工作区在系统钥匙串中使用的命名空间，默认工作区为空（沿用原有的条目）
+func KeyringNamespace(name string) string {
+	if name == DefaultName {
+		return ""
+	}
+	return name
+}
+
+func setActiveDir(dir string) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	activeDir = dir
+}
+
+// ConfigDir 返回当前工作区的配置目录，并确保它存在。
+// 没有加载工作区时（例如命令行工具或测试中）返回应用配置目录。
+func ConfigDir() (string, error) {
+	activeMu.RLock()
+	dir := activeDir
+	activeMu.RUnlock()
+
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user config directory: %w", err)
+		}
+		dir = filepath.Join(configDir, "DevTools")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create app config directory: %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,143 @@
+// Package hotkey 注册系统级（全局）快捷键：应用窗口不在前台甚至被隐藏时也能触发。
+//
+// 快捷键使用与 Electron 类似的 accelerator 字符串描述，例如 "CmdOrCtrl+Shift+Space"、"Alt+F5"。
+// Windows 使用 RegisterHotKey，macOS 使用 Carbon 的 RegisterEventHotKey，其他平台返回 ErrUnsupported。
+package hotkey
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// ErrUnsupported 表示当前平台不支持全局快捷键
+var ErrUnsupported = errors.New("global shortcuts are not supported on this platform")
+
+// Modifier 是快捷键的修饰键组合
+type Modifier uint8
+
+const (
+	ModCtrl Modifier = 1 << iota
+	ModShift
+	ModAlt
+	ModSuper // macOS 上为 Command，Windows 上为 Win 键
+)
+
+// Hotkey 是解析后的快捷键
+type Hotkey struct {
+	Mods Modifier
+	Key  string // 规范化的按键名：A-Z、0-9、F1-F24、Space、Enter、Tab、Escape、Up、Down、Left、Right
+}
+
+// String 返回规范化的 accelerator 字符串
+func (h Hotkey) String() string {
+	var parts []string
+	if h.Mods&ModCtrl != 0 {
+		parts = append(parts, "Ctrl")
+	}
+	if h.Mods&ModAlt != 0 {
+		parts = append(parts, "Alt")
+	}
+	if h.Mods&ModShift != 0 {
+		parts = append(parts, "Shift")
+	}
+	if h.Mods&ModSuper != 0 {
+		parts = append(parts, "Super")
+	}
+	return strings.Join(append(parts, h.Key), "+")
+}
+
+// namedKeys 是除字母、数字和功能键之外支持的按键及其别名
+var namedKeys = map[string]string{
+	"space":  "Space",
+	"enter":  "Enter",
+	"return": "Enter",
+	"tab":    "Tab",
+	"escape": "Escape",
+	"esc":    "Escape",
+	"up":     "Up",
+	"down":   "Down",
+	"left":   "Left",
+	"right":  "Right",
+}
+
+// Parse 解析 accelerator 字符串。至少需要一个修饰键，避免抢占普通按键。
+// "CmdOrCtrl" 在 macOS 上是 Command，其他平台上是 Ctrl。
+func Parse(accelerator string) (Hotkey, error) {
+	var h Hotkey
+	parts := strings.Split(strings.TrimSpace(accelerator), "+")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Hotkey{}, fmt.Errorf("invalid shortcut '%s'", accelerator)
+		}
+		if i < len(parts)-1 {
+			mod, ok := parseModifier(part)
+			if !ok {
+				return Hotkey{}, fmt.Errorf("invalid shortcut '%s': unknown modifier '%s'", accelerator, part)
+			}
+			h.Mods |= mod
+			continue
+		}
+		key, ok := parseKey(part)
+		if !ok {
+			return Hotkey{}, fmt.Errorf("invalid shortcut '%s': unsupported key '%s'", accelerator, part)
+		}
+		h.Key = key
+	}
+	if h.Mods == 0 {
+		return Hotkey{}, fmt.Errorf("invalid shortcut '%s': at least one modifier is required", accelerator)
+	}
+	return h, nil
+}
+
+func parseModifier(s string) (Modifier, bool) {
+	switch strings.ToLower(s) {
+	case "ctrl", "control":
+		return ModCtrl, true
+	case "shift":
+		return ModShift, true
+	case "alt", "option":
+		return ModAlt, true
+	case "cmd", "command", "super", "win", "meta":
+		return ModSuper, true
+	case "cmdorctrl", "commandorcontrol":
+		if runtime.GOOS == "darwin" {
+			return ModSuper, true
+		}
+		return ModCtrl, true
+	}
+	return 0, false
+}
+
+func parseKey(s string) (string, bool) {
+	if len(s) == 1 {
+		c := strings.ToUpper(s)[0]
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			return string(c), true
+		}
+		return "", false
+	}
+	if name, ok := namedKeys[strings.ToLower(s)]; ok {
+		return name, true
+	}
+	var n int
+	if (s[0] == 'F' || s[0] == 'f') && len(s) <= 3 {
+		if _, err := fmt.Sscanf(s[1:], "%d", &n); err == nil && n >= 1 && n <= 24 && fmt.Sprint(n) == s[1:] {
+			return fmt.Sprintf("F%d", n), true
+		}
+	}
+	return "", false
+}
+
+// Supported 报告当前平台是否支持全局快捷键
+func Supported() bool {
+	return supported
+}
+
+// Register 注册一个全局快捷键，按下时在单独的 goroutine 中调用 fn。返回的函数用于注销。
+// 快捷键已被其他程序占用时返回错误。
+func Register(h Hotkey, fn func()) (unregister func(), err error) {
+	return register(h, fn)
+}
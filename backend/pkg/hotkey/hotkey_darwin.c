@@ -0,0 +1,60 @@
+//go:build darwin && cgo
+
+#include <Carbon/Carbon.h>
+#include <dispatch/dispatch.h>
+#include <pthread.h>
+#include <stdint.h>
+
+// 由 hotkey_darwin.go 导出
+extern void devtoolsHotKeyPressed(uint32_t id);
+
+static const OSType devtoolsHotKeySignature = 'dvtl';
+static int devtoolsHandlerInstalled = 0;
+
+static OSStatus devtoolsHotKeyHandler(EventHandlerCallRef next, EventRef event, void *data) {
+	EventHotKeyID hotKeyID;
+	OSStatus status = GetEventParameter(event, kEventParamDirectObject, typeEventHotKeyID, NULL,
+	                                    sizeof(hotKeyID), NULL, &hotKeyID);
+	if (status != noErr || hotKeyID.signature != devtoolsHotKeySignature) {
+		return eventNotHandledErr;
+	}
+	devtoolsHotKeyPressed(hotKeyID.id);
+	return noErr;
+}
+
+// Carbon 事件 API 需要在主线程调用
+static void devtoolsRunOnMain(dispatch_block_t block) {
+	if (pthread_main_np()) {
+		block();
+	} else {
+		dispatch_sync(dispatch_get_main_queue(), block);
+	}
+}
+
+int devtoolsRegisterHotKey(uint32_t keyCode, uint32_t mods, uint32_t id, void **ref) {
+	__block OSStatus status = noErr;
+	devtoolsRunOnMain(^{
+		if (!devtoolsHandlerInstalled) {
+			EventTypeSpec spec = {kEventClassKeyboard, kEventHotKeyPressed};
+			status = InstallApplicationEventHandler(&devtoolsHotKeyHandler, 1, &spec, NULL, NULL);
+			if (status != noErr) {
+				return;
+			}
+			devtoolsHandlerInstalled = 1;
+		}
+		EventHotKeyID hotKeyID = {devtoolsHotKeySignature, id};
+		EventHotKeyRef hotKeyRef = NULL;
+		status = RegisterEventHotKey(keyCode, mods, hotKeyID, GetApplicationEventTarget(), 0, &hotKeyRef);
+		*ref = hotKeyRef;
+	});
+	return (int)status;
+}
+
+void devtoolsUnregisterHotKey(void *ref) {
+	if (ref == NULL) {
+		return;
+	}
+	devtoolsRunOnMain(^{
+		UnregisterEventHotKey((EventHotKeyRef)ref);
+	});
+}
@@ -0,0 +1,102 @@
+//go:build darwin && cgo
+
+package hotkey
+
+/*
+#cgo LDFLAGS: -framework Carbon
+#include <stdint.h>
+
+int devtoolsRegisterHotKey(uint32_t keyCode, uint32_t mods, uint32_t id, void **ref);
+void devtoolsUnregisterHotKey(void *ref);
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// Carbon 的修饰键掩码
+const (
+	cmdKey     = 0x0100
+	shiftKey   = 0x0200
+	optionKey  = 0x0800
+	controlKey = 0x1000
+)
+
+// keyCodes 是按键对应的 macOS 虚拟键码（kVK_*，与键盘布局中的物理位置对应）
+var keyCodes = map[string]uint32{
+	"A": 0x00, "S": 0x01, "D": 0x02, "F": 0x03, "H": 0x04, "G": 0x05, "Z": 0x06, "X": 0x07,
+	"C": 0x08, "V": 0x09, "B": 0x0B, "Q": 0x0C, "W": 0x0D, "E": 0x0E, "R": 0x0F, "Y": 0x10,
+	"T": 0x11, "1": 0x12, "2": 0x13, "3": 0x14, "4": 0x15, "6": 0x16, "5": 0x17, "9": 0x19,
+	"7": 0x1A, "8": 0x1C, "0": 0x1D, "O": 0x1F, "U": 0x20, "I": 0x22, "P": 0x23, "L": 0x25,
+	"J": 0x26, "K": 0x28, "N": 0x2D, "M": 0x2E,
+	"Enter": 0x24, "Tab": 0x30, "Space": 0x31, "Escape": 0x35,
+	"F1": 0x7A, "F2": 0x78, "F3": 0x63, "F4": 0x76, "F5": 0x60, "F6": 0x61, "F7": 0x62, "F8": 0x64,
+	"F9": 0x65, "F10": 0x6D, "F11": 0x67, "F12": 0x6F, "F13": 0x69, "F14": 0x6B, "F15": 0x71,
+	"F16": 0x6A, "F17": 0x40, "F18": 0x4F, "F19": 0x50, "F20": 0x5A,
+	"Left": 0x7B, "Right": 0x7C, "Down": 0x7D, "Up": 0x7E,
+}
+
+const supported = true
+
+var (
+	handlersMu sync.Mutex
+	handlers   = map[uint32]func(){}
+	nextID     uint32
+)
+
+//export devtoolsHotKeyPressed
+func devtoolsHotKeyPressed(id C.uint32_t) {
+	handlersMu.Lock()
+	fn := handlers[uint32(id)]
+	handlersMu.Unlock()
+	if fn != nil {
+		go fn()
+	}
+}
+
+func register(h Hotkey, fn func()) (func(), error) {
+	code, ok := keyCodes[h.Key]
+	if !ok {
+		return nil, fmt.Errorf("unsupported key '%s'", h.Key)
+	}
+	var mods uint32
+	if h.Mods&ModCtrl != 0 {
+		mods |= controlKey
+	}
+	if h.Mods&ModShift != 0 {
+		mods |= shiftKey
+	}
+	if h.Mods&ModAlt != 0 {
+		mods |= optionKey
+	}
+	if h.Mods&ModSuper != 0 {
+		mods |= cmdKey
+	}
+
+	handlersMu.Lock()
+	nextID++
+	id := nextID
+	handlers[id] = fn
+	handlersMu.Unlock()
+
+	var ref unsafe.Pointer
+	if status := C.devtoolsRegisterHotKey(C.uint32_t(code), C.uint32_t(mods), C.uint32_t(id), &ref); status != 0 {
+		handlersMu.Lock()
+		delete(handlers, id)
+		handlersMu.Unlock()
+		return nil, fmt.Errorf("shortcut %s is already in use (OSStatus %d)", h, int(status))
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			C.devtoolsUnregisterHotKey(ref)
+			handlersMu.Lock()
+			delete(handlers, id)
+			handlersMu.Unlock()
+		})
+	}, nil
+}
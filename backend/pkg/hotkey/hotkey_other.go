@@ -0,0 +1,10 @@
+//go:build !windows && !(darwin && cgo)
+
+package hotkey
+
+const supported = false
+
+// register 在不支持的平台上总是返回 ErrUnsupported。
+func register(Hotkey, func()) (func(), error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,134 @@
+//go:build windows
+
+package hotkey
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32                = windows.NewLazySystemDLL("user32.dll")
+	procRegisterHotKey    = user32.NewProc("RegisterHotKey")
+	procUnregisterHotKey  = user32.NewProc("UnregisterHotKey")
+	procGetMessageW       = user32.NewProc("GetMessageW")
+	procPostThreadMessage = user32.NewProc("PostThreadMessageW")
+)
+
+const (
+	modAlt      = 0x0001
+	modControl  = 0x0002
+	modShift    = 0x0004
+	modWin      = 0x0008
+	modNoRepeat = 0x4000
+
+	wmQuit   = 0x0012
+	wmHotkey = 0x0312
+)
+
+const supported = true
+
+// msg 对应 Win32 的 MSG 结构
+type msg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      struct{ x, y int32 }
+	private uint32
+}
+
+// virtualKey 返回按键的 Windows 虚拟键码
+func virtualKey(key string) (uintptr, bool) {
+	switch key {
+	case "Space":
+		return 0x20, true
+	case "Enter":
+		return 0x0D, true
+	case "Tab":
+		return 0x09, true
+	case "Escape":
+		return 0x1B, true
+	case "Left":
+		return 0x25, true
+	case "Up":
+		return 0x26, true
+	case "Right":
+		return 0x27, true
+	case "Down":
+		return 0x28, true
+	}
+	if len(key) == 1 {
+		// A-Z 与 0-9 的虚拟键码等于其 ASCII 码
+		return uintptr(key[0]), true
+	}
+	var n int
+	if _, err := fmt.Sscanf(key, "F%d", &n); err == nil {
+		return uintptr(0x70 + n - 1), true
+	}
+	return 0, false
+}
+
+// register 在一个锁定的系统线程上注册快捷键并运行消息循环。
+// RegisterHotKey 的 WM_HOTKEY 消息只会发送到注册它的线程。
+func register(h Hotkey, fn func()) (func(), error) {
+	vk, ok := virtualKey(h.Key)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key '%s'", h.Key)
+	}
+	mods := uintptr(modNoRepeat)
+	if h.Mods&ModCtrl != 0 {
+		mods |= modControl
+	}
+	if h.Mods&ModShift != 0 {
+		mods |= modShift
+	}
+	if h.Mods&ModAlt != 0 {
+		mods |= modAlt
+	}
+	if h.Mods&ModSuper != 0 {
+		mods |= modWin
+	}
+
+	type result struct {
+		threadID uint32
+		err      error
+	}
+	started := make(chan result, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		threadID := windows.GetCurrentThreadId()
+		if r, _, err := procRegisterHotKey.Call(0, 1, mods, vk); r == 0 {
+			started <- result{err: fmt.Errorf("shortcut %s is already in use: %w", h, err)}
+			return
+		}
+		defer procUnregisterHotKey.Call(0, 1)
+		started <- result{threadID: threadID}
+
+		var m msg
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			// 0 为 WM_QUIT，-1 为出错
+			if r == 0 || int32(r) == -1 {
+				return
+			}
+			if m.message == wmHotkey {
+				go fn()
+			}
+		}
+	}()
+
+	res := <-started
+	if res.err != nil {
+		return nil, res.err
+	}
+	return func() {
+		procPostThreadMessage.Call(uintptr(res.threadID), wmQuit, 0, 0)
+	}, nil
+}
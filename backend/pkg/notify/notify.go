@@ -0,0 +1,31 @@
+// Package notify 通过操作系统自带的通知中心发送桌面通知，应用窗口关闭或不在前台时用户也能看到
+package notify
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnsupported 表示当前平台不支持（或缺少发送通知所需的系统工具）
+var ErrUnsupported = errors.New("desktop notifications are not supported on this platform")
+
+// 标题与正文的最大长度，过长的内容会被系统截断或导致通知发送失败
+const (
+	maxTitleLength   = 128
+	maxMessageLength = 512
+)
+
+// Send 发送一条桌面通知。它会阻塞到系统工具返回为止，调用方通常应在 goroutine 中调用。
+func Send(title, message string) error {
+	return send(truncate(title, maxTitleLength), truncate(message, maxMessageLength))
+}
+
+// truncate 把 s 截断为最多 n 个字符（按 rune 计算），并去掉多余的空白
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,22 @@
+//go:build darwin
+
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// send 使用 osascript 的 display notification。标题与正文通过参数传入，不需要转义 AppleScript 字符串。
+func send(title, message string) error {
+	cmd := exec.Command("osascript",
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		title, message)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("osascript failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
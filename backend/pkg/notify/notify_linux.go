@@ -0,0 +1,22 @@
+//go:build linux
+
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// send 使用 libnotify 的 notify-send，没有安装时返回 ErrUnsupported
+func send(title, message string) error {
+	path, err := exec.LookPath("notify-send")
+	if err != nil {
+		return ErrUnsupported
+	}
+	cmd := exec.Command(path, "--app-name=DevTools", "--", title, message)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify-send failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// toastScript 通过 WinRT 的 ToastNotificationManager 显示一条 Toast 通知。
+// 标题与正文通过环境变量传入并做 XML 转义，避免拼接 PowerShell 字符串。
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$title = [System.Security.SecurityElement]::Escape($env:DEVTOOLS_NOTIFY_TITLE)
+$message = [System.Security.SecurityElement]::Escape($env:DEVTOOLS_NOTIFY_MESSAGE)
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml("<toast><visual><binding template=""ToastGeneric""><text>$title</text><text>$message</text></binding></visual></toast>")
+$appId = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($appId).Show([Windows.UI.Notifications.ToastNotification]::new($xml))
+`
+
+// send 使用 PowerShell 显示 Toast 通知，不弹出控制台窗口
+func send(title, message string) error {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "DEVTOOLS_NOTIFY_TITLE="+title, "DEVTOOLS_NOTIFY_MESSAGE="+message)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("powershell toast failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package sshconfig
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// HostName 的类型
+const (
+	HostNameIP  = "ip"  // 字面量 IP 地址
+	HostNameDNS = "dns" // 需要 DNS 解析的主机名（包括未设置 HostName 时直接使用的别名）
+)
+
+// 多个别名指向同一目标时的类型
+const (
+	TargetRedundant   = "redundant"   // 用户、密钥与跳板机都相同，其中一个别名很可能是多余的
+	TargetConflicting = "conflicting" // 用户或密钥不同，可能是有意为之，也可能是遗留的过期配置
+)
+
+// HostTarget 是一个具体别名的连接目标
+type HostTarget struct {
+	Alias         string   `json:"alias"`
+	HostName      string   `json:"hostName"` // 规范化后的 HostName：IP 使用标准写法，域名小写且去掉末尾的点
+	Port          string   `json:"port"`
+	User          string   `json:"user"`
+	IdentityFiles []string `json:"identityFiles,omitempty"`
+	ProxyJump     string   `json:"proxyJump,omitempty"` // ProxyJump 或 ProxyCommand，经由不同跳板机的相同地址不算重复
+	Kind          string   `json:"kind"`                // ip | dns
+	ResolvedIPs   []string `json:"resolvedIps,omitempty"`
+	ResolveError  string   `json:"resolveError,omitempty"`
+}
+
+// TargetGroup 是指向同一 HostName:Port（或解析到同一地址）的多个别名
+type TargetGroup struct {
+	Target      string   `json:"target"` // 例如 "10.0.0.1:22"
+	Aliases     []string `json:"aliases"`
+	Kind        string   `json:"kind"`                  // redundant | conflicting
+	Differences []string `json:"differences,omitempty"` // 例如 "User: root (web), deploy (web-old)"
+}
+
+// HostAnalysis 是 AnalyzeHosts 的结果
+type HostAnalysis struct {
+	Targets    []HostTarget  `json:"targets"`
+	Duplicates []TargetGroup `json:"duplicates"`
+}
+
+// Resolver 把主机名解析为 IP 地址，AnalyzeHosts 用它找出不同写法指向同一台机器的别名
+type Resolver func(host string) ([]string, error)
+
+// AnalyzeHosts 计算每个具体别名（不含通配符）的连接目标，并找出指向同一 HostName:Port 的别名。
+// resolve 不为空时解析域名，域名与 IP（或两个域名）解析到同一地址的别名也会被归为一组；
+// 经由跳板机连接的主机名在本机解析没有意义，不会被解析。
+func (m *SSHConfigManager) AnalyzeHosts(resolve Resolver) *HostAnalysis {
+	analysis := &HostAnalysis{Targets: []HostTarget{}, Duplicates: []TargetGroup{}}
+	seen := make(map[string]bool)
+	for _, b := range m.getIndex().blocks {
+		for _, alias := range b.aliases {
+			if seen[alias] || strings.ContainsAny(alias, "*?!") {
+				continue
+			}
+			seen[alias] = true
+			analysis.Targets = append(analysis.Targets, m.hostTarget(alias, resolve))
+		}
+	}
+	analysis.Duplicates = groupTargets(analysis.Targets)
+	return analysis
+}
+
+// hostTarget 计算 alias 的连接目标
+func (m *SSHConfigManager) hostTarget(alias string, resolve Resolver) HostTarget {
+	tokens := m.TokensFor(alias)
+	cfg := m.ResolveHost(alias)
+	target := HostTarget{
+		Alias:     alias,
+		Port:      tokens.Port,
+		User:      tokens.RemoteUser,
+		ProxyJump: cfg.Get("ProxyJump"),
+	}
+	if target.ProxyJump == "" || strings.EqualFold(target.ProxyJump, "none") {
+		target.ProxyJump = cfg.Get("ProxyCommand")
+	}
+	if strings.EqualFold(target.ProxyJump, "none") {
+		target.ProxyJump = ""
+	}
+	for _, f := range cfg.GetAll("IdentityFile") {
+		target.IdentityFiles = append(target.IdentityFiles, ExpandTokensWith(f, tokens))
+	}
+
+	target.HostName, target.Kind = normalizeHostName(tokens.HostName)
+	if target.Kind == HostNameIP {
+		target.ResolvedIPs = []string{target.HostName}
+	} else if resolve != nil && target.ProxyJump == "" {
+		ips, err := resolve(target.HostName)
+		if err != nil {
+			target.ResolveError = err.Error()
+		} else {
+			for _, ip := range ips {
+				if normalized, kind := normalizeHostName(ip); kind == HostNameIP && !slices.Contains(target.ResolvedIPs, normalized) {
+					target.ResolvedIPs = append(target.ResolvedIPs, normalized)
+				}
+			}
+			sort.Strings(target.ResolvedIPs)
+		}
+	}
+	return target
+}
+
+// normalizeHostName 返回 HostName 的规范写法及其类型
+func normalizeHostName(hostName string) (string, string) {
+	host := strings.Trim(hostName, "[]")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), HostNameIP
+	}
+	return strings.TrimSuffix(strings.ToLower(host), "."), HostNameDNS
+}
+
+// groupTargets 把指向同一目标的别名归为一组：HostName:Port 相同，或任一解析出的地址与端口相同，且跳板机相同。
+// 只有一个别名的组不会返回。
+func groupTargets(targets []HostTarget) []TargetGroup {
+	// 并查集：共享任一目标键的别名属于同一组
+	parent := make([]int, len(targets))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	owner := make(map[string]int)
+	for i, t := range targets {
+		keys := []string{net.JoinHostPort(t.HostName, t.Port)}
+		for _, ip := range t.ResolvedIPs {
+			keys = append(keys, net.JoinHostPort(ip, t.Port))
+		}
+		for _, key := range keys {
+			key = t.ProxyJump + "|" + key
+			if j, ok := owner[key]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				owner[key] = i
+			}
+		}
+	}
+
+	members := make(map[int][]HostTarget)
+	var roots []int
+	for i, t := range targets {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], t)
+	}
+
+	groups := []TargetGroup{}
+	for _, root := range roots {
+		if len(members[root]) < 2 {
+			continue
+		}
+		groups = append(groups, newTargetGroup(members[root]))
+	}
+	return groups
+}
+
+// newTargetGroup 比较一组别名的用户与密钥，生成差异说明
+func newTargetGroup(targets []HostTarget) TargetGroup {
+	first := targets[0]
+	group := TargetGroup{Target: net.JoinHostPort(first.HostName, first.Port), Kind: TargetRedundant}
+	users := make([]string, 0, len(targets))
+	keys := make([]string, 0, len(targets))
+	for _, t := range targets {
+		group.Aliases = append(group.Aliases, t.Alias)
+		users = append(users, fmt.Sprintf("%s (%s)", t.User, t.Alias))
+		files := strings.Join(t.IdentityFiles, ", ")
+		if files == "" {
+			files = "default"
+		}
+		keys = append(keys, fmt.Sprintf("%s (%s)", files, t.Alias))
+		if t.User != first.User {
+			group.Kind = TargetConflicting
+		}
+	}
+	if group.Kind == TargetConflicting {
+		group.Differences = append(group.Differences, "User: "+strings.Join(users, ", "))
+	}
+	for _, t := range targets[1:] {
+		if !slices.Equal(t.IdentityFiles, first.IdentityFiles) {
+			group.Kind = TargetConflicting
+			group.Differences = append(group.Differences, "IdentityFile: "+strings.Join(keys, "; "))
+			break
+		}
+	}
+	return group
+}
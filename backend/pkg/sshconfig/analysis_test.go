@@ -0,0 +1,93 @@
+package sshconfig
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestAnalyzeHosts 测试 HostName 的规范化与分类、同一目标的别名分组，以及经由跳板机的主机不与直连主机归为一组
+func TestAnalyzeHosts(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host web web-old",
+		"    HostName 10.0.0.1",
+		"    User root",
+		"",
+		"Host web-deploy",
+		"    HostName 10.0.0.1",
+		"    Port 22",
+		"    User deploy",
+		"    IdentityFile ~/.ssh/deploy",
+		"",
+		"Host internal",
+		"    HostName 10.0.0.1",
+		"    User root",
+		"    ProxyJump bastion",
+		"",
+		"Host db db-dns",
+		"    User postgres",
+		"Host db",
+		"    HostName DB.Example.com.",
+		"Host db-dns",
+		"    HostName db.example.com",
+		"",
+		"Host v6",
+		"    HostName [2001:db8:0::1]",
+		"",
+		"Host app",
+		"    HostName app.example.com",
+		"    User root",
+		"",
+		"Host *",
+		"    User nobody",
+	}}
+
+	resolve := func(host string) ([]string, error) {
+		switch host {
+		case "app.example.com":
+			return []string{"10.0.0.1"}, nil
+		case "db.example.com":
+			return []string{"10.0.0.9"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	analysis := m.AnalyzeHosts(resolve)
+
+	if len(analysis.Targets) != 8 {
+		t.Fatalf("expected 8 concrete hosts, got %+v", analysis.Targets)
+	}
+	byAlias := map[string]HostTarget{}
+	for _, target := range analysis.Targets {
+		byAlias[target.Alias] = target
+	}
+	if db := byAlias["db"]; db.HostName != "db.example.com" || db.Kind != HostNameDNS || db.User != "postgres" {
+		t.Errorf("unexpected target for db: %+v", db)
+	}
+	if v6 := byAlias["v6"]; v6.HostName != "2001:db8::1" || v6.Kind != HostNameIP {
+		t.Errorf("IPv6 HostName should be normalized: %+v", v6)
+	}
+	if internal := byAlias["internal"]; internal.ProxyJump != "bastion" || internal.ResolvedIPs[0] != "10.0.0.1" {
+		t.Errorf("unexpected target for internal: %+v", internal)
+	}
+
+	want := []TargetGroup{
+		{
+			Target:  "10.0.0.1:22",
+			Aliases: []string{"web", "web-old", "web-deploy", "app"},
+			Kind:    TargetConflicting,
+			Differences: []string{
+				"User: root (web), root (web-old), deploy (web-deploy), root (app)",
+				"IdentityFile: default (web); default (web-old); ~/.ssh/deploy (web-deploy); default (app)",
+			},
+		},
+		{Target: "db.example.com:22", Aliases: []string{"db", "db-dns"}, Kind: TargetRedundant},
+	}
+	if !reflect.DeepEqual(analysis.Duplicates, want) {
+		t.Errorf("Duplicates = %+v", analysis.Duplicates)
+	}
+
+	// 不解析域名时，app 只能按 HostName 比较
+	if got := m.AnalyzeHosts(nil).Duplicates[0].Aliases; !reflect.DeepEqual(got, []string{"web", "web-old", "web-deploy"}) {
+		t.Errorf("without resolving, app should not be grouped with web: %v", got)
+	}
+}
@@ -0,0 +1,40 @@
+package sshconfig
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DuplicateHost 复制 sourceAlias 所在的 Host 块（包括描述注释与块内注释），
+// 以 newAlias 作为唯一别名插入到来源块之后。副本紧跟在来源块后面而不是追加到文件末尾，
+// 这样它与 "Host *" 等通配块的先后顺序和来源一致，生效配置也相同。
+// 复制只读块得到的副本可以编辑：带有 LockTag 的注释不会被复制。调用方负责 Save。
+func (m *SSHConfigManager) DuplicateHost(sourceAlias, newAlias string) error {
+	if newAlias == "" || strings.ContainsAny(newAlias, " \t*?!") {
+		return &ConfigError{"duplicate_host", fmt.Errorf("invalid host alias '%s'", newAlias)}
+	}
+	if strings.Contains(sourceAlias, "*") {
+		return &ConfigError{"duplicate_host", fmt.Errorf("cannot duplicate wildcard host %s", sourceAlias)}
+	}
+
+	ix := m.getIndex()
+	if len(ix.byAlias[newAlias]) > 0 {
+		return &ConfigError{"duplicate_host", fmt.Errorf("host %s already exists", newAlias)}
+	}
+	blocks := ix.byAlias[sourceAlias]
+	if len(blocks) == 0 {
+		return &HostNotFoundError{Alias: sourceAlias}
+	}
+	src := blocks[0]
+	r := m.blockRangeAt(src.line, ix.end(src, len(m.rawLines)))
+	insertAt := m.lastContentLine(r)
+
+	copied := []string{""}
+	copied = append(copied, m.rawLines[r.descStart:r.hostLine]...)
+	copied = append(copied, getLineIndent(m.rawLines[r.hostLine])+"Host "+quoteArg(newAlias))
+	copied = append(copied, m.rawLines[r.hostLine+1:insertAt]...)
+	copied = slices.DeleteFunc(copied, hasLockTag)
+	m.insertLines(insertAt, copied...)
+	return nil
+}
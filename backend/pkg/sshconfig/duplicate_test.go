@@ -0,0 +1,98 @@
+package sshconfig
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestDuplicateHost_CopiesBlockAfterSource 测试复制注释与参数，并插入到来源块之后
+func TestDuplicateHost_CopiesBlockAfterSource(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"# web server",
+		"Host web www",
+		"    HostName 10.0.0.5",
+		"    # deploy user",
+		"    User deploy",
+		"",
+		"Host *",
+		"    ServerAliveInterval 30",
+	}}
+
+	if err := m.DuplicateHost("www", "web-staging"); err != nil {
+		t.Fatalf("DuplicateHost failed: %v", err)
+	}
+	assertIndexConsistent(t, m)
+
+	want := []string{
+		"# web server",
+		"Host web www",
+		"    HostName 10.0.0.5",
+		"    # deploy user",
+		"    User deploy",
+		"",
+		"# web server",
+		"Host web-staging",
+		"    HostName 10.0.0.5",
+		"    # deploy user",
+		"    User deploy",
+		"",
+		"Host *",
+		"    ServerAliveInterval 30",
+	}
+	if !reflect.DeepEqual(m.rawLines, want) {
+		t.Fatalf("Unexpected config:\n%s", strings.Join(m.rawLines, "\n"))
+	}
+
+	if got := m.ResolveHost("web-staging").Get("User"); got != "deploy" {
+		t.Errorf("Expected duplicated User 'deploy', got %q", got)
+	}
+}
+
+// TestDuplicateHost_AtEndOfFile 测试复制文件末尾的块
+func TestDuplicateHost_AtEndOfFile(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host db",
+		"    HostName 10.0.0.6",
+	}}
+
+	if err := m.DuplicateHost("db", "db2"); err != nil {
+		t.Fatalf("DuplicateHost failed: %v", err)
+	}
+	assertIndexConsistent(t, m)
+
+	want := []string{
+		"Host db",
+		"    HostName 10.0.0.6",
+		"",
+		"Host db2",
+		"    HostName 10.0.0.6",
+	}
+	if !reflect.DeepEqual(m.rawLines, want) {
+		t.Fatalf("Unexpected config:\n%s", strings.Join(m.rawLines, "\n"))
+	}
+}
+
+// TestDuplicateHost_Errors 测试来源不存在、新别名已存在或无效
+func TestDuplicateHost_Errors(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host web",
+		"    HostName 10.0.0.5",
+		"Host db",
+		"    HostName 10.0.0.6",
+	}}
+
+	var notFound *HostNotFoundError
+	if err := m.DuplicateHost("missing", "copy"); !errors.As(err, &notFound) {
+		t.Errorf("Expected HostNotFoundError, got %v", err)
+	}
+	for _, alias := range []string{"db", "", "two words", "web-*"} {
+		if err := m.DuplicateHost("web", alias); err == nil {
+			t.Errorf("Expected error for new alias %q", alias)
+		}
+	}
+	if len(m.rawLines) != 4 {
+		t.Errorf("Config should be unchanged on error, got %d lines", len(m.rawLines))
+	}
+}
@@ -0,0 +1,127 @@
+package sshconfig
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Forward 是一条解析后的 LocalForward / RemoteForward / DynamicForward
+type Forward struct {
+	Keyword     string // "LocalForward"、"RemoteForward" 或 "DynamicForward"
+	BindAddress string // 为空表示未指定（由 GatewayPorts 决定），"*" 表示所有地址
+	Port        int
+	TargetHost  string // DynamicForward 以及没有目标的 RemoteForward 为空
+	TargetPort  int
+}
+
+// forwardKeywords 小写指令名 → 规范写法
+var forwardKeywords = map[string]string{
+	"localforward":   "LocalForward",
+	"remoteforward":  "RemoteForward",
+	"dynamicforward": "DynamicForward",
+}
+
+// IsForwardKeyword 判断 key 是否是三种转发指令之一（不区分大小写）
+func IsForwardKeyword(key string) bool {
+	_, ok := forwardKeywords[strings.ToLower(key)]
+	return ok
+}
+
+// ParseForward 解析转发指令的值。语法与 Validate 的检查一致；Unix 域套接字转发无法用端口表示，会返回错误。
+func ParseForward(keyword, value string) (*Forward, error) {
+	lower := strings.ToLower(keyword)
+	canonical, ok := forwardKeywords[lower]
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a forward directive", keyword)
+	}
+	if err := validateForward(lower, value); err != nil {
+		return nil, fmt.Errorf("%s: %w", canonical, err)
+	}
+	args, _ := splitArgs(value)
+	for _, arg := range args {
+		if isSocketPath(arg) {
+			return nil, fmt.Errorf("%s: Unix socket '%s' is not supported", canonical, arg)
+		}
+	}
+
+	fwd := &Forward{Keyword: canonical}
+	addr, port, _ := splitForwardSpec(args[0])
+	fwd.BindAddress = addr
+	fwd.Port, _ = strconv.Atoi(port)
+	if len(args) == 2 {
+		host, port, _ := splitForwardSpec(args[1])
+		fwd.TargetHost = host
+		fwd.TargetPort, _ = strconv.Atoi(port)
+	}
+	return fwd, nil
+}
+
+// String 返回指令的值（不含指令名），例如 "127.0.0.1:8080 db:5432"
+func (f *Forward) String() string {
+	value := joinForwardSpec(f.BindAddress, f.Port)
+	if f.TargetHost != "" {
+		value += " " + joinForwardSpec(f.TargetHost, f.TargetPort)
+	}
+	return value
+}
+
+// joinForwardSpec 拼接 [addr:]port，IPv6 地址使用方括号
+func joinForwardSpec(addr string, port int) string {
+	p := strconv.Itoa(port)
+	if addr == "" {
+		return p
+	}
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+		return "[" + addr + "]:" + p
+	}
+	return addr + ":" + p
+}
+
+// ReplaceParamValue 把主机块中第一个值为 oldValue 的 key 参数改为 newValue（值按参数拆分后比较，
+// 忽略空白与引号的差异），用于修改同名参数中的某一条，例如多个 LocalForward 中的一个。
+func (m *SSHConfigManager) ReplaceParamValue(hostname, key, oldValue, newValue string) error {
+	if hostname == "" || key == "" {
+		return &ConfigError{"replace_param", fmt.Errorf("hostname and key cannot be empty")}
+	}
+
+	hostStart, hostEnd, found := m.findHost(hostname)
+	if !found {
+		return &ConfigError{"replace_param", fmt.Errorf("host %s not found", hostname)}
+	}
+	if m.blockLocked(hostStart, hostEnd) {
+		return &HostLockedError{Alias: hostname}
+	}
+	if hostEnd == -1 || hostEnd > len(m.rawLines) {
+		hostEnd = len(m.rawLines)
+	}
+
+	want := normalizeArgs(oldValue)
+	for i := hostStart + 1; i < hostEnd; i++ {
+		trimmed := strings.TrimSpace(m.rawLines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if isDirective(trimmed, "Host", "Include") {
+			break
+		}
+		paramKey, rest := splitDirective(trimmed)
+		if !strings.EqualFold(paramKey, key) || normalizeArgs(rest) != want {
+			continue
+		}
+		indent := getLineIndent(m.rawLines[i])
+		m.setLine(i, fmt.Sprintf("%s%s %s", indent, paramKey, formatParamValue(key, newValue)))
+		return nil
+	}
+	return &ConfigError{"replace_param", fmt.Errorf("%s '%s' not found in host %s", key, oldValue, hostname)}
+}
+
+// normalizeArgs 把参数值规范化为以单个空格分隔的参数，无法拆分时按空白拆分
+func normalizeArgs(value string) string {
+	args, err := splitArgs(strings.TrimSpace(value))
+	if err != nil {
+		args = strings.Fields(value)
+	}
+	return strings.Join(args, " ")
+}
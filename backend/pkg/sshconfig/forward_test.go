@@ -0,0 +1,81 @@
+package sshconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseForward 测试解析转发指令，以及 String 写回的格式
+func TestParseForward(t *testing.T) {
+	tests := []struct {
+		keyword string
+		value   string
+		want    Forward
+		str     string
+	}{
+		{"LocalForward", "8080 db.internal:5432", Forward{Keyword: "LocalForward", Port: 8080, TargetHost: "db.internal", TargetPort: 5432}, "8080 db.internal:5432"},
+		{"localforward", "*:8080 10.0.0.5:80", Forward{Keyword: "LocalForward", BindAddress: "*", Port: 8080, TargetHost: "10.0.0.5", TargetPort: 80}, "*:8080 10.0.0.5:80"},
+		{"LocalForward", "[::1]:8080 [fd00::5]:80", Forward{Keyword: "LocalForward", BindAddress: "::1", Port: 8080, TargetHost: "fd00::5", TargetPort: 80}, "[::1]:8080 [fd00::5]:80"},
+		{"LocalForward", "127.0.0.1/8080 db/5432", Forward{Keyword: "LocalForward", BindAddress: "127.0.0.1", Port: 8080, TargetHost: "db", TargetPort: 5432}, "127.0.0.1:8080 db:5432"},
+		{"DynamicForward", "1080", Forward{Keyword: "DynamicForward", Port: 1080}, "1080"},
+		{"RemoteForward", "0 localhost:22", Forward{Keyword: "RemoteForward", TargetHost: "localhost", TargetPort: 22}, "0 localhost:22"},
+	}
+	for _, tt := range tests {
+		got, err := ParseForward(tt.keyword, tt.value)
+		if err != nil {
+			t.Errorf("ParseForward(%q, %q) error: %v", tt.keyword, tt.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("ParseForward(%q, %q) = %+v, want %+v", tt.keyword, tt.value, *got, tt.want)
+		}
+		if s := got.String(); s != tt.str {
+			t.Errorf("String() = %q, want %q", s, tt.str)
+		}
+	}
+
+	for _, bad := range [][2]string{
+		{"LocalForward", "8080"},
+		{"LocalForward", "/tmp/local.sock db:5432"},
+		{"DynamicForward", "70000"},
+		{"ProxyJump", "bastion"},
+	} {
+		if _, err := ParseForward(bad[0], bad[1]); err == nil {
+			t.Errorf("ParseForward(%q, %q) should fail", bad[0], bad[1])
+		}
+	}
+}
+
+// TestReplaceParamValue 测试只替换同名参数中值匹配的那一条，保留缩进
+func TestReplaceParamValue(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host other",
+		"    LocalForward 8080 db:5432",
+		"Host app",
+		"    LocalForward 9000 cache:6379",
+		"\tLocalForward  8080   db:5432",
+		"    DynamicForward 1080",
+	}}
+
+	if err := m.ReplaceParamValue("app", "LocalForward", "8080 db:5432", "*:8081 db:5432"); err != nil {
+		t.Fatalf("ReplaceParamValue failed: %v", err)
+	}
+	want := []string{
+		"Host other",
+		"    LocalForward 8080 db:5432",
+		"Host app",
+		"    LocalForward 9000 cache:6379",
+		"\tLocalForward *:8081 db:5432",
+		"    DynamicForward 1080",
+	}
+	if !reflect.DeepEqual(m.rawLines, want) {
+		t.Errorf("rawLines = %q, want %q", m.rawLines, want)
+	}
+
+	if err := m.ReplaceParamValue("app", "LocalForward", "7000 db:5432", "7001 db:5432"); err == nil {
+		t.Error("ReplaceParamValue should fail when no line has the old value")
+	}
+	if err := m.ReplaceParamValue("missing", "LocalForward", "8080 db:5432", "8081 db:5432"); err == nil {
+		t.Error("ReplaceParamValue should fail for an unknown host")
+	}
+}
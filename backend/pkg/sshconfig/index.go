@@ -0,0 +1,164 @@
+package sshconfig
+
+import (
+	"sort"
+)
+
+// hostBlock 描述一个 Host 块在 rawLines 中的位置
+type hostBlock struct {
+	line    int      // Host 行的行号
+	aliases []string // Host 行中的所有别名
+}
+
+// hostIndex 是 rawLines 中所有 Host 块的索引（别名 → 块），
+// 避免每次查找主机都线性扫描整个文件。它在编辑时增量维护，
+// 只有整体替换 rawLines（Load、ReorderHosts）时才会重建。
+type hostIndex struct {
+	blocks  []*hostBlock            // 按行号升序
+	byAlias map[string][]*hostBlock // 精确别名 → 包含该别名的块（按文件顺序）
+}
+
+// isHostLine 判断一行是否为 Host 指令，并返回其中的别名
+func isHostLine(line string) ([]string, bool) {
+	after, ok := cutDirective(line, "Host")
+	if !ok {
+		return nil, false
+	}
+	return parseHostNames(after), true
+}
+
+func buildHostIndex(lines []string) *hostIndex {
+	ix := &hostIndex{byAlias: make(map[string][]*hostBlock)}
+	for i, line := range lines {
+		if aliases, ok := isHostLine(line); ok {
+			b := &hostBlock{line: i, aliases: aliases}
+			ix.blocks = append(ix.blocks, b)
+			for _, alias := range aliases {
+				ix.byAlias[alias] = append(ix.byAlias[alias], b)
+			}
+		}
+	}
+	return ix
+}
+
+// search 返回第一个行号 >= line 的块的位置
+func (ix *hostIndex) search(line int) int {
+	return sort.Search(len(ix.blocks), func(i int) bool { return ix.blocks[i].line >= line })
+}
+
+// end 返回块的结束行（下一个 Host 行或文件结尾）
+func (ix *hostIndex) end(b *hostBlock, totalLines int) int {
+	pos := ix.search(b.line)
+	if pos+1 < len(ix.blocks) {
+		return ix.blocks[pos+1].line
+	}
+	return totalLines
+}
+
+func (ix *hostIndex) add(b *hostBlock) {
+	pos := ix.search(b.line)
+	ix.blocks = append(ix.blocks, nil)
+	copy(ix.blocks[pos+1:], ix.blocks[pos:])
+	ix.blocks[pos] = b
+
+	for _, alias := range b.aliases {
+		list := ix.byAlias[alias]
+		i := sort.Search(len(list), func(i int) bool { return list[i].line >= b.line })
+		list = append(list, nil)
+		copy(list[i+1:], list[i:])
+		list[i] = b
+		ix.byAlias[alias] = list
+	}
+}
+
+func (ix *hostIndex) remove(b *hostBlock) {
+	if pos := ix.search(b.line); pos < len(ix.blocks) && ix.blocks[pos] == b {
+		ix.blocks = append(ix.blocks[:pos], ix.blocks[pos+1:]...)
+	}
+	for _, alias := range b.aliases {
+		list := ix.byAlias[alias]
+		for i, other := range list {
+			if other == b {
+				list = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(list) == 0 {
+			delete(ix.byAlias, alias)
+		} else {
+			ix.byAlias[alias] = list
+		}
+	}
+}
+
+// shift 将所有行号 >= from 的块平移 delta 行
+func (ix *hostIndex) shift(from, delta int) {
+	for _, b := range ix.blocks[ix.search(from):] {
+		b.line += delta
+	}
+}
+
+// getIndex 返回当前的索引，必要时重新构建
+func (m *SSHConfigManager) getIndex() *hostIndex {
+	if m.index == nil {
+		m.index = buildHostIndex(m.rawLines)
+	}
+	return m.index
+}
+
+// setLines 整体替换 rawLines，索引会在下次查找时重建
+func (m *SSHConfigManager) setLines(lines []string) {
+	m.rawLines = lines
+	m.index = nil
+}
+
+// setLine 替换一行并同步更新索引
+func (m *SSHConfigManager) setLine(i int, line string) {
+	old := m.rawLines[i]
+	m.rawLines[i] = line
+	if m.index == nil {
+		return
+	}
+	if _, ok := isHostLine(old); ok {
+		if pos := m.index.search(i); pos < len(m.index.blocks) && m.index.blocks[pos].line == i {
+			m.index.remove(m.index.blocks[pos])
+		}
+	}
+	if aliases, ok := isHostLine(line); ok {
+		m.index.add(&hostBlock{line: i, aliases: aliases})
+	}
+}
+
+// insertLines 在 pos 处插入若干行并同步更新索引
+func (m *SSHConfigManager) insertLines(pos int, lines ...string) {
+	if pos == len(m.rawLines) {
+		m.rawLines = append(m.rawLines, lines...)
+	} else {
+		newLines := make([]string, 0, len(m.rawLines)+len(lines))
+		newLines = append(newLines, m.rawLines[:pos]...)
+		newLines = append(newLines, lines...)
+		newLines = append(newLines, m.rawLines[pos:]...)
+		m.rawLines = newLines
+	}
+	if m.index == nil {
+		return
+	}
+	m.index.shift(pos, len(lines))
+	for i, line := range lines {
+		if aliases, ok := isHostLine(line); ok {
+			m.index.add(&hostBlock{line: pos + i, aliases: aliases})
+		}
+	}
+}
+
+// deleteLines 删除 [start, end) 范围内的行并同步更新索引
+func (m *SSHConfigManager) deleteLines(start, end int) {
+	m.rawLines = append(m.rawLines[:start], m.rawLines[end:]...)
+	if m.index == nil {
+		return
+	}
+	for pos := m.index.search(start); pos < len(m.index.blocks) && m.index.blocks[pos].line < end; {
+		m.index.remove(m.index.blocks[pos])
+	}
+	m.index.shift(end, start-end)
+}
@@ -0,0 +1,176 @@
+package sshconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// generateConfig 生成一个包含 n 个主机的配置，模拟 IaC 工具生成的大文件
+func generateConfig(n int) []string {
+	lines := []string{"# generated", "Host *", "    ServerAliveInterval 30", ""}
+	for i := 0; i < n; i++ {
+		lines = append(lines,
+			fmt.Sprintf("# node %d", i),
+			fmt.Sprintf("Host node-%d node-%d.internal", i, i),
+			fmt.Sprintf("    HostName 10.0.%d.%d", i/256, i%256),
+			"    User deploy",
+			"",
+		)
+	}
+	return lines
+}
+
+// assertIndexConsistent 检查增量维护的索引与重新构建的索引一致
+func assertIndexConsistent(t *testing.T, m *SSHConfigManager) {
+	t.Helper()
+	got := m.getIndex()
+	want := buildHostIndex(m.rawLines)
+
+	if len(got.blocks) != len(want.blocks) {
+		t.Fatalf("Expected %d blocks, got %d", len(want.blocks), len(got.blocks))
+	}
+	for i := range want.blocks {
+		if !reflect.DeepEqual(*got.blocks[i], *want.blocks[i]) {
+			t.Fatalf("Block %d mismatch: expected %+v, got %+v", i, *want.blocks[i], *got.blocks[i])
+		}
+	}
+	if len(got.byAlias) != len(want.byAlias) {
+		t.Fatalf("Expected %d aliases, got %d", len(want.byAlias), len(got.byAlias))
+	}
+	for alias, blocks := range want.byAlias {
+		gotBlocks := got.byAlias[alias]
+		if len(gotBlocks) != len(blocks) {
+			t.Fatalf("Alias %s: expected %d blocks, got %d", alias, len(blocks), len(gotBlocks))
+		}
+		for i := range blocks {
+			if gotBlocks[i].line != blocks[i].line {
+				t.Fatalf("Alias %s: expected line %d, got %d", alias, blocks[i].line, gotBlocks[i].line)
+			}
+		}
+	}
+}
+
+// TestHostIndex_IncrementalEdits 测试编辑后索引保持一致
+func TestHostIndex_IncrementalEdits(t *testing.T) {
+	m := &SSHConfigManager{rawLines: generateConfig(50)}
+	assertIndexConsistent(t, m)
+
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"SetParam existing", func() error { return m.SetParam("node-10", "User", "root") }},
+		{"SetParam new key", func() error { return m.SetParam("node-3", "Port", "2222") }},
+		{"SetParam new host", func() error { return m.SetParam("fresh", "HostName", "fresh.example.com") }},
+		{"RemoveParam", func() error { return m.RemoveParam("node-20", "User") }},
+		{"RemoveHost", func() error { return m.RemoveHost("node-5") }},
+		{"RenameHost", func() error { return m.RenameHost("node-7", "renamed-7") }},
+		{"AddComment", func() error { return m.AddComment("node-30", "important") }},
+		{"AddInclude", func() error { m.AddInclude("~/.ssh/conf.d/*"); return nil }},
+		{"AddHost", func() error { m.AddHost("node-new"); return nil }},
+		{"ReorderHosts", func() error { return m.ReorderHosts([]string{"node-40", "fresh", "node-1"}) }},
+		{"RemoveHost after reorder", func() error { return m.RemoveHost("node-40") }},
+	}
+	for _, step := range steps {
+		if err := step.fn(); err != nil {
+			t.Fatalf("%s failed: %v", step.name, err)
+		}
+		assertIndexConsistent(t, m)
+	}
+
+	if m.HasHost("node-5") {
+		t.Error("node-5 should have been removed")
+	}
+	if !m.HasHost("renamed-7") {
+		t.Error("renamed-7 should exist")
+	}
+	if v, err := m.GetParam("node-10", "User"); err != nil || v != "root" {
+		t.Errorf("Expected node-10 User root, got %q (%v)", v, err)
+	}
+}
+
+// TestHostIndex_DuplicateAliasUsesFirstBlock 测试重复别名时返回文件中的第一个块
+func TestHostIndex_DuplicateAliasUsesFirstBlock(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host dup",
+		"    HostName first.example.com",
+		"Host dup",
+		"    HostName second.example.com",
+	}}
+
+	if v, _ := m.GetParam("dup", "HostName"); v != "first.example.com" {
+		t.Errorf("Expected first.example.com, got %q", v)
+	}
+	if err := m.RemoveHost("dup"); err != nil {
+		t.Fatalf("RemoveHost failed: %v", err)
+	}
+	assertIndexConsistent(t, m)
+	if v, _ := m.GetParam("dup", "HostName"); v != "second.example.com" {
+		t.Errorf("Expected second.example.com after removing the first block, got %q", v)
+	}
+}
+
+// TestHostIndex_WildcardMatch 测试精确匹配失败时回退到通配符匹配
+func TestHostIndex_WildcardMatch(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host *",
+		"    User global",
+		"Host *.prod",
+		"    User prod",
+		"Host web.prod",
+		"    User web",
+	}}
+
+	if v, _ := m.GetParam("web.prod", "User"); v != "web" {
+		t.Errorf("Expected exact match to win, got %q", v)
+	}
+	if v, _ := m.GetParam("db.prod", "User"); v != "prod" {
+		t.Errorf("Expected wildcard match, got %q", v)
+	}
+	if m.HasHost("db.staging") {
+		t.Error("Host * should not be matched implicitly")
+	}
+}
+
+// TestGetAllHosts_LargeConfig 测试大配置文件的主机列表
+func TestGetAllHosts_LargeConfig(t *testing.T) {
+	m := &SSHConfigManager{rawLines: generateConfig(2000)}
+
+	hosts, err := m.GetAllHosts()
+	if err != nil {
+		t.Fatalf("GetAllHosts failed: %v", err)
+	}
+	// Host * 加上每个主机的两个别名
+	if len(hosts) != 1+2*2000 {
+		t.Errorf("Expected %d hosts, got %d", 1+2*2000, len(hosts))
+	}
+	last := hosts[len(hosts)-1]
+	if last.Name != "node-1999.internal" || last.Params["HostName"][0].Value != "10.0.7.207" {
+		t.Errorf("Unexpected last host: %s %v", last.Name, last.Params["HostName"])
+	}
+	if !strings.Contains(last.Description, "node 1999") {
+		t.Errorf("Expected description 'node 1999', got %q", last.Description)
+	}
+}
+
+func BenchmarkGetAllHosts_2000Hosts(b *testing.B) {
+	m := &SSHConfigManager{rawLines: generateConfig(2000)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.GetAllHosts(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetParam_2000Hosts(b *testing.B) {
+	m := &SSHConfigManager{rawLines: generateConfig(2000)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := m.SetParam(fmt.Sprintf("node-%d", i%2000), "Port", "22"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
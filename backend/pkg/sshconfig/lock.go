@@ -0,0 +1,136 @@
+package sshconfig
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// LockTag 写在 Host 块的描述注释或块内注释中，将该块标记为只读，例如 "# Managed by IT @locked"
+const LockTag = "@locked"
+
+// ErrHostLocked 表示主机所在的 Host 块是只读的，可以用 errors.Is(err, ErrHostLocked) 判断
+var ErrHostLocked = errors.New("host is locked")
+
+// HostLockedError 表示修改被拒绝，因为别名所在的 Host 块带有 LockTag 或在锁定列表中
+type HostLockedError struct {
+	Alias string
+}
+
+func (e *HostLockedError) Error() string {
+	return fmt.Sprintf("host '%s' is locked and cannot be modified", e.Alias)
+}
+
+// Is 让 errors.Is(err, ErrHostLocked) 成立
+func (e *HostLockedError) Is(target error) bool {
+	return target == ErrHostLocked
+}
+
+// SetLockedHosts 设置额外锁定的别名（例如来自应用配置），锁定整个包含该别名的 Host 块
+func (m *SSHConfigManager) SetLockedHosts(aliases []string) {
+	m.lockedAliases = make(map[string]bool, len(aliases))
+	for _, alias := range aliases {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			m.lockedAliases[alias] = true
+		}
+	}
+}
+
+// IsHostLocked 判断 alias 所在的 Host 块是否只读。与 SetParam 等修改方法一样，没有精确匹配时按通配块查找。
+func (m *SSHConfigManager) IsHostLocked(alias string) bool {
+	start, end, found := m.findHost(alias)
+	return found && m.blockLocked(start, end)
+}
+
+// LockedHosts 返回所有只读 Host 块中的别名，按文件顺序排列
+func (m *SSHConfigManager) LockedHosts() []string {
+	ix := m.getIndex()
+	var aliases []string
+	for _, b := range ix.blocks {
+		if m.blockLocked(b.line, ix.end(b, len(m.rawLines))) {
+			aliases = append(aliases, b.aliases...)
+		}
+	}
+	return aliases
+}
+
+// checkWritable 在 hostname 所在的块只读时返回 *HostLockedError；主机不存在时返回 nil，由调用方处理
+func (m *SSHConfigManager) checkWritable(hostname string) error {
+	if m.IsHostLocked(hostname) {
+		return &HostLockedError{Alias: hostname}
+	}
+	return nil
+}
+
+// blockLocked 判断从 hostLine 开始、到 end 结束的 Host 块是否只读
+func (m *SSHConfigManager) blockLocked(hostLine, end int) bool {
+	if aliases, ok := isHostLine(m.rawLines[hostLine]); ok && slices.ContainsFunc(aliases, func(a string) bool { return m.lockedAliases[a] }) {
+		return true
+	}
+	r := m.blockRangeAt(hostLine, end)
+	return slices.ContainsFunc(m.rawLines[r.descStart:r.bodyEnd], hasLockTag)
+}
+
+// lineLocked 判断第 line 行（从 0 开始）是否属于一个只读的 Host 块
+func (m *SSHConfigManager) lineLocked(line int) bool {
+	ix := m.getIndex()
+	pos := ix.search(line + 1)
+	if pos == 0 {
+		return false // 位于第一个 Host 块之前
+	}
+	b := ix.blocks[pos-1]
+	return m.blockLocked(b.line, ix.end(b, len(m.rawLines)))
+}
+
+// hasLockTag 判断一行是否为带有 LockTag 的注释
+func hasLockTag(line string) bool {
+	comment, ok := strings.CutPrefix(strings.TrimSpace(line), "#")
+	return ok && slices.ContainsFunc(strings.Fields(comment), func(f string) bool { return strings.EqualFold(f, LockTag) })
+}
+
+// CheckLockedBlocks 检查用 lines 整体替换配置（例如在原始文本编辑器中保存）时，只读的 Host 块是否保持不变。
+// 被删除或修改的只读块返回 *HostLockedError。块的位置可以变化。
+func (m *SSHConfigManager) CheckLockedBlocks(lines []string) error {
+	next := &SSHConfigManager{rawLines: lines, lockedAliases: m.lockedAliases}
+	nextIx := next.getIndex()
+	ix := m.getIndex()
+	for _, b := range ix.blocks {
+		end := ix.end(b, len(m.rawLines))
+		if !m.blockLocked(b.line, end) {
+			continue
+		}
+		locked := &HostLockedError{Alias: strings.TrimSpace(m.rawLines[b.line])}
+		if len(b.aliases) > 0 {
+			locked.Alias = b.aliases[0]
+		}
+		want := m.blockContent(b.line, end)
+
+		var found bool
+		for _, candidate := range nextIx.blocks {
+			if slices.Equal(next.blockContent(candidate.line, nextIx.end(candidate, len(lines))), want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return locked
+		}
+	}
+	return nil
+}
+
+// blockContent 返回 Host 块从描述注释到最后一个参数的内容，忽略行尾空白。
+// 块末尾的空行与注释（LockTag 除外）不计入：它们在块后面插入新块时会变成新块的描述。
+func (m *SSHConfigManager) blockContent(hostLine, end int) []string {
+	r := m.blockRangeAt(hostLine, end)
+	last := r.bodyEnd
+	for last > hostLine+1 && (isBlankLine(m.rawLines[last-1]) || isCommentLine(m.rawLines[last-1]) && !hasLockTag(m.rawLines[last-1])) {
+		last--
+	}
+	content := make([]string, 0, last-r.descStart)
+	for _, line := range m.rawLines[r.descStart:last] {
+		content = append(content, strings.TrimRight(line, " \t\r"))
+	}
+	return content
+}
@@ -0,0 +1,102 @@
+package sshconfig
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newLockTestManager() *SSHConfigManager {
+	return &SSHConfigManager{rawLines: []string{
+		"# Managed by IT @locked",
+		"Host bastion",
+		"    HostName 10.0.0.1",
+		"",
+		"Host web www",
+		"    HostName 10.0.0.5",
+		"    ProxyJump bastion",
+		"",
+		"Host db",
+		"    HostName 10.0.0.6",
+	}}
+}
+
+// TestLockedHosts_RejectMutations 测试带有 LockTag 或在锁定列表中的块无法被修改
+func TestLockedHosts_RejectMutations(t *testing.T) {
+	m := newLockTestManager()
+	m.SetLockedHosts([]string{"www"})
+	before := append([]string(nil), m.rawLines...)
+
+	for _, alias := range []string{"bastion", "web", "www"} {
+		if !m.IsHostLocked(alias) {
+			t.Errorf("Expected %s to be locked", alias)
+		}
+		checks := map[string]error{
+			"SetParam":   m.SetParam(alias, "User", "root"),
+			"RemoveHost": m.RemoveHost(alias),
+			"RenameHost": m.RenameHost(alias, alias+"-new"),
+		}
+		for op, err := range checks {
+			if !errors.Is(err, ErrHostLocked) {
+				t.Errorf("%s(%s): expected ErrHostLocked, got %v", op, alias, err)
+			}
+		}
+	}
+	if !reflect.DeepEqual(m.rawLines, before) {
+		t.Fatalf("Locked blocks should be unchanged:\n%s", strings.Join(m.rawLines, "\n"))
+	}
+	if got := m.LockedHosts(); !reflect.DeepEqual(got, []string{"bastion", "web", "www"}) {
+		t.Errorf("Unexpected locked hosts: %v", got)
+	}
+
+	if m.IsHostLocked("db") {
+		t.Error("Expected db to be writable")
+	}
+	if err := m.SetParam("db", "User", "postgres"); err != nil {
+		t.Errorf("SetParam on an unlocked host failed: %v", err)
+	}
+}
+
+// TestDuplicateHost_DropsLockTag 测试只读块的副本可以编辑
+func TestDuplicateHost_DropsLockTag(t *testing.T) {
+	m := newLockTestManager()
+	if err := m.DuplicateHost("bastion", "bastion2"); err != nil {
+		t.Fatalf("DuplicateHost failed: %v", err)
+	}
+	assertIndexConsistent(t, m)
+	if m.IsHostLocked("bastion2") {
+		t.Fatalf("The copy should not be locked:\n%s", strings.Join(m.rawLines, "\n"))
+	}
+	if err := m.SetParam("bastion2", "User", "admin"); err != nil {
+		t.Errorf("SetParam on the copy failed: %v", err)
+	}
+}
+
+// TestCheckLockedBlocks 测试整体替换配置时，只读块可以移动但不能修改或删除
+func TestCheckLockedBlocks(t *testing.T) {
+	m := newLockTestManager()
+
+	moved := []string{
+		"Host db",
+		"    HostName 10.0.0.7",
+		"",
+		"# Managed by IT @locked",
+		"Host bastion",
+		"    HostName 10.0.0.1   ",
+	}
+	if err := m.CheckLockedBlocks(moved); err != nil {
+		t.Errorf("Moving a locked block should be allowed: %v", err)
+	}
+
+	modified := append([]string(nil), m.rawLines...)
+	modified[2] = "    HostName 10.0.0.2"
+	var locked *HostLockedError
+	if err := m.CheckLockedBlocks(modified); !errors.As(err, &locked) || locked.Alias != "bastion" {
+		t.Errorf("Expected bastion to be reported as locked, got %v", err)
+	}
+
+	if err := m.CheckLockedBlocks(m.rawLines[4:]); !errors.Is(err, ErrHostLocked) {
+		t.Errorf("Removing a locked block should fail, got %v", err)
+	}
+}
@@ -2,30 +2,64 @@ package sshconfig
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
+	"slices"
 	"strings"
-	"time"
 )
 
+// maxLineSize 是配置文件单行的最大长度
+const maxLineSize = 1024 * 1024
+
+// errNoStorage 表示管理器没有关联存储（例如由 Parse 创建），不能保存
+var errNoStorage = errors.New("config is not backed by a file or storage")
+
 // SSHConfigManager SSH配置管理器
 type SSHConfigManager struct {
-	filename string
+	filename string  // 存储的位置（文件存储为文件路径），用于解析 Include 与定位引用
+	storage  Storage // 持久化后端，为 nil 时使用 filename 对应的文件（filename 也为空时不能保存）
 	rawLines []string
+	index    *hostIndex // Host 块索引，nil 表示需要重建
+
+	warningsBlockSave bool             // Save 在有警告时也拒绝写入
+	rules             []ValidationRule // Save 时额外执行的校验规则，见 SetValidationRules
+
+	lockedAliases map[string]bool // 额外锁定的别名，见 SetLockedHosts
 }
 
 // HostConfig 主机配置
 type HostConfig struct {
 	Name        string
 	Params      map[string][]Param // 支持多个相同key的参数
+	Ordered     []Param            // 与 Params 相同的参数，按文件中出现的顺序排列
 	Description string             // Host块的描述信息
 	IsGlobal    bool               // 是否为全局配置 (Host *)
 }
 
+// OrderedParams 返回按文件中出现顺序排列的全部参数（同名参数各占一项）
+func (h *HostConfig) OrderedParams() []Param {
+	return append([]Param(nil), h.Ordered...)
+}
+
+// Keys 返回参数名，按每个参数第一次出现的顺序排列
+func (h *HostConfig) Keys() []string {
+	keys := make([]string, 0, len(h.Params))
+	seen := make(map[string]bool, len(h.Params))
+	for _, p := range h.Ordered {
+		if !seen[p.Key] {
+			seen[p.Key] = true
+			keys = append(keys, p.Key)
+		}
+	}
+	return keys
+}
+
 // Param 配置参数
 type Param struct {
 	Key   string
@@ -44,66 +78,137 @@ func (e *ConfigError) Error() string {
 	return fmt.Sprintf("ssh config %s: %v", e.Op, e.Err)
 }
 
-// NewManager 创建新的配置管理器
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// NewManager 创建新的配置管理器，配置保存在 filename 文件中。filename 为空时创建一个不能保存的空配置。
 func NewManager(filename string) (*SSHConfigManager, error) {
-	expandedPath := expandHomeDir(filename)
+	if filename == "" {
+		manager := &SSHConfigManager{}
+		manager.setLines([]string{})
+		return manager, nil
+	}
+	return NewManagerWithStorage(NewFileStorage(filename))
+}
 
+// NewManagerWithStorage 创建使用指定存储的配置管理器，存储中还没有配置时从空配置开始
+func NewManagerWithStorage(storage Storage) (*SSHConfigManager, error) {
 	manager := &SSHConfigManager{
-		filename: expandedPath,
+		filename: storage.Location(),
+		storage:  storage,
 	}
 
 	err := manager.Load()
-	if err != nil && !os.IsNotExist(err) {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return nil, &ConfigError{"load", err}
 	}
 
-	if os.IsNotExist(err) {
-		manager.rawLines = []string{}
+	if errors.Is(err, fs.ErrNotExist) {
+		manager.setLines([]string{})
 	}
 
 	return manager, nil
 }
 
-// Load 加载配置文件
+// store 返回配置的存储：没有指定存储时使用 filename 对应的文件，两者都没有时返回 nil
+func (m *SSHConfigManager) store() Storage {
+	if m.storage != nil {
+		return m.storage
+	}
+	if m.filename == "" {
+		return nil
+	}
+	return &FileStorage{path: m.filename}
+}
+
+// Load 从存储中重新加载配置
 func (m *SSHConfigManager) Load() error {
-	file, err := os.Open(m.filename)
+	storage := m.store()
+	if storage == nil {
+		return &fs.PathError{Op: "open", Path: "", Err: fs.ErrNotExist}
+	}
+	content, err := storage.Load()
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
 
-	if err := scanner.Err(); err != nil {
+	lines, err := scanLines(bytes.NewReader(content))
+	if err != nil {
 		return err
 	}
 
-	m.rawLines = lines
+	m.setLines(lines)
 	return nil
 }
 
+// Parse 从内存中的配置内容创建一个不关联存储的管理器，用于读取其他来源（例如团队共享）的配置片段。
+// 这样的管理器不能 Save；需要保存时使用 NewManagerWithStorage。
+func Parse(content string) (*SSHConfigManager, error) {
+	lines, err := scanLines(strings.NewReader(content))
+	if err != nil {
+		return nil, &ConfigError{"parse", err}
+	}
+	manager := &SSHConfigManager{}
+	manager.setLines(lines)
+	return manager, nil
+}
+
+// scanLines 按行读取配置内容
+func scanLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	// 允许超长行（例如很长的 ProxyCommand），默认上限只有 64KB
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
 // Save 保存配置到文件
 func (m *SSHConfigManager) Save() error {
+	_, err := m.SaveWithDiagnostics()
+	return err
+}
+
+// SetWarningsBlockSave 设置 Save 是否在有警告（例如未知参数、重复参数）时也拒绝写入。
+// 默认只有错误会阻止保存，这样 ssh 能够读取的旧配置也可以在应用中编辑。
+func (m *SSHConfigManager) SetWarningsBlockSave(block bool) {
+	m.warningsBlockSave = block
+}
+
+// SetValidationRules 设置 Save 时在内置语法检查之后额外执行的校验规则，错误级别的诊断会阻止写入
+func (m *SSHConfigManager) SetValidationRules(rules []ValidationRule) {
+	m.rules = slices.Clone(rules)
+}
+
+// SaveWithDiagnostics 校验并保存配置，无论是否写入都返回完整的诊断列表。
+// 校验阻止写入时返回 *ValidationError（可以用 errors.As 取得其中的 *ConfigError）。
+func (m *SSHConfigManager) SaveWithDiagnostics() ([]Diagnostic, error) {
+	storage := m.store()
+	if storage == nil {
+		return nil, &ConfigError{"write", errNoStorage}
+	}
 	content := m.BuildConfig()
-	err := m.Validate()
-	if err != nil {
-		return err
+	validator := NewConfigValidator(m.rawLines)
+	for _, rule := range m.rules {
+		validator.AddRule(rule)
 	}
-
-	dir := filepath.Dir(m.filename)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return &ConfigError{"mkdir", err}
+	diagnostics, err := validator.Check(m.warningsBlockSave)
+	if err != nil {
+		return diagnostics, err
 	}
 
-	if err := os.WriteFile(m.filename, []byte(content), 0o600); err != nil {
-		return &ConfigError{"write", err}
+	if err := storage.Save([]byte(content)); err != nil {
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			err = &ConfigError{"write", err}
+		}
+		return diagnostics, err
 	}
 
-	return nil
+	return diagnostics, nil
 }
 
 // BuildConfig 构建配置文件内容
@@ -128,8 +233,7 @@ func (m *SSHConfigManager) GetHost(alias string) (*HostConfig, error) {
 	}
 
 	// 检查是否为全局配置
-	line := strings.TrimSpace(m.rawLines[hostStart])
-	if strings.ToLower(line) == "host *" {
+	if hostPart, _ := cutDirective(m.rawLines[hostStart], "Host"); hostPart == "*" {
 		hostConfig.IsGlobal = true
 	}
 
@@ -154,6 +258,13 @@ func (m *SSHConfigManager) GetHost(alias string) (*HostConfig, error) {
 	}
 
 	// 解析主机参数
+	m.parseHostParams(hostConfig, hostStart, hostEnd)
+
+	return hostConfig, nil
+}
+
+// parseHostParams 解析 [hostStart+1, hostEnd) 中的参数，填入 Params 与 Ordered
+func (m *SSHConfigManager) parseHostParams(hostConfig *HostConfig, hostStart, hostEnd int) {
 	for i := hostStart + 1; i < hostEnd && i < len(m.rawLines); i++ {
 		line := m.rawLines[i]
 		trimmed := strings.TrimSpace(line)
@@ -162,37 +273,33 @@ func (m *SSHConfigManager) GetHost(alias string) (*HostConfig, error) {
 		}
 
 		// 跳过Include等特殊指令
-		if strings.HasPrefix(trimmed, "Host ") || strings.HasPrefix(trimmed, "Include ") {
+		if isDirective(trimmed, "Host", "Include") {
 			break
 		}
 
 		if key, value := parseParamLine(trimmed); key != "" {
-			hostConfig.Params[key] = append(hostConfig.Params[key], Param{
+			param := Param{
 				Key:   key,
 				Value: value,
 				Line:  i,
 				Raw:   line,
-			})
+			}
+			hostConfig.Params[key] = append(hostConfig.Params[key], param)
+			hostConfig.Ordered = append(hostConfig.Ordered, param)
 		}
 	}
-
-	return hostConfig, nil
 }
 
 // GetAllHosts 获取所有主机配置（包括全局配置）
 func (m *SSHConfigManager) GetAllHosts() ([]*HostConfig, error) {
 	var hosts []*HostConfig
 
-	for i := 0; i < len(m.rawLines); i++ {
-		line := strings.TrimSpace(m.rawLines[i])
-		if strings.HasPrefix(line, "Host ") {
-			hostAliases := parseHostNames(strings.TrimPrefix(line, "Host"))
-			for _, hostAlias := range hostAliases {
-				// 处理所有主机，包括全局配置
-				host, err := m.GetHost(hostAlias)
-				if err == nil {
-					hosts = append(hosts, host)
-				}
+	for _, b := range m.getIndex().blocks {
+		for _, hostAlias := range b.aliases {
+			// 处理所有主机，包括全局配置
+			host, err := m.GetHost(hostAlias)
+			if err == nil {
+				hosts = append(hosts, host)
 			}
 		}
 	}
@@ -218,27 +325,7 @@ func (m *SSHConfigManager) GetGlobalConfig() (*HostConfig, error) {
 	}
 
 	// 解析全局参数
-	for i := hostStart + 1; i < hostEnd && i < len(m.rawLines); i++ {
-		line := m.rawLines[i]
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-
-		// 跳过Include等特殊指令
-		if strings.HasPrefix(trimmed, "Host ") || strings.HasPrefix(trimmed, "Include ") {
-			break
-		}
-
-		if key, value := parseParamLine(trimmed); key != "" {
-			hostConfig.Params[key] = append(hostConfig.Params[key], Param{
-				Key:   key,
-				Value: value,
-				Line:  i,
-				Raw:   line,
-			})
-		}
-	}
+	m.parseHostParams(hostConfig, hostStart, hostEnd)
 
 	return hostConfig, nil
 }
@@ -252,22 +339,38 @@ func (m *SSHConfigManager) AddHost(hostname string) *HostConfig {
 
 	// 如果文件不为空且最后一行不是空行，添加空行分隔
 	if len(m.rawLines) > 0 && strings.TrimSpace(m.rawLines[len(m.rawLines)-1]) != "" {
-		m.rawLines = append(m.rawLines, "")
+		m.insertLines(len(m.rawLines), "")
 	}
 
 	// 添加Host行
 	hostLine := fmt.Sprintf("Host %s", hostname)
-	m.rawLines = append(m.rawLines, hostLine)
+	m.insertLines(len(m.rawLines), hostLine)
 
 	return hostConfig
 }
 
+// AddHostUnique 以 UniqueAlias(baseName) 生成的别名在文件末尾添加主机，并返回该别名。
+// 导入、复制等需要自动命名的流程应使用它，而不是自行用 HasHost 循环检查冲突。
+func (m *SSHConfigManager) AddHostUnique(baseName string) (string, error) {
+	baseName = strings.TrimSpace(baseName)
+	if baseName == "" || strings.ContainsAny(baseName, " \t*?!") {
+		return "", &ConfigError{"add_host", fmt.Errorf("invalid host alias '%s'", baseName)}
+	}
+	alias := m.UniqueAlias(baseName)
+	m.AddHost(alias)
+	return alias, nil
+}
+
 // SetParam 设置主机参数
 func (m *SSHConfigManager) SetParam(hostname, key, value string) error {
 	if hostname == "" || key == "" {
 		return &ConfigError{"set_param", fmt.Errorf("hostname and key cannot be empty")}
 	}
 
+	if err := m.checkWritable(hostname); err != nil {
+		return err
+	}
+
 	hostStart, hostEnd, found := m.findHost(hostname)
 	if !found {
 		// 如果主机不存在，先添加主机
@@ -280,20 +383,12 @@ func (m *SSHConfigManager) SetParam(hostname, key, value string) error {
 	if paramLine != -1 {
 		// 更新现有参数
 		indent := getLineIndent(m.rawLines[paramLine])
-		m.rawLines[paramLine] = fmt.Sprintf("%s%s %s", indent, key, value)
+		m.setLine(paramLine, fmt.Sprintf("%s%s %s", indent, key, formatParamValue(key, value)))
 	} else {
 		// 添加新参数（在Host行之后）
-		newLine := fmt.Sprintf("  %s %s", key, value)
-		insertPos := hostStart + 1
-		if insertPos >= len(m.rawLines) {
-			m.rawLines = append(m.rawLines, newLine)
-		} else {
-			// 在Host块中插入新行
-			lines := append([]string{}, m.rawLines[:insertPos]...)
-			lines = append(lines, newLine)
-			lines = append(lines, m.rawLines[insertPos:]...)
-			m.rawLines = lines
-		}
+		newLine := fmt.Sprintf("  %s %s", key, formatParamValue(key, value))
+		insertPos := min(hostStart+1, len(m.rawLines))
+		m.insertLines(insertPos, newLine)
 	}
 
 	return nil
@@ -309,11 +404,14 @@ func (m *SSHConfigManager) RemoveParam(hostname, key string) error {
 	if !found {
 		return &ConfigError{"remove_param", fmt.Errorf("host %s not found", hostname)}
 	}
+	if m.blockLocked(hostStart, hostEnd) {
+		return &HostLockedError{Alias: hostname}
+	}
 
 	paramLine := m.findParamInHost(hostStart, hostEnd, key)
 	if paramLine != -1 {
 		// 删除参数行
-		m.rawLines = append(m.rawLines[:paramLine], m.rawLines[paramLine+1:]...)
+		m.deleteLines(paramLine, paramLine+1)
 	}
 
 	return nil
@@ -329,6 +427,9 @@ func (m *SSHConfigManager) RemoveHost(hostname string) error {
 	if !found {
 		return &ConfigError{"remove_host", fmt.Errorf("host %s not found", hostname)}
 	}
+	if m.blockLocked(hostStart, hostEnd) {
+		return &HostLockedError{Alias: hostname}
+	}
 
 	// 删除主机块（包括前后空行）
 	start := hostStart
@@ -344,27 +445,27 @@ func (m *SSHConfigManager) RemoveHost(hostname string) error {
 		end++
 	}
 
-	m.rawLines = append(m.rawLines[:start], m.rawLines[end:]...)
+	m.deleteLines(start, end)
 	return nil
 }
 
 // RenameHost renames a host alias in the configuration.
 // It handles hosts defined with multiple aliases on the same line.
 func (m *SSHConfigManager) RenameHost(oldName, newName string) error {
-	hostStart, _, found := m.findHost(oldName)
+	hostStart, hostEnd, found := m.findHost(oldName)
 	if !found {
 		return &HostNotFoundError{Alias: oldName}
 	}
+	if m.blockLocked(hostStart, hostEnd) {
+		return &HostLockedError{Alias: oldName}
+	}
 
 	hostLine := m.rawLines[hostStart]
-	prefix := "Host "
-	trimmedLine := strings.TrimSpace(hostLine)
-
-	if !strings.HasPrefix(trimmedLine, prefix) {
+	hostPart, ok := cutDirective(hostLine, "Host")
+	if !ok {
 		return fmt.Errorf("internal error: line %d is not a valid Host line: %s", hostStart+1, hostLine)
 	}
 
-	hostPart := strings.TrimPrefix(trimmedLine, prefix)
 	hostNames := parseHostNames(hostPart)
 
 	foundInLine := false
@@ -381,7 +482,7 @@ func (m *SSHConfigManager) RenameHost(oldName, newName string) error {
 	}
 
 	indent := getLineIndent(hostLine)
-	m.rawLines[hostStart] = indent + prefix + strings.Join(hostNames, " ")
+	m.setLine(hostStart, indent+"Host "+joinHostNames(hostNames))
 	return nil
 }
 
@@ -403,7 +504,7 @@ func (m *SSHConfigManager) GetParam(hostname, key string) (string, error) {
 			continue
 		}
 
-		if strings.HasPrefix(line, "Host ") || strings.HasPrefix(line, "Include ") {
+		if isDirective(line, "Host", "Include") {
 			break
 		}
 
@@ -417,22 +518,39 @@ func (m *SSHConfigManager) GetParam(hostname, key string) (string, error) {
 	return "", &ConfigError{"get_param", fmt.Errorf("parameter %s not found for host %s", key, hostname)}
 }
 
-// HasHost 检查主机是否存在
+// HasHost 检查主机是否存在。与 GetHost 一致，被通配符块（例如 Host *.corp）匹配的名称也算存在；
+// 只想知道别名是否已被占用时使用 HasHostExact。
 func (m *SSHConfigManager) HasHost(hostname string) bool {
 	_, _, found := m.findHost(hostname)
 	return found
 }
 
+// HasHostExact 检查是否有 Host 块以 alias 作为别名（不做通配符匹配）
+func (m *SSHConfigManager) HasHostExact(alias string) bool {
+	return len(m.getIndex().byAlias[alias]) > 0
+}
+
+// HasHostPattern 检查是否有通配符块（不包括单独的 *）匹配 alias，不论 alias 本身是否存在
+func (m *SSHConfigManager) HasHostPattern(alias string) bool {
+	return findPatternBlock(m.getIndex(), alias) != nil
+}
+
+// UniqueAlias 返回以 baseName 为基础、尚未被任何 Host 块使用的别名：
+// baseName 本身、baseName-2、baseName-3……只与精确的别名比较，被通配符块匹配不算冲突。
+func (m *SSHConfigManager) UniqueAlias(baseName string) string {
+	alias := baseName
+	for i := 2; m.HasHostExact(alias); i++ {
+		alias = fmt.Sprintf("%s-%d", baseName, i)
+	}
+	return alias
+}
+
 // GetHostNames 获取所有主机名（包括*）
 func (m *SSHConfigManager) GetHostNames() ([]string, error) {
 	var hostNames []string
 
-	for i := 0; i < len(m.rawLines); i++ {
-		line := strings.TrimSpace(m.rawLines[i])
-		if strings.HasPrefix(line, "Host ") {
-			names := parseHostNames(strings.TrimPrefix(line, "Host"))
-			hostNames = append(hostNames, names...)
-		}
+	for _, b := range m.getIndex().blocks {
+		hostNames = append(hostNames, b.aliases...)
 	}
 
 	return hostNames, nil
@@ -440,18 +558,18 @@ func (m *SSHConfigManager) GetHostNames() ([]string, error) {
 
 // AddComment 为主机添加注释
 func (m *SSHConfigManager) AddComment(hostname, comment string) error {
-	hostStart, _, found := m.findHost(hostname)
+	hostStart, hostEnd, found := m.findHost(hostname)
 	if !found {
 		return &ConfigError{"add_comment", fmt.Errorf("host %s not found", hostname)}
 	}
+	if m.blockLocked(hostStart, hostEnd) {
+		return &HostLockedError{Alias: hostname}
+	}
 
 	commentLine := fmt.Sprintf("# %s", comment)
 
 	// 在Host行之前插入注释
-	lines := append([]string{}, m.rawLines[:hostStart]...)
-	lines = append(lines, commentLine)
-	lines = append(lines, m.rawLines[hostStart:]...)
-	m.rawLines = lines
+	m.insertLines(hostStart, commentLine)
 
 	return nil
 }
@@ -479,68 +597,18 @@ func (m *SSHConfigManager) ReorderHosts(orderedAliases []string) error {
 
 	var sortableBlocks []hostBlock
 	var globalBlocks [][]string // For Host * and other non-sortable blocks
-	var headerContent []string  // For content before the first Host directive
-
-	// --- Partitioning from bottom to top with greedy upward scan for comments ---
-	blockEndIdx := len(m.rawLines)
-	for i := len(m.rawLines) - 1; i >= 0; i-- {
-		line := m.rawLines[i]
-		trimmedLine := strings.TrimSpace(line)
-
-		isHostDirective := strings.HasPrefix(trimmedLine, "Host ")
-		isIncludeDirective := strings.HasPrefix(trimmedLine, "Include ")
-		isMatchDirective := strings.HasPrefix(trimmedLine, "Match ")
-
-		if isHostDirective || isIncludeDirective || isMatchDirective {
-			// Found an anchor. Now, perform the greedy upward scan to find the true start of this block.
-			blockStartIdx := i
-			for j := i - 1; j >= 0; j-- {
-				prevLine := m.rawLines[j]
-				trimmedPrevLine := strings.TrimSpace(prevLine)
-				if trimmedPrevLine == "" || strings.HasPrefix(trimmedPrevLine, "#") {
-					// This line is part of the preamble, so we continue scanning up.
-					blockStartIdx = j
-				} else {
-					// We've hit a line that's not a comment or blank, so the preamble ends.
-					break
-				}
-			}
 
-			// We have now defined a complete block from blockStartIdx to blockEndIdx.
-			block := m.rawLines[blockStartIdx:blockEndIdx]
-
-			// Classify the block.
-			if isIncludeDirective || isMatchDirective {
-				globalBlocks = append(globalBlocks, block)
-			} else {
-				// It's a Host directive.
-				hostLine := strings.TrimSpace(m.rawLines[i])
-				aliases := parseHostNames(strings.TrimPrefix(hostLine, "Host "))
-				isSortable := len(aliases) > 0 && !strings.Contains(aliases[0], "*")
-
-				if isSortable {
-					sortableBlocks = append(sortableBlocks, hostBlock{primaryAlias: aliases[0], aliases: aliases, content: block})
-				} else {
-					// It's a "Host *" or malformed Host line.
-					globalBlocks = append(globalBlocks, block)
-				}
-			}
-
-			// Prepare for the next iteration.
-			blockEndIdx = blockStartIdx
-			i = blockStartIdx // The loop will decrement it to blockStartIdx - 1
+	// Each block starts with the comments and blank lines right above its directive.
+	headerContent, blocks := m.splitBlocks()
+	for _, b := range blocks {
+		if b.isHost() && len(b.aliases) > 0 && !strings.Contains(b.aliases[0], "*") {
+			sortableBlocks = append(sortableBlocks, hostBlock{primaryAlias: b.aliases[0], aliases: b.aliases, content: b.lines})
+		} else {
+			// Include, Match, "Host *" or a malformed Host line.
+			globalBlocks = append(globalBlocks, b.lines)
 		}
 	}
 
-	// The content before the first processed block is the header.
-	if blockEndIdx > 0 {
-		headerContent = m.rawLines[0:blockEndIdx]
-	}
-
-	// Reverse the collected blocks to restore original file order.
-	reverse(sortableBlocks)
-	reverse(globalBlocks)
-
 	// --- Reassembly ---
 	hostBlockMap := make(map[string][]string)
 	aliasToPrimary := make(map[string]string)
@@ -581,7 +649,7 @@ func (m *SSHConfigManager) ReorderHosts(orderedAliases []string) error {
 		}
 	}
 
-	m.rawLines = newLines
+	m.setLines(newLines)
 	return nil
 }
 
@@ -592,48 +660,21 @@ func reverse[S ~[]E, E any](s S) {
 	}
 }
 
-// Backup 创建配置文件备份
+// Backup 通过存储保存一份当前配置的备份，返回备份的位置
 func (m *SSHConfigManager) Backup() (string, error) {
-	// Generate a timestamped backup filename
-	timestamp := time.Now().Format("2006-01-02T15-04-05")
-	backupPath := fmt.Sprintf("%s.bak.%s", m.filename, timestamp)
-	content := m.BuildConfig()
-
-	if err := os.WriteFile(backupPath, []byte(content), 0o600); err != nil {
-		return "", &ConfigError{"backup", err}
+	storage := m.store()
+	if storage == nil {
+		return "", &ConfigError{"backup", errNoStorage}
 	}
-
-	// Clean up old backups, keeping only the most recent 3
-	if err := m.cleanupOldBackups(5); err != nil {
-		// Log the error but don't fail the backup operation
-		log.Printf("Warning: failed to clean up old backups: %v", err)
-	}
-
-	return backupPath, nil
-}
-
-// cleanupOldBackups keeps a specified number of the most recent backups and deletes the rest.
-func (m *SSHConfigManager) cleanupOldBackups(keepCount int) error {
-	dir := filepath.Dir(m.filename)
-	base := filepath.Base(m.filename)
-	pattern := filepath.Join(dir, base+".bak.*")
-
-	matches, err := filepath.Glob(pattern)
+	backupPath, err := storage.Backup([]byte(m.BuildConfig()))
 	if err != nil {
-		return err
-	}
-
-	if len(matches) > keepCount {
-		// Sort files by name (which is chronological due to the timestamp format)
-		sort.Strings(matches)
-		// Delete the oldest files
-		for i := 0; i < len(matches)-keepCount; i++ {
-			if err := os.Remove(matches[i]); err != nil {
-				log.Printf("Warning: failed to remove old backup file %s: %v", matches[i], err)
-			}
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			err = &ConfigError{"backup", err}
 		}
+		return "", err
 	}
-	return nil
+	return backupPath, nil
 }
 
 // GetIncludes 获取所有Include指令
@@ -641,9 +682,7 @@ func (m *SSHConfigManager) GetIncludes() []string {
 	var includes []string
 
 	for _, line := range m.rawLines {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "Include ") {
-			includePath := strings.TrimPrefix(trimmed, "Include ")
+		if includePath, ok := cutDirective(line, "Include"); ok {
 			includes = append(includes, includePath)
 		}
 	}
@@ -659,17 +698,14 @@ func (m *SSHConfigManager) AddInclude(includePath string) {
 	insertPos := 0
 	for i, line := range m.rawLines {
 		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "Include ") {
+		if isDirective(trimmed, "Include") {
 			insertPos = i + 1
 		} else if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
 			break
 		}
 	}
 
-	lines := append([]string{}, m.rawLines[:insertPos]...)
-	lines = append(lines, includeLine)
-	lines = append(lines, m.rawLines[insertPos:]...)
-	m.rawLines = lines
+	m.insertLines(insertPos, includeLine)
 }
 
 // SetGlobalParam 设置全局参数
@@ -685,13 +721,12 @@ func (m *SSHConfigManager) GetGlobalParam(key string) (string, error) {
 // getGlobalHost 查找全局配置Host *
 func (m *SSHConfigManager) getGlobalHost() (start, end int, found bool) {
 	for i, line := range m.rawLines {
-		trimmed := strings.TrimSpace(line)
-		if strings.ToLower(trimmed) == "host *" {
+		if hostPart, ok := cutDirective(line, "Host"); ok && hostPart == "*" {
 			start = i
 			// 查找结束位置（下一个Host或文件结尾）
 			for j := i + 1; j < len(m.rawLines); j++ {
 				nextLine := strings.TrimSpace(m.rawLines[j])
-				if strings.HasPrefix(nextLine, "Host ") {
+				if isDirective(nextLine, "Host") {
 					end = j
 					return start, end, true
 				}
@@ -715,54 +750,17 @@ func (e *HostNotFoundError) Error() string {
 
 // findHost 查找主机配置的开始和结束行号
 func (m *SSHConfigManager) findHost(hostname string) (start, end int, found bool) {
-	// 首先尝试精确匹配
-	for i, line := range m.rawLines {
-		trimmed := strings.TrimSpace(line)
-		if after, ok := strings.CutPrefix(trimmed, "Host "); ok {
-			hostLine := after
-			hostNames := parseHostNames(hostLine)
-			for _, name := range hostNames {
-				// 精确匹配
-				if name == hostname {
-					start = i
-					// 查找结束位置（下一个Host或文件结尾）
-					for j := i + 1; j < len(m.rawLines); j++ {
-						nextLine := strings.TrimSpace(m.rawLines[j])
-						if strings.HasPrefix(nextLine, "Host ") {
-							end = j
-							return start, end, true
-						}
-					}
-					end = len(m.rawLines)
-					return start, end, true
-				}
-			}
-		}
+	ix := m.getIndex()
+
+	// 首先尝试精确匹配（同一别名出现多次时取文件中的第一个）
+	if blocks := ix.byAlias[hostname]; len(blocks) > 0 {
+		b := blocks[0]
+		return b.line, ix.end(b, len(m.rawLines)), true
 	}
 
-	// 如果没有精确匹配，查找通配符匹配
-	for i, line := range m.rawLines {
-		trimmed := strings.TrimSpace(line)
-		if after, ok := strings.CutPrefix(trimmed, "Host "); ok {
-			hostLine := after
-			hostNames := parseHostNames(hostLine)
-			for _, name := range hostNames {
-				// 通配符匹配（除了单独的*）
-				if name != "*" && matchHostName(name, hostname) {
-					start = i
-					// 查找结束位置（下一个Host或文件结尾）
-					for j := i + 1; j < len(m.rawLines); j++ {
-						nextLine := strings.TrimSpace(m.rawLines[j])
-						if strings.HasPrefix(nextLine, "Host ") {
-							end = j
-							return start, end, true
-						}
-					}
-					end = len(m.rawLines)
-					return start, end, true
-				}
-			}
-		}
+	// 如果没有精确匹配，查找通配符匹配（除了单独的*）
+	if b := findPatternBlock(ix, hostname); b != nil {
+		return b.line, ix.end(b, len(m.rawLines)), true
 	}
 
 	// 注意：不再自动匹配Host *，让调用者决定是否需要全局配置
@@ -770,6 +768,18 @@ func (m *SSHConfigManager) findHost(hostname string) (start, end int, found bool
 	return -1, -1, false
 }
 
+// findPatternBlock 返回第一个通配符模式匹配 hostname 的块（不包括单独的 *），没有时返回 nil
+func findPatternBlock(ix *hostIndex, hostname string) *hostBlock {
+	for _, b := range ix.blocks {
+		for _, name := range b.aliases {
+			if name != "*" && strings.Contains(name, "*") && matchHostName(name, hostname) {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
 // findParamInHost 在主机配置块中查找参数
 func (m *SSHConfigManager) findParamInHost(start, end int, key string) int {
 	if end == -1 || end > len(m.rawLines) {
@@ -778,12 +788,14 @@ func (m *SSHConfigManager) findParamInHost(start, end int, key string) int {
 
 	for i := start + 1; i < end && i < len(m.rawLines); i++ {
 		line := strings.TrimSpace(m.rawLines[i])
-		if strings.HasPrefix(line, key+" ") || strings.HasPrefix(line, key+"\t") ||
-			strings.HasPrefix(line, key+"=") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if paramKey, _ := splitDirective(line); strings.EqualFold(paramKey, key) {
 			return i
 		}
 		// 遇到下一个Host或Include时停止
-		if strings.HasPrefix(line, "Host ") || strings.HasPrefix(line, "Include ") {
+		if isDirective(line, "Host", "Include") {
 			break
 		}
 	}
@@ -801,6 +813,11 @@ func expandHomeDir(path string) string {
 	return path
 }
 
+// Filename 返回配置文件的路径（已展开 ~）
+func (m *SSHConfigManager) Filename() string {
+	return m.filename
+}
+
 // GetRawLines 获取原始行（用于调试）
 func (m *SSHConfigManager) GetRawLines() []string {
 	// 返回副本，避免外部修改内部状态
@@ -811,12 +828,15 @@ func (m *SSHConfigManager) GetRawLines() []string {
 
 // Helper functions
 
-// parseHostNames 解析Host行中的主机名列表（改进版）
+// parseHostNames 解析Host行中的主机名列表，支持带引号的名称
 func parseHostNames(hostLine string) []string {
-	var names []string
+	fields, err := splitArgs(hostLine)
+	if err != nil {
+		// 引号未闭合时退回按空白拆分，由 Validate 报告错误
+		fields = strings.Fields(hostLine)
+	}
 
-	// 简化版本：使用strings.Fields然后处理引号
-	fields := strings.Fields(hostLine)
+	var names []string
 	for _, field := range fields {
 		// 移除首尾的引号
 		trimmed := strings.Trim(field, "\"'")
@@ -828,6 +848,15 @@ func parseHostNames(hostLine string) []string {
 	return names
 }
 
+// joinHostNames 把主机名列表拼接为 Host 行的参数部分，必要时为名称加引号
+func joinHostNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteArg(name)
+	}
+	return strings.Join(quoted, " ")
+}
+
 // matchHostName 检查主机名是否匹配（支持通配符）
 func matchHostName(pattern, hostname string) bool {
 	// 精确匹配
@@ -845,34 +874,22 @@ func matchHostName(pattern, hostname string) bool {
 	return false
 }
 
-// parseParamLine 解析参数行
+// parseParamLine 解析参数行。关键字与值之间可以是空白或 "="（两侧允许空白），
+// 值整体是单个带引号的参数时会去掉引号，见 unquoteValue。
 func parseParamLine(line string) (key, value string) {
 	// 移除行首的空白
 	line = strings.TrimSpace(line)
 
 	// 忽略注释行和特殊指令
-	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "Host ") || strings.HasPrefix(line, "Include ") {
+	if line == "" || strings.HasPrefix(line, "#") || isDirective(line, "Host", "Include") {
 		return "", ""
 	}
 
-	// 支持 key=value 和 key value 两种格式
-	var parts []string
-	if strings.Contains(line, "=") && !strings.Contains(line, " ") {
-		parts = strings.SplitN(line, "=", 2)
-	} else {
-		parts = strings.Fields(line)
-	}
-
-	if len(parts) >= 2 {
-		key = parts[0]
-		value = strings.Join(parts[1:], " ")
-		return key, value
-	} else if len(parts) == 1 {
-		key = parts[0]
-		return key, ""
+	key, rest := splitDirective(line)
+	if key == "" {
+		return "", ""
 	}
-
-	return "", ""
+	return key, unquoteValue(rest)
 }
 
 // getLineIndent 获取行的缩进
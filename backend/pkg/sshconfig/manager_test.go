@@ -669,6 +669,62 @@ func TestHasHost_NotFound(t *testing.T) {
 	}
 }
 
+// TestHasHostExact_Pattern 测试精确别名与通配符匹配的区别
+func TestHasHostExact_Pattern(t *testing.T) {
+	manager := &SSHConfigManager{
+		rawLines: []string{"Host web.corp", "    HostName 10.0.0.1", "", "Host *.corp", "    User deploy", "", "Host *", "    TCPKeepAlive yes"},
+	}
+
+	tests := []struct {
+		alias                  string
+		has, exact, hasPattern bool
+	}{
+		{"web.corp", true, true, true},
+		{"db.corp", true, false, true},
+		{"other", false, false, false},
+		{"*", true, true, false},
+	}
+	for _, tt := range tests {
+		if got := manager.HasHost(tt.alias); got != tt.has {
+			t.Errorf("HasHost(%q) = %v, want %v", tt.alias, got, tt.has)
+		}
+		if got := manager.HasHostExact(tt.alias); got != tt.exact {
+			t.Errorf("HasHostExact(%q) = %v, want %v", tt.alias, got, tt.exact)
+		}
+		if got := manager.HasHostPattern(tt.alias); got != tt.hasPattern {
+			t.Errorf("HasHostPattern(%q) = %v, want %v", tt.alias, got, tt.hasPattern)
+		}
+	}
+}
+
+// TestAddHostUnique 测试自动生成不冲突的别名
+func TestAddHostUnique(t *testing.T) {
+	manager := &SSHConfigManager{
+		rawLines: []string{"Host server", "    HostName 10.0.0.1", "", "Host server-2", "    HostName 10.0.0.2", "", "Host *.corp", "    User deploy"},
+	}
+
+	for _, want := range []string{"server-3", "server-4"} {
+		alias, err := manager.AddHostUnique("server")
+		if err != nil {
+			t.Fatalf("AddHostUnique failed: %v", err)
+		}
+		if alias != want || !manager.HasHostExact(alias) {
+			t.Errorf("AddHostUnique(server) = %q, want %q", alias, want)
+		}
+	}
+
+	// 只被通配符块匹配的名称可以直接使用
+	if alias, err := manager.AddHostUnique(" web.corp "); err != nil || alias != "web.corp" {
+		t.Errorf("AddHostUnique(web.corp) = %q, %v, want web.corp", alias, err)
+	}
+
+	for _, invalid := range []string{"", "  ", "a b", "db*"} {
+		if _, err := manager.AddHostUnique(invalid); err == nil {
+			t.Errorf("AddHostUnique(%q) should fail", invalid)
+		}
+	}
+}
+
 // TestGetHostNames_Success 测试成功获取主机名列表
 func TestGetHostNames_Success(t *testing.T) {
 	manager := &SSHConfigManager{
@@ -2230,3 +2286,82 @@ Host hostA
 		t.Errorf("Reordered content mismatch (Mixed Directives).\nExpected:\n---\n%s\n---\nGot:\n---\n%s\n---", expected, actual)
 	}
 }
+
+// TestGetHost_OrderedParams 测试参数按文件顺序保存，同名参数各占一项
+func TestGetHost_OrderedParams(t *testing.T) {
+	manager := &SSHConfigManager{
+		rawLines: []string{
+			"Host web",
+			"    User deploy",
+			"    IdentityFile ~/.ssh/id_a",
+			"    # comment",
+			"    HostName 10.0.0.1",
+			"    IdentityFile ~/.ssh/id_b",
+			"    Port 2222",
+		},
+	}
+
+	host, err := manager.GetHost("web")
+	if err != nil {
+		t.Fatalf("GetHost failed: %v", err)
+	}
+
+	var got []string
+	for _, p := range host.OrderedParams() {
+		got = append(got, p.Key+"="+p.Value)
+	}
+	want := "User=deploy IdentityFile=~/.ssh/id_a HostName=10.0.0.1 IdentityFile=~/.ssh/id_b Port=2222"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Unexpected ordered params: %v", got)
+	}
+
+	if keys := strings.Join(host.Keys(), " "); keys != "User IdentityFile HostName Port" {
+		t.Errorf("Unexpected keys order: %s", keys)
+	}
+	if len(host.Params["IdentityFile"]) != 2 {
+		t.Errorf("Expected 2 IdentityFile params in map, got %d", len(host.Params["IdentityFile"]))
+	}
+
+	// OrderedParams 返回副本，修改不影响主机配置
+	host.OrderedParams()[0].Value = "changed"
+	if host.Ordered[0].Value != "deploy" {
+		t.Error("OrderedParams should return a copy")
+	}
+}
+
+// TestGetGlobalConfig_OrderedParams 测试全局配置同样保留参数顺序
+func TestGetGlobalConfig_OrderedParams(t *testing.T) {
+	manager := &SSHConfigManager{
+		rawLines: []string{
+			"Host *",
+			"    ServerAliveInterval 60",
+			"    TCPKeepAlive yes",
+			"    AddKeysToAgent yes",
+		},
+	}
+
+	global, err := manager.GetGlobalConfig()
+	if err != nil {
+		t.Fatalf("GetGlobalConfig failed: %v", err)
+	}
+	if keys := strings.Join(global.Keys(), " "); keys != "ServerAliveInterval TCPKeepAlive AddKeysToAgent" {
+		t.Errorf("Unexpected keys order: %s", keys)
+	}
+}
+
+// TestParse 测试从内存内容创建管理器，且不能保存
+func TestParse(t *testing.T) {
+	manager, err := Parse("Host *\n    User team\n\nHost build\n    HostName 10.1.0.5\n")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if value, err := manager.GetParam("build", "HostName"); err != nil || value != "10.1.0.5" {
+		t.Errorf("Expected HostName '10.1.0.5', got %q (err: %v)", value, err)
+	}
+	if got := manager.ResolveHost("build").Get("User"); got != "team" {
+		t.Errorf("Expected inherited User 'team', got %q", got)
+	}
+	if err := manager.Save(); err == nil {
+		t.Error("Save should fail for a parsed config")
+	}
+}
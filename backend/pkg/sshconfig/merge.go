@@ -0,0 +1,205 @@
+package sshconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeConflict 描述合并时目标块与来源块对同一参数给出了不同的值。
+// 发生冲突时保留目标块的值。
+type MergeConflict struct {
+	Key         string
+	TargetValue string
+	SourceAlias string // 来源块 Host 行中的第一个别名
+	SourceValue string
+}
+
+// MergeResult 是 MergeHosts 的结果
+type MergeResult struct {
+	Aliases   []string        // 合并后目标块 Host 行中的全部别名
+	Added     []Param         // 从来源块加入目标块的参数
+	Conflicts []MergeConflict // 值不同、未被合并的参数
+}
+
+// blockRange 描述一个 Host 块在 rawLines 中的范围
+type blockRange struct {
+	descStart int // Host 行之前描述注释的起始行（没有注释时等于 hostLine）
+	hostLine  int
+	bodyEnd   int // 块内容的结束行（不含属于下一个块的描述注释）
+}
+
+// blockRangeAt 计算从 hostLine 开始、到 end 结束的 Host 块范围
+func (m *SSHConfigManager) blockRangeAt(hostLine, end int) blockRange {
+	r := blockRange{descStart: hostLine, hostLine: hostLine, bodyEnd: end}
+	for r.descStart > 0 && isCommentLine(m.rawLines[r.descStart-1]) {
+		r.descStart--
+	}
+	// 紧贴下一个 Host 行的注释是下一个块的描述（与 GetHost 的规则一致）
+	if end < len(m.rawLines) {
+		for r.bodyEnd > hostLine+1 && isCommentLine(m.rawLines[r.bodyEnd-1]) {
+			r.bodyEnd--
+		}
+	}
+	return r
+}
+
+// lastContentLine 返回块中最后一个非空行的下一行，即追加内容的位置
+func (m *SSHConfigManager) lastContentLine(r blockRange) int {
+	pos := r.bodyEnd
+	for pos > r.hostLine+1 && isBlankLine(m.rawLines[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// MergeHosts 将 sourceAliases 所在的 Host 块合并进 targetAlias 所在的块：
+// 来源块的所有别名追加到目标 Host 行，目标块没有的参数（以及可累加参数如 IdentityFile 的新值）
+// 追加到目标块末尾，来源块中的注释一并保留，最后删除来源块。
+// 两边值不同的参数保留目标块的值，并作为冲突返回。调用方负责 Save。
+func (m *SSHConfigManager) MergeHosts(targetAlias string, sourceAliases []string) (*MergeResult, error) {
+	if strings.Contains(targetAlias, "*") {
+		return nil, &ConfigError{"merge_hosts", fmt.Errorf("cannot merge into wildcard host %s", targetAlias)}
+	}
+	ix := m.getIndex()
+	targetBlocks := ix.byAlias[targetAlias]
+	if len(targetBlocks) == 0 {
+		return nil, &HostNotFoundError{Alias: targetAlias}
+	}
+	target := targetBlocks[0]
+	if m.blockLocked(target.line, ix.end(target, len(m.rawLines))) {
+		return nil, &HostLockedError{Alias: targetAlias}
+	}
+
+	// 收集来源块（同一块中的多个别名只处理一次，与目标同块的直接跳过）
+	var sources []*hostBlock
+	seen := map[*hostBlock]bool{target: true}
+	for _, alias := range sourceAliases {
+		if strings.Contains(alias, "*") {
+			return nil, &ConfigError{"merge_hosts", fmt.Errorf("cannot merge wildcard host %s", alias)}
+		}
+		blocks := ix.byAlias[alias]
+		if len(blocks) == 0 {
+			return nil, &HostNotFoundError{Alias: alias}
+		}
+		if m.blockLocked(blocks[0].line, ix.end(blocks[0], len(m.rawLines))) {
+			return nil, &HostLockedError{Alias: alias}
+		}
+		if !seen[blocks[0]] {
+			seen[blocks[0]] = true
+			sources = append(sources, blocks[0])
+		}
+	}
+
+	targetRange := m.blockRangeAt(target.line, ix.end(target, len(m.rawLines)))
+	result := &MergeResult{Aliases: append([]string{}, target.aliases...)}
+
+	// 目标块已有的参数：小写 key -> 值
+	existing := make(map[string][]string)
+	indent := "  "
+	foundIndent := false
+	for i := target.line + 1; i < targetRange.bodyEnd; i++ {
+		key, value := parseParamLine(m.rawLines[i])
+		if key == "" {
+			continue
+		}
+		if !foundIndent {
+			indent, foundIndent = getLineIndent(m.rawLines[i]), true
+		}
+		lower := strings.ToLower(key)
+		existing[lower] = append(existing[lower], value)
+	}
+
+	var additions []string
+	deleted := make(map[int]bool)
+	for _, src := range sources {
+		r := m.blockRangeAt(src.line, ix.end(src, len(m.rawLines)))
+
+		for _, alias := range src.aliases {
+			if !containsString(result.Aliases, alias) {
+				result.Aliases = append(result.Aliases, alias)
+			}
+		}
+
+		// 来源块的描述注释随参数一起移入目标块
+		for i := r.descStart; i < r.hostLine; i++ {
+			additions = append(additions, indent+strings.TrimSpace(m.rawLines[i]))
+		}
+		for i := r.hostLine + 1; i < r.bodyEnd; i++ {
+			line := m.rawLines[i]
+			if isBlankLine(line) {
+				continue
+			}
+			if isCommentLine(line) {
+				additions = append(additions, indent+strings.TrimSpace(line))
+				continue
+			}
+			key, value := parseParamLine(line)
+			if key == "" {
+				continue
+			}
+			lower := strings.ToLower(key)
+			values, ok := existing[lower]
+			switch {
+			case !ok || (accumulatingKeys[lower] && !containsString(values, value)):
+				existing[lower] = append(existing[lower], value)
+				additions = append(additions, indent+strings.TrimSpace(line))
+				result.Added = append(result.Added, Param{Key: key, Value: value, Line: i, Raw: line})
+			case !containsString(values, value):
+				result.Conflicts = append(result.Conflicts, MergeConflict{
+					Key:         key,
+					TargetValue: values[0],
+					SourceAlias: src.aliases[0],
+					SourceValue: value,
+				})
+			}
+		}
+
+		// 删除来源块；位于文件末尾时连同前面的空行一起删除，避免留下多余空行
+		start := r.descStart
+		if r.bodyEnd == len(m.rawLines) {
+			for start > 0 && isBlankLine(m.rawLines[start-1]) {
+				start--
+			}
+		}
+		for i := start; i < r.bodyEnd; i++ {
+			deleted[i] = true
+		}
+	}
+
+	insertAt := m.lastContentLine(targetRange)
+	hostLine := m.rawLines[target.line]
+	newHostLine := getLineIndent(hostLine) + "Host " + joinHostNames(result.Aliases)
+
+	lines := make([]string, 0, len(m.rawLines)+len(additions))
+	for i, line := range m.rawLines {
+		if i == insertAt {
+			lines = append(lines, additions...)
+		}
+		if deleted[i] {
+			continue
+		}
+		if i == target.line {
+			line = newHostLine
+		}
+		lines = append(lines, line)
+	}
+	if insertAt == len(m.rawLines) {
+		lines = append(lines, additions...)
+	}
+	m.setLines(lines)
+	return result, nil
+}
+
+// isCommentLine 检查是否为注释行
+func isCommentLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package sshconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMergeHosts_CombinesBlocks 测试合并别名、参数并保留注释
+func TestMergeHosts_CombinesBlocks(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host *",
+		"    ServerAliveInterval 30",
+		"",
+		"# web server",
+		"Host web",
+		"    HostName 10.0.0.5",
+		"    User deploy",
+		"    IdentityFile ~/.ssh/web",
+		"",
+		"# old name of web",
+		"Host web-old legacy",
+		"    HostName 10.0.0.5",
+		"    User root",
+		"    Port 2222",
+		"    # jump through bastion",
+		"    IdentityFile ~/.ssh/legacy",
+		"",
+		"# database",
+		"Host db",
+		"    HostName 10.0.0.6",
+	}}
+
+	result, err := m.MergeHosts("web", []string{"web-old", "legacy"})
+	if err != nil {
+		t.Fatalf("MergeHosts failed: %v", err)
+	}
+	assertIndexConsistent(t, m)
+
+	want := []string{
+		"Host *",
+		"    ServerAliveInterval 30",
+		"",
+		"# web server",
+		"Host web web-old legacy",
+		"    HostName 10.0.0.5",
+		"    User deploy",
+		"    IdentityFile ~/.ssh/web",
+		"    # old name of web",
+		"    Port 2222",
+		"    # jump through bastion",
+		"    IdentityFile ~/.ssh/legacy",
+		"",
+		"# database",
+		"Host db",
+		"    HostName 10.0.0.6",
+	}
+	if !reflect.DeepEqual(m.rawLines, want) {
+		t.Fatalf("Unexpected merged config:\n%s", strings.Join(m.rawLines, "\n"))
+	}
+
+	if !reflect.DeepEqual(result.Aliases, []string{"web", "web-old", "legacy"}) {
+		t.Errorf("Unexpected aliases: %v", result.Aliases)
+	}
+	if len(result.Added) != 2 || result.Added[0].Key != "Port" || result.Added[1].Value != "~/.ssh/legacy" {
+		t.Errorf("Unexpected added params: %+v", result.Added)
+	}
+	if len(result.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %+v", result.Conflicts)
+	}
+	c := result.Conflicts[0]
+	if c.Key != "User" || c.TargetValue != "deploy" || c.SourceValue != "root" || c.SourceAlias != "web-old" {
+		t.Errorf("Unexpected conflict: %+v", c)
+	}
+
+	for _, alias := range []string{"web", "web-old", "legacy"} {
+		if v, _ := m.GetParam(alias, "User"); v != "deploy" {
+			t.Errorf("Expected %s User deploy, got %q", alias, v)
+		}
+	}
+}
+
+// TestMergeHosts_SourceAtEndOfFile 测试合并位于文件末尾的块时不留下多余空行
+func TestMergeHosts_SourceAtEndOfFile(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host a",
+		"    HostName example.com",
+		"",
+		"Host b",
+		"    HostName example.com",
+		"    ForwardAgent yes",
+	}}
+
+	if _, err := m.MergeHosts("a", []string{"b"}); err != nil {
+		t.Fatalf("MergeHosts failed: %v", err)
+	}
+	assertIndexConsistent(t, m)
+
+	want := []string{
+		"Host a b",
+		"    HostName example.com",
+		"    ForwardAgent yes",
+	}
+	if !reflect.DeepEqual(m.rawLines, want) {
+		t.Fatalf("Unexpected merged config:\n%s", strings.Join(m.rawLines, "\n"))
+	}
+}
+
+// TestMergeHosts_SourceBeforeTarget 测试来源块位于目标块之前
+func TestMergeHosts_SourceBeforeTarget(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host b",
+		"    User alice",
+		"",
+		"Host a",
+		"    HostName example.com",
+	}}
+
+	if _, err := m.MergeHosts("a", []string{"b"}); err != nil {
+		t.Fatalf("MergeHosts failed: %v", err)
+	}
+	assertIndexConsistent(t, m)
+
+	want := []string{
+		"Host a b",
+		"    HostName example.com",
+		"    User alice",
+	}
+	if !reflect.DeepEqual(m.rawLines, want) {
+		t.Fatalf("Unexpected merged config:\n%s", strings.Join(m.rawLines, "\n"))
+	}
+}
+
+// TestMergeHosts_Errors 测试无效参数
+func TestMergeHosts_Errors(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host *.prod",
+		"    User prod",
+		"Host a",
+		"    HostName a.example.com",
+	}}
+	original := m.GetRawLines()
+
+	if _, err := m.MergeHosts("missing", []string{"a"}); err == nil {
+		t.Error("Expected error for missing target")
+	}
+	if _, err := m.MergeHosts("a", []string{"missing"}); err == nil {
+		t.Error("Expected error for missing source")
+	}
+	if _, err := m.MergeHosts("a", []string{"*.prod"}); err == nil {
+		t.Error("Expected error for wildcard source")
+	}
+	if _, err := m.MergeHosts("*.prod", []string{"a"}); err == nil {
+		t.Error("Expected error for wildcard target")
+	}
+	if !reflect.DeepEqual(m.rawLines, original) {
+		t.Error("Config should be unchanged after failed merges")
+	}
+
+	// 与目标在同一块中的别名无需合并
+	if _, err := m.MergeHosts("a", []string{"a"}); err != nil {
+		t.Fatalf("MergeHosts with the target itself failed: %v", err)
+	}
+	if !reflect.DeepEqual(m.rawLines, original) {
+		t.Error("Merging a host into itself should not change the config")
+	}
+}
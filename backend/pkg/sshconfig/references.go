@@ -0,0 +1,283 @@
+package sshconfig
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxIncludeDepth 与 OpenSSH 的 Include 嵌套上限一致
+const maxIncludeDepth = 16
+
+// Reference 描述配置中引用了某个值的一个参数
+type Reference struct {
+	File    string   // 参数所在的文件（主配置文件或 Include 引入的文件）
+	Line    int      // 参数所在行，从 0 开始（与 Param.Line 一致）
+	Aliases []string // 所在 Host 块的别名；第一个 Host 之前的参数为空
+	Key     string   // 参数在文件中的写法，例如 ProxyJump 或 ProxyCommand
+	Value   string   // 参数的完整值
+}
+
+// WhereUsed 扫描所有 Host 块（包括 Include 引入的文件），返回引用了 value 的参数：
+//   - IdentityFile / CertificateFile: 展开 ~ 与 %d 后比较路径
+//   - ProxyJump: 匹配 ProxyJump 中任意一跳的主机，以及 ProxyCommand 中作为独立参数出现的主机
+//   - 其它参数: 值完全相同
+//
+// 用于在删除密钥文件或重命名跳板机之前，向用户展示受影响的主机。
+func (m *SSHConfigManager) WhereUsed(paramKey, value string) ([]Reference, error) {
+	if paramKey == "" || value == "" {
+		return nil, &ConfigError{"where_used", fmt.Errorf("key and value cannot be empty")}
+	}
+
+	s := &referenceScanner{
+		match:   referenceMatcher(paramKey, value),
+		baseDir: filepath.Dir(m.filename),
+		visited: map[string]bool{},
+		refs:    []Reference{},
+	}
+	if m.filename != "" {
+		s.visited[filepath.Clean(m.filename)] = true
+	}
+	s.scan(m.filename, m.rawLines, nil, 0)
+	return s.refs, nil
+}
+
+// IncludedFiles 返回 Include 引入的所有文件（展开通配符，包括嵌套的 Include），按引入顺序排列
+func (m *SSHConfigManager) IncludedFiles() []string {
+	s := &referenceScanner{
+		match:   func(string, string) bool { return false },
+		baseDir: filepath.Dir(m.filename),
+		visited: map[string]bool{},
+		files:   []string{},
+	}
+	if m.filename != "" {
+		s.visited[filepath.Clean(m.filename)] = true
+	}
+	s.scan(m.filename, m.rawLines, nil, 0)
+	return s.files
+}
+
+// referenceScanner 在主配置与 Include 文件中收集引用
+type referenceScanner struct {
+	match   func(key, value string) bool
+	baseDir string // 相对路径的 Include 相对于主配置文件所在目录（即 ~/.ssh）
+	visited map[string]bool
+	files   []string // 已扫描的 Include 文件，按引入顺序
+	refs    []Reference
+}
+
+// scan 扫描一个文件的行；aliases 是 Include 所在 Host 块的别名，作用于被引入文件中第一个 Host 之前的参数
+func (s *referenceScanner) scan(file string, lines []string, aliases []string, depth int) {
+	current := aliases
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if after, ok := cutDirective(trimmed, "Host"); ok {
+			current = parseHostNames(after)
+			continue
+		}
+		if after, ok := cutDirective(trimmed, "Include"); ok {
+			if depth < maxIncludeDepth {
+				for _, path := range s.includeFiles(after) {
+					if included, err := readLines(path); err == nil {
+						s.files = append(s.files, path)
+						s.scan(path, included, current, depth+1)
+					}
+				}
+			}
+			continue
+		}
+		if key, value := parseParamLine(trimmed); key != "" && s.match(key, value) {
+			s.refs = append(s.refs, Reference{
+				File:    file,
+				Line:    i,
+				Aliases: append([]string(nil), current...),
+				Key:     key,
+				Value:   value,
+			})
+		}
+	}
+}
+
+// includeFiles 展开 Include 的参数（可以有多个，支持通配符），跳过已扫描过的文件
+func (s *referenceScanner) includeFiles(args string) []string {
+	var files []string
+	patterns, err := splitArgs(args)
+	if err != nil {
+		return nil
+	}
+	for _, pattern := range patterns {
+		pattern = expandHomeDir(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(s.baseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, path := range matches {
+			path = filepath.Clean(path)
+			if s.visited[path] {
+				continue
+			}
+			s.visited[path] = true
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// readLines 读取一个 Include 文件的所有行
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return scanLines(file)
+}
+
+// referenceMatcher 返回判断一个参数是否引用了 value 的函数
+func referenceMatcher(paramKey, value string) func(key, v string) bool {
+	switch lower := strings.ToLower(paramKey); lower {
+	case "identityfile", "certificatefile":
+		want := normalizeKeyPath(value)
+		return func(key, v string) bool {
+			return strings.EqualFold(key, lower) && normalizeKeyPath(v) == want
+		}
+	case "proxyjump":
+		return func(key, v string) bool {
+			switch strings.ToLower(key) {
+			case "proxyjump":
+				return containsString(proxyJumpHosts(v), value)
+			case "proxycommand":
+				return proxyCommandReferences(v, value)
+			}
+			return false
+		}
+	default:
+		return func(key, v string) bool {
+			return strings.EqualFold(key, paramKey) && v == value
+		}
+	}
+}
+
+// normalizeKeyPath 将密钥路径规范化以便比较：去掉引号，展开 %d 与 ~
+func normalizeKeyPath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), `"`)
+	if rest, ok := strings.CutPrefix(path, "%d"); ok {
+		path = "~" + rest
+	}
+	return filepath.Clean(expandHomeDir(path))
+}
+
+// proxyJumpHosts 返回 ProxyJump 值（逗号分隔的 [user@]host[:port]）中每一跳的主机
+func proxyJumpHosts(value string) []string {
+	var hosts []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "ssh://")
+		if at := strings.LastIndex(part, "@"); at >= 0 {
+			part = part[at+1:]
+		}
+		if h, _, err := net.SplitHostPort(part); err == nil {
+			part = h
+		}
+		if part != "" {
+			hosts = append(hosts, part)
+		}
+	}
+	return hosts
+}
+
+// proxyCommandReferences 判断 ProxyCommand 是否把 host 作为独立参数使用，例如 "ssh -W %h:%p bastion"
+func proxyCommandReferences(command, host string) bool {
+	for _, field := range strings.Fields(command) {
+		if at := strings.LastIndex(field, "@"); at >= 0 {
+			field = field[at+1:]
+		}
+		if field == host {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameJumpReferences 将主配置文件中 ProxyJump 与 ProxyCommand 对 oldAlias 的引用改为 newAlias，
+// 返回被修改的参数（Value 为修改后的值）。Include 引入的文件不会被修改，
+// 调用方可以用 WhereUsed 找出其中剩余的引用提示用户手动处理。只读 Host 块中的引用同样保持不变。调用方负责 Save。
+func (m *SSHConfigManager) RenameJumpReferences(oldAlias, newAlias string) []Reference {
+	refs, err := m.WhereUsed("ProxyJump", oldAlias)
+	if err != nil {
+		return nil
+	}
+
+	var updated []Reference
+	for _, ref := range refs {
+		if ref.File != m.filename || m.lineLocked(ref.Line) {
+			continue
+		}
+		// 在原始行上替换，保留缩进、分隔符（空格或 =）以及值中的空白
+		line := m.rawLines[ref.Line]
+		rest := strings.TrimSpace(line)[len(ref.Key):]
+		value := strings.TrimLeft(rest, " \t=")
+		sep := rest[:len(rest)-len(value)]
+		if strings.EqualFold(ref.Key, "ProxyJump") {
+			value = renameProxyJumpHost(value, oldAlias, newAlias)
+		} else {
+			value = renameCommandHost(value, oldAlias, newAlias)
+		}
+		m.setLine(ref.Line, getLineIndent(line)+ref.Key+sep+value)
+		_, ref.Value = parseParamLine(m.rawLines[ref.Line])
+		updated = append(updated, ref)
+	}
+	return updated
+}
+
+// renameProxyJumpHost 替换 ProxyJump 中主机为 oldHost 的每一跳，保留用户名、端口与 ssh:// 前缀
+func renameProxyJumpHost(value, oldHost, newHost string) string {
+	parts := strings.Split(value, ",")
+	for i, part := range parts {
+		hostPart := strings.TrimPrefix(strings.TrimSpace(part), "ssh://")
+		prefix := strings.TrimSuffix(strings.TrimSpace(part), hostPart)
+		if at := strings.LastIndex(hostPart, "@"); at >= 0 {
+			prefix += hostPart[:at+1]
+			hostPart = hostPart[at+1:]
+		}
+		suffix := ""
+		if h, p, err := net.SplitHostPort(hostPart); err == nil {
+			hostPart, suffix = h, ":"+p
+		}
+		if hostPart == oldHost {
+			parts[i] = prefix + newHost + suffix
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// renameCommandHost 替换 ProxyCommand 中作为独立参数（或 user@host）出现的 oldHost，保留原有空白
+func renameCommandHost(command, oldHost, newHost string) string {
+	var b strings.Builder
+	for i := 0; i < len(command); {
+		if command[i] == ' ' || command[i] == '\t' {
+			b.WriteByte(command[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(command) && command[end] != ' ' && command[end] != '\t' {
+			end++
+		}
+		field := command[i:end]
+		at := strings.LastIndex(field, "@")
+		if field[at+1:] == oldHost {
+			field = field[:at+1] + newHost
+		}
+		b.WriteString(field)
+		i = end
+	}
+	return b.String()
+}
@@ -0,0 +1,167 @@
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestWhereUsed_ProxyJump 测试查找 ProxyJump 与 ProxyCommand 中对跳板机的引用，包括 Include 文件
+func TestWhereUsed_ProxyJump(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "conf.d"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	included := strings.Join([]string{
+		"Host db",
+		"    ProxyJump admin@bastion:2222",
+		"Host cache",
+		"    ProxyJump bastion-old",
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(dir, "conf.d", "prod.conf"), []byte(included), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &SSHConfigManager{filename: filepath.Join(dir, "config"), rawLines: []string{
+		"Include conf.d/*.conf",
+		"",
+		"Host bastion",
+		"    HostName 1.2.3.4",
+		"",
+		"Host web app",
+		"    ProxyJump other,bastion",
+		"",
+		"Host legacy",
+		"    ProxyCommand ssh -W %h:%p ops@bastion",
+		"",
+		"Host unrelated",
+		"    ProxyCommand nc bastion.example.com %p",
+	}}
+
+	refs, err := m.WhereUsed("ProxyJump", "bastion")
+	if err != nil {
+		t.Fatalf("WhereUsed failed: %v", err)
+	}
+
+	var got []string
+	for _, r := range refs {
+		got = append(got, filepath.Base(r.File)+":"+strings.Join(r.Aliases, ",")+":"+r.Key)
+	}
+	want := []string{
+		"prod.conf:db:ProxyJump",
+		"config:web,app:ProxyJump",
+		"config:legacy:ProxyCommand",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Unexpected references: %v", got)
+	}
+	if refs[1].Line != 6 || refs[1].Value != "other,bastion" {
+		t.Errorf("Unexpected reference details: %+v", refs[1])
+	}
+}
+
+// TestWhereUsed_IdentityFile 测试展开 ~ 与 %d 后比较密钥路径
+func TestWhereUsed_IdentityFile(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"IdentityFile ~/.ssh/id_ed25519",
+		"Host web",
+		"    IdentityFile %d/.ssh/id_ed25519",
+		"Host db",
+		"    identityfile \"~/.ssh/./id_ed25519\"",
+		"Host other",
+		"    IdentityFile ~/.ssh/id_rsa",
+	}}
+
+	refs, err := m.WhereUsed("IdentityFile", "~/.ssh/id_ed25519")
+	if err != nil {
+		t.Fatalf("WhereUsed failed: %v", err)
+	}
+	if len(refs) != 3 {
+		t.Fatalf("Expected 3 references, got %d: %+v", len(refs), refs)
+	}
+	if refs[0].Aliases != nil || !reflect.DeepEqual(refs[2].Aliases, []string{"db"}) {
+		t.Errorf("Unexpected aliases: %v, %v", refs[0].Aliases, refs[2].Aliases)
+	}
+
+	if _, err := m.WhereUsed("", "x"); err == nil {
+		t.Error("Expected error for empty key")
+	}
+}
+
+// TestRenameJumpReferences 测试重命名跳板机时更新 ProxyJump 与 ProxyCommand 引用
+func TestRenameJumpReferences(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host bastion-new",
+		"    HostName 1.2.3.4",
+		"Host web",
+		"    ProxyJump other,admin@bastion:2222",
+		"Host legacy",
+		"    ProxyCommand ssh  -W %h:%p ops@bastion",
+		"Host keep",
+		"    ProxyJump bastion-old",
+	}}
+
+	updated := m.RenameJumpReferences("bastion", "bastion-new")
+	if len(updated) != 2 {
+		t.Fatalf("Expected 2 updated references, got %+v", updated)
+	}
+	assertIndexConsistent(t, m)
+
+	want := []string{
+		"Host bastion-new",
+		"    HostName 1.2.3.4",
+		"Host web",
+		"    ProxyJump other,admin@bastion-new:2222",
+		"Host legacy",
+		"    ProxyCommand ssh  -W %h:%p ops@bastion-new",
+		"Host keep",
+		"    ProxyJump bastion-old",
+	}
+	if !reflect.DeepEqual(m.rawLines, want) {
+		t.Fatalf("Unexpected config:\n%s", strings.Join(m.rawLines, "\n"))
+	}
+	if updated[0].Value != "other,admin@bastion-new:2222" {
+		t.Errorf("Unexpected updated value: %q", updated[0].Value)
+	}
+}
+
+// TestIncludedFiles 测试展开 Include（通配符、嵌套、重复引入）后的文件列表
+func TestIncludedFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config.d"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"config.d/a.conf": "Include nested.conf\nHost a\n    HostName 10.0.0.1\n",
+		"config.d/b.conf": "Host b\n    HostName 10.0.0.2\n",
+		"nested.conf":     "Host nested\n    HostName 10.0.0.3\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &SSHConfigManager{filename: filepath.Join(dir, "config"), rawLines: []string{
+		"Include config.d/*",
+		"Include ~/does-not-exist/* nested.conf",
+		"",
+		"Host main",
+		"    HostName 10.0.0.9",
+	}}
+	want := []string{
+		filepath.Join(dir, "config.d", "a.conf"),
+		filepath.Join(dir, "nested.conf"),
+		filepath.Join(dir, "config.d", "b.conf"),
+	}
+	if got := m.IncludedFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("IncludedFiles() = %v, want %v", got, want)
+	}
+
+	empty := &SSHConfigManager{filename: filepath.Join(dir, "config"), rawLines: []string{"Host main"}}
+	if got := empty.IncludedFiles(); len(got) != 0 {
+		t.Errorf("IncludedFiles() without Include = %v, want empty", got)
+	}
+}
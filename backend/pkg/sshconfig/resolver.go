@@ -0,0 +1,148 @@
+package sshconfig
+
+import (
+	"strings"
+)
+
+// accumulatingKeys 列出了 OpenSSH 中会累加（而不是“首个值生效”）的参数，均为小写
+var accumulatingKeys = map[string]bool{
+	"identityfile":    true,
+	"certificatefile": true,
+	"localforward":    true,
+	"remoteforward":   true,
+	"dynamicforward":  true,
+	"sendenv":         true,
+}
+
+// EffectiveConfig 是某个别名最终生效的配置视图。
+// 它按照 OpenSSH 的规则合并所有匹配的 Host 块：对于每个参数，第一个获得的值生效。
+type EffectiveConfig struct {
+	Alias  string
+	params map[string][]string // 小写 key -> 值列表
+	keys   map[string]string   // 小写 key -> 首次出现时的原始写法
+}
+
+// Get 返回参数的第一个值（key 不区分大小写），不存在时返回空字符串
+func (c *EffectiveConfig) Get(key string) string {
+	if values := c.params[strings.ToLower(key)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// GetAll 返回参数的所有值（例如多个 IdentityFile）
+func (c *EffectiveConfig) GetAll(key string) []string {
+	values := c.params[strings.ToLower(key)]
+	out := make([]string, len(values))
+	copy(out, values)
+	return out
+}
+
+// Has 检查参数是否被设置
+func (c *EffectiveConfig) Has(key string) bool {
+	_, ok := c.params[strings.ToLower(key)]
+	return ok
+}
+
+// Params 返回所有生效参数的副本，key 使用配置文件中首次出现的写法
+func (c *EffectiveConfig) Params() map[string][]string {
+	out := make(map[string][]string, len(c.params))
+	for lower, values := range c.params {
+		copied := make([]string, len(values))
+		copy(copied, values)
+		out[c.keys[lower]] = copied
+	}
+	return out
+}
+
+func (c *EffectiveConfig) apply(key, value string) {
+	lower := strings.ToLower(key)
+	if _, exists := c.params[lower]; exists && !accumulatingKeys[lower] {
+		return // 首个值生效
+	}
+	if _, ok := c.keys[lower]; !ok {
+		c.keys[lower] = key
+	}
+	c.params[lower] = append(c.params[lower], value)
+}
+
+// ResolveHost 计算 alias 的生效配置。
+// 它依次遍历文件中的所有 Host 块（包括通配符块与 Host *），对匹配的块应用其参数。
+// 第一个 Host 之前的参数被视为对所有主机生效；Match 块无法静态求值，会被跳过。
+// 注意：Include 引入的文件不会被解析。
+func (m *SSHConfigManager) ResolveHost(alias string) *EffectiveConfig {
+	cfg := &EffectiveConfig{
+		Alias:  alias,
+		params: make(map[string][]string),
+		keys:   make(map[string]string),
+	}
+
+	applies := true // 第一个 Host 之前的参数对所有主机生效
+	for _, line := range m.rawLines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if after, ok := cutDirective(trimmed, "Host"); ok {
+			applies = hostPatternsMatch(parseHostNames(after), alias)
+			continue
+		}
+		if isDirective(trimmed, "Match") {
+			applies = false
+			continue
+		}
+		if isDirective(trimmed, "Include") || !applies {
+			continue
+		}
+		if key, value := parseParamLine(trimmed); key != "" {
+			cfg.apply(key, value)
+		}
+	}
+
+	return cfg
+}
+
+// hostPatternsMatch 判断一个 Host 行的模式列表是否匹配 alias。
+// 与 OpenSSH 一致：任一正向模式匹配且没有任何否定模式（!pattern）匹配时，才算匹配。
+func hostPatternsMatch(patterns []string, alias string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			if wildcardMatch(negated, alias) {
+				return false
+			}
+			continue
+		}
+		if wildcardMatch(pattern, alias) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// wildcardMatch 实现 ssh_config 的模式匹配：'*' 匹配任意多个字符，'?' 匹配单个字符
+func wildcardMatch(pattern, name string) bool {
+	p, n := 0, 0
+	starIdx, matchIdx := -1, 0
+	for n < len(name) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == name[n]):
+			p++
+			n++
+		case p < len(pattern) && pattern[p] == '*':
+			starIdx = p
+			matchIdx = n
+			p++
+		case starIdx != -1:
+			p = starIdx + 1
+			matchIdx++
+			n = matchIdx
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
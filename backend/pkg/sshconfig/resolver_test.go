@@ -0,0 +1,141 @@
+package sshconfig
+
+import (
+	"testing"
+)
+
+// TestResolveHost_FirstValueWins 测试第一个获得的值生效
+func TestResolveHost_FirstValueWins(t *testing.T) {
+	manager := &SSHConfigManager{
+		rawLines: []string{
+			"Host web",
+			"    HostName web.example.com",
+			"    User deploy",
+			"",
+			"Host *",
+			"    User root",
+			"    ServerAliveInterval 30",
+		},
+	}
+
+	cfg := manager.ResolveHost("web")
+	if cfg.Get("User") != "deploy" {
+		t.Errorf("Expected User 'deploy', got %q", cfg.Get("User"))
+	}
+	if cfg.Get("serveraliveinterval") != "30" {
+		t.Errorf("Expected ServerAliveInterval '30' from Host *, got %q", cfg.Get("ServerAliveInterval"))
+	}
+	if cfg.Get("HostName") != "web.example.com" {
+		t.Errorf("Expected HostName 'web.example.com', got %q", cfg.Get("HostName"))
+	}
+}
+
+// TestResolveHost_WildcardBlocks 测试通配符块的参数会应用到匹配的主机
+func TestResolveHost_WildcardBlocks(t *testing.T) {
+	manager := &SSHConfigManager{
+		rawLines: []string{
+			"Host db-prod",
+			"    HostName 10.0.0.5",
+			"",
+			"Host *-prod !bastion-prod",
+			"    User admin",
+			"    IdentityFile ~/.ssh/prod",
+			"",
+			"Host db-?rod",
+			"    Port 2222",
+		},
+	}
+
+	cfg := manager.ResolveHost("db-prod")
+	if cfg.Get("User") != "admin" {
+		t.Errorf("Expected User 'admin' from wildcard block, got %q", cfg.Get("User"))
+	}
+	if cfg.Get("Port") != "2222" {
+		t.Errorf("Expected Port '2222' from '?' pattern, got %q", cfg.Get("Port"))
+	}
+
+	negated := manager.ResolveHost("bastion-prod")
+	if negated.Has("User") {
+		t.Errorf("Negated pattern should not apply to bastion-prod, got User %q", negated.Get("User"))
+	}
+}
+
+// TestResolveHost_AccumulatingKeys 测试 IdentityFile 等参数会累加
+func TestResolveHost_AccumulatingKeys(t *testing.T) {
+	manager := &SSHConfigManager{
+		rawLines: []string{
+			"Host app",
+			"    IdentityFile ~/.ssh/app",
+			"Host *",
+			"    IdentityFile ~/.ssh/id_ed25519",
+		},
+	}
+
+	files := manager.ResolveHost("app").GetAll("IdentityFile")
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 identity files, got %d", len(files))
+	}
+	if files[0] != "~/.ssh/app" || files[1] != "~/.ssh/id_ed25519" {
+		t.Errorf("Unexpected identity file order: %v", files)
+	}
+}
+
+// TestResolveHost_SkipsMatchBlocks 测试 Match 块不会被应用
+func TestResolveHost_SkipsMatchBlocks(t *testing.T) {
+	manager := &SSHConfigManager{
+		rawLines: []string{
+			"Compression yes",
+			"Match host app exec \"true\"",
+			"    User matched",
+			"Host app",
+			"    HostName app.local",
+		},
+	}
+
+	cfg := manager.ResolveHost("app")
+	if cfg.Has("User") {
+		t.Errorf("Match block params should be skipped, got User %q", cfg.Get("User"))
+	}
+	if cfg.Get("Compression") != "yes" {
+		t.Errorf("Params before the first Host should apply to all hosts, got %q", cfg.Get("Compression"))
+	}
+}
+
+// TestResolveHost_Params 测试 Params 返回原始写法的 key
+func TestResolveHost_Params(t *testing.T) {
+	manager := &SSHConfigManager{
+		rawLines: []string{
+			"Host app",
+			"    HostName app.local",
+		},
+	}
+
+	params := manager.ResolveHost("app").Params()
+	if values, ok := params["HostName"]; !ok || values[0] != "app.local" {
+		t.Errorf("Expected HostName key in original case, got %v", params)
+	}
+}
+
+// TestWildcardMatch 测试通配符匹配
+func TestWildcardMatch(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*", "anything", true},
+		{"*.corp", "db.corp", true},
+		{"*.corp", "db.corp.net", false},
+		{"web-?", "web-1", true},
+		{"web-?", "web-10", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"exact", "exact", true},
+		{"exact", "other", false},
+	}
+
+	for _, tc := range testCases {
+		if got := wildcardMatch(tc.pattern, tc.name); got != tc.expected {
+			t.Errorf("wildcardMatch(%q, %q) = %v, expected %v", tc.pattern, tc.name, got, tc.expected)
+		}
+	}
+}
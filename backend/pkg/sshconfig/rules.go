@@ -0,0 +1,273 @@
+package sshconfig
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Severity 是诊断的严重程度
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // 阻止保存
+	SeverityWarning Severity = "warning" // 只提示
+)
+
+// SyntaxRuleID 是内置语法检查产生的诊断的规则 ID
+const SyntaxRuleID = "syntax"
+
+// Diagnostic 是校验产生的一条诊断
+type Diagnostic struct {
+	Line            int      `json:"line"` // 从 1 开始，0 表示整个文件
+	Severity        Severity `json:"severity"`
+	Message         string   `json:"message"`
+	Rule            string   `json:"rule"`                      // 产生诊断的规则 ID
+	RuleDescription string   `json:"ruleDescription,omitempty"` // 规则的说明，便于界面解释为什么需要修改
+}
+
+// RuleFunc 检查配置文件的所有行并返回诊断。Rule、RuleDescription 与 Severity 留空时由所属规则补全。
+type RuleFunc func(lines []string) []Diagnostic
+
+// ValidationRule 是调用方注册的额外校验规则（例如团队的安全策略）
+type ValidationRule struct {
+	ID          string
+	Description string
+	Severity    Severity // 为空时为 SeverityError
+	Check       RuleFunc
+}
+
+// AddRule 注册额外的校验规则，Validate 与 Diagnostics 会在内置语法检查之后执行它们
+func (v *ConfigValidator) AddRule(rule ValidationRule) {
+	v.rules = append(v.rules, rule)
+}
+
+// Diagnostics 返回所有诊断：每一行的语法错误、内置的警告（未知参数、重复参数），以及已注册规则的结果，按行号排序
+func (v *ConfigValidator) Diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	hasError := make(map[int]bool)
+	for i, line := range v.lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if err := v.validateConfigLine(line, i+1); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:     i + 1,
+				Severity: SeverityError,
+				Message:  err.Error(),
+				Rule:     SyntaxRuleID,
+			})
+			hasError[i+1] = true
+		}
+	}
+	diagnostics = append(diagnostics, v.warningDiagnostics(hasError)...)
+	diagnostics = append(diagnostics, v.ruleDiagnostics()...)
+	sort.SliceStable(diagnostics, func(i, j int) bool { return diagnostics[i].Line < diagnostics[j].Line })
+	return diagnostics
+}
+
+// ValidationError 表示校验结果阻止了保存，Diagnostics 是完整的诊断列表
+type ValidationError struct {
+	Diagnostics []Diagnostic
+	Err         error // 第一条阻止保存的诊断
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Check 返回全部诊断，并在存在错误（warningsBlock 为 true 时也包括警告）时返回 *ValidationError，
+// 供保存前的校验使用：警告只提示，不阻止保存
+func (v *ConfigValidator) Check(warningsBlock bool) ([]Diagnostic, error) {
+	diagnostics := v.Diagnostics()
+	if err := v.Validate(); err != nil {
+		return diagnostics, &ValidationError{Diagnostics: diagnostics, Err: err}
+	}
+	if warningsBlock {
+		for _, d := range diagnostics {
+			if d.Severity == SeverityWarning {
+				err := &ConfigError{"validate", fmt.Errorf("line %d: %s (%s)", d.Line, d.Message, d.Rule)}
+				return diagnostics, &ValidationError{Diagnostics: diagnostics, Err: err}
+			}
+		}
+	}
+	return diagnostics, nil
+}
+
+// ruleDiagnostics 执行已注册的规则，并用规则的元数据补全诊断
+func (v *ConfigValidator) ruleDiagnostics() []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, rule := range v.rules {
+		if rule.Check == nil {
+			continue
+		}
+		for _, d := range rule.Check(v.lines) {
+			if d.Rule == "" {
+				d.Rule = rule.ID
+			}
+			if d.RuleDescription == "" {
+				d.RuleDescription = rule.Description
+			}
+			if d.Severity == "" {
+				d.Severity = rule.Severity
+			}
+			if d.Severity == "" {
+				d.Severity = SeverityError
+			}
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+// validateRules 返回第一条错误级别的规则诊断
+func (v *ConfigValidator) validateRules() error {
+	for _, d := range v.ruleDiagnostics() {
+		if d.Severity != SeverityError {
+			continue
+		}
+		if d.Line > 0 {
+			return &ConfigError{"validate", fmt.Errorf("line %d: %s (%s)", d.Line, d.Message, d.Rule)}
+		}
+		return &ConfigError{"validate", fmt.Errorf("%s (%s)", d.Message, d.Rule)}
+	}
+	return nil
+}
+
+// RequireParamRule 返回一条规则：模式匹配 hostPattern 的每个 Host 块都必须设置 key，
+// 例如 RequireParamRule("prod-identity", "Production hosts must set IdentityFile", "prod-*", "IdentityFile")
+func RequireParamRule(id, description, hostPattern, key string) ValidationRule {
+	return ValidationRule{
+		ID:          id,
+		Description: description,
+		Check: func(lines []string) []Diagnostic {
+			var diagnostics []Diagnostic
+			for _, block := range scanRuleBlocks(lines, hostPattern) {
+				if _, ok := block.params[strings.ToLower(key)]; !ok {
+					diagnostics = append(diagnostics, Diagnostic{
+						Line:    block.line,
+						Message: fmt.Sprintf("Host %s must set %s", strings.Join(block.patterns, " "), key),
+					})
+				}
+			}
+			return diagnostics
+		},
+	}
+}
+
+// RequireParamValueRule 返回一条规则：模式匹配 hostPattern 的 Host 块中，key 如果设置了就必须等于 value（不区分大小写），
+// 例如 RequireParamValueRule("no-agent", "Agent forwarding is not allowed", "*", "ForwardAgent", "no")
+func RequireParamValueRule(id, description, hostPattern, key, value string) ValidationRule {
+	return ValidationRule{
+		ID:          id,
+		Description: description,
+		Check: func(lines []string) []Diagnostic {
+			var diagnostics []Diagnostic
+			for _, block := range scanRuleBlocks(lines, hostPattern) {
+				param, ok := block.params[strings.ToLower(key)]
+				if ok && !strings.EqualFold(param.value, value) {
+					diagnostics = append(diagnostics, Diagnostic{
+						Line:    param.line,
+						Message: fmt.Sprintf("%s must be '%s' for Host %s", key, value, strings.Join(block.patterns, " ")),
+					})
+				}
+			}
+			return diagnostics
+		},
+	}
+}
+
+// 内置规则的 ID
+const (
+	GlobalForwardAgentRuleID = "global-forward-agent"
+	GlobalHostKeyCheckRuleID = "global-host-key-checking"
+)
+
+// BuiltinRules 返回内置的安全规则，它们只对 "Host *" 块生效，级别为警告（只提示，不阻止保存）：
+// 对所有主机开启 ForwardAgent 会把本地 ssh-agent 暴露给连接的每一台服务器，
+// 对所有主机关闭 StrictHostKeyChecking 会让每一次连接都跳过主机密钥校验。
+func BuiltinRules() []ValidationRule {
+	return []ValidationRule{
+		globalParamRule(GlobalForwardAgentRuleID, "ForwardAgent should not be enabled for every host", "ForwardAgent",
+			func(value string) bool { return !strings.EqualFold(value, "no") }),
+		globalParamRule(GlobalHostKeyCheckRuleID, "StrictHostKeyChecking should not be disabled for every host", "StrictHostKeyChecking",
+			func(value string) bool { return strings.EqualFold(value, "no") || strings.EqualFold(value, "off") }),
+	}
+}
+
+// globalParamRule 返回一条警告规则：Host * 块中的 key 被 bad 判定为不安全时报告在参数所在行
+func globalParamRule(id, description, key string, bad func(value string) bool) ValidationRule {
+	return ValidationRule{
+		ID:          id,
+		Description: description,
+		Severity:    SeverityWarning,
+		Check: func(lines []string) []Diagnostic {
+			var diagnostics []Diagnostic
+			for _, block := range scanRuleBlocks(lines, "*") {
+				if !slices.Contains(block.patterns, "*") {
+					continue
+				}
+				if param, ok := block.params[strings.ToLower(key)]; ok && bad(param.value) {
+					diagnostics = append(diagnostics, Diagnostic{
+						Line:    param.line,
+						Message: fmt.Sprintf("%s %s applies to every host", key, param.value),
+					})
+				}
+			}
+			return diagnostics
+		},
+	}
+}
+
+// ruleParam 是 Host 块中某个参数第一次出现的位置与值（与 ssh 一样，第一次出现的值生效）
+type ruleParam struct {
+	line  int
+	value string
+}
+
+// ruleBlock 是规则检查用的 Host 块
+type ruleBlock struct {
+	line     int // Host 行的行号，从 1 开始
+	patterns []string
+	params   map[string]ruleParam // 小写参数名 → 参数
+}
+
+// scanRuleBlocks 返回 Host 行中任一模式被 hostPattern 匹配的 Host 块（"*" 匹配所有块，包括 "Host *"）
+func scanRuleBlocks(lines []string, hostPattern string) []ruleBlock {
+	var blocks []ruleBlock
+	var current *ruleBlock
+	for i, line := range lines {
+		if patterns, ok := isHostLine(line); ok {
+			current = nil
+			for _, p := range patterns {
+				if wildcardMatch(hostPattern, p) {
+					blocks = append(blocks, ruleBlock{line: i + 1, patterns: patterns, params: make(map[string]ruleParam)})
+					current = &blocks[len(blocks)-1]
+					break
+				}
+			}
+			continue
+		}
+		if isDirective(line, "Match") {
+			current = nil
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		key, value := parseParamLine(line)
+		if key == "" {
+			continue
+		}
+		if _, seen := current.params[strings.ToLower(key)]; !seen {
+			current.params[strings.ToLower(key)] = ruleParam{line: i + 1, value: value}
+		}
+	}
+	return blocks
+}
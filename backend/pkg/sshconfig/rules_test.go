@@ -0,0 +1,140 @@
+package sshconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+var policyConfig = []string{
+	"Host *",
+	"    ForwardAgent yes",
+	"",
+	"Host prod-web prod-db",
+	"    HostName 10.0.0.1",
+	"",
+	"Host prod-api",
+	"    IdentityFile ~/.ssh/prod",
+	"",
+	"Host dev",
+	"    HostName 10.0.1.1",
+}
+
+// TestRequireParamRule 测试匹配模式的 Host 块必须设置参数
+func TestRequireParamRule(t *testing.T) {
+	v := NewConfigValidator(policyConfig)
+	v.AddRule(RequireParamRule("prod-identity", "Production hosts must set IdentityFile", "prod-*", "IdentityFile"))
+
+	diagnostics := v.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("Diagnostics() = %+v, want 1 diagnostic", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Line != 4 || d.Rule != "prod-identity" || d.Severity != SeverityError || d.RuleDescription != "Production hosts must set IdentityFile" {
+		t.Errorf("unexpected diagnostic: %+v", d)
+	}
+	if !strings.Contains(d.Message, "prod-web prod-db") {
+		t.Errorf("message should name the Host line, got %q", d.Message)
+	}
+
+	err := v.Validate()
+	if err == nil {
+		t.Fatal("Validate should fail when an error-level rule is violated")
+	}
+	if !strings.Contains(err.Error(), "line 4:") || !strings.Contains(err.Error(), "(prod-identity)") {
+		t.Errorf("error should contain the line and rule ID, got: %v", err)
+	}
+}
+
+// TestRequireParamValueRule 测试参数值策略，报告在参数所在行
+func TestRequireParamValueRule(t *testing.T) {
+	v := NewConfigValidator(policyConfig)
+	v.AddRule(RequireParamValueRule("no-agent", "", "*", "ForwardAgent", "no"))
+
+	diagnostics := v.Diagnostics()
+	if len(diagnostics) != 1 || diagnostics[0].Line != 2 || diagnostics[0].Rule != "no-agent" {
+		t.Fatalf("Diagnostics() = %+v, want one diagnostic on line 2", diagnostics)
+	}
+
+	// 值符合要求或未设置时不报告
+	ok := NewConfigValidator([]string{"Host *", "    ForwardAgent NO", "Host web", "    HostName web"})
+	ok.AddRule(RequireParamValueRule("no-agent", "", "*", "ForwardAgent", "no"))
+	if d := ok.Diagnostics(); len(d) != 0 {
+		t.Errorf("Diagnostics() = %+v, want none", d)
+	}
+}
+
+// TestAddRule_WarningDoesNotFailValidate 测试警告级别的规则只出现在诊断中
+func TestAddRule_WarningDoesNotFailValidate(t *testing.T) {
+	v := NewConfigValidator(policyConfig)
+	v.AddRule(ValidationRule{
+		ID:       "custom",
+		Severity: SeverityWarning,
+		Check: func(lines []string) []Diagnostic {
+			return []Diagnostic{{Message: "file-level note"}}
+		},
+	})
+
+	if err := v.Validate(); err != nil {
+		t.Errorf("Validate should ignore warnings, got: %v", err)
+	}
+	diagnostics := v.Diagnostics()
+	if len(diagnostics) != 1 || diagnostics[0].Severity != SeverityWarning || diagnostics[0].Rule != "custom" || diagnostics[0].Line != 0 {
+		t.Errorf("Diagnostics() = %+v", diagnostics)
+	}
+}
+
+// TestDiagnostics_SyntaxAndRules 测试语法错误与规则诊断一起按行号排序返回
+func TestDiagnostics_SyntaxAndRules(t *testing.T) {
+	v := NewConfigValidator([]string{
+		"Host web",
+		"    Port abc",
+		"Host prod-db",
+		"    Port 70000",
+	})
+	v.AddRule(RequireParamRule("prod-identity", "", "prod-*", "IdentityFile"))
+
+	diagnostics := v.Diagnostics()
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, d.Rule)
+	}
+	if strings.Join(got, ",") != "syntax,prod-identity,syntax" {
+		t.Errorf("Diagnostics() rules = %v, want [syntax prod-identity syntax]", got)
+	}
+	if diagnostics[0].Line != 2 || diagnostics[2].Line != 4 {
+		t.Errorf("unexpected lines: %+v", diagnostics)
+	}
+}
+
+// TestBuiltinRules 测试内置规则只对 Host * 块报告警告，不阻止保存
+func TestBuiltinRules(t *testing.T) {
+	v := NewConfigValidator([]string{
+		"Host web",
+		"    ForwardAgent yes",
+		"    StrictHostKeyChecking no",
+		"",
+		"Host * !bastion",
+		"    ForwardAgent yes",
+		"    StrictHostKeyChecking accept-new",
+		"",
+		"Host *",
+		"    StrictHostKeyChecking off",
+	})
+	for _, rule := range BuiltinRules() {
+		v.AddRule(rule)
+	}
+
+	diagnostics := v.Diagnostics()
+	if len(diagnostics) != 2 {
+		t.Fatalf("Diagnostics() = %+v, want 2 diagnostics", diagnostics)
+	}
+	if d := diagnostics[0]; d.Line != 6 || d.Rule != GlobalForwardAgentRuleID || d.Severity != SeverityWarning {
+		t.Errorf("unexpected diagnostic: %+v", d)
+	}
+	if d := diagnostics[1]; d.Line != 10 || d.Rule != GlobalHostKeyCheckRuleID || d.Severity != SeverityWarning {
+		t.Errorf("unexpected diagnostic: %+v", d)
+	}
+	if err := v.Validate(); err != nil {
+		t.Errorf("built-in rules should not block saving: %v", err)
+	}
+}
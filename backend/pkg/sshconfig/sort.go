@@ -0,0 +1,212 @@
+package sshconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortHosts 的排序方式
+const (
+	SortByAlias = "alias" // 按主机别名的字母顺序
+	SortByTag   = "tag"   // 按 Host 块中 Tag 参数的值分组，组内按别名排序，没有 Tag 的主机排在最后
+)
+
+// configBlock 是配置中的一个块：Host、Match 或 Include 指令，连同它前面的注释与空行，
+// 直到下一个块之前
+type configBlock struct {
+	lines   []string
+	keyword string   // Host | Match | Include，与文件中的写法一致
+	aliases []string // Host 块的主机名（模式）
+}
+
+// splitBlocks 把配置拆分为第一个块之前的内容与依次排列的块。
+// 从下往上扫描，每个块向上贪婪地吸收紧邻的注释与空行，使注释始终跟随它描述的块。
+func (m *SSHConfigManager) splitBlocks() (header []string, blocks []configBlock) {
+	blockEnd := len(m.rawLines)
+	for i := len(m.rawLines) - 1; i >= 0; i-- {
+		trimmed := strings.TrimSpace(m.rawLines[i])
+		keyword, rest := splitDirective(trimmed)
+		if !isDirective(trimmed, "Host", "Include", "Match") {
+			continue
+		}
+
+		blockStart := i
+		for j := i - 1; j >= 0; j-- {
+			prev := strings.TrimSpace(m.rawLines[j])
+			if prev != "" && !strings.HasPrefix(prev, "#") {
+				break
+			}
+			blockStart = j
+		}
+
+		block := configBlock{lines: m.rawLines[blockStart:blockEnd], keyword: keyword}
+		if strings.EqualFold(keyword, "Host") {
+			block.aliases = parseHostNames(rest)
+		}
+		blocks = append(blocks, block)
+
+		blockEnd = blockStart
+		i = blockStart
+	}
+	reverse(blocks)
+	return m.rawLines[:blockEnd], blocks
+}
+
+// isHost 判断块是否为 Host 块
+func (b configBlock) isHost() bool {
+	return strings.EqualFold(b.keyword, "Host")
+}
+
+// literal 判断块是否为只包含具体主机名（不含通配符与否定模式）的 Host 块。
+// 只有这样的块可以安全地调整顺序：ssh 按文件顺序取第一个匹配的值，
+// 移动 Host *、Match 或带通配符的块会改变其他主机最终生效的参数。
+func (b configBlock) literal() bool {
+	if !b.isHost() || len(b.aliases) == 0 {
+		return false
+	}
+	for _, alias := range b.aliases {
+		if strings.ContainsAny(alias, "*?!") {
+			return false
+		}
+	}
+	return true
+}
+
+// param 返回块中第一个 key 参数的值
+func (b configBlock) param(key string) string {
+	for _, line := range b.lines {
+		if value, ok := cutDirective(strings.TrimSpace(line), key); ok {
+			return strings.Trim(strings.TrimSpace(value), "\"")
+		}
+	}
+	return ""
+}
+
+// SortHosts 按 mode（SortByAlias 或 SortByTag）整理 Host 块的顺序，用于"整理一下配置文件"。
+// 与 ReorderHosts 不同，Host *、带通配符的 Host、Match 与 Include 块保持原来的位置，
+// 具体主机只在这些块之间的区段内排序，主机名有重叠的块保持原来的相对顺序，因此每个主机生效的参数不会改变。
+// 每个块前面的注释随块一起移动，块之间的空行保持原来的布局。
+func (m *SSHConfigManager) SortHosts(mode string) error {
+	switch mode {
+	case SortByAlias:
+		m.sortHosts(func(configBlock) string { return "" })
+	case SortByTag:
+		m.sortHosts(func(b configBlock) string { return b.param("Tag") })
+	default:
+		return fmt.Errorf("unknown sort mode '%s'", mode)
+	}
+	return nil
+}
+
+// SortHostsBy 与 SortHosts 相同，但按 group 返回的分组排序：分组名相同的主机排在一起并按别名排序，
+// 分组按名称排序，分组为空的主机排在最后。group 为 nil 时只按别名排序。
+// group 的参数是块的全部主机名，第一个为主别名。
+func (m *SSHConfigManager) SortHostsBy(group func(aliases []string) string) {
+	m.sortHosts(func(b configBlock) string {
+		if group == nil {
+			return ""
+		}
+		return group(b.aliases)
+	})
+}
+
+// sortHosts 在每个由不可移动的块分隔的区段内，按 (分组, 主别名) 对具体主机的块排序
+func (m *SSHConfigManager) sortHosts(group func(configBlock) string) {
+	header, blocks := m.splitBlocks()
+	newLines := append([]string(nil), header...)
+
+	for start := 0; start < len(blocks); {
+		if !blocks[start].literal() {
+			newLines = append(newLines, blocks[start].lines...)
+			start++
+			continue
+		}
+		end := start
+		for end < len(blocks) && blocks[end].literal() {
+			end++
+		}
+		segment := blocks[start:end]
+
+		// order 是排序后的块在 segment 中的序号
+		order := make([]int, len(segment))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			bi, bj := segment[order[i]], segment[order[j]]
+			gi, gj := group(bi), group(bj)
+			if gi != gj {
+				// 没有分组的主机排在最后
+				if gi == "" || gj == "" {
+					return gj == ""
+				}
+				return strings.ToLower(gi) < strings.ToLower(gj)
+			}
+			return strings.ToLower(bi.aliases[0]) < strings.ToLower(bj.aliases[0])
+		})
+		keepOverlapOrder(segment, order)
+
+		// 块的内容按新的顺序排列，每个位置前后的空行保持原样，避免排序后块之间缺少或多出空行
+		for i := range segment {
+			lead, _, trail := trimBlankLines(segment[i].lines)
+			_, body, _ := trimBlankLines(segment[order[i]].lines)
+			newLines = append(newLines, lead...)
+			newLines = append(newLines, body...)
+			newLines = append(newLines, trail...)
+		}
+		start = end
+	}
+	m.setLines(newLines)
+}
+
+// keepOverlapOrder 调整 order，让主机名有重叠的块（例如 Host web 与 Host web web-prod）保持原来的相对顺序。
+// ssh 对同一个主机按文件顺序取第一个匹配的值，交换这样的两个块会改变该主机生效的参数。
+// 互相重叠（直接或经由其他块）的块占据排序后的位置不变，按原来的顺序重新填入这些位置。
+func keepOverlapOrder(segment []configBlock, order []int) {
+	// 按主机名把块合并为组，parent 指向同组的另一个块，根为组的代表
+	parent := make([]int, len(segment))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			i = parent[i]
+		}
+		return i
+	}
+	owner := make(map[string]int)
+	for i, b := range segment {
+		for _, alias := range b.aliases {
+			key := strings.ToLower(alias)
+			if j, ok := owner[key]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				owner[key] = i
+			}
+		}
+	}
+
+	members := make(map[int][]int) // 组的代表 -> 组内的块（原来的顺序）
+	for i := range segment {
+		members[find(i)] = append(members[find(i)], i)
+	}
+	for pos, i := range order {
+		root := find(i)
+		order[pos] = members[root][0]
+		members[root] = members[root][1:]
+	}
+}
+
+// trimBlankLines 把 lines 分为开头的空行、中间的内容与结尾的空行
+func trimBlankLines(lines []string) (lead, body, trail []string) {
+	start := 0
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	end := len(lines)
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return lines[:start], lines[start:end], lines[end:]
+}
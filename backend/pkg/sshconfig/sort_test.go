@@ -0,0 +1,161 @@
+package sshconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSortHosts(t *testing.T) {
+	initialContent := `# Global settings
+Include ~/.ssh/global
+
+# charlie 是跳板机
+Host charlie
+  HostName c.com
+
+Host Bravo b2
+  HostName b.com
+  Tag work
+
+# alpha
+Host alpha
+  HostName a.com
+
+Host *.internal
+  User ops
+
+Host zulu
+  HostName z.com
+  Tag work
+
+Host yankee
+  HostName y.com
+
+Match host x
+  User x
+
+Host xray
+  HostName x.com
+`
+	newManager := func() *SSHConfigManager {
+		return &SSHConfigManager{rawLines: strings.Split(initialContent, "\n")}
+	}
+
+	t.Run("按别名排序", func(t *testing.T) {
+		manager := newManager()
+		if err := manager.SortHosts(SortByAlias); err != nil {
+			t.Fatalf("SortHosts failed: %v", err)
+		}
+
+		// 注释随块移动，Include、通配符 Host 与 Match 块保持原位，只在它们之间的区段内排序
+		expected := `# Global settings
+Include ~/.ssh/global
+
+# alpha
+Host alpha
+  HostName a.com
+
+Host Bravo b2
+  HostName b.com
+  Tag work
+
+# charlie 是跳板机
+Host charlie
+  HostName c.com
+
+Host *.internal
+  User ops
+
+Host yankee
+  HostName y.com
+
+Host zulu
+  HostName z.com
+  Tag work
+
+Match host x
+  User x
+
+Host xray
+  HostName x.com
+`
+		if actual := strings.Join(manager.rawLines, "\n"); actual != expected {
+			t.Errorf("Sorted content mismatch.\nExpected:\n%s\nGot:\n%s", expected, actual)
+		}
+	})
+
+	t.Run("按 Tag 分组", func(t *testing.T) {
+		manager := newManager()
+		if err := manager.SortHosts(SortByTag); err != nil {
+			t.Fatalf("SortHosts failed: %v", err)
+		}
+
+		// 有 Tag 的主机排在前面，没有 Tag 的主机按别名排在后面
+		expectedOrder := []string{"Bravo", "alpha", "charlie", "*.internal", "zulu", "yankee", "xray"}
+		var actualOrder []string
+		_, blocks := manager.splitBlocks()
+		for _, b := range blocks {
+			if b.isHost() {
+				actualOrder = append(actualOrder, b.aliases[0])
+			}
+		}
+		if strings.Join(actualOrder, ",") != strings.Join(expectedOrder, ",") {
+			t.Errorf("Expected order %v, got %v", expectedOrder, actualOrder)
+		}
+	})
+
+	t.Run("自定义分组", func(t *testing.T) {
+		manager := newManager()
+		manager.SortHostsBy(func(aliases []string) string {
+			if aliases[0] == "charlie" {
+				return "jump"
+			}
+			return ""
+		})
+
+		_, blocks := manager.splitBlocks()
+		if blocks[1].aliases[0] != "charlie" || blocks[2].aliases[0] != "alpha" || blocks[3].aliases[0] != "Bravo" {
+			t.Errorf("Expected charlie first in its segment, got %v, %v, %v", blocks[1].aliases, blocks[2].aliases, blocks[3].aliases)
+		}
+	})
+
+	t.Run("主机名重叠的块保持相对顺序", func(t *testing.T) {
+		manager := &SSHConfigManager{rawLines: strings.Split(`Host web-prod web
+  User deploy
+
+Host db
+  HostName db.com
+
+Host web
+  User root
+  HostName web.com
+
+Host api
+  HostName api.com
+`, "\n")}
+		if err := manager.SortHosts(SortByAlias); err != nil {
+			t.Fatalf("SortHosts failed: %v", err)
+		}
+
+		// web-prod 块排在 web 块前面，web 的 User 仍然是 deploy；其他块正常排序
+		var actualOrder []string
+		_, blocks := manager.splitBlocks()
+		for _, b := range blocks {
+			actualOrder = append(actualOrder, b.aliases[0])
+		}
+		expectedOrder := []string{"api", "db", "web-prod", "web"}
+		if strings.Join(actualOrder, ",") != strings.Join(expectedOrder, ",") {
+			t.Errorf("Expected order %v, got %v", expectedOrder, actualOrder)
+		}
+	})
+
+	t.Run("未知的排序方式", func(t *testing.T) {
+		manager := newManager()
+		if err := manager.SortHosts("size"); err == nil {
+			t.Error("Expected an error for an unknown sort mode")
+		}
+		if actual := strings.Join(manager.rawLines, "\n"); actual != initialContent {
+			t.Error("Content should not change for an unknown sort mode")
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package sshconfig
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxBackups 是文件存储保留的备份数量
+const maxBackups = 5
+
+// Storage 是配置内容的持久化后端。管理器只负责解析与编辑，读写全部通过 Storage 完成，
+// 因此可以接入文件以外的来源（例如内存、团队共享的远程存储或加密存储）而不改动编辑逻辑。
+type Storage interface {
+	// Location 返回配置的位置，用于错误信息、解析相对路径的 Include 以及定位引用所在的文件。
+	// 不对应本地文件的存储可以返回空字符串或任意描述。
+	Location() string
+	// Load 返回完整的配置内容，配置不存在时返回满足 errors.Is(err, fs.ErrNotExist) 的错误
+	Load() ([]byte, error)
+	// Save 用 content 替换保存的配置内容
+	Save(content []byte) error
+	// Backup 保存一份 content 的备份，返回备份的位置
+	Backup(content []byte) (string, error)
+}
+
+// FileStorage 把配置保存在本地文件中，这是 NewManager 使用的存储
+type FileStorage struct {
+	path string
+}
+
+// NewFileStorage 创建文件存储，path 中的 ~ 会被展开为用户主目录
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{path: expandHomeDir(path)}
+}
+
+// Location 返回文件路径
+func (s *FileStorage) Location() string {
+	return s.path
+}
+
+// Load 读取文件内容
+func (s *FileStorage) Load() ([]byte, error) {
+	return os.ReadFile(s.path)
+}
+
+// Save 写入文件，目录不存在时会先创建
+func (s *FileStorage) Save(content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return &ConfigError{"mkdir", err}
+	}
+	if err := os.WriteFile(s.path, content, 0o600); err != nil {
+		return &ConfigError{"write", err}
+	}
+	return nil
+}
+
+// Backup 在文件旁边写入带时间戳的备份（<文件名>.bak.<时间>），只保留最近的几份
+func (s *FileStorage) Backup(content []byte) (string, error) {
+	timestamp := time.Now().Format("2006-01-02T15-04-05")
+	backupPath := fmt.Sprintf("%s.bak.%s", s.path, timestamp)
+	if err := os.WriteFile(backupPath, content, 0o600); err != nil {
+		return "", &ConfigError{"backup", err}
+	}
+
+	// Clean up old backups; a failure is logged but doesn't fail the backup
+	if err := s.cleanupOldBackups(maxBackups); err != nil {
+		log.Printf("Warning: failed to clean up old backups: %v", err)
+	}
+	return backupPath, nil
+}
+
+// cleanupOldBackups keeps a specified number of the most recent backups and deletes the rest.
+func (s *FileStorage) cleanupOldBackups(keepCount int) error {
+	pattern := filepath.Join(filepath.Dir(s.path), filepath.Base(s.path)+".bak.*")
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+
+	if len(matches) > keepCount {
+		// Sort files by name (which is chronological due to the timestamp format)
+		sort.Strings(matches)
+		// Delete the oldest files
+		for i := 0; i < len(matches)-keepCount; i++ {
+			if err := os.Remove(matches[i]); err != nil {
+				log.Printf("Warning: failed to remove old backup file %s: %v", matches[i], err)
+			}
+		}
+	}
+	return nil
+}
+
+// MemoryStorage 把配置保存在内存中，主要用于测试，也可以作为其他存储的缓存层。可以并发使用。
+type MemoryStorage struct {
+	name string
+
+	mu      sync.Mutex
+	content []byte
+	exists  bool
+	backups [][]byte
+	saves   int
+}
+
+// NewMemoryStorage 创建内存存储，content 为 nil 时表示配置尚不存在
+func NewMemoryStorage(name string, content []byte) *MemoryStorage {
+	s := &MemoryStorage{name: name}
+	if content != nil {
+		s.content, s.exists = append([]byte(nil), content...), true
+	}
+	return s
+}
+
+// Location 返回创建时指定的名称
+func (s *MemoryStorage) Location() string {
+	return s.name
+}
+
+// Load 返回当前内容的副本
+func (s *MemoryStorage) Load() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.exists {
+		return nil, &fs.PathError{Op: "open", Path: s.name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), s.content...), nil
+}
+
+// Save 替换当前内容
+func (s *MemoryStorage) Save(content []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content, s.exists = append([]byte(nil), content...), true
+	s.saves++
+	return nil
+}
+
+// Backup 记录一份备份，位置形如 "<名称>.bak.1"
+func (s *MemoryStorage) Backup(content []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backups = append(s.backups, append([]byte(nil), content...))
+	return fmt.Sprintf("%s.bak.%d", s.name, len(s.backups)), nil
+}
+
+// Content 返回当前内容，配置不存在时返回 nil
+func (s *MemoryStorage) Content() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.exists {
+		return nil
+	}
+	return append([]byte(nil), s.content...)
+}
+
+// Backups 返回所有备份的内容，按创建顺序排列
+func (s *MemoryStorage) Backups() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	backups := make([][]byte, len(s.backups))
+	for i, b := range s.backups {
+		backups[i] = append([]byte(nil), b...)
+	}
+	return backups
+}
+
+// Saves 返回 Save 被调用的次数
+func (s *MemoryStorage) Saves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
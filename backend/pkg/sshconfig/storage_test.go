@@ -0,0 +1,218 @@
+package sshconfig
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingStorage 是读写总是失败的存储，用于测试错误的传递
+type failingStorage struct {
+	loadErr, saveErr error
+}
+
+func (s *failingStorage) Location() string              { return "failing" }
+func (s *failingStorage) Load() ([]byte, error)         { return nil, s.loadErr }
+func (s *failingStorage) Save([]byte) error             { return s.saveErr }
+func (s *failingStorage) Backup([]byte) (string, error) { return "", s.saveErr }
+
+// newMemoryManager 创建一个使用内存存储的管理器
+func newMemoryManager(t *testing.T, content string) (*SSHConfigManager, *MemoryStorage) {
+	t.Helper()
+	storage := NewMemoryStorage("memory", []byte(content))
+	m, err := NewManagerWithStorage(storage)
+	if err != nil {
+		t.Fatalf("NewManagerWithStorage failed: %v", err)
+	}
+	return m, storage
+}
+
+// TestNewManagerWithStorage_Empty 测试存储中还没有配置时从空配置开始，保存后写入存储，并能被新的管理器读取
+func TestNewManagerWithStorage_Empty(t *testing.T) {
+	storage := NewMemoryStorage("memory", nil)
+	m, err := NewManagerWithStorage(storage)
+	if err != nil {
+		t.Fatalf("NewManagerWithStorage failed: %v", err)
+	}
+	if names, _ := m.GetHostNames(); len(names) != 0 {
+		t.Fatalf("expected an empty config, got hosts %v", names)
+	}
+	if m.Filename() != "memory" {
+		t.Errorf("Filename should be the storage location, got %q", m.Filename())
+	}
+
+	m.AddHost("web")
+	if err := m.SetParam("web", "HostName", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if storage.Saves() != 1 || !strings.Contains(string(storage.Content()), "HostName 10.0.0.1") {
+		t.Fatalf("config was not saved to the storage: %q", storage.Content())
+	}
+
+	reloaded, err := NewManagerWithStorage(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := reloaded.GetParam("web", "HostName"); err != nil || value != "10.0.0.1" {
+		t.Errorf("reloaded HostName = %q, %v", value, err)
+	}
+}
+
+// TestMemoryStorage_EditRoundTrip 测试编辑操作经由存储保存后，注释与未修改的内容保持原样
+func TestMemoryStorage_EditRoundTrip(t *testing.T) {
+	m, storage := newMemoryManager(t, `# personal hosts
+Host web
+    HostName 10.0.0.1
+    User root
+
+Host db
+    HostName 10.0.0.2
+
+Host old
+    HostName 10.0.0.3
+`)
+
+	if err := m.SetParam("web", "User", "deploy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RenameHost("db", "database"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveHost("old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	content := string(storage.Content())
+	for _, want := range []string{"# personal hosts", "Host web", "User deploy", "Host database", "HostName 10.0.0.2"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("saved config should contain %q:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"User root", "Host db\n", "Host old", "10.0.0.3"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("saved config should not contain %q:\n%s", unwanted, content)
+		}
+	}
+}
+
+// TestMemoryStorage_Reload 测试 Load 重新读取存储中被外部修改的内容
+func TestMemoryStorage_Reload(t *testing.T) {
+	m, storage := newMemoryManager(t, "Host web\n    HostName 10.0.0.1\n")
+	if err := storage.Save([]byte("Host api\n    HostName 10.0.0.9\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if m.HasHost("web") || !m.HasHost("api") {
+		t.Errorf("Load should replace the config with the stored content, got %v", m.GetRawLines())
+	}
+}
+
+// TestMemoryStorage_ValidationBlocksSave 测试校验失败时不会写入存储
+func TestMemoryStorage_ValidationBlocksSave(t *testing.T) {
+	m, storage := newMemoryManager(t, "Host web\n    Port abc\n")
+	err := m.Save()
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Op != "validate" {
+		t.Fatalf("Save should fail validation, got %v", err)
+	}
+	if storage.Saves() != 0 {
+		t.Errorf("an invalid config should not be written, got %d saves", storage.Saves())
+	}
+}
+
+// TestMemoryStorage_Backup 测试备份通过存储保存当前内容（包括尚未保存的修改）
+func TestMemoryStorage_Backup(t *testing.T) {
+	m, storage := newMemoryManager(t, "Host web\n    HostName 10.0.0.1\n")
+	if err := m.SetParam("web", "Port", "2222"); err != nil {
+		t.Fatal(err)
+	}
+	location, err := m.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location != "memory.bak.1" {
+		t.Errorf("unexpected backup location %q", location)
+	}
+	backups := storage.Backups()
+	if len(backups) != 1 || !strings.Contains(string(backups[0]), "Port 2222") {
+		t.Errorf("backup should contain the current config, got %q", backups)
+	}
+	if strings.Contains(string(storage.Content()), "Port 2222") {
+		t.Error("Backup should not save the config itself")
+	}
+}
+
+// TestStorageErrors 测试存储的读写错误包装为 ConfigError，以及不关联存储的管理器不能保存
+func TestStorageErrors(t *testing.T) {
+	boom := errors.New("boom")
+
+	_, err := NewManagerWithStorage(&failingStorage{loadErr: boom})
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Op != "load" || !errors.Is(err, boom) {
+		t.Errorf("a load failure should be a ConfigError with Op load, got %v", err)
+	}
+
+	m, err := NewManagerWithStorage(&failingStorage{loadErr: fs.ErrNotExist, saveErr: boom})
+	if err != nil {
+		t.Fatalf("a missing config should not be an error: %v", err)
+	}
+	m.AddHost("web")
+	if err := m.Save(); !errors.As(err, &configErr) || configErr.Op != "write" || !errors.Is(err, boom) {
+		t.Errorf("a save failure should be a ConfigError with Op write, got %v", err)
+	}
+	if _, err := m.Backup(); !errors.As(err, &configErr) || configErr.Op != "backup" {
+		t.Errorf("a backup failure should be a ConfigError with Op backup, got %v", err)
+	}
+
+	parsed, err := Parse("Host web\n    HostName 10.0.0.1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.Save(); !errors.Is(err, errNoStorage) {
+		t.Errorf("a parsed config should not be saveable, got %v", err)
+	}
+	if _, err := parsed.Backup(); !errors.Is(err, errNoStorage) {
+		t.Errorf("a parsed config should not be backed up, got %v", err)
+	}
+}
+
+// TestFileStorage 测试文件存储创建缺失的目录，并只保留最近的几份备份
+func TestFileStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ssh", "config")
+	storage := NewFileStorage(path)
+	if _, err := storage.Load(); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("loading a missing file should return fs.ErrNotExist, got %v", err)
+	}
+	if err := storage.Save([]byte("Host web\n")); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "Host web\n" {
+		t.Fatalf("unexpected file content %q, %v", content, err)
+	}
+
+	for i := 0; i < maxBackups+2; i++ {
+		// 备份文件名精确到秒，直接创建旧备份以避免等待
+		old := path + ".bak.2000-01-01T00-00-0" + string(rune('0'+i))
+		if err := os.WriteFile(old, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := storage.Backup([]byte("Host web\n")); err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := filepath.Glob(path + ".bak.*")
+	if len(matches) != maxBackups {
+		t.Errorf("expected %d backups to be kept, got %d", maxBackups, len(matches))
+	}
+}
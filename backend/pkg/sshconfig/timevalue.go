@@ -0,0 +1,61 @@
+package sshconfig
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// timeUnits 是 sshd_config(5) TIME FORMATS 中的时间单位（不区分大小写）
+var timeUnits = map[rune]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// ParseTimeValue 按 sshd_config(5) TIME FORMATS 解析时间参数（如 ServerAliveInterval、ConnectTimeout），
+// 与 OpenSSH 的 convtime 一致：每段是数字加可选的单位 s、m、h、d、w，没有单位时为秒，多段相加（如 1m30s、1h30）。
+func ParseTimeValue(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty time value")
+	}
+
+	const limit = math.MaxInt32 * time.Second // 与 convtime 一样限制在 INT_MAX 秒以内
+	var total, number time.Duration
+	digits := false
+	add := func(unit time.Duration) error {
+		if number > (limit-total)/unit {
+			return fmt.Errorf("time value %q is too large", value)
+		}
+		total += number * unit
+		number, digits = 0, false
+		return nil
+	}
+	for _, c := range strings.ToLower(value) {
+		if c >= '0' && c <= '9' {
+			if number > math.MaxInt32 {
+				return 0, fmt.Errorf("time value %q is too large", value)
+			}
+			number = number*10 + time.Duration(c-'0')
+			digits = true
+			continue
+		}
+		unit, ok := timeUnits[c]
+		if !ok || !digits {
+			return 0, fmt.Errorf("invalid time value %q", value)
+		}
+		if err := add(unit); err != nil {
+			return 0, err
+		}
+	}
+	if digits {
+		if err := add(time.Second); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,44 @@
+package sshconfig
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseTimeValue 测试按 sshd_config(5) TIME FORMATS 解析时间参数，与 OpenSSH 的 convtime 一致
+func TestParseTimeValue(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "30", want: 30 * time.Second},
+		{value: " 45 ", want: 45 * time.Second},
+		{value: "1m", want: time.Minute},
+		{value: "1m30s", want: 90 * time.Second},
+		{value: "1h30", want: time.Hour + 30*time.Second},
+		{value: "1H30M", want: 90 * time.Minute},
+		{value: "1d", want: 24 * time.Hour},
+		{value: "2w", want: 14 * 24 * time.Hour},
+		{value: "10s10s", want: 20 * time.Second},
+		{value: "", wantErr: true},
+		{value: "m", wantErr: true},
+		{value: "1x", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "1.5m", wantErr: true},
+		{value: "1m s", wantErr: true},
+		{value: "2147483648", wantErr: true},
+		{value: "9999999999w", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeValue(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTimeValue(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTimeValue(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
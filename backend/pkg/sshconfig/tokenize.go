@@ -0,0 +1,143 @@
+package sshconfig
+
+import (
+	"errors"
+	"strings"
+)
+
+// errUnterminatedQuote 表示参数中的引号没有闭合
+var errUnterminatedQuote = errors.New("unterminated quoted string")
+
+// singleArgKeywords 是只接受一个参数的关键字（小写）。它们的值可能包含空格（如路径），
+// 写入时需要加引号，否则 ssh 会把值拆成多个参数。
+var singleArgKeywords = map[string]bool{
+	"certificatefile":     true,
+	"controlpath":         true,
+	"identityagent":       true,
+	"identityfile":        true,
+	"pkcs11provider":      true,
+	"revokedhostkeys":     true,
+	"securitykeyprovider": true,
+	"user":                true,
+	"xauthlocation":       true,
+}
+
+// splitDirective 按 OpenSSH readconf 的规则把一行拆为关键字与参数部分：
+// 关键字以空白或 "=" 结束，其后可以有一个 "="，两侧允许空白，
+// 即 "Key value"、"Key=value" 与 "Key = value" 等价。参数部分去掉首尾空白，保持原样。
+func splitDirective(line string) (keyword, rest string) {
+	line = strings.TrimSpace(line)
+	end := strings.IndexAny(line, " \t=")
+	if end == -1 {
+		return line, ""
+	}
+	keyword = line[:end]
+	rest = strings.TrimLeft(line[end:], " \t")
+	if strings.HasPrefix(rest, "=") {
+		rest = strings.TrimLeft(rest[1:], " \t")
+	}
+	return keyword, strings.TrimRight(rest, " \t")
+}
+
+// cutDirective 判断 line 是否为 keyword 指令（不区分大小写），并返回其参数部分
+func cutDirective(line, keyword string) (string, bool) {
+	k, rest := splitDirective(line)
+	if !strings.EqualFold(k, keyword) {
+		return "", false
+	}
+	return rest, true
+}
+
+// isDirective 判断 line 是否为 keywords 中任一指令
+func isDirective(line string, keywords ...string) bool {
+	k, _ := splitDirective(line)
+	for _, keyword := range keywords {
+		if strings.EqualFold(k, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitArgs 按 OpenSSH 的 argv_split 规则拆分参数：参数以空白分隔，
+// 单引号或双引号内的空白属于同一参数，反斜杠可以转义引号、反斜杠以及（引号外的）空格。
+func splitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\'' || s[i+1] == '\\' || (quote == 0 && s[i+1] == ' ')):
+			i++
+			current.WriteByte(s[i])
+			inArg = true
+		case quote == 0 && (c == ' ' || c == '\t'):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+			inArg = true
+		case quote != 0 && c == quote:
+			quote = 0
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errUnterminatedQuote
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// SplitArgs 拆分包含多个参数的值（例如 SendEnv、SetEnv），规则同 splitArgs
+func SplitArgs(s string) ([]string, error) {
+	return splitArgs(s)
+}
+
+// quoteArg 在需要时为参数加上双引号，使 splitArgs 能还原出同一个参数
+func quoteArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\") && !strings.HasPrefix(s, "#") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// unquoteValue 把参数部分转换为参数值：整体是单个参数时去掉引号与转义
+// （如 IdentityFile "/path with spaces/id_rsa"），包含多个参数时保持原样
+// （如 ProxyCommand ssh -W "%h:%p" jump），以便按原文交给 shell 或再次写回。
+func unquoteValue(rest string) string {
+	args, err := splitArgs(rest)
+	if err != nil || len(args) != 1 {
+		return rest
+	}
+	return args[0]
+}
+
+// formatParamValue 返回写入配置文件时 key 的参数部分，
+// 保证 parseParamLine 读回的值与 value 相同
+func formatParamValue(key, value string) string {
+	value = strings.TrimSpace(value)
+	if args, err := splitArgs(value); err == nil && len(args) == 1 && args[0] == value {
+		return value // 不含空白与引号，原样写入
+	}
+	if singleArgKeywords[strings.ToLower(key)] {
+		return quoteArg(value)
+	}
+	if unquoteValue(value) != value {
+		// 多参数的值整体只是一个带引号的参数，读回时会被去掉引号，需要再包一层
+		return quoteArg(value)
+	}
+	return value
+}
@@ -0,0 +1,168 @@
+package sshconfig
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestParseParamLine_Separators 测试关键字与值之间的各种分隔方式
+func TestParseParamLine_Separators(t *testing.T) {
+	tests := []struct {
+		line  string
+		key   string
+		value string
+	}{
+		{"Port 22", "Port", "22"},
+		{"Port=22", "Port", "22"},
+		{"Port = 22", "Port", "22"},
+		{"Port= 22", "Port", "22"},
+		{"Port =22", "Port", "22"},
+		{"\tPort\t=\t22  ", "Port", "22"},
+		{"ProxyCommand=ssh -W %h:%p jump", "ProxyCommand", "ssh -W %h:%p jump"},
+		{"ProxyCommand = ssh -W \"%h:%p\" jump", "ProxyCommand", "ssh -W \"%h:%p\" jump"},
+		{"SetEnv FOO=bar", "SetEnv", "FOO=bar"},
+		{"IdentityFile \"/path with spaces/id_rsa\"", "IdentityFile", "/path with spaces/id_rsa"},
+		{"IdentityFile = '~/My Keys/id_ed25519'", "IdentityFile", "~/My Keys/id_ed25519"},
+		{"IdentityFile ~/My\\ Keys/id_rsa", "IdentityFile", "~/My Keys/id_rsa"},
+		{"LocalCommand echo \"unterminated", "LocalCommand", "echo \"unterminated"},
+		{"Host=web", "", ""},
+		{"Include = ~/.ssh/conf.d/*", "", ""},
+		{"=22", "", ""},
+	}
+	for _, tt := range tests {
+		key, value := parseParamLine(tt.line)
+		if key != tt.key || value != tt.value {
+			t.Errorf("parseParamLine(%q) = (%q, %q), want (%q, %q)", tt.line, key, value, tt.key, tt.value)
+		}
+	}
+}
+
+// TestSplitArgs 测试按 OpenSSH 规则拆分参数
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"a b  c", []string{"a", "b", "c"}},
+		{`"a b" c`, []string{"a b", "c"}},
+		{`'a "b"' c`, []string{`a "b"`, "c"}},
+		{`a\ b \"c\"`, []string{"a b", `"c"`}},
+		{`"a\"b"`, []string{`a"b`}},
+		{`C:\Users\me`, []string{`C:\Users\me`}},
+		{`x"y z"`, []string{"xy z"}},
+		{`""`, []string{""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.input)
+		if err != nil {
+			t.Errorf("splitArgs(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := splitArgs(`"open`); err == nil {
+		t.Error("splitArgs should fail for an unterminated quote")
+	}
+}
+
+// TestSetParam_QuotingRoundTrip 测试 SetParam 写入的值能被原样读回
+func TestSetParam_QuotingRoundTrip(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		line  string // 期望写入的行
+	}{
+		{"HostName", "example.com", "  HostName example.com"},
+		{"IdentityFile", "/path with spaces/id_rsa", `  IdentityFile "/path with spaces/id_rsa"`},
+		{"IdentityFile", `C:\My Keys\id_rsa`, `  IdentityFile "C:\\My Keys\\id_rsa"`},
+		{"ProxyCommand", `ssh -W "%h:%p" jump`, `  ProxyCommand ssh -W "%h:%p" jump`},
+		{"LocalForward", "8080 localhost:80", "  LocalForward 8080 localhost:80"},
+		{"LocalCommand", `"echo hi"`, `  LocalCommand "\"echo hi\""`},
+		{"User", `de"ploy`, `  User "de\"ploy"`},
+	}
+	for _, tt := range tests {
+		m := &SSHConfigManager{}
+		m.setLines([]string{"Host web"})
+		if err := m.SetParam("web", tt.key, tt.value); err != nil {
+			t.Fatalf("SetParam(%q, %q) failed: %v", tt.key, tt.value, err)
+		}
+		if got := m.rawLines[1]; got != tt.line {
+			t.Errorf("SetParam(%q, %q) wrote %q, want %q", tt.key, tt.value, got, tt.line)
+		}
+		got, err := m.GetParam("web", tt.key)
+		if err != nil || got != tt.value {
+			t.Errorf("GetParam(%q) = %q, %v; want %q", tt.key, got, err, tt.value)
+		}
+		if err := m.Validate(); err != nil {
+			t.Errorf("Validate failed after SetParam(%q, %q): %v", tt.key, tt.value, err)
+		}
+	}
+}
+
+// TestSetParam_UpdatesEqualsSyntax 测试更新以 "Key = value" 写法定义的参数
+func TestSetParam_UpdatesEqualsSyntax(t *testing.T) {
+	m := &SSHConfigManager{}
+	m.setLines([]string{"Host web", "    port = 22", "    HostName=web.example.com"})
+
+	if err := m.SetParam("web", "Port", "2222"); err != nil {
+		t.Fatalf("SetParam failed: %v", err)
+	}
+	if m.rawLines[1] != "    Port 2222" || len(m.rawLines) != 3 {
+		t.Errorf("Existing parameter should be replaced in place, got %q", m.rawLines)
+	}
+}
+
+// TestHostLine_EqualsSyntax 测试以 "Host=..." 与 "Host = ..." 定义的 Host 块
+func TestHostLine_EqualsSyntax(t *testing.T) {
+	m := &SSHConfigManager{}
+	m.setLines([]string{
+		"Host=web",
+		"    HostName web.example.com",
+		"",
+		"Host = db \"db backup\"",
+		"    User = postgres",
+	})
+
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	names, _ := m.GetHostNames()
+	if !slices.Equal(names, []string{"web", "db", "db backup"}) {
+		t.Errorf("Unexpected host names: %q", names)
+	}
+	if value, err := m.GetParam("db backup", "User"); err != nil || value != "postgres" {
+		t.Errorf("GetParam(db backup, User) = %q, %v", value, err)
+	}
+	if value, err := m.GetParam("web", "HostName"); err != nil || value != "web.example.com" {
+		t.Errorf("GetParam(web, HostName) = %q, %v", value, err)
+	}
+
+	if err := m.RenameHost("db", "database"); err != nil {
+		t.Fatalf("RenameHost failed: %v", err)
+	}
+	if m.rawLines[3] != `Host database "db backup"` {
+		t.Errorf("Unexpected Host line after rename: %q", m.rawLines[3])
+	}
+}
+
+// TestValidate_UnterminatedQuote 测试引号未闭合的参数会报错，命令类参数除外
+func TestValidate_UnterminatedQuote(t *testing.T) {
+	invalid := [][]string{
+		{"Host web", `    IdentityFile "/path/id_rsa`},
+		{`Host "web`},
+	}
+	for _, lines := range invalid {
+		if err := NewConfigValidator(lines).Validate(); err == nil {
+			t.Errorf("Validate(%q) should fail for an unterminated quote", lines)
+		}
+	}
+
+	valid := []string{"Host web", `    ProxyCommand sh -c "nc %h %p`}
+	if err := NewConfigValidator(valid).Validate(); err != nil {
+		t.Errorf("Validate should accept command parameters as is: %v", err)
+	}
+}
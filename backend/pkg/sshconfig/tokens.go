@@ -0,0 +1,125 @@
+package sshconfig
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"os/user"
+	"strings"
+)
+
+// Tokens 是 OpenSSH TOKENS 展开时使用的值
+type Tokens struct {
+	Alias      string // %n 命令行上的原始别名
+	HostName   string // %h 目标主机名
+	Port       string // %p 端口
+	RemoteUser string // %r 远程用户名
+	LocalUser  string // %u 本地用户名
+	HomeDir    string // %d 本地用户主目录
+	LocalHost  string // %l 本地主机名（含域名），%L 为其第一段
+}
+
+// ExpandTokens 计算 alias 的生效配置，并展开 value 中的 token 与 ${ENV} 环境变量，
+// 用于向用户展示 LocalCommand、ControlPath 等参数的实际值。
+// 未设置 HostName/Port/User 时使用 OpenSSH 的默认值（别名、22、本地用户名）。
+func (m *SSHConfigManager) ExpandTokens(alias, value string) string {
+	return ExpandTokensWith(value, m.TokensFor(alias))
+}
+
+// TokensFor 返回 alias 对应的 token 值
+func (m *SSHConfigManager) TokensFor(alias string) Tokens {
+	t := LocalTokens()
+	t.Alias = alias
+
+	cfg := m.ResolveHost(alias)
+	t.HostName = alias
+	if hostName := cfg.Get("HostName"); hostName != "" {
+		// HostName 本身可以引用 %h（即原始别名）
+		t.HostName = ExpandTokensWith(hostName, Tokens{HostName: alias})
+	}
+	t.Port = "22"
+	if port := cfg.Get("Port"); port != "" {
+		t.Port = port
+	}
+	t.RemoteUser = t.LocalUser
+	if u := cfg.Get("User"); u != "" {
+		t.RemoteUser = u
+	}
+	return t
+}
+
+// LocalTokens 返回只与本机相关的 token 值（%u、%d、%l）
+func LocalTokens() Tokens {
+	var t Tokens
+	if u, err := user.Current(); err == nil {
+		t.LocalUser = u.Username
+		// Windows 上用户名形如 DOMAIN\user
+		if i := strings.LastIndex(t.LocalUser, `\`); i >= 0 {
+			t.LocalUser = t.LocalUser[i+1:]
+		}
+	} else if name := os.Getenv("USER"); name != "" {
+		t.LocalUser = name
+	} else {
+		t.LocalUser = os.Getenv("USERNAME")
+	}
+	t.HomeDir, _ = os.UserHomeDir()
+	t.LocalHost, _ = os.Hostname()
+	return t
+}
+
+// ExpandTokensWith 使用给定的值展开 value 中的 token（%h %p %r %u %d %L %l %n %C %%）与 ${ENV}。
+// %C 按 OpenSSH 的定义取 %l%h%p%r 的 SHA1 十六进制值，常用于 ControlPath。
+// 未知的 token 与未设置的环境变量保持原样，便于用户看出哪些部分无法在本地解析。
+func ExpandTokensWith(value string, t Tokens) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '%' && i+1 < len(value):
+			i++
+			switch value[i] {
+			case 'h':
+				b.WriteString(t.HostName)
+			case 'p':
+				b.WriteString(t.Port)
+			case 'r':
+				b.WriteString(t.RemoteUser)
+			case 'u':
+				b.WriteString(t.LocalUser)
+			case 'd':
+				b.WriteString(t.HomeDir)
+			case 'l':
+				b.WriteString(t.LocalHost)
+			case 'L':
+				short, _, _ := strings.Cut(t.LocalHost, ".")
+				b.WriteString(short)
+			case 'n':
+				b.WriteString(t.Alias)
+			case 'C':
+				sum := sha1.Sum([]byte(t.LocalHost + t.HostName + t.Port + t.RemoteUser))
+				b.WriteString(hex.EncodeToString(sum[:]))
+			case '%':
+				b.WriteByte('%')
+			default:
+				b.WriteByte('%')
+				b.WriteByte(value[i])
+			}
+		case c == '$' && i+1 < len(value) && value[i+1] == '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				b.WriteString(value[i:])
+				return b.String()
+			}
+			name := value[i+2 : i+2+end]
+			if env, ok := os.LookupEnv(name); ok && name != "" {
+				b.WriteString(env)
+			} else {
+				b.WriteString(value[i : i+3+end])
+			}
+			i += 2 + end
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
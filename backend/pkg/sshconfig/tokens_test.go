@@ -0,0 +1,57 @@
+package sshconfig
+
+import "testing"
+
+// TestExpandTokensWith 测试 token 与环境变量展开
+func TestExpandTokensWith(t *testing.T) {
+	t.Setenv("DEVTOOLS_TEST_DIR", "/tmp/devtools")
+	tokens := Tokens{
+		Alias:      "web",
+		HostName:   "web.example.com",
+		Port:       "2222",
+		RemoteUser: "deploy",
+		LocalUser:  "alice",
+		HomeDir:    "/home/alice",
+		LocalHost:  "laptop.lan",
+	}
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"%d/.ssh/cm-%r@%h:%p", "/home/alice/.ssh/cm-deploy@web.example.com:2222"},
+		{"%u@%L (%l) -> %n", "alice@laptop (laptop.lan) -> web"},
+		{"100%%", "100%"},
+		{"~/.ssh/cm-%C", "~/.ssh/cm-a7776e2fb909b8502f635d32738518385edb0c09"},
+		{"%x %", "%x %"},
+		{"${DEVTOOLS_TEST_DIR}/%h.sock", "/tmp/devtools/web.example.com.sock"},
+		{"${DEVTOOLS_UNSET_VAR}/x", "${DEVTOOLS_UNSET_VAR}/x"},
+		{"${unterminated", "${unterminated"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := ExpandTokensWith(tt.value, tokens); got != tt.want {
+			t.Errorf("ExpandTokensWith(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestExpandTokens_UsesEffectiveConfig 测试从生效配置中取值，并使用 OpenSSH 默认值
+func TestExpandTokens_UsesEffectiveConfig(t *testing.T) {
+	m := &SSHConfigManager{rawLines: []string{
+		"Host web",
+		"    HostName %h.example.com",
+		"    User deploy",
+		"Host *",
+		"    Port 2200",
+	}}
+
+	if got := m.ExpandTokens("web", "%r@%h:%p"); got != "deploy@web.example.com:2200" {
+		t.Errorf("Unexpected expansion for web: %q", got)
+	}
+
+	local := LocalTokens()
+	if got := m.ExpandTokens("other", "%r@%h"); got != local.LocalUser+"@other" {
+		t.Errorf("Unexpected expansion for other: %q", got)
+	}
+}
@@ -2,12 +2,23 @@ package sshconfig
 
 import (
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
 )
 
+// commandKeywords 是值为 shell 命令的参数（小写），ssh 不会按引号拆分它们的值
+var commandKeywords = map[string]bool{
+	"proxycommand":      true,
+	"localcommand":      true,
+	"remotecommand":     true,
+	"knownhostscommand": true,
+}
+
 // ConfigValidator SSH配置验证器
 type ConfigValidator struct {
 	lines []string
+	rules []ValidationRule // 调用方通过 AddRule 注册的额外规则
 }
 
 // NewConfigValidator 创建新的配置验证器
@@ -34,23 +45,20 @@ func (v *ConfigValidator) Validate() error {
 		}
 	}
 
-	return nil
+	// 语法正确后再执行额外规则，警告级别的诊断不影响结果
+	return v.validateRules()
 }
 
 // validateConfigLine 验证单个配置行
 func (v *ConfigValidator) validateConfigLine(line string, lineNumber int) error {
-	// Host指令验证 - 检查原始行是否以"Host "开头
-	if strings.HasPrefix(line, "Host ") {
+	// ssh 不关心缩进，关键字与参数之间可以是空白或 "="（如 "Host=web"）
+	keyword, _ := splitDirective(line)
+	switch strings.ToLower(keyword) {
+	case "host": // Host指令验证
 		return v.validateHostLine(line, lineNumber)
-	}
-
-	// Include指令验证
-	if strings.HasPrefix(line, "Include ") {
+	case "include": // Include指令验证
 		return v.validateIncludeLine(line, lineNumber)
-	}
-
-	// Match指令验证（可选支持）
-	if strings.HasPrefix(line, "Match ") {
+	case "match": // Match指令验证
 		return v.validateMatchLine(line, lineNumber)
 	}
 
@@ -60,16 +68,17 @@ func (v *ConfigValidator) validateConfigLine(line string, lineNumber int) error
 
 // validateHostLine 验证Host行
 func (v *ConfigValidator) validateHostLine(line string, lineNumber int) error {
-	if !strings.HasPrefix(line, "Host ") {
+	trimmedHostPart, ok := cutDirective(line, "Host")
+	if !ok {
 		return &ConfigError{"validate", fmt.Errorf("line %d: not a valid Host line", lineNumber)}
 	}
 
-	hostPart := strings.TrimPrefix(line, "Host ")
-	trimmedHostPart := strings.TrimSpace(hostPart)
-
 	if trimmedHostPart == "" {
 		return &ConfigError{"validate", fmt.Errorf("line %d: Host directive requires at least one hostname", lineNumber)}
 	}
+	if _, err := splitArgs(trimmedHostPart); err != nil {
+		return &ConfigError{"validate", fmt.Errorf("line %d: Host: %v", lineNumber, err)}
+	}
 
 	// 验证主机名格式
 	hostNames := parseHostNames(trimmedHostPart)
@@ -88,16 +97,17 @@ func (v *ConfigValidator) validateHostLine(line string, lineNumber int) error {
 
 // validateIncludeLine 验证Include行
 func (v *ConfigValidator) validateIncludeLine(line string, lineNumber int) error {
-	if !strings.HasPrefix(line, "Include ") {
+	includePart, ok := cutDirective(line, "Include")
+	if !ok {
 		return &ConfigError{"validate", fmt.Errorf("line %d: not a valid Include line", lineNumber)}
 	}
 
-	includePart := strings.TrimPrefix(line, "Include ")
-	trimmedIncludePart := strings.TrimSpace(includePart)
-
-	if trimmedIncludePart == "" {
+	if includePart == "" {
 		return &ConfigError{"validate", fmt.Errorf("line %d: Include directive requires a path", lineNumber)}
 	}
+	if _, err := splitArgs(includePart); err != nil {
+		return &ConfigError{"validate", fmt.Errorf("line %d: Include: %v", lineNumber, err)}
+	}
 
 	// 基本路径格式验证
 	if strings.Contains(includePart, "\n") {
@@ -111,17 +121,19 @@ func (v *ConfigValidator) validateIncludeLine(line string, lineNumber int) error
 func (v *ConfigValidator) validateParamLine(line string, lineNumber int) error {
 	trimmed := strings.TrimSpace(line)
 
-	// 参数行必须有缩进（空格或制表符）
-	if len(line) > 0 && line[0] != ' ' && line[0] != '\t' {
-		return &ConfigError{"validate", fmt.Errorf("line %d: parameter lines must be indented", lineNumber)}
-	}
-
-	// 解析参数
+	// 解析参数（缩进只是习惯，ssh 不要求）
 	key, value := parseParamLine(trimmed)
 	if key == "" && trimmed != "" {
 		return &ConfigError{"validate", fmt.Errorf("line %d: invalid parameter format", lineNumber)}
 	}
 
+	// 命令类参数整行交给 shell 执行，其余参数的引号必须闭合
+	if _, rest := splitDirective(trimmed); !commandKeywords[strings.ToLower(key)] {
+		if _, err := splitArgs(rest); err != nil {
+			return &ConfigError{"validate", fmt.Errorf("line %d: %s: %v", lineNumber, key, err)}
+		}
+	}
+
 	// 验证参数值（基本验证）
 	if err := v.validateParamValue(key, value, lineNumber); err != nil {
 		return err
@@ -130,36 +142,39 @@ func (v *ConfigValidator) validateParamLine(line string, lineNumber int) error {
 	return nil
 }
 
-// validateMatchLine 验证Match行
-// validateMatchLine 验证Match行
+// matchCriteria 是 ssh_config(5) 中 Match 的条件（小写）及其是否需要参数。
+// address、localaddress、localport 与 rdomain 属于 sshd_config，为兼容共用的配置片段也接受。
+var matchCriteria = map[string]bool{
+	"all": false, "canonical": false, "final": false,
+	"exec": true, "localnetwork": true, "host": true, "originalhost": true, "tagged": true,
+	"command": true, "user": true, "localuser": true, "version": true, "sessiontype": true,
+	"address": true, "localaddress": true, "localport": true, "rdomain": true,
+}
+
+// validateMatchLine 验证Match行：条件可以用 "!" 取反，all、canonical 与 final 没有参数
 func (v *ConfigValidator) validateMatchLine(line string, lineNumber int) error {
-	matchPart := strings.TrimPrefix(strings.TrimSpace(line), "Match ")
-	if strings.TrimSpace(matchPart) == "" {
+	matchPart, _ := cutDirective(line, "Match")
+	if matchPart == "" {
 		return &ConfigError{"validate", fmt.Errorf("line %d: Match directive requires criteria", lineNumber)}
 	}
-	// 基本验证
-	validCriteria := []string{"User", "Host", "Address", "LocalAddress", "LocalPort", "RDomain", "Canonical", "All"}
-	criteria := strings.Fields(matchPart)
-	for i := 0; i < len(criteria); i += 2 {
-		if i+1 >= len(criteria) {
-			return &ConfigError{"validate", fmt.Errorf("line %d: Match criteria incomplete", lineNumber)}
-		}
+	criteria, err := splitArgs(matchPart)
+	if err != nil {
+		return &ConfigError{"validate", fmt.Errorf("line %d: Match: %v", lineNumber, err)}
+	}
+	for i := 0; i < len(criteria); i++ {
 		criterion := criteria[i]
-		value := criteria[i+1]
-		valid := false
-		for _, validCriterion := range validCriteria {
-			if strings.EqualFold(criterion, validCriterion) {
-				valid = true
-				break
-			}
-		}
+		needsValue, valid := matchCriteria[strings.ToLower(strings.TrimPrefix(criterion, "!"))]
 		if !valid {
 			return &ConfigError{"validate", fmt.Errorf("line %d: invalid Match criterion '%s'", lineNumber, criterion)}
 		}
-		// 验证值格式
-		// 去除首尾空格和引号后检查是否为空
-		finalValue := strings.Trim(strings.TrimSpace(value), "\"'")
-		if finalValue == "" {
+		if !needsValue {
+			continue
+		}
+		if i+1 >= len(criteria) {
+			return &ConfigError{"validate", fmt.Errorf("line %d: Match criteria incomplete", lineNumber)}
+		}
+		i++
+		if strings.TrimSpace(criteria[i]) == "" {
 			return &ConfigError{"validate", fmt.Errorf("line %d: Match criterion '%s' requires a value", lineNumber, criterion)}
 		}
 	}
@@ -177,7 +192,7 @@ func (v *ConfigValidator) validateParamValue(key, value string, lineNumber int)
 		}
 	}
 
-	// 常见参数的值验证
+	// 常见参数的值验证；取值不在已知范围内的参数只产生警告，见 checkParamValue
 	switch lowerKey {
 	case "port":
 		if value != "" {
@@ -189,23 +204,160 @@ func (v *ConfigValidator) validateParamValue(key, value string, lineNumber int)
 				return &ConfigError{"validate", fmt.Errorf("line %d: Port must be between 1 and 65535", lineNumber)}
 			}
 		}
-	case "serveraliveinterval", "serveralivemaxcount", "connecttimeout":
-		if value != "" && !v.isNumeric(value) {
-			return &ConfigError{"validate", fmt.Errorf("line %d: %s must be numeric", lineNumber, key)}
+	case "localforward", "remoteforward", "dynamicforward":
+		if err := validateForward(lowerKey, value); err != nil {
+			return &ConfigError{"validate", fmt.Errorf("line %d: %s: %v", lineNumber, key, err)}
+		}
+	}
+
+	return nil
+}
+
+// validateForward 检查转发指令的参数，格式与 ssh(1) 的 -L/-R/-D 相同：
+//
+//	LocalForward   [bind_address:]port host:hostport
+//	RemoteForward  [bind_address:]port [host:hostport]
+//	DynamicForward [bind_address:]port
+//
+// bind_address 可以是 "*"、主机名、IPv4 或用方括号包围的 IPv6 地址（也可以写成 address/port），
+// 以 "/" 或 "~" 开头的参数是 Unix 域套接字路径。RemoteForward 的端口可以是 0，由服务器分配。
+func validateForward(keyword, value string) error {
+	args, err := splitArgs(value)
+	if err != nil {
+		return err
+	}
+	switch keyword {
+	case "localforward":
+		if len(args) != 2 {
+			return fmt.Errorf("expected '[bind_address:]port host:hostport', got %d argument(s)", len(args))
+		}
+		if err := validateForwardListen(args[0], false); err != nil {
+			return err
+		}
+		return validateForwardTarget(args[1])
+	case "remoteforward":
+		if len(args) != 1 && len(args) != 2 {
+			return fmt.Errorf("expected '[bind_address:]port [host:hostport]', got %d argument(s)", len(args))
+		}
+		if err := validateForwardListen(args[0], true); err != nil {
+			return err
 		}
-	case "compression", "tcpkeepalive", "usedns", "useprivilegedport", "stricthostkeychecking":
-		if value != "" && !v.isValidYesNo(value) {
-			return &ConfigError{"validate", fmt.Errorf("line %d: %s must be 'yes' or 'no'", lineNumber, key)}
+		if len(args) == 2 {
+			return validateForwardTarget(args[1])
 		}
-	case "protocol":
-		if value != "" && value != "1" && value != "2" {
-			return &ConfigError{"validate", fmt.Errorf("line %d: Protocol must be '1' or '2'", lineNumber)}
+	case "dynamicforward":
+		if len(args) != 1 {
+			return fmt.Errorf("expected '[bind_address:]port', got %d argument(s)", len(args))
 		}
+		if isSocketPath(args[0]) {
+			return fmt.Errorf("dynamic forwarding does not support Unix socket '%s'", args[0])
+		}
+		return validateForwardListen(args[0], false)
 	}
+	return nil
+}
 
+// validateForwardListen 检查监听端的 [bind_address:]port
+func validateForwardListen(spec string, allowZeroPort bool) error {
+	if isSocketPath(spec) {
+		return nil
+	}
+	addr, port, err := splitForwardSpec(spec)
+	if err != nil {
+		return err
+	}
+	if addr != "" && addr != "*" && !isValidForwardHost(addr) {
+		return fmt.Errorf("invalid bind address '%s'", addr)
+	}
+	return validateForwardPort(port, allowZeroPort)
+}
+
+// validateForwardTarget 检查目标端的 host:hostport
+func validateForwardTarget(spec string) error {
+	if isSocketPath(spec) {
+		return nil
+	}
+	host, port, err := splitForwardSpec(spec)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("forward target '%s' must be host:hostport", spec)
+	}
+	if !isValidForwardHost(host) {
+		return fmt.Errorf("invalid forward target host '%s'", host)
+	}
+	return validateForwardPort(port, false)
+}
+
+// splitForwardSpec 将 "addr:port"、"[addr]:port"、"addr/port" 或 "port" 拆分为地址与端口
+func splitForwardSpec(spec string) (addr, port string, err error) {
+	if strings.HasPrefix(spec, "[") {
+		end := strings.Index(spec, "]")
+		if end < 0 {
+			return "", "", fmt.Errorf("missing ']' in '%s'", spec)
+		}
+		rest := spec[end+1:]
+		if len(rest) < 1 || (rest[0] != ':' && rest[0] != '/') {
+			return "", "", fmt.Errorf("missing port after '%s'", spec[:end+1])
+		}
+		addr = spec[1:end]
+		if net.ParseIP(addr) == nil {
+			return "", "", fmt.Errorf("'%s' is not an IP address", addr)
+		}
+		return addr, rest[1:], nil
+	}
+	i := strings.LastIndexAny(spec, ":/")
+	if i < 0 {
+		return "", spec, nil
+	}
+	addr, port = spec[:i], spec[i+1:]
+	if spec[i] == ':' && strings.Contains(addr, ":") {
+		return "", "", fmt.Errorf("IPv6 address in '%s' must be enclosed in brackets or use address/port", spec)
+	}
+	return addr, port, nil
+}
+
+// validateForwardPort 检查端口是否为 1-65535（allowZero 时允许 0）
+func validateForwardPort(port string, allowZero bool) error {
+	if port == "" {
+		return fmt.Errorf("missing port")
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || strings.HasPrefix(port, "+") || strings.HasPrefix(port, "-") {
+		return fmt.Errorf("port '%s' must be numeric", port)
+	}
+	lowest := 1
+	if allowZero {
+		lowest = 0
+	}
+	if n < lowest || n > 65535 {
+		return fmt.Errorf("port %d must be between %d and 65535", n, lowest)
+	}
 	return nil
 }
 
+// isValidForwardHost 检查转发中的主机：IP 地址或由字母、数字、"."、"-"、"_" 组成的主机名
+func isValidForwardHost(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	if len(host) > 253 {
+		return false
+	}
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// isSocketPath 判断转发参数是否是 Unix 域套接字路径
+func isSocketPath(spec string) bool {
+	return strings.HasPrefix(spec, "/") || strings.HasPrefix(spec, "~")
+}
+
 // validateHostname 验证主机名格式
 func (v *ConfigValidator) validateHostname(hostname string) error {
 	if hostname == "" {
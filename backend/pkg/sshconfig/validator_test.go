@@ -64,21 +64,22 @@ func TestValidate_ValidConfigWithInclude(t *testing.T) {
 	}
 }
 
-// TestValidate_ParameterLineNotIndented 测试参数行未缩进
+// TestValidate_ParameterLineNotIndented 测试参数行未缩进（ssh 不要求缩进）
 func TestValidate_ParameterLineNotIndented(t *testing.T) {
 	lines := []string{
 		"Host test",
 		"HostName example.com", // 未缩进的参数行
+		"  Host indented",      // 缩进的 Host 行同样是指令
+		"Port abc",
 	}
 
 	validator := NewConfigValidator(lines)
-	err := validator.Validate()
+	diagnostics, err := validator.Check(false)
 	if err == nil {
-		t.Error("Validate should fail for unindented parameter line")
+		t.Fatal("Validate should still check unindented parameter lines")
 	}
-
-	if err.Error() != "ssh config validate: line 2: parameter lines must be indented" {
-		t.Errorf("Expected specific error message, got: %v", err)
+	if len(diagnostics) != 1 || diagnostics[0].Line != 4 {
+		t.Errorf("only the invalid port should be reported, got %+v", diagnostics)
 	}
 }
 
@@ -130,6 +131,7 @@ func TestValidate_InvalidYesNo(t *testing.T) {
 		{"", true}, // 空值应该通过
 	}
 
+	// 未知的取值只产生警告，不阻止保存
 	for _, tc := range testCases {
 		lines := []string{
 			"Host test",
@@ -137,12 +139,14 @@ func TestValidate_InvalidYesNo(t *testing.T) {
 		}
 
 		validator := NewConfigValidator(lines)
-		err := validator.Validate()
-
-		if tc.expected && err != nil {
-			t.Errorf("Compression value '%s' should pass validation, but got error: %v", tc.value, err)
-		} else if !tc.expected && err == nil {
-			t.Errorf("Compression value '%s' should fail validation, but passed", tc.value)
+		if err := validator.Validate(); err != nil {
+			t.Errorf("Compression value '%s' should not fail validation: %v", tc.value, err)
+		}
+		warned := hasRule(validator.Diagnostics(), InvalidValueRuleID)
+		if tc.expected && warned {
+			t.Errorf("Compression value '%s' should not be warned about", tc.value)
+		} else if !tc.expected && !warned {
+			t.Errorf("Compression value '%s' should be warned about", tc.value)
 		}
 	}
 }
@@ -156,10 +160,12 @@ func TestValidate_InvalidProtocol(t *testing.T) {
 		{"1", true},
 		{"2", true},
 		{"3", false},
-		{"1,2", false}, // 虽然SSH支持，但我们简化验证
-		{"", true},     // 空值应该通过
+		{"1,2", true},
+		{"2,1", true},
+		{"", true}, // 空值应该通过
 	}
 
+	// 已废弃的参数，取值有问题时只产生警告
 	for _, tc := range testCases {
 		lines := []string{
 			"Host test",
@@ -167,12 +173,14 @@ func TestValidate_InvalidProtocol(t *testing.T) {
 		}
 
 		validator := NewConfigValidator(lines)
-		err := validator.Validate()
-
-		if tc.expected && err != nil {
-			t.Errorf("Protocol %s should pass validation, but got error: %v", tc.protocol, err)
-		} else if !tc.expected && err == nil {
-			t.Errorf("Protocol %s should fail validation, but passed", tc.protocol)
+		if err := validator.Validate(); err != nil {
+			t.Errorf("Protocol %s should not fail validation: %v", tc.protocol, err)
+		}
+		warned := hasRule(validator.Diagnostics(), InvalidValueRuleID)
+		if tc.expected && warned {
+			t.Errorf("Protocol %s should not be warned about", tc.protocol)
+		} else if !tc.expected && !warned {
+			t.Errorf("Protocol %s should be warned about", tc.protocol)
 		}
 	}
 }
@@ -258,6 +266,27 @@ func TestValidate_ValidMatchLine(t *testing.T) {
 	}
 }
 
+// TestValidate_MatchCriteria 测试 ssh_config(5) 中的所有 Match 条件都被接受
+func TestValidate_MatchCriteria(t *testing.T) {
+	for _, match := range []string{
+		"Match all",
+		"Match canonical all",
+		"Match final host *.corp",
+		"Match exec \"test -f ~/.vpn\"",
+		"Match !exec \"nc -z bastion 22\" host prod-*",
+		"Match localnetwork 10.0.0.0/8",
+		"Match originalhost web user deploy localuser me",
+		"Match tagged work",
+		"Match version OpenSSH_9*",
+		"Match sessiontype shell command \"*\"",
+	} {
+		validator := NewConfigValidator([]string{match, "    User admin"})
+		if err := validator.Validate(); err != nil {
+			t.Errorf("Match line '%s' should pass validation: %v", match, err)
+		}
+	}
+}
+
 // TestValidate_InvalidMatchLine 测试无效的Match行
 func TestValidate_InvalidMatchLine(t *testing.T) {
 	testCases := []string{
@@ -354,8 +383,6 @@ func TestValidateParamValue_Port(t *testing.T) {
 
 // TestValidateParamValue_YesNo 测试yes/no参数值验证
 func TestValidateParamValue_YesNo(t *testing.T) {
-	validator := NewConfigValidator([]string{})
-
 	testCases := []struct {
 		value    string
 		line     int
@@ -372,36 +399,43 @@ func TestValidateParamValue_YesNo(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		err := validator.validateParamValue("Compression", tc.value, tc.line)
-		if tc.expected && err != nil {
-			t.Errorf("Value '%s' should pass validation, but got error: %v", tc.value, err)
-		} else if !tc.expected && err == nil {
-			t.Errorf("Value '%s' should fail validation, but passed", tc.value)
+		if tc.value == "" {
+			continue // 空值不会被检查
+		}
+		message := checkParamValue("Compression", tc.value)
+		if tc.expected && message != "" {
+			t.Errorf("Value '%s' should pass validation, but got warning: %s", tc.value, message)
+		} else if !tc.expected && message == "" {
+			t.Errorf("Value '%s' should be warned about, but passed", tc.value)
+		}
+	}
+
+	// StrictHostKeyChecking 还接受 ask、accept-new 与 off
+	for _, value := range []string{"accept-new", "ask", "off", "yes"} {
+		if message := checkParamValue("StrictHostKeyChecking", value); message != "" {
+			t.Errorf("StrictHostKeyChecking %s should be accepted, got warning: %s", value, message)
 		}
 	}
 }
 
 // TestValidateParamValue_Protocol 测试协议参数值验证
 func TestValidateParamValue_Protocol(t *testing.T) {
-	validator := NewConfigValidator([]string{})
-
 	testCases := []struct {
 		protocol string
-		line     int
 		expected bool // true表示应该通过验证
 	}{
-		{"1", 1, true},
-		{"2", 1, true},
-		{"3", 1, false},
-		{"", 1, true}, // 空值应该通过
+		{"1", true},
+		{"2", true},
+		{"2,1", true},
+		{"3", false},
 	}
 
 	for _, tc := range testCases {
-		err := validator.validateParamValue("Protocol", tc.protocol, tc.line)
-		if tc.expected && err != nil {
-			t.Errorf("Protocol '%s' should pass validation, but got error: %v", tc.protocol, err)
-		} else if !tc.expected && err == nil {
-			t.Errorf("Protocol '%s' should fail validation, but passed", tc.protocol)
+		message := checkParamValue("Protocol", tc.protocol)
+		if tc.expected && message != "" {
+			t.Errorf("Protocol '%s' should pass validation, but got warning: %s", tc.protocol, message)
+		} else if !tc.expected && message == "" {
+			t.Errorf("Protocol '%s' should be warned about, but passed", tc.protocol)
 		}
 	}
 }
@@ -519,10 +553,10 @@ func TestValidateParamLine(t *testing.T) {
 		t.Errorf("Valid parameter line should pass: %v", err)
 	}
 
-	// 无效的参数行（未缩进）
+	// 未缩进的参数行同样有效
 	err = validator.validateParamLine("HostName example.com", 1)
-	if err == nil {
-		t.Error("Unindented parameter line should fail")
+	if err != nil {
+		t.Errorf("Unindented parameter line should pass: %v", err)
 	}
 }
 
@@ -756,14 +790,15 @@ func TestValidateMatchLine_EmptyValue(t *testing.T) {
 
 // TestValidateParamValue_NumericError 测试数值参数的错误值
 func TestValidateParamValue_NumericError(t *testing.T) {
-	validator := NewConfigValidator([]string{})
-
-	// 测试非数字的ServerAliveInterval
-	err := validator.validateParamValue("ServerAliveInterval", "not_a_number", 1)
-	if err == nil {
-		t.Error("validateParamValue should fail for non-numeric ServerAliveInterval")
+	// 测试非数字的ServerAliveInterval，时间格式（如 1m30s）是有效的
+	if message := checkParamValue("ServerAliveInterval", "not_a_number"); message == "" {
+		t.Error("checkParamValue should warn about a non-numeric ServerAliveInterval")
+	}
+	for _, value := range []string{"30", "1m30s", "2h"} {
+		if message := checkParamValue("ServerAliveInterval", value); message != "" {
+			t.Errorf("ServerAliveInterval %s should be accepted, got warning: %s", value, message)
+		}
 	}
-	t.Logf("validateParamValue error (expected): %v", err)
 }
 
 // TestValidateHostname_TooLong 测试过长的主机名
@@ -896,3 +931,90 @@ func TestValidateParamValue_NonRequiredParamEmpty(t *testing.T) {
 		// 注意：validateParamValue 对于空的 Compression 值不会触发 yes/no 检查，因为 `value != ""` 条件不满足。
 	}
 }
+
+// TestValidate_ForwardDirectives 测试 LocalForward/RemoteForward/DynamicForward 的参数格式
+func TestValidate_ForwardDirectives(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected bool // true表示应该通过验证
+	}{
+		// LocalForward
+		{"LocalForward 8080 localhost:80", true},
+		{"LocalForward 127.0.0.1:8080 db.internal:5432", true},
+		{"LocalForward *:8080 10.0.0.5:80", true},
+		{"LocalForward :8080 localhost:80", true},
+		{"LocalForward [::1]:8080 [2001:db8::1]:80", true},
+		{"LocalForward ::1/8080 localhost:80", true},
+		{"LocalForward=8080 localhost:80", true},
+		{"LocalForward /tmp/local.sock /var/run/docker.sock", true},
+		{"LocalForward 8080", false},
+		{"LocalForward", false},
+		{"LocalForward 8080 localhost", false},
+		{"LocalForward 8080 localhost:", false},
+		{"LocalForward 0 localhost:80", false},
+		{"LocalForward 65536 localhost:80", false},
+		{"LocalForward abc localhost:80", false},
+		{"LocalForward 8080 localhost:http", false},
+		{"LocalForward ::1:8080 localhost:80", false},
+		{"LocalForward [::1 localhost:80", false},
+		{"LocalForward [not-ip]:8080 localhost:80", false},
+		{"LocalForward bad!addr:8080 localhost:80", false},
+		{"LocalForward 8080 :80", false},
+		{"LocalForward 8080 localhost:80 extra", false},
+		// RemoteForward
+		{"RemoteForward 9000 localhost:3000", true},
+		{"RemoteForward 0 localhost:3000", true},
+		{"RemoteForward 1080", true},
+		{"RemoteForward 0.0.0.0:9000 localhost:3000", true},
+		{"RemoteForward -1 localhost:3000", false},
+		{"RemoteForward 9000 localhost:99999", false},
+		// DynamicForward
+		{"DynamicForward 1080", true},
+		{"DynamicForward localhost:1080", true},
+		{"DynamicForward [::1]:1080", true},
+		{"DynamicForward 1080 localhost:80", false},
+		{"DynamicForward /tmp/socks.sock", false},
+		{"DynamicForward 70000", false},
+	}
+
+	for _, tc := range testCases {
+		lines := []string{
+			"Host test",
+			"    " + tc.line,
+		}
+
+		err := NewConfigValidator(lines).Validate()
+		if tc.expected && err != nil {
+			t.Errorf("'%s' should pass validation, but got error: %v", tc.line, err)
+		} else if !tc.expected && err == nil {
+			t.Errorf("'%s' should fail validation, but passed", tc.line)
+		}
+	}
+}
+
+// TestValidate_ForwardErrorMessage 测试转发错误信息包含行号与指令名
+func TestValidate_ForwardErrorMessage(t *testing.T) {
+	lines := []string{
+		"Host test",
+		"    LocalForward 8080",
+	}
+
+	err := NewConfigValidator(lines).Validate()
+	if err == nil {
+		t.Fatal("Validate should fail for LocalForward without a target")
+	}
+	want := "ssh config validate: line 2: LocalForward: expected '[bind_address:]port host:hostport', got 1 argument(s)"
+	if err.Error() != want {
+		t.Errorf("Expected %q, got: %v", want, err)
+	}
+}
+
+// hasRule 判断诊断中是否有 rule 的结果
+func hasRule(diagnostics []Diagnostic, rule string) bool {
+	for _, d := range diagnostics {
+		if d.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
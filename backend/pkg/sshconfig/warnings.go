@@ -0,0 +1,166 @@
+package sshconfig
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// 内置的警告级别检查：ssh 能够读取这些配置，只是很可能不是用户想要的结果，因此不阻止保存
+const (
+	UnknownOptionRuleID  = "unknown-option"
+	DuplicateParamRuleID = "duplicate-param"
+	InvalidValueRuleID   = "invalid-value"
+)
+
+// yesNoValues 是布尔参数的取值
+var yesNoValues = []string{"yes", "no", "true", "false"}
+
+// enumValues 是取值有限的参数（小写）的已知取值。新版本的 ssh 可能增加取值，
+// 旧版本可能不认识新的取值，因此其他值只产生警告。
+var enumValues = map[string][]string{
+	"compression":           yesNoValues,
+	"tcpkeepalive":          yesNoValues,
+	"usedns":                yesNoValues,
+	"useprivilegedport":     yesNoValues,
+	"stricthostkeychecking": append([]string{"ask", "accept-new", "off"}, yesNoValues...),
+}
+
+// knownKeywords 是 ssh_config(5) 中的关键字（小写），包括已废弃但仍被接受的关键字，
+// 以及常见发行版补丁增加的关键字（例如 macOS 的 UseKeychain）
+var knownKeywords = map[string]bool{
+	"host": true, "match": true, "include": true,
+	"addkeystoagent": true, "addressfamily": true, "batchmode": true, "bindaddress": true, "bindinterface": true,
+	"canonicaldomains": true, "canonicalizefallbacklocal": true, "canonicalizehostname": true, "canonicalizemaxdots": true,
+	"canonicalizepermittedcnames": true, "casignaturealgorithms": true, "certificatefile": true, "channeltimeout": true,
+	"checkhostip": true, "ciphers": true, "clearallforwardings": true, "compression": true, "connectionattempts": true,
+	"connecttimeout": true, "controlmaster": true, "controlpath": true, "controlpersist": true, "dynamicforward": true,
+	"enableescapecommandline": true, "enablesshkeysign": true, "escapechar": true, "exitonforwardfailure": true,
+	"fingerprinthash": true, "forkafterauthentication": true, "forwardagent": true, "forwardx11": true,
+	"forwardx11timeout": true, "forwardx11trusted": true, "gatewayports": true, "globalknownhostsfile": true,
+	"gssapiauthentication": true, "gssapidelegatecredentials": true, "hashknownhosts": true,
+	"hostbasedacceptedalgorithms": true, "hostbasedauthentication": true, "hostbasedkeytypes": true,
+	"hostkeyalgorithms": true, "hostkeyalias": true, "hostname": true, "identitiesonly": true, "identityagent": true,
+	"identityfile": true, "ignoreunknown": true, "ipqos": true, "kbdinteractiveauthentication": true,
+	"kbdinteractivedevices": true, "kexalgorithms": true, "knownhostscommand": true, "localcommand": true,
+	"localforward": true, "loglevel": true, "logverbose": true, "macs": true, "nohostauthenticationforlocalhost": true,
+	"numberofpasswordprompts": true, "obscurekeystroketiming": true, "passwordauthentication": true,
+	"permitlocalcommand": true, "permitremoteopen": true, "pkcs11provider": true, "port": true,
+	"preferredauthentications": true, "proxycommand": true, "proxyjump": true, "proxyusefdpass": true,
+	"pubkeyacceptedalgorithms": true, "pubkeyacceptedkeytypes": true, "pubkeyauthentication": true, "rekeylimit": true,
+	"refuseconnection": true, "remotecommand": true, "remoteforward": true, "requesttty": true, "requiredrsasize": true,
+	"revokedhostkeys": true, "securitykeyprovider": true, "sendenv": true, "serveralivecountmax": true,
+	"serveraliveinterval": true, "sessiontype": true, "setenv": true, "stdinnull": true, "streamlocalbindmask": true,
+	"streamlocalbindunlink": true, "stricthostkeychecking": true, "syslogfacility": true, "tag": true,
+	"tcpkeepalive": true, "tunnel": true, "tunneldevice": true, "updatehostkeys": true, "user": true,
+	"userknownhostsfile": true, "verifyhostkeydns": true, "versionaddendum": true, "visualhostkey": true,
+	"warnweakcrypto": true, "xauthlocation": true,
+	// 已废弃，ssh 仍然接受（忽略或给出提示）
+	"challengeresponseauthentication": true, "cipher": true, "compressionlevel": true, "protocol": true,
+	"rhostsrsaauthentication": true, "rsaauthentication": true, "useprivilegedport": true, "usersh": true,
+	"fallbacktorsh": true, "useroaming": true, "usedns": true,
+	// 发行版补丁
+	"usekeychain": true, "gssapikeyexchange": true, "gssapiclientidentity": true, "gssapiserveridentity": true,
+	"gssapirenewalforcesrekey": true, "gssapitrustdns": true, "gssapikexalgorithms": true,
+}
+
+// warningDiagnostics 返回内置的警告：未知的参数（IgnoreUnknown 列出的除外）、取值不在已知范围内的参数，
+// 以及同一块中重复设置的参数。
+// hasError 标记的行已经有语法错误，不再重复报告。
+func (v *ConfigValidator) warningDiagnostics(hasError map[int]bool) []Diagnostic {
+	var ignoreUnknown []string
+	for _, line := range v.lines {
+		if key, value := parseParamLine(line); strings.EqualFold(key, "IgnoreUnknown") {
+			ignoreUnknown = append(ignoreUnknown, strings.Split(value, ",")...)
+		}
+	}
+
+	var diagnostics []Diagnostic
+	seen := make(map[string]int) // 当前块中的小写参数名 → 第一次出现的行号
+	for i, line := range v.lines {
+		lineNumber := i + 1
+		if isDirective(line, "Host", "Match") {
+			seen = make(map[string]int)
+			continue
+		}
+		key, _ := parseParamLine(line)
+		if key == "" || hasError[lineNumber] {
+			continue
+		}
+		lower := strings.ToLower(key)
+
+		if !knownKeywords[lower] && !matchesAny(ignoreUnknown, lower) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:     lineNumber,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("unknown option '%s'", key),
+				Rule:     UnknownOptionRuleID,
+			})
+			continue
+		}
+		if _, value := parseParamLine(line); value != "" {
+			if message := checkParamValue(key, value); message != "" {
+				diagnostics = append(diagnostics, Diagnostic{
+					Line:     lineNumber,
+					Severity: SeverityWarning,
+					Message:  message,
+					Rule:     InvalidValueRuleID,
+				})
+			}
+		}
+		if accumulatingKeys[lower] {
+			continue
+		}
+		if first, ok := seen[lower]; ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:     lineNumber,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("%s is already set on line %d, only the first value takes effect", key, first),
+				Rule:     DuplicateParamRuleID,
+			})
+			continue
+		}
+		seen[lower] = lineNumber
+	}
+	return diagnostics
+}
+
+// matchesAny 判断小写的 keyword 是否匹配 patterns 中的任一通配符模式（不区分大小写）
+func matchesAny(patterns []string, keyword string) bool {
+	for _, p := range patterns {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" && wildcardMatch(p, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkParamValue 检查参数的取值，返回警告信息；取值正常或不检查该参数时返回空字符串
+func checkParamValue(key, value string) string {
+	lower := strings.ToLower(key)
+	if values, ok := enumValues[lower]; ok {
+		if !slices.Contains(values, strings.ToLower(value)) {
+			return fmt.Sprintf("unknown value '%s' for %s, expected one of %s", value, key, strings.Join(values, ", "))
+		}
+		return ""
+	}
+	switch lower {
+	case "protocol":
+		// 已废弃，ssh 只使用协议 2，但仍接受 "2,1" 这样的列表
+		for _, part := range strings.Split(value, ",") {
+			if p := strings.TrimSpace(part); p != "1" && p != "2" {
+				return fmt.Sprintf("Protocol should be a list of '1' and '2', got '%s'", value)
+			}
+		}
+	case "serveraliveinterval", "connecttimeout":
+		if _, err := ParseTimeValue(value); err != nil {
+			return fmt.Sprintf("%s should be a time such as 30 or 1m30s, got '%s'", key, value)
+		}
+	case "serveralivecountmax", "connectionattempts", "numberofpasswordprompts":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Sprintf("%s should be a number, got '%s'", key, value)
+		}
+	}
+	return ""
+}
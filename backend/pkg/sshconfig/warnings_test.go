@@ -0,0 +1,74 @@
+package sshconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var legacyConfig = []string{
+	"    IgnoreUnknown UseKeychain,Gss*",
+	"Host *",
+	"    UseKeychain yes",
+	"    GssFoo yes",
+	"    ServerAliveInterval 30",
+	"",
+	"Host web",
+	"    HostName 10.0.0.1",
+	"    FrobnicateLevel 3",
+	"    IdentityFile ~/.ssh/a",
+	"    IdentityFile ~/.ssh/b",
+	"    hostname 10.0.0.2",
+}
+
+// TestDiagnostics_Warnings 测试未知参数与同一块中的重复参数只产生警告，IgnoreUnknown 与可累加的参数除外
+func TestDiagnostics_Warnings(t *testing.T) {
+	v := NewConfigValidator(legacyConfig)
+	if err := v.Validate(); err != nil {
+		t.Fatalf("warnings should not fail Validate: %v", err)
+	}
+
+	diagnostics := v.Diagnostics()
+	if len(diagnostics) != 2 {
+		t.Fatalf("Diagnostics() = %+v, want 2 warnings", diagnostics)
+	}
+	if d := diagnostics[0]; d.Line != 9 || d.Rule != UnknownOptionRuleID || d.Severity != SeverityWarning {
+		t.Errorf("unexpected unknown option diagnostic: %+v", d)
+	}
+	if d := diagnostics[1]; d.Line != 12 || d.Rule != DuplicateParamRuleID || d.Message != "hostname is already set on line 8, only the first value takes effect" {
+		t.Errorf("unexpected duplicate diagnostic: %+v", d)
+	}
+}
+
+// TestSaveWithDiagnostics 测试警告默认不阻止保存，SetWarningsBlockSave 后才阻止；错误总是阻止保存
+func TestSaveWithDiagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	m := &SSHConfigManager{filename: path, rawLines: append([]string(nil), legacyConfig...)}
+
+	diagnostics, err := m.SaveWithDiagnostics()
+	if err != nil {
+		t.Fatalf("warnings should not block saving: %v", err)
+	}
+	if len(diagnostics) != 2 {
+		t.Errorf("diagnostics should be returned after saving, got %+v", diagnostics)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("config should be written: %v", err)
+	}
+
+	m.SetWarningsBlockSave(true)
+	diagnostics, err = m.SaveWithDiagnostics()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Diagnostics) != 2 || len(diagnostics) != 2 {
+		t.Fatalf("warnings should block saving when configured, got %v (%+v)", err, diagnostics)
+	}
+
+	m.SetWarningsBlockSave(false)
+	m.rawLines = append(m.rawLines, "    Port abc")
+	_, err = m.SaveWithDiagnostics()
+	var configErr *ConfigError
+	if !errors.As(err, &validationErr) || !errors.As(err, &configErr) || configErr.Op != "validate" {
+		t.Errorf("errors should block saving with a *ValidationError wrapping a *ConfigError, got %T: %v", err, err)
+	}
+}
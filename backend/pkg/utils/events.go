@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// headlessKey 标记无界面模式的上下文
+type headlessKey struct{}
+
+// WithoutEvents 返回标记为无界面模式（例如 CLI 模式）的上下文，EmitEvent 在此上下文及其派生的上下文中不发送事件
+func WithoutEvents(ctx context.Context) context.Context {
+	return context.WithValue(ctx, headlessKey{}, true)
+}
+
+// EmitEvent 向前端发送一个 Wails 事件。
+// ctx 为 nil 或由 WithoutEvents 标记时事件会被忽略，因为没有 Wails 运行时的上下文会使 runtime.EventsEmit 直接终止进程。
+func EmitEvent(ctx context.Context, eventName string, data ...interface{}) {
+	if ctx == nil || ctx.Value(headlessKey{}) != nil {
+		return
+	}
+	runtime.EventsEmit(ctx, eventName, data...)
+}
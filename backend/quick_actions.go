@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/wailsapp/wails/v2/pkg/menu"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// 快捷操作：启停收藏的隧道、打开收藏主机的终端、暂停所有同步监控。
+//
+// 限制：Wails v2 没有系统托盘 API，第三方托盘库需要自己的主线程事件循环，与 Wails 冲突，
+// 因此快捷操作放在应用菜单的 "Quick Actions" 中，而不是托盘图标。
+// macOS 上关闭窗口只是隐藏（HideWindowOnClose），菜单栏仍然可用，不需要打开主窗口；
+// Windows 与 Linux 上应用菜单属于主窗口，关闭窗口即退出应用，只能在窗口打开时使用这些操作。
+// 迁移到提供托盘 API 的 Wails v3 后再改为托盘菜单。
+
+// quickActionEvents 是需要重建快捷操作菜单的后端事件
+var quickActionEvents = []string{
+	"ssh_favorites_changed",
+	"saved_tunnels_changed",
+	"tunnels:changed",
+	"sync:paused_changed",
+}
+
+// addQuickActionsMenu 在应用菜单中加入 "Quick Actions" 子菜单，内容在服务启动后由 refreshQuickActions 填充
+func (a *App) addQuickActionsMenu(appMenu *menu.Menu) {
+	a.quickMenu = appMenu.AddSubmenu("Quick Actions")
+	a.quickMenu.AddText("Loading...", nil, nil).Disabled = true
+}
+
+// startQuickActions 在所有服务启动后填充菜单，并订阅相关事件保持菜单与状态同步
+func (a *App) startQuickActions() {
+	if a.quickMenu == nil {
+		return
+	}
+	for _, name := range quickActionEvents {
+		runtime.EventsOn(a.ctx, name, func(...interface{}) { a.refreshQuickActions() })
+	}
+	a.refreshQuickActions()
+}
+
+// refreshQuickActions 按当前的收藏与运行状态重建快捷操作菜单
+func (a *App) refreshQuickActions() {
+	a.quickMu.Lock()
+	defer a.quickMu.Unlock()
+
+	a.quickMenu.Items = nil
+
+	// --- 收藏的隧道：点击启动或停止 ---
+	tunnels := a.SSHGateService.GetQuickTunnels()
+	if len(tunnels) == 0 {
+		a.quickMenu.AddText("No favorite tunnels", nil, nil).Disabled = true
+	}
+	for _, t := range tunnels {
+		label := "Start Tunnel: " + t.Name
+		if t.Running {
+			label = "Stop Tunnel: " + t.Name
+		}
+		item := a.quickMenu.AddText(label, nil, func(_ *menu.CallbackData) {
+			go func() {
+				if err := a.SSHGateService.ToggleQuickTunnel(t.ID); err != nil {
+					logger.Printf("Quick action: failed to toggle tunnel %s: %v", t.Name, err)
+					a.ShowErrorDialog("Tunnel", fmt.Sprintf("Failed to toggle tunnel '%s': %v", t.Name, err))
+				}
+			}()
+		})
+		item.Checked = t.Running
+	}
+	a.quickMenu.AddSeparator()
+
+	// --- 收藏的主机：在外部终端中连接 ---
+	terminalMenu := a.quickMenu.AddSubmenu("Open Terminal")
+	hosts, err := a.SSHGateService.GetFavorites()
+	if err != nil {
+		logger.Printf("Warning: quick actions could not load favorite hosts: %v", err)
+	}
+	if len(hosts) == 0 {
+		terminalMenu.AddText("No pinned hosts", nil, nil).Disabled = true
+	}
+	for _, h := range hosts {
+		alias := h.Alias
+		terminalMenu.AddText(alias, nil, func(_ *menu.CallbackData) {
+			go func() {
+				result, err := a.SSHGateService.ConnectInTerminal(alias, false)
+				if err == nil && !result.Success {
+					err = fmt.Errorf("%s", result.ErrorMessage)
+				}
+				if err != nil {
+					logger.Printf("Quick action: failed to open terminal for %s: %v", alias, err)
+					a.ShowErrorDialog("Open Terminal", fmt.Sprintf("Could not connect to '%s': %v\n\nOpen DevTools to enter a password or trust the host key.", alias, err))
+				}
+			}()
+		})
+	}
+	a.quickMenu.AddSeparator()
+
+	// --- 同步监控 ---
+	if a.FileSyncService.IsWatchingPaused() {
+		a.quickMenu.AddText("Resume Sync Watchers", nil, func(_ *menu.CallbackData) {
+			go func() {
+				if err := a.FileSyncService.ResumeAllWatchers(); err != nil {
+					logger.Printf("Quick action: failed to resume sync watchers: %v", err)
+				}
+			}()
+		})
+	} else {
+		a.quickMenu.AddText("Pause All Sync Watchers", nil, func(_ *menu.CallbackData) {
+			go a.FileSyncService.PauseAllWatchers()
+		})
+	}
+
+	runtime.MenuUpdateApplicationMenu(a.ctx)
+}
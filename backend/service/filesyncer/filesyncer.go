@@ -3,22 +3,38 @@ package filesyncer
 import (
 	"context"
 	"fmt"
-	"log"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/logging"
+	"devtools/backend/internal/sshmanager"
 	"devtools/backend/internal/syncconfig"
 	"devtools/backend/internal/syncer"
 	"devtools/backend/internal/types"
-
-	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"devtools/backend/internal/workspace"
+	"devtools/backend/pkg/utils"
 )
 
+var logger = logging.For("syncer")
+
 // Service 结构体封装了一个特定功能领域的所有依赖和逻辑。
 // 它就像一个高度专业化的部门经理。
 type Service struct {
 	ctx           context.Context
 	configManager *syncconfig.ConfigManager
 	watcherSvc    *syncer.WatcherService
+	history       *syncer.HistoryStore
+
+	// 暂停时记录被暂停的配置 ID，恢复时重新开始监控
+	pausedIDs []string
+	pauseMu   sync.Mutex
+
+	// 同步出错时的回调（例如发送桌面通知），在 Startup 之前设置
+	onError func(message string)
 }
 
 // NewService 是 FileSyncer 服务的构造函数。
@@ -33,8 +49,27 @@ func NewService(cfgManager *syncconfig.ConfigManager) *Service {
 // Startup 在应用启动时被调用。它接收应用上下文并可以启动后台任务。
 func (s *Service) Startup(ctx context.Context) error {
 	s.ctx = ctx
+
+	// 同步历史保存在当前工作区的配置目录，加载失败不影响同步本身
+	configDir, err := workspace.ConfigDir()
+	if err != nil {
+		return err
+	}
+	s.history = syncer.NewHistoryStore(filepath.Join(configDir, "sync_history.json"))
+	if err := s.history.Load(); err != nil {
+		logger.Printf("Warning: failed to load sync history: %v", err)
+	}
+
 	// 初始化并启动文件监控服务
-	s.watcherSvc = syncer.NewWatcherService(s.ctx)
+	s.watcherSvc = syncer.NewWatcherService(s.ctx, s.history)
+	// 监控失效期间的变化不会触发事件，恢复后补做一次完整同步
+	s.watcherSvc.SetRecoveredHandler(func(pairs []types.SyncPair, cfg types.SSHConfig) {
+		for _, pair := range pairs {
+			s.emitLog("WARN", fmt.Sprintf("Watcher for %s was re-armed, running a full sync to catch up.", pair.LocalPath))
+			s.fullSync(pair, cfg)
+		}
+	})
+	s.watcherSvc.SetErrorHandler(s.reportError)
 	go s.watcherSvc.Start()
 
 	// 交给前端来控制是激活监控
@@ -58,6 +93,25 @@ func (s *Service) Shutdown() {
 	if s.watcherSvc != nil {
 		s.watcherSvc.Stop()
 	}
+	s.history.Flush()
+}
+
+// ReloadWorkspace 在切换工作区时调用：停止旧工作区的所有监控，
+// 改用新工作区的同步配置（config.json）与同步历史后重新启动监控服务
+func (s *Service) ReloadWorkspace() error {
+	s.Shutdown()
+	s.pauseMu.Lock()
+	s.pausedIDs = nil
+	s.pauseMu.Unlock()
+
+	configDir, err := workspace.ConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := s.configManager.SetPath(filepath.Join(configDir, "config.json")); err != nil {
+		logger.Printf("Warning: failed to load sync config of the new workspace: %v", err)
+	}
+	return s.Startup(s.ctx)
 }
 
 // --- 配置管理方法 ---
@@ -67,13 +121,20 @@ func (s *Service) GetConfigs() ([]types.SSHConfig, error) {
 }
 
 func (s *Service) SaveConfig(config types.SSHConfig) error {
+	if config.Clipboard.KeepHistory < 0 || config.Clipboard.KeepHistory > syncer.MaxClipboardHistory {
+		return i18n.Errorf("sync.invalid_clipboard_history", syncer.MaxClipboardHistory)
+	}
+	overrides := sshmanager.DialOverrides{ConnectTimeoutSeconds: config.ConnectTimeoutSeconds, AddressFamily: config.AddressFamily}
+	if err := overrides.Validate(); err != nil {
+		return err
+	}
 	return s.configManager.SaveSSHConfig(config)
 }
 
 func (s *Service) DeleteConfig(configID string) error {
 	// 在删除配置前，停止对其的监控
 	if err := s.StopWatching(configID); err != nil {
-		log.Printf("Warning: failed to stop watching config %s before deletion: %v", configID, err)
+		logger.Printf("Warning: failed to stop watching config %s before deletion: %v", configID, err)
 	}
 	return s.configManager.DeleteSSHConfig(configID)
 }
@@ -85,6 +146,18 @@ func (s *Service) GetSyncPairs(configID string) ([]types.SyncPair, error) {
 }
 
 func (s *Service) SaveSyncPair(pair types.SyncPair) error {
+	switch pair.SymlinkMode {
+	case "", types.SymlinkSkip, types.SymlinkCopy, types.SymlinkRecreate:
+	default:
+		return i18n.Errorf("sync.invalid_symlink_mode", pair.SymlinkMode)
+	}
+	if pair.MaxFileSize < 0 {
+		return i18n.Errorf("sync.invalid_max_file_size", pair.MaxFileSize)
+	}
+	if pair.TrashRetentionDays < 0 || pair.TrashRetentionDays > types.MaxTrashRetentionDays {
+		return i18n.Errorf("sync.invalid_trash_retention", types.MaxTrashRetentionDays)
+	}
+
 	isUpdate := pair.ID != ""
 	var oldPair types.SyncPair
 	var foundOld bool
@@ -116,14 +189,15 @@ func (s *Service) SaveSyncPair(pair types.SyncPair) error {
 
 		if isUpdate && foundOld {
 			// --- 更新操作 ---
-			if oldPair.LocalPath != pair.LocalPath || oldPair.RemotePath != pair.RemotePath {
-				log.Printf("Sync pair %s is being updated while active. Updating watcher.", pair.ID)
+			// 路径或同步选项变化时都需要重新监控，正在运行的监控持有的是旧的同步对副本
+			if oldPair != pair {
+				logger.Printf("Sync pair %s is being updated while active. Updating watcher.", pair.ID)
 				s.watcherSvc.RemoveWatch(oldPair)
 				s.startWatchAndSyncForPair(pair, cfg)
 			}
 		} else {
 			// --- 新增操作 ---
-			log.Printf("Adding new sync pair %s to active watcher.", pair.ID)
+			logger.Printf("Adding new sync pair %s to active watcher.", pair.ID)
 			s.startWatchAndSyncForPair(pair, cfg)
 		}
 	}
@@ -131,21 +205,27 @@ func (s *Service) SaveSyncPair(pair types.SyncPair) error {
 	return nil
 }
 
+// fullSync 连接远程并执行一次完整同步（包括同步前后的钩子），结果记入同步历史
+func (s *Service) fullSync(pair types.SyncPair, cfg types.SSHConfig) {
+	client, err := syncer.NewSFTPClient(cfg)
+	if err != nil {
+		s.emitLog("ERROR", fmt.Sprintf("Initial sync for %s failed, could not connect: %v", pair.LocalPath, err))
+		s.history.Record(syncer.NewFailedRun(pair.ID, syncer.TriggerFullSync, err))
+		return
+	}
+	defer client.Close()
+	s.history.Record(syncer.SyncWithHooks(client, cfg, pair, s.emitLog))
+}
+
 // startWatchAndSyncForPair 是一个辅助函数，用于添加监控并执行初始同步
 func (s *Service) startWatchAndSyncForPair(pair types.SyncPair, cfg types.SSHConfig) {
 	if err := s.watcherSvc.AddWatch(pair, cfg); err == nil {
 		go func(p types.SyncPair, c types.SSHConfig) {
-			client, err := syncer.NewSFTPClient(c)
-			if err != nil {
-				s.emitLog("ERROR", fmt.Sprintf("Initial sync for %s failed, could not connect: %v", p.LocalPath, err))
-				return
-			}
-			defer client.Close()
-			log.Printf("Performing initial sync for %s", p.LocalPath)
-			syncer.ReconcileDirectory(client, p, s.emitLog)
+			logger.Printf("Performing initial sync for %s", p.LocalPath)
+			s.fullSync(p, c)
 		}(pair, cfg)
 	} else {
-		log.Printf("Error adding watch for %s: %v", pair.LocalPath, err)
+		logger.Printf("Error adding watch for %s: %v", pair.LocalPath, err)
 	}
 }
 
@@ -159,10 +239,109 @@ func (s *Service) DeleteSyncPair(pairID string) error {
 
 	// 停止对该同步对的监控
 	s.watcherSvc.RemoveWatch(pair)
+	if err := s.history.DeletePair(pairID); err != nil {
+		logger.Printf("Warning: failed to delete sync history for %s: %v", pairID, err)
+	}
 
 	return s.configManager.DeleteSyncPair(pairID)
 }
 
+// PreviewSyncPair 返回同步对的 dry-run 计划：列出完整同步将执行的操作，不修改远程的任何内容
+func (s *Service) PreviewSyncPair(pairID string) (*syncer.SyncPlan, error) {
+	pair, found := s.configManager.GetSyncPairByID(pairID)
+	if !found {
+		return nil, fmt.Errorf("sync pair '%s' not found", pairID)
+	}
+	cfg, found := s.configManager.GetSSHConfigByID(pair.ConfigID)
+	if !found {
+		return nil, &syncconfig.ConfigNotFoundError{ConfigID: pair.ConfigID}
+	}
+
+	client, err := syncer.NewSFTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return syncer.PlanDirectory(client, pair)
+}
+
+// GetSyncHistory 返回同步对最近的 limit 条同步记录（最新的在前），limit <= 0 时返回全部
+func (s *Service) GetSyncHistory(pairID string, limit int) ([]syncer.SyncRun, error) {
+	if _, found := s.configManager.GetSyncPairByID(pairID); !found {
+		return nil, fmt.Errorf("sync pair '%s' not found", pairID)
+	}
+	return s.history.Runs(pairID, limit), nil
+}
+
+// RetryFailed 重新同步同步对上一次同步中失败的文件，返回本次重试的记录
+func (s *Service) RetryFailed(pairID string) (*syncer.SyncRun, error) {
+	pair, found := s.configManager.GetSyncPairByID(pairID)
+	if !found {
+		return nil, fmt.Errorf("sync pair '%s' not found", pairID)
+	}
+	cfg, found := s.configManager.GetSSHConfigByID(pair.ConfigID)
+	if !found {
+		return nil, &syncconfig.ConfigNotFoundError{ConfigID: pair.ConfigID}
+	}
+	last, ok := s.history.LastRun(pairID)
+	if !ok {
+		return nil, fmt.Errorf("sync pair '%s' has no sync history", pairID)
+	}
+	failed := last.FailedTransfers()
+	if len(failed) == 0 {
+		return nil, fmt.Errorf("no failed files in the last sync of '%s'", pairID)
+	}
+
+	client, err := syncer.NewSFTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	s.emitLog("INFO", fmt.Sprintf("Retrying %d failed files for: %s", len(failed), pair.LocalPath))
+	run := syncer.RetryTransfers(client, cfg, pair, failed, s.emitLog)
+	s.history.Record(run)
+	return run, nil
+}
+
+// syncPairWithConfig 返回同步对及其所属的 SSH 配置
+func (s *Service) syncPairWithConfig(pairID string) (types.SyncPair, types.SSHConfig, error) {
+	pair, found := s.configManager.GetSyncPairByID(pairID)
+	if !found {
+		return types.SyncPair{}, types.SSHConfig{}, i18n.Errorf("sync.pair_not_found", pairID)
+	}
+	cfg, found := s.configManager.GetSSHConfigByID(pair.ConfigID)
+	if !found {
+		return types.SyncPair{}, types.SSHConfig{}, &syncconfig.ConfigNotFoundError{ConfigID: pair.ConfigID}
+	}
+	return pair, cfg, nil
+}
+
+// ListDeletedFiles 返回同步对远程回收站中的文件（同步删除时被移入），最近删除的在前
+func (s *Service) ListDeletedFiles(pairID string) ([]syncer.DeletedItem, error) {
+	pair, cfg, err := s.syncPairWithConfig(pairID)
+	if err != nil {
+		return nil, err
+	}
+	return syncer.ListDeleted(cfg, pair)
+}
+
+// RestoreDeleted 把远程回收站中的文件移回原来的位置，本地已不存在时同时下载回本地。
+// trashPath 是 ListDeletedFiles 返回的 TrashPath。
+func (s *Service) RestoreDeleted(pairID, trashPath string) (*syncer.DeletedItem, error) {
+	pair, cfg, err := s.syncPairWithConfig(pairID)
+	if err != nil {
+		return nil, err
+	}
+	item, err := syncer.RestoreDeleted(cfg, pair, trashPath)
+	if err != nil {
+		s.emitLog("ERROR", fmt.Sprintf("Failed to restore %s: %v", trashPath, err))
+		return nil, err
+	}
+	s.emitLog("SUCCESS", fmt.Sprintf("Restored from trash: %s", path.Join(pair.RemotePath, item.Path)))
+	return item, nil
+}
+
 // --- 核心功能方法 ---
 
 func (s *Service) TestConnection(config types.SSHConfig) (string, error) {
@@ -177,6 +356,24 @@ func (s *Service) UpdateRemoteFileFromClipboard(configID string, remotePath stri
 	return syncer.UpdateRemoteFile(cfg, cfg.Clipboard.FilePath, content, asHTML)
 }
 
+// GetClipboardPushHistory 返回配置的剪贴板文件在远程保留的历史推送（需开启 KeepHistory），最新的在前
+func (s *Service) GetClipboardPushHistory(configID string) ([]syncer.ClipboardPush, error) {
+	cfg, found := s.configManager.GetSSHConfigByID(configID)
+	if !found {
+		return nil, &syncconfig.ConfigNotFoundError{ConfigID: configID}
+	}
+	return syncer.GetClipboardHistory(cfg)
+}
+
+// GetClipboardPushContent 读取一份历史推送的内容，用于找回之前推送过的剪贴板
+func (s *Service) GetClipboardPushContent(configID string, historyPath string) (string, error) {
+	cfg, found := s.configManager.GetSSHConfigByID(configID)
+	if !found {
+		return "", &syncconfig.ConfigNotFoundError{ConfigID: configID}
+	}
+	return syncer.ReadClipboardPush(cfg, historyPath)
+}
+
 // GetDefaultHTMLTemplate 返回剪贴板同步的默认HTML模板。
 func (s *Service) GetDefaultHTMLTemplate() string {
 	return syncer.GetDefaultHTMLTemplate()
@@ -185,7 +382,7 @@ func (s *Service) GetDefaultHTMLTemplate() string {
 // --- 监控控制方法 ---
 
 func (s *Service) StartWatching(configID string) error {
-	log.Printf("FileSyncer Service: Received request to start watching config ID: %s", configID)
+	logger.Printf("FileSyncer Service: Received request to start watching config ID: %s", configID)
 
 	s.configManager.AddActiveWatcher(configID)
 
@@ -196,20 +393,12 @@ func (s *Service) StartWatching(configID string) error {
 	pairs := s.configManager.GetSyncPairsByConfigID(configID)
 
 	for _, pair := range pairs {
-		go func(p types.SyncPair, c types.SSHConfig) {
-			client, err := syncer.NewSFTPClient(c)
-			if err != nil {
-				s.emitLog("ERROR", fmt.Sprintf("Initial sync failed for %s, could not connect: %v", p.LocalPath, err))
-				return
-			}
-			defer client.Close()
-			syncer.ReconcileDirectory(client, p, s.emitLog)
-		}(pair, cfg)
+		go s.fullSync(pair, cfg)
 	}
 	for _, pair := range pairs {
-		log.Printf("Info: Start to watch %s", pair.LocalPath)
+		logger.Printf("Info: Start to watch %s", pair.LocalPath)
 		if err := s.watcherSvc.AddWatch(pair, cfg); err != nil {
-			log.Printf("Error: Failed to watch %s -> %v", pair.LocalPath, err)
+			logger.Printf("Error: Failed to watch %s -> %v", pair.LocalPath, err)
 		}
 	}
 	return nil
@@ -220,19 +409,78 @@ func (s *Service) StopWatching(configID string) error {
 	s.configManager.RemoveActiveWatcher(configID)
 	// ---
 
+	// 停止后不应再被 ResumeAllWatchers 恢复
+	s.pauseMu.Lock()
+	s.pausedIDs = slices.DeleteFunc(s.pausedIDs, func(id string) bool { return id == configID })
+	s.pauseMu.Unlock()
+
 	pairs := s.configManager.GetSyncPairsByConfigID(configID)
 	for _, pair := range pairs {
 		s.watcherSvc.RemoveWatch(pair)
 	}
-	log.Printf("FileSyncer Service: Stopped watching config: %s", configID)
+	logger.Printf("FileSyncer Service: Stopped watching config: %s", configID)
+	return nil
+}
+
+// PauseAllWatchers 暂停所有正在运行的监控，返回被暂停的配置 ID。
+// 与 StopWatching 不同，暂停不会修改持久化的活动监控列表，下次启动时仍会按原状态恢复。
+func (s *Service) PauseAllWatchers() []string {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	for _, cfg := range s.configManager.GetAllSSHConfigs() {
+		if !s.watcherSvc.IsConfigBeingWatched(cfg.ID) {
+			continue
+		}
+		for _, pair := range s.configManager.GetSyncPairsByConfigID(cfg.ID) {
+			s.watcherSvc.RemoveWatch(pair)
+		}
+		s.pausedIDs = append(s.pausedIDs, cfg.ID)
+	}
+	if len(s.pausedIDs) > 0 {
+		s.emitLog("WARN", fmt.Sprintf("Paused %d sync watchers.", len(s.pausedIDs)))
+		utils.EmitEvent(s.ctx, "sync:paused_changed", true)
+	}
+	return append([]string{}, s.pausedIDs...)
+}
+
+// ResumeAllWatchers 恢复 PauseAllWatchers 暂停的监控，并对每个同步对执行一次完整同步以补上暂停期间的变化
+func (s *Service) ResumeAllWatchers() error {
+	s.pauseMu.Lock()
+	ids := s.pausedIDs
+	s.pausedIDs = nil
+	s.pauseMu.Unlock()
+
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, id := range ids {
+		if err := s.StartWatching(id); err != nil {
+			logger.Printf("Warning: failed to resume watching config %s: %v", id, err)
+		}
+	}
+	s.emitLog("INFO", fmt.Sprintf("Resumed %d sync watchers.", len(ids)))
+	utils.EmitEvent(s.ctx, "sync:paused_changed", false)
 	return nil
 }
 
+// IsWatchingPaused 返回监控是否处于暂停状态
+func (s *Service) IsWatchingPaused() bool {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	return len(s.pausedIDs) > 0
+}
+
 // --- 暴露给前端的方法，用于在启动时获取状态 ---
 func (s *Service) GetActiveWatcherIDs() []string {
 	return s.configManager.GetActiveWatcherIDs()
 }
 
+// GetWatcherHealth 返回每个被监控目录的健康状态，状态变化时还会发送 watcher:degraded / watcher:recovered 事件
+func (s *Service) GetWatcherHealth() []syncer.WatchHealth {
+	return s.watcherSvc.Health()
+}
+
 // --- 日志和对话框 (这些是应用级的辅助函数，但与FileSyncer紧密相关) ---
 
 func (s *Service) emitLog(level, message string) {
@@ -241,7 +489,21 @@ func (s *Service) emitLog(level, message string) {
 		Level:     level,
 		Message:   message,
 	}
-	runtime.EventsEmit(s.ctx, "log_event", entry)
+	utils.EmitEvent(s.ctx, "log_event", entry)
+	if level == "ERROR" {
+		s.reportError(message)
+	}
+}
+
+// SetErrorHandler 设置同步出错（ERROR 级别的日志）时的回调，需要在 Startup 之前调用
+func (s *Service) SetErrorHandler(fn func(message string)) {
+	s.onError = fn
+}
+
+func (s *Service) reportError(message string) {
+	if s.onError != nil {
+		s.onError(message)
+	}
 }
 
 // SelectFile 和 SelectDirectory 依然是 App 的职责，因为它们是通用的 Runtime 调用
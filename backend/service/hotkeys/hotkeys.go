@@ -0,0 +1,125 @@
+// Package hotkeys 管理系统级的全局快捷键。快捷键在设置中配置，按下时执行对应的动作
+// （例如打开快速连接面板、启停某个隧道），并发送 "hotkey:triggered" 事件由前端处理界面部分。
+package hotkeys
+
+import (
+	"context"
+	"sync"
+
+	"devtools/backend/internal/logging"
+	"devtools/backend/internal/settings"
+	"devtools/backend/pkg/hotkey"
+	"devtools/backend/pkg/utils"
+)
+
+var logger = logging.For("hotkeys")
+
+// Status 是一个快捷键的注册结果，供设置页面展示
+type Status struct {
+	settings.GlobalHotkey
+	Registered bool   `json:"registered"`
+	Error      string `json:"error,omitempty"` // 例如快捷键已被其他程序占用
+}
+
+// Service 按设置注册全局快捷键
+type Service struct {
+	ctx     context.Context
+	handler func(settings.GlobalHotkey)
+
+	mu         sync.Mutex
+	ready      bool
+	bindings   []settings.GlobalHotkey
+	unregister []func()
+	statuses   []Status
+}
+
+// NewService 是全局快捷键服务的构造函数。handler 在快捷键按下时、发送事件之前被调用，
+// 用于执行不依赖前端的动作（显示窗口、启停隧道）。
+func NewService(handler func(settings.GlobalHotkey)) *Service {
+	return &Service{handler: handler}
+}
+
+// Startup 在应用启动时被调用，注册设置中的快捷键
+func (s *Service) Startup(ctx context.Context) error {
+	s.ctx = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = true
+	s.reconcile_nolock()
+	return nil
+}
+
+// Shutdown 注销所有快捷键
+func (s *Service) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = false
+	s.unregisterAll_nolock()
+}
+
+// ApplySettings 在设置变化时重新注册快捷键。启动完成之前只记录设置。
+func (s *Service) ApplySettings(cfg settings.Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bindings = append([]settings.GlobalHotkey(nil), cfg.GlobalHotkeys...)
+	s.reconcile_nolock()
+}
+
+// GetHotkeyStatus 返回每个已配置快捷键的注册结果
+func (s *Service) GetHotkeyStatus() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Status{}, s.statuses...)
+}
+
+// IsSupported 报告当前平台是否支持全局快捷键
+func (s *Service) IsSupported() bool {
+	return hotkey.Supported()
+}
+
+// reconcile_nolock 注销旧的快捷键并注册当前设置中的快捷键，调用方需持有 s.mu
+func (s *Service) reconcile_nolock() {
+	if !s.ready {
+		return
+	}
+	s.unregisterAll_nolock()
+
+	s.statuses = make([]Status, 0, len(s.bindings))
+	for _, b := range s.bindings {
+		status := Status{GlobalHotkey: b}
+		h, err := hotkey.Parse(b.Accelerator)
+		if err == nil {
+			var unregister func()
+			unregister, err = hotkey.Register(h, func() { s.trigger(b) })
+			if err == nil {
+				s.unregister = append(s.unregister, unregister)
+				status.Registered = true
+			}
+		}
+		if err != nil {
+			status.Error = err.Error()
+			logger.Printf("Warning: failed to register global shortcut %s (%s): %v", b.Accelerator, b.Action, err)
+		}
+		s.statuses = append(s.statuses, status)
+	}
+	if n := len(s.unregister); n > 0 {
+		logger.Printf("Registered %d global shortcuts.", n)
+	}
+}
+
+// unregisterAll_nolock 注销所有已注册的快捷键，调用方需持有 s.mu
+func (s *Service) unregisterAll_nolock() {
+	for _, unregister := range s.unregister {
+		unregister()
+	}
+	s.unregister = nil
+}
+
+// trigger 执行快捷键对应的动作并通知前端
+func (s *Service) trigger(b settings.GlobalHotkey) {
+	logger.Printf("Global shortcut %s pressed (%s).", b.Accelerator, b.Action)
+	if s.handler != nil {
+		s.handler(b)
+	}
+	utils.EmitEvent(s.ctx, "hotkey:triggered", b)
+}
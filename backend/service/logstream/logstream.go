@@ -0,0 +1,250 @@
+package logstream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"devtools/backend/internal/logging"
+	"devtools/backend/pkg/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// flushInterval 控制向前端推送日志批次的频率，避免每条日志一个事件
+	flushInterval = 250 * time.Millisecond
+	// maxPausedEntries 是暂停期间最多缓存的日志条数，超出后丢弃最旧的
+	maxPausedEntries = 1000
+)
+
+// LogFilter 描述前端日志查看器的过滤条件，空值表示不过滤
+type LogFilter struct {
+	Level     string `json:"level"`     // 最低级别：debug | info | warn | error
+	Subsystem string `json:"subsystem"` // 例如 sshgate、tunnel、terminal、syncer
+	Text      string `json:"text"`      // 不区分大小写的子串匹配
+}
+
+// StreamStatus 是日志流的当前状态
+type StreamStatus struct {
+	Streaming bool      `json:"streaming"`
+	Paused    bool      `json:"paused"`
+	Buffered  int       `json:"buffered"`
+	Dropped   int       `json:"dropped"`
+	Filter    LogFilter `json:"filter"`
+}
+
+// Service 将 app.log 中的日志实时推送给前端（"logs:batch" 事件）。
+// 日志来自 logging 包的内存订阅，因此与 app.log 的内容完全一致；
+// 审计等其他日志只要通过 logging.For 写入，也会出现在同一个流中。
+type Service struct {
+	ctx context.Context
+
+	mu          sync.Mutex
+	filter      LogFilter
+	minLevel    slog.Level
+	streaming   bool
+	paused      bool
+	pending     []logging.Entry // 待推送（或暂停期间缓存）的日志
+	dropped     int
+	unsubscribe func()
+	stopCh      chan struct{}
+}
+
+// NewService 是日志流服务的构造函数
+func NewService() *Service {
+	return &Service{minLevel: slog.LevelDebug}
+}
+
+// Startup 在应用启动时被调用
+func (s *Service) Startup(ctx context.Context) error {
+	s.ctx = ctx
+	return nil
+}
+
+// Shutdown 停止日志流
+func (s *Service) Shutdown() {
+	s.StopStream()
+}
+
+// StartStream 开始向前端推送符合过滤条件的日志。重复调用只会更新过滤条件。
+func (s *Service) StartStream(filter LogFilter) error {
+	if err := s.SetStreamFilter(filter); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streaming {
+		return nil
+	}
+	s.streaming = true
+	s.paused = false
+	s.pending = nil
+	s.dropped = 0
+	s.stopCh = make(chan struct{})
+	s.unsubscribe = logging.Subscribe(s.onEntry)
+	go s.flushLoop(s.stopCh)
+	return nil
+}
+
+// StopStream 停止推送日志
+func (s *Service) StopStream() {
+	s.mu.Lock()
+	if !s.streaming {
+		s.mu.Unlock()
+		return
+	}
+	unsubscribe := s.unsubscribe
+	close(s.stopCh)
+	s.streaming = false
+	s.paused = false
+	s.pending = nil
+	s.mu.Unlock()
+
+	// 在锁外取消订阅：订阅回调 onEntry 本身也需要 s.mu
+	unsubscribe()
+}
+
+// PauseStream 暂停推送；暂停期间的日志会被缓存，恢复后一并推送
+func (s *Service) PauseStream() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+}
+
+// ResumeStream 恢复推送
+func (s *Service) ResumeStream() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+}
+
+// SetStreamFilter 更新过滤条件，只影响之后的日志
+func (s *Service) SetStreamFilter(filter LogFilter) error {
+	level := slog.LevelDebug
+	if filter.Level != "" {
+		l, err := logging.ParseLevel(filter.Level)
+		if err != nil {
+			return err
+		}
+		level = l
+	}
+	filter.Text = strings.TrimSpace(filter.Text)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = filter
+	s.minLevel = level
+	return nil
+}
+
+// GetStreamStatus 返回日志流的当前状态
+func (s *Service) GetStreamStatus() StreamStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return StreamStatus{
+		Streaming: s.streaming,
+		Paused:    s.paused,
+		Buffered:  len(s.pending),
+		Dropped:   s.dropped,
+		Filter:    s.filter,
+	}
+}
+
+// GetFilteredLogs 返回内存中最近的、符合过滤条件的日志，用于打开查看器时回填
+func (s *Service) GetFilteredLogs(filter LogFilter, limit int) []logging.Entry {
+	entries := logging.GetRecentLogs(filter.Level, filter.Subsystem, logging.RecentCapacity)
+	if filter.Text != "" {
+		matched := entries[:0]
+		for _, e := range entries {
+			if matchesText(e, filter.Text) {
+				matched = append(matched, e)
+			}
+		}
+		entries = matched
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries
+}
+
+// ExportLogs 将符合过滤条件的日志导出到用户选择的文件，返回文件路径。
+// 用户取消时返回空字符串。
+func (s *Service) ExportLogs(filter LogFilter) (string, error) {
+	path, err := runtime.SaveFileDialog(s.ctx, runtime.SaveDialogOptions{
+		Title:           "Export Logs",
+		DefaultFilename: fmt.Sprintf("devtools-logs-%s.log", time.Now().Format("20060102-150405")),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open save dialog: %w", err)
+	}
+	if path == "" {
+		return "", nil
+	}
+
+	var b strings.Builder
+	for _, e := range s.GetFilteredLogs(filter, 0) {
+		fmt.Fprintf(&b, "%s %-5s [%s] %s\n", e.Time.Format("2006/01/02 15:04:05.000"), e.Level, e.Subsystem, e.Message)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write log export: %w", err)
+	}
+	return path, nil
+}
+
+// onEntry 是 logging 的订阅回调，注意这里不能再写日志
+func (s *Service) onEntry(e logging.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.matches(e) {
+		return
+	}
+	s.pending = append(s.pending, e)
+	if s.paused && len(s.pending) > maxPausedEntries {
+		s.dropped += len(s.pending) - maxPausedEntries
+		s.pending = s.pending[len(s.pending)-maxPausedEntries:]
+	}
+}
+
+// matches 判断日志是否符合当前过滤条件，调用方需持有 s.mu
+func (s *Service) matches(e logging.Entry) bool {
+	if s.filter.Subsystem != "" && e.Subsystem != s.filter.Subsystem {
+		return false
+	}
+	if l, _ := logging.ParseLevel(e.Level); l < s.minLevel {
+		return false
+	}
+	return s.filter.Text == "" || matchesText(e, s.filter.Text)
+}
+
+func matchesText(e logging.Entry, text string) bool {
+	return strings.Contains(strings.ToLower(e.Message), strings.ToLower(text))
+}
+
+func (s *Service) flushLoop(stopCh chan struct{}) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.paused || len(s.pending) == 0 {
+				s.mu.Unlock()
+				continue
+			}
+			batch := s.pending
+			s.pending = nil
+			s.mu.Unlock()
+
+			utils.EmitEvent(s.ctx, "logs:batch", batch)
+		case <-stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package notifications 在重要事件发生时发送系统桌面通知（隧道断开、同步出错、主机密钥变化），
+// 这样应用窗口关闭或不在前台时用户也能及时发现问题。每类事件都可以在设置中单独开关。
+package notifications
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/logging"
+	"devtools/backend/internal/settings"
+	"devtools/backend/pkg/notify"
+	"devtools/backend/pkg/utils"
+)
+
+var logger = logging.For("notify")
+
+// 可以发送通知的事件
+const (
+	EventTunnelDisconnected = "tunnel_disconnected"
+	EventSyncError          = "sync_error"
+	EventHostKeyChanged     = "host_key_changed"
+)
+
+// cooldown 内同一事件（同一隧道、同一主机）只通知一次，避免反复重连或持续出错时刷屏
+const cooldown = time.Minute
+
+// Service 按设置把事件转换为桌面通知
+type Service struct {
+	ctx  context.Context
+	send func(title, message string) error
+
+	mu          sync.Mutex
+	cfg         settings.NotificationSettings
+	lastSent    map[string]time.Time
+	unsupported bool // 已经记录过当前平台不支持通知，之后不再重复记录
+}
+
+// NewService 是通知服务的构造函数
+func NewService() *Service {
+	return &Service{
+		send:     notify.Send,
+		cfg:      settings.Defaults().Notifications,
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// Startup 在应用启动时被调用
+func (s *Service) Startup(ctx context.Context) error {
+	s.ctx = ctx
+	return nil
+}
+
+// Shutdown 在应用退出时被调用
+func (s *Service) Shutdown() {}
+
+// ApplySettings 应用设置中的通知开关
+func (s *Service) ApplySettings(cfg settings.Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg.Notifications
+}
+
+// SendTestNotification 立即发送一条测试通知（不受开关与冷却时间限制），供设置页面检查系统通知是否可用
+func (s *Service) SendTestNotification() error {
+	return s.send(i18n.T("notify.test"), i18n.T("notify.test_body"))
+}
+
+// TunnelDisconnected 通知隧道的 SSH 连接意外断开
+func (s *Service) TunnelDisconnected(tunnelID, alias, message string) {
+	s.Notify(EventTunnelDisconnected, tunnelID, i18n.T("notify.tunnel_disconnected"), i18n.T("notify.tunnel_disconnected_body", alias, message))
+}
+
+// SyncError 通知文件同步出错。同步出错时通常会连续产生多条日志，冷却时间内只通知第一条。
+func (s *Service) SyncError(message string) {
+	s.Notify(EventSyncError, "", i18n.T("notify.sync_error"), message)
+}
+
+// HostKeyChanged 通知主机密钥与 known_hosts 不一致
+func (s *Service) HostKeyChanged(alias, address string) {
+	s.Notify(EventHostKeyChanged, alias, i18n.T("notify.host_key_changed"), i18n.T("ssh.host_key_mismatch", alias))
+}
+
+// Notify 在事件开启且不在冷却时间内时，异步发送一条通知。key 区分同一事件的不同对象（例如隧道 ID），
+// 不同 key 的冷却时间相互独立。
+func (s *Service) Notify(event, key, title, message string) {
+	if !s.shouldSend(event, key, time.Now()) {
+		return
+	}
+	utils.SafeGo(logger.StdLogger(), func() {
+		err := s.send(title, message)
+		switch {
+		case err == nil:
+			logger.Debugf("Sent %s notification: %s", event, title)
+		case errors.Is(err, notify.ErrUnsupported):
+			s.mu.Lock()
+			logged := s.unsupported
+			s.unsupported = true
+			s.mu.Unlock()
+			if !logged {
+				logger.Printf("Warning: %v", err)
+			}
+		default:
+			logger.Printf("Warning: failed to send %s notification: %v", event, err)
+		}
+	})
+}
+
+// shouldSend 检查事件开关与冷却时间，需要发送时记录发送时间
+func (s *Service) shouldSend(event, key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.cfg.Enabled || !eventEnabled(s.cfg, event) {
+		return false
+	}
+	id := event + "|" + key
+	if last, ok := s.lastSent[id]; ok && now.Sub(last) < cooldown {
+		return false
+	}
+	s.lastSent[id] = now
+	return true
+}
+
+// eventEnabled 返回事件对应的开关
+func eventEnabled(cfg settings.NotificationSettings, event string) bool {
+	switch event {
+	case EventTunnelDisconnected:
+		return cfg.TunnelDisconnected
+	case EventSyncError:
+		return cfg.SyncError
+	case EventHostKeyChanged:
+		return cfg.HostKeyChanged
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"devtools/backend/internal/settings"
+)
+
+// TestShouldSend 测试总开关、按事件的开关，以及同一事件同一对象在冷却时间内只通知一次
+func TestShouldSend(t *testing.T) {
+	s := NewService()
+	now := time.Now()
+
+	if !s.shouldSend(EventTunnelDisconnected, "t1", now) {
+		t.Fatal("the first notification should be sent")
+	}
+	if s.shouldSend(EventTunnelDisconnected, "t1", now.Add(30*time.Second)) {
+		t.Error("a repeated notification within the cooldown should be suppressed")
+	}
+	if !s.shouldSend(EventTunnelDisconnected, "t2", now.Add(30*time.Second)) {
+		t.Error("another tunnel should have its own cooldown")
+	}
+	if !s.shouldSend(EventTunnelDisconnected, "t1", now.Add(cooldown)) {
+		t.Error("the notification should be sent again after the cooldown")
+	}
+
+	cfg := settings.Defaults()
+	cfg.Notifications.SyncError = false
+	s.ApplySettings(cfg)
+	if s.shouldSend(EventSyncError, "", now) {
+		t.Error("a disabled event should not be sent")
+	}
+	if !s.shouldSend(EventHostKeyChanged, "web", now) {
+		t.Error("an enabled event should be sent")
+	}
+
+	cfg.Notifications.Enabled = false
+	s.ApplySettings(cfg)
+	if s.shouldSend(EventHostKeyChanged, "db", now) {
+		t.Error("no notification should be sent when notifications are disabled")
+	}
+	if s.shouldSend("unknown", "", now) {
+		t.Error("unknown events should not be sent")
+	}
+}
@@ -0,0 +1,91 @@
+package settings
+
+import (
+	"context"
+
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/logging"
+	appsettings "devtools/backend/internal/settings"
+	"devtools/backend/pkg/utils"
+)
+
+var logger = logging.For("settings")
+
+// Service 将应用设置暴露给前端，并在设置变化时发出 "settings:changed" 事件
+type Service struct {
+	ctx     context.Context
+	manager *appsettings.Manager
+}
+
+// NewService 是设置服务的构造函数
+func NewService(manager *appsettings.Manager) *Service {
+	return &Service{manager: manager}
+}
+
+// Startup 在应用启动时被调用
+func (s *Service) Startup(ctx context.Context) error {
+	s.ctx = ctx
+	s.manager.Subscribe(func(cfg appsettings.Settings) {
+		utils.EmitEvent(s.ctx, "settings:changed", cfg)
+	})
+	return nil
+}
+
+func (s *Service) Shutdown() {}
+
+// GetSettings 返回当前设置
+func (s *Service) GetSettings() appsettings.Settings {
+	return s.manager.Get()
+}
+
+// GetDefaultSettings 返回默认设置，供前端展示“恢复默认”时参考
+func (s *Service) GetDefaultSettings() appsettings.Settings {
+	return appsettings.Defaults()
+}
+
+// GetSupportedLocales 返回设置中可选的语言
+func (s *Service) GetSupportedLocales() []string {
+	return i18n.SupportedLocales()
+}
+
+// SaveSettings 校验并保存设置
+func (s *Service) SaveSettings(cfg appsettings.Settings) error {
+	// 首次启用本地 API 时自动生成 token
+	if cfg.LocalAPIEnabled && cfg.LocalAPIToken == "" {
+		token, err := appsettings.GenerateLocalAPIToken()
+		if err != nil {
+			return err
+		}
+		cfg.LocalAPIToken = token
+	}
+	if err := s.manager.Set(cfg); err != nil {
+		logger.Printf("Failed to save settings: %v", err)
+		return err
+	}
+	logger.Println("Settings saved.")
+	return nil
+}
+
+// RegenerateLocalAPIToken 生成新的本地 API token 并保存，旧 token 立即失效
+func (s *Service) RegenerateLocalAPIToken() (string, error) {
+	token, err := appsettings.GenerateLocalAPIToken()
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.manager.Update(func(cfg *appsettings.Settings) {
+		cfg.LocalAPIToken = token
+	}); err != nil {
+		return "", err
+	}
+	logger.Println("Local API token regenerated.")
+	return token, nil
+}
+
+// ResetSettings 将所有设置恢复为默认值
+func (s *Service) ResetSettings() (appsettings.Settings, error) {
+	defaults := appsettings.Defaults()
+	if err := s.manager.Set(defaults); err != nil {
+		return appsettings.Settings{}, err
+	}
+	return defaults, nil
+}
@@ -0,0 +1,395 @@
+// Package snippets 管理可复用的命令片段。片段可以包含 {{port}} 这样的变量，
+// 终端界面通过命令面板渲染后粘贴到任意会话中。
+package snippets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"devtools/backend/internal/logging"
+	"devtools/backend/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+var logger = logging.For("snippets")
+
+// variablePattern 匹配 {{name}} 或带默认值的 {{name:default}}
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*(?::([^}]*))?\}\}`)
+
+// Snippet 是一个命名的命令片段
+type Snippet struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Folder      string    `json:"folder"` // 所在文件夹，空字符串表示根目录
+	Command     string    `json:"command"`
+	Description string    `json:"description,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Variable 是片段中出现的一个变量
+type Variable struct {
+	Name       string `json:"name"`
+	Default    string `json:"default,omitempty"`
+	HasDefault bool   `json:"hasDefault"`
+}
+
+// snippetsFile 是 snippets.json 的根对象。文件夹单独保存，以便保留空文件夹。
+type snippetsFile struct {
+	Folders  []string  `json:"folders"`
+	Snippets []Snippet `json:"snippets"`
+}
+
+// Service 提供命令片段的增删改查、搜索与渲染
+type Service struct {
+	ctx        context.Context
+	configPath string
+	data       snippetsFile
+	mu         sync.RWMutex
+}
+
+// NewService 是片段服务的构造函数
+func NewService() *Service {
+	return &Service{data: snippetsFile{Folders: []string{}, Snippets: []Snippet{}}}
+}
+
+// Startup 在应用启动时被调用，加载保存的片段
+func (s *Service) Startup(ctx context.Context) error {
+	s.ctx = ctx
+	if err := s.load(); err != nil {
+		// 片段不是关键功能，加载失败时以空列表继续运行
+		logger.Printf("Warning: could not load snippets: %v", err)
+	}
+	return nil
+}
+
+func (s *Service) Shutdown() {}
+
+// load 从 DevTools 配置目录读取 snippets.json
+func (s *Service) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	appConfigDir := filepath.Join(configDir, "DevTools")
+	if err := os.MkdirAll(appConfigDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create app config directory: %w", err)
+	}
+	s.configPath = filepath.Join(appConfigDir, "snippets.json")
+
+	raw, err := os.ReadFile(s.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read snippets file: %w", err)
+	}
+
+	var data snippetsFile
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("failed to unmarshal snippets: %w", err)
+	}
+	if data.Folders == nil {
+		data.Folders = []string{}
+	}
+	if data.Snippets == nil {
+		data.Snippets = []Snippet{}
+	}
+	s.data = data
+	logger.Printf("Loaded %d snippets in %d folders.", len(data.Snippets), len(data.Folders))
+	return nil
+}
+
+// save_nolock 写入 snippets.json 并通知前端，调用方需持有 s.mu
+func (s *Service) save_nolock() error {
+	if s.configPath == "" {
+		return fmt.Errorf("snippets path is not initialized")
+	}
+	raw, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snippets: %w", err)
+	}
+	if err := os.WriteFile(s.configPath, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write snippets file: %w", err)
+	}
+	utils.EmitEvent(s.ctx, "snippets:changed")
+	return nil
+}
+
+// findSnippet_nolock 返回片段的下标，不存在时返回 -1
+func (s *Service) findSnippet_nolock(id string) int {
+	for i := range s.data.Snippets {
+		if s.data.Snippets[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeFolder 规范化文件夹名：去掉首尾空白与多余的 '/'，例如 " ops//docker/ " -> "ops/docker"
+func normalizeFolder(folder string) string {
+	var parts []string
+	for _, p := range strings.Split(folder, "/") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// inFolder 判断 folder 是否为 parent 或其子文件夹
+func inFolder(folder, parent string) bool {
+	return folder == parent || strings.HasPrefix(folder, parent+"/")
+}
+
+// --- 片段 ---
+
+// GetSnippets 返回所有片段，按文件夹和名称排序
+func (s *Service) GetSnippets() []Snippet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := slices.Clone(s.data.Snippets)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Folder != out[j].Folder {
+			return out[i].Folder < out[j].Folder
+		}
+		return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name)
+	})
+	return out
+}
+
+// SaveSnippet 新增（ID 为空时）或更新一个片段，并返回保存后的片段。
+// 片段所在的文件夹不存在时会自动创建。
+func (s *Service) SaveSnippet(snippet Snippet) (*Snippet, error) {
+	snippet.Name = strings.TrimSpace(snippet.Name)
+	snippet.Folder = normalizeFolder(snippet.Folder)
+	if snippet.Name == "" {
+		return nil, fmt.Errorf("snippet name cannot be empty")
+	}
+	if strings.TrimSpace(snippet.Command) == "" {
+		return nil, fmt.Errorf("snippet command cannot be empty")
+	}
+	snippet.UpdatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if snippet.ID == "" {
+		snippet.ID = uuid.NewString()
+		s.data.Snippets = append(s.data.Snippets, snippet)
+	} else {
+		i := s.findSnippet_nolock(snippet.ID)
+		if i < 0 {
+			return nil, fmt.Errorf("snippet with ID %s not found", snippet.ID)
+		}
+		s.data.Snippets[i] = snippet
+	}
+	if snippet.Folder != "" && !slices.Contains(s.data.Folders, snippet.Folder) {
+		s.data.Folders = append(s.data.Folders, snippet.Folder)
+		sort.Strings(s.data.Folders)
+	}
+	return &snippet, s.save_nolock()
+}
+
+// DeleteSnippet 删除一个片段
+func (s *Service) DeleteSnippet(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.findSnippet_nolock(id)
+	if i < 0 {
+		return nil // 已经不存在，视为成功
+	}
+	s.data.Snippets = slices.Delete(s.data.Snippets, i, i+1)
+	return s.save_nolock()
+}
+
+// SearchSnippets 按名称、命令、描述与文件夹搜索片段（不区分大小写，空格分隔的每个词都需要匹配）
+func (s *Service) SearchSnippets(query string) []Snippet {
+	terms := strings.Fields(strings.ToLower(query))
+	all := s.GetSnippets()
+	if len(terms) == 0 {
+		return all
+	}
+
+	result := []Snippet{}
+	for _, snippet := range all {
+		text := strings.ToLower(strings.Join([]string{snippet.Name, snippet.Command, snippet.Description, snippet.Folder}, "\n"))
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(text, term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, snippet)
+		}
+	}
+	return result
+}
+
+// --- 文件夹 ---
+
+// GetSnippetFolders 返回所有文件夹（用 '/' 表示层级），按名称排序
+func (s *Service) GetSnippetFolders() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.data.Folders)
+}
+
+// CreateSnippetFolder 创建一个文件夹
+func (s *Service) CreateSnippetFolder(name string) error {
+	name = normalizeFolder(name)
+	if name == "" {
+		return fmt.Errorf("folder name cannot be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.Contains(s.data.Folders, name) {
+		return fmt.Errorf("folder '%s' already exists", name)
+	}
+	s.data.Folders = append(s.data.Folders, name)
+	sort.Strings(s.data.Folders)
+	return s.save_nolock()
+}
+
+// RenameSnippetFolder 重命名文件夹，其子文件夹与其中的片段随之移动
+func (s *Service) RenameSnippetFolder(oldName, newName string) error {
+	oldName, newName = normalizeFolder(oldName), normalizeFolder(newName)
+	if oldName == "" || newName == "" {
+		return fmt.Errorf("folder name cannot be empty")
+	}
+	if oldName == newName {
+		return nil
+	}
+	if inFolder(newName, oldName) {
+		return fmt.Errorf("cannot move folder '%s' into itself", oldName)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Contains(s.data.Folders, oldName) {
+		return fmt.Errorf("folder '%s' not found", oldName)
+	}
+	if slices.Contains(s.data.Folders, newName) {
+		return fmt.Errorf("folder '%s' already exists", newName)
+	}
+
+	rename := func(folder string) string {
+		if inFolder(folder, oldName) {
+			return newName + strings.TrimPrefix(folder, oldName)
+		}
+		return folder
+	}
+	for i, folder := range s.data.Folders {
+		s.data.Folders[i] = rename(folder)
+	}
+	slices.Sort(s.data.Folders)
+	s.data.Folders = slices.Compact(s.data.Folders)
+	for i := range s.data.Snippets {
+		s.data.Snippets[i].Folder = rename(s.data.Snippets[i].Folder)
+	}
+	return s.save_nolock()
+}
+
+// DeleteSnippetFolder 删除文件夹及其子文件夹。deleteSnippets 为 false 时，其中的片段移动到上一级文件夹。
+func (s *Service) DeleteSnippetFolder(name string, deleteSnippets bool) error {
+	name = normalizeFolder(name)
+	parent := ""
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		parent = name[:i]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Folders = slices.DeleteFunc(s.data.Folders, func(folder string) bool { return inFolder(folder, name) })
+	s.data.Snippets = slices.DeleteFunc(s.data.Snippets, func(snippet Snippet) bool {
+		return deleteSnippets && inFolder(snippet.Folder, name)
+	})
+	for i := range s.data.Snippets {
+		if inFolder(s.data.Snippets[i].Folder, name) {
+			s.data.Snippets[i].Folder = parent
+		}
+	}
+	return s.save_nolock()
+}
+
+// --- 变量与渲染 ---
+
+// GetSnippetVariables 返回片段中出现的变量（按首次出现的顺序，重复的变量只返回一次），
+// 前端据此生成输入表单
+func (s *Service) GetSnippetVariables(id string) ([]Variable, error) {
+	snippet, err := s.getSnippet(id)
+	if err != nil {
+		return nil, err
+	}
+	return parseVariables(snippet.Command), nil
+}
+
+// RenderSnippet 用 vars 替换片段中的变量并返回最终命令。
+// 没有提供值的变量使用默认值；既没有值也没有默认值时返回错误并列出缺少的变量。
+func (s *Service) RenderSnippet(id string, vars map[string]string) (string, error) {
+	snippet, err := s.getSnippet(id)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	rendered := variablePattern.ReplaceAllStringFunc(snippet.Command, func(match string) string {
+		m := variablePattern.FindStringSubmatch(match)
+		if value, ok := vars[m[1]]; ok {
+			return value
+		}
+		if strings.Contains(match, ":") {
+			return m[2]
+		}
+		if !slices.Contains(missing, m[1]) {
+			missing = append(missing, m[1])
+		}
+		return match
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing values for variables: %s", strings.Join(missing, ", "))
+	}
+	return rendered, nil
+}
+
+func (s *Service) getSnippet(id string) (Snippet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := s.findSnippet_nolock(id)
+	if i < 0 {
+		return Snippet{}, fmt.Errorf("snippet with ID %s not found", id)
+	}
+	return s.data.Snippets[i], nil
+}
+
+// parseVariables 提取命令中的变量
+func parseVariables(command string) []Variable {
+	vars := []Variable{}
+	seen := map[string]bool{}
+	for _, m := range variablePattern.FindAllStringSubmatch(command, -1) {
+		if seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		v := Variable{Name: m[1]}
+		if strings.Contains(m[0], ":") {
+			v.Default, v.HasDefault = m[2], true
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
@@ -0,0 +1,135 @@
+package sshgate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+// ErrAuthChallengeCancelled 表示用户取消了 keyboard-interactive 问答
+var ErrAuthChallengeCancelled = errors.New("keyboard-interactive authentication was cancelled")
+
+// AuthChallenge 是推送给前端的一轮 keyboard-interactive 问答（事件 ssh:auth_challenge）。
+// 前端收集答案后调用 RespondAuthChallenge，或调用 CancelAuthChallenge 放弃本次连接。
+type AuthChallenge struct {
+	ID             string                                 `json:"id"`
+	Alias          string                                 `json:"alias"`
+	User           string                                 `json:"user"`
+	Name           string                                 `json:"name"`
+	Instruction    string                                 `json:"instruction"`
+	Prompts        []sshmanager.KeyboardInteractivePrompt `json:"prompts"`
+	TimeoutSeconds int                                    `json:"timeoutSeconds"`
+}
+
+// AuthChallengeClosed 在问答结束时推送（事件 ssh:auth_challenge_closed），前端据此关闭对话框。
+// Reason 为 answered、cancelled、timeout 之一。
+type AuthChallengeClosed struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// pendingChallenge 是一个等待用户作答的问答
+type pendingChallenge struct {
+	prompts int
+	reply   chan []string // 关闭且没有答案表示用户取消
+}
+
+// promptKeyboardInteractive 是注册给 sshmanager 的处理器：推送问题并阻塞等待前端作答或超时
+func (s *Service) promptKeyboardInteractive(c sshmanager.KeyboardInteractiveChallenge) ([]string, error) {
+	if s.ctx == nil {
+		return nil, sshmanager.ErrNoKeyboardInteractiveHandler
+	}
+
+	challenge := AuthChallenge{
+		ID:             uuid.NewString(),
+		Alias:          c.Alias,
+		User:           c.User,
+		Name:           c.Name,
+		Instruction:    c.Instruction,
+		Prompts:        c.Prompts,
+		TimeoutSeconds: int(sshmanager.KeyboardInteractiveTimeout / time.Second),
+	}
+	pending := &pendingChallenge{prompts: len(c.Prompts), reply: make(chan []string, 1)}
+
+	s.challengeMu.Lock()
+	s.challenges[challenge.ID] = pending
+	s.challengeMu.Unlock()
+
+	utils.EmitEvent(s.ctx, "ssh:auth_challenge", challenge)
+
+	timer := time.NewTimer(sshmanager.KeyboardInteractiveTimeout)
+	defer timer.Stop()
+
+	var answers []string
+	var err error
+	reason := "answered"
+	select {
+	case a, ok := <-pending.reply:
+		if ok {
+			answers = a
+		} else {
+			reason, err = "cancelled", ErrAuthChallengeCancelled
+		}
+	case <-timer.C:
+		reason = "timeout"
+		err = fmt.Errorf("no answer to keyboard-interactive prompt for %s within %s", c.Alias, sshmanager.KeyboardInteractiveTimeout)
+	case <-s.ctx.Done():
+		reason, err = "cancelled", ErrAuthChallengeCancelled
+	}
+
+	s.challengeMu.Lock()
+	delete(s.challenges, challenge.ID)
+	s.challengeMu.Unlock()
+
+	if err != nil {
+		logger.Printf("Warning: keyboard-interactive prompt for %s ended: %s", c.Alias, reason)
+	}
+	utils.EmitEvent(s.ctx, "ssh:auth_challenge_closed", AuthChallengeClosed{ID: challenge.ID, Reason: reason})
+	return answers, err
+}
+
+// RespondAuthChallenge 提交一轮问答的答案，答案顺序与问题一致
+func (s *Service) RespondAuthChallenge(id string, answers []string) error {
+	s.challengeMu.Lock()
+	defer s.challengeMu.Unlock()
+
+	pending, ok := s.challenges[id]
+	if !ok {
+		return fmt.Errorf("authentication challenge '%s' not found or already expired", id)
+	}
+	if len(answers) != pending.prompts {
+		return fmt.Errorf("expected %d answers, got %d", pending.prompts, len(answers))
+	}
+	delete(s.challenges, id)
+	pending.reply <- answers
+	return nil
+}
+
+// CancelAuthChallenge 放弃一轮问答，对应的连接会以认证失败结束
+func (s *Service) CancelAuthChallenge(id string) error {
+	s.challengeMu.Lock()
+	defer s.challengeMu.Unlock()
+
+	pending, ok := s.challenges[id]
+	if !ok {
+		return nil // 已经结束，视为成功
+	}
+	delete(s.challenges, id)
+	close(pending.reply)
+	return nil
+}
+
+// cancelAllAuthChallenges 在关闭服务时取消所有等待中的问答
+func (s *Service) cancelAllAuthChallenges() {
+	s.challengeMu.Lock()
+	defer s.challengeMu.Unlock()
+	for id, pending := range s.challenges {
+		delete(s.challenges, id)
+		close(pending.reply)
+	}
+}
@@ -0,0 +1,160 @@
+package sshgate
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"devtools/backend/internal/sshtunnel"
+	"devtools/backend/pkg/sshconfig"
+)
+
+// --- Tunnel suggestions from the forwards declared in ssh_config ---
+
+// ForwardSuggestion is a LocalForward/DynamicForward line of a host, offered as a saved tunnel.
+type ForwardSuggestion struct {
+	Tunnel sshtunnel.SavedTunnelConfig `json:"tunnel"` // Unsaved (empty ID) and linked to the line
+	Line   int                         `json:"line"`   // 1-based line in ssh_config
+	// ID of a saved tunnel already linked to, or forwarding the same port as, this line
+	ExistingID string `json:"existingId,omitempty"`
+	// Why the line cannot be imported, e.g. RemoteForward or a Unix socket; Tunnel is empty then
+	Unsupported string `json:"unsupported,omitempty"`
+}
+
+// ImportForwardsFromHost turns the forwards declared in alias's Host block into tunnel suggestions.
+// The suggestions are not saved; pass the chosen ones to SaveTunnelConfig. Each suggestion stays linked
+// to its ssh_config line, and setting ConfigForward.WriteBack makes later edits rewrite that line.
+func (s *Service) ImportForwardsFromHost(alias string) ([]ForwardSuggestion, error) {
+	params, err := s.sshManager.ConfigForwards(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	suggestions := []ForwardSuggestion{}
+	for _, p := range params {
+		suggestion := ForwardSuggestion{Line: p.Line + 1}
+		tunnel, err := tunnelFromForward(alias, p.Key, p.Value)
+		if err != nil {
+			suggestion.Unsupported = err.Error()
+		} else {
+			suggestion.Tunnel = *tunnel
+			suggestion.ExistingID = s.findForwardTunnel_nolock(tunnel)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// tunnelFromForward builds an unsaved tunnel config linked to a forward line of alias.
+func tunnelFromForward(alias, keyword, value string) (*sshtunnel.SavedTunnelConfig, error) {
+	fwd, err := sshconfig.ParseForward(keyword, value)
+	if err != nil {
+		return nil, err
+	}
+	if fwd.Keyword == "RemoteForward" {
+		return nil, fmt.Errorf("RemoteForward is not supported by saved tunnels")
+	}
+	gatewayPorts, ok := bindGatewayPorts(fwd.BindAddress)
+	if !ok {
+		return nil, fmt.Errorf("bind address '%s' is not supported, tunnels listen on loopback or on all interfaces", fwd.BindAddress)
+	}
+
+	tunnel := &sshtunnel.SavedTunnelConfig{
+		LocalPort:     fwd.Port,
+		GatewayPorts:  gatewayPorts,
+		HostSource:    "ssh_config",
+		HostAlias:     alias,
+		ConfigForward: &sshtunnel.ConfigForwardLink{Keyword: fwd.Keyword, Value: value},
+	}
+	if fwd.Keyword == "DynamicForward" {
+		tunnel.TunnelType = "dynamic"
+		tunnel.Name = fmt.Sprintf("%s SOCKS %d", alias, fwd.Port)
+	} else {
+		tunnel.TunnelType = "local"
+		tunnel.RemoteHost = fwd.TargetHost
+		tunnel.RemotePort = fwd.TargetPort
+		tunnel.Name = fmt.Sprintf("%s %d → %s:%d", alias, fwd.Port, fwd.TargetHost, fwd.TargetPort)
+	}
+	return tunnel, nil
+}
+
+// bindGatewayPorts maps a forward's bind address to GatewayPorts; ok is false for a specific
+// non-loopback address, which a tunnel cannot listen on without exposing more interfaces.
+func bindGatewayPorts(bind string) (gatewayPorts, ok bool) {
+	switch bind {
+	case "", "localhost":
+		return false, true
+	case "*", "0.0.0.0", "::":
+		return true, true
+	}
+	if ip := net.ParseIP(bind); ip != nil && ip.IsLoopback() {
+		return false, true
+	}
+	return false, false
+}
+
+// findForwardTunnel_nolock returns the ID of a saved tunnel linked to the same line as suggestion,
+// or forwarding the same local port through the same host. The caller must hold configMu.
+func (s *Service) findForwardTunnel_nolock(suggestion *sshtunnel.SavedTunnelConfig) string {
+	link := suggestion.ConfigForward
+	for _, t := range s.tunnelsConfig.Tunnels {
+		if t.HostSource != "ssh_config" || t.HostAlias != suggestion.HostAlias {
+			continue
+		}
+		if t.ConfigForward != nil && strings.EqualFold(t.ConfigForward.Keyword, link.Keyword) &&
+			strings.Join(strings.Fields(t.ConfigForward.Value), " ") == strings.Join(strings.Fields(link.Value), " ") {
+			return t.ID
+		}
+		if t.TunnelType == suggestion.TunnelType && t.LocalPort == suggestion.LocalPort &&
+			t.RemoteHost == suggestion.RemoteHost && t.RemotePort == suggestion.RemotePort {
+			return t.ID
+		}
+	}
+	return ""
+}
+
+// writeBackConfigForward rewrites the ssh_config line linked to config when write-back is enabled,
+// and updates the link to the new value. It is a no-op when the forward is unchanged.
+func (s *Service) writeBackConfigForward(config *sshtunnel.SavedTunnelConfig) error {
+	link := config.ConfigForward
+	if link == nil || !link.WriteBack || config.HostSource != "ssh_config" {
+		return nil
+	}
+	old, err := sshconfig.ParseForward(link.Keyword, link.Value)
+	if err != nil {
+		return fmt.Errorf("linked ssh_config forward is invalid: %w", err)
+	}
+
+	wantType := "local"
+	if old.Keyword == "DynamicForward" {
+		wantType = "dynamic"
+	}
+	if config.TunnelType != wantType {
+		return fmt.Errorf("a %s tunnel cannot be written back to %s, turn off write-back to change the tunnel type", config.TunnelType, old.Keyword)
+	}
+
+	fwd := sshconfig.Forward{Keyword: old.Keyword, BindAddress: old.BindAddress, Port: config.LocalPort}
+	// Keep the bind address as written unless GatewayPorts changed
+	if gatewayPorts, ok := bindGatewayPorts(old.BindAddress); !ok || gatewayPorts != config.GatewayPorts {
+		fwd.BindAddress = ""
+		if config.GatewayPorts {
+			fwd.BindAddress = "*"
+		}
+	}
+	if wantType == "local" {
+		fwd.TargetHost, fwd.TargetPort = config.RemoteHost, config.RemotePort
+	}
+	if fwd == *old {
+		return nil
+	}
+
+	value := fwd.String()
+	if err := s.sshManager.ReplaceConfigForward(config.HostAlias, old.Keyword, link.Value, value); err != nil {
+		return fmt.Errorf("failed to write the tunnel back to ssh_config: %w", err)
+	}
+	link.Value = value
+	return nil
+}
@@ -0,0 +1,137 @@
+package sshgate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/workspace"
+)
+
+// --- Connection Policy (timeouts & retries) ---
+
+// loadConnectionPolicies loads the persisted connection policies and applies them to the ssh manager.
+func (s *Service) loadConnectionPolicies() error {
+	s.policyMu.Lock()
+	defer s.policyMu.Unlock()
+
+	appConfigDir, err := workspace.ConfigDir()
+	if err != nil {
+		return err
+	}
+	s.policiesConfigPath = filepath.Join(appConfigDir, "connection_policies.json")
+
+	data, err := os.ReadFile(s.policiesConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Keep the built-in defaults.
+		}
+		return fmt.Errorf("failed to read connection policies file: %w", err)
+	}
+
+	policies := sshmanager.ConnectionPolicies{Global: sshmanager.DefaultConnectionPolicy()}
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return fmt.Errorf("failed to unmarshal connection policies: %w", err)
+	}
+	if err := policies.Global.Validate(); err != nil {
+		logger.Printf("Warning: invalid global connection policy in %s, using defaults: %v", s.policiesConfigPath, err)
+		policies.Global = sshmanager.DefaultConnectionPolicy()
+	}
+	for alias, p := range policies.Hosts {
+		if err := p.Validate(); err != nil {
+			logger.Printf("Warning: ignoring invalid connection policy for host '%s': %v", alias, err)
+			delete(policies.Hosts, alias)
+		}
+	}
+
+	s.sshManager.SetConnectionPolicies(policies)
+	logger.Printf("Successfully loaded connection policies (%d host overrides).", len(policies.Hosts))
+	return nil
+}
+
+// saveConnectionPolicies persists the given policies and applies them to the ssh manager.
+// The caller must hold s.policyMu.
+func (s *Service) saveConnectionPolicies(policies sshmanager.ConnectionPolicies) error {
+	if s.policiesConfigPath == "" {
+		return fmt.Errorf("connection policies path is not initialized")
+	}
+	data, err := json.MarshalIndent(policies, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal connection policies: %w", err)
+	}
+	if err := os.WriteFile(s.policiesConfigPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write connection policies file: %w", err)
+	}
+	s.sshManager.SetConnectionPolicies(policies)
+	return nil
+}
+
+// GetConnectionPolicies returns the global connection policy and all per-host overrides.
+func (s *Service) GetConnectionPolicies() sshmanager.ConnectionPolicies {
+	return s.sshManager.GetConnectionPolicies()
+}
+
+// GetEffectiveConnectionPolicy returns the policy that will be used when connecting to the given alias.
+func (s *Service) GetEffectiveConnectionPolicy(alias string) sshmanager.ConnectionPolicy {
+	return s.sshManager.ConnectionPolicyFor(alias)
+}
+
+// SaveGlobalConnectionPolicy updates the policy used by every host without an override,
+// saved tunnels with manual hosts, and file sync connections.
+func (s *Service) SaveGlobalConnectionPolicy(policy sshmanager.ConnectionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	s.policyMu.Lock()
+	defer s.policyMu.Unlock()
+
+	policies := s.sshManager.GetConnectionPolicies()
+	policies.Global = policy
+	return s.saveConnectionPolicies(policies)
+}
+
+// SaveHostConnectionPolicy sets a policy for a single host alias, replacing the global policy for that host.
+func (s *Service) SaveHostConnectionPolicy(alias string, policy sshmanager.ConnectionPolicy) error {
+	if alias == "" {
+		return fmt.Errorf("host alias cannot be empty")
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	s.policyMu.Lock()
+	defer s.policyMu.Unlock()
+
+	policies := s.sshManager.GetConnectionPolicies()
+	policies.Hosts[alias] = policy
+	return s.saveConnectionPolicies(policies)
+}
+
+// DeleteHostConnectionPolicy removes the override of a host so it falls back to the global policy.
+func (s *Service) DeleteHostConnectionPolicy(alias string) error {
+	s.policyMu.Lock()
+	defer s.policyMu.Unlock()
+
+	policies := s.sshManager.GetConnectionPolicies()
+	if _, ok := policies.Hosts[alias]; !ok {
+		return nil
+	}
+	delete(policies.Hosts, alias)
+	return s.saveConnectionPolicies(policies)
+}
+
+// renameHostConnectionPolicy moves a host override to its new alias after a rename.
+func (s *Service) renameHostConnectionPolicy(oldAlias, newAlias string) error {
+	s.policyMu.Lock()
+	defer s.policyMu.Unlock()
+
+	policies := s.sshManager.GetConnectionPolicies()
+	policy, ok := policies.Hosts[oldAlias]
+	if !ok {
+		return nil
+	}
+	delete(policies.Hosts, oldAlias)
+	policies.Hosts[newAlias] = policy
+	return s.saveConnectionPolicies(policies)
+}
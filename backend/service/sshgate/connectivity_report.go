@@ -0,0 +1,204 @@
+package sshgate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/utils"
+)
+
+// reportWorkers bounds how many hosts are probed at the same time.
+const reportWorkers = 4
+
+// HostConnectivity is the diagnostic result of a single host in a connectivity report.
+// It is streamed to the frontend via the "hosts:report_result" event.
+type HostConnectivity struct {
+	*sshmanager.HostProbe
+	// Verify is the result of a full connection with stored credentials; only set when
+	// the report was run with fullVerify.
+	Verify    *types.ConnectionResult `json:"verify,omitempty"`
+	Completed int                     `json:"completed"`
+	Total     int                     `json:"total"`
+}
+
+// ConnectivityReport is returned by RunConnectivityReport and emitted
+// with the "hosts:report_done" event once all hosts are probed.
+type ConnectivityReport struct {
+	GeneratedAt time.Time          `json:"generatedAt"`
+	DurationMs  int64              `json:"durationMs"`
+	FullVerify  bool               `json:"fullVerify"`
+	Total       int                `json:"total"`
+	Reachable   int                `json:"reachable"`
+	Verified    int                `json:"verified"`
+	Results     []HostConnectivity `json:"results"`
+}
+
+// RunConnectivityReport probes every configured host concurrently: TCP reachability, SSH banner,
+// host key and the auth methods the server offers, without completing authentication.
+// With fullVerify, hosts are additionally connected with stored credentials (keys, agent, keychain);
+// hosts that need a password or a host key confirmation are reported as such instead of prompting.
+func (s *Service) RunConnectivityReport(fullVerify bool) (*ConnectivityReport, error) {
+	if !s.reportRunning.CompareAndSwap(false, true) {
+		return nil, errors.New("a connectivity report is already running")
+	}
+	defer s.reportRunning.Store(false)
+
+	hosts, err := s.sshManager.GetSSHHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	report := &ConnectivityReport{
+		GeneratedAt: start,
+		FullVerify:  fullVerify,
+		Total:       len(hosts),
+		Results:     make([]HostConnectivity, 0, len(hosts)),
+	}
+	logger.Printf("Starting connectivity report for %d hosts (full verify: %v).", len(hosts), fullVerify)
+
+	jobCh := make(chan string)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for i := 0; i < reportWorkers && i < len(hosts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for alias := range jobCh {
+				item := HostConnectivity{HostProbe: s.sshManager.ProbeHost(alias)}
+				// 经由跳板机的主机无法直接探测，完整验证仍然可以走 ProxyJump
+				if fullVerify && (item.Reachable || item.Via != "") {
+					host, err := s.sshManager.VerifyConnection(alias, "")
+					if err != nil {
+						item.Verify, _ = s.handleSSHConnectError(alias, host, err)
+					} else {
+						item.Verify = &types.ConnectionResult{Success: true}
+					}
+				}
+
+				mu.Lock()
+				if item.Reachable {
+					report.Reachable++
+				}
+				if item.Verify != nil && item.Verify.Success {
+					report.Verified++
+				}
+				item.Completed = len(report.Results) + 1
+				item.Total = report.Total
+				report.Results = append(report.Results, item)
+				mu.Unlock()
+
+				utils.EmitEvent(s.ctx, "hosts:report_result", item)
+			}
+		}()
+	}
+
+	for _, host := range hosts {
+		jobCh <- host.Alias
+	}
+	close(jobCh)
+	wg.Wait()
+
+	sort.Slice(report.Results, func(i, j int) bool { return report.Results[i].Alias < report.Results[j].Alias })
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	s.reportMu.Lock()
+	s.lastReport = report
+	s.reportMu.Unlock()
+
+	logger.Printf("Connectivity report finished in %dms: %d/%d hosts reachable.", report.DurationMs, report.Reachable, report.Total)
+	utils.EmitEvent(s.ctx, "hosts:report_done", report)
+	return report, nil
+}
+
+// GetLastConnectivityReport 返回最近一次生成的连通性报告，尚未生成时为 nil
+func (s *Service) GetLastConnectivityReport() *ConnectivityReport {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+	return s.lastReport
+}
+
+// ExportConnectivityReport 将最近一次的连通性报告导出为 "json" 或 "markdown" 文本
+func (s *Service) ExportConnectivityReport(format string) (string, error) {
+	report := s.GetLastConnectivityReport()
+	if report == nil {
+		return "", errors.New("no connectivity report has been generated yet")
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal connectivity report: %w", err)
+		}
+		return string(data), nil
+	case "markdown", "md":
+		return report.markdown(), nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// markdown 将报告渲染为 Markdown 表格
+func (r *ConnectivityReport) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# SSH Connectivity Report\n\n")
+	fmt.Fprintf(&b, "- Generated: %s\n", r.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Duration: %dms\n", r.DurationMs)
+	fmt.Fprintf(&b, "- Reachable: %d/%d\n", r.Reachable, r.Total)
+	if r.FullVerify {
+		fmt.Fprintf(&b, "- Verified: %d/%d\n", r.Verified, r.Total)
+	}
+	b.WriteString("\n| Host | Address | Reachable | Latency | Banner | Auth Methods | Verify | Error |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, res := range r.Results {
+		address := fmt.Sprintf("%s@%s:%s", res.User, res.HostName, res.Port)
+		reachable := "no"
+		switch {
+		case res.Via != "":
+			reachable = "via " + res.Via
+		case res.Reachable:
+			reachable = "yes"
+		}
+		latency := ""
+		if res.Reachable {
+			latency = fmt.Sprintf("%dms", res.LatencyMs)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			markdownCell(res.Alias), markdownCell(address), markdownCell(reachable), latency,
+			markdownCell(res.Banner), strings.Join(res.AuthMethods, ", "),
+			markdownCell(verifyStatus(res.Verify)), markdownCell(res.Error))
+	}
+	return b.String()
+}
+
+// verifyStatus 将完整验证的结果概括为一个简短的状态
+func verifyStatus(result *types.ConnectionResult) string {
+	switch {
+	case result == nil:
+		return ""
+	case result.Success:
+		return "ok"
+	case result.PasswordRequired != nil:
+		return "password required"
+	case result.HostKeyVerificationRequired != nil:
+		return "host key not trusted"
+	default:
+		return "failed: " + result.ErrorMessage
+	}
+}
+
+// markdownCell 转义表格单元格中的竖线与换行
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
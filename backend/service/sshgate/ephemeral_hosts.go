@@ -0,0 +1,166 @@
+package sshgate
+
+import (
+	"fmt"
+	"strings"
+
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/sshtunnel"
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/utils"
+)
+
+// --- Session-scoped temporary hosts ---
+
+// EphemeralHostParams describes a host for a quick one-off connection. Alias is optional;
+// one is generated from HostName when it is empty.
+type EphemeralHostParams struct {
+	Alias        string `json:"alias,omitempty"`
+	HostName     string `json:"hostName"`
+	User         string `json:"user"`
+	Port         string `json:"port,omitempty"`
+	IdentityFile string `json:"identityFile,omitempty"`
+}
+
+// CreateEphemeralHost registers a temporary host and returns its alias. The alias can be used for
+// terminals and tunnels like any host from ~/.ssh/config until the app exits, but nothing is written
+// to the ssh config; use PersistEphemeralHost to keep it.
+func (s *Service) CreateEphemeralHost(params EphemeralHostParams) (string, error) {
+	host := types.SSHHost{
+		Alias:        strings.TrimSpace(params.Alias),
+		HostName:     params.HostName,
+		User:         params.User,
+		Port:         params.Port,
+		IdentityFile: params.IdentityFile,
+	}
+	if host.Alias == "" {
+		host.Alias = s.generateEphemeralAlias(strings.TrimSpace(params.HostName))
+	}
+	if err := validateAndSanitizeHost(&host); err != nil {
+		return "", err
+	}
+	if err := s.sshManager.AddEphemeralHost(host); err != nil {
+		return "", err
+	}
+
+	logger.Printf("Created temporary host '%s' (%s@%s).", host.Alias, host.User, host.HostName)
+	utils.EmitEvent(s.ctx, "ssh_ephemeral_hosts_changed")
+	return host.Alias, nil
+}
+
+// GetEphemeralHosts returns the temporary hosts created during this run.
+func (s *Service) GetEphemeralHosts() []types.SSHHost {
+	return s.sshManager.GetEphemeralHosts()
+}
+
+// RemoveEphemeralHost forgets a temporary host and stops the tunnels that were started through it.
+func (s *Service) RemoveEphemeralHost(alias string) error {
+	if !s.sshManager.RemoveEphemeralHost(alias) {
+		return fmt.Errorf("temporary host '%s' not found", alias)
+	}
+
+	for _, t := range s.takeSessionTunnels(alias) {
+		for _, active := range s.tunnelManager.GetActiveTunnels() {
+			if active.ConfigID != t.ID {
+				continue
+			}
+			if err := s.tunnelManager.StopForward(active.ID); err != nil {
+				logger.Printf("Warning: failed to stop tunnel %s of temporary host '%s': %v", active.ID, alias, err)
+			}
+		}
+	}
+
+	logger.Printf("Removed temporary host '%s'.", alias)
+	utils.EmitEvent(s.ctx, "ssh_ephemeral_hosts_changed")
+	return nil
+}
+
+// PersistEphemeralHost turns a temporary host into a real Host block in ~/.ssh/config, optionally
+// under a new alias. Tunnels started through it during this run become saved tunnels.
+func (s *Service) PersistEphemeralHost(alias string, newAlias string) (*types.SSHHost, error) {
+	hosts := s.sshManager.GetEphemeralHosts()
+	var host *types.SSHHost
+	for i := range hosts {
+		if hosts[i].Alias == alias {
+			host = &hosts[i]
+			break
+		}
+	}
+	if host == nil {
+		return nil, fmt.Errorf("temporary host '%s' not found", alias)
+	}
+
+	target := strings.TrimSpace(newAlias)
+	if target == "" {
+		target = alias
+	}
+	if strings.Contains(target, " ") {
+		return nil, fmt.Errorf("alias cannot contain spaces")
+	}
+	if s.sshManager.HasHostExact(target) || (target != alias && s.sshManager.IsEphemeralHost(target)) {
+		return nil, fmt.Errorf("host with alias '%s' already exists", target)
+	}
+
+	req := sshmanager.HostUpdateRequest{
+		Name: target,
+		Params: map[string]string{
+			"HostName":     host.HostName,
+			"User":         host.User,
+			"Port":         host.Port,
+			"IdentityFile": host.IdentityFile,
+		},
+	}
+	if _, err := s.sshManager.AddHostWithParams(req); err != nil {
+		logger.Printf("PersistEphemeralHost failed, reloading ssh manager to discard in-memory changes: %v", err)
+		_ = s.sshManager.Reload()
+		return nil, err
+	}
+	s.sshManager.RemoveEphemeralHost(alias)
+
+	if tunnels := s.takeSessionTunnels(alias); len(tunnels) > 0 {
+		s.configMu.Lock()
+		for _, t := range tunnels {
+			t.HostAlias = target
+			s.tunnelsConfig.Tunnels = append([]sshtunnel.SavedTunnelConfig{t}, s.tunnelsConfig.Tunnels...)
+		}
+		if err := s.saveTunnelsConfig(); err != nil {
+			logger.Printf("Warning: failed to save tunnels of persisted host '%s': %v", target, err)
+		}
+		s.configMu.Unlock()
+	}
+
+	logger.Printf("Persisted temporary host '%s' as '%s'.", alias, target)
+	utils.EmitEvent(s.ctx, "ssh_ephemeral_hosts_changed")
+	return s.sshManager.GetSSHHostByAlias(target)
+}
+
+// takeSessionTunnels removes and returns the in-memory tunnel configurations of a temporary host.
+func (s *Service) takeSessionTunnels(alias string) []sshtunnel.SavedTunnelConfig {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	var taken []sshtunnel.SavedTunnelConfig
+	kept := s.sessionTunnels[:0]
+	for _, t := range s.sessionTunnels {
+		if t.HostAlias == alias {
+			taken = append(taken, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	s.sessionTunnels = kept
+	return taken
+}
+
+// generateEphemeralAlias derives an unused alias such as "tmp-10.0.0.5" from the host name.
+func (s *Service) generateEphemeralAlias(hostName string) string {
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '-'
+		}
+	}, hostName)
+	return s.sshManager.UniqueAlias("tmp-" + strings.Trim(base, "-"))
+}
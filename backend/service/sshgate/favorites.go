@@ -0,0 +1,78 @@
+package sshgate
+
+import (
+	"fmt"
+	"slices"
+
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/utils"
+)
+
+// --- Favorite (pinned) hosts, stored in host_groups.json alongside the groups ---
+
+// SetHostFavorite pins or unpins a host. Pinned hosts keep the order in which they were pinned.
+func (s *Service) SetHostFavorite(alias string, favorite bool) error {
+	if favorite && !s.sshManager.HasHost(alias) {
+		return fmt.Errorf("host with alias '%s' not found", alias)
+	}
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	if favorite {
+		if slices.Contains(s.hostGroups.Favorites, alias) {
+			return nil
+		}
+		s.hostGroups.Favorites = append(s.hostGroups.Favorites, alias)
+	} else if !s.removeFavorite_nolock(alias) {
+		return nil
+	}
+	if err := s.saveHostGroups(); err != nil {
+		return err
+	}
+	utils.EmitEvent(s.ctx, "ssh_favorites_changed")
+	return nil
+}
+
+// GetFavorites returns the pinned hosts in pin order, e.g. for a quick-connect menu.
+// Favorites whose host no longer exists in ssh_config are skipped.
+func (s *Service) GetFavorites() ([]types.SSHHost, error) {
+	hosts, err := s.GetSSHHosts()
+	if err != nil {
+		return nil, err
+	}
+	byAlias := make(map[string]types.SSHHost, len(hosts))
+	for _, h := range hosts {
+		byAlias[h.Alias] = h
+	}
+
+	s.groupMu.Lock()
+	aliases := slices.Clone(s.hostGroups.Favorites)
+	s.groupMu.Unlock()
+
+	favorites := []types.SSHHost{}
+	for _, alias := range aliases {
+		if h, ok := byAlias[alias]; ok {
+			favorites = append(favorites, h)
+		}
+	}
+	return favorites, nil
+}
+
+// markFavorites sets the Favorite flag on hosts that are pinned.
+func (s *Service) markFavorites(hosts []types.SSHHost) {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+	for i := range hosts {
+		hosts[i].Favorite = slices.Contains(s.hostGroups.Favorites, hosts[i].Alias)
+	}
+}
+
+// removeFavorite_nolock unpins alias and reports whether it was pinned. The caller must hold s.groupMu.
+func (s *Service) removeFavorite_nolock(alias string) bool {
+	i := slices.Index(s.hostGroups.Favorites, alias)
+	if i < 0 {
+		return false
+	}
+	s.hostGroups.Favorites = slices.Delete(s.hostGroups.Favorites, i, i+1)
+	return true
+}
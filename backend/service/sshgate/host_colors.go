@@ -0,0 +1,311 @@
+package sshgate
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"devtools/backend/internal/workspace"
+	"devtools/backend/pkg/utils"
+)
+
+// --- Color tokens for host cards, tunnels and terminals, stored in host_colors.json ---
+
+// ColorTokens is the palette a host can be colored with; the frontend maps each token to a theme color.
+var ColorTokens = []string{"red", "orange", "amber", "green", "teal", "blue", "indigo", "purple", "pink", "gray"}
+
+// hashColorTokens are the tokens assigned to hosts without a rule. Red is left out so that only
+// hosts tagged as production are red, and gray is left out because it reads as "disabled".
+var hashColorTokens = []string{"orange", "amber", "green", "teal", "blue", "indigo", "purple", "pink"}
+
+// Where a host's color comes from
+const (
+	ColorSourceHost = "host" // set explicitly for the host
+	ColorSourceRule = "rule" // a tag of the host matched a rule
+	ColorSourceHash = "hash" // derived from the alias
+)
+
+// ColorRule colors every host carrying Tag. Tags are the host's environment (from its notes)
+// and the names of the groups it belongs to, compared case-insensitively.
+type ColorRule struct {
+	Tag   string `json:"tag"`
+	Color string `json:"color"`
+}
+
+// HostColorsConfig is the root object of host_colors.json.
+type HostColorsConfig struct {
+	Rules []ColorRule       `json:"rules"`           // checked in order, the first match wins
+	Hosts map[string]string `json:"hosts,omitempty"` // alias → token, takes precedence over the rules
+}
+
+// HostColor is the resolved color of a host or tunnel.
+type HostColor struct {
+	Color  string `json:"color"`
+	Source string `json:"source"`
+	Tag    string `json:"tag,omitempty"` // the tag that matched when Source is "rule"
+}
+
+// defaultColorRules are used until the user saves their own rules.
+func defaultColorRules() []ColorRule {
+	return []ColorRule{
+		{Tag: "prod", Color: "red"},
+		{Tag: "production", Color: "red"},
+		{Tag: "staging", Color: "amber"},
+		{Tag: "test", Color: "blue"},
+		{Tag: "dev", Color: "green"},
+		{Tag: "development", Color: "green"},
+	}
+}
+
+// normalize lowercases the tags and drops empty or duplicate rules.
+func (c *HostColorsConfig) normalize() {
+	rules := []ColorRule{}
+	seen := map[string]bool{}
+	for _, r := range c.Rules {
+		r.Tag = strings.ToLower(strings.TrimSpace(r.Tag))
+		r.Color = strings.TrimSpace(r.Color)
+		if r.Tag == "" || seen[r.Tag] {
+			continue
+		}
+		seen[r.Tag] = true
+		rules = append(rules, r)
+	}
+	c.Rules = rules
+	if c.Hosts == nil {
+		c.Hosts = map[string]string{}
+	}
+}
+
+// Validate checks that every color is a known token.
+func (c HostColorsConfig) Validate() error {
+	for _, r := range c.Rules {
+		if !slices.Contains(ColorTokens, r.Color) {
+			return fmt.Errorf("invalid color '%s' for tag '%s', expected one of %s", r.Color, r.Tag, strings.Join(ColorTokens, ", "))
+		}
+	}
+	for alias, color := range c.Hosts {
+		if !slices.Contains(ColorTokens, color) {
+			return fmt.Errorf("invalid color '%s' for host '%s', expected one of %s", color, alias, strings.Join(ColorTokens, ", "))
+		}
+	}
+	return nil
+}
+
+// loadHostColors loads the color rules from host_colors.json next to tunnels.json.
+func (s *Service) loadHostColors() error {
+	s.colorsMu.Lock()
+	defer s.colorsMu.Unlock()
+
+	appConfigDir, err := workspace.ConfigDir()
+	if err != nil {
+		return err
+	}
+	s.hostColorsConfigPath = filepath.Join(appConfigDir, "host_colors.json")
+
+	data, err := os.ReadFile(s.hostColorsConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read host colors file: %w", err)
+	}
+
+	config := HostColorsConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to unmarshal host colors: %w", err)
+	}
+	config.normalize()
+	s.hostColors = config
+	logger.Printf("Successfully loaded %d color rules.", len(config.Rules))
+	return nil
+}
+
+// saveHostColors persists the color rules. The caller must hold s.colorsMu.
+func (s *Service) saveHostColors() error {
+	if s.hostColorsConfigPath == "" {
+		return fmt.Errorf("host colors path is not initialized")
+	}
+	data, err := json.MarshalIndent(s.hostColors, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal host colors: %w", err)
+	}
+	if err := os.WriteFile(s.hostColorsConfigPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write host colors file: %w", err)
+	}
+	utils.EmitEvent(s.ctx, "ssh_host_colors_changed")
+	return nil
+}
+
+// GetColorTokens returns the palette, in display order.
+func (s *Service) GetColorTokens() []string {
+	return slices.Clone(ColorTokens)
+}
+
+// GetHostColorsConfig returns the color rules and the per-host colors.
+func (s *Service) GetHostColorsConfig() HostColorsConfig {
+	s.colorsMu.Lock()
+	defer s.colorsMu.Unlock()
+	return HostColorsConfig{Rules: slices.Clone(s.hostColors.Rules), Hosts: maps.Clone(s.hostColors.Hosts)}
+}
+
+// SaveHostColorsConfig replaces the color rules and the per-host colors.
+func (s *Service) SaveHostColorsConfig(config HostColorsConfig) error {
+	config.normalize()
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	s.colorsMu.Lock()
+	defer s.colorsMu.Unlock()
+	s.hostColors = config
+	return s.saveHostColors()
+}
+
+// SetHostColor pins a host to a color; an empty color returns the host to its rule or derived color.
+func (s *Service) SetHostColor(alias, color string) error {
+	if alias == "" {
+		return fmt.Errorf("host alias cannot be empty")
+	}
+	if color != "" && !slices.Contains(ColorTokens, color) {
+		return fmt.Errorf("invalid color '%s', expected one of %s", color, strings.Join(ColorTokens, ", "))
+	}
+	s.colorsMu.Lock()
+	defer s.colorsMu.Unlock()
+	if s.hostColors.Hosts[alias] == color {
+		return nil
+	}
+	if color == "" {
+		delete(s.hostColors.Hosts, alias)
+	} else {
+		s.hostColors.Hosts[alias] = color
+	}
+	return s.saveHostColors()
+}
+
+// GetHostColor returns the color of a host. The result only depends on the alias, its tags and
+// host_colors.json, so the same host gets the same color in every session and on every machine.
+func (s *Service) GetHostColor(alias string) HostColor {
+	tags := s.hostTags()
+	s.colorsMu.Lock()
+	defer s.colorsMu.Unlock()
+	return s.hostColor_nolock(alias, tags[alias])
+}
+
+// GetHostColors returns the color of every host, keyed by alias.
+func (s *Service) GetHostColors() (map[string]HostColor, error) {
+	hosts, err := s.GetSSHHosts()
+	if err != nil {
+		return nil, err
+	}
+	tags := s.hostTags()
+
+	s.colorsMu.Lock()
+	defer s.colorsMu.Unlock()
+	colors := make(map[string]HostColor, len(hosts))
+	for _, h := range hosts {
+		colors[h.Alias] = s.hostColor_nolock(h.Alias, tags[h.Alias])
+	}
+	return colors, nil
+}
+
+// GetTunnelColors returns the color of every saved tunnel, keyed by tunnel ID. Tunnels through an
+// ssh_config host share the host's color; tunnels to a manual host are colored by its hostname.
+func (s *Service) GetTunnelColors() map[string]HostColor {
+	tags := s.hostTags()
+
+	s.configMu.RLock()
+	tunnels := slices.Clone(s.tunnelsConfig.Tunnels)
+	s.configMu.RUnlock()
+
+	s.colorsMu.Lock()
+	defer s.colorsMu.Unlock()
+	colors := make(map[string]HostColor, len(tunnels))
+	for _, t := range tunnels {
+		switch {
+		case t.HostSource == "ssh_config":
+			colors[t.ID] = s.hostColor_nolock(t.HostAlias, tags[t.HostAlias])
+		case t.ManualHost != nil:
+			colors[t.ID] = HostColor{Color: hashColor(t.ManualHost.HostName), Source: ColorSourceHash}
+		}
+	}
+	return colors
+}
+
+// ExportHostColors returns host_colors.json, so that the colors can be carried to another machine.
+func (s *Service) ExportHostColors() (string, error) {
+	s.colorsMu.Lock()
+	defer s.colorsMu.Unlock()
+	data, err := json.MarshalIndent(s.hostColors, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal host colors: %w", err)
+	}
+	return string(data), nil
+}
+
+// ImportHostColors replaces the color rules and per-host colors with an exported host_colors.json.
+func (s *Service) ImportHostColors(data string) error {
+	var config HostColorsConfig
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		return fmt.Errorf("invalid host colors: %w", err)
+	}
+	return s.SaveHostColorsConfig(config)
+}
+
+// hostColor_nolock resolves the color of alias from its tags. The caller must hold s.colorsMu.
+func (s *Service) hostColor_nolock(alias string, tags []string) HostColor {
+	if color, ok := s.hostColors.Hosts[alias]; ok {
+		return HostColor{Color: color, Source: ColorSourceHost}
+	}
+	for _, r := range s.hostColors.Rules {
+		if slices.Contains(tags, r.Tag) {
+			return HostColor{Color: r.Color, Source: ColorSourceRule, Tag: r.Tag}
+		}
+	}
+	return HostColor{Color: hashColor(alias), Source: ColorSourceHash}
+}
+
+// hostTags returns the lowercased tags of every host that has any: its environment and its groups.
+func (s *Service) hostTags() map[string][]string {
+	tags := map[string][]string{}
+	s.notesMu.Lock()
+	for alias, notes := range s.hostNotes {
+		if env := strings.ToLower(notes.Environment); env != "" {
+			tags[alias] = append(tags[alias], env)
+		}
+	}
+	s.notesMu.Unlock()
+
+	s.groupMu.Lock()
+	for _, g := range s.hostGroups.Groups {
+		name := strings.ToLower(strings.TrimSpace(g.Name))
+		for _, alias := range g.Aliases {
+			tags[alias] = append(tags[alias], name)
+		}
+	}
+	s.groupMu.Unlock()
+	return tags
+}
+
+// hashColor derives a stable color token from a name.
+func hashColor(name string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strings.ToLower(name)))
+	return hashColorTokens[h.Sum32()%uint32(len(hashColorTokens))]
+}
+
+// renameHostColor moves a host's color to its new alias after a rename.
+func (s *Service) renameHostColor(oldAlias, newAlias string) error {
+	s.colorsMu.Lock()
+	defer s.colorsMu.Unlock()
+	color, ok := s.hostColors.Hosts[oldAlias]
+	if !ok {
+		return nil
+	}
+	delete(s.hostColors.Hosts, oldAlias)
+	s.hostColors.Hosts[newAlias] = color
+	return s.saveHostColors()
+}
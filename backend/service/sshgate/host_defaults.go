@@ -0,0 +1,56 @@
+package sshgate
+
+import (
+	"strconv"
+
+	"devtools/backend/internal/settings"
+	"devtools/backend/internal/types"
+)
+
+// --- Defaults for new hosts ---
+
+// NewHostTemplate is what the host editor starts from when a host is added: the host fields
+// prefilled from the settings, plus extra ssh_config parameters that will be written for it.
+type NewHostTemplate struct {
+	Host   types.SSHHost     `json:"host"`
+	Params map[string]string `json:"params"`
+}
+
+// GetNewHostTemplate returns the defaults SaveSSHHost applies to a new host, so the editor can show them up front.
+func (s *Service) GetNewHostTemplate() NewHostTemplate {
+	var host types.SSHHost
+	params := s.applyNewHostDefaults(&host)
+	return NewHostTemplate{Host: host, Params: params}
+}
+
+// applyNewHostDefaults fills the empty fields of host with the configured defaults and returns the
+// extra parameters (such as ServerAliveInterval) that should be written to the new Host block.
+func (s *Service) applyNewHostDefaults(host *types.SSHHost) map[string]string {
+	s.defaultsMu.RLock()
+	defaults := s.newHostDefaults
+	s.defaultsMu.RUnlock()
+
+	if host.User == "" {
+		host.User = defaults.User
+	}
+	// Port 22 is what ssh uses anyway, so only a different default is worth writing.
+	if host.Port == "" && defaults.Port != "22" {
+		host.Port = defaults.Port
+	}
+	if host.IdentityFile == "" {
+		host.IdentityFile = defaults.IdentityFile
+	}
+
+	params := map[string]string{}
+	if defaults.ServerAliveInterval > 0 {
+		params["ServerAliveInterval"] = strconv.Itoa(defaults.ServerAliveInterval)
+	}
+	return params
+}
+
+// setNewHostDefaults stores the defaults from the app settings.
+func (s *Service) setNewHostDefaults(defaults settings.NewHostDefaults) {
+	s.defaultsMu.Lock()
+	defer s.defaultsMu.Unlock()
+	s.newHostDefaults = defaults
+}
@@ -0,0 +1,117 @@
+package sshgate
+
+import (
+	"fmt"
+	"slices"
+
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/sshconfig"
+)
+
+// --- Resources that depend on a host, shown before DeleteSSHHost removes it ---
+
+// HostDependency is one resource that uses a host, e.g. a saved tunnel or an open terminal.
+type HostDependency struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// HostDependencies lists what breaks when a host is deleted, so the confirmation dialog can name it.
+type HostDependencies struct {
+	Alias         string                `json:"alias"`
+	SavedTunnels  []HostDependency      `json:"savedTunnels"`  // saved tunnels connecting through the host; they can't start afterwards
+	ActiveTunnels []HostDependency      `json:"activeTunnels"` // running tunnels; they keep running until stopped but can't reconnect
+	Terminals     []HostDependency      `json:"terminals"`     // open terminal sessions; they can't reconnect
+	SyncConfigs   []HostDependency      `json:"syncConfigs"`   // file sync configs whose host is the alias
+	References    []sshconfig.Reference `json:"references"`    // ProxyJump/ProxyCommand lines of other hosts that jump through it
+}
+
+// SetTerminalSessionSource sets the callback that lists the open terminal sessions of a host.
+// It must be called before Startup.
+func (s *Service) SetTerminalSessionSource(fn func(alias string) []HostDependency) {
+	s.terminalSessions = fn
+}
+
+// SetLoginCommandHandlers sets the callbacks that move and delete the login commands the terminal service
+// keeps per host alias, so they follow a host that is renamed and go away with a host that is deleted.
+// It must be called before Startup.
+func (s *Service) SetLoginCommandHandlers(rename func(oldAlias, newAlias string) error, remove func(alias string) error) {
+	s.renameLoginCommand = rename
+	s.deleteLoginCommand = remove
+}
+
+// renameHostLoginCommand moves the login command of oldAlias to newAlias, if the handlers are set.
+func (s *Service) renameHostLoginCommand(oldAlias, newAlias string) error {
+	if s.renameLoginCommand == nil {
+		return nil
+	}
+	return s.renameLoginCommand(oldAlias, newAlias)
+}
+
+// deleteHostLoginCommand deletes the login command of alias, if the handlers are set.
+func (s *Service) deleteHostLoginCommand(alias string) error {
+	if s.deleteLoginCommand == nil {
+		return nil
+	}
+	return s.deleteLoginCommand(alias)
+}
+
+// SetSyncConfigSource sets the callback that returns the file sync configs. It must be called before Startup.
+func (s *Service) SetSyncConfigSource(fn func() []types.SSHConfig) {
+	s.syncConfigs = fn
+}
+
+// GetHostDependencies returns the tunnels, terminals, sync configs and ProxyJump references that use
+// alias. DeleteSSHHost doesn't refuse to delete a host that is in use; this report lets the user decide.
+func (s *Service) GetHostDependencies(alias string) (*HostDependencies, error) {
+	if !s.sshManager.HasHost(alias) && !s.sshManager.IsEphemeralHost(alias) {
+		return nil, fmt.Errorf("host '%s' not found", alias)
+	}
+
+	deps := &HostDependencies{
+		Alias:         alias,
+		SavedTunnels:  []HostDependency{},
+		ActiveTunnels: []HostDependency{},
+		Terminals:     []HostDependency{},
+		SyncConfigs:   []HostDependency{},
+		References:    []sshconfig.Reference{},
+	}
+
+	s.configMu.RLock()
+	for _, tunnel := range slices.Concat(s.tunnelsConfig.Tunnels, s.sessionTunnels) {
+		if tunnel.HostSource == "ssh_config" && tunnel.HostAlias == alias {
+			deps.SavedTunnels = append(deps.SavedTunnels, HostDependency{ID: tunnel.ID, Name: tunnel.Name})
+		}
+	}
+	s.configMu.RUnlock()
+
+	for _, tunnel := range s.tunnelManager.GetActiveTunnels() {
+		if tunnel.Alias == alias {
+			deps.ActiveTunnels = append(deps.ActiveTunnels, HostDependency{ID: tunnel.ID, Name: tunnel.LocalAddr + " → " + tunnel.RemoteAddr})
+		}
+	}
+
+	if s.terminalSessions != nil {
+		deps.Terminals = append(deps.Terminals, s.terminalSessions(alias)...)
+	}
+
+	if s.syncConfigs != nil {
+		for _, config := range s.syncConfigs() {
+			if config.Host == alias {
+				deps.SyncConfigs = append(deps.SyncConfigs, HostDependency{ID: config.ID, Name: config.Name})
+			}
+		}
+	}
+
+	refs, err := s.sshManager.WhereUsed("ProxyJump", alias)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		// A host jumping through itself is the host's own block, which goes away with it.
+		if !slices.Contains(ref.Aliases, alias) {
+			deps.References = append(deps.References, ref)
+		}
+	}
+	return deps, nil
+}
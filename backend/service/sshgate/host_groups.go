@@ -0,0 +1,374 @@
+package sshgate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"devtools/backend/internal/types"
+	"devtools/backend/internal/workspace"
+
+	"github.com/google/uuid"
+)
+
+// --- Host Groups (folders in the host list) ---
+
+// HostGroup is a named, ordered folder of ssh_config host aliases.
+// A host belongs to at most one group; hosts without a group are shown ungrouped.
+type HostGroup struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Color     string   `json:"color,omitempty"` // e.g. "#3b82f6"
+	Aliases   []string `json:"aliases"`
+	Collapsed bool     `json:"collapsed,omitempty"`
+}
+
+// HostGroupsConfig is the root object for the host groups JSON file. Groups are kept in display order.
+type HostGroupsConfig struct {
+	Groups    []HostGroup `json:"groups"`
+	Favorites []string    `json:"favorites,omitempty"` // pinned host aliases, in pin order
+}
+
+// GroupedSSHHost is an SSH host annotated with the group it belongs to.
+type GroupedSSHHost struct {
+	types.SSHHost
+	GroupID   string `json:"groupId,omitempty"`
+	GroupName string `json:"groupName,omitempty"`
+}
+
+var hostGroupColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// loadHostGroups loads the host groups from host_groups.json next to tunnels.json.
+func (s *Service) loadHostGroups() error {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	appConfigDir, err := workspace.ConfigDir()
+	if err != nil {
+		return err
+	}
+	s.hostGroupsConfigPath = filepath.Join(appConfigDir, "host_groups.json")
+
+	data, err := os.ReadFile(s.hostGroupsConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read host groups file: %w", err)
+	}
+
+	config := &HostGroupsConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("failed to unmarshal host groups: %w", err)
+	}
+	for i := range config.Groups {
+		if config.Groups[i].Aliases == nil {
+			config.Groups[i].Aliases = []string{}
+		}
+	}
+	s.hostGroups = config
+	logger.Printf("Successfully loaded %d host groups.", len(config.Groups))
+	return nil
+}
+
+// saveHostGroups persists the host groups. The caller must hold s.groupMu.
+func (s *Service) saveHostGroups() error {
+	if s.hostGroupsConfigPath == "" {
+		return fmt.Errorf("host groups path is not initialized")
+	}
+	data, err := json.MarshalIndent(s.hostGroups, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal host groups: %w", err)
+	}
+	if err := os.WriteFile(s.hostGroupsConfigPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write host groups file: %w", err)
+	}
+	return nil
+}
+
+// findHostGroup_nolock returns the index of the group with the given ID, or -1.
+func (s *Service) findHostGroup_nolock(id string) int {
+	for i, g := range s.hostGroups.Groups {
+		if g.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeAliasFromGroups_nolock removes alias from every group and reports whether anything changed.
+func (s *Service) removeAliasFromGroups_nolock(alias string) bool {
+	changed := false
+	for i := range s.hostGroups.Groups {
+		g := &s.hostGroups.Groups[i]
+		kept := g.Aliases[:0]
+		for _, a := range g.Aliases {
+			if a == alias {
+				changed = true
+				continue
+			}
+			kept = append(kept, a)
+		}
+		g.Aliases = kept
+	}
+	return changed
+}
+
+// sanitizeHostGroup trims and validates the user editable fields of a group.
+func sanitizeHostGroup(name, color string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	color = strings.TrimSpace(color)
+	if name == "" {
+		return "", "", fmt.Errorf("group name cannot be empty")
+	}
+	if color != "" && !hostGroupColorPattern.MatchString(color) {
+		return "", "", fmt.Errorf("invalid group color '%s', expected a hex color like #3b82f6", color)
+	}
+	return name, color, nil
+}
+
+// copyHostGroups returns a deep copy of the groups so callers can't modify the shared state.
+func copyHostGroups(groups []HostGroup) []HostGroup {
+	out := make([]HostGroup, len(groups))
+	for i, g := range groups {
+		g.Aliases = append([]string{}, g.Aliases...)
+		out[i] = g
+	}
+	return out
+}
+
+// GetHostGroups returns all host groups in display order.
+func (s *Service) GetHostGroups() []HostGroup {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+	return copyHostGroups(s.hostGroups.Groups)
+}
+
+// CreateHostGroup creates a new, empty group at the end of the list.
+func (s *Service) CreateHostGroup(name, color string) (*HostGroup, error) {
+	name, color, err := sanitizeHostGroup(name, color)
+	if err != nil {
+		return nil, err
+	}
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	for _, g := range s.hostGroups.Groups {
+		if strings.EqualFold(g.Name, name) {
+			return nil, fmt.Errorf("host group '%s' already exists", name)
+		}
+	}
+	group := HostGroup{ID: uuid.NewString(), Name: name, Color: color, Aliases: []string{}}
+	s.hostGroups.Groups = append(s.hostGroups.Groups, group)
+	if err := s.saveHostGroups(); err != nil {
+		s.hostGroups.Groups = s.hostGroups.Groups[:len(s.hostGroups.Groups)-1]
+		return nil, err
+	}
+	return &group, nil
+}
+
+// UpdateHostGroup renames or recolors a group. Membership is changed with SetHostGroupHosts and MoveHostToGroup.
+func (s *Service) UpdateHostGroup(id, name, color string) error {
+	name, color, err := sanitizeHostGroup(name, color)
+	if err != nil {
+		return err
+	}
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	idx := s.findHostGroup_nolock(id)
+	if idx < 0 {
+		return fmt.Errorf("host group with ID '%s' not found", id)
+	}
+	for i, g := range s.hostGroups.Groups {
+		if i != idx && strings.EqualFold(g.Name, name) {
+			return fmt.Errorf("host group '%s' already exists", name)
+		}
+	}
+	s.hostGroups.Groups[idx].Name = name
+	s.hostGroups.Groups[idx].Color = color
+	return s.saveHostGroups()
+}
+
+// SetHostGroupCollapsed remembers whether a group folder is collapsed in the host list.
+func (s *Service) SetHostGroupCollapsed(id string, collapsed bool) error {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	idx := s.findHostGroup_nolock(id)
+	if idx < 0 {
+		return fmt.Errorf("host group with ID '%s' not found", id)
+	}
+	if s.hostGroups.Groups[idx].Collapsed == collapsed {
+		return nil
+	}
+	s.hostGroups.Groups[idx].Collapsed = collapsed
+	return s.saveHostGroups()
+}
+
+// DeleteHostGroup deletes a group. Its hosts are not deleted, they simply become ungrouped.
+func (s *Service) DeleteHostGroup(id string) error {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	idx := s.findHostGroup_nolock(id)
+	if idx < 0 {
+		return fmt.Errorf("host group with ID '%s' not found", id)
+	}
+	s.hostGroups.Groups = append(s.hostGroups.Groups[:idx], s.hostGroups.Groups[idx+1:]...)
+	return s.saveHostGroups()
+}
+
+// UpdateHostGroupsOrder reorders the groups. IDs missing from order keep their relative order at the end.
+func (s *Service) UpdateHostGroupsOrder(order []string) error {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	byID := make(map[string]HostGroup, len(s.hostGroups.Groups))
+	for _, g := range s.hostGroups.Groups {
+		byID[g.ID] = g
+	}
+	reordered := make([]HostGroup, 0, len(s.hostGroups.Groups))
+	for _, id := range order {
+		if g, ok := byID[id]; ok {
+			reordered = append(reordered, g)
+			delete(byID, id)
+		}
+	}
+	for _, g := range s.hostGroups.Groups {
+		if _, ok := byID[g.ID]; ok {
+			reordered = append(reordered, g)
+		}
+	}
+	s.hostGroups.Groups = reordered
+	return s.saveHostGroups()
+}
+
+// SetHostGroupHosts replaces the ordered members of a group.
+// Hosts that were in other groups are moved into this one.
+func (s *Service) SetHostGroupHosts(id string, aliases []string) error {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	idx := s.findHostGroup_nolock(id)
+	if idx < 0 {
+		return fmt.Errorf("host group with ID '%s' not found", id)
+	}
+	members := make([]string, 0, len(aliases))
+	seen := make(map[string]bool, len(aliases))
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" || seen[alias] {
+			continue
+		}
+		if !s.sshManager.HasHost(alias) {
+			return fmt.Errorf("host with alias '%s' not found", alias)
+		}
+		seen[alias] = true
+		members = append(members, alias)
+	}
+	for _, alias := range members {
+		s.removeAliasFromGroups_nolock(alias)
+	}
+	s.hostGroups.Groups[idx].Aliases = members
+	return s.saveHostGroups()
+}
+
+// MoveHostToGroup moves a host into a group at the given position (a negative or too large
+// position appends it). An empty groupID removes the host from its group.
+func (s *Service) MoveHostToGroup(alias, groupID string, position int) error {
+	if !s.sshManager.HasHost(alias) {
+		return fmt.Errorf("host with alias '%s' not found", alias)
+	}
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	if groupID == "" {
+		if !s.removeAliasFromGroups_nolock(alias) {
+			return nil
+		}
+		return s.saveHostGroups()
+	}
+
+	idx := s.findHostGroup_nolock(groupID)
+	if idx < 0 {
+		return fmt.Errorf("host group with ID '%s' not found", groupID)
+	}
+	s.removeAliasFromGroups_nolock(alias)
+	g := &s.hostGroups.Groups[idx]
+	if position < 0 || position > len(g.Aliases) {
+		position = len(g.Aliases)
+	}
+	g.Aliases = append(g.Aliases, "")
+	copy(g.Aliases[position+1:], g.Aliases[position:])
+	g.Aliases[position] = alias
+	return s.saveHostGroups()
+}
+
+// GetSSHHostsWithGroups returns the same hosts as GetSSHHosts, each annotated with its group.
+func (s *Service) GetSSHHostsWithGroups() ([]GroupedSSHHost, error) {
+	hosts, err := s.GetSSHHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	s.groupMu.Lock()
+	membership := make(map[string]HostGroup)
+	for _, g := range s.hostGroups.Groups {
+		for _, alias := range g.Aliases {
+			membership[alias] = g
+		}
+	}
+	s.groupMu.Unlock()
+
+	result := make([]GroupedSSHHost, len(hosts))
+	for i, h := range hosts {
+		result[i] = GroupedSSHHost{SSHHost: h}
+		if g, ok := membership[h.Alias]; ok {
+			result[i].GroupID = g.ID
+			result[i].GroupName = g.Name
+		}
+	}
+	return result, nil
+}
+
+// renameHostInGroups keeps group membership and the favorite flag after a host is renamed.
+func (s *Service) renameHostInGroups(oldAlias, newAlias string) error {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	changed := false
+	for i := range s.hostGroups.Groups {
+		for j, alias := range s.hostGroups.Groups[i].Aliases {
+			if alias == oldAlias {
+				s.hostGroups.Groups[i].Aliases[j] = newAlias
+				changed = true
+			}
+		}
+	}
+	if i := slices.Index(s.hostGroups.Favorites, oldAlias); i >= 0 {
+		s.hostGroups.Favorites[i] = newAlias
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return s.saveHostGroups()
+}
+
+// removeHostFromGroups drops a deleted host from its group and from the favorites.
+func (s *Service) removeHostFromGroups(alias string) error {
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	removedGroup := s.removeAliasFromGroups_nolock(alias)
+	removedFavorite := s.removeFavorite_nolock(alias)
+	if !removedGroup && !removedFavorite {
+		return nil
+	}
+	return s.saveHostGroups()
+}
@@ -0,0 +1,177 @@
+package sshgate
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"devtools/backend/internal/workspace"
+	"devtools/backend/pkg/utils"
+)
+
+// --- Host notes and metadata, stored in host_notes.json instead of ssh_config comments ---
+
+// maxHostNotesLength limits the free-form notes of one host.
+const maxHostNotesLength = 64 * 1024
+
+// HostNotes documents a host: who owns it, which environment it belongs to and related links.
+type HostNotes struct {
+	Notes       string   `json:"notes,omitempty"`
+	URLs        []string `json:"urls,omitempty"` // e.g. dashboards, runbooks; http(s) only
+	Owner       string   `json:"owner,omitempty"`
+	Environment string   `json:"environment,omitempty"` // e.g. "production", "staging"
+	UpdatedAt   string   `json:"updatedAt,omitempty"`   // RFC3339, set on save
+}
+
+// normalize trims the fields and drops empty URLs.
+func (n *HostNotes) normalize() {
+	n.Notes = strings.TrimRight(n.Notes, " \t\r\n")
+	n.Owner = strings.TrimSpace(n.Owner)
+	n.Environment = strings.TrimSpace(n.Environment)
+	urls := []string{}
+	for _, u := range n.URLs {
+		if u = strings.TrimSpace(u); u != "" && !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	n.URLs = urls
+}
+
+// IsEmpty reports whether the notes carry no information.
+func (n HostNotes) IsEmpty() bool {
+	return n.Notes == "" && len(n.URLs) == 0 && n.Owner == "" && n.Environment == ""
+}
+
+// Validate checks the size of the notes and that every URL is an absolute http(s) URL.
+func (n HostNotes) Validate() error {
+	if len(n.Notes) > maxHostNotesLength {
+		return fmt.Errorf("notes cannot be longer than %d bytes", maxHostNotesLength)
+	}
+	for _, raw := range n.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid URL '%s': only http and https links are supported", raw)
+		}
+	}
+	return nil
+}
+
+// loadHostNotes loads the host notes from host_notes.json next to tunnels.json.
+func (s *Service) loadHostNotes() error {
+	s.notesMu.Lock()
+	defer s.notesMu.Unlock()
+
+	appConfigDir, err := workspace.ConfigDir()
+	if err != nil {
+		return err
+	}
+	s.hostNotesConfigPath = filepath.Join(appConfigDir, "host_notes.json")
+
+	data, err := os.ReadFile(s.hostNotesConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read host notes file: %w", err)
+	}
+
+	notes := map[string]HostNotes{}
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return fmt.Errorf("failed to unmarshal host notes: %w", err)
+	}
+	s.hostNotes = notes
+	logger.Printf("Successfully loaded notes for %d hosts.", len(notes))
+	return nil
+}
+
+// saveHostNotes persists the host notes. The caller must hold s.notesMu.
+func (s *Service) saveHostNotes() error {
+	if s.hostNotesConfigPath == "" {
+		return fmt.Errorf("host notes path is not initialized")
+	}
+	data, err := json.MarshalIndent(s.hostNotes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal host notes: %w", err)
+	}
+	if err := os.WriteFile(s.hostNotesConfigPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write host notes file: %w", err)
+	}
+	utils.EmitEvent(s.ctx, "ssh_host_notes_changed")
+	return nil
+}
+
+// GetAllHostNotes returns the notes of all hosts, keyed by alias.
+func (s *Service) GetAllHostNotes() map[string]HostNotes {
+	s.notesMu.Lock()
+	defer s.notesMu.Unlock()
+	return maps.Clone(s.hostNotes)
+}
+
+// GetHostNotes returns the notes of a host. Hosts without notes get an empty value.
+func (s *Service) GetHostNotes(alias string) HostNotes {
+	s.notesMu.Lock()
+	defer s.notesMu.Unlock()
+	return s.hostNotes[alias]
+}
+
+// SaveHostNotes replaces the notes of a host. Saving empty notes deletes them.
+func (s *Service) SaveHostNotes(alias string, notes HostNotes) (*HostNotes, error) {
+	if alias == "" {
+		return nil, fmt.Errorf("host alias cannot be empty")
+	}
+	notes.normalize()
+	if err := notes.Validate(); err != nil {
+		return nil, err
+	}
+	if notes.IsEmpty() {
+		return &notes, s.DeleteHostNotes(alias)
+	}
+	if s.sshManager.IsEphemeralHost(alias) {
+		return nil, fmt.Errorf("host '%s' is temporary, persist it before adding notes", alias)
+	}
+	if _, err := s.sshManager.GetSSHHost(alias); err != nil {
+		return nil, fmt.Errorf("host with alias '%s' not found", alias)
+	}
+	notes.UpdatedAt = time.Now().Format(time.RFC3339)
+
+	s.notesMu.Lock()
+	defer s.notesMu.Unlock()
+	if s.hostNotes == nil {
+		s.hostNotes = make(map[string]HostNotes)
+	}
+	s.hostNotes[alias] = notes
+	if err := s.saveHostNotes(); err != nil {
+		return nil, err
+	}
+	return &notes, nil
+}
+
+// DeleteHostNotes removes the notes of a host.
+func (s *Service) DeleteHostNotes(alias string) error {
+	s.notesMu.Lock()
+	defer s.notesMu.Unlock()
+	if _, ok := s.hostNotes[alias]; !ok {
+		return nil
+	}
+	delete(s.hostNotes, alias)
+	return s.saveHostNotes()
+}
+
+// renameHostNotes moves a host's notes to its new alias after a rename.
+func (s *Service) renameHostNotes(oldAlias, newAlias string) error {
+	s.notesMu.Lock()
+	defer s.notesMu.Unlock()
+	notes, ok := s.hostNotes[oldAlias]
+	if !ok {
+		return nil
+	}
+	delete(s.hostNotes, oldAlias)
+	s.hostNotes[newAlias] = notes
+	return s.saveHostNotes()
+}
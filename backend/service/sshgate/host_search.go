@@ -0,0 +1,153 @@
+package sshgate
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"devtools/backend/internal/types"
+)
+
+// --- Host search for the quick-connect palette ---
+
+// maxHostSearchResults limits how many hosts SearchHosts returns.
+const maxHostSearchResults = 50
+
+// Search field weights (percent of the match score): a hit on the alias ranks above the same hit in the notes.
+var hostSearchFields = []struct {
+	name   string
+	weight int
+	fuzzy  bool // long free-form text only matches substrings, subsequences would match almost anything
+}{
+	{"alias", 100, true},
+	{"hostName", 80, true},
+	{"user", 50, true},
+	{"group", 60, true},
+	{"environment", 60, true},
+	{"owner", 50, true},
+	{"notes", 30, false},
+}
+
+// HostSearchResult is a host matched by SearchHosts.
+type HostSearchResult struct {
+	Host         types.SSHHost `json:"host"`
+	Score        int           `json:"score"`
+	MatchedField string        `json:"matchedField,omitempty"` // field with the best match, e.g. "alias" or "notes"
+	GroupName    string        `json:"groupName,omitempty"`
+	Environment  string        `json:"environment,omitempty"`
+}
+
+// SearchHosts ranks hosts by how well they match query. Every whitespace-separated term must match
+// the alias, HostName, User, group, environment, owner or notes of a host; terms match exactly,
+// by prefix, as a substring or, for terms of 3 or more characters, as a subsequence (e.g. "pdb" matches "prod-db").
+// An empty query returns the favorites first, then the other hosts by alias.
+func (s *Service) SearchHosts(query string) ([]HostSearchResult, error) {
+	hosts, err := s.GetSSHHostsWithGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	s.notesMu.Lock()
+	candidates := make([]HostSearchResult, len(hosts))
+	fields := make([][]string, len(hosts))
+	for i, h := range hosts {
+		notes := s.hostNotes[h.Alias]
+		candidates[i] = HostSearchResult{Host: h.SSHHost, GroupName: h.GroupName, Environment: notes.Environment}
+		fields[i] = []string{h.Alias, h.HostName, h.User, h.GroupName, notes.Environment, notes.Owner, notes.Notes}
+	}
+	s.notesMu.Unlock()
+
+	return rankHosts(candidates, fields, query), nil
+}
+
+// rankHosts scores each candidate against the query terms; fields[i] holds candidate i's values in hostSearchFields order.
+func rankHosts(candidates []HostSearchResult, fields [][]string, query string) []HostSearchResult {
+	terms := strings.Fields(strings.ToLower(query))
+	results := []HostSearchResult{}
+	for i, c := range candidates {
+		total, bestField, bestScore := 0, "", 0
+		matched := true
+		for _, term := range terms {
+			termBest := 0
+			for j, f := range hostSearchFields {
+				score := matchScore(term, strings.ToLower(fields[i][j]), f.fuzzy) * f.weight / 100
+				if score > termBest {
+					termBest = score
+				}
+				if score > bestScore {
+					bestScore, bestField = score, f.name
+				}
+			}
+			if termBest == 0 {
+				matched = false
+				break
+			}
+			total += termBest
+		}
+		if !matched {
+			continue
+		}
+		if c.Host.Favorite {
+			total += 50
+		}
+		c.Score, c.MatchedField = total, bestField
+		results = append(results, c)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Host.Alias < results[j].Host.Alias
+	})
+	if len(results) > maxHostSearchResults {
+		results = results[:maxHostSearchResults]
+	}
+	return results
+}
+
+// matchScore returns how well term matches text (both lower-case), 0 meaning no match.
+func matchScore(term, text string, fuzzy bool) int {
+	if text == "" {
+		return 0
+	}
+	switch {
+	case text == term:
+		return 1000
+	case strings.HasPrefix(text, term):
+		return 800 - min(utf8.RuneCountInString(text)-utf8.RuneCountInString(term), 100)
+	}
+	if i := strings.Index(text, term); i >= 0 {
+		if isWordBoundary(text, i) {
+			return 600 - min(i, 100)
+		}
+		return 400 - min(i, 100)
+	}
+	// Terms shorter than 3 runes only match as substrings, "db" as a subsequence would match most hosts.
+	if !fuzzy || utf8.RuneCountInString(term) < 3 {
+		return 0
+	}
+	// Subsequence match: every rune appears in order, fewer skipped bytes score higher.
+	gaps, pos := 0, 0
+	for _, r := range term {
+		i := strings.IndexRune(text[pos:], r)
+		if i < 0 {
+			return 0
+		}
+		gaps += i
+		pos += i + utf8.RuneLen(r)
+	}
+	return max(200-gaps*10, 10)
+}
+
+// isWordBoundary reports whether position i in text starts a word, e.g. "db" in "prod-db".
+func isWordBoundary(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	switch text[i-1] {
+	case '-', '_', '.', ' ', '@', '/', ':':
+		return true
+	}
+	return false
+}
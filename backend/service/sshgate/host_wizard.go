@@ -0,0 +1,223 @@
+package sshgate
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/types"
+)
+
+// --- Guided "add server" flow ---
+
+// wizardResolveTimeout bounds the DNS lookup of TestHostnameResolves.
+const wizardResolveTimeout = 5 * time.Second
+
+// defaultIdentityFiles are the keys ssh tries when no IdentityFile is set, in its order.
+var defaultIdentityFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// Hints returned by SuggestConfig; the frontend turns them into explanations.
+const (
+	WizardHintAliasTaken   = "alias_taken"   // The requested alias is in use; a numbered one is suggested
+	WizardHintPasswordOnly = "password_only" // The server accepts no keys, a password or OTP is needed
+	WizardHintNoKey        = "no_key"        // The server accepts keys, but no key file was found to suggest
+	WizardHintUnknownUser  = "unknown_user"  // No user was given; ssh would log in as the local user
+)
+
+// HostnameCheck is the result of TestHostnameResolves.
+type HostnameCheck struct {
+	Host      string   `json:"host"`
+	Resolves  bool     `json:"resolves"`
+	IsIP      bool     `json:"isIp,omitempty"` // host is an IP address, so there is nothing to resolve
+	Addresses []string `json:"addresses"`
+	LatencyMs int64    `json:"latencyMs,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// HostWizardInput is what the user entered in the wizard so far.
+type HostWizardInput struct {
+	Host  string `json:"host"`  // Host name or IP address
+	Port  int    `json:"port"`  // 0 means 22
+	User  string `json:"user"`  // Optional
+	Alias string `json:"alias"` // Optional; derived from Host when empty
+}
+
+// HostSuggestion is the host SuggestConfig proposes, together with the checks it is based on.
+// Nothing is written until the frontend passes Host to SaveSSHHost.
+type HostSuggestion struct {
+	Host    types.SSHHost         `json:"host"`
+	Params  map[string]string     `json:"params"` // Extra parameters SaveSSHHost adds from the new host defaults
+	Resolve *HostnameCheck        `json:"resolve"`
+	Probe   *sshmanager.HostProbe `json:"probe,omitempty"` // Not set when the host name does not resolve
+	Ready   bool                  `json:"ready"`           // The host resolves and an SSH server answered with its host key
+	Hints   []string              `json:"hints"`
+}
+
+// TestHostnameResolves checks that host resolves to at least one address.
+func (s *Service) TestHostnameResolves(host string) *HostnameCheck {
+	host = strings.TrimSpace(host)
+	check := &HostnameCheck{Host: host, Addresses: []string{}}
+	if host == "" {
+		check.Error = "host name cannot be empty"
+		return check
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		check.Resolves, check.IsIP = true, true
+		check.Addresses = append(check.Addresses, ip.String())
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wizardResolveTimeout)
+	defer cancel()
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	check.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.Resolves = len(addrs) > 0
+	check.Addresses = append(check.Addresses, addrs...)
+	return check
+}
+
+// TestTCPReachable checks that host:port accepts connections and answers with an SSH banner.
+// No SSH handshake is made.
+func (s *Service) TestTCPReachable(host string, port int) (*sshmanager.HostProbe, error) {
+	portStr, err := wizardPort(port)
+	if err != nil {
+		return nil, err
+	}
+	return s.sshManager.ProbeTCP(strings.Trim(strings.TrimSpace(host), "[]"), portStr), nil
+}
+
+// DetectAuthMethods reads the host key and the authentication methods the server at host:port
+// offers to the local user, without submitting any password or key.
+func (s *Service) DetectAuthMethods(host string, port int) (*sshmanager.HostProbe, error) {
+	portStr, err := wizardPort(port)
+	if err != nil {
+		return nil, err
+	}
+	return s.sshManager.ProbeAddress(strings.Trim(strings.TrimSpace(host), "[]"), portStr, ""), nil
+}
+
+// SuggestConfig runs the wizard checks for input and proposes the host to add: an unused alias,
+// the new host defaults, and an identity file when the server accepts keys.
+func (s *Service) SuggestConfig(input HostWizardInput) (*HostSuggestion, error) {
+	hostName := strings.Trim(strings.TrimSpace(input.Host), "[]")
+	if hostName == "" {
+		return nil, fmt.Errorf("host name cannot be empty")
+	}
+	portStr, err := wizardPort(input.Port)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion := &HostSuggestion{Hints: []string{}}
+	host := types.SSHHost{HostName: hostName, User: strings.TrimSpace(input.User)}
+	suggestion.Params = s.applyNewHostDefaults(&host)
+	if input.Port != 0 {
+		// An explicit port wins over the default one; 22 is what ssh uses anyway
+		host.Port = ""
+		if portStr != "22" {
+			host.Port = portStr
+		}
+	}
+	if host.User == "" {
+		suggestion.Hints = append(suggestion.Hints, WizardHintUnknownUser)
+	}
+
+	alias := strings.TrimSpace(input.Alias)
+	if alias == "" {
+		host.Alias = s.sshManager.UniqueAlias(wizardAlias(hostName))
+	} else {
+		if strings.ContainsAny(alias, " \t*?!") {
+			return nil, fmt.Errorf("invalid host alias '%s'", alias)
+		}
+		host.Alias = s.sshManager.UniqueAlias(alias)
+		if host.Alias != alias {
+			suggestion.Hints = append(suggestion.Hints, WizardHintAliasTaken)
+		}
+	}
+
+	suggestion.Resolve = s.TestHostnameResolves(hostName)
+	if suggestion.Resolve.Resolves {
+		probePort := host.Port
+		if probePort == "" {
+			probePort = "22"
+		}
+		suggestion.Probe = s.sshManager.ProbeAddress(hostName, probePort, host.User)
+		suggestion.Ready = suggestion.Probe.HostKey != ""
+
+		methods := suggestion.Probe.AuthMethods
+		switch {
+		case len(methods) > 0 && !slices.Contains(methods, "publickey"):
+			suggestion.Hints = append(suggestion.Hints, WizardHintPasswordOnly)
+		case slices.Contains(methods, "publickey") && host.IdentityFile == "":
+			if host.IdentityFile = s.defaultIdentityFile(); host.IdentityFile == "" {
+				suggestion.Hints = append(suggestion.Hints, WizardHintNoKey)
+			}
+		}
+	}
+	suggestion.Host = host
+	return suggestion, nil
+}
+
+// wizardPort validates port and returns it as written in ssh_config; 0 means 22.
+func wizardPort(port int) (string, error) {
+	if port == 0 {
+		return "22", nil
+	}
+	if port < 1 || port > 65535 {
+		return "", fmt.Errorf("port must be between 1 and 65535")
+	}
+	return strconv.Itoa(port), nil
+}
+
+// wizardAlias derives an alias from a host name: its first label ("web01" for
+// "web01.corp.example.com"), or the whole address for IP addresses.
+func wizardAlias(hostName string) string {
+	base := hostName
+	if net.ParseIP(hostName) == nil {
+		base, _, _ = strings.Cut(hostName, ".")
+	}
+	base = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '-'
+		}
+	}, base)
+	if base = strings.Trim(base, "-"); base == "" {
+		return "server"
+	}
+	return base
+}
+
+// defaultIdentityFile returns the first of ssh's default keys that exists next to the ssh_config,
+// written with ~ when it is in the home directory, or "" if there is none.
+func (s *Service) defaultIdentityFile() string {
+	dir := filepath.Dir(s.sshManager.ConfigPath())
+	home, _ := os.UserHomeDir()
+	for _, name := range defaultIdentityFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if home != "" {
+			if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+				return "~/" + filepath.ToSlash(rel)
+			}
+		}
+		return path
+	}
+	return ""
+}
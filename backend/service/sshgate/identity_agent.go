@@ -0,0 +1,18 @@
+package sshgate
+
+import "devtools/backend/internal/sshmanager"
+
+// GetHostIdentityAgent returns the ssh-agent used to authenticate to alias, from IdentityAgent in ssh_config.
+func (s *Service) GetHostIdentityAgent(alias string) sshmanager.IdentityAgentSettings {
+	return s.sshManager.IdentityAgentFor(alias)
+}
+
+// SetHostIdentityAgent writes IdentityAgent for alias to ssh_config: "none", SSH_AUTH_SOCK, $VAR or a
+// socket path such as the 1Password agent. An empty value removes it, so SSH_AUTH_SOCK is used again.
+func (s *Service) SetHostIdentityAgent(alias, value string) error {
+	if _, err := s.sshManager.SetIdentityAgent(alias, value); err != nil {
+		return err
+	}
+	logger.Printf("Saved IdentityAgent for host %s: %q.", alias, value)
+	return nil
+}
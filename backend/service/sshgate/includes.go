@@ -0,0 +1,32 @@
+package sshgate
+
+import (
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/types"
+)
+
+// ScanForAdjacentConfigs 列出 ~/.ssh/config.d 等目录中的配置文件以及它们是否已被 Include。
+// 前端在 ssh_config 中没有 Include 但找到了这些文件时（常见于公司统一下发的配置）提示用户引入。
+func (s *Service) ScanForAdjacentConfigs() (*sshmanager.AdjacentConfigScan, error) {
+	return s.sshManager.ScanForAdjacentConfigs()
+}
+
+// AdoptInclude 在 ssh_config 中添加 Include path 并重新加载，返回写入的 Include 参数
+func (s *Service) AdoptInclude(path string) (string, error) {
+	arg, err := s.sshManager.AdoptInclude(path)
+	if err != nil {
+		logger.Printf("ERROR: failed to adopt include '%s': %v", path, err)
+		return "", err
+	}
+	return arg, nil
+}
+
+// appendIncludedHosts 只读地追加 Include 文件中的主机，返回追加后的列表与追加的别名
+func (s *Service) appendIncludedHosts(hosts []types.SSHHost) ([]types.SSHHost, map[string]bool) {
+	added := make(map[string]bool)
+	for _, host := range s.sshManager.GetIncludedHosts() {
+		added[host.Alias] = true
+		hosts = append(hosts, host)
+	}
+	return hosts, added
+}
@@ -0,0 +1,228 @@
+package sshgate
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"devtools/backend/internal/settings"
+	"devtools/backend/internal/sshtunnel"
+)
+
+// localAPI 是一个可选的本地 HTTP API，供外部工具（Raycast/Alfred 扩展、脚本等）
+// 查询主机与隧道状态并启动/停止隧道。它只监听 127.0.0.1，且每个请求都需要携带
+// "Authorization: Bearer <token>"。所有操作都直接复用 Service 的方法。
+//
+//	GET  /api/v1/hosts                 列出 ~/.ssh/config 中的主机
+//	GET  /api/v1/tunnels               列出已保存的隧道
+//	GET  /api/v1/tunnels/active        列出正在运行的隧道
+//	POST /api/v1/tunnels/{id}/start    按已保存的配置 ID 启动隧道（密码从系统钥匙串读取）
+//	POST /api/v1/tunnels/{id}/stop     停止隧道，id 可以是运行中隧道的 ID 或其配置 ID
+type localAPI struct {
+	gate *Service
+
+	mu      sync.Mutex
+	ready   bool // Service.Startup 完成后才允许启动，确保隧道配置已加载
+	enabled bool
+	port    int
+	token   string
+	server  *http.Server
+	addr    string
+}
+
+func newLocalAPI(gate *Service) *localAPI {
+	return &localAPI{gate: gate}
+}
+
+// configure 根据设置启动、重启或停止本地 API
+func (l *localAPI) configure(cfg settings.Settings) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enabled = cfg.LocalAPIEnabled
+	l.port = cfg.LocalAPIPort
+	l.token = cfg.LocalAPIToken
+	l.reconcile_nolock()
+}
+
+// setReady 在 Service 启动完成后调用
+func (l *localAPI) setReady() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ready = true
+	l.reconcile_nolock()
+}
+
+// stop 关闭本地 API，应用退出时调用
+func (l *localAPI) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ready = false
+	l.stop_nolock()
+}
+
+// reconcile_nolock 使服务器的运行状态与当前设置一致，调用方需持有 l.mu
+func (l *localAPI) reconcile_nolock() {
+	if !l.ready || !l.enabled || l.token == "" {
+		l.stop_nolock()
+		return
+	}
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(l.port))
+	if l.server != nil && l.addr == addr {
+		return // token 在每个请求中读取，端口不变时无需重启
+	}
+	l.stop_nolock()
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Printf("ERROR: failed to start local API on %s: %v", addr, err)
+		return
+	}
+	server := &http.Server{
+		Handler:           l.routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	l.server = server
+	l.addr = addr
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Printf("ERROR: local API server stopped unexpectedly: %v", err)
+		}
+	}()
+	logger.Printf("Local API listening on http://%s", addr)
+}
+
+func (l *localAPI) stop_nolock() {
+	if l.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := l.server.Shutdown(ctx); err != nil {
+		logger.Printf("Warning: failed to shut down local API gracefully: %v", err)
+	}
+	logger.Printf("Local API on %s stopped.", l.addr)
+	l.server = nil
+	l.addr = ""
+}
+
+func (l *localAPI) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/hosts", l.handleListHosts)
+	mux.HandleFunc("GET /api/v1/tunnels", l.handleListTunnels)
+	mux.HandleFunc("GET /api/v1/tunnels/active", l.handleListActiveTunnels)
+	mux.HandleFunc("POST /api/v1/tunnels/{id}/start", l.handleStartTunnel)
+	mux.HandleFunc("POST /api/v1/tunnels/{id}/stop", l.handleStopTunnel)
+	return l.authenticate(mux)
+}
+
+// authenticate 校验 Bearer token，并拒绝来自浏览器页面的跨站请求
+func (l *localAPI) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeAPIError(w, http.StatusForbidden, "cross-origin requests are not allowed")
+			return
+		}
+		l.mu.Lock()
+		token := l.token
+		l.mu.Unlock()
+
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *localAPI) handleListHosts(w http.ResponseWriter, r *http.Request) {
+	hosts, err := l.gate.GetSSHHosts()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, hosts)
+}
+
+func (l *localAPI) handleListTunnels(w http.ResponseWriter, r *http.Request) {
+	tunnels, err := l.gate.GetSavedTunnels()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, tunnels)
+}
+
+func (l *localAPI) handleListActiveTunnels(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, l.gate.GetActiveTunnels())
+}
+
+func (l *localAPI) handleStartTunnel(w http.ResponseWriter, r *http.Request) {
+	configID := r.PathValue("id")
+	if !l.gate.hasSavedTunnel(configID) {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("tunnel configuration '%s' not found", configID))
+		return
+	}
+	logger.Printf("Local API: starting tunnel from config %s", configID)
+	tunnelID, err := l.gate.StartTunnelFromConfig(configID, "")
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]string{"tunnelId": tunnelID})
+}
+
+func (l *localAPI) handleStopTunnel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var matched []sshtunnel.ActiveTunnelInfo
+	for _, t := range l.gate.GetActiveTunnels() {
+		if t.ID == id || t.ConfigID == id {
+			matched = append(matched, t)
+		}
+	}
+	if len(matched) == 0 {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("no running tunnel matches '%s'", id))
+		return
+	}
+
+	stopped := make([]string, 0, len(matched))
+	for _, t := range matched {
+		logger.Printf("Local API: stopping tunnel %s", t.ID)
+		if err := l.gate.StopForward(t.ID); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		stopped = append(stopped, t.ID)
+	}
+	writeAPIJSON(w, http.StatusOK, map[string][]string{"stopped": stopped})
+}
+
+// hasSavedTunnel 判断指定 ID 的隧道配置是否存在
+func (s *Service) hasSavedTunnel(configID string) bool {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	for _, t := range s.tunnelsConfig.Tunnels {
+		if t.ID == configID {
+			return true
+		}
+	}
+	return false
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,33 @@
+package sshgate
+
+import (
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/pkg/utils"
+)
+
+// --- Offline detection ---
+
+// GetNetworkStatus returns the last observed network state. The frontend greys out connect
+// buttons while Online is false, and listens to "network:changed" for updates.
+func (s *Service) GetNetworkStatus() sshmanager.NetworkState {
+	return s.network.State()
+}
+
+// onNetworkChange forwards network changes to the frontend. Going offline or coming back also
+// emits "network:offline" / "network:online", so listeners need not compare states themselves.
+func (s *Service) onNetworkChange(previous, current sshmanager.NetworkState) {
+	utils.EmitEvent(s.ctx, "network:changed", current)
+	switch {
+	case previous.Online && !current.Online:
+		utils.EmitEvent(s.ctx, "network:offline", current)
+	case !previous.Online && current.Online:
+		utils.EmitEvent(s.ctx, "network:online", current)
+	}
+}
+
+// GetConnectionDiagnostics returns keep-alive round trips, lost keep-alives and estimated rekeys of the
+// host's active and recently closed connections, with a guess whether losses come from the local
+// network or from the server, for users whose tunnels keep dropping.
+func (s *Service) GetConnectionDiagnostics(alias string) sshmanager.ConnectionDiagnostics {
+	return sshmanager.GetConnectionDiagnostics(alias)
+}
@@ -0,0 +1,190 @@
+package sshgate
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Listening port detection ("forward this port") ---
+
+// listeningPortsCommand lists listening TCP sockets with ss, falling back to the GNU and BSD
+// flavors of netstat on hosts without iproute2. Process names need root for sockets of other users.
+const listeningPortsCommand = "ss -ltnp 2>/dev/null || netstat -ltnp 2>/dev/null || netstat -an -p tcp 2>/dev/null"
+
+// detectPortsTimeout bounds the remote command, not the SSH connection itself.
+const detectPortsTimeout = 15 * time.Second
+
+// ssProcessPattern extracts the first process of ss's users:(("name",pid=123,fd=4)) column.
+var ssProcessPattern = regexp.MustCompile(`users:\(\("([^"]*)",pid=(\d+)`)
+
+// DetectedPort is a TCP port a remote host is listening on.
+type DetectedPort struct {
+	Port      int      `json:"port"`
+	Addresses []string `json:"addresses"`         // bind addresses, e.g. "0.0.0.0", "::", "127.0.0.1"
+	Process   string   `json:"process,omitempty"` // empty if ss/netstat could not tell (not root, or BSD netstat)
+	PID       int      `json:"pid,omitempty"`
+}
+
+// DetectedPortForward is returned by ForwardDetectedPort.
+type DetectedPortForward struct {
+	TunnelID   string `json:"tunnelId"`
+	LocalPort  int    `json:"localPort"`
+	RemoteHost string `json:"remoteHost"`
+	RemotePort int    `json:"remotePort"`
+}
+
+// DetectListeningPorts runs ss (or netstat) on the host and returns its listening TCP ports,
+// sorted by port, with the owning process when it is visible to the login user.
+// The stored keychain password is used for hosts that need one.
+func (s *Service) DetectListeningPorts(alias string) ([]DetectedPort, error) {
+	output, err := s.sshManager.RunCommand(alias, "", listeningPortsCommand, detectPortsTimeout)
+	if err != nil {
+		if len(output) == 0 {
+			return nil, fmt.Errorf("failed to list listening ports on %s: %w", alias, err)
+		}
+		logger.Printf("Warning: listing listening ports on %s exited with an error, using partial output: %v", alias, err)
+	}
+	ports := parseListeningPorts(string(output))
+
+	s.detectedPortsMu.Lock()
+	s.detectedPorts[alias] = ports
+	s.detectedPortsMu.Unlock()
+
+	logger.Printf("Detected %d listening ports on %s.", len(ports), alias)
+	return ports, nil
+}
+
+// ForwardDetectedPort creates (or reuses) and starts a local forward to remotePort on the host.
+// The local port is the same as the remote port when it is free, and a random free port otherwise.
+// If the last DetectListeningPorts call found the port bound to a single non-loopback address,
+// the forward targets that address; otherwise it targets localhost on the remote side.
+func (s *Service) ForwardDetectedPort(alias string, remotePort int) (*DetectedPortForward, error) {
+	if remotePort < 1 || remotePort > 65535 {
+		return nil, fmt.Errorf("invalid remote port %d", remotePort)
+	}
+	localPort, err := pickLocalPort(remotePort)
+	if err != nil {
+		return nil, err
+	}
+	remoteHost := s.detectedTargetHost(alias, remotePort)
+
+	tunnelID, err := s.CreateAndStartTunnel("local", alias, localPort, remoteHost, remotePort, false, "")
+	if err != nil {
+		return nil, err
+	}
+	return &DetectedPortForward{TunnelID: tunnelID, LocalPort: localPort, RemoteHost: remoteHost, RemotePort: remotePort}, nil
+}
+
+// detectedTargetHost returns the remote address a forward to port should connect to.
+func (s *Service) detectedTargetHost(alias string, port int) string {
+	s.detectedPortsMu.Lock()
+	defer s.detectedPortsMu.Unlock()
+	for _, p := range s.detectedPorts[alias] {
+		if p.Port != port {
+			continue
+		}
+		if len(p.Addresses) == 1 {
+			if ip := net.ParseIP(p.Addresses[0]); ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() && ip.To4() != nil {
+				return p.Addresses[0]
+			}
+		}
+		break
+	}
+	return "localhost"
+}
+
+// pickLocalPort returns preferred if it can be bound on 127.0.0.1, or a free port chosen by the OS.
+func pickLocalPort(preferred int) (int, error) {
+	for _, port := range []int{preferred, 0} {
+		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			continue
+		}
+		port = l.Addr().(*net.TCPAddr).Port
+		l.Close()
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free local port available")
+}
+
+// parseListeningPorts parses the output of `ss -ltnp`, `netstat -ltnp` (Linux) or `netstat -an -p tcp` (BSD/macOS).
+// Sockets on the same port (e.g. IPv4 and IPv6) are merged into one entry.
+func parseListeningPorts(output string) []DetectedPort {
+	byPort := make(map[int]*DetectedPort)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		var local, process string
+		var pid int
+		switch {
+		case fields[0] == "LISTEN": // ss: State Recv-Q Send-Q Local Peer Process
+			local = fields[3]
+			if m := ssProcessPattern.FindStringSubmatch(line); m != nil {
+				process = m[1]
+				pid, _ = strconv.Atoi(m[2])
+			}
+		case strings.HasPrefix(fields[0], "tcp") && len(fields) >= 6 && fields[5] == "LISTEN": // netstat: Proto Recv-Q Send-Q Local Foreign State [PID/Program]
+			local = fields[3]
+			if len(fields) >= 7 {
+				if id, name, ok := strings.Cut(fields[6], "/"); ok {
+					pid, _ = strconv.Atoi(id)
+					process = strings.TrimSuffix(name, ":")
+				}
+			}
+		default:
+			continue
+		}
+
+		addr, port, ok := splitListenAddress(local)
+		if !ok {
+			continue
+		}
+		entry := byPort[port]
+		if entry == nil {
+			entry = &DetectedPort{Port: port, Addresses: []string{}}
+			byPort[port] = entry
+		}
+		if !slices.Contains(entry.Addresses, addr) {
+			entry.Addresses = append(entry.Addresses, addr)
+		}
+		if entry.Process == "" && process != "" {
+			entry.Process, entry.PID = process, pid
+		}
+	}
+
+	ports := make([]DetectedPort, 0, len(byPort))
+	for _, p := range byPort {
+		ports = append(ports, *p)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Port < ports[j].Port })
+	return ports
+}
+
+// splitListenAddress splits "0.0.0.0:22", "[::]:22", "*:22", "127.0.0.53%lo:53" or BSD's "*.22"
+// into the bind address and the port. The "*" wildcard of ss and BSD netstat is kept as is.
+func splitListenAddress(local string) (string, int, bool) {
+	i := strings.LastIndexAny(local, ":.")
+	if i < 0 {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(local[i+1:])
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, false
+	}
+	addr := strings.Trim(local[:i], "[]")
+	if zone := strings.IndexByte(addr, '%'); zone >= 0 {
+		addr = addr[:zone]
+	}
+	if addr == "" {
+		addr = "*"
+	}
+	return addr, port, true
+}
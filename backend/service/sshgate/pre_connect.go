@@ -0,0 +1,115 @@
+package sshgate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/workspace"
+)
+
+// --- Pre-connect commands (port knock, VPN checks) ---
+
+// loadPreConnectCommands loads the persisted per-host pre-connect commands and applies them to the ssh manager.
+func (s *Service) loadPreConnectCommands() error {
+	s.preConnectMu.Lock()
+	defer s.preConnectMu.Unlock()
+
+	appConfigDir, err := workspace.ConfigDir()
+	if err != nil {
+		return err
+	}
+	s.preConnectConfigPath = filepath.Join(appConfigDir, "pre_connect.json")
+
+	data, err := os.ReadFile(s.preConnectConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read pre-connect commands file: %w", err)
+	}
+
+	commands := map[string]sshmanager.PreConnectCommand{}
+	if err := json.Unmarshal(data, &commands); err != nil {
+		return fmt.Errorf("failed to unmarshal pre-connect commands: %w", err)
+	}
+	for alias, c := range commands {
+		if err := c.Validate(); err != nil {
+			logger.Printf("Warning: ignoring invalid pre-connect command for host '%s': %v", alias, err)
+			delete(commands, alias)
+		}
+	}
+
+	s.sshManager.SetPreConnectCommands(commands)
+	logger.Printf("Successfully loaded pre-connect commands for %d hosts.", len(commands))
+	return nil
+}
+
+// savePreConnectCommands persists the given commands and applies them to the ssh manager.
+// The caller must hold s.preConnectMu.
+func (s *Service) savePreConnectCommands(commands map[string]sshmanager.PreConnectCommand) error {
+	if s.preConnectConfigPath == "" {
+		return fmt.Errorf("pre-connect commands path is not initialized")
+	}
+	data, err := json.MarshalIndent(commands, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pre-connect commands: %w", err)
+	}
+	if err := os.WriteFile(s.preConnectConfigPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write pre-connect commands file: %w", err)
+	}
+	s.sshManager.SetPreConnectCommands(commands)
+	return nil
+}
+
+// GetPreConnectCommands returns the pre-connect commands of all hosts, keyed by alias.
+func (s *Service) GetPreConnectCommands() map[string]sshmanager.PreConnectCommand {
+	return s.sshManager.GetPreConnectCommands()
+}
+
+// SaveHostPreConnectCommand sets the local command that runs before terminals and tunnels connect to alias,
+// e.g. a port knock. The connection is not attempted if the command fails, unless IgnoreFailure is set.
+func (s *Service) SaveHostPreConnectCommand(alias string, command sshmanager.PreConnectCommand) error {
+	if alias == "" {
+		return fmt.Errorf("host alias cannot be empty")
+	}
+	if err := command.Validate(); err != nil {
+		return err
+	}
+	s.preConnectMu.Lock()
+	defer s.preConnectMu.Unlock()
+
+	commands := s.sshManager.GetPreConnectCommands()
+	commands[alias] = command
+	return s.savePreConnectCommands(commands)
+}
+
+// DeleteHostPreConnectCommand removes the pre-connect command of a host.
+func (s *Service) DeleteHostPreConnectCommand(alias string) error {
+	s.preConnectMu.Lock()
+	defer s.preConnectMu.Unlock()
+
+	commands := s.sshManager.GetPreConnectCommands()
+	if _, ok := commands[alias]; !ok {
+		return nil
+	}
+	delete(commands, alias)
+	return s.savePreConnectCommands(commands)
+}
+
+// renameHostPreConnectCommand moves a host's pre-connect command to its new alias after a rename.
+func (s *Service) renameHostPreConnectCommand(oldAlias, newAlias string) error {
+	s.preConnectMu.Lock()
+	defer s.preConnectMu.Unlock()
+
+	commands := s.sshManager.GetPreConnectCommands()
+	command, ok := commands[oldAlias]
+	if !ok {
+		return nil
+	}
+	delete(commands, oldAlias)
+	commands[newAlias] = command
+	return s.savePreConnectCommands(commands)
+}
@@ -0,0 +1,96 @@
+package sshgate
+
+import (
+	"errors"
+	"sync"
+
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/utils"
+)
+
+// preflightWorkers bounds how many saved tunnels are verified at the same time.
+const preflightWorkers = 4
+
+// TunnelPreflightResult is the outcome of the pre-flight check of a single saved tunnel.
+// It is streamed to the frontend via the "tunnels:preflight_result" event.
+type TunnelPreflightResult struct {
+	ConfigID  string                  `json:"configId"`
+	Name      string                  `json:"name"`
+	Result    *types.ConnectionResult `json:"result"`
+	Completed int                     `json:"completed"`
+	Total     int                     `json:"total"`
+}
+
+// TunnelPreflightSummary is returned by VerifyAllSavedTunnels and emitted
+// with the "tunnels:preflight_done" event once all checks are finished.
+type TunnelPreflightSummary struct {
+	Total     int                     `json:"total"`
+	Startable int                     `json:"startable"`
+	Results   []TunnelPreflightResult `json:"results"`
+}
+
+// VerifyAllSavedTunnels runs the pre-flight check for every saved tunnel concurrently.
+// Stored passwords from the keychain are used; tunnels that need a password or a host key
+// confirmation are reported as such instead of prompting.
+// Each result is emitted as soon as it is available so the UI can update progressively.
+func (s *Service) VerifyAllSavedTunnels() (*TunnelPreflightSummary, error) {
+	if !s.preflightRunning.CompareAndSwap(false, true) {
+		return nil, errors.New("a pre-flight check of all saved tunnels is already running")
+	}
+	defer s.preflightRunning.Store(false)
+
+	type job struct{ id, name string }
+	s.configMu.RLock()
+	jobs := make([]job, 0, len(s.tunnelsConfig.Tunnels))
+	for _, t := range s.tunnelsConfig.Tunnels {
+		jobs = append(jobs, job{id: t.ID, name: t.Name})
+	}
+	s.configMu.RUnlock()
+
+	summary := &TunnelPreflightSummary{Total: len(jobs), Results: make([]TunnelPreflightResult, 0, len(jobs))}
+	logger.Printf("Starting pre-flight check for %d saved tunnels.", len(jobs))
+
+	jobCh := make(chan job)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for i := 0; i < preflightWorkers && i < len(jobs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobCh {
+				result, err := s.VerifyTunnelConfigConnection(j.id, "")
+				if err != nil {
+					result = &types.ConnectionResult{Success: false, ErrorMessage: err.Error()}
+				}
+
+				mu.Lock()
+				if result.Success {
+					summary.Startable++
+				}
+				item := TunnelPreflightResult{
+					ConfigID:  j.id,
+					Name:      j.name,
+					Result:    result,
+					Completed: len(summary.Results) + 1,
+					Total:     summary.Total,
+				}
+				summary.Results = append(summary.Results, item)
+				mu.Unlock()
+
+				utils.EmitEvent(s.ctx, "tunnels:preflight_result", item)
+			}
+		}()
+	}
+
+	for _, j := range jobs {
+		jobCh <- j
+	}
+	close(jobCh)
+	wg.Wait()
+
+	logger.Printf("Pre-flight check finished: %d/%d saved tunnels are startable.", summary.Startable, summary.Total)
+	utils.EmitEvent(s.ctx, "tunnels:preflight_done", summary)
+	return summary, nil
+}
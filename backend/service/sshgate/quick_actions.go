@@ -0,0 +1,66 @@
+package sshgate
+
+import (
+	"fmt"
+)
+
+// --- Quick actions (used by the Quick Actions menu, without opening the main window) ---
+
+// QuickTunnel is a favorite tunnel with its running state.
+type QuickTunnel struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Running  bool   `json:"running"`
+	ActiveID string `json:"activeId,omitempty"` // ID of the running tunnel, for StopForward
+}
+
+// SetTunnelFavorite marks a saved tunnel as favorite so it is offered in the quick actions menu.
+func (s *Service) SetTunnelFavorite(configID string, favorite bool) error {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	for i := range s.tunnelsConfig.Tunnels {
+		if s.tunnelsConfig.Tunnels[i].ID == configID {
+			if s.tunnelsConfig.Tunnels[i].Favorite == favorite {
+				return nil
+			}
+			s.tunnelsConfig.Tunnels[i].Favorite = favorite
+			return s.saveTunnelsConfig()
+		}
+	}
+	return fmt.Errorf("tunnel configuration with ID %s not found", configID)
+}
+
+// GetQuickTunnels returns the favorite tunnels in display order with their running state.
+func (s *Service) GetQuickTunnels() []QuickTunnel {
+	saved, _ := s.GetSavedTunnels()
+	running := make(map[string]string)
+	for _, t := range s.tunnelManager.GetActiveTunnels() {
+		if t.ConfigID != "" {
+			running[t.ConfigID] = t.ID
+		}
+	}
+
+	tunnels := []QuickTunnel{}
+	for _, t := range saved {
+		if !t.Favorite {
+			continue
+		}
+		activeID, ok := running[t.ID]
+		tunnels = append(tunnels, QuickTunnel{ID: t.ID, Name: t.Name, Running: ok, ActiveID: activeID})
+	}
+	return tunnels
+}
+
+// ToggleQuickTunnel starts a saved tunnel if it is stopped and stops it if it is running.
+// The tunnel is started with the password stored in the keychain, if any; hosts that need
+// an interactive password have to be started from the main window.
+func (s *Service) ToggleQuickTunnel(configID string) error {
+	for _, t := range s.tunnelManager.GetActiveTunnels() {
+		if t.ConfigID == configID {
+			return s.StopForward(t.ID)
+		}
+	}
+	_, err := s.StartTunnelFromConfig(configID, "")
+	return err
+}
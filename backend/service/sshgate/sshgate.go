@@ -5,29 +5,36 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/logging"
+	"devtools/backend/internal/migrate"
+	"devtools/backend/internal/settings"
 	"devtools/backend/internal/sshmanager"
 	"devtools/backend/internal/sshtunnel"
 	"devtools/backend/internal/types"
+	"devtools/backend/internal/workspace"
 	"devtools/backend/pkg/sshconfig"
+	"devtools/backend/pkg/utils"
 
 	"github.com/google/uuid"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
-	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/knownhosts"
 )
 
+var logger = logging.For("sshgate")
+
 // TunnelsConfig is the root object for the tunnels JSON configuration file.
 type TunnelsConfig struct {
-	Tunnels      []sshtunnel.SavedTunnelConfig `json:"tunnels"`
-	TunnelsOrder []string                      `json:"tunnelsOrder,omitempty"`
+	SchemaVersion int                           `json:"schemaVersion"` // 见 migrate.TunnelsSchemaVersion
+	Tunnels       []sshtunnel.SavedTunnelConfig `json:"tunnels"`
+	TunnelsOrder  []string                      `json:"tunnelsOrder,omitempty"`
 }
 
 // Service 封装了所有与 SSH Gate 功能相关的后端逻辑
@@ -45,6 +52,73 @@ type Service struct {
 	savedTunnelsEventDebouncer   *time.Timer
 	savedTunnelsDebounceDuration time.Duration
 	savedTunnelsEventMu          sync.Mutex
+
+	// --- For connection policy persistence ---
+	policiesConfigPath string
+	policyMu           sync.Mutex
+
+	// --- For pre-connect command persistence ---
+	preConnectConfigPath string
+	preConnectMu         sync.Mutex
+
+	// --- Defaults applied to new hosts, from the app settings ---
+	newHostDefaults settings.NewHostDefaults
+	defaultsMu      sync.RWMutex
+
+	// --- For host group persistence ---
+	hostGroupsConfigPath string
+	hostGroups           *HostGroupsConfig
+	groupMu              sync.Mutex
+
+	// --- For host notes persistence ---
+	hostNotesConfigPath string
+	hostNotes           map[string]HostNotes
+	notesMu             sync.Mutex
+
+	// --- For host color persistence ---
+	hostColorsConfigPath string
+	hostColors           HostColorsConfig
+	colorsMu             sync.Mutex
+
+	// --- For keyboard-interactive (2FA/OTP) prompts forwarded to the frontend ---
+	challenges  map[string]*pendingChallenge
+	challengeMu sync.Mutex
+
+	// Guards against overlapping VerifyAllSavedTunnels runs
+	preflightRunning atomic.Bool
+
+	// Guards against overlapping RunConnectivityReport runs; the last report is kept for export
+	reportRunning atomic.Bool
+	lastReport    *ConnectivityReport
+	reportMu      sync.Mutex
+
+	// Tunnels of temporary hosts, kept in memory only (guarded by configMu)
+	sessionTunnels []sshtunnel.SavedTunnelConfig
+
+	// Copy/re-key keychain passwords when tunnels are duplicated or change host source, from the app settings
+	migrateCredentials atomic.Bool
+
+	// Optional localhost API for external tooling, controlled by settings
+	localAPI *localAPI
+
+	// Read-only hosts shared by the team, refreshed periodically according to settings
+	teamConfig *teamConfig
+
+	// Watches interfaces and the default route; keep-alives and dial retries pause while offline
+	network *sshmanager.NetworkMonitor
+
+	// Listening ports found by the last DetectListeningPorts call per host, used to pick the forward target
+	detectedPorts   map[string][]DetectedPort
+	detectedPortsMu sync.Mutex
+
+	// Terminal sessions, login commands and sync configs live in other services; set once by the app before Startup
+	terminalSessions   func(alias string) []HostDependency
+	renameLoginCommand func(oldAlias, newAlias string) error
+	deleteLoginCommand func(alias string) error
+	syncConfigs        func() []types.SSHConfig
+
+	// Directory of the per-tunnel log files; set once by the app before Startup
+	tunnelLogDir string
 }
 
 // NewService 是 SSHGate 服务的构造函数
@@ -54,8 +128,17 @@ func NewService(sshMgr *sshmanager.Manager) *Service {
 		sshManager:                   sshMgr,
 		tunnelManager:                tunnelMgr,
 		tunnelsConfig:                &TunnelsConfig{Tunnels: []sshtunnel.SavedTunnelConfig{}},
+		hostGroups:                   &HostGroupsConfig{Groups: []HostGroup{}},
+		hostNotes:                    make(map[string]HostNotes),
+		hostColors:                   HostColorsConfig{Rules: defaultColorRules(), Hosts: map[string]string{}},
+		challenges:                   make(map[string]*pendingChallenge),
+		detectedPorts:                make(map[string][]DetectedPort),
 		savedTunnelsDebounceDuration: 200 * time.Millisecond,
 	}
+	s.localAPI = newLocalAPI(s)
+	s.teamConfig = newTeamConfig(s)
+	s.network = sshmanager.NewNetworkMonitor(s.onNetworkChange)
+	s.migrateCredentials.Store(true)
 	return s
 }
 
@@ -63,29 +146,84 @@ func NewService(sshMgr *sshmanager.Manager) *Service {
 func (s *Service) Startup(ctx context.Context) error {
 	s.ctx = ctx
 
-	// Load tunnel configurations at startup.
-	if err := s.loadTunnelsConfig(); err != nil {
-		log.Printf("Warning: could not load tunnel configurations: %v", err)
-		// We don't return the error, as the app can still function without saved tunnels.
-	}
+	// Load the tunnels, policies, groups, notes and colors of the active workspace.
+	s.loadWorkspaceConfigs()
 
-	return s.tunnelManager.Startup(ctx)
+	// Forward keyboard-interactive prompts (2FA/OTP) of tunnels, terminals and verification to the frontend.
+	s.sshManager.SetKeyboardInteractiveHandler(s.promptKeyboardInteractive)
+
+	if err := s.tunnelManager.Startup(ctx); err != nil {
+		return err
+	}
+	s.localAPI.setReady()
+	s.teamConfig.setReady()
+	s.network.Start()
+	return nil
 }
 
 func (s *Service) Shutdown() {
+	s.network.Stop()
+	s.localAPI.stop()
+	s.teamConfig.stop()
+	s.cancelAllAuthChallenges()
 	s.tunnelManager.Shutdown()
 }
 
+// SetTunnelDisconnectHandler sets the callback run when a tunnel's SSH connection is lost
+// unexpectedly, e.g. to send a desktop notification.
+func (s *Service) SetTunnelDisconnectHandler(fn func(tunnelID, alias, message string)) {
+	s.tunnelManager.SetDisconnectHandler(fn)
+}
+
+// loadWorkspaceConfigs loads every per-workspace config file. A file that fails to load is
+// logged and skipped, as the app can still function without it.
+func (s *Service) loadWorkspaceConfigs() {
+	// Load tunnel configurations.
+	if err := s.loadTunnelsConfig(); err != nil {
+		logger.Printf("Warning: could not load tunnel configurations: %v", err)
+		// We don't return the error, as the app can still function without saved tunnels.
+	}
+
+	// Load connection policies; the built-in defaults are used if this fails.
+	if err := s.loadConnectionPolicies(); err != nil {
+		logger.Printf("Warning: could not load connection policies: %v", err)
+	}
+
+	// Load pre-connect commands; hosts are dialed directly if this fails.
+	if err := s.loadPreConnectCommands(); err != nil {
+		logger.Printf("Warning: could not load pre-connect commands: %v", err)
+	}
+
+	// Load host groups; hosts are shown ungrouped if this fails.
+	if err := s.loadHostGroups(); err != nil {
+		logger.Printf("Warning: could not load host groups: %v", err)
+	}
+
+	// Load host notes; hosts are shown without notes if this fails.
+	if err := s.loadHostNotes(); err != nil {
+		logger.Printf("Warning: could not load host notes: %v", err)
+	}
+
+	// Load color rules; the default rules are used if this fails.
+	if err := s.loadHostColors(); err != nil {
+		logger.Printf("Warning: could not load host colors: %v", err)
+	}
+}
+
 // / GetSSHHosts 调用 internal/sshconfig 的实现
 func (a *Service) GetSSHHosts() ([]types.SSHHost, error) {
 	// 直接调用内部管理器的方法
 	hosts, err := a.sshManager.GetSSHHosts()
 	if err != nil {
 		// 可以在这里添加应用层的日志记录
-		log.Printf("Service: Error getting SSH hosts: %v", err)
+		logger.Printf("Service: Error getting SSH hosts: %v", err)
 		return nil, err // 错误已经被内部封装过了
 	}
-	log.Printf("Service: Successfully retrieved %d SSH hosts.", len(hosts))
+	// Include 文件与团队共享的主机只读地追加在后面，个人配置中的同名主机优先
+	hosts, included := a.appendIncludedHosts(hosts)
+	hosts = a.appendTeamHosts(hosts, included)
+	a.markFavorites(hosts)
+	logger.Printf("Service: Successfully retrieved %d SSH hosts.", len(hosts))
 	return hosts, nil
 }
 
@@ -119,29 +257,49 @@ func validateAndSanitizeHost(host *types.SSHHost) error {
 	return nil
 }
 
-// SaveSSHHost 保存（新增或更新）一个 SSH 主机配置
-// originalAlias 是编辑前的主机别名。如果为空，则表示是新增主机。
-func (a *Service) SaveSSHHost(host types.SSHHost, originalAlias string) error {
+// SaveSSHHost 保存（新增或更新）一个 SSH 主机配置，返回实际新增、修改和删除的参数。
+// originalAlias 是编辑前的主机别名。如果为空，则表示是新增主机。空值的参数不会被写入。
+func (a *Service) SaveSSHHost(host types.SSHHost, originalAlias string) (*sshmanager.HostUpdateResult, error) {
+	isNewHost := originalAlias == ""
+	if !isNewHost && a.sshManager.IsTeamHost(originalAlias) {
+		return nil, fmt.Errorf("host '%s' comes from the shared team config and is read-only", originalAlias)
+	}
+	if !isNewHost && a.sshManager.IsIncludedHost(originalAlias) {
+		return nil, fmt.Errorf("host '%s' is defined in an Include file and must be edited there", originalAlias)
+	}
+
+	// New hosts get the configured defaults for the fields left empty.
+	var defaultParams map[string]string
+	if isNewHost {
+		host.User = strings.TrimSpace(host.User)
+		host.Port = strings.TrimSpace(host.Port)
+		host.IdentityFile = strings.TrimSpace(host.IdentityFile)
+		defaultParams = a.applyNewHostDefaults(&host)
+	}
+
 	if err := validateAndSanitizeHost(&host); err != nil {
-		return err
+		return nil, err
 	}
 
-	isNewHost := originalAlias == ""
 	isRename := !isNewHost && originalAlias != host.Alias
 
 	// --- Phase 1: Pre-flight checks and in-memory operations ---
 
 	// For both new hosts and renames, check if the target alias already exists.
 	if isNewHost || isRename {
-		if a.sshManager.HasHost(host.Alias) {
-			return fmt.Errorf("host with alias '%s' already exists", host.Alias)
+		if a.sshManager.HasHostExact(host.Alias) || a.sshManager.IsEphemeralHost(host.Alias) {
+			return nil, fmt.Errorf("host with alias '%s' already exists", host.Alias)
 		}
 	}
 
 	if isRename {
 		// Rename the host in memory. The change will be persisted by UpdateHost/AddHostWithParams.
 		if err := a.sshManager.RenameHost(originalAlias, host.Alias); err != nil {
-			return fmt.Errorf("failed to rename host from '%s' to '%s': %w", originalAlias, host.Alias, err)
+			return nil, fmt.Errorf("failed to rename host from '%s' to '%s': %w", originalAlias, host.Alias, err)
+		}
+		// Hosts that jump through the renamed host would otherwise silently break.
+		if refs := a.sshManager.RenameJumpReferences(originalAlias, host.Alias); len(refs) > 0 {
+			logger.Printf("Updating %d ProxyJump/ProxyCommand references from '%s' to '%s'.", len(refs), originalAlias, host.Alias)
 		}
 	}
 
@@ -151,6 +309,9 @@ func (a *Service) SaveSSHHost(host types.SSHHost, originalAlias string) error {
 	params["User"] = host.User
 	params["Port"] = host.Port
 	params["IdentityFile"] = host.IdentityFile
+	for key, value := range defaultParams {
+		params[key] = value
+	}
 
 	updateReq := sshmanager.HostUpdateRequest{
 		Name:   host.Alias, // Always use the new alias
@@ -159,47 +320,155 @@ func (a *Service) SaveSSHHost(host types.SSHHost, originalAlias string) error {
 
 	// --- Phase 2: Commit the primary change (to ~/.ssh/config) ---
 
+	var result *sshmanager.HostUpdateResult
 	var mainErr error
 	if isNewHost {
-		mainErr = a.sshManager.AddHostWithParams(updateReq)
+		result, mainErr = a.sshManager.AddHostWithParams(updateReq)
 	} else {
-		mainErr = a.sshManager.UpdateHost(updateReq)
+		result, mainErr = a.sshManager.UpdateHost(updateReq)
 	}
 
 	if mainErr != nil {
 		// If the main save operation fails, we should revert any in-memory changes
 		// to ensure consistency for the next operation.
-		log.Printf("SaveSSHHost failed, reloading ssh manager to discard in-memory changes: %v", mainErr)
+		logger.Printf("SaveSSHHost failed, reloading ssh manager to discard in-memory changes: %v", mainErr)
 		_ = a.sshManager.Reload() // Revert in-memory state. Error is ignored as we are already in an error state.
-		return mainErr
+		return nil, mainErr
 	}
 
 	// --- Phase 3: Commit side-effect changes (keychain, tunnels.json) ---
 	// These are performed only after the primary config has been successfully saved.
 	if isRename {
 		if err := a.sshManager.RenamePassword(originalAlias, host.Alias); err != nil {
-			log.Printf("Warning: failed to rename password in keychain from '%s' to '%s': %v", originalAlias, host.Alias, err)
+			logger.Printf("Warning: failed to rename password in keychain from '%s' to '%s': %v", originalAlias, host.Alias, err)
 		}
 		if err := a.updateTunnelsUsingAlias(originalAlias, host.Alias); err != nil {
-			log.Printf("Warning: failed to update saved tunnels from alias '%s' to '%s': %v", originalAlias, host.Alias, err)
+			logger.Printf("Warning: failed to update saved tunnels from alias '%s' to '%s': %v", originalAlias, host.Alias, err)
+		}
+		if err := a.renameHostConnectionPolicy(originalAlias, host.Alias); err != nil {
+			logger.Printf("Warning: failed to move connection policy from alias '%s' to '%s': %v", originalAlias, host.Alias, err)
+		}
+		if err := a.renameHostPreConnectCommand(originalAlias, host.Alias); err != nil {
+			logger.Printf("Warning: failed to move pre-connect command from alias '%s' to '%s': %v", originalAlias, host.Alias, err)
+		}
+		if err := a.renameHostInGroups(originalAlias, host.Alias); err != nil {
+			logger.Printf("Warning: failed to update host group from alias '%s' to '%s': %v", originalAlias, host.Alias, err)
+		}
+		if err := a.renameHostNotes(originalAlias, host.Alias); err != nil {
+			logger.Printf("Warning: failed to move host notes from alias '%s' to '%s': %v", originalAlias, host.Alias, err)
+		}
+		if err := a.renameHostColor(originalAlias, host.Alias); err != nil {
+			logger.Printf("Warning: failed to move host color from alias '%s' to '%s': %v", originalAlias, host.Alias, err)
+		}
+		if err := a.renameHostLoginCommand(originalAlias, host.Alias); err != nil {
+			logger.Printf("Warning: failed to move login command from alias '%s' to '%s': %v", originalAlias, host.Alias, err)
 		}
 	}
 
-	return nil
+	return result, nil
+}
+
+// HostRenamePreview lists what SaveSSHHost will update besides the Host line when a host is renamed,
+// so the frontend can ask for confirmation first.
+type HostRenamePreview struct {
+	References         []sshconfig.Reference `json:"references"`         // ProxyJump/ProxyCommand lines that will be rewritten
+	IncludedReferences []sshconfig.Reference `json:"includedReferences"` // references in Include files, which must be edited by hand
+	Tunnels            []string              `json:"tunnels"`            // names of saved tunnels that use the host
+	GroupName          string                `json:"groupName,omitempty"`
 }
 
-// DeleteSSHHost 删除一个 SSH 主机配置
+// PreviewHostRename returns the references to oldAlias that renaming it to newAlias will update.
+func (a *Service) PreviewHostRename(oldAlias, newAlias string) (*HostRenamePreview, error) {
+	if !a.sshManager.HasHost(oldAlias) {
+		return nil, fmt.Errorf("host '%s' not found", oldAlias)
+	}
+	if oldAlias != newAlias && a.sshManager.HasHostExact(newAlias) {
+		return nil, fmt.Errorf("host with alias '%s' already exists", newAlias)
+	}
+
+	refs, err := a.sshManager.WhereUsed("ProxyJump", oldAlias)
+	if err != nil {
+		return nil, err
+	}
+	preview := &HostRenamePreview{
+		References:         []sshconfig.Reference{},
+		IncludedReferences: []sshconfig.Reference{},
+		Tunnels:            []string{},
+	}
+	configPath := a.sshManager.ConfigPath()
+	for _, ref := range refs {
+		if ref.File == configPath {
+			preview.References = append(preview.References, ref)
+		} else {
+			preview.IncludedReferences = append(preview.IncludedReferences, ref)
+		}
+	}
+
+	a.configMu.RLock()
+	for _, tunnel := range a.tunnelsConfig.Tunnels {
+		if tunnel.HostSource == "ssh_config" && tunnel.HostAlias == oldAlias {
+			preview.Tunnels = append(preview.Tunnels, tunnel.Name)
+		}
+	}
+	a.configMu.RUnlock()
+
+	a.groupMu.Lock()
+	for _, g := range a.hostGroups.Groups {
+		if slices.Contains(g.Aliases, oldAlias) {
+			preview.GroupName = g.Name
+			break
+		}
+	}
+	a.groupMu.Unlock()
+
+	return preview, nil
+}
+
+// DeleteSSHHost 删除一个 SSH 主机配置。删除前可用 GetHostDependencies 列出会受影响的隧道、终端与同步配置
 func (a *Service) DeleteSSHHost(alias string) error {
+	// Temporary hosts only live in memory and have nothing to clean up in the ssh config.
+	if a.sshManager.IsEphemeralHost(alias) {
+		return a.RemoveEphemeralHost(alias)
+	}
+	if a.sshManager.IsTeamHost(alias) {
+		return fmt.Errorf("host '%s' comes from the shared team config and is read-only", alias)
+	}
+	if a.sshManager.IsIncludedHost(alias) {
+		return fmt.Errorf("host '%s' is defined in an Include file and must be edited there", alias)
+	}
+
 	// When deleting a host, we should also clean up any associated passwords.
 	// 1. Delete the password for the host alias itself.
 	if err := a.sshManager.DeletePassword(alias); err != nil {
 		// This is a non-critical error, so we only log it.
-		log.Printf("Warning: failed to delete password for alias %s: %v", alias, err)
+		logger.Printf("Warning: failed to delete password for alias %s: %v", alias, err)
 	}
 
 	// 2. Delete passwords for any tunnels that depend on this host alias.
 	if err := a.deletePasswordsForTunnelsUsingAlias(alias); err != nil {
-		log.Printf("Warning: failed to delete passwords for tunnels using alias %s: %v", alias, err)
+		logger.Printf("Warning: failed to delete passwords for tunnels using alias %s: %v", alias, err)
+	}
+
+	// 3. Drop the host's connection policy override, if any.
+	if err := a.DeleteHostConnectionPolicy(alias); err != nil {
+		logger.Printf("Warning: failed to delete connection policy for alias %s: %v", alias, err)
+	}
+	if err := a.DeleteHostPreConnectCommand(alias); err != nil {
+		logger.Printf("Warning: failed to delete pre-connect command for alias %s: %v", alias, err)
+	}
+
+	// 4. Remove the host from its group and unpin it.
+	if err := a.removeHostFromGroups(alias); err != nil {
+		logger.Printf("Warning: failed to remove alias %s from host groups and favorites: %v", alias, err)
+	}
+	if err := a.DeleteHostNotes(alias); err != nil {
+		logger.Printf("Warning: failed to delete notes for alias %s: %v", alias, err)
+	}
+	if err := a.SetHostColor(alias, ""); err != nil {
+		logger.Printf("Warning: failed to delete color for alias %s: %v", alias, err)
+	}
+	if err := a.deleteHostLoginCommand(alias); err != nil {
+		logger.Printf("Warning: failed to delete login command for alias %s: %v", alias, err)
 	}
 	return a.sshManager.DeleteHost(alias)
 }
@@ -209,14 +478,35 @@ func (a *Service) ReloadSSHHosts() error {
 	return a.sshManager.Reload()
 }
 
+// GetReadOnlyStatus 返回SSH配置是否只读（在设置中开启，或配置文件不可写）。
+// 只读时所有修改SSH配置的接口都返回 sshmanager.ErrReadOnly，前端应据此禁用编辑控件。
+func (a *Service) GetReadOnlyStatus() sshmanager.ReadOnlyStatus {
+	return a.sshManager.ReadOnlyStatus()
+}
+
 // GetSSHConfigFileContent 获取SSH配置文件的原始内容
 func (a *Service) GetSSHConfigFileContent() (string, error) {
 	return a.sshManager.GetRawContent()
 }
 
-// SaveSSHConfigFileContent 保存SSH配置文件的原始内容
-func (a *Service) SaveSSHConfigFileContent(content string) error {
-	return a.sshManager.SaveRawContent(content)
+// GetSSHConfigFileRaw 获取SSH配置文件的原始内容及其校验和，保存时把校验和传给 SaveSSHConfigFileContent
+func (a *Service) GetSSHConfigFileRaw() (*sshmanager.RawConfig, error) {
+	return a.sshManager.GetRawConfig()
+}
+
+// SaveSSHConfigFileContent 保存SSH配置文件的原始内容，并返回诊断：错误会阻止保存，警告只用于提示。
+// expectedChecksum 是加载内容时的校验和（GetSSHConfigFileRaw），必须提供。文件在加载之后被修改时不会保存，
+// 结果的 Saved 为 false，Conflict 中包含当前内容、校验和与差异；用户合并后以 Conflict 中的校验和重新保存。
+func (a *Service) SaveSSHConfigFileContent(content, expectedChecksum string) (*sshmanager.RawSaveResult, error) {
+	if expectedChecksum == "" {
+		return nil, fmt.Errorf("checksum of the loaded SSH config is required")
+	}
+	return a.sshManager.SaveRawContent(content, expectedChecksum)
+}
+
+// ValidateSSHConfigFileContent 返回配置文件内容的诊断，供编辑器在保存前标记问题
+func (a *Service) ValidateSSHConfigFileContent(content string) []sshconfig.Diagnostic {
+	return a.sshManager.ValidateContent(content)
 }
 
 // --- Tunnel Configuration Management ---
@@ -226,20 +516,16 @@ func (s *Service) loadTunnelsConfig() error {
 	s.configMu.Lock()
 	defer s.configMu.Unlock()
 
-	configDir, err := os.UserConfigDir()
+	appConfigDir, err := workspace.ConfigDir()
 	if err != nil {
-		return fmt.Errorf("failed to get user config directory: %w", err)
-	}
-	appConfigDir := filepath.Join(configDir, "DevTools") // Use your app's name
-	if err := os.MkdirAll(appConfigDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create app config directory: %w", err)
+		return err
 	}
 	s.tunnelsConfigPath = filepath.Join(appConfigDir, "tunnels.json")
 
 	data, err := os.ReadFile(s.tunnelsConfigPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			log.Println("Tunnels config file not found, will create a new one on save.")
+			logger.Println("Tunnels config file not found, will create a new one on save.")
 			s.tunnelsConfig = &TunnelsConfig{Tunnels: []sshtunnel.SavedTunnelConfig{}}
 			return nil
 		}
@@ -250,12 +536,13 @@ func (s *Service) loadTunnelsConfig() error {
 		return fmt.Errorf("failed to unmarshal tunnels config: %w", err)
 	}
 
-	log.Printf("Successfully loaded %d saved tunnel configurations.", len(s.tunnelsConfig.Tunnels))
+	logger.Printf("Successfully loaded %d saved tunnel configurations.", len(s.tunnelsConfig.Tunnels))
 	return nil
 }
 
 // saveTunnelsConfig saves the current tunnel configurations to the JSON file.
 func (s *Service) saveTunnelsConfig() error {
+	s.tunnelsConfig.SchemaVersion = migrate.TunnelsSchemaVersion
 	data, err := json.MarshalIndent(s.tunnelsConfig, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal tunnels config: %w", err)
@@ -265,11 +552,27 @@ func (s *Service) saveTunnelsConfig() error {
 		return fmt.Errorf("failed to write tunnels config file: %w", err)
 	}
 
-	log.Printf("Successfully saved %d tunnel configurations to %s.", len(s.tunnelsConfig.Tunnels), s.tunnelsConfigPath)
+	logger.Printf("Successfully saved %d tunnel configurations to %s.", len(s.tunnelsConfig.Tunnels), s.tunnelsConfigPath)
 	s.debounceSavedTunnelsChangeEvent()
 	return nil
 }
 
+// ApplySettings updates the event debounce durations, the tunnel drain timeout and slow latency threshold, credential migration, the local API, the new host defaults and the team config source from the app settings.
+func (s *Service) ApplySettings(cfg settings.Settings) {
+	s.localAPI.configure(cfg)
+	s.teamConfig.configure(cfg)
+	s.setNewHostDefaults(cfg.NewHostDefaults)
+	d := time.Duration(cfg.TunnelEventDebounceMs) * time.Millisecond
+	s.savedTunnelsEventMu.Lock()
+	s.savedTunnelsDebounceDuration = d
+	s.savedTunnelsEventMu.Unlock()
+	s.tunnelManager.SetEventDebounceDuration(d)
+	s.tunnelManager.SetDrainTimeout(time.Duration(cfg.TunnelDrainTimeoutSeconds) * time.Second)
+	s.tunnelManager.SetSlowLatencyThreshold(time.Duration(cfg.TunnelSlowLatencyMs) * time.Millisecond)
+	s.tunnelManager.SetFileLogging(s.tunnelLogDir, cfg.TunnelFileLogs, cfg.TunnelLogRetentionDays)
+	s.migrateCredentials.Store(cfg.MigrateTunnelCredentials)
+}
+
 // debounceSavedTunnelsChangeEvent schedules a "saved_tunnels_changed" event to be sent to the frontend.
 func (s *Service) debounceSavedTunnelsChangeEvent() {
 	s.savedTunnelsEventMu.Lock()
@@ -280,9 +583,9 @@ func (s *Service) debounceSavedTunnelsChangeEvent() {
 	}
 
 	s.savedTunnelsEventDebouncer = time.AfterFunc(s.savedTunnelsDebounceDuration, func() {
-		log.Println("Debouncer fired: emitting 'saved_tunnels_changed' event to frontend.")
+		logger.Println("Debouncer fired: emitting 'saved_tunnels_changed' event to frontend.")
 		// This runs in a new goroutine, so we wrap it for safety.
-		runtime.EventsEmit(s.ctx, "saved_tunnels_changed")
+		utils.EmitEvent(s.ctx, "saved_tunnels_changed")
 	})
 }
 
@@ -333,19 +636,73 @@ func (s *Service) GetSavedTunnels() ([]sshtunnel.SavedTunnelConfig, error) {
 }
 
 // SaveTunnelConfig saves (creates or updates) a tunnel configuration.
+// Invalid fields are reported as a *TunnelValidationError, see ValidateTunnelConfig.
 func (s *Service) SaveTunnelConfig(config sshtunnel.SavedTunnelConfig) error {
+	normalizeTunnelConfig(&config)
+	if config.HostSource == "ssh_config" && s.sshManager.IsEphemeralHost(config.HostAlias) {
+		return fmt.Errorf("host '%s' is temporary, persist it before saving tunnels for it", config.HostAlias)
+	}
+	if fields := s.validateTunnelConfig(config); hasTunnelErrors(fields) {
+		return &TunnelValidationError{Fields: fields}
+	}
+	if config.DNSForward != nil && config.DNSForward.Enabled {
+		if config.TunnelType != "dynamic" {
+			return fmt.Errorf("DNS forwarding is only available for dynamic tunnels")
+		}
+		if _, err := config.DNSForward.Normalize(config.LocalPort); err != nil {
+			return err
+		}
+	}
+	if config.SocksACL != nil {
+		if config.TunnelType != "dynamic" {
+			return fmt.Errorf("destination rules are only available for dynamic tunnels")
+		}
+		acl, err := config.SocksACL.Normalize()
+		if err != nil {
+			return err
+		}
+		config.SocksACL = &acl
+		if acl.IsEmpty() {
+			config.SocksACL = nil
+		}
+	}
+	if config.LoadBalance != nil && len(config.LoadBalance.Targets) > 0 {
+		if config.TunnelType != "local" {
+			return fmt.Errorf("multiple remote targets are only available for local tunnels")
+		}
+		primary := fmt.Sprintf("%s:%d", config.RemoteHost, config.RemotePort)
+		if _, err := config.LoadBalance.Normalize(primary); err != nil {
+			return err
+		}
+	}
+	if config.Hooks != nil {
+		hooks, err := config.Hooks.Normalize()
+		if err != nil {
+			return err
+		}
+		config.Hooks = &hooks
+		if hooks.IsEmpty() {
+			config.Hooks = nil
+		}
+	}
+	if err := s.writeBackConfigForward(&config); err != nil {
+		return err
+	}
+
 	s.configMu.Lock()
 	defer s.configMu.Unlock()
 
+	var previous *sshtunnel.SavedTunnelConfig
 	if config.ID == "" {
 		config.ID = uuid.NewString()
-		log.Printf("Assigning new ID to tunnel config: %s", config.ID)
+		logger.Printf("Assigning new ID to tunnel config: %s", config.ID)
 		// Prepend the new config to the slice so it appears at the top of the list.
 		s.tunnelsConfig.Tunnels = append([]sshtunnel.SavedTunnelConfig{config}, s.tunnelsConfig.Tunnels...)
 	} else {
 		found := false
 		for i, t := range s.tunnelsConfig.Tunnels {
 			if t.ID == config.ID {
+				previous = &t
 				s.tunnelsConfig.Tunnels[i] = config
 				found = true
 				break
@@ -358,7 +715,28 @@ func (s *Service) SaveTunnelConfig(config sshtunnel.SavedTunnelConfig) error {
 		}
 	}
 
-	return s.saveTunnelsConfig()
+	if err := s.saveTunnelsConfig(); err != nil {
+		return err
+	}
+	if previous != nil && s.migrateCredentials.Load() {
+		s.rekeyTunnelCredential(*previous, config)
+	}
+	// Destination rules take effect immediately on a running SOCKS tunnel.
+	if config.TunnelType == "dynamic" {
+		acl := sshtunnel.SocksACLConfig{}
+		if config.SocksACL != nil {
+			acl = *config.SocksACL
+		}
+		for _, active := range s.tunnelManager.GetActiveTunnels() {
+			if active.ConfigID != config.ID {
+				continue
+			}
+			if err := s.tunnelManager.SetSocksACL(active.ID, acl); err != nil {
+				logger.Printf("Warning: failed to apply destination rules to running tunnel %s: %v", active.ID, err)
+			}
+		}
+	}
+	return nil
 }
 
 // DeleteTunnelConfig deletes a tunnel configuration by its ID.
@@ -391,19 +769,26 @@ func (s *Service) DeleteTunnelConfig(id string) error {
 		// Also delete any saved password for this tunnel
 		if err := s.sshManager.DeletePassword(id); err != nil {
 			// Log as a warning, as the primary operation (deleting the config) succeeded.
-			log.Printf("Warning: could not delete password for tunnel ID %s: %v", id, err)
+			logger.Printf("Warning: could not delete password for tunnel ID %s: %v", id, err)
 		}
 
-		log.Printf("Deleted tunnel config with ID: %s", id)
+		logger.Printf("Deleted tunnel config with ID: %s", id)
 		return s.saveTunnelsConfig()
 	}
 
-	log.Printf("Could not delete tunnel config: ID %s not found.", id)
+	logger.Printf("Could not delete tunnel config: ID %s not found.", id)
 	return fmt.Errorf("tunnel config with ID %s not found", id)
 }
 
-// DuplicateTunnelConfig creates a copy of an existing tunnel configuration.
+// DuplicateTunnelConfig creates a copy of an existing tunnel configuration. The saved password is
+// copied to the new tunnel when credential migration is enabled in the settings.
 func (s *Service) DuplicateTunnelConfig(id string) (*sshtunnel.SavedTunnelConfig, error) {
+	return s.DuplicateTunnelConfigWithCredential(id, s.migrateCredentials.Load())
+}
+
+// DuplicateTunnelConfigWithCredential creates a copy of an existing tunnel configuration;
+// copyCredential is the user's choice whether the saved password is copied too.
+func (s *Service) DuplicateTunnelConfigWithCredential(id string, copyCredential bool) (*sshtunnel.SavedTunnelConfig, error) {
 	s.configMu.Lock()
 	defer s.configMu.Unlock()
 
@@ -425,15 +810,43 @@ func (s *Service) DuplicateTunnelConfig(id string) (*sshtunnel.SavedTunnelConfig
 		newManualHost := *originalConfig.ManualHost
 		newConfig.ManualHost = &newManualHost
 	}
+	if originalConfig.LoadBalance != nil {
+		newLoadBalance := *originalConfig.LoadBalance
+		newLoadBalance.Targets = append([]string(nil), originalConfig.LoadBalance.Targets...)
+		newConfig.LoadBalance = &newLoadBalance
+	}
+	if originalConfig.SocksACL != nil {
+		newACL := sshtunnel.SocksACLConfig{
+			Allow: append([]string(nil), originalConfig.SocksACL.Allow...),
+			Deny:  append([]string(nil), originalConfig.SocksACL.Deny...),
+		}
+		newConfig.SocksACL = &newACL
+	}
+	if originalConfig.Hooks != nil {
+		newHooks := *originalConfig.Hooks
+		newConfig.Hooks = &newHooks
+	}
+	// Only the original stays linked, two tunnels must not rewrite the same ssh_config line
+	newConfig.ConfigForward = nil
 
 	// Assign a new ID and a new name
 	newConfig.ID = uuid.NewString()
 	newConfig.Name = fmt.Sprintf("%s (copy)", originalConfig.Name)
+	// The copy forwards the same as the original until the user edits it, only other problems block duplicating
+	if fields := s.validateTunnelConfig_nolock(newConfig, originalConfig); hasTunnelErrors(fields) {
+		return nil, &TunnelValidationError{Fields: fields}
+	}
 
 	// Prepend the new config to the list so it appears at the top.
 	s.tunnelsConfig.Tunnels = append([]sshtunnel.SavedTunnelConfig{newConfig}, s.tunnelsConfig.Tunnels...)
 
-	return &newConfig, s.saveTunnelsConfig()
+	if err := s.saveTunnelsConfig(); err != nil {
+		return nil, err
+	}
+	if copyCredential {
+		s.copyTunnelCredential(id, newConfig.ID)
+	}
+	return &newConfig, nil
 }
 
 // UpdateTunnelsOrder saves the new order of tunnels.
@@ -442,7 +855,7 @@ func (s *Service) UpdateTunnelsOrder(order []string) error {
 	defer s.configMu.Unlock()
 
 	s.tunnelsConfig.TunnelsOrder = order
-	log.Printf("Updating tunnels order. New order has %d items.", len(order))
+	logger.Printf("Updating tunnels order. New order has %d items.", len(order))
 
 	// We save the entire config, which now includes the new order.
 	// This will also trigger the 'saved_tunnels_changed' event, which is what we want,
@@ -450,6 +863,61 @@ func (s *Service) UpdateTunnelsOrder(order []string) error {
 	return s.saveTunnelsConfig()
 }
 
+// findTunnelConfig_nolock returns the saved or session tunnel configuration with the given ID, or nil.
+// The caller must hold s.configMu.
+func (s *Service) findTunnelConfig_nolock(configID string) *sshtunnel.SavedTunnelConfig {
+	for i := range s.tunnelsConfig.Tunnels {
+		if s.tunnelsConfig.Tunnels[i].ID == configID {
+			return &s.tunnelsConfig.Tunnels[i]
+		}
+	}
+	for i := range s.sessionTunnels {
+		if s.sessionTunnels[i].ID == configID {
+			return &s.sessionTunnels[i]
+		}
+	}
+	return nil
+}
+
+// ExportTunnelAsCommand returns the ssh command line that sets up the same forward as the saved tunnel,
+// e.g. "ssh -N -o ExitOnForwardFailure=yes -L 5432:db:5432 -J ops@10.0.0.1 deploy@10.0.1.5",
+// to document the tunnel or run it outside the app. Host aliases are expanded so the command
+// doesn't depend on the local ssh_config.
+func (s *Service) ExportTunnelAsCommand(configID string) (string, error) {
+	s.configMu.RLock()
+	found := s.findTunnelConfig_nolock(configID)
+	var savedConfig sshtunnel.SavedTunnelConfig
+	if found != nil {
+		savedConfig = *found
+	}
+	s.configMu.RUnlock()
+	if found == nil {
+		return "", i18n.Errorf("tunnel.config_not_found", configID)
+	}
+
+	var target *sshmanager.ConnectionConfig
+	switch savedConfig.HostSource {
+	case "ssh_config":
+		var err error
+		if target, err = s.sshManager.GetCommandConfig(savedConfig.HostAlias); err != nil {
+			return "", fmt.Errorf("failed to resolve host '%s': %s", savedConfig.HostAlias, err.Error())
+		}
+	case "manual":
+		if savedConfig.ManualHost == nil {
+			return "", i18n.Errorf("tunnel.manual_host_missing")
+		}
+		target = &sshmanager.ConnectionConfig{
+			HostName:     savedConfig.ManualHost.HostName,
+			Port:         savedConfig.ManualHost.Port,
+			User:         savedConfig.ManualHost.User,
+			IdentityFile: savedConfig.ManualHost.IdentityFile,
+		}
+	default:
+		return "", i18n.Errorf("tunnel.unknown_host_source")
+	}
+	return sshtunnel.BuildCommand(savedConfig, target)
+}
+
 // updateTunnelsUsingAlias updates saved tunnel configurations when a host alias is renamed.
 func (s *Service) updateTunnelsUsingAlias(oldAlias, newAlias string) error {
 	s.configMu.Lock()
@@ -464,7 +932,7 @@ func (s *Service) updateTunnelsUsingAlias(oldAlias, newAlias string) error {
 	}
 
 	if changed {
-		log.Printf("Updated alias from %s to %s in saved tunnel configurations.", oldAlias, newAlias)
+		logger.Printf("Updated alias from %s to %s in saved tunnel configurations.", oldAlias, newAlias)
 		// saveTunnelsConfig will also emit the 'saved_tunnels_changed' event,
 		// which will cause the frontend to refresh its list.
 		return s.saveTunnelsConfig()
@@ -483,7 +951,7 @@ func (s *Service) deletePasswordsForTunnelsUsingAlias(alias string) error {
 			// This tunnel uses the deleted alias, so we should delete its password.
 			if err := s.sshManager.DeletePassword(tunnel.ID); err != nil {
 				// Log and continue, don't stop the whole process for one failure.
-				log.Printf("Warning: failed to delete password for tunnel %s (using alias %s): %v", tunnel.ID, alias, err)
+				logger.Printf("Warning: failed to delete password for tunnel %s (using alias %s): %v", tunnel.ID, alias, err)
 			}
 		}
 	}
@@ -497,11 +965,48 @@ func (a *Service) StopForward(tunnelID string) error {
 	return a.tunnelManager.StopForward(tunnelID)
 }
 
+// ForceStopForward 立即停止隧道并关闭所有活动连接，不等待它们结束（也用于结束正在 draining 的隧道）
+func (a *Service) ForceStopForward(tunnelID string) error {
+	return a.tunnelManager.StopForwardWithTimeout(tunnelID, 0)
+}
+
 // GetActiveTunnels 获取当前活动的隧道列表
 func (a *Service) GetActiveTunnels() []sshtunnel.ActiveTunnelInfo {
 	return a.tunnelManager.GetActiveTunnels()
 }
 
+// GetTunnelConnectionLog 获取指定隧道最近的连接事件，用于排查转发问题
+func (a *Service) GetTunnelConnectionLog(tunnelID string) ([]sshtunnel.ConnectionEvent, error) {
+	return a.tunnelManager.GetTunnelConnectionLog(tunnelID)
+}
+
+// SetLogDir sets the app's log directory; with the tunnelFileLogs setting on, each tunnel writes
+// its lifecycle and connection events to tunnels/<configID>_<name>-<date>.log in it. Call before Startup.
+func (s *Service) SetLogDir(dir string) {
+	s.tunnelLogDir = filepath.Join(dir, sshtunnel.TunnelLogDirName)
+}
+
+// GetTunnelLogPath returns the most recent log file of the saved tunnel configID, so that
+// disconnects that happened while nobody was watching can be looked at afterwards.
+func (s *Service) GetTunnelLogPath(configID string) (string, error) {
+	s.configMu.RLock()
+	config := s.findTunnelConfig_nolock(configID)
+	var name string
+	if config != nil {
+		name = config.Name
+	}
+	s.configMu.RUnlock()
+	if config == nil {
+		return "", fmt.Errorf("tunnel configuration with ID %s not found", configID)
+	}
+	return s.tunnelManager.TunnelLogPath(name, configID)
+}
+
+// GetTunnelProcessUsage 获取使用指定隧道的本地进程及其连接数与流量
+func (a *Service) GetTunnelProcessUsage(tunnelID string) ([]sshtunnel.ProcessUsage, error) {
+	return a.tunnelManager.GetTunnelProcessUsage(tunnelID)
+}
+
 // SavePassword 将密码安全地存储到系统钥匙串中
 func (a *Service) SavePassword(key string, password string) error {
 	return a.sshManager.SavePassword(key, password)
@@ -517,13 +1022,7 @@ func (s *Service) StartTunnelFromConfig(configID string, password string) (strin
 	s.configMu.RLock()
 	defer s.configMu.RUnlock()
 
-	var savedConfig *sshtunnel.SavedTunnelConfig
-	for i := range s.tunnelsConfig.Tunnels {
-		if s.tunnelsConfig.Tunnels[i].ID == configID {
-			savedConfig = &s.tunnelsConfig.Tunnels[i]
-			break
-		}
-	}
+	savedConfig := s.findTunnelConfig_nolock(configID)
 
 	if savedConfig == nil {
 		return "", fmt.Errorf("tunnel configuration with ID %s not found", configID)
@@ -578,10 +1077,31 @@ func (s *Service) StartTunnelFromConfig(configID string, password string) (strin
 		return "", fmt.Errorf("unsupported tunnel type '%s'", savedConfig.TunnelType)
 	}
 
-	result, err := s.tunnelManager.CreateTunnelFromConfig(configID, aliasForDisplay, savedConfig.LocalPort, savedConfig.GatewayPorts, savedConfig.TunnelType, remoteAddr, connConfig)
+	result, err := s.tunnelManager.CreateTunnelFromConfig(configID, savedConfig.Name, aliasForDisplay, savedConfig.LocalPort, savedConfig.GatewayPorts, savedConfig.TunnelType, remoteAddr, connConfig, savedConfig.SocksACL, savedConfig.Hooks)
 	if err != nil {
 		return "", s.translateNetworkError(err, aliasForDisplay)
 	}
+
+	// Start the DNS forwarder next to the SOCKS port. A tunnel that was asked to prevent DNS
+	// leaks but can't do so is stopped rather than left running without the forwarder.
+	if savedConfig.TunnelType == "dynamic" && savedConfig.DNSForward != nil && savedConfig.DNSForward.Enabled {
+		if _, err := s.tunnelManager.StartDNSForwarder(result, *savedConfig.DNSForward); err != nil {
+			if stopErr := s.tunnelManager.StopForward(result); stopErr != nil {
+				logger.Printf("Warning: failed to stop tunnel %s after DNS forwarder error: %v", result, stopErr)
+			}
+			return "", fmt.Errorf("failed to start DNS forwarder: %w", s.translateNetworkError(err, aliasForDisplay))
+		}
+	}
+
+	// Balance connections across the extra remote targets, if any.
+	if savedConfig.TunnelType == "local" && savedConfig.LoadBalance != nil && len(savedConfig.LoadBalance.Targets) > 0 {
+		if err := s.tunnelManager.StartLoadBalancer(result, *savedConfig.LoadBalance); err != nil {
+			if stopErr := s.tunnelManager.StopForward(result); stopErr != nil {
+				logger.Printf("Warning: failed to stop tunnel %s after load balancer error: %v", result, stopErr)
+			}
+			return "", fmt.Errorf("failed to start load balancer: %s", err.Error())
+		}
+	}
 	return result, nil
 }
 
@@ -603,8 +1123,15 @@ func (s *Service) CreateAndStartTunnel(
 	var configIDToStart string
 	found := false
 
+	// Tunnels of temporary hosts are kept in memory only, like the hosts themselves.
+	ephemeral := s.sshManager.IsEphemeralHost(hostAlias)
+	candidates := s.tunnelsConfig.Tunnels
+	if ephemeral {
+		candidates = s.sessionTunnels
+	}
+
 	// --- Check for existing tunnel config ---
-	for _, t := range s.tunnelsConfig.Tunnels {
+	for _, t := range candidates {
 		if t.TunnelType == tunnelType && t.HostSource == "ssh_config" && t.HostAlias == hostAlias && t.LocalPort == localPort && t.GatewayPorts == gatewayPorts {
 			isMatch := false
 			switch tunnelType {
@@ -617,7 +1144,7 @@ func (s *Service) CreateAndStartTunnel(
 			}
 
 			if isMatch {
-				log.Printf("Found existing tunnel configuration with ID %s.", t.ID)
+				logger.Printf("Found existing tunnel configuration with ID %s.", t.ID)
 				configIDToStart = t.ID
 				found = true
 				break
@@ -626,7 +1153,7 @@ func (s *Service) CreateAndStartTunnel(
 	}
 
 	if !found {
-		log.Println("No existing tunnel configuration found. Creating a new one.")
+		logger.Println("No existing tunnel configuration found. Creating a new one.")
 		newConfig := sshtunnel.SavedTunnelConfig{
 			ID:           uuid.NewString(),
 			TunnelType:   tunnelType,
@@ -639,10 +1166,14 @@ func (s *Service) CreateAndStartTunnel(
 		}
 		newConfig.Name = generateTunnelName(&newConfig)
 
-		s.tunnelsConfig.Tunnels = append([]sshtunnel.SavedTunnelConfig{newConfig}, s.tunnelsConfig.Tunnels...)
-		if err := s.saveTunnelsConfig(); err != nil {
-			s.configMu.Unlock()
-			return "", fmt.Errorf("failed to auto-save new tunnel config: %w", err)
+		if ephemeral {
+			s.sessionTunnels = append(s.sessionTunnels, newConfig)
+		} else {
+			s.tunnelsConfig.Tunnels = append([]sshtunnel.SavedTunnelConfig{newConfig}, s.tunnelsConfig.Tunnels...)
+			if err := s.saveTunnelsConfig(); err != nil {
+				s.configMu.Unlock()
+				return "", fmt.Errorf("failed to auto-save new tunnel config: %w", err)
+			}
 		}
 		configIDToStart = newConfig.ID
 	}
@@ -668,13 +1199,7 @@ func generateTunnelName(config *sshtunnel.SavedTunnelConfig) string {
 // This is used when the user explicitly trusts a new host during a 'verify' connection flow.
 func (s *Service) TrustHostKeyForTunnel(configID string) error {
 	s.configMu.RLock()
-	var savedConfig *sshtunnel.SavedTunnelConfig
-	for i := range s.tunnelsConfig.Tunnels {
-		if s.tunnelsConfig.Tunnels[i].ID == configID {
-			savedConfig = &s.tunnelsConfig.Tunnels[i]
-			break
-		}
-	}
+	savedConfig := s.findTunnelConfig_nolock(configID)
 	s.configMu.RUnlock()
 
 	if savedConfig == nil {
@@ -705,33 +1230,27 @@ func (s *Service) TrustHostKeyForTunnel(configID string) error {
 		return fmt.Errorf("unknown host source '%s' for tunnel config %s", savedConfig.HostSource, configID)
 	}
 
-	remoteKey, err := s.sshManager.CaptureHostKey(hostToTrust)
+	captured, err := s.hostKeyToTrust(hostToTrust)
 	if err != nil {
 		return fmt.Errorf("failed to capture remote host key: %w", err)
 	}
-	if err := s.sshManager.AddHostKeyToKnownHosts(hostToTrust, remoteKey); err != nil {
+	if err := s.sshManager.AddHostKeyToKnownHosts(hostToTrust, captured.Key); err != nil {
 		// This should be a critical error in this context.
 		return fmt.Errorf("failed to add host key to known_hosts: %w", err)
 	}
 
-	log.Printf("Successfully added host key for tunnel '%s' to known_hosts.", savedConfig.Name)
+	logger.Printf("Successfully added host key for tunnel '%s' to known_hosts.", savedConfig.Name)
 	return nil
 }
 
 // VerifyTunnelConfigConnection performs a pre-flight check for a saved tunnel configuration.
 func (s *Service) VerifyTunnelConfigConnection(configID string, password string) (*types.ConnectionResult, error) {
 	s.configMu.RLock()
-	var savedConfig *sshtunnel.SavedTunnelConfig
-	for i := range s.tunnelsConfig.Tunnels {
-		if s.tunnelsConfig.Tunnels[i].ID == configID {
-			savedConfig = &s.tunnelsConfig.Tunnels[i]
-			break
-		}
-	}
+	savedConfig := s.findTunnelConfig_nolock(configID)
 	s.configMu.RUnlock()
 
 	if savedConfig == nil {
-		return &types.ConnectionResult{Success: false, ErrorMessage: fmt.Sprintf("tunnel configuration with ID %s not found", configID)}, nil
+		return &types.ConnectionResult{Success: false, ErrorMessage: i18n.T("tunnel.config_not_found", configID)}, nil
 	}
 
 	var hostToVerify *types.SSHHost
@@ -748,7 +1267,7 @@ func (s *Service) VerifyTunnelConfigConnection(configID string, password string)
 		}
 	case "manual":
 		if savedConfig.ManualHost == nil {
-			return &types.ConnectionResult{Success: false, ErrorMessage: "manual host info is missing"}, nil
+			return &types.ConnectionResult{Success: false, ErrorMessage: i18n.T("tunnel.manual_host_missing")}, nil
 		}
 		aliasForDisplay = savedConfig.Name // Use tunnel name as alias for context
 		hostToVerify = &types.SSHHost{
@@ -759,7 +1278,7 @@ func (s *Service) VerifyTunnelConfigConnection(configID string, password string)
 			IdentityFile: savedConfig.ManualHost.IdentityFile,
 		}
 	default:
-		return &types.ConnectionResult{Success: false, ErrorMessage: "unknown host source"}, nil
+		return &types.ConnectionResult{Success: false, ErrorMessage: i18n.T("tunnel.unknown_host_source")}, nil
 	}
 
 	// Replicate the core logic of sshmanager.VerifyConnection but with a constructed host object.
@@ -768,8 +1287,7 @@ func (s *Service) VerifyTunnelConfigConnection(configID string, password string)
 		return s.handleSSHConnectError(aliasForDisplay, hostToVerify, err)
 	}
 
-	serverAddr := fmt.Sprintf("%s:%s", connConfig.HostName, connConfig.Port)
-	client, err := ssh.Dial("tcp", serverAddr, connConfig.ClientConfig)
+	client, err := sshmanager.Dial(connConfig)
 	if err != nil {
 		return s.handleSSHConnectError(aliasForDisplay, hostToVerify, err)
 	}
@@ -780,117 +1298,80 @@ func (s *Service) VerifyTunnelConfigConnection(configID string, password string)
 
 // -----ssh连接-------------------------------------------------
 
-// translateSyscallError is a platform-specific helper to translate syscall errors.
-// It is defined in sshgate_unix.go and sshgate_windows.go.
-// func translateSyscallError(syscallErr *os.SyscallError, hostIdentifier string) error
-
 // translateNetworkError converts raw network or SSH errors into user-friendly,
-// IPC-safe error messages. It's crucial for providing clear feedback to the frontend
-// and avoiding serialization issues with complex Go error types.
+// IPC-safe errors. The classification itself lives in sshmanager so that every
+// service reports the same kind for the same failure; the returned
+// *sshmanager.ConnectError carries that kind across the Wails bridge.
 func (s *Service) translateNetworkError(err error, hostIdentifier string) error {
 	if err == nil {
 		return nil
 	}
-
-	// First, check for specific structured errors if they are passed up.
-	var passwordRequiredError *types.PasswordRequiredError
-	if errors.As(err, &passwordRequiredError) {
-		return fmt.Errorf("password is required for '%s'", hostIdentifier)
-	}
-
-	// Now, dissect generic network errors.
-	var opErr *net.OpError
-	if errors.As(err, &opErr) {
-		if opErr.Timeout() {
-			return fmt.Errorf("connection to '%s' timed out, the server may be offline or firewalled", hostIdentifier)
-		}
-
-		var dnsErr *net.DNSError
-		if errors.As(opErr.Err, &dnsErr) {
-			return fmt.Errorf("could not resolve hostname for '%s': %s, check the hostname and your DNS settings", hostIdentifier, dnsErr.Name)
-		}
-
-		var syscallErr *os.SyscallError
-		if errors.As(opErr.Err, &syscallErr) {
-			// Delegate to platform-specific implementation.
-			if translatedErr := translateSyscallError(syscallErr, hostIdentifier); translatedErr != nil {
-				return translatedErr
-			}
-		}
-	}
-
-	// Check for common error strings from SSH and net libraries.
-	errMsg := strings.ToLower(err.Error())
-	if strings.Contains(errMsg, "address already in use") {
-		return fmt.Errorf("the local port is already in use, please choose another port")
-	}
-	if strings.Contains(errMsg, "unable to authenticate") || strings.Contains(errMsg, "permission denied") || strings.Contains(errMsg, "authentication failed") {
-		return fmt.Errorf("authentication failed for '%s', please check your password or SSH key", hostIdentifier)
-	}
-
-	// Fallback for any other error.
-	return fmt.Errorf("an unexpected error occurred for '%s': %v", hostIdentifier, err)
+	return sshmanager.NewConnectError(err, hostIdentifier)
 }
 
 // 辅助函数，用于处理“预检”阶段的错误
 func (a *Service) handleSSHConnectError(alias string, host *types.SSHHost, err error) (*types.ConnectionResult, error) {
-	var hostNotFoundError *sshconfig.HostNotFoundError
+	kind := sshmanager.Classify(err)
+	result := &types.ConnectionResult{Success: false, ErrorKind: string(kind)}
+
 	var passwordRequiredError *types.PasswordRequiredError
 	var authFailedError *types.AuthenticationFailedError
-	var keyErr *knownhosts.KeyError
 
-	// Check for specific error strings first, as they are more reliable for generic errors from the ssh library.
-	if err != nil {
-		errMsg := err.Error()
-		// "unable to authenticate" is a common message for wrong password/key.
-		// "permission denied" can also indicate auth failure.
-		if strings.Contains(errMsg, "unable to authenticate") || strings.Contains(errMsg, "permission denied") {
-			log.Printf("Connection check for '%s' failed with auth error: %v. Re-prompting for password.", alias, err)
-			return &types.ConnectionResult{Success: false, PasswordRequired: &types.PasswordRequiredError{Alias: alias, Message: "Authentication failed. Please try again."}}, nil
-		}
-	}
-
-	switch {
-	case errors.As(err, &hostNotFoundError):
-		log.Printf("Connection check for '%s' failed: Host not found.", alias)
-		return &types.ConnectionResult{Success: false, ErrorMessage: "Host not found"}, nil
-	case errors.As(err, &passwordRequiredError):
-		// 检查是否是需要密码的错误
-		log.Printf("Connection check for '%s' failed: Password required.", alias)
-		return &types.ConnectionResult{Success: false, PasswordRequired: passwordRequiredError}, nil
-	case errors.As(err, &authFailedError):
-		log.Printf("Connection check for '%s' failed: Authentication failed.", alias)
-		// 我们将这个错误也包装在 PasswordRequired 字段里，
-		// 前端可以通过检查 Error() 字符串来区分
-		return &types.ConnectionResult{Success: false, PasswordRequired: &types.PasswordRequiredError{Alias: alias}}, nil
-	case errors.As(err, &keyErr):
+	switch kind {
+	case sshmanager.KindHostNotFound:
+		logger.Printf("Connection check for '%s' failed: Host not found.", alias)
+		result.ErrorMessage = i18n.T("ssh.host_not_found")
+	case sshmanager.KindPasswordRequired, sshmanager.KindPassphraseRequired:
+		// 检查是否是需要密码的错误（私钥需要密码短语时也允许用户改用服务器密码）
+		logger.Printf("Connection check for '%s' failed: Password required (%s).", alias, kind)
+		if !errors.As(err, &passwordRequiredError) {
+			passwordRequiredError = &types.PasswordRequiredError{Alias: alias}
+		}
+		result.PasswordRequired = passwordRequiredError
+	case sshmanager.KindAuthFailed:
+		if errors.As(err, &authFailedError) {
+			logger.Printf("Connection check for '%s' failed: Authentication failed.", alias)
+			// 我们将这个错误也包装在 PasswordRequired 字段里，
+			// 前端可以通过 ErrorKind 来区分
+			result.PasswordRequired = &types.PasswordRequiredError{Alias: alias}
+		} else {
+			// "unable to authenticate"、"permission denied" 等来自 ssh 库的认证失败，提示用户重新输入密码
+			logger.Printf("Connection check for '%s' failed with auth error: %v. Re-prompting for password.", alias, err)
+			result.PasswordRequired = &types.PasswordRequiredError{Alias: alias, Message: i18n.T("ssh.auth_retry")}
+		}
+	case sshmanager.KindHostKeyUnknown, sshmanager.KindHostKeyMismatch:
 		// 检查是否是主机密钥验证错误
-		log.Printf("Host key error for %s, attempting to capture new key...", alias)
-		remoteKey, captureErr := a.sshManager.CaptureHostKey(host)
+		if a.sshManager.HostKeyPolicy() == settings.HostKeyPolicyStrict {
+			logger.Printf("Host key for %s is not trusted and host key policy is strict.", alias)
+			result.ErrorMessage = i18n.T("ssh.host_key_strict", alias)
+			return result, nil
+		}
+		logger.Printf("Host key error for %s, attempting to capture new key...", alias)
+		captured, captureErr := a.sshManager.CaptureHostKey(host)
 		if captureErr != nil {
-			return &types.ConnectionResult{Success: false, ErrorMessage: "Failed to capture remote host key"}, nil
-		}
-		hostAddress := fmt.Sprintf("%s:%s", host.HostName, host.Port)
-
-		return &types.ConnectionResult{
-			Success: false,
-			HostKeyVerificationRequired: &types.HostKeyVerificationRequiredError{
-				Alias:       alias,
-				Fingerprint: ssh.FingerprintSHA256(remoteKey),
-				HostAddress: hostAddress,
-			},
-		}, nil
+			logger.Printf("Failed to capture host key for %s: %v", alias, captureErr)
+			result.ErrorMessage = i18n.T("ssh.host_key_capture_failed")
+			return result, nil
+		}
+		result.HostKeyVerificationRequired = &types.HostKeyVerificationRequiredError{
+			Alias:           alias,
+			Fingerprint:     captured.Fingerprint,
+			HostAddress:     net.JoinHostPort(host.HostName, host.Port),
+			KeyType:         captured.KeyType,
+			ResolvedAddress: captured.Address,
+		}
 	default:
 		// For other generic network errors, translate them into a user-friendly message.
 		translatedErr := a.translateNetworkError(err, alias)
-		log.Printf("Error during connection pre-flight check for '%s': %v", alias, err)
-		return &types.ConnectionResult{Success: false, ErrorMessage: translatedErr.Error()}, nil
+		logger.Printf("Error during connection pre-flight check for '%s': %v", alias, err)
+		result.ErrorMessage = translatedErr.Error()
 	}
+	return result, nil
 }
 
 // ConnectInTerminal 尝试无密码连接
 func (a *Service) ConnectInTerminal(alias string, dryRun bool) (*types.ConnectionResult, error) {
-	log.Printf("Attempting connection for '%s'", alias)
+	logger.Printf("Attempting connection for '%s'", alias)
 	// 执行“预检”
 	host, err := a.sshManager.VerifyConnection(alias, "") // password 为空
 	if err != nil {
@@ -898,7 +1379,7 @@ func (a *Service) ConnectInTerminal(alias string, dryRun bool) (*types.Connectio
 		return a.handleSSHConnectError(alias, host, err)
 	}
 	// 预检通过，执行连接
-	log.Printf("Pre-flight check for '%s' passed. Launching terminal.", alias)
+	logger.Printf("Pre-flight check for '%s' passed. Launching terminal.", alias)
 	// 对于调用第三方ssh终端的，密码是没办法作为 ssh 的参数传递的。只能由用户在ssh终端中输入密码。对于秘钥验证的可以免密登录成功
 	// 所以此处不传递 host，只需要传递 alias 就可以
 	if err := a.sshManager.ConnectInTerminal(alias, dryRun); err != nil {
@@ -908,9 +1389,38 @@ func (a *Service) ConnectInTerminal(alias string, dryRun bool) (*types.Connectio
 	return &types.ConnectionResult{Success: true}, nil
 }
 
+// GetControlMasterStatus 返回主机的 OpenSSH 连接复用状态（是否已有可复用的主连接）
+func (a *Service) GetControlMasterStatus(alias string) sshmanager.ControlMasterStatus {
+	return a.sshManager.ControlMasterStatus(alias)
+}
+
+// GetControlMasterStatuses 返回所有配置了 ControlPath 的主机的连接复用状态
+func (a *Service) GetControlMasterStatuses() ([]sshmanager.ControlMasterStatus, error) {
+	hosts, err := a.sshManager.GetSSHHosts()
+	if err != nil {
+		return nil, err
+	}
+	statuses := []sshmanager.ControlMasterStatus{}
+	for _, host := range hosts {
+		if status := a.sshManager.ControlMasterStatus(host.Alias); status.ControlPath != "" {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// ConnectInTerminalViaControlMaster 复用已有的主连接（ssh -S）打开终端，跳过预检与认证，实现即时登录
+func (a *Service) ConnectInTerminalViaControlMaster(alias string) (*types.ConnectionResult, error) {
+	logger.Printf("Attempting connection for '%s' via existing ControlMaster", alias)
+	if err := a.sshManager.ConnectInTerminalViaControlMaster(alias); err != nil {
+		return &types.ConnectionResult{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return &types.ConnectionResult{Success: true}, nil
+}
+
 // ConnectInTerminalWithPassword 接收密码进行连接
 func (a *Service) ConnectInTerminalWithPassword(alias string, password string, savePassword bool, dryRun bool) (*types.ConnectionResult, error) {
-	log.Printf("Attempting connection for '%s' with provided password", alias)
+	logger.Printf("Attempting connection for '%s' with provided password", alias)
 	// 预检：使用用户提供的密码
 	host, err := a.sshManager.VerifyConnection(alias, password)
 	if err != nil {
@@ -918,12 +1428,15 @@ func (a *Service) ConnectInTerminalWithPassword(alias string, password string, s
 	}
 
 	// 预检通过，执行连接
-	log.Printf("Credentials for '%s' are valid. Launching terminal.", alias)
+	logger.Printf("Credentials for '%s' are valid. Launching terminal.", alias)
 	// 只有在连接预检成功后，我们才保存密码，避免保存错误密码
-	if savePassword && password != "" {
-		log.Printf("Saving password to keychain for key '%s'", alias)
+	if savePassword && password != "" && a.sshManager.IsEphemeralHost(alias) {
+		// Temporary hosts must not leave anything behind once the app exits.
+		logger.Printf("Not saving password for temporary host '%s'.", alias)
+	} else if savePassword && password != "" {
+		logger.Printf("Saving password to keychain for key '%s'", alias)
 		if err := a.sshManager.SavePassword(alias, password); err != nil {
-			log.Printf("Warning: failed to save password for key '%s': %v", alias, err)
+			logger.Printf("Warning: failed to save password for key '%s': %v", alias, err)
 		}
 	}
 	if err := a.sshManager.ConnectInTerminal(alias, dryRun); err != nil {
@@ -934,30 +1447,123 @@ func (a *Service) ConnectInTerminalWithPassword(alias string, password string, s
 
 // ConnectInTerminalAndTrustHost 用户确认后，接受主机指纹并连接
 func (a *Service) ConnectInTerminalAndTrustHost(alias string, password string, savePassword bool, dryRun bool) (*types.ConnectionResult, error) {
-	log.Printf("User trusted host key for '%s'. Adding to known_hosts.", alias)
+	logger.Printf("User trusted host key for '%s'. Adding to known_hosts.", alias)
 	// 先将新的主机密钥添加到 known_hosts 文件
 	host, err := a.sshManager.GetSSHHostByAlias(alias)
 	if err != nil {
 		return &types.ConnectionResult{Success: false, ErrorMessage: err.Error()}, nil
 	}
-	remoteKey, err := a.sshManager.CaptureHostKey(host)
+	captured, err := a.hostKeyToTrust(host)
 	if err != nil {
 		return &types.ConnectionResult{Success: false, ErrorMessage: err.Error()}, nil
 	}
-	if err := a.sshManager.AddHostKeyToKnownHosts(host, remoteKey); err != nil {
+	if err := a.sshManager.AddHostKeyToKnownHosts(host, captured.Key); err != nil {
 		// 这是一个非致命错误，我们只记录警告，然后继续尝试连接
-		log.Printf("Warning: failed to add host key to known_hosts: %v", err)
+		logger.Printf("Warning: failed to add host key to known_hosts: %v", err)
 	}
 
 	// 信任后，再次尝试连接，但这次可能还需要密码
 	// 我们直接调用 ConnectInTerminalWithPassword，如果 password 为空，
 	// 它会自动尝试密钥或钥匙串，完美地处理了所有情况。
-	log.Printf("Host key for '%s' added. Re-attempting connection.", alias)
+	logger.Printf("Host key for '%s' added. Re-attempting connection.", alias)
 	return a.ConnectInTerminalWithPassword(alias, password, savePassword, dryRun)
 }
 
+// hostKeyToTrust returns the host key the user was just shown for host, capturing it again only
+// when that result has expired. Reusing it avoids a second handshake and guarantees that the key
+// written to known_hosts is the one whose fingerprint was confirmed.
+func (a *Service) hostKeyToTrust(host *types.SSHHost) (*sshmanager.CapturedHostKey, error) {
+	if captured := a.sshManager.RecentHostKey(host); captured != nil {
+		return captured, nil
+	}
+	return a.sshManager.CaptureHostKey(host)
+}
+
+// HashKnownHosts rewrites the plaintext host names in ~/.ssh/known_hosts in hashed form, like
+// "ssh-keygen -H", so the file no longer reveals which hosts the user connects to. It returns the
+// number of host names that were hashed; the original file is kept as known_hosts.old.
+func (s *Service) HashKnownHosts() (int, error) {
+	return s.sshManager.HashKnownHosts()
+}
+
 // UpdateHostsOrder saves the new order of hosts from the visual editor.
 func (s *Service) UpdateHostsOrder(orderedAliases []string) error {
 	// 调用 sshmanager 中实现的排序方法
 	return s.sshManager.ReorderHosts(orderedAliases)
 }
+
+// SortModeGroup sorts hosts by their host group in display order, in addition to the
+// sshconfig.SortByAlias and sshconfig.SortByTag modes.
+const SortModeGroup = "group"
+
+// SortSSHHosts tidies up ssh_config by sorting the Host blocks alphabetically, by their Tag
+// parameter, or by host group. Comments move with their block, and Host *, Match and Include
+// blocks stay where they are so the effective settings of every host are unchanged.
+func (s *Service) SortSSHHosts(mode string) error {
+	if mode != SortModeGroup {
+		return s.sshManager.SortHosts(mode)
+	}
+
+	// Group keys are zero-padded display positions, so groups keep the order of the host list.
+	groupOf := make(map[string]string)
+	s.groupMu.Lock()
+	for i, g := range s.hostGroups.Groups {
+		for _, alias := range g.Aliases {
+			groupOf[alias] = fmt.Sprintf("%04d", i)
+		}
+	}
+	s.groupMu.Unlock()
+
+	return s.sshManager.SortHostsBy(func(aliases []string) string {
+		for _, alias := range aliases {
+			if key, ok := groupOf[alias]; ok {
+				return key
+			}
+		}
+		return ""
+	})
+}
+
+// MergeSSHHosts combines several Host blocks that point at the same machine into one block
+// with multiple aliases. Parameters with different values are reported as conflicts and the
+// target's value is kept.
+func (s *Service) MergeSSHHosts(targetAlias string, sourceAliases []string) (*sshconfig.MergeResult, error) {
+	result, err := s.sshManager.MergeHosts(targetAlias, sourceAliases)
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Merged %v into host '%s' (%d params added, %d conflicts).", sourceAliases, targetAlias, len(result.Added), len(result.Conflicts))
+	return result, nil
+}
+
+// DuplicateSSHHost creates a copy of an existing host under newAlias, with overrides such as
+// HostName or Port applied (an empty value removes the parameter), and returns the new host.
+func (s *Service) DuplicateSSHHost(sourceAlias, newAlias string, overrides map[string]string) (*types.SSHHost, error) {
+	newAlias = strings.TrimSpace(newAlias)
+	if err := s.sshManager.DuplicateHost(sourceAlias, newAlias, overrides); err != nil {
+		return nil, err
+	}
+	logger.Printf("Duplicated host '%s' as '%s' with %d overrides.", sourceAlias, newAlias, len(overrides))
+	return s.sshManager.GetSSHHostByAlias(newAlias)
+}
+
+// FindSSHConfigReferences lists every host that references an identity file (paramKey "IdentityFile")
+// or a jump host (paramKey "ProxyJump"), so the user can see what breaks before deleting or renaming it.
+func (s *Service) FindSSHConfigReferences(paramKey, value string) ([]sshconfig.Reference, error) {
+	return s.sshManager.WhereUsed(paramKey, value)
+}
+
+// AnalyzeSSHHosts reports the normalized target of every host and groups aliases that point at the
+// same HostName:Port, flagging groups whose users or identity files differ as conflicting. With
+// resolveDNS, host names are resolved so a DNS name and an IP literal for the same machine are grouped too.
+func (s *Service) AnalyzeSSHHosts(resolveDNS bool) *sshconfig.HostAnalysis {
+	analysis := s.sshManager.AnalyzeHosts(resolveDNS)
+	logger.Printf("Analyzed %d hosts, found %d groups of aliases sharing a target.", len(analysis.Targets), len(analysis.Duplicates))
+	return analysis
+}
+
+// ExpandSSHConfigTokens shows the concrete value of an ssh_config setting such as
+// ControlPath or LocalCommand for the given host, with %-tokens and ${ENV} expanded.
+func (s *Service) ExpandSSHConfigTokens(alias, value string) string {
+	return s.sshManager.ExpandTokens(alias, value)
+}
@@ -0,0 +1,686 @@
+package sshgate
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/sshtunnel"
+	"devtools/backend/internal/types"
+
+	"github.com/zalando/go-keyring"
+)
+
+// newTestService 创建一个使用临时 ssh_config 的服务
+func newTestService(t *testing.T, content string) (*Service, string) {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to create test config: %v", err)
+	}
+	mgr, err := sshmanager.NewManager(configFile)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	return NewService(mgr), configFile
+}
+
+func readConfig(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	return string(data)
+}
+
+// TestSaveSSHHost_NewHostOmitsEmptyParams 新建主机时空的 Port/IdentityFile 不应写入配置
+func TestSaveSSHHost_NewHostOmitsEmptyParams(t *testing.T) {
+	s, configFile := newTestService(t, "")
+
+	result, err := s.SaveSSHHost(types.SSHHost{Alias: "web", HostName: "10.0.0.1", User: "deploy", Port: " ", IdentityFile: ""}, "")
+	if err != nil {
+		t.Fatalf("SaveSSHHost failed: %v", err)
+	}
+	if want := []string{"HostName", "User"}; !reflect.DeepEqual(result.Added, want) {
+		t.Errorf("Added = %v, want %v", result.Added, want)
+	}
+
+	content := readConfig(t, configFile)
+	for _, key := range []string{"Port", "IdentityFile"} {
+		if strings.Contains(content, key) {
+			t.Errorf("config should not contain an empty %s:\n%s", key, content)
+		}
+	}
+}
+
+// TestSaveSSHHost_UpdateReportsChanges 更新主机时应返回新增、修改和删除的参数，并删除被清空的参数
+func TestSaveSSHHost_UpdateReportsChanges(t *testing.T) {
+	s, configFile := newTestService(t, `Host web
+  HostName 10.0.0.1
+  User deploy
+  IdentityFile ~/.ssh/id_web
+`)
+
+	result, err := s.SaveSSHHost(types.SSHHost{Alias: "web", HostName: "10.0.0.2", User: "deploy", Port: "2222"}, "web")
+	if err != nil {
+		t.Fatalf("SaveSSHHost failed: %v", err)
+	}
+	want := &sshmanager.HostUpdateResult{
+		Added:   []string{"Port"},
+		Updated: []string{"HostName"},
+		Removed: []string{"IdentityFile"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+
+	content := readConfig(t, configFile)
+	if strings.Contains(content, "IdentityFile") {
+		t.Errorf("IdentityFile should have been removed:\n%s", content)
+	}
+	if !strings.Contains(content, "Port 2222") || !strings.Contains(content, "HostName 10.0.0.2") {
+		t.Errorf("config was not updated:\n%s", content)
+	}
+
+	// 再次保存相同的内容不应报告任何变化
+	result, err = s.SaveSSHHost(types.SSHHost{Alias: "web", HostName: "10.0.0.2", User: "deploy", Port: "2222"}, "web")
+	if err != nil {
+		t.Fatalf("SaveSSHHost failed: %v", err)
+	}
+	if len(result.Added)+len(result.Updated)+len(result.Removed) != 0 {
+		t.Errorf("unchanged save reported changes: %+v", result)
+	}
+}
+
+// TestAdoptInclude_ShowsHostsFromConfigDir config.d 中的主机在 Include 之后出现在主机列表中，并且只读
+func TestAdoptInclude_ShowsHostsFromConfigDir(t *testing.T) {
+	s, configFile := newTestService(t, "Host web\n    HostName 10.0.0.1\n")
+	dir := filepath.Join(filepath.Dir(configFile), "config.d")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "corp"), []byte("Host db\n    HostName 10.0.0.2\n    User ops\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "corp.bak"), []byte("Host old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	scan, err := s.ScanForAdjacentConfigs()
+	if err != nil {
+		t.Fatalf("ScanForAdjacentConfigs failed: %v", err)
+	}
+	if scan.HasInclude || len(scan.Files) != 1 || scan.Files[0].Included || !reflect.DeepEqual(scan.Files[0].Hosts, []string{"db"}) {
+		t.Errorf("scan = %+v, want one unincluded file with host db", scan)
+	}
+	if !reflect.DeepEqual(scan.Suggested, []string{"config.d/*"}) {
+		t.Fatalf("Suggested = %v, want [config.d/*]", scan.Suggested)
+	}
+
+	arg, err := s.AdoptInclude(scan.Suggested[0])
+	if err != nil {
+		t.Fatalf("AdoptInclude failed: %v", err)
+	}
+	if arg != "config.d/*" || !strings.HasPrefix(readConfig(t, configFile), "Include config.d/*\n") {
+		t.Errorf("AdoptInclude wrote %q, config:\n%s", arg, readConfig(t, configFile))
+	}
+	// 再次引入不会重复写入
+	if _, err := s.AdoptInclude("config.d/*"); err != nil {
+		t.Fatalf("second AdoptInclude failed: %v", err)
+	}
+	if n := strings.Count(readConfig(t, configFile), "Include"); n != 1 {
+		t.Errorf("config has %d Include lines, want 1", n)
+	}
+
+	hosts, err := s.GetSSHHosts()
+	if err != nil {
+		t.Fatalf("GetSSHHosts failed: %v", err)
+	}
+	var db *types.SSHHost
+	for i := range hosts {
+		if hosts[i].Alias == "db" {
+			db = &hosts[i]
+		}
+	}
+	if db == nil || db.Source != sshmanager.HostSourceInclude || db.User != "ops" {
+		t.Fatalf("included host db = %+v, want source %q", db, sshmanager.HostSourceInclude)
+	}
+	if err := s.DeleteSSHHost("db"); err == nil {
+		t.Error("DeleteSSHHost on an included host should fail")
+	}
+}
+
+// TestSearchHosts_RanksAliasMatchesFirst 别名匹配排在前面，笔记中的匹配也能找到主机，所有词都必须匹配
+func TestSearchHosts_RanksAliasMatchesFirst(t *testing.T) {
+	s, _ := newTestService(t, `Host prod-db
+  HostName 10.0.0.5
+  User postgres
+
+Host db-backup
+  HostName backup.internal
+  User ops
+
+Host web
+  HostName prod-web.example.com
+  User deploy
+`)
+	s.hostNotes = map[string]HostNotes{"web": {Notes: "Serves the storefront", Owner: "alice"}}
+
+	aliases := func(results []HostSearchResult) []string {
+		out := []string{}
+		for _, r := range results {
+			out = append(out, r.Host.Alias)
+		}
+		return out
+	}
+
+	results, err := s.SearchHosts("db")
+	if err != nil {
+		t.Fatalf("SearchHosts failed: %v", err)
+	}
+	if got := aliases(results); !reflect.DeepEqual(got, []string{"db-backup", "prod-db"}) {
+		t.Errorf("SearchHosts(db) = %v, want [db-backup prod-db]", got)
+	}
+
+	if results, _ = s.SearchHosts("pdb"); len(results) == 0 || results[0].Host.Alias != "prod-db" {
+		t.Errorf("SearchHosts(pdb) = %v, want prod-db first", aliases(results))
+	}
+	if results, _ = s.SearchHosts("storefront"); !reflect.DeepEqual(aliases(results), []string{"web"}) || results[0].MatchedField != "notes" {
+		t.Errorf("SearchHosts(storefront) = %+v, want web matched by notes", results)
+	}
+	if results, _ = s.SearchHosts("prod ops"); len(results) != 0 {
+		t.Errorf("SearchHosts(prod ops) = %v, want no host matching both terms", aliases(results))
+	}
+	if results, _ = s.SearchHosts(""); len(results) != 3 {
+		t.Errorf("SearchHosts(\"\") returned %d hosts, want 3", len(results))
+	}
+}
+
+// TestImportForwardsFromHost_WriteBack 配置中的转发应作为建议返回，开启回写后修改隧道会改写对应的行
+func TestImportForwardsFromHost_WriteBack(t *testing.T) {
+	s, configFile := newTestService(t, strings.Join([]string{
+		"Host app",
+		"    HostName 10.0.0.1",
+		"    LocalForward 127.0.0.1:8080 db:5432",
+		"    DynamicForward 1080",
+		"    RemoteForward 9000 localhost:22",
+		"    LocalForward 8081 cache:6379",
+		"",
+	}, "\n"))
+	s.tunnelsConfigPath = filepath.Join(t.TempDir(), "tunnels.json")
+
+	suggestions, err := s.ImportForwardsFromHost("app")
+	if err != nil {
+		t.Fatalf("ImportForwardsFromHost failed: %v", err)
+	}
+	if len(suggestions) != 4 {
+		t.Fatalf("got %d suggestions, want 4: %+v", len(suggestions), suggestions)
+	}
+	local := suggestions[0].Tunnel
+	if local.TunnelType != "local" || local.LocalPort != 8080 || local.RemoteHost != "db" || local.RemotePort != 5432 || local.GatewayPorts {
+		t.Errorf("unexpected local suggestion: %+v", local)
+	}
+	if suggestions[0].Line != 3 {
+		t.Errorf("Line = %d, want 3", suggestions[0].Line)
+	}
+	if dynamic := suggestions[1].Tunnel; dynamic.TunnelType != "dynamic" || dynamic.LocalPort != 1080 {
+		t.Errorf("unexpected dynamic suggestion: %+v", dynamic)
+	}
+	if suggestions[2].Unsupported == "" {
+		t.Error("RemoteForward should be reported as unsupported")
+	}
+
+	local.ConfigForward.WriteBack = true
+	local.LocalPort = 18080
+	if err := s.SaveTunnelConfig(local); err != nil {
+		t.Fatalf("SaveTunnelConfig failed: %v", err)
+	}
+	content := readConfig(t, configFile)
+	if !strings.Contains(content, "    LocalForward 127.0.0.1:18080 db:5432\n") || !strings.Contains(content, "LocalForward 8081 cache:6379") {
+		t.Errorf("only the linked line should be rewritten:\n%s", content)
+	}
+
+	suggestions, err = s.ImportForwardsFromHost("app")
+	if err != nil {
+		t.Fatalf("ImportForwardsFromHost failed: %v", err)
+	}
+	saved, err := s.GetSavedTunnels()
+	if err != nil {
+		t.Fatalf("GetSavedTunnels failed: %v", err)
+	}
+	if len(saved) != 1 || suggestions[0].ExistingID != saved[0].ID {
+		t.Errorf("the saved tunnel should be reported as existing, got %q", suggestions[0].ExistingID)
+	}
+	if saved[0].ConfigForward.Value != "127.0.0.1:18080 db:5432" {
+		t.Errorf("link value = %q, want the rewritten value", saved[0].ConfigForward.Value)
+	}
+}
+
+// TestDuplicateTunnelConfig_CopiesCredential 复制隧道时应复制钥匙串中的密码，主机来源变化时迁移密码
+func TestDuplicateTunnelConfig_CopiesCredential(t *testing.T) {
+	keyring.MockInit()
+	s, _ := newTestService(t, "Host app\n    HostName 10.0.0.2\n    User ops\n\nHost other\n    HostName 10.0.0.3\n    User ops\n")
+	s.tunnelsConfigPath = filepath.Join(t.TempDir(), "tunnels.json")
+
+	tunnel := sshtunnel.SavedTunnelConfig{
+		Name: "db", TunnelType: "local", LocalPort: 5432, RemoteHost: "db", RemotePort: 5432,
+		HostSource: "manual", ManualHost: &sshtunnel.ManualHostInfo{HostName: "10.0.0.2", Port: "22", User: "ops"},
+	}
+	if err := s.SaveTunnelConfig(tunnel); err != nil {
+		t.Fatalf("SaveTunnelConfig failed: %v", err)
+	}
+	saved, _ := s.GetSavedTunnels()
+	original := saved[0]
+	if err := s.SavePassword(original.ID, "secret"); err != nil {
+		t.Fatalf("SavePassword failed: %v", err)
+	}
+
+	copied, err := s.DuplicateTunnelConfig(original.ID)
+	if err != nil {
+		t.Fatalf("DuplicateTunnelConfig failed: %v", err)
+	}
+	if !s.TunnelHasSavedPassword(copied.ID) {
+		t.Error("the duplicate should have the original's password")
+	}
+	skipped, err := s.DuplicateTunnelConfigWithCredential(original.ID, false)
+	if err != nil {
+		t.Fatalf("DuplicateTunnelConfigWithCredential failed: %v", err)
+	}
+	if s.TunnelHasSavedPassword(skipped.ID) {
+		t.Error("the password should not be copied when the user declines")
+	}
+
+	// manual → ssh_config: the password moves to the host alias
+	original.HostSource, original.HostAlias, original.ManualHost = "ssh_config", "app", nil
+	if err := s.SaveTunnelConfig(original); err != nil {
+		t.Fatalf("SaveTunnelConfig failed: %v", err)
+	}
+	if s.TunnelHasSavedPassword(original.ID) {
+		t.Error("the tunnel's password should be moved away")
+	}
+	if !s.sshManager.HasPassword("app") {
+		t.Error("the host alias should have the moved password")
+	}
+
+	// ssh_config → manual host on another server: the alias's password is not copied
+	original.HostSource, original.HostAlias, original.ManualHost = "manual", "", &sshtunnel.ManualHostInfo{HostName: "10.0.0.9", User: "ops"}
+	previous := original
+	previous.HostSource, previous.HostAlias, previous.ManualHost = "ssh_config", "app", nil
+	s.rekeyTunnelCredential(previous, original)
+	if s.TunnelHasSavedPassword(original.ID) {
+		t.Error("the host's password must not be copied to a tunnel to another server")
+	}
+
+	// manual → ssh_config alias of another server: the password is dropped, not moved
+	if err := s.SavePassword(copied.ID, "secret"); err != nil {
+		t.Fatalf("SavePassword failed: %v", err)
+	}
+	copied.HostSource, copied.HostAlias, copied.ManualHost = "ssh_config", "other", nil
+	if err := s.SaveTunnelConfig(*copied); err != nil {
+		t.Fatalf("SaveTunnelConfig failed: %v", err)
+	}
+	if s.sshManager.HasPassword("other") || s.TunnelHasSavedPassword(copied.ID) {
+		t.Error("the password of a manual host must not move to an alias of another server")
+	}
+}
+
+// TestGetHostColors 测试按环境与分组标签匹配颜色规则，单独设置的颜色优先，其余主机的颜色由别名稳定地派生
+func TestGetHostColors(t *testing.T) {
+	s, _ := newTestService(t, `Host api
+  HostName 10.0.0.1
+
+Host cache
+  HostName 10.0.0.2
+
+Host web
+  HostName 10.0.0.3
+`)
+	s.hostColorsConfigPath = filepath.Join(t.TempDir(), "host_colors.json")
+	s.hostNotes = map[string]HostNotes{"api": {Environment: "Production"}}
+	s.hostGroups.Groups = []HostGroup{{ID: "g1", Name: "Staging", Aliases: []string{"cache"}}}
+
+	colors, err := s.GetHostColors()
+	if err != nil {
+		t.Fatalf("GetHostColors failed: %v", err)
+	}
+	if got := colors["api"]; got != (HostColor{Color: "red", Source: ColorSourceRule, Tag: "production"}) {
+		t.Errorf("api should be red by its environment, got %+v", got)
+	}
+	if got := colors["cache"]; got.Color != "amber" || got.Tag != "staging" {
+		t.Errorf("cache should be amber by its group, got %+v", got)
+	}
+	if got := colors["web"]; got.Source != ColorSourceHash || got.Color != hashColor("web") || got.Color == "red" {
+		t.Errorf("web should get a derived color that is not red, got %+v", got)
+	}
+
+	if err := s.SetHostColor("api", "purple"); err != nil {
+		t.Fatalf("SetHostColor failed: %v", err)
+	}
+	if got := s.GetHostColor("api"); got != (HostColor{Color: "purple", Source: ColorSourceHost}) {
+		t.Errorf("a host color should take precedence over rules, got %+v", got)
+	}
+	if err := s.SetHostColor("api", "crimson"); err == nil {
+		t.Error("unknown color tokens should be rejected")
+	}
+
+	exported, err := s.ExportHostColors()
+	if err != nil {
+		t.Fatalf("ExportHostColors failed: %v", err)
+	}
+	other, _ := newTestService(t, "")
+	other.hostColorsConfigPath = filepath.Join(t.TempDir(), "host_colors.json")
+	if err := other.ImportHostColors(exported); err != nil {
+		t.Fatalf("ImportHostColors failed: %v", err)
+	}
+	if got := other.GetHostColor("api"); got.Color != "purple" {
+		t.Errorf("imported colors should apply on another machine, got %+v", got)
+	}
+}
+
+func TestParseListeningPorts(t *testing.T) {
+	ss := `State  Recv-Q Send-Q Local Address:Port  Peer Address:Port Process
+LISTEN 0      4096   127.0.0.53%lo:53       0.0.0.0:*     users:(("systemd-resolve",pid=612,fd=14))
+LISTEN 0      128          0.0.0.0:22       0.0.0.0:*     users:(("sshd",pid=901,fd=3))
+LISTEN 0      128             [::]:22          [::]:*     users:(("sshd",pid=901,fd=4))
+LISTEN 0      511        10.0.0.5:8080      0.0.0.0:*
+`
+	want := []DetectedPort{
+		{Port: 22, Addresses: []string{"0.0.0.0", "::"}, Process: "sshd", PID: 901},
+		{Port: 53, Addresses: []string{"127.0.0.53"}, Process: "systemd-resolve", PID: 612},
+		{Port: 8080, Addresses: []string{"10.0.0.5"}},
+	}
+	if got := parseListeningPorts(ss); !reflect.DeepEqual(got, want) {
+		t.Errorf("ss output parsed as %+v", got)
+	}
+
+	netstat := `Active Internet connections (only servers)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State       PID/Program name
+tcp        0      0 0.0.0.0:5432            0.0.0.0:*               LISTEN      1234/postgres
+tcp6       0      0 :::80                   :::*                    LISTEN      -
+`
+	want = []DetectedPort{
+		{Port: 80, Addresses: []string{"::"}},
+		{Port: 5432, Addresses: []string{"0.0.0.0"}, Process: "postgres", PID: 1234},
+	}
+	if got := parseListeningPorts(netstat); !reflect.DeepEqual(got, want) {
+		t.Errorf("netstat output parsed as %+v", got)
+	}
+
+	bsd := "tcp4       0      0  127.0.0.1.3000         *.*                    LISTEN\ntcp46      0      0  *.22                   *.*                    LISTEN\ntcp4       0      0  192.168.1.2.50000      1.2.3.4.443            ESTABLISHED\n"
+	want = []DetectedPort{
+		{Port: 22, Addresses: []string{"*"}},
+		{Port: 3000, Addresses: []string{"127.0.0.1"}},
+	}
+	if got := parseListeningPorts(bsd); !reflect.DeepEqual(got, want) {
+		t.Errorf("BSD netstat output parsed as %+v", got)
+	}
+}
+
+// TestValidateTunnelConfig 测试保存隧道前的字段校验：端口范围、远程主机、主机别名，以及与已保存隧道的重复检测
+func TestValidateTunnelConfig(t *testing.T) {
+	s, _ := newTestService(t, "Host app\n    HostName 10.0.0.1\n")
+	s.tunnelsConfigPath = filepath.Join(t.TempDir(), "tunnels.json")
+
+	fieldsOf := func(errs []TunnelFieldError) map[string]bool {
+		fields := make(map[string]bool)
+		for _, e := range errs {
+			fields[e.Field] = e.Warning
+		}
+		return fields
+	}
+
+	invalid := sshtunnel.SavedTunnelConfig{
+		Name: "db", TunnelType: "local", LocalPort: 70000, RemoteHost: " ", RemotePort: 0,
+		HostSource: "ssh_config", HostAlias: "missing",
+	}
+	got := fieldsOf(s.ValidateTunnelConfig(invalid))
+	for _, field := range []string{"localPort", "remoteHost", "remotePort", "hostAlias"} {
+		if warning, ok := got[field]; !ok || warning {
+			t.Errorf("expected an error for %s, got %v", field, got)
+		}
+	}
+	err := s.SaveTunnelConfig(invalid)
+	var validationErr *TunnelValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 4 {
+		t.Fatalf("SaveTunnelConfig should return the field errors, got %v", err)
+	}
+
+	manual := sshtunnel.SavedTunnelConfig{
+		Name: "proxy", TunnelType: "dynamic", LocalPort: 1080,
+		HostSource: "manual", ManualHost: &sshtunnel.ManualHostInfo{HostName: "", Port: "ssh"},
+	}
+	if got := fieldsOf(s.ValidateTunnelConfig(manual)); len(got) != 2 || !hasKey(got, "manualHost.hostName") || !hasKey(got, "manualHost.port") {
+		t.Errorf("unexpected manual host errors: %v", got)
+	}
+
+	db := sshtunnel.SavedTunnelConfig{
+		Name: "db", TunnelType: "local", LocalPort: 5432, RemoteHost: "db", RemotePort: 5432,
+		HostSource: "ssh_config", HostAlias: "app",
+	}
+	if err := s.SaveTunnelConfig(db); err != nil {
+		t.Fatalf("SaveTunnelConfig failed: %v", err)
+	}
+
+	duplicate := db
+	duplicate.Name = "db copy"
+	if got := fieldsOf(s.ValidateTunnelConfig(duplicate)); len(got) != 1 || got["localPort"] {
+		t.Errorf("the same forward should be an error on localPort, got %v", got)
+	}
+	other := db
+	other.RemoteHost = "replica"
+	if got := fieldsOf(s.ValidateTunnelConfig(other)); len(got) != 1 || !got["localPort"] {
+		t.Errorf("sharing the local port should only warn, got %v", got)
+	}
+	if err := s.SaveTunnelConfig(other); err != nil {
+		t.Errorf("a warning should not block saving: %v", err)
+	}
+
+	// 复制出的隧道与原隧道转发相同，只改名称时两者都可以保存
+	saved, _ := s.GetSavedTunnels()
+	original := saved[slices.IndexFunc(saved, func(c sshtunnel.SavedTunnelConfig) bool { return c.Name == "db" })]
+	copied, err := s.DuplicateTunnelConfigWithCredential(original.ID, false)
+	if err != nil {
+		t.Fatalf("DuplicateTunnelConfigWithCredential failed: %v", err)
+	}
+	original.Name = "db primary"
+	if err := s.SaveTunnelConfig(original); err != nil {
+		t.Errorf("renaming the original should not fail on its own forward: %v", err)
+	}
+	copied.Name = "db secondary"
+	if err := s.SaveTunnelConfig(*copied); err != nil {
+		t.Errorf("renaming the copy should not fail on its own forward: %v", err)
+	}
+	other.RemoteHost = "db"
+	if err := s.SaveTunnelConfig(other); !errors.As(err, &validationErr) {
+		t.Errorf("a new tunnel with an existing forward should still fail, got %v", err)
+	}
+}
+
+func hasKey(m map[string]bool, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+// TestGetHostDependencies 测试删除主机前列出使用它的隧道、终端、同步配置与 ProxyJump 引用
+func TestGetHostDependencies(t *testing.T) {
+	s, _ := newTestService(t, `Host bastion
+    HostName 10.0.0.1
+
+Host app
+    HostName 10.0.0.2
+    ProxyJump bastion
+`)
+	s.tunnelsConfigPath = filepath.Join(t.TempDir(), "tunnels.json")
+	tunnel := sshtunnel.SavedTunnelConfig{
+		Name: "db", TunnelType: "local", LocalPort: 5432, RemoteHost: "db", RemotePort: 5432,
+		HostSource: "ssh_config", HostAlias: "bastion",
+	}
+	if err := s.SaveTunnelConfig(tunnel); err != nil {
+		t.Fatalf("SaveTunnelConfig failed: %v", err)
+	}
+	s.SetTerminalSessionSource(func(alias string) []HostDependency {
+		if alias == "bastion" {
+			return []HostDependency{{ID: "term-1", Name: "ops@bastion"}}
+		}
+		return nil
+	})
+	s.SetSyncConfigSource(func() []types.SSHConfig {
+		return []types.SSHConfig{{ID: "sync-1", Name: "site", Host: "bastion"}, {ID: "sync-2", Name: "other", Host: "10.0.0.1"}}
+	})
+
+	deps, err := s.GetHostDependencies("bastion")
+	if err != nil {
+		t.Fatalf("GetHostDependencies failed: %v", err)
+	}
+	if len(deps.SavedTunnels) != 1 || deps.SavedTunnels[0].Name != "db" {
+		t.Errorf("SavedTunnels = %+v, want the db tunnel", deps.SavedTunnels)
+	}
+	if len(deps.Terminals) != 1 || deps.Terminals[0].ID != "term-1" {
+		t.Errorf("Terminals = %+v, want term-1", deps.Terminals)
+	}
+	if len(deps.SyncConfigs) != 1 || deps.SyncConfigs[0].ID != "sync-1" {
+		t.Errorf("SyncConfigs = %+v, want only sync-1", deps.SyncConfigs)
+	}
+	if len(deps.References) != 1 || deps.References[0].Aliases[0] != "app" {
+		t.Errorf("References = %+v, want the ProxyJump of app", deps.References)
+	}
+
+	deps, err = s.GetHostDependencies("app")
+	if err != nil {
+		t.Fatalf("GetHostDependencies failed: %v", err)
+	}
+	if len(deps.SavedTunnels)+len(deps.Terminals)+len(deps.SyncConfigs)+len(deps.References) != 0 {
+		t.Errorf("app should have no dependencies, got %+v", deps)
+	}
+
+	if _, err := s.GetHostDependencies("missing"); err == nil {
+		t.Error("GetHostDependencies should fail for an unknown host")
+	}
+}
+
+// TestExportTunnelAsCommand 测试导出的命令展开跳板机别名，并对含空格的参数加引号
+func TestExportTunnelAsCommand(t *testing.T) {
+	s, _ := newTestService(t, `Host bastion
+    HostName 10.0.0.1
+    User ops
+    Port 2200
+
+Host app
+    HostName 10.0.1.5
+    User deploy
+    IdentityFile ~/.ssh/id app
+    ProxyJump bastion
+`)
+	s.tunnelsConfigPath = filepath.Join(t.TempDir(), "tunnels.json")
+	tunnels := []sshtunnel.SavedTunnelConfig{
+		{
+			Name: "db", TunnelType: "local", LocalPort: 5432, RemoteHost: "db.internal", RemotePort: 5432,
+			HostSource: "ssh_config", HostAlias: "app",
+		},
+		{
+			Name: "socks", TunnelType: "dynamic", LocalPort: 1080, GatewayPorts: true,
+			HostSource: "manual", ManualHost: &sshtunnel.ManualHostInfo{HostName: "10.0.0.9", Port: "22", User: "me"},
+		},
+	}
+	for _, tunnel := range tunnels {
+		if err := s.SaveTunnelConfig(tunnel); err != nil {
+			t.Fatalf("SaveTunnelConfig failed: %v", err)
+		}
+	}
+	saved, _ := s.GetSavedTunnels()
+
+	want := map[string]string{
+		"db":    `ssh -N -o ExitOnForwardFailure=yes -L 5432:db.internal:5432 -i ~/'.ssh/id app' -J ops@10.0.0.1:2200 deploy@10.0.1.5`,
+		"socks": `ssh -N -o ExitOnForwardFailure=yes -D 0.0.0.0:1080 me@10.0.0.9`,
+	}
+	for _, tunnel := range saved {
+		got, err := s.ExportTunnelAsCommand(tunnel.ID)
+		if err != nil {
+			t.Fatalf("ExportTunnelAsCommand(%s) failed: %v", tunnel.Name, err)
+		}
+		if got != want[tunnel.Name] {
+			t.Errorf("ExportTunnelAsCommand(%s) = %s, want %s", tunnel.Name, got, want[tunnel.Name])
+		}
+	}
+
+	if _, err := s.ExportTunnelAsCommand("missing"); err == nil {
+		t.Error("ExportTunnelAsCommand should fail for an unknown tunnel")
+	}
+}
+
+func TestSuggestConfig(t *testing.T) {
+	s, configFile := newTestService(t, "Host web01\n    HostName 10.0.0.1\n")
+
+	// 无法解析的主机名不做探测，别名取第一段并避开已有的 web01
+	got, err := s.SuggestConfig(HostWizardInput{Host: "web01.example.invalid"})
+	if err != nil {
+		t.Fatalf("SuggestConfig failed: %v", err)
+	}
+	if got.Host.Alias != "web01-2" || got.Host.HostName != "web01.example.invalid" || got.Host.Port != "" {
+		t.Errorf("unexpected host: %+v", got.Host)
+	}
+	if got.Resolve.Resolves || got.Probe != nil || got.Ready {
+		t.Errorf("an unresolvable host should not be probed: %+v", got)
+	}
+	if !slices.Contains(got.Hints, WizardHintUnknownUser) {
+		t.Errorf("hints = %v, want %s", got.Hints, WizardHintUnknownUser)
+	}
+
+	// 本地只发送版本标识的服务器：可达，但没有完成握手
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("SSH-2.0-Test\r\n"))
+			conn.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	got, err = s.SuggestConfig(HostWizardInput{Host: "127.0.0.1", Port: port, User: "deploy", Alias: "web01"})
+	if err != nil {
+		t.Fatalf("SuggestConfig failed: %v", err)
+	}
+	if got.Host.Alias != "web01-2" || got.Host.User != "deploy" || got.Host.Port == "" {
+		t.Errorf("unexpected host: %+v", got.Host)
+	}
+	if !slices.Contains(got.Hints, WizardHintAliasTaken) || slices.Contains(got.Hints, WizardHintUnknownUser) {
+		t.Errorf("unexpected hints: %v", got.Hints)
+	}
+	if !got.Resolve.IsIP || got.Probe == nil || !got.Probe.Reachable || got.Probe.Banner != "SSH-2.0-Test" {
+		t.Errorf("unexpected checks: resolve=%+v probe=%+v", got.Resolve, got.Probe)
+	}
+	if got.Ready {
+		t.Error("a server that did not send its host key should not be ready")
+	}
+
+	probe, err := s.TestTCPReachable("127.0.0.1", port)
+	if err != nil || !probe.Reachable || probe.Banner != "SSH-2.0-Test" || probe.Error != "" {
+		t.Errorf("TestTCPReachable = %+v, %v", probe, err)
+	}
+	if _, err := s.TestTCPReachable("127.0.0.1", 70000); err == nil {
+		t.Error("TestTCPReachable should reject an invalid port")
+	}
+	if _, err := s.SuggestConfig(HostWizardInput{Host: "10.0.0.2", Alias: "bad alias"}); err == nil {
+		t.Error("SuggestConfig should reject an invalid alias")
+	}
+
+	// 向导不会写入 ssh_config
+	if strings.Contains(readConfig(t, configFile), "web01-2") {
+		t.Error("SuggestConfig must not modify ssh_config")
+	}
+}
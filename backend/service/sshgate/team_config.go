@@ -0,0 +1,286 @@
+package sshgate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"devtools/backend/internal/settings"
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/utils"
+)
+
+// 团队配置读取的限制
+const (
+	teamConfigFetchTimeout = 15 * time.Second
+	teamConfigMaxBytes     = 1 << 20
+	teamConfigGitTimeout   = 30 * time.Second
+)
+
+// TeamConfigStatus is the state of the shared team config source, shown in the settings page.
+type TeamConfigStatus struct {
+	Location    string                        `json:"location"`
+	LastRefresh string                        `json:"lastRefresh,omitempty"` // RFC3339, empty if never refreshed successfully
+	LastError   string                        `json:"lastError,omitempty"`
+	HostCount   int                           `json:"hostCount"`
+	Conflicts   []sshmanager.TeamHostConflict `json:"conflicts"` // team hosts hidden by a personal host with the same alias
+}
+
+// teamConfig periodically reads the team ssh_config fragment into the manager's read-only team hosts.
+type teamConfig struct {
+	s *Service
+
+	mu          sync.Mutex
+	ready       bool
+	source      settings.TeamConfigSource
+	cancel      context.CancelFunc
+	lastRefresh time.Time
+	lastError   string
+}
+
+func newTeamConfig(s *Service) *teamConfig {
+	return &teamConfig{s: s}
+}
+
+// configure 应用新的设置；来源或刷新间隔变化时重启刷新循环
+func (t *teamConfig) configure(cfg settings.Settings) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	source := cfg.TeamConfig
+	source.Location = strings.TrimSpace(source.Location)
+	if source == t.source && t.cancel != nil {
+		return
+	}
+	if source.Location != t.source.Location {
+		t.lastRefresh = time.Time{}
+		t.lastError = ""
+	}
+	t.source = source
+	t.reconcile_nolock()
+}
+
+// setReady 在 Service 启动完成后调用
+func (t *teamConfig) setReady() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ready = true
+	t.reconcile_nolock()
+}
+
+// stop 停止定期刷新，应用退出时调用
+func (t *teamConfig) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ready = false
+	t.stop_nolock()
+}
+
+// reconcile_nolock 按当前设置启动或停止刷新循环，调用方需持有 t.mu
+func (t *teamConfig) reconcile_nolock() {
+	t.stop_nolock()
+	if !t.ready {
+		return
+	}
+	if t.source.Location == "" {
+		// 清除之前来源的主机
+		if len(t.s.sshManager.GetTeamHosts()) > 0 {
+			t.s.sshManager.SetTeamHosts(nil)
+			utils.EmitEvent(t.s.ctx, "ssh_team_config_changed")
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	go t.loop(ctx, t.source)
+}
+
+// stop_nolock 停止刷新循环，调用方需持有 t.mu
+func (t *teamConfig) stop_nolock() {
+	if t.cancel != nil {
+		t.cancel()
+		t.cancel = nil
+	}
+}
+
+// loop 立即读取一次，之后按间隔刷新，直到 ctx 被取消
+func (t *teamConfig) loop(ctx context.Context, source settings.TeamConfigSource) {
+	_ = t.refresh(ctx, source)
+	if source.RefreshMinutes <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(source.RefreshMinutes) * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = t.refresh(ctx, source)
+		}
+	}
+}
+
+// refresh 读取并解析团队配置。失败时保留上一次成功读取的主机。
+func (t *teamConfig) refresh(ctx context.Context, source settings.TeamConfigSource) error {
+	content, err := fetchTeamConfig(ctx, source.Location)
+	var hosts []types.SSHHost
+	if err == nil {
+		hosts, err = sshmanager.ParseTeamConfig(content)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	t.mu.Lock()
+	if source.Location != t.source.Location {
+		// 刷新期间来源已经变化
+		t.mu.Unlock()
+		return fmt.Errorf("team config source changed during refresh")
+	}
+	if err != nil {
+		t.lastError = err.Error()
+		t.mu.Unlock()
+		logger.Printf("Warning: failed to refresh team config from %s: %v", source.Location, err)
+		utils.EmitEvent(t.s.ctx, "ssh_team_config_changed")
+		return err
+	}
+	t.s.sshManager.SetTeamHosts(hosts)
+	t.lastRefresh = time.Now()
+	t.lastError = ""
+	t.mu.Unlock()
+
+	conflicts := t.s.sshManager.TeamConflicts()
+	logger.Printf("Loaded %d team hosts from %s (%d conflicting with the personal config).", len(hosts), source.Location, len(conflicts))
+	utils.EmitEvent(t.s.ctx, "ssh_team_config_changed")
+	return nil
+}
+
+// status 返回团队配置的当前状态
+func (t *teamConfig) status() TeamConfigStatus {
+	t.mu.Lock()
+	status := TeamConfigStatus{
+		Location:  t.source.Location,
+		LastError: t.lastError,
+	}
+	if !t.lastRefresh.IsZero() {
+		status.LastRefresh = t.lastRefresh.Format(time.RFC3339)
+	}
+	t.mu.Unlock()
+	status.HostCount = len(t.s.sshManager.GetTeamHosts())
+	status.Conflicts = t.s.sshManager.TeamConflicts()
+	return status
+}
+
+// fetchTeamConfig 读取团队配置的内容：http(s) URL 直接下载，本地文件位于 Git 仓库中时先尝试 git pull --ff-only
+func fetchTeamConfig(ctx context.Context, location string) (string, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return downloadTeamConfig(ctx, location)
+	}
+
+	path := location
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	pullTeamConfigRepo(ctx, filepath.Dir(path))
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open team config: %w", err)
+	}
+	defer f.Close()
+	return readTeamConfig(f)
+}
+
+// downloadTeamConfig 通过 HTTP 下载团队配置
+func downloadTeamConfig(ctx context.Context, url string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, teamConfigFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid team config URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download team config: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download team config: %s", resp.Status)
+	}
+	return readTeamConfig(resp.Body)
+}
+
+// readTeamConfig 读取团队配置内容，超过 teamConfigMaxBytes 时报错
+func readTeamConfig(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, teamConfigMaxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read team config: %w", err)
+	}
+	if len(data) > teamConfigMaxBytes {
+		return "", fmt.Errorf("team config is larger than %d bytes", teamConfigMaxBytes)
+	}
+	return string(data), nil
+}
+
+// pullTeamConfigRepo 如果 dir 位于 Git 仓库中，执行 git pull --ff-only 获取团队的最新修改。
+// 失败（没有 git、离线、本地有修改等）时只记录警告，继续使用当前的文件内容。
+func pullTeamConfigRepo(ctx context.Context, dir string) {
+	ctx, cancel := context.WithTimeout(ctx, teamConfigGitTimeout)
+	defer cancel()
+	if err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		return
+	}
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "pull", "--ff-only", "--quiet")
+	// 不要在后台弹出凭据提示
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		logger.Printf("Warning: git pull of team config in %s failed: %v: %s", dir, err, strings.TrimSpace(string(out)))
+	}
+}
+
+// RefreshTeamConfig reads the team config source again and returns its status.
+func (s *Service) RefreshTeamConfig() (*TeamConfigStatus, error) {
+	s.teamConfig.mu.Lock()
+	source := s.teamConfig.source
+	s.teamConfig.mu.Unlock()
+	if source.Location == "" {
+		return nil, fmt.Errorf("no team config source is configured")
+	}
+	if err := s.teamConfig.refresh(context.Background(), source); err != nil {
+		return nil, err
+	}
+	status := s.teamConfig.status()
+	return &status, nil
+}
+
+// GetTeamConfigStatus returns the last refresh time, error and conflicts of the team config source.
+func (s *Service) GetTeamConfigStatus() TeamConfigStatus {
+	return s.teamConfig.status()
+}
+
+// GetTeamHosts returns all hosts of the team config, including those hidden by a personal host.
+func (s *Service) GetTeamHosts() []types.SSHHost {
+	return s.sshManager.GetTeamHosts()
+}
+
+// appendTeamHosts adds the team hosts that are not shadowed by a personal host
+// or by a host from an Include file (listed in shadowed) to hosts.
+func (s *Service) appendTeamHosts(hosts []types.SSHHost, shadowed map[string]bool) []types.SSHHost {
+	for _, host := range s.sshManager.GetTeamHosts() {
+		if !shadowed[host.Alias] && s.sshManager.IsTeamHost(host.Alias) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,114 @@
+package sshgate
+
+import (
+	"strings"
+
+	"devtools/backend/internal/sshtunnel"
+	"devtools/backend/pkg/sshconfig"
+)
+
+// --- Keychain passwords of saved tunnels ---
+//
+// Tunnels to manual hosts store their password in the keychain under the tunnel ID;
+// tunnels to ssh_config hosts use the password stored under the host alias.
+
+// TunnelHasSavedPassword reports whether a password is stored in the keychain for the tunnel,
+// so the frontend can ask whether to copy it before duplicating the tunnel.
+func (s *Service) TunnelHasSavedPassword(id string) bool {
+	return s.sshManager.HasPassword(id)
+}
+
+// copyTunnelCredential copies the saved password of tunnel fromID to toID. Failures are only logged,
+// the user is prompted for the password again in that case.
+func (s *Service) copyTunnelCredential(fromID, toID string) {
+	copied, err := s.sshManager.CopyPassword(fromID, toID)
+	if err != nil {
+		logger.Printf("Warning: failed to copy the saved password of tunnel %s to %s: %v", fromID, toID, err)
+		return
+	}
+	if copied {
+		logger.Printf("Copied the saved password of tunnel %s to %s.", fromID, toID)
+	}
+}
+
+// rekeyTunnelCredential moves the saved password to the keychain entry the tunnel uses after its host
+// source changed: a manual host's password moves to the alias (unless the alias already has one), and
+// an alias's password is copied to the tunnel, since the host keeps using it.
+// A password is only carried over when the alias and the manual host resolve to the same server,
+// port and user; otherwise it would be sent to a server it was never meant for. In that case the
+// manual host's password is dropped and the user is asked for the new one on the next connection.
+func (s *Service) rekeyTunnelCredential(previous, current sshtunnel.SavedTunnelConfig) {
+	switch {
+	case previous.HostSource == "manual" && current.HostSource == "ssh_config":
+		if !s.sameEndpoint(previous.ManualHost, current.HostAlias) {
+			if !s.sshManager.HasPassword(current.ID) {
+				return
+			}
+			if err := s.sshManager.DeletePassword(current.ID); err != nil {
+				logger.Printf("Warning: failed to delete the saved password of tunnel %s: %v", current.ID, err)
+			}
+			logger.Printf("Host %s is a different server than tunnel %s used before, dropped the tunnel's saved password.", current.HostAlias, current.ID)
+			return
+		}
+		copied, err := s.sshManager.CopyPassword(current.ID, current.HostAlias)
+		if err != nil {
+			logger.Printf("Warning: failed to move the saved password of tunnel %s to host %s: %v", current.ID, current.HostAlias, err)
+			return
+		}
+		if !copied {
+			return
+		}
+		if err := s.sshManager.DeletePassword(current.ID); err != nil {
+			logger.Printf("Warning: failed to delete the saved password of tunnel %s after moving it: %v", current.ID, err)
+		}
+		logger.Printf("Moved the saved password of tunnel %s to host %s.", current.ID, current.HostAlias)
+	case previous.HostSource == "ssh_config" && current.HostSource == "manual":
+		if !s.sameEndpoint(current.ManualHost, previous.HostAlias) {
+			logger.Printf("Tunnel %s now uses a different server than host %s, not copying its saved password.", current.ID, previous.HostAlias)
+			return
+		}
+		copied, err := s.sshManager.CopyPassword(previous.HostAlias, current.ID)
+		if err != nil {
+			logger.Printf("Warning: failed to copy the saved password of host %s to tunnel %s: %v", previous.HostAlias, current.ID, err)
+			return
+		}
+		if copied {
+			logger.Printf("Copied the saved password of host %s to tunnel %s.", previous.HostAlias, current.ID)
+		}
+	}
+}
+
+// sameEndpoint reports whether the manual host and the ssh_config alias resolve to the same
+// HostName, Port and User, with ssh's defaults (port 22, the local user) filled in.
+func (s *Service) sameEndpoint(manual *sshtunnel.ManualHostInfo, alias string) bool {
+	if manual == nil {
+		return false
+	}
+	host, err := s.sshManager.GetSSHHostByAlias(alias)
+	if err != nil {
+		return false
+	}
+	hostName := host.HostName
+	if hostName == "" {
+		hostName = alias
+	}
+	return strings.EqualFold(strings.TrimSpace(manual.HostName), strings.TrimSpace(hostName)) &&
+		endpointPort(manual.Port) == endpointPort(host.Port) &&
+		endpointUser(manual.User) == endpointUser(host.User)
+}
+
+// endpointPort returns the port ssh connects to; an empty port means 22.
+func endpointPort(port string) string {
+	if port = strings.TrimSpace(port); port == "" {
+		return "22"
+	}
+	return port
+}
+
+// endpointUser returns the user ssh logs in as; an empty user means the local user (without the Windows domain).
+func endpointUser(name string) string {
+	if name = strings.TrimSpace(name); name == "" {
+		return sshconfig.LocalTokens().LocalUser
+	}
+	return name
+}
@@ -0,0 +1,190 @@
+package sshgate
+
+import (
+	"strconv"
+	"strings"
+
+	"devtools/backend/internal/i18n"
+	"devtools/backend/internal/sshtunnel"
+)
+
+// --- Tunnel config validation, run before SaveTunnelConfig writes tunnels.json ---
+
+// TunnelFieldError is a problem with one field of a tunnel config, shown next to that field in the dialog.
+type TunnelFieldError struct {
+	Field   string `json:"field"` // JSON name of the field, e.g. "localPort" or "manualHost.hostName"
+	Message string `json:"message"`
+	Warning bool   `json:"warning,omitempty"` // Warnings are shown but don't block saving
+}
+
+// TunnelValidationError is returned by SaveTunnelConfig when a field is invalid.
+type TunnelValidationError struct {
+	Fields []TunnelFieldError `json:"fields"` // Every problem found, including warnings
+}
+
+func (e *TunnelValidationError) Error() string {
+	var problems []string
+	for _, f := range e.Fields {
+		if !f.Warning {
+			problems = append(problems, f.Field+": "+f.Message)
+		}
+	}
+	return i18n.T("tunnel.invalid_config", strings.Join(problems, "; "))
+}
+
+// ValidateTunnelConfig checks a tunnel config without saving it, so the dialog can mark invalid
+// fields while the user edits. It returns an empty list when the config can be saved as is.
+func (s *Service) ValidateTunnelConfig(config sshtunnel.SavedTunnelConfig) []TunnelFieldError {
+	normalizeTunnelConfig(&config)
+	return s.validateTunnelConfig(config)
+}
+
+// normalizeTunnelConfig trims the user-entered text fields.
+func normalizeTunnelConfig(config *sshtunnel.SavedTunnelConfig) {
+	config.Name = strings.TrimSpace(config.Name)
+	config.RemoteHost = strings.TrimSpace(config.RemoteHost)
+	config.HostAlias = strings.TrimSpace(config.HostAlias)
+	if config.ManualHost != nil {
+		manual := *config.ManualHost
+		manual.HostName = strings.TrimSpace(manual.HostName)
+		manual.Port = strings.TrimSpace(manual.Port)
+		manual.User = strings.TrimSpace(manual.User)
+		config.ManualHost = &manual
+	}
+}
+
+// validateTunnelConfig checks the ports, the remote target and the host of a normalized config,
+// and compares it with the other saved tunnels.
+func (s *Service) validateTunnelConfig(config sshtunnel.SavedTunnelConfig) []TunnelFieldError {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	var previous *sshtunnel.SavedTunnelConfig
+	for i := range s.tunnelsConfig.Tunnels {
+		if s.tunnelsConfig.Tunnels[i].ID == config.ID {
+			previous = &s.tunnelsConfig.Tunnels[i]
+			break
+		}
+	}
+	return s.validateTunnelConfig_nolock(config, previous)
+}
+
+// validateTunnelConfig_nolock is validateTunnelConfig for a config whose forward was previously previous,
+// nil for a new tunnel. A duplicate forward that previous already had is only a warning, so renaming
+// one of two identical tunnels (e.g. right after duplicating) is not blocked. The caller must hold s.configMu.
+func (s *Service) validateTunnelConfig_nolock(config sshtunnel.SavedTunnelConfig, previous *sshtunnel.SavedTunnelConfig) []TunnelFieldError {
+	fields := []TunnelFieldError{}
+	fail := func(field, message string) {
+		fields = append(fields, TunnelFieldError{Field: field, Message: message})
+	}
+
+	switch config.TunnelType {
+	case "local":
+		if config.RemoteHost == "" {
+			fail("remoteHost", i18n.T("tunnel.remote_host_required"))
+		} else if strings.ContainsAny(config.RemoteHost, " \t") {
+			fail("remoteHost", i18n.T("tunnel.remote_host_invalid"))
+		}
+		if !validPort(config.RemotePort) {
+			fail("remotePort", i18n.T("tunnel.invalid_port"))
+		}
+	case "dynamic":
+	default:
+		fail("tunnelType", i18n.T("tunnel.invalid_type"))
+	}
+	if !validPort(config.LocalPort) {
+		fail("localPort", i18n.T("tunnel.invalid_port"))
+	}
+
+	switch config.HostSource {
+	case "ssh_config":
+		if config.HostAlias == "" {
+			fail("hostAlias", i18n.T("tunnel.alias_required"))
+		} else if !s.sshManager.HasHost(config.HostAlias) && !s.sshManager.IsIncludedHost(config.HostAlias) &&
+			!s.sshManager.IsTeamHost(config.HostAlias) && !s.sshManager.IsEphemeralHost(config.HostAlias) {
+			fail("hostAlias", i18n.T("tunnel.alias_not_found", config.HostAlias))
+		}
+	case "manual":
+		if config.ManualHost == nil {
+			fail("manualHost", i18n.T("tunnel.manual_host_missing"))
+			break
+		}
+		if config.ManualHost.HostName == "" {
+			fail("manualHost.hostName", i18n.T("tunnel.hostname_required"))
+		}
+		if config.ManualHost.Port != "" {
+			if port, err := strconv.Atoi(config.ManualHost.Port); err != nil || !validPort(port) {
+				fail("manualHost.port", i18n.T("tunnel.invalid_port"))
+			}
+		}
+	default:
+		fail("hostSource", i18n.T("tunnel.invalid_host_source"))
+	}
+
+	if !validPort(config.LocalPort) {
+		return fields
+	}
+	unchanged := previous != nil && sameForward(config, *previous)
+	for _, other := range s.tunnelsConfig.Tunnels {
+		if other.ID == config.ID || other.LocalPort != config.LocalPort {
+			continue
+		}
+		if sameForward(config, other) {
+			fields = append(fields, TunnelFieldError{
+				Field:   "localPort",
+				Message: i18n.T("tunnel.duplicate_forward", other.Name),
+				Warning: unchanged,
+			})
+			continue
+		}
+		// Tunnels sharing a port are fine as long as they don't run together, e.g. the same port on prod and staging.
+		fields = append(fields, TunnelFieldError{
+			Field:   "localPort",
+			Message: i18n.T("tunnel.port_shared", config.LocalPort, other.Name),
+			Warning: true,
+		})
+	}
+	return fields
+}
+
+// sameForward reports whether two tunnels forward the same local port to the same place through the same host.
+func sameForward(a, b sshtunnel.SavedTunnelConfig) bool {
+	if a.TunnelType != b.TunnelType || a.LocalPort != b.LocalPort || a.GatewayPorts != b.GatewayPorts || a.HostSource != b.HostSource {
+		return false
+	}
+	if a.TunnelType == "local" && (!strings.EqualFold(a.RemoteHost, b.RemoteHost) || a.RemotePort != b.RemotePort) {
+		return false
+	}
+	switch a.HostSource {
+	case "ssh_config":
+		return a.HostAlias == b.HostAlias
+	case "manual":
+		if a.ManualHost == nil || b.ManualHost == nil {
+			return a.ManualHost == b.ManualHost
+		}
+		return strings.EqualFold(a.ManualHost.HostName, b.ManualHost.HostName) &&
+			manualPort(a.ManualHost.Port) == manualPort(b.ManualHost.Port) && a.ManualHost.User == b.ManualHost.User
+	}
+	return false
+}
+
+// manualPort returns the SSH port of a manual host, an empty port meaning 22.
+func manualPort(port string) string {
+	if port == "" {
+		return "22"
+	}
+	return port
+}
+
+func validPort(port int) bool {
+	return port >= 1 && port <= 65535
+}
+
+// hasTunnelErrors reports whether any of the problems blocks saving.
+func hasTunnelErrors(fields []TunnelFieldError) bool {
+	for _, f := range fields {
+		if !f.Warning {
+			return true
+		}
+	}
+	return false
+}
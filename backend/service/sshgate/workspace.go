@@ -0,0 +1,42 @@
+package sshgate
+
+import (
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/sshtunnel"
+)
+
+// ReloadWorkspace is called when the active workspace changes. It stops every tunnel and pending
+// prompt of the previous workspace and reloads tunnels, policies, pre-connect commands, groups,
+// notes and colors from the new workspace's config directory. The local API, the team config
+// and the network monitor are not tied to a workspace and keep running.
+func (s *Service) ReloadWorkspace() {
+	s.cancelAllAuthChallenges()
+	s.tunnelManager.StopAll()
+
+	// Files that do not exist in the new workspace leave the state untouched when loading,
+	// so everything is reset to the defaults of NewService first.
+	s.configMu.Lock()
+	s.tunnelsConfig = &TunnelsConfig{Tunnels: []sshtunnel.SavedTunnelConfig{}}
+	s.configMu.Unlock()
+	s.policyMu.Lock()
+	s.sshManager.SetConnectionPolicies(sshmanager.ConnectionPolicies{Global: sshmanager.DefaultConnectionPolicy()})
+	s.policyMu.Unlock()
+	s.preConnectMu.Lock()
+	s.sshManager.SetPreConnectCommands(map[string]sshmanager.PreConnectCommand{})
+	s.preConnectMu.Unlock()
+	s.groupMu.Lock()
+	s.hostGroups = &HostGroupsConfig{Groups: []HostGroup{}}
+	s.groupMu.Unlock()
+	s.notesMu.Lock()
+	s.hostNotes = make(map[string]HostNotes)
+	s.notesMu.Unlock()
+	s.colorsMu.Lock()
+	s.hostColors = HostColorsConfig{Rules: defaultColorRules(), Hosts: map[string]string{}}
+	s.colorsMu.Unlock()
+	s.detectedPortsMu.Lock()
+	s.detectedPorts = make(map[string][]DetectedPort)
+	s.detectedPortsMu.Unlock()
+
+	s.loadWorkspaceConfigs()
+	s.debounceSavedTunnelsChangeEvent()
+}
@@ -0,0 +1,291 @@
+package terminal
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"devtools/backend/internal/sshmanager"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// x11AuthProtocol 是转发 X11 时使用的认证协议
+const x11AuthProtocol = "MIT-MAGIC-COOKIE-1"
+
+// sessionForwarding 是一个远程会话实际使用的转发设置
+type sessionForwarding struct {
+	agent       bool
+	agentSocket string
+	x11         bool
+}
+
+// resolveForwarding 合并主机在 ssh_config 中的转发设置与本次启动的选项
+func (s *Service) resolveForwarding(alias string, opts RemoteSessionOptions) sessionForwarding {
+	hostFwd := s.sshManager.ForwardingFor(alias)
+	fwd := sessionForwarding{agent: hostFwd.Agent, agentSocket: hostFwd.AgentSocket, x11: hostFwd.X11}
+	if opts.ForwardAgent != nil {
+		fwd.agent = *opts.ForwardAgent
+	}
+	if opts.ForwardX11 != nil {
+		fwd.x11 = *opts.ForwardX11
+	}
+	return fwd
+}
+
+// GetHostForwarding 返回主机的代理转发与 X11 转发设置
+func (s *Service) GetHostForwarding(alias string) sshmanager.ForwardingSettings {
+	return s.sshManager.ForwardingFor(alias)
+}
+
+// SetHostForwarding 保存主机的代理转发与 X11 转发设置（写入 ssh_config 的 ForwardAgent / ForwardX11）
+func (s *Service) SetHostForwarding(alias string, agent, x11 bool) error {
+	if _, err := s.sshManager.SetForwarding(alias, agent, x11); err != nil {
+		return err
+	}
+	logger.Printf("Saved forwarding for host %s (agent: %t, x11: %t).", alias, agent, x11)
+	return nil
+}
+
+// setupAgentForwarding 将远程会话的 ssh-agent 请求转发到本地代理。
+// socket 为空时自动选择本地代理（SSH_AUTH_SOCK；Windows 上为 OpenSSH 代理的命名管道或 Pageant）。
+func setupAgentForwarding(client *ssh.Client, session *ssh.Session, socket string) error {
+	conn, err := sshmanager.DialAgent(socket)
+	if err != nil {
+		return fmt.Errorf("cannot connect to local ssh-agent: %w", err)
+	}
+	if err := agent.ForwardToAgent(client, agent.NewClient(conn)); err != nil {
+		conn.Close()
+		return err
+	}
+	if err := agent.RequestAgentForwarding(session); err != nil {
+		conn.Close()
+		return err
+	}
+	// 连接关闭后释放本地代理连接
+	go func() {
+		_ = client.Wait()
+		conn.Close()
+	}()
+	return nil
+}
+
+// x11Request 是 "x11-req" 请求的负载（RFC 4254 6.3.1）
+type x11Request struct {
+	SingleConnection bool
+	AuthProtocol     string
+	AuthCookie       string
+	ScreenNumber     uint32
+}
+
+// setupX11Forwarding 请求 X11 转发，并把服务器打开的 x11 通道连接到本地的 X 服务器（DISPLAY）
+func setupX11Forwarding(client *ssh.Client, session *ssh.Session) error {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return fmt.Errorf("DISPLAY is not set; start an X server (e.g. XQuartz, VcXsrv) first")
+	}
+	network, address, screen, err := parseDisplay(display)
+	if err != nil {
+		return err
+	}
+
+	// 与 OpenSSH 一致，发给服务器的是随机生成的假 cookie，真正的 cookie 不离开本机：
+	// 服务器上的 root 用户能看到 x11-req 中的 cookie，拿到真 cookie 就能直接连接本地 X 服务器。
+	// 每个转发回来的 x11 通道先校验假 cookie，再在本地替换为真 cookie。
+	auth, err := newX11Auth(display)
+	if err != nil {
+		return err
+	}
+	channels := client.HandleChannelOpen("x11")
+	if channels == nil {
+		return fmt.Errorf("x11 channels are already handled for this connection")
+	}
+	ok, err := session.SendRequest("x11-req", true, ssh.Marshal(&x11Request{
+		AuthProtocol: x11AuthProtocol,
+		AuthCookie:   hex.EncodeToString(auth.fake),
+		ScreenNumber: screen,
+	}))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("server refused X11 forwarding (is X11Forwarding enabled in sshd_config?)")
+	}
+
+	go func() {
+		for ch := range channels {
+			go forwardX11Channel(ch, network, address, auth)
+		}
+	}()
+	return nil
+}
+
+// forwardX11Channel 将一个 x11 通道连接到本地 X 服务器并双向复制数据
+func forwardX11Channel(newCh ssh.NewChannel, network, address string, auth *x11Auth) {
+	local, err := net.Dial(network, address)
+	if err != nil {
+		logger.Printf("Warning: cannot connect to local X server %s: %v", address, err)
+		_ = newCh.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		local.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	// 第一个包是 X11 连接建立请求，其中带有 cookie
+	setup, err := rewriteX11Setup(ch, auth)
+	if err == nil {
+		_, err = local.Write(setup)
+	}
+	if err != nil {
+		logger.Printf("Warning: rejected X11 connection from server: %v", err)
+		ch.Close()
+		local.Close()
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(ch, local)
+		_ = ch.CloseWrite()
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(local, ch)
+		if c, ok := local.(interface{ CloseWrite() error }); ok {
+			_ = c.CloseWrite()
+		}
+	}()
+	wg.Wait()
+	ch.Close()
+	local.Close()
+}
+
+// parseDisplay 解析 DISPLAY，返回本地 X 服务器的网络类型、地址与屏幕号。
+// 支持 ":0"、"unix:0"（Unix 套接字）、"localhost:10.0"（TCP 6000+n）以及 XQuartz 的套接字路径。
+func parseDisplay(display string) (network, address string, screen uint32, err error) {
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return "", "", 0, fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	host, rest := display[:colon], display[colon+1:]
+	number, screenStr, _ := strings.Cut(rest, ".")
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	if screenStr != "" {
+		s, err := strconv.Atoi(screenStr)
+		if err != nil {
+			return "", "", 0, fmt.Errorf("invalid DISPLAY %q", display)
+		}
+		screen = uint32(s)
+	}
+
+	switch {
+	case strings.HasPrefix(host, "/"):
+		// XQuartz: /private/tmp/com.apple.launchd.xxx/org.xquartz:0
+		return "unix", host + ":" + number, screen, nil
+	case host == "" || host == "unix":
+		return "unix", "/tmp/.X11-unix/X" + number, screen, nil
+	default:
+		return "tcp", net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(6000+n)), screen, nil
+	}
+}
+
+// x11Auth 是一个会话的 X11 认证数据：fake 发给服务器，real 只在本地使用
+type x11Auth struct {
+	fake []byte
+	real []byte
+}
+
+// newX11Auth 读取本地 X 服务器的 cookie（来自 xauth）并生成同样长度的假 cookie。
+// 没有 xauth 时真 cookie 与假 cookie 相同，这时本地 X 服务器通常不要求认证。
+func newX11Auth(display string) (*x11Auth, error) {
+	cookie := localX11Cookie(display)
+	size := len(cookie)
+	if size == 0 {
+		size = 16
+	}
+	fake := make([]byte, size)
+	if _, err := rand.Read(fake); err != nil {
+		return nil, fmt.Errorf("failed to generate X11 cookie: %w", err)
+	}
+	if cookie == nil {
+		cookie = fake
+	}
+	return &x11Auth{fake: fake, real: cookie}, nil
+}
+
+// localX11Cookie 返回 xauth 中 display 的 MIT-MAGIC-COOKIE-1，找不到时返回 nil
+func localX11Cookie(display string) []byte {
+	out, err := exec.Command("xauth", "list", display).Output()
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[1] == x11AuthProtocol {
+			if cookie, err := hex.DecodeString(fields[2]); err == nil && len(cookie) > 0 {
+				return cookie
+			}
+		}
+	}
+	return nil
+}
+
+// rewriteX11Setup 读取 X11 连接建立请求，校验其中的假 cookie，返回替换为真 cookie 的请求。
+// 请求格式：字节序（'B' 或 'l'）、1 字节填充、主次版本号、认证协议名长度、认证数据长度、2 字节填充，
+// 然后是各自按 4 字节对齐的协议名与认证数据。
+func rewriteX11Setup(r io.Reader, auth *x11Auth) ([]byte, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read X11 setup: %w", err)
+	}
+	var order binary.ByteOrder
+	switch header[0] {
+	case 'B':
+		order = binary.BigEndian
+	case 'l':
+		order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("invalid X11 byte order %#x", header[0])
+	}
+	nameLen, dataLen := int(order.Uint16(header[6:8])), int(order.Uint16(header[8:10]))
+	body := make([]byte, x11Pad(nameLen)+x11Pad(dataLen))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("failed to read X11 setup: %w", err)
+	}
+	name := string(body[:nameLen])
+	data := body[x11Pad(nameLen) : x11Pad(nameLen)+dataLen]
+	if name != x11AuthProtocol || subtle.ConstantTimeCompare(data, auth.fake) != 1 {
+		return nil, fmt.Errorf("X11 authentication data does not match")
+	}
+
+	out := make([]byte, 0, len(header)+x11Pad(nameLen)+x11Pad(len(auth.real)))
+	out = append(out, header...)
+	order.PutUint16(out[8:10], uint16(len(auth.real)))
+	out = append(out, body[:x11Pad(nameLen)]...)
+	out = append(out, auth.real...)
+	return append(out, make([]byte, x11Pad(len(auth.real))-len(auth.real))...), nil
+}
+
+// x11Pad 返回 n 按 4 字节对齐后的长度
+func x11Pad(n int) int {
+	return (n + 3) &^ 3
+}
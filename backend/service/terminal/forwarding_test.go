@@ -0,0 +1,52 @@
+package terminal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// x11Setup 构造一个 X11 连接建立请求
+func x11Setup(order byte, name string, data []byte) []byte {
+	var bo binary.ByteOrder = binary.BigEndian
+	if order == 'l' {
+		bo = binary.LittleEndian
+	}
+	header := make([]byte, 12)
+	header[0] = order
+	bo.PutUint16(header[2:4], 11)
+	bo.PutUint16(header[6:8], uint16(len(name)))
+	bo.PutUint16(header[8:10], uint16(len(data)))
+	out := append(header, name...)
+	out = append(out, make([]byte, x11Pad(len(name))-len(name))...)
+	out = append(out, data...)
+	return append(out, make([]byte, x11Pad(len(data))-len(data))...)
+}
+
+// TestRewriteX11Setup 测试只有带假 cookie 的连接会被放行，并且发往本地的请求中是真 cookie
+func TestRewriteX11Setup(t *testing.T) {
+	auth := &x11Auth{fake: bytes.Repeat([]byte{0xaa}, 16), real: bytes.Repeat([]byte{0x55}, 16)}
+
+	for _, order := range []byte{'B', 'l'} {
+		got, err := rewriteX11Setup(bytes.NewReader(x11Setup(order, x11AuthProtocol, auth.fake)), auth)
+		if err != nil {
+			t.Fatalf("rewriteX11Setup(%c) failed: %v", order, err)
+		}
+		if want := x11Setup(order, x11AuthProtocol, auth.real); !bytes.Equal(got, want) {
+			t.Errorf("rewriteX11Setup(%c) = %x, want %x", order, got, want)
+		}
+	}
+
+	rejected := [][]byte{
+		x11Setup('B', x11AuthProtocol, auth.real),                         // 服务器不应该知道真 cookie
+		x11Setup('B', x11AuthProtocol, nil),                               // 没有认证数据
+		x11Setup('B', "XDM-AUTHORIZATION-1", auth.fake),                   // 其他协议
+		append([]byte{'x'}, x11Setup('B', x11AuthProtocol, auth.fake)...), // 字节序无效
+		x11Setup('B', x11AuthProtocol, auth.fake)[:20],                    // 被截断
+	}
+	for i, setup := range rejected {
+		if _, err := rewriteX11Setup(bytes.NewReader(setup), auth); err == nil {
+			t.Errorf("case %d: rewriteX11Setup should reject the setup request", i)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package terminal
+
+import (
+	"fmt"
+	"time"
+
+	"devtools/backend/internal/settings"
+	"devtools/backend/pkg/utils"
+)
+
+// idleCheckInterval 是检查远程会话是否空闲的间隔，空闲超时以分钟为单位，这个精度已经足够
+const idleCheckInterval = 15 * time.Second
+
+// IdleEvent 是 "terminal:idle" 事件的负载
+type IdleEvent struct {
+	SessionID   string `json:"sessionId"`
+	Alias       string `json:"alias"`
+	IdleSeconds int    `json:"idleSeconds"`
+	Action      string `json:"action"` // warn | command | close，见 settings.IdleAction*
+}
+
+// idleState 记录会话最近一次用户输入的时间，以及本次空闲是否已经触发过动作
+type idleState struct {
+	lastInput time.Time
+	fired     bool
+	// 本会话单独设置的空闲策略，为空时使用应用设置中的策略
+	override *settings.TerminalIdlePolicy
+}
+
+// touchInput 记录一次用户输入（键盘、鼠标或粘贴），重新开始计算空闲时间
+func (sess *Session) touchInput() {
+	sess.idleMu.Lock()
+	defer sess.idleMu.Unlock()
+	sess.idle.lastInput = time.Now()
+	sess.idle.fired = false
+}
+
+// idlePolicyFor 返回会话生效的空闲策略
+func (s *Service) idlePolicyFor(session *Session) settings.TerminalIdlePolicy {
+	session.idleMu.Lock()
+	override := session.idle.override
+	session.idleMu.Unlock()
+	if override != nil {
+		return *override
+	}
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.idlePolicy
+}
+
+// SetSessionIdlePolicy 为一个远程会话单独设置空闲策略；policy 为空时恢复使用应用设置中的策略
+func (s *Service) SetSessionIdlePolicy(sessionID string, policy *settings.TerminalIdlePolicy) error {
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		p := *policy
+		policy = &p
+	}
+	s.mu.RLock()
+	session, ok := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+	if session.localCmd != nil {
+		return fmt.Errorf("session %s is a local session, idle policies only apply to remote sessions", sessionID)
+	}
+
+	session.idleMu.Lock()
+	defer session.idleMu.Unlock()
+	session.idle.override = policy
+	session.idle.fired = false
+	return nil
+}
+
+// watchIdle 定期检查远程会话是否空闲，直到会话被清理
+func (s *Service) watchIdle(session *Session) {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-session.closed:
+			return
+		case now := <-ticker.C:
+			s.checkIdle(session, now)
+		}
+	}
+}
+
+// checkIdle 在会话超过空闲时间没有输入时执行一次空闲动作；之后要等到下一次输入才会重新计时。
+// 断开的会话不检测空闲。
+func (s *Service) checkIdle(session *Session, now time.Time) {
+	policy := s.idlePolicyFor(session)
+	if policy.TimeoutMinutes <= 0 {
+		return
+	}
+	session.connMu.Lock()
+	connected := session.status == StatusConnected
+	session.connMu.Unlock()
+	if !connected {
+		return
+	}
+
+	session.idleMu.Lock()
+	idle := now.Sub(session.idle.lastInput)
+	if session.idle.fired || idle < time.Duration(policy.TimeoutMinutes)*time.Minute {
+		session.idleMu.Unlock()
+		return
+	}
+	session.idle.fired = true
+	session.idleMu.Unlock()
+
+	logger.Printf("Remote session %s (%s) has been idle for %s, action: %s", session.ID, session.Alias, idle.Round(time.Second), policy.Action)
+	utils.EmitEvent(s.ctx, "terminal:idle", IdleEvent{
+		SessionID:   session.ID,
+		Alias:       session.Alias,
+		IdleSeconds: int(idle / time.Second),
+		Action:      policy.Action,
+	})
+
+	switch policy.Action {
+	case settings.IdleActionCommand:
+		ptyIn, _ := session.pipes()
+		if _, err := ptyIn.Write([]byte(policy.Command + "\r")); err != nil {
+			logger.Printf("Warning: failed to write idle command to session %s: %v", session.ID, err)
+		}
+	case settings.IdleActionClose:
+		s.cleanupSession(session.ID)
+	}
+}
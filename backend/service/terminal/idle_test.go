@@ -0,0 +1,52 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"devtools/backend/internal/settings"
+)
+
+type nopWriteCloser struct{ bytes.Buffer }
+
+func (*nopWriteCloser) Close() error { return nil }
+
+// TestCheckIdle 测试空闲动作在超时后只触发一次、输入后重新计时，以及会话单独设置的策略优先于应用设置
+func TestCheckIdle(t *testing.T) {
+	s := NewService(nil)
+	s.ApplySettings(settings.Settings{TerminalIdle: settings.TerminalIdlePolicy{TimeoutMinutes: 10, Action: settings.IdleActionCommand, Command: "clear"}})
+
+	ptyIn := &nopWriteCloser{}
+	start := time.Now()
+	session := &Session{ID: "s1", Alias: "web", ptyIn: ptyIn, status: StatusConnected, closed: make(chan struct{})}
+	session.idle.lastInput = start
+	s.sessions[session.ID] = session
+
+	s.checkIdle(session, start.Add(9*time.Minute))
+	if ptyIn.Len() != 0 {
+		t.Fatalf("the idle command should not run before the timeout: %q", ptyIn.String())
+	}
+	s.checkIdle(session, start.Add(11*time.Minute))
+	s.checkIdle(session, start.Add(30*time.Minute))
+	if got := ptyIn.String(); got != "clear\r" {
+		t.Fatalf("the idle command should run once per idle period, got %q", got)
+	}
+
+	session.touchInput()
+	s.checkIdle(session, time.Now().Add(11*time.Minute))
+	if got := ptyIn.String(); got != "clear\rclear\r" {
+		t.Fatalf("input should restart the idle timer, got %q", got)
+	}
+
+	if err := s.SetSessionIdlePolicy("s1", &settings.TerminalIdlePolicy{TimeoutMinutes: 1, Action: settings.IdleActionClose}); err != nil {
+		t.Fatal(err)
+	}
+	s.checkIdle(session, time.Now().Add(2*time.Minute))
+	if _, ok := s.sessions["s1"]; ok {
+		t.Error("the session's own close policy should close it")
+	}
+	if err := s.SetSessionIdlePolicy("s1", nil); err == nil {
+		t.Error("setting the policy of a closed session should fail")
+	}
+}
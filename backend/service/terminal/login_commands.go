@@ -0,0 +1,165 @@
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"devtools/backend/internal/settings"
+	"devtools/backend/internal/workspace"
+)
+
+// LoginCommand 是连接某个主机、远程 Shell 启动后自动写入终端的脚本，例如 "sudo -i" 或 "cd /var/log"。
+// 脚本中的 {{alias}}、{{user}}、{{host}} 会被替换为主机别名、登录用户名与主机地址。
+type LoginCommand struct {
+	Script  string `json:"script"`
+	Enabled bool   `json:"enabled"`
+}
+
+// loginCommandsFile 是 login_commands.json 的根对象
+type loginCommandsFile struct {
+	Hosts map[string]LoginCommand `json:"hosts"`
+}
+
+// RemoteSessionOptions 是启动远程会话时的可选项
+type RemoteSessionOptions struct {
+	SkipLoginCommand bool  `json:"skipLoginCommand"`       // 本次启动不执行主机的登录命令
+	ForwardAgent     *bool `json:"forwardAgent,omitempty"` // 本次启动是否转发 ssh-agent，为空时使用主机的 ForwardAgent 设置
+	ForwardX11       *bool `json:"forwardX11,omitempty"`   // 本次启动是否转发 X11，为空时使用主机的 ForwardX11 设置
+	// 本次启动使用的空闲策略，为空时使用应用设置中的策略
+	Idle *settings.TerminalIdlePolicy `json:"idle,omitempty"`
+}
+
+// loadLoginCommands 从当前工作区的配置目录加载各主机的登录命令（不写入 ssh_config）。
+// 登录命令按主机别名保存，不同工作区的同名主机可以是不同的机器，因此每个工作区各有一份。
+func (s *Service) loadLoginCommands() error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+
+	// 新工作区没有这个文件时不应沿用上一个工作区的登录命令
+	s.loginCommands = make(map[string]LoginCommand)
+	appConfigDir, err := workspace.ConfigDir()
+	if err != nil {
+		s.loginCommandsPath = ""
+		return err
+	}
+	s.loginCommandsPath = filepath.Join(appConfigDir, "login_commands.json")
+
+	data, err := os.ReadFile(s.loginCommandsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read login commands file: %w", err)
+	}
+
+	var file loginCommandsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to unmarshal login commands: %w", err)
+	}
+	if file.Hosts != nil {
+		s.loginCommands = file.Hosts
+	}
+	logger.Printf("Loaded login commands for %d hosts.", len(s.loginCommands))
+	return nil
+}
+
+// saveLoginCommands_nolock 将登录命令写入磁盘，调用方需持有 s.loginMu
+func (s *Service) saveLoginCommands_nolock() error {
+	if s.loginCommandsPath == "" {
+		return fmt.Errorf("login commands path is not initialized")
+	}
+	data, err := json.MarshalIndent(loginCommandsFile{Hosts: s.loginCommands}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal login commands: %w", err)
+	}
+	if err := os.WriteFile(s.loginCommandsPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write login commands file: %w", err)
+	}
+	return nil
+}
+
+// GetLoginCommands 返回所有主机的登录命令，key 为主机别名
+func (s *Service) GetLoginCommands() map[string]LoginCommand {
+	s.loginMu.RLock()
+	defer s.loginMu.RUnlock()
+	out := make(map[string]LoginCommand, len(s.loginCommands))
+	for alias, cmd := range s.loginCommands {
+		out[alias] = cmd
+	}
+	return out
+}
+
+// SaveLoginCommand 设置主机的登录命令；脚本为空时等同于删除
+func (s *Service) SaveLoginCommand(alias string, cmd LoginCommand) error {
+	if alias == "" {
+		return fmt.Errorf("host alias cannot be empty")
+	}
+	if strings.TrimSpace(cmd.Script) == "" {
+		return s.DeleteLoginCommand(alias)
+	}
+
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	s.loginCommands[alias] = cmd
+	return s.saveLoginCommands_nolock()
+}
+
+// DeleteLoginCommand 删除主机的登录命令
+func (s *Service) DeleteLoginCommand(alias string) error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	if _, ok := s.loginCommands[alias]; !ok {
+		return nil
+	}
+	delete(s.loginCommands, alias)
+	return s.saveLoginCommands_nolock()
+}
+
+// RenameLoginCommand 在主机改名后把登录命令移到新别名下，旧别名没有登录命令时不做任何事
+func (s *Service) RenameLoginCommand(oldAlias, newAlias string) error {
+	if newAlias == "" {
+		return fmt.Errorf("host alias cannot be empty")
+	}
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	cmd, ok := s.loginCommands[oldAlias]
+	if !ok || oldAlias == newAlias {
+		return nil
+	}
+	delete(s.loginCommands, oldAlias)
+	s.loginCommands[newAlias] = cmd
+	return s.saveLoginCommands_nolock()
+}
+
+// runLoginCommand 在远程 Shell 启动后写入主机的登录命令（如果已启用）。
+// Shell 尚未读取的输入会被缓冲，因此不需要等待提示符出现。
+func (s *Service) runLoginCommand(session *Session, shell *remoteShell) {
+	if session.skipLoginCommand {
+		return
+	}
+	s.loginMu.RLock()
+	cmd, ok := s.loginCommands[session.Alias]
+	s.loginMu.RUnlock()
+	if !ok || !cmd.Enabled {
+		return
+	}
+
+	script := strings.NewReplacer(
+		"{{alias}}", session.Alias,
+		"{{user}}", shell.config.User,
+		"{{host}}", shell.config.HostName,
+	).Replace(cmd.Script)
+	script = strings.ReplaceAll(script, "\r\n", "\n")
+	if !strings.HasSuffix(script, "\n") {
+		script += "\n"
+	}
+
+	if _, err := shell.ptyIn.Write([]byte(script)); err != nil {
+		logger.Printf("Warning: failed to send login command for session %s (%s): %v", session.ID, session.Alias, err)
+		return
+	}
+	logger.Printf("Sent login command to session %s (%s).", session.ID, session.Alias)
+}
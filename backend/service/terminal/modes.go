@@ -0,0 +1,221 @@
+package terminal
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// remotePtyModes 是请求远程 PTY 时使用的终端模式。
+// 输入必须是 8 位透明的：X10/normal 编码的鼠标事件用 32+坐标 的单字节表示列号，超过 95 列时最高位为 1，
+// 如果服务器的 PTY 开启了 ISTRIP 或使用 7 位字符，鼠标点击会落到错误的位置。
+var remotePtyModes = ssh.TerminalModes{
+	ssh.ECHO:          1,
+	ssh.ICRNL:         1,
+	ssh.ISTRIP:        0,
+	ssh.CS8:           1,
+	ssh.PARENB:        0,
+	ssh.IUTF8:         1,    // UTF-8 编码（DECSET 1005）的鼠标坐标与中文输入按字符擦除
+	ssh.VERASE:        0x7f, // xterm.js 的退格键发送 DEL
+	ssh.TTY_OP_ISPEED: 38400,
+	ssh.TTY_OP_OSPEED: 38400,
+}
+
+// 鼠标跟踪模式（DECSET 9/1000/1002/1003）
+const (
+	MouseOff    = "off"
+	MouseX10    = "x10"    // 9：只报告按下
+	MouseNormal = "normal" // 1000：报告按下与释放
+	MouseButton = "button" // 1002：另外报告按住按键时的移动
+	MouseAny    = "any"    // 1003：报告所有移动
+)
+
+// 鼠标坐标编码（DECSET 1005/1006/1015/1016）
+const (
+	MouseEncodingDefault   = "default"
+	MouseEncodingUTF8      = "utf8"
+	MouseEncodingSGR       = "sgr"
+	MouseEncodingURXVT     = "urxvt"
+	MouseEncodingSGRPixels = "sgr-pixels"
+)
+
+// 退出各模式的控制序列
+const (
+	mouseTrackingOff   = "\x1b[?1003l\x1b[?1002l\x1b[?1000l\x1b[?9l"
+	mouseEncodingOff   = "\x1b[?1016l\x1b[?1015l\x1b[?1006l\x1b[?1005l"
+	alternateScreenOff = "\x1b[?1049l"
+)
+
+var mouseTrackingModes = map[int]string{9: MouseX10, 1000: MouseNormal, 1002: MouseButton, 1003: MouseAny}
+
+var mouseEncodingModes = map[int]string{
+	1005: MouseEncodingUTF8, 1006: MouseEncodingSGR, 1015: MouseEncodingURXVT, 1016: MouseEncodingSGRPixels,
+}
+
+// TerminalModes 是远程程序通过 DECSET/DECRST 打开的、影响鼠标与屏幕的模式，用于排查 TUI 程序的显示问题
+type TerminalModes struct {
+	MouseTracking   string `json:"mouseTracking"`
+	MouseEncoding   string `json:"mouseEncoding"`
+	AlternateScreen bool   `json:"alternateScreen"` // DECSET 47/1047/1049
+}
+
+// 识别 DECSET/DECRST 序列时的解析状态
+const (
+	modeGround  = iota // 普通输出
+	modeEscape         // 刚读到 ESC
+	modeCSI            // ESC [ 之后，还没有参数
+	modePrivate        // ESC [ ? 之后，正在收集参数
+	modeSkip           // 其他 CSI 序列，跳过直到结束字节
+)
+
+// maxModeParamsLength 是 DECSET 参数的最大长度，超出后放弃解析
+const maxModeParamsLength = 64
+
+// modeTracker 从 PTY 输出中识别 DECSET/DECRST（ESC [ ? Pm h/l）与 RIS（ESC c），跟踪鼠标与备用屏幕模式。
+// 它只观察数据而不修改数据，能处理被拆到多次 Read 中的序列。零值即可使用。
+type modeTracker struct {
+	mu        sync.Mutex
+	state     int
+	params    []byte
+	mouse     string
+	encoding  string
+	altScreen bool
+}
+
+// Feed 处理一段输出，更新模式状态
+func (t *modeTracker) Feed(data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, b := range data {
+		switch t.state {
+		case modeGround:
+			if b == 0x1b {
+				t.state = modeEscape
+			}
+		case modeEscape:
+			switch b {
+			case '[':
+				t.state = modeCSI
+			case 'c': // RIS：完全重置终端
+				t.reset_nolock()
+				t.state = modeGround
+			case 0x1b:
+				// 连续的 ESC，保持状态
+			default:
+				t.state = modeGround
+			}
+		case modeCSI:
+			if b == '?' {
+				t.state = modePrivate
+				t.params = t.params[:0]
+			} else {
+				t.skip(b)
+			}
+		case modePrivate:
+			switch {
+			case b >= '0' && b <= '9', b == ';':
+				if len(t.params) >= maxModeParamsLength {
+					t.state = modeSkip
+					continue
+				}
+				t.params = append(t.params, b)
+			case b == 'h', b == 'l':
+				t.apply_nolock(string(t.params), b == 'h')
+				t.state = modeGround
+			default:
+				t.skip(b)
+			}
+		case modeSkip:
+			t.skip(b)
+		}
+	}
+}
+
+// skip 跳过 CSI 序列中的一个字节，读到结束字节（0x40-0x7e）时回到普通状态
+func (t *modeTracker) skip(b byte) {
+	switch {
+	case b == 0x1b:
+		t.state = modeEscape
+	case b >= 0x40 && b <= 0x7e:
+		t.state = modeGround
+	default:
+		t.state = modeSkip
+	}
+}
+
+// apply_nolock 应用一个 DECSET（set=true）或 DECRST 序列的参数，调用方需持有 t.mu。
+// 与 xterm 一致：设置任一跟踪模式会替换当前模式，重置任一跟踪模式都会关闭鼠标跟踪。
+func (t *modeTracker) apply_nolock(params string, set bool) {
+	for _, p := range strings.Split(params, ";") {
+		mode, err := strconv.Atoi(p)
+		if err != nil {
+			continue
+		}
+		if tracking, ok := mouseTrackingModes[mode]; ok {
+			t.mouse = ""
+			if set {
+				t.mouse = tracking
+			}
+		}
+		if encoding, ok := mouseEncodingModes[mode]; ok {
+			if set {
+				t.encoding = encoding
+			} else if t.encoding == encoding {
+				t.encoding = ""
+			}
+		}
+		if mode == 47 || mode == 1047 || mode == 1049 {
+			t.altScreen = set
+		}
+	}
+}
+
+func (t *modeTracker) reset_nolock() {
+	t.mouse, t.encoding, t.altScreen = "", "", false
+}
+
+// Modes 返回当前的模式
+func (t *modeTracker) Modes() TerminalModes {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	modes := TerminalModes{MouseTracking: MouseOff, MouseEncoding: MouseEncodingDefault, AlternateScreen: t.altScreen}
+	if t.mouse != "" {
+		modes.MouseTracking = t.mouse
+	}
+	if t.encoding != "" {
+		modes.MouseEncoding = t.encoding
+	}
+	return modes
+}
+
+// Reset 清除所有模式，并返回让前端终端退出这些模式的控制序列；没有打开任何模式时返回 nil。
+// 远程连接断开时，打开鼠标跟踪的程序已经不存在，如果前端仍然报告鼠标事件，这些字节会作为输入发给重新连接后的 shell。
+func (t *modeTracker) Reset() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var seq strings.Builder
+	if t.mouse != "" {
+		seq.WriteString(mouseTrackingOff)
+	}
+	if t.encoding != "" {
+		seq.WriteString(mouseEncodingOff)
+	}
+	if t.altScreen {
+		seq.WriteString(alternateScreenOff)
+	}
+	t.reset_nolock()
+	t.state = modeGround
+	if seq.Len() == 0 {
+		return nil
+	}
+	return []byte(seq.String())
+}
+
+// isControlMessage 判断一条 WebSocket 消息是否可能是 JSON 控制消息（resize、paste）。
+// 其余消息是原始输入，按原样写入 PTY：鼠标报告在任意移动模式下非常频繁，不应逐条尝试 JSON 解码。
+func isControlMessage(message []byte) bool {
+	return len(message) > 0 && message[0] == '{'
+}
@@ -0,0 +1,31 @@
+package terminal
+
+import "testing"
+
+// TestModeTracker 测试识别被拆开的 DECSET 序列、组合参数、DECRST 与 RIS，以及断线时生成的退出序列
+func TestModeTracker(t *testing.T) {
+	var tracker modeTracker
+	tracker.Feed([]byte("\x1b[?104"))
+	tracker.Feed([]byte("9h\x1b[?1002;1006h\x1b[1;31mtext"))
+	if got := tracker.Modes(); got != (TerminalModes{MouseTracking: MouseButton, MouseEncoding: MouseEncodingSGR, AlternateScreen: true}) {
+		t.Fatalf("unexpected modes after DECSET: %+v", got)
+	}
+
+	tracker.Feed([]byte("\x1b[?1000l"))
+	if got := tracker.Modes(); got.MouseTracking != MouseOff || got.MouseEncoding != MouseEncodingSGR {
+		t.Errorf("resetting any tracking mode should turn mouse tracking off: %+v", got)
+	}
+
+	tracker.Feed([]byte("\x1b[?1003h"))
+	if seq := string(tracker.Reset()); seq != mouseTrackingOff+mouseEncodingOff+alternateScreenOff {
+		t.Errorf("Reset() = %q", seq)
+	}
+	if tracker.Reset() != nil {
+		t.Error("Reset() should return nil when no mode is on")
+	}
+
+	tracker.Feed([]byte("\x1b[?1003h\x1bc"))
+	if got := tracker.Modes(); got.MouseTracking != MouseOff {
+		t.Errorf("RIS should reset all modes: %+v", got)
+	}
+}
@@ -0,0 +1,93 @@
+package terminal
+
+import "strings"
+
+// maxOSCLength 是单个 OSC 序列的最大长度，超出后放弃解析，避免异常输出占用内存
+const maxOSCLength = 4096
+
+const (
+	oscGround       = iota // 普通输出
+	oscEscape              // 刚读到 ESC
+	oscString              // 位于 ESC ] 之后，正在收集 OSC 内容
+	oscStringEscape        // OSC 内容中读到 ESC，可能是 ST（ESC \）
+)
+
+// oscParser 从 PTY 输出流中识别 OSC 序列（ESC ] ... BEL 或 ST）。
+// 它只观察数据而不修改数据，并能处理跨多次 Read 被拆开的序列。
+type oscParser struct {
+	state int
+	buf   []byte
+}
+
+// Feed 处理一段输出，对其中每个完整的 OSC 序列按出现顺序调用 fn。
+// payload 是 ESC ] 与结束符之间的内容，end 是结束符之后的字节在 data 中的偏移。
+func (p *oscParser) Feed(data []byte, fn func(payload string, end int)) {
+	for i, b := range data {
+		switch p.state {
+		case oscGround:
+			if b == 0x1b {
+				p.state = oscEscape
+			}
+		case oscEscape:
+			switch b {
+			case ']':
+				p.state = oscString
+				p.buf = p.buf[:0]
+			case 0x1b:
+				// 连续的 ESC，保持状态
+			default:
+				p.state = oscGround
+			}
+		case oscString:
+			switch b {
+			case 0x07: // BEL 结束
+				p.finish(fn, i+1)
+			case 0x1b:
+				p.state = oscStringEscape
+			default:
+				if len(p.buf) >= maxOSCLength {
+					p.state = oscGround
+					p.buf = p.buf[:0]
+					continue
+				}
+				p.buf = append(p.buf, b)
+			}
+		case oscStringEscape:
+			if b == '\\' { // ST 结束
+				p.finish(fn, i+1)
+				continue
+			}
+			// 序列被另一个转义序列打断，按新的 ESC 重新开始
+			p.buf = p.buf[:0]
+			p.state = oscEscape
+			if b == ']' {
+				p.state = oscString
+			}
+		}
+	}
+}
+
+// finish 结束当前 OSC 序列并交给 fn
+func (p *oscParser) finish(fn func(payload string, end int), end int) {
+	p.state = oscGround
+	payload := string(p.buf)
+	p.buf = p.buf[:0]
+	fn(payload, end)
+}
+
+// titleParser 从 PTY 输出流中识别 OSC 0/2（设置窗口标题）序列
+type titleParser struct {
+	osc oscParser
+}
+
+// Feed 处理一段输出，返回其中完整出现的标题（按出现顺序）
+func (p *titleParser) Feed(data []byte) []string {
+	var titles []string
+	p.osc.Feed(data, func(payload string, _ int) {
+		ps, pt, ok := strings.Cut(payload, ";")
+		if ok && (ps == "0" || ps == "2") {
+			titles = append(titles, pt)
+		}
+	})
+	return titles
+}
@@ -0,0 +1,59 @@
+package terminal
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestTitleParser 测试识别 BEL 与 ST 结束的 OSC 0/2 标题序列，包括被拆到多次 Read 中的序列、
+// 被其他转义序列打断的序列以及超长序列
+func TestTitleParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   []string
+	}{
+		{"bel", []string{"\x1b]0;hello\x07"}, []string{"hello"}},
+		{"st", []string{"\x1b]2;user@web: ~\x1b\\"}, []string{"user@web: ~"}},
+		{"surrounding output", []string{"ls\r\n\x1b]0;a\x07out\x1b]2;b\x1b\\$ "}, []string{"a", "b"}},
+		{"split before bracket", []string{"\x1b", "]0;title\x07"}, []string{"title"}},
+		{"split in payload", []string{"\x1b]0;ti", "t", "le\x07"}, []string{"title"}},
+		{"split inside st", []string{"\x1b]2;x\x1b", "\\rest"}, []string{"x"}},
+		{"empty title", []string{"\x1b]0;\x07"}, []string{""}},
+		{"icon name ignored", []string{"\x1b]1;icon\x07"}, nil},
+		{"other osc ignored", []string{"\x1b]7;file://web/home\x07\x1b]133;A\x07"}, nil},
+		{"no parameter", []string{"\x1b]0\x07"}, nil},
+		{"repeated escape", []string{"\x1b\x1b]0;t\x07"}, []string{"t"}},
+		{"interrupted by csi", []string{"\x1b]0;ab\x1b[31m\x1b]0;ok\x07"}, []string{"ok"}},
+		{"restarted osc", []string{"\x1b]0;ab\x1b]2;ok\x1b\\"}, []string{"ok"}},
+		{"unterminated", []string{"\x1b]0;never ends"}, nil},
+		{"too long", []string{"\x1b]0;" + strings.Repeat("x", maxOSCLength) + "\x07", "\x1b]0;next\x07"}, []string{"next"}},
+		{"at the limit", []string{"\x1b]0;" + strings.Repeat("x", maxOSCLength-2), "\x07"}, []string{strings.Repeat("x", maxOSCLength-2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p titleParser
+			var got []string
+			for _, chunk := range tt.chunks {
+				got = append(got, p.Feed([]byte(chunk))...)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("titles = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOSCParserEnd 测试回调中的 end 是结束符之后的字节在本次数据中的偏移
+func TestOSCParserEnd(t *testing.T) {
+	var p oscParser
+	var ends []int
+	record := func(_ string, end int) { ends = append(ends, end) }
+
+	p.Feed([]byte("ab\x1b]0;t\x07cd\x1b]2;u"), record)
+	p.Feed([]byte("v\x1b\\ef"), record)
+	if !slices.Equal(ends, []int{8, 3}) {
+		t.Errorf("ends = %v, want [8 3]", ends)
+	}
+}
@@ -0,0 +1,105 @@
+package terminal
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// outputReadSize 是每次从 PTY 读取的最大字节数
+	outputReadSize = 32 * 1024
+	// outputFlushInterval 是合并输出的等待时间：收到输出后最多等待这么久，把期间的后续输出合并为一帧发送
+	outputFlushInterval = 5 * time.Millisecond
+	// outputMaxFrameSize 是一帧的大小上限，达到后立即发送
+	outputMaxFrameSize = 64 * 1024
+	// outputQueueSize 是等待发送的读取块数量上限。队列满时读取 PTY 的循环会阻塞，
+	// 由 PTY（或 SSH 通道）的流量控制让输出方暂停，而不是在内存中堆积
+	outputQueueSize = 16
+	// outputWriteTimeout 是发送一帧的超时，前端长时间不接收时放弃该连接
+	outputWriteTimeout = 10 * time.Second
+)
+
+// outputCoalescer 把 PTY 的输出合并为较大的 WebSocket 帧发送。
+// 例如 cat 一个大文件时，PTY 每次只返回几 KB，逐块发送会产生成千上万个小帧，使前端忙于处理消息而卡顿。
+type outputCoalescer struct {
+	send   func([]byte) error // 发送一帧
+	chunks chan []byte
+	done   chan struct{} // 发送循环退出时关闭
+	err    error         // 发送失败的错误，done 关闭后可读
+	once   sync.Once
+}
+
+// newOutputCoalescer 创建并启动输出合并器，send 在单独的 goroutine 中被调用
+func newOutputCoalescer(send func([]byte) error) *outputCoalescer {
+	c := &outputCoalescer{
+		send:   send,
+		chunks: make(chan []byte, outputQueueSize),
+		done:   make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// Write 把一块输出加入发送队列，队列已满时阻塞直到有空位。发送已经失败时返回该错误。
+func (c *outputCoalescer) Write(data []byte) error {
+	chunk := append([]byte(nil), data...)
+	select {
+	case c.chunks <- chunk:
+		return nil
+	case <-c.done:
+		return c.err
+	}
+}
+
+// Close 发送队列中剩余的输出并停止发送循环，返回发送失败的错误
+func (c *outputCoalescer) Close() error {
+	c.once.Do(func() { close(c.chunks) })
+	<-c.done
+	return c.err
+}
+
+// run 是发送循环：取到一块输出后，在 outputFlushInterval 内继续合并后续的输出，直到帧达到大小上限
+func (c *outputCoalescer) run() {
+	defer close(c.done)
+	frame := make([]byte, 0, outputMaxFrameSize)
+	timer := time.NewTimer(outputFlushInterval)
+	timer.Stop()
+	for {
+		chunk, ok := <-c.chunks
+		if !ok {
+			return
+		}
+		frame = append(frame[:0], chunk...)
+
+		open := true
+		timer.Reset(outputFlushInterval)
+	collect:
+		for len(frame) < outputMaxFrameSize {
+			select {
+			case chunk, ok := <-c.chunks:
+				if !ok {
+					open = false
+					break collect
+				}
+				frame = append(frame, chunk...)
+			case <-timer.C:
+				break collect
+			}
+		}
+		if !timer.Stop() {
+			// 计时器已经触发：如果事件还没有被取走，清空它，以便下一次 Reset
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		if err := c.send(frame); err != nil {
+			c.err = err
+			return
+		}
+		if !open {
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package terminal
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// TestOutputCoalescer 测试连续的小块输出被合并为少量的帧，帧不超过大小上限，内容与顺序保持不变
+func TestOutputCoalescer(t *testing.T) {
+	var mu sync.Mutex
+	var frames [][]byte
+	c := newOutputCoalescer(func(frame []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		frames = append(frames, append([]byte(nil), frame...))
+		return nil
+	})
+
+	var want bytes.Buffer
+	chunk := bytes.Repeat([]byte("x"), 1024)
+	for i := 0; i < 200; i++ {
+		chunk[0] = byte('a' + i%26)
+		want.Write(chunk)
+		if err := c.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(frames) >= 200 {
+		t.Errorf("expected output to be coalesced, got %d frames", len(frames))
+	}
+	var got bytes.Buffer
+	for _, frame := range frames {
+		if len(frame) > outputMaxFrameSize+len(chunk) {
+			t.Errorf("frame of %d bytes exceeds the limit", len(frame))
+		}
+		got.Write(frame)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Error("coalesced output differs from the input")
+	}
+}
+
+// TestOutputCoalescer_SendError 测试发送失败后 Write 与 Close 返回该错误，而不是阻塞
+func TestOutputCoalescer_SendError(t *testing.T) {
+	boom := errors.New("boom")
+	c := newOutputCoalescer(func([]byte) error { return boom })
+	for i := 0; i < outputQueueSize*4; i++ {
+		if err := c.Write([]byte("data")); err != nil {
+			if !errors.Is(err, boom) {
+				t.Fatalf("Write returned %v", err)
+			}
+			break
+		}
+	}
+	if err := c.Close(); !errors.Is(err, boom) {
+		t.Errorf("Close returned %v", err)
+	}
+}
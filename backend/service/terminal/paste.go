@@ -0,0 +1,196 @@
+package terminal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"devtools/backend/internal/settings"
+	"devtools/backend/pkg/utils"
+)
+
+// 大段粘贴按块写入 PTY，块之间稍作停顿，避免一次写入数 MB 时远程 PTY 的输入缓冲被塞满导致会话卡死
+const (
+	pasteChunkSize  = 4096
+	pasteChunkDelay = 5 * time.Millisecond
+)
+
+// bracketed paste 模式（DECSET 2004）相关的控制序列
+const (
+	bracketedPasteOn    = "\x1b[?2004h"
+	bracketedPasteOff   = "\x1b[?2004l"
+	bracketedPasteStart = "\x1b[200~"
+	bracketedPasteEnd   = "\x1b[201~"
+)
+
+// errPasteCanceled 表示粘贴被新的输入中止
+var errPasteCanceled = errors.New("paste canceled by new input")
+
+// pasteJob 是一次在后台分块写入的粘贴
+type pasteJob struct {
+	cancel chan struct{} // 关闭时在下一块之前停止写入
+	done   chan struct{} // 写入结束后关闭
+}
+
+// pasteMessage 是前端通过 WebSocket 发送的粘贴消息：
+// {"type":"paste","id":"...","data":"...","confirmed":false}
+type pasteMessage struct {
+	Type      string `json:"type"`
+	ID        string `json:"id,omitempty"`
+	Data      string `json:"data"`
+	Confirmed bool   `json:"confirmed"` // 用户已确认超大粘贴
+}
+
+// PasteConfirmEvent 是 "terminal:paste_confirm" 事件的负载。前端确认后应以 confirmed=true 重新发送同一粘贴。
+type PasteConfirmEvent struct {
+	SessionID string `json:"sessionId"`
+	PasteID   string `json:"pasteId,omitempty"`
+	Size      int    `json:"size"`
+	Limit     int    `json:"limit"`
+}
+
+// pasteModeTracker 从 PTY 输出中识别远程程序是否开启了 bracketed paste 模式，
+// 能处理被拆到两次 Read 中的控制序列
+type pasteModeTracker struct {
+	enabled bool
+	tail    []byte
+}
+
+// Feed 处理一段输出，更新模式状态
+func (t *pasteModeTracker) Feed(data []byte) {
+	buf := append(t.tail, data...)
+	on := bytes.LastIndex(buf, []byte(bracketedPasteOn))
+	off := bytes.LastIndex(buf, []byte(bracketedPasteOff))
+	if on > off {
+		t.enabled = true
+	} else if off > on {
+		t.enabled = false
+	}
+	keep := min(len(buf), len(bracketedPasteOn)-1)
+	t.tail = append(t.tail[:0], buf[len(buf)-keep:]...)
+}
+
+// ApplySettings 应用与终端相关的设置
+func (s *Service) ApplySettings(cfg settings.Settings) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.maxPasteBytes = cfg.TerminalMaxPasteBytes
+	s.bracketedPaste = cfg.TerminalBracketedPaste
+	s.shellIntegration = cfg.TerminalShellIntegration
+	s.idlePolicy = cfg.TerminalIdle
+}
+
+func (s *Service) pasteSettings() (maxBytes int, bracketed bool) {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.maxPasteBytes, s.bracketedPaste
+}
+
+// trackPasteMode 记录会话输出中 bracketed paste 模式的变化
+func (s *Service) trackPasteMode(session *Session, data []byte) {
+	session.pasteMu.Lock()
+	session.pasteMode.Feed(data)
+	session.pasteMu.Unlock()
+}
+
+// handlePaste 处理一条粘贴消息：超过大小限制且未确认时通知前端确认，否则按需包裹后在后台分块写入 PTY。
+// 写入不阻塞 WebSocket 的读取循环，因此之后的输入（包括新的粘贴）可以中止它。
+func (s *Service) handlePaste(session *Session, msg pasteMessage) {
+	maxBytes, bracketed := s.pasteSettings()
+	if maxBytes > 0 && len(msg.Data) > maxBytes && !msg.Confirmed {
+		logger.Printf("Paste of %d bytes into session %s exceeds the %d byte limit, asking for confirmation", len(msg.Data), session.ID, maxBytes)
+		utils.EmitEvent(s.ctx, "terminal:paste_confirm", PasteConfirmEvent{
+			SessionID: session.ID,
+			PasteID:   msg.ID,
+			Size:      len(msg.Data),
+			Limit:     maxBytes,
+		})
+		return
+	}
+
+	data := msg.Data
+	session.pasteMu.Lock()
+	wrap := bracketed && session.pasteMode.enabled
+	session.pasteMu.Unlock()
+	if wrap {
+		data = wrapBracketedPaste(data)
+	}
+	s.startPaste(session, []byte(data), wrap)
+}
+
+// wrapBracketedPaste 用 bracketed paste 序列包裹粘贴内容。
+// 反复去掉内容中的结束序列直到不再出现，防止嵌套的序列（如 "\x1b[201\x1b[201~~"）在去掉一次后重新组成结束序列，
+// 使粘贴提前结束，其余内容被当作命令执行。
+func wrapBracketedPaste(data string) string {
+	for strings.Contains(data, bracketedPasteEnd) {
+		data = strings.ReplaceAll(data, bracketedPasteEnd, "")
+	}
+	return bracketedPasteStart + data + bracketedPasteEnd
+}
+
+// startPaste 中止会话中仍在进行的粘贴，然后在后台分块写入 data。
+// 包裹了 bracketed paste 序列的粘贴被中止时补上结束序列，使远程程序退出粘贴状态。
+func (s *Service) startPaste(session *Session, data []byte, wrapped bool) {
+	session.cancelPaste()
+
+	job := &pasteJob{cancel: make(chan struct{}), done: make(chan struct{})}
+	session.pasteMu.Lock()
+	session.pasting = job
+	session.pasteMu.Unlock()
+
+	go func() {
+		defer close(job.done)
+		err := s.writeChunked(session, data, job.cancel)
+		if errors.Is(err, errPasteCanceled) && wrapped {
+			ptyIn, _ := session.pipes()
+			_, _ = ptyIn.Write([]byte(bracketedPasteEnd))
+		}
+		if err != nil {
+			logger.Printf("Error pasting into session %s: %v", session.ID, err)
+		}
+
+		session.pasteMu.Lock()
+		if session.pasting == job {
+			session.pasting = nil
+		}
+		session.pasteMu.Unlock()
+	}()
+}
+
+// cancelPaste 中止会话中正在进行的粘贴，并等待已开始的那一块写完，之后的写入不会与粘贴内容交错
+func (sess *Session) cancelPaste() {
+	sess.pasteMu.Lock()
+	job := sess.pasting
+	sess.pasting = nil
+	sess.pasteMu.Unlock()
+	if job == nil {
+		return
+	}
+	close(job.cancel)
+	<-job.done
+}
+
+// writeChunked 将 data 分块写入会话的 PTY。会话关闭或 cancel 被关闭时停止写入。
+func (s *Service) writeChunked(session *Session, data []byte, cancel <-chan struct{}) error {
+	for len(data) > 0 {
+		n := min(len(data), pasteChunkSize)
+		ptyIn, _ := session.pipes()
+		if _, err := ptyIn.Write(data[:n]); err != nil {
+			return fmt.Errorf("paste interrupted with %d bytes left: %w", len(data), err)
+		}
+		data = data[n:]
+		if len(data) == 0 {
+			break
+		}
+		select {
+		case <-session.closed:
+			return fmt.Errorf("session closed during paste")
+		case <-cancel:
+			return fmt.Errorf("%w with %d bytes left", errPasteCanceled, len(data))
+		case <-time.After(pasteChunkDelay):
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+
+	"devtools/backend/internal/settings"
+)
+
+// TestWrapBracketedPaste 测试包裹粘贴内容时去掉其中的结束序列，包括去掉一次后重新组成的嵌套序列
+func TestWrapBracketedPaste(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"plain", "ls -la\n", "ls -la\n"},
+		{"end sequence", "echo hi\x1b[201~; rm -rf x\n", "echo hi; rm -rf x\n"},
+		{"nested end sequence", "\x1b[201\x1b[201~~; rm -rf x\n", "; rm -rf x\n"},
+		{"deeply nested", "\x1b[201\x1b[201\x1b[201~~~ok", "ok"},
+		{"start sequence kept", "\x1b[200~text", "\x1b[200~text"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapBracketedPaste(tt.data)
+			if got != bracketedPasteStart+tt.want+bracketedPasteEnd {
+				t.Errorf("wrapBracketedPaste(%q) = %q, want the content %q", tt.data, got, tt.want)
+			}
+			inner := strings.TrimSuffix(strings.TrimPrefix(got, bracketedPasteStart), bracketedPasteEnd)
+			if strings.Contains(inner, bracketedPasteEnd) {
+				t.Errorf("the wrapped content still contains the end sequence: %q", inner)
+			}
+		})
+	}
+}
+
+// TestHandlePaste 测试远程程序开启 bracketed paste 时粘贴被包裹，未开启或设置关闭时原样写入
+func TestHandlePaste(t *testing.T) {
+	tests := []struct {
+		name      string
+		bracketed bool // 设置中的 bracketed paste
+		modeOn    bool // 远程程序开启了 bracketed paste 模式
+		data      string
+		want      string
+	}{
+		{"wrapped", true, true, "ls\n", bracketedPasteStart + "ls\n" + bracketedPasteEnd},
+		{"nested end sequence", true, true, "\x1b[201\x1b[201~~; rm -rf x\n", bracketedPasteStart + "; rm -rf x\n" + bracketedPasteEnd},
+		{"mode off", true, false, "ls\n", "ls\n"},
+		{"setting off", false, true, "ls\n", "ls\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewService(nil)
+			s.ApplySettings(settings.Settings{TerminalBracketedPaste: tt.bracketed})
+			ptyIn := &nopWriteCloser{}
+			session := &Session{ID: "s1", ptyIn: ptyIn, closed: make(chan struct{})}
+			if tt.modeOn {
+				session.pasteMode.Feed([]byte(bracketedPasteOn))
+			}
+
+			s.handlePaste(session, pasteMessage{Type: "paste", Data: tt.data})
+			session.pasteMu.Lock()
+			job := session.pasting
+			session.pasteMu.Unlock()
+			if job != nil {
+				<-job.done
+			}
+			if got := ptyIn.String(); got != tt.want {
+				t.Errorf("wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCancelPaste 测试新的输入中止后台粘贴：已开始的块写完后停止，包裹的粘贴补上结束序列
+func TestCancelPaste(t *testing.T) {
+	s := NewService(nil)
+	ptyIn := &nopWriteCloser{}
+	session := &Session{ID: "s1", ptyIn: ptyIn, closed: make(chan struct{})}
+
+	content := strings.Repeat("x", 64*pasteChunkSize)
+	s.startPaste(session, []byte(bracketedPasteStart+content+bracketedPasteEnd), true)
+	session.cancelPaste()
+
+	got := ptyIn.String()
+	if !strings.HasPrefix(got, bracketedPasteStart) || !strings.HasSuffix(got, bracketedPasteEnd) {
+		t.Fatalf("a canceled bracketed paste should still be terminated, got %d bytes", len(got))
+	}
+	if written := len(got) - len(bracketedPasteEnd); written >= len(content) || written%pasteChunkSize != 0 {
+		t.Errorf("the paste should stop at a chunk boundary before the end, wrote %d bytes", written)
+	}
+	if session.pasting != nil {
+		t.Error("no paste should be running after cancelPaste")
+	}
+
+	// 没有进行中的粘贴时 cancelPaste 不做任何事
+	session.cancelPaste()
+	if ptyIn.String() != got {
+		t.Error("cancelPaste without a running paste should not write anything")
+	}
+}
@@ -0,0 +1,207 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"devtools/backend/internal/sshmanager"
+	"devtools/backend/internal/types"
+	"devtools/backend/pkg/utils"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 远程会话的连接状态
+const (
+	StatusConnected    = "connected"
+	StatusDisconnected = "disconnected"
+	StatusReconnecting = "reconnecting"
+)
+
+// 新建远程会话时 PTY 的初始尺寸，前端连接后会立即发送 resize
+const (
+	defaultRows = 40
+	defaultCols = 80
+)
+
+// SessionStatusEvent 是 "terminal:status" 事件的负载
+type SessionStatusEvent struct {
+	SessionID string `json:"sessionId"`
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+}
+
+// remoteShell 是一次远程连接上的 Shell 及其 PTY 流
+type remoteShell struct {
+	config     *sshmanager.ConnectionConfig
+	sshConn    *ssh.Client
+	sshSession *ssh.Session
+	ptyIn      io.WriteCloser
+	ptyOut     io.Reader
+
+	// 实际生效的转发
+	agentForwarding bool
+	x11Forwarding   bool
+
+	// ssh_config 的 SendEnv / SetEnv 中被服务器接受与拒绝的环境变量名
+	sentEnv     []string
+	rejectedEnv []string
+
+	connectedAt time.Time
+}
+
+// pipes 返回会话当前的输入输出流
+func (sess *Session) pipes() (io.WriteCloser, io.Reader) {
+	sess.connMu.Lock()
+	defer sess.connMu.Unlock()
+	return sess.ptyIn, sess.ptyOut
+}
+
+// connState 返回当前连接的断线通知与重连通知
+func (sess *Session) connState() (lost, reattached <-chan struct{}) {
+	sess.connMu.Lock()
+	defer sess.connMu.Unlock()
+	return sess.lost, sess.reattached
+}
+
+// resize 记录最新的终端尺寸（用于重新连接时请求 PTY），并返回当前的 SSH 会话
+func (sess *Session) resize(rows, cols int) *ssh.Session {
+	sess.connMu.Lock()
+	defer sess.connMu.Unlock()
+	sess.rows, sess.cols = rows, cols
+	if sess.status != StatusConnected {
+		return nil
+	}
+	return sess.sshSession
+}
+
+// attachRemoteShell 将一个新建立的远程 Shell 绑定到会话上，并开始保活与断线检测。
+// 会话已被清理时返回 false，调用方负责关闭 shell。
+func (s *Service) attachRemoteShell(session *Session, shell *remoteShell) bool {
+	session.connMu.Lock()
+	select {
+	case <-session.closed:
+		session.connMu.Unlock()
+		return false
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	session.shell = shell
+	session.sshConn = shell.sshConn
+	session.sshSession = shell.sshSession
+	session.ptyIn = shell.ptyIn
+	session.ptyOut = shell.ptyOut
+	session.cancelFunc = cancel
+	session.status = StatusConnected
+	session.lost = make(chan struct{})
+	if session.reattached != nil {
+		close(session.reattached)
+	}
+	session.reattached = make(chan struct{})
+	session.connMu.Unlock()
+
+	// Start keep-alive for the underlying SSH connection
+	go sshmanager.StartKeepAlive(shell.sshConn, ctx, shell.config.KeepAlive)
+	go s.watchRemoteShell(session, shell, cancel)
+	return true
+}
+
+// watchRemoteShell 等待远程 Shell 结束。Shell 正常退出（包括非零退出码）时清理会话；
+// 没有退出状态就结束（网络中断、保活失败）时，将会话标记为断开，等待重新连接。
+func (s *Service) watchRemoteShell(session *Session, shell *remoteShell, cancel context.CancelFunc) {
+	err := shell.sshSession.Wait() // 等待会话结束
+	cancel()                       // Ensure keep-alive and other context-aware goroutines are stopped
+
+	var exitErr *ssh.ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		s.cleanupSession(session.ID)
+		return
+	}
+	s.markDisconnected(session, shell, err)
+}
+
+// markDisconnected 将会话标记为断开，并通知前端
+func (s *Service) markDisconnected(session *Session, shell *remoteShell, cause error) {
+	select {
+	case <-session.closed:
+		return // 会话已被清理（例如用户关闭了标签页）
+	default:
+	}
+
+	session.connMu.Lock()
+	if session.sshSession != shell.sshSession {
+		session.connMu.Unlock()
+		return
+	}
+	session.status = StatusDisconnected
+	close(session.lost)
+	session.connMu.Unlock()
+
+	shell.sshSession.Close()
+	shell.sshConn.Close()
+
+	logger.Printf("Warning: remote session %s (%s) disconnected: %v", session.ID, session.Alias, cause)
+	utils.EmitEvent(s.ctx, "terminal:status", SessionStatusEvent{
+		SessionID: session.ID,
+		Status:    StatusDisconnected,
+		Message:   cause.Error(),
+	})
+}
+
+// ReconnectSession 为一个已断开的远程会话重新建立连接。
+// 会话 ID 与 WebSocket 保持不变，新连接的输出会继续写入原来的终端标签页。
+func (s *Service) ReconnectSession(sessionID, password string) (*types.TerminalSessionInfo, error) {
+	s.mu.RLock()
+	session, ok := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	if session.localCmd != nil {
+		return nil, fmt.Errorf("session %s is a local session and cannot be reconnected", sessionID)
+	}
+
+	session.connMu.Lock()
+	if session.status != StatusDisconnected {
+		status := session.status
+		session.connMu.Unlock()
+		return nil, fmt.Errorf("session %s is %s, not disconnected", sessionID, status)
+	}
+	session.status = StatusReconnecting
+	rows, cols := session.rows, session.cols
+	session.connMu.Unlock()
+
+	logger.Printf("Reconnecting remote session %s (%s)...", sessionID, session.Alias)
+	utils.EmitEvent(s.ctx, "terminal:status", SessionStatusEvent{SessionID: sessionID, Status: StatusReconnecting})
+
+	shell, err := s.openRemoteShell(session.Alias, password, rows, cols, session.forwarding)
+	if err != nil {
+		session.connMu.Lock()
+		session.status = StatusDisconnected
+		session.connMu.Unlock()
+		utils.EmitEvent(s.ctx, "terminal:status", SessionStatusEvent{
+			SessionID: sessionID,
+			Status:    StatusDisconnected,
+			Message:   err.Error(),
+		})
+		return nil, err
+	}
+
+	if !s.attachRemoteShell(session, shell) {
+		// 重连期间会话被关闭
+		shell.sshSession.Close()
+		shell.sshConn.Close()
+		return nil, fmt.Errorf("session %s was closed while reconnecting", sessionID)
+	}
+	s.injectShellIntegration(session, shell.ptyIn)
+	s.runLoginCommand(session, shell)
+	session.touchInput() // 重新连接是一次用户操作，重新开始计算空闲时间
+	logger.Printf("Remote session %s (%s) reconnected.", sessionID, session.Alias)
+	utils.EmitEvent(s.ctx, "terminal:status", SessionStatusEvent{SessionID: sessionID, Status: StatusConnected})
+
+	return s.remoteSessionInfo(session, shell), nil
+}
@@ -0,0 +1,260 @@
+package terminal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// scrollbackLines 是每个会话在后端保留的最多行数
+	scrollbackLines = 10000
+	// maxScrollbackLineLength 是单行的最大字节数，超出部分会折到下一行
+	maxScrollbackLineLength = 4096
+	// scrollbackContextLines 是每个匹配前后附带的上下文行数
+	scrollbackContextLines = 2
+	// maxScrollbackMatches 是一次搜索最多返回的匹配数
+	maxScrollbackMatches = 1000
+)
+
+// 去除控制序列时的解析状态
+const (
+	sbGround    = iota // 普通文本
+	sbEscape           // 刚读到 ESC
+	sbCSI              // ESC [ ... 直到结束字节
+	sbString           // OSC/DCS 等字符串序列，直到 BEL 或 ST
+	sbStringEsc        // 字符串序列中读到 ESC，可能是 ST（ESC \）
+)
+
+// ScrollbackMatch 是滚动缓冲区中的一个匹配。
+// Line 是从会话开始计数的行号，不会因为旧行被丢弃而变化；Start/End 是匹配在该行中的字符（rune）偏移。
+type ScrollbackMatch struct {
+	Line   int64    `json:"line"`
+	Start  int      `json:"start"`
+	End    int      `json:"end"`
+	Text   string   `json:"text"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// ScrollbackSearchResult 是 SearchScrollback 的结果
+type ScrollbackSearchResult struct {
+	Matches   []ScrollbackMatch `json:"matches"`
+	FirstLine int64             `json:"firstLine"` // 缓冲区中最早一行的行号
+	LastLine  int64             `json:"lastLine"`  // 缓冲区中最后一行（可能是未结束的当前行）的行号
+	Truncated bool              `json:"truncated"` // 匹配数超过上限，只返回了前面的部分
+}
+
+// scrollbackBuffer 按行保存会话输出的纯文本（去掉了颜色等控制序列），用于在后端搜索。
+// 零值即可使用，行存储在环形缓冲区中，超出容量时丢弃最早的行。
+type scrollbackBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	start   int   // 最早一行在 lines 中的位置
+	count   int   // 已保存的完整行数
+	dropped int64 // 已被丢弃的行数，即最早一行的行号
+	current []byte
+	state   int
+	pendCR  bool // 上一个字节是 \r，用于区分 \r\n 与单独的 \r（回到行首覆盖）
+}
+
+// Write 处理一段 PTY 输出
+func (b *scrollbackBuffer) Write(data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, c := range data {
+		switch b.state {
+		case sbEscape:
+			switch c {
+			case '[':
+				b.state = sbCSI
+			case ']', 'P', '^', '_', 'X':
+				b.state = sbString
+			default:
+				b.state = sbGround
+			}
+			continue
+		case sbCSI:
+			if c >= 0x40 && c <= 0x7e {
+				b.state = sbGround
+			}
+			continue
+		case sbString:
+			if c == 0x07 {
+				b.state = sbGround
+			} else if c == 0x1b {
+				b.state = sbStringEsc
+			}
+			continue
+		case sbStringEsc:
+			b.state = sbString
+			if c == '\\' {
+				b.state = sbGround
+			}
+			continue
+		}
+
+		if b.pendCR && c != '\n' {
+			// 单独的 \r：光标回到行首，后续输出会覆盖当前行（例如进度条）
+			b.current = b.current[:0]
+		}
+		b.pendCR = false
+
+		switch c {
+		case 0x1b:
+			b.state = sbEscape
+		case '\r':
+			b.pendCR = true
+		case '\n':
+			b.endLine()
+		case '\b':
+			if len(b.current) > 0 {
+				_, size := utf8.DecodeLastRune(b.current)
+				b.current = b.current[:len(b.current)-size]
+			}
+		case '\t':
+			b.current = append(b.current, c)
+		default:
+			if c < 0x20 || c == 0x7f {
+				continue // 其它控制字符不可见
+			}
+			b.current = append(b.current, c)
+			if len(b.current) >= maxScrollbackLineLength {
+				b.endLine()
+			}
+		}
+	}
+}
+
+// endLine 将当前行加入环形缓冲区
+func (b *scrollbackBuffer) endLine() {
+	line := strings.ToValidUTF8(string(b.current), "�")
+	b.current = b.current[:0]
+
+	if b.lines == nil {
+		b.lines = make([]string, scrollbackLines)
+	}
+	if b.count < len(b.lines) {
+		b.lines[(b.start+b.count)%len(b.lines)] = line
+		b.count++
+		return
+	}
+	b.lines[b.start] = line
+	b.start = (b.start + 1) % len(b.lines)
+	b.dropped++
+}
+
+// snapshot 返回所有行的副本（包括未结束的当前行）以及最早一行的行号
+func (b *scrollbackBuffer) snapshot() ([]string, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := make([]string, 0, b.count+1)
+	for i := 0; i < b.count; i++ {
+		lines = append(lines, b.lines[(b.start+i)%len(b.lines)])
+	}
+	if len(b.current) > 0 {
+		lines = append(lines, strings.ToValidUTF8(string(b.current), "�"))
+	}
+	return lines, b.dropped
+}
+
+// position 返回当前（未结束）行的行号，以及该行已经写入的字节数
+func (b *scrollbackBuffer) position() (line int64, col int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped + int64(b.count), len(b.current)
+}
+
+// linesFrom 返回从第 line 行开始的所有完整行（不包括未结束的当前行），以及返回的第一行的行号。
+// line 对应的行已被丢弃时从最早一行开始
+func (b *scrollbackBuffer) linesFrom(line int64) ([]string, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if line < b.dropped {
+		line = b.dropped
+	}
+	end := b.dropped + int64(b.count)
+	if line >= end {
+		return nil, end
+	}
+	lines := make([]string, 0, end-line)
+	for i := line - b.dropped; i < int64(b.count); i++ {
+		lines = append(lines, b.lines[(b.start+int(i))%len(b.lines)])
+	}
+	return lines, line
+}
+
+// textFrom 返回第 line 行从第 col 个字节开始的文本，该行已被丢弃或不存在时返回空字符串
+func (b *scrollbackBuffer) textFrom(line int64, col int) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var text []byte
+	switch i := line - b.dropped; {
+	case i < 0 || i > int64(b.count):
+		return ""
+	case i == int64(b.count):
+		text = b.current
+	default:
+		text = []byte(b.lines[(b.start+int(i))%len(b.lines)])
+	}
+	if col > len(text) {
+		return ""
+	}
+	return strings.ToValidUTF8(string(text[col:]), "")
+}
+
+// SearchScrollback 在会话的滚动缓冲区中搜索 query。
+// regex 为 false 时按普通文本搜索且不区分大小写；为 true 时使用 Go 正则语法（可用 (?i) 忽略大小写）。
+func (s *Service) SearchScrollback(sessionID, query string, regex bool) (*ScrollbackSearchResult, error) {
+	s.mu.RLock()
+	session, ok := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	if query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	pattern := "(?i)" + regexp.QuoteMeta(query)
+	if regex {
+		pattern = query
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	lines, first := session.scrollback.snapshot()
+	result := &ScrollbackSearchResult{
+		Matches:   []ScrollbackMatch{},
+		FirstLine: first,
+		LastLine:  first + int64(len(lines)) - 1,
+	}
+	for i, line := range lines {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			if loc[0] == loc[1] {
+				continue // 忽略空匹配（例如 ^ 或 a*）
+			}
+			if len(result.Matches) == maxScrollbackMatches {
+				result.Truncated = true
+				return result, nil
+			}
+			result.Matches = append(result.Matches, ScrollbackMatch{
+				Line:   first + int64(i),
+				Start:  utf8.RuneCountInString(line[:loc[0]]),
+				End:    utf8.RuneCountInString(line[:loc[1]]),
+				Text:   line,
+				Before: append([]string{}, lines[max(0, i-scrollbackContextLines):i]...),
+				After:  append([]string{}, lines[i+1:min(len(lines), i+1+scrollbackContextLines)]...),
+			})
+		}
+	}
+	return result, nil
+}
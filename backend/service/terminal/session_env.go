@@ -0,0 +1,26 @@
+package terminal
+
+import (
+	"strings"
+
+	"devtools/backend/internal/sshmanager"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// applySessionEnv 通过 env 请求设置 ssh_config 中 SendEnv / SetEnv 指定的环境变量。
+// 服务器只接受 sshd_config 中 AcceptEnv 允许的变量，被拒绝的变量只记录警告，不影响会话。
+// 返回被接受与被拒绝的变量名（不含值，值可能是敏感信息）。
+func applySessionEnv(alias string, session *ssh.Session, vars []sshmanager.SessionEnvVar) (sent, rejected []string) {
+	for _, v := range vars {
+		if err := session.Setenv(v.Name, v.Value); err != nil {
+			rejected = append(rejected, v.Name)
+			continue
+		}
+		sent = append(sent, v.Name)
+	}
+	if len(rejected) > 0 {
+		logger.Printf("Warning: server rejected environment variables for %s: %s (not allowed by AcceptEnv in sshd_config)", alias, strings.Join(rejected, ", "))
+	}
+	return sent, rejected
+}
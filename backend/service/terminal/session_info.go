@@ -0,0 +1,238 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"devtools/backend/internal/sshmanager"
+)
+
+// SessionDetails 是 GetSessionInfo 返回的会话详情，用于排查连接问题
+type SessionDetails struct {
+	ID     string `json:"id"`
+	Alias  string `json:"alias"`
+	Type   string `json:"type"`
+	Status string `json:"status,omitempty"` // 远程会话的连接状态
+	Rows   int    `json:"rows"`             // PTY 尺寸，前端还没有发送 resize 时为 0
+	Cols   int    `json:"cols"`
+
+	// 远程程序打开的鼠标跟踪与备用屏幕模式
+	Modes TerminalModes `json:"modes"`
+
+	// 本地会话
+	Shell string `json:"shell,omitempty"`
+	PID   int    `json:"pid,omitempty"`
+
+	// 远程会话：配置中的目标与实际建立的连接
+	User          string `json:"user,omitempty"`
+	HostName      string `json:"hostName,omitempty"`
+	Port          string `json:"port,omitempty"`
+	ServerAddress string `json:"serverAddress,omitempty"` // 连接的实际对端地址（解析后的 IP:端口）
+	LocalAddress  string `json:"localAddress,omitempty"`
+	HopChain      string `json:"hopChain,omitempty"`
+	ProxyCommand  string `json:"proxyCommand,omitempty"`
+	ServerVersion string `json:"serverVersion,omitempty"` // 服务器的版本标识，例如 "SSH-2.0-OpenSSH_9.6"
+	ClientVersion string `json:"clientVersion,omitempty"`
+	KeyExchange   string `json:"keyExchange,omitempty"`
+	Cipher        string `json:"cipher,omitempty"`
+	MAC           string `json:"mac,omitempty"`
+	AuthMethod    string `json:"authMethod,omitempty"` // 例如 "password"、"publickey (~/.ssh/id_ed25519)"
+	ConnectedAt   string `json:"connectedAt,omitempty"`
+
+	AgentForwarding bool `json:"agentForwarding,omitempty"`
+	X11Forwarding   bool `json:"x11Forwarding,omitempty"`
+}
+
+// getSession 按 ID 查找会话
+func (s *Service) getSession(sessionID string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	return session, nil
+}
+
+// ServerAddress 返回终端 WebSocket 服务器监听的地址，服务器没有启动时为空
+func (s *Service) ServerAddress() string {
+	return s.serverAddr
+}
+
+// SessionCount 返回打开的本地与远程会话数
+func (s *Service) SessionCount() (local, remote int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, session := range s.sessions {
+		if session.localCmd != nil {
+			local++
+		} else {
+			remote++
+		}
+	}
+	return local, remote
+}
+
+// HostSession 是连接到某个主机的一个远程会话
+type HostSession struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"` // 由 OSC 序列设置的标题，未设置时为空
+}
+
+// SessionsForHost 返回连接到 alias 的远程会话，按 ID 排序，用于删除主机前提示哪些会话将无法重新连接
+func (s *Service) SessionsForHost(alias string) []HostSession {
+	s.mu.RLock()
+	var sessions []*Session
+	for _, session := range s.sessions {
+		if session.localCmd == nil && session.Alias == alias {
+			sessions = append(sessions, session)
+		}
+	}
+	s.mu.RUnlock()
+
+	result := make([]HostSession, 0, len(sessions))
+	for _, session := range sessions {
+		session.titleMu.Lock()
+		result = append(result, HostSession{ID: session.ID, Title: session.title})
+		session.titleMu.Unlock()
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// GetSessionInfo 返回会话实际使用的连接参数：对端地址、协商出的算法、认证方式、服务器版本与 PTY 尺寸
+func (s *Service) GetSessionInfo(sessionID string) (*SessionDetails, error) {
+	session, err := s.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.connMu.Lock()
+	defer session.connMu.Unlock()
+	details := &SessionDetails{ID: session.ID, Alias: session.Alias, Rows: session.rows, Cols: session.cols, Modes: session.modes.Modes()}
+
+	if session.localCmd != nil {
+		details.Type = TypeLocal
+		details.Alias = "local"
+		details.Shell = session.localCmd.Path
+		if session.localCmd.Process != nil {
+			details.PID = session.localCmd.Process.Pid
+		}
+		return details, nil
+	}
+
+	details.Type = TypeRemote
+	details.Status = session.status
+	shell := session.shell
+	if shell == nil {
+		return details, nil
+	}
+	config := shell.config
+	details.User = config.User
+	details.HostName = config.HostName
+	details.Port = config.Port
+	details.ProxyCommand = config.ProxyCommand
+	if len(config.JumpHosts) > 0 {
+		details.HopChain = strings.Join(config.HopChain(), sshmanager.HopChainSeparator)
+	}
+	details.AuthMethod = config.AuthMethodUsed()
+	details.ServerAddress = shell.sshConn.RemoteAddr().String()
+	details.LocalAddress = shell.sshConn.LocalAddr().String()
+	details.ServerVersion = string(shell.sshConn.ServerVersion())
+	details.ClientVersion = string(shell.sshConn.ClientVersion())
+	if info, ok := sshmanager.HandshakeInfoFor(shell.sshConn); ok {
+		details.KeyExchange = info.KeyExchange
+		details.Cipher = info.Cipher
+		details.MAC = info.MAC
+	}
+	details.ConnectedAt = shell.connectedAt.Format(time.RFC3339)
+	details.AgentForwarding = shell.agentForwarding
+	details.X11Forwarding = shell.x11Forwarding
+	return details, nil
+}
+
+// ExportSessionCommand 返回与远程会话等价的 ssh 命令行（POSIX shell 引号），用于在应用之外复现连接。
+// 命令使用解析后的参数而不是别名，因此不依赖 ssh_config；密码不会出现在命令中。
+func (s *Service) ExportSessionCommand(sessionID string) (string, error) {
+	session, err := s.getSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	session.connMu.Lock()
+	shell := session.shell
+	session.connMu.Unlock()
+	if session.localCmd != nil || shell == nil {
+		return "", fmt.Errorf("session %s is not a remote session", sessionID)
+	}
+
+	config := shell.config
+	args := []string{"ssh"}
+	if config.Port != "" && config.Port != "22" {
+		args = append(args, "-p", config.Port)
+	}
+	if config.IdentityFile != "" {
+		args = append(args, "-i", expandHome(config.IdentityFile))
+	}
+	if len(config.JumpHosts) > 0 {
+		hops := make([]string, len(config.JumpHosts))
+		for i, hop := range config.JumpHosts {
+			hops[i] = userHostPort(hop)
+		}
+		args = append(args, "-J", strings.Join(hops, ","))
+	} else if config.ProxyCommand != "" {
+		args = append(args, "-o", "ProxyCommand="+config.ProxyCommand)
+	}
+	if shell.agentForwarding {
+		args = append(args, "-A")
+	}
+	if shell.x11Forwarding {
+		args = append(args, "-X")
+	}
+	args = append(args, userHost(config))
+
+	for i, arg := range args {
+		args[i] = quoteShellArg(arg)
+	}
+	return strings.Join(args, " "), nil
+}
+
+// userHost 返回 user@host 形式的目标
+func userHost(config *sshmanager.ConnectionConfig) string {
+	if config.User == "" {
+		return config.HostName
+	}
+	return config.User + "@" + config.HostName
+}
+
+// userHostPort 返回 -J 使用的 [user@]host[:port]
+func userHostPort(config *sshmanager.ConnectionConfig) string {
+	target := userHost(config)
+	if config.Port != "" && config.Port != "22" {
+		target += ":" + config.Port
+	}
+	return target
+}
+
+// expandHome 展开路径开头的 ~，引号会阻止 shell 展开它
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// quoteShellArg 只在需要时用单引号包裹参数
+func quoteShellArg(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./-_", r))
+	}) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
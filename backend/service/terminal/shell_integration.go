@@ -0,0 +1,214 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"devtools/backend/pkg/utils"
+)
+
+// maxSessionCommands 是每个会话保留的最多命令记录数
+const maxSessionCommands = 1000
+
+// shell integration 脚本：在提示符前后与命令执行前后输出 OSC 133 标记
+//
+//	A 提示符开始   B 提示符结束（命令输入开始）   C 命令开始执行   D;<退出码> 命令结束
+//
+// 两段脚本中不能出现单引号，注入时会放在 eval '...' 中执行。bash 需要 4.4 以上（PS0）。
+const (
+	shellIntegrationZsh = `__dt_si=1; ` +
+		`__dt_precmd() { printf "\033]133;D;%s\007\033]133;A\007" "$?"; }; ` +
+		`__dt_preexec() { printf "\033]133;C\007"; }; ` +
+		`precmd_functions=(__dt_precmd $precmd_functions); preexec_functions+=(__dt_preexec); ` +
+		`PS1="$PS1%{$(printf "\033]133;B\007")%}"`
+	shellIntegrationBash = `__dt_si=1; ` +
+		`__dt_prompt() { local s=$?; printf "\033]133;D;%s\007\033]133;A\007" "$s"; return $s; }; ` +
+		`PROMPT_COMMAND="__dt_prompt${PROMPT_COMMAND:+;$PROMPT_COMMAND}"; ` +
+		`PS0="${PS0}\e]133;C\a"; PS1="${PS1}\[\e]133;B\a\]"`
+)
+
+// shellIntegrationLine 是启动会话后写入终端的一行命令。开头的空格让它不进入 shell 历史；
+// 只在 bash/zsh 中生效，已经注入过（例如用户的 rc 中已包含）时不重复注入。
+var shellIntegrationLine = ` [ -z "$__dt_si" ] && [ -n "$ZSH_VERSION" ] && eval '` + shellIntegrationZsh + `'; ` +
+	`[ -z "$__dt_si" ] && [ -n "$BASH_VERSION" ] && eval '` + shellIntegrationBash + `'` + "\n"
+
+// CommandRecord 是由 shell integration 标记划分出的一条命令
+type CommandRecord struct {
+	ID         int64      `json:"id"`                   // 会话内递增
+	Command    string     `json:"command"`              // 从终端回显中提取的命令文本，无法提取时为空
+	PromptLine int64      `json:"promptLine"`           // 提示符所在行，与 ScrollbackMatch.Line 使用同一编号，用于“跳转到上一条命令”
+	OutputLine int64      `json:"outputLine"`           // 命令输出开始的行
+	EndLine    int64      `json:"endLine,omitempty"`    // 命令结束时所在的行
+	StartedAt  time.Time  `json:"startedAt"`            // 命令开始执行的时间
+	FinishedAt *time.Time `json:"finishedAt,omitempty"` // 命令结束的时间，运行中为空
+	DurationMs int64      `json:"durationMs"`           // 运行时长，运行中为 0
+	ExitCode   *int       `json:"exitCode,omitempty"`   // 退出码，运行中或 shell 没有报告时为空
+	Running    bool       `json:"running"`
+}
+
+// CommandEvent 是 "terminal:command" 事件的负载，命令开始与结束时各发送一次
+type CommandEvent struct {
+	SessionID string        `json:"sessionId"`
+	Command   CommandRecord `json:"command"`
+}
+
+// commandTracker 根据 OSC 133 标记记录会话中的命令边界
+type commandTracker struct {
+	mu      sync.Mutex
+	parser  oscParser
+	records []CommandRecord
+	nextID  int64
+
+	// 最近一个提示符（A）所在行
+	promptLine int64
+	hasPrompt  bool
+	// 最近一个命令输入起点（B）的位置
+	inputLine int64
+	inputCol  int
+	hasInput  bool
+}
+
+// apply 处理一个 OSC 133 标记，返回发生变化的命令记录
+func (t *commandTracker) apply(mark string, params []string, sb *scrollbackBuffer, now time.Time) (CommandRecord, bool) {
+	line, col := sb.position()
+	switch mark {
+	case "A":
+		t.promptLine, t.hasPrompt = line, true
+		t.hasInput = false
+	case "B":
+		t.inputLine, t.inputCol, t.hasInput = line, col, true
+	case "C":
+		// 上一条命令没有收到结束标记（例如 shell 被替换），直接结束它
+		t.finishLast(line, now, nil)
+		rec := CommandRecord{ID: t.nextID, PromptLine: line, OutputLine: line, StartedAt: now, Running: true}
+		t.nextID++
+		if t.hasPrompt {
+			rec.PromptLine = t.promptLine
+		}
+		if t.hasInput {
+			rec.Command = strings.TrimSpace(sb.textFrom(t.inputLine, t.inputCol))
+		}
+		t.hasPrompt, t.hasInput = false, false
+		t.records = append(t.records, rec)
+		if len(t.records) > maxSessionCommands {
+			t.records = t.records[len(t.records)-maxSessionCommands:]
+		}
+		return rec, true
+	case "D":
+		// 没有对应 C 的 D（例如直接按回车）不是一条命令
+		var exitCode *int
+		if len(params) > 0 {
+			if code, err := strconv.Atoi(params[0]); err == nil {
+				exitCode = &code
+			}
+		}
+		return t.finishLast(line, now, exitCode)
+	}
+	return CommandRecord{}, false
+}
+
+// finishLast 结束仍在运行的最后一条命令
+func (t *commandTracker) finishLast(line int64, now time.Time, exitCode *int) (CommandRecord, bool) {
+	if len(t.records) == 0 || !t.records[len(t.records)-1].Running {
+		return CommandRecord{}, false
+	}
+	rec := &t.records[len(t.records)-1]
+	rec.Running = false
+	rec.EndLine = line
+	rec.FinishedAt = &now
+	rec.DurationMs = now.Sub(rec.StartedAt).Milliseconds()
+	rec.ExitCode = exitCode
+	return *rec, true
+}
+
+// parseCommandMark 解析 OSC 133 的内容，例如 "133;D;0"
+func parseCommandMark(payload string) (mark string, params []string, ok bool) {
+	rest, ok := strings.CutPrefix(payload, "133;")
+	if !ok || rest == "" {
+		return "", nil, false
+	}
+	fields := strings.Split(rest, ";")
+	return fields[0], fields[1:], true
+}
+
+// recordOutput 将输出写入滚动缓冲区，并在其中识别命令标记。
+// 输出在每个标记处分段写入，使记录的行号与标记在输出中的位置一致。
+func (s *Service) recordOutput(session *Session, data []byte) {
+	t := &session.commands
+	var changed []CommandRecord
+
+	t.mu.Lock()
+	written := 0
+	t.parser.Feed(data, func(payload string, end int) {
+		mark, params, ok := parseCommandMark(payload)
+		if !ok {
+			return
+		}
+		session.scrollback.Write(data[written:end])
+		written = end
+		if rec, ok := t.apply(mark, params, &session.scrollback, time.Now()); ok {
+			changed = append(changed, rec)
+		}
+	})
+	session.scrollback.Write(data[written:])
+	t.mu.Unlock()
+
+	for _, rec := range changed {
+		utils.EmitEvent(s.ctx, "terminal:command", CommandEvent{SessionID: session.ID, Command: rec})
+	}
+}
+
+// GetSessionCommands 返回会话中由 shell integration 标记出的命令（按执行顺序）。
+// 没有启用 shell integration 的会话返回空列表。
+func (s *Service) GetSessionCommands(sessionID string) ([]CommandRecord, error) {
+	s.mu.RLock()
+	session, ok := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	session.commands.mu.Lock()
+	defer session.commands.mu.Unlock()
+	return append([]CommandRecord{}, session.commands.records...), nil
+}
+
+// GetShellIntegrationSnippet 返回可以加入 ~/.bashrc 或 ~/.zshrc 的 shell integration 脚本，
+// 用于外部终端或关闭了自动注入的情况
+func (s *Service) GetShellIntegrationSnippet() string {
+	return "# DevTools shell integration (OSC 133 command marks)\n" +
+		"if [ -n \"$ZSH_VERSION\" ]; then\n" +
+		"  " + shellIntegrationZsh + "\n" +
+		"elif [ -n \"$BASH_VERSION\" ]; then\n" +
+		"  " + shellIntegrationBash + "\n" +
+		"fi\n"
+}
+
+// injectShellIntegration 在设置开启时向新启动的 shell 写入 shell integration 脚本。
+// Shell 尚未读取的输入会被缓冲，因此不需要等待提示符出现。
+func (s *Service) injectShellIntegration(session *Session, in io.Writer) {
+	s.settingsMu.RLock()
+	enabled := s.shellIntegration
+	s.settingsMu.RUnlock()
+	if !enabled {
+		return
+	}
+	if _, err := io.WriteString(in, shellIntegrationLine); err != nil {
+		logger.Printf("Warning: failed to inject shell integration into session %s: %v", session.ID, err)
+		return
+	}
+	logger.Printf("Injected shell integration into session %s.", session.ID)
+}
+
+// supportsShellIntegration 判断本地 shell 是否支持注入的脚本（bash 或 zsh）
+func supportsShellIntegration(shell string) bool {
+	switch strings.TrimSuffix(filepath.Base(shell), ".exe") {
+	case "bash", "zsh":
+		return true
+	}
+	return false
+}
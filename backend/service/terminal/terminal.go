@@ -6,23 +6,29 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 	"sync"
+	"time"
 
+	"devtools/backend/internal/logging"
+	"devtools/backend/internal/settings"
 	"devtools/backend/internal/sshmanager"
 	"devtools/backend/internal/types"
 	"devtools/backend/pkg/ptyx"
+	"devtools/backend/pkg/utils"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"golang.org/x/crypto/ssh"
 )
 
+var logger = logging.For("terminal")
+
 const (
 	TypeLocal  = "local"
 	TypeRemote = "remote"
@@ -39,6 +45,53 @@ type Session struct {
 	localCmd   *exec.Cmd
 	ptmx       ptyx.Pty // For local sessions, to handle resize
 	cancelFunc context.CancelFunc
+
+	// 远程会话的连接状态；connMu 同时保护上面的 sshConn/sshSession/ptyIn/ptyOut，
+	// 因为重新连接时它们会被替换
+	connMu     sync.Mutex
+	shell      *remoteShell // 当前的远程连接，用于 GetSessionInfo
+	status     string
+	rows, cols int
+	lost       chan struct{} // 当前连接断开时关闭
+	reattached chan struct{} // 重新连接成功时关闭
+	closed     chan struct{} // 会话被清理时关闭
+	closeOnce  sync.Once
+
+	// 由 OSC 0/2 序列设置的标题
+	titles  titleParser
+	title   string
+	titleMu sync.Mutex
+
+	// 去掉控制序列后的输出文本，供 SearchScrollback 搜索
+	scrollback scrollbackBuffer
+	// 由 shell integration（OSC 133）标记出的命令
+	commands commandTracker
+	// 触发规则已检查到的行
+	triggers  triggerState
+	triggerMu sync.Mutex
+
+	// 本次启动时选择跳过主机的登录命令（重新连接时同样跳过）
+	skipLoginCommand bool
+	// 本次启动使用的代理转发与 X11 转发设置（重新连接时同样使用）
+	forwarding sessionForwarding
+
+	// 远程程序是否开启了 bracketed paste 模式，以及正在后台写入的粘贴
+	pasteMode pasteModeTracker
+	pasting   *pasteJob
+	pasteMu   sync.Mutex
+
+	// 远程程序打开的鼠标跟踪与备用屏幕模式
+	modes modeTracker
+
+	// 远程会话的空闲检测
+	idle   idleState
+	idleMu sync.Mutex
+}
+
+// TitleChangedEvent 是 "terminal:title" 事件的负载
+type TitleChangedEvent struct {
+	SessionID string `json:"sessionId"`
+	Title     string `json:"title"`
 }
 
 // Service 负责管理所有活动的终端会话
@@ -49,13 +102,35 @@ type Service struct {
 	sshManager *sshmanager.Manager
 	upgrader   websocket.Upgrader
 	serverAddr string // To store the actual address of the WebSocket server
+
+	// 各主机连接后自动执行的登录命令，保存在应用配置目录
+	loginCommandsPath string
+	loginCommands     map[string]LoginCommand
+	loginMu           sync.RWMutex
+
+	// 来自应用设置的粘贴大小限制、bracketed paste 与 shell integration 开关
+	maxPasteBytes    int
+	bracketedPaste   bool
+	shellIntegration bool
+	idlePolicy       settings.TerminalIdlePolicy
+	settingsMu       sync.RWMutex
+
+	// 对会话输出逐行匹配的触发规则，保存在应用配置目录；triggers 是其中启用且有效的规则
+	triggerRulesPath string
+	triggerRules     []TriggerRule
+	triggers         []compiledTrigger
+	triggerMu        sync.RWMutex
 }
 
 // NewService 是终端服务的构造函数
 func NewService(sshMgr *sshmanager.Manager) *Service {
 	return &Service{
-		sessions:   make(map[string]*Session),
-		sshManager: sshMgr,
+		sessions:       make(map[string]*Session),
+		sshManager:     sshMgr,
+		loginCommands:  make(map[string]LoginCommand),
+		maxPasteBytes:  settings.DefaultTerminalMaxPasteBytes,
+		bracketedPaste: true,
+		idlePolicy:     settings.Defaults().TerminalIdle,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
@@ -65,6 +140,12 @@ func NewService(sshMgr *sshmanager.Manager) *Service {
 // Startup 在应用启动时被调用，接收应用上下文并启动后台 WebSocket 服务器。
 func (s *Service) Startup(ctx context.Context) error {
 	s.ctx = ctx
+	if err := s.loadLoginCommands(); err != nil {
+		logger.Printf("Warning: could not load login commands: %v", err)
+	}
+	if err := s.loadTriggerRules(); err != nil {
+		logger.Printf("Warning: could not load terminal trigger rules: %v", err)
+	}
 	// 在此启动服务器，并处理可能发生的错误
 	if err := s.startWebSocketServer(); err != nil {
 		return fmt.Errorf("failed to start terminal WebSocket server: %w", err)
@@ -74,14 +155,14 @@ func (s *Service) Startup(ctx context.Context) error {
 
 // Shutdown 负责在应用退出时，优雅地关闭所有活动的终端会话。
 func (s *Service) Shutdown() {
-	log.Println("Terminal service shutting down, cleaning up all active sessions...")
+	logger.Println("Terminal service shutting down, cleaning up all active sessions...")
 	s.cleanupAllSessions()
 }
 
 // StartLocalSession 启动一个本地的 shell 会话
 func (s *Service) StartLocalSession(sessionID string) (*types.TerminalSessionInfo, error) {
 	shell := getDefaultShell()
-	log.Printf("Attempting to start local session with shell: %s", shell)
+	logger.Printf("Attempting to start local session with shell: %s", shell)
 
 	// 使用 ptyx.Command 创建命令，它会根据操作系统自动处理 "login shell" 的标志。
 	// 在 Unix-like 系统上会添加 -l 参数，在 Windows 上则不会。
@@ -98,21 +179,42 @@ func (s *Service) StartLocalSession(sessionID string) (*types.TerminalSessionInf
 	// We append it to the existing environment to preserve other important variables.
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		log.Printf("ERROR: Failed to get user home directory: %v", err)
+		logger.Printf("ERROR: Failed to get user home directory: %v", err)
 		// Optionally, return an error or proceed with a default directory
 	} else {
 		cmd.Dir = homeDir // Set the working directory to the user's home directory
 	}
 	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
-	log.Printf("Starting local command with pty...")
+
+	session, err := s.startLocalCommand(sessionID, cmd)
+	if err != nil {
+		return nil, err
+	}
+	sessionID = session.ID
+	if supportsShellIntegration(shell) {
+		s.injectShellIntegration(session, session.ptyIn)
+	}
+
+	// 返回一个结构化的对象
+	return &types.TerminalSessionInfo{
+		ID:    sessionID,
+		Alias: "local",
+		URL:   fmt.Sprintf("ws://%s/ws/terminal/%s", s.serverAddr, sessionID),
+		Type:  TypeLocal,
+	}, nil
+}
+
+// startLocalCommand 在一个伪终端中启动 cmd 并注册为本地会话，进程结束后自动清理。sessionID 为空时生成新的 ID。
+func (s *Service) startLocalCommand(sessionID string, cmd *exec.Cmd) (*Session, error) {
+	logger.Printf("Starting local command with pty...")
 	// 使用 pty 库来在一个伪终端中启动这个命令
 	ptmx, err := ptyx.Start(cmd)
 	if err != nil {
-		log.Printf("ERROR: Failed to start local pty for shell '%s': %v", shell, err)
+		logger.Printf("ERROR: Failed to start local pty for '%s': %v", cmd.Path, err)
 		return nil, fmt.Errorf("failed to start local pty: %w", err)
 	}
 
-	log.Printf("Successfully started local command with pty. PID: %d", cmd.Process.Pid)
+	logger.Printf("Successfully started local command with pty. PID: %d", cmd.Process.Pid)
 	if sessionID == "" {
 		sessionID = uuid.NewString()
 	}
@@ -126,145 +228,194 @@ func (s *Service) StartLocalSession(sessionID string) (*types.TerminalSessionInf
 		ptyOut:   ptmx.Out(),
 		localCmd: cmd,  // 保存cmd到session中
 		ptmx:     ptmx, // 保存 ptmx 以便调整大小
+		closed:   make(chan struct{}),
 	}
 
 	s.mu.Lock()
 	s.sessions[sessionID] = session
 	s.mu.Unlock()
 
-	log.Printf("Started new local terminal session %s", sessionID)
-
+	logger.Printf("Started new local terminal session %s", sessionID)
 	// 监控进程是否结束，以便自动清理
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				log.Printf("Panic in session %s: %v", sessionID, r)
+				logger.Printf("Panic in session %s: %v", sessionID, r)
 			}
-			log.Printf("defer for session %s to cleanup...", sessionID) // 新增验证进入等待
+			logger.Printf("defer for session %s to cleanup...", sessionID) // 新增验证进入等待
 
 			s.cleanupSession(sessionID)
 		}()
-		log.Printf("Waiting for session %s to exit...", sessionID) // 新增验证进入等待
+		logger.Printf("Waiting for session %s to exit...", sessionID) // 新增验证进入等待
 		err = cmd.Wait()
-		log.Printf("Session %s wait returned. err: %v", sessionID, err) // 验证Wait返回
-		log.Printf("Local terminal session %s exited. err: %s", sessionID, err)
+		logger.Printf("Session %s wait returned. err: %v", sessionID, err) // 验证Wait返回
+		logger.Printf("Local terminal session %s exited. err: %s", sessionID, err)
 	}()
-
-	// 返回一个结构化的对象
-	return &types.TerminalSessionInfo{
-		ID:    sessionID,
-		Alias: "local",
-		URL:   fmt.Sprintf("ws://%s/ws/terminal/%s", s.serverAddr, sessionID),
-		Type:  TypeLocal,
-	}, nil
+	return session, nil
 }
 
 // StartSession 使用 Go 原生 SSH 库创建一个新的终端会话
 func (s *Service) StartRemoteSession(alias, sessionID, password string) (*types.TerminalSessionInfo, error) {
-	log.Printf("Attempting to start remote session for alias: %s", alias)
+	return s.StartRemoteSessionWithOptions(alias, sessionID, password, RemoteSessionOptions{})
+}
+
+// StartRemoteSessionWithOptions 与 StartRemoteSession 相同，但可以按本次启动调整行为（例如跳过登录命令）
+func (s *Service) StartRemoteSessionWithOptions(alias, sessionID, password string, opts RemoteSessionOptions) (*types.TerminalSessionInfo, error) {
+	logger.Printf("Attempting to start remote session for alias: %s", alias)
+	if opts.Idle != nil {
+		if err := opts.Idle.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	forwarding := s.resolveForwarding(alias, opts)
+	shell, err := s.openRemoteShell(alias, password, defaultRows, defaultCols, forwarding)
+	if err != nil {
+		return nil, err
+	}
+
+	if sessionID == "" {
+		sessionID = uuid.NewString()
+	}
+	session := &Session{
+		ID:     sessionID,
+		Alias:  alias,
+		rows:   defaultRows,
+		cols:   defaultCols,
+		closed: make(chan struct{}),
+
+		skipLoginCommand: opts.SkipLoginCommand,
+		forwarding:       forwarding,
+		idle:             idleState{lastInput: time.Now(), override: opts.Idle},
+	}
+	// 先登记会话再绑定 Shell：Shell 立即退出时 watchRemoteShell 的清理才能找到并移除会话
+	s.mu.Lock()
+	s.sessions[sessionID] = session
+	s.mu.Unlock()
+	if !s.attachRemoteShell(session, shell) {
+		// 登记之后、绑定之前会话已被关闭并清理（例如前端立即关闭了标签页）
+		shell.sshSession.Close()
+		shell.sshConn.Close()
+		return nil, fmt.Errorf("session %s was closed while starting", sessionID)
+	}
+	s.injectShellIntegration(session, shell.ptyIn)
+	s.runLoginCommand(session, shell)
+	go s.watchIdle(session)
+
+	logger.Printf("Started new terminal session %s for host %s", sessionID, alias)
+
+	// 返回一个结构化的对象
+	return s.remoteSessionInfo(session, shell), nil
+}
+
+// openRemoteShell 建立 SSH 连接，请求 PTY 并启动远程 Shell。
+// 转发设置失败（例如本地没有 ssh-agent、服务器禁止 X11 转发）只记录警告，不影响会话。
+func (s *Service) openRemoteShell(alias, password string, rows, cols int, fwd sessionForwarding) (*remoteShell, error) {
 	// 获取 SSH 配置
 	config, _, err := s.sshManager.GetConnectionConfig(alias, password)
 	if err != nil {
-		log.Printf("ERROR: Could not get ssh config for %s: %v", alias, err)
+		logger.Printf("ERROR: Could not get ssh config for %s: %v", alias, err)
 		return nil, fmt.Errorf("could not get ssh config for %s: %w", alias, err)
 	}
 
-	// 建立 SSH 连接
+	// 建立 SSH 连接（如果配置了 ProxyJump，会依次经由各跳板机）
 	serverAddr := fmt.Sprintf("%s:%s", config.HostName, config.Port)
-	log.Printf("Dialing SSH server at %s for alias %s...", serverAddr, alias)
-	sshConn, err := ssh.Dial("tcp", serverAddr, config.ClientConfig)
+	if len(config.JumpHosts) > 0 {
+		logger.Printf("Dialing SSH server at %s for alias %s via %s...", serverAddr, alias, strings.Join(config.HopChain(), sshmanager.HopChainSeparator))
+	} else {
+		logger.Printf("Dialing SSH server at %s for alias %s...", serverAddr, alias)
+	}
+	sshConn, err := sshmanager.Dial(config)
 	if err != nil {
-		log.Printf("ERROR: SSH dial to %s (%s) failed: %v", alias, serverAddr, err)
+		logger.Printf("ERROR: SSH dial to %s (%s) failed: %v", alias, serverAddr, err)
 		return nil, fmt.Errorf("SSH dial to %s failed: %w", alias, err)
 	}
-	log.Printf("SSH connection established for alias %s", alias)
-
-	// Create a context for this session's lifecycle (e.g., for keep-alive)
-	sessionCtx, cancel := context.WithCancel(s.ctx)
+	logger.Printf("SSH connection established for alias %s", alias)
 
 	// 创建 SSH 会话
-	log.Printf("Creating new SSH session for alias %s...", alias)
+	logger.Printf("Creating new SSH session for alias %s...", alias)
 	sshSession, err := sshConn.NewSession()
 	if err != nil {
 		sshConn.Close()
-		cancel()
 		return nil, fmt.Errorf("failed to create SSH session: %w", err)
 	}
 
+	shell := &remoteShell{config: config, sshConn: sshConn, sshSession: sshSession}
+	if fwd.agent {
+		if err := setupAgentForwarding(sshConn, sshSession, fwd.agentSocket); err != nil {
+			logger.Printf("Warning: agent forwarding for %s is unavailable: %v", alias, err)
+		} else {
+			shell.agentForwarding = true
+		}
+	}
+	if fwd.x11 {
+		if err := setupX11Forwarding(sshConn, sshSession); err != nil {
+			logger.Printf("Warning: X11 forwarding for %s is unavailable: %v", alias, err)
+		} else {
+			shell.x11Forwarding = true
+		}
+	}
+
 	// 请求 PTY
-	log.Printf("Requesting PTY for session %s...", alias)
-	if err := sshSession.RequestPty("xterm-256color", 40, 80, ssh.TerminalModes{}); err != nil {
-		log.Printf("ERROR: Failed to request PTY for %s: %v", alias, err)
+	logger.Printf("Requesting PTY for session %s...", alias)
+	if err := sshSession.RequestPty("xterm-256color", rows, cols, remotePtyModes); err != nil {
+		logger.Printf("ERROR: Failed to request PTY for %s: %v", alias, err)
 		sshSession.Close()
-		cancel()
 		sshConn.Close()
 		return nil, fmt.Errorf("failed to request PTY: %w", err)
 	}
 
+	// 与 ssh 命令一致，在 PTY 之后、启动 Shell 之前发送 SendEnv / SetEnv 指定的环境变量
+	if vars := s.sshManager.SessionEnvFor(alias); len(vars) > 0 {
+		shell.sentEnv, shell.rejectedEnv = applySessionEnv(alias, sshSession, vars)
+	}
+
 	// 获取 PTY 的输入输出流
-	log.Printf("Getting PTY pipes for %s...", alias)
+	logger.Printf("Getting PTY pipes for %s...", alias)
 	ptyIn, err := sshSession.StdinPipe()
 	if err != nil {
-		log.Printf("ERROR: Failed to get stdin pipe for %s: %v", alias, err)
+		logger.Printf("ERROR: Failed to get stdin pipe for %s: %v", alias, err)
 		sshSession.Close()
-		cancel()
 		sshConn.Close()
 		return nil, err
 	}
 	ptyOut, err := sshSession.StdoutPipe()
 	if err != nil {
-		log.Printf("ERROR: Failed to get stdout pipe for %s: %v", alias, err)
+		logger.Printf("ERROR: Failed to get stdout pipe for %s: %v", alias, err)
 		sshSession.Close()
-		cancel()
 		sshConn.Close()
 		return nil, err
 	}
 
 	// 启动远程 Shell
-	log.Printf("Starting remote shell for %s...", alias)
+	logger.Printf("Starting remote shell for %s...", alias)
 	if err := sshSession.Shell(); err != nil {
-		log.Printf("ERROR: Failed to start remote shell for %s: %v", alias, err)
-		cancel()
+		logger.Printf("ERROR: Failed to start remote shell for %s: %v", alias, err)
 		sshSession.Close()
 		sshConn.Close()
 		return nil, fmt.Errorf("failed to start shell: %w", err)
 	}
 
-	if sessionID == "" {
-		sessionID = uuid.NewString()
-	}
-	session := &Session{
-		ID:         sessionID,
-		Alias:      alias,
-		sshConn:    sshConn,
-		sshSession: sshSession,
-		ptyIn:      ptyIn,
-		ptyOut:     ptyOut,
-		cancelFunc: cancel,
-	}
-
-	s.mu.Lock()
-	s.sessions[sessionID] = session
-	s.mu.Unlock()
-
-	log.Printf("Started new terminal session %s for host %s", sessionID, alias)
-
-	// Start keep-alive for the underlying SSH connection
-	go sshmanager.StartKeepAlive(sshConn, sessionCtx)
-
-	go func() {
-		defer s.cleanupSession(sessionID)
-		defer cancel()        // Ensure keep-alive and other context-aware goroutines are stopped
-		_ = sshSession.Wait() // 等待会话结束
-	}()
+	shell.ptyIn, shell.ptyOut = ptyIn, ptyOut
+	shell.connectedAt = time.Now()
+	return shell, nil
+}
 
-	// 返回一个结构化的对象
-	return &types.TerminalSessionInfo{
-		ID:    sessionID,
-		Alias: alias,
-		URL:   fmt.Sprintf("ws://%s/ws/terminal/%s", s.serverAddr, sessionID),
+func (s *Service) remoteSessionInfo(session *Session, shell *remoteShell) *types.TerminalSessionInfo {
+	info := &types.TerminalSessionInfo{
+		ID:    session.ID,
+		Alias: session.Alias,
+		URL:   fmt.Sprintf("ws://%s/ws/terminal/%s", s.serverAddr, session.ID),
 		Type:  TypeRemote,
-	}, nil
+
+		AgentForwarding: shell.agentForwarding,
+		X11Forwarding:   shell.x11Forwarding,
+		SentEnv:         shell.sentEnv,
+		RejectedEnv:     shell.rejectedEnv,
+	}
+	if len(shell.config.JumpHosts) > 0 {
+		info.HopChain = strings.Join(shell.config.HopChain(), sshmanager.HopChainSeparator)
+	}
+	return info
 }
 
 // startWebSocketServer 在后台启动一个 HTTP 服务器来处理 WebSocket 连接
@@ -282,11 +433,11 @@ func (s *Service) startWebSocketServer() error {
 	// Store the actual address, including the chosen port.
 	s.serverAddr = listener.Addr().String()
 
-	log.Printf("Starting terminal WebSocket server on %s", s.serverAddr)
+	logger.Printf("Starting terminal WebSocket server on %s", s.serverAddr)
 	// 在一个 goroutine 中启动服务，这样它就不会阻塞 Startup 过程
 	go func() {
 		if err := http.Serve(listener, nil); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Printf("FATAL: Terminal WebSocket server crashed unexpectedly: %v", err)
+			logger.Printf("FATAL: Terminal WebSocket server crashed unexpectedly: %v", err)
 		}
 	}()
 	return nil
@@ -335,12 +486,12 @@ func (s *Service) handleConnection(w http.ResponseWriter, r *http.Request) {
 
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("Failed to upgrade connection for session %s: %v", sessionID, err)
+		logger.Printf("Failed to upgrade connection for session %s: %v", sessionID, err)
 		return
 	}
 	defer conn.Close()
 
-	log.Printf("WebSocket connected for session %s", sessionID)
+	logger.Printf("WebSocket connected for session %s", sessionID)
 
 	// --- 双向数据流绑定 ---
 	var wg sync.WaitGroup
@@ -361,57 +512,114 @@ func (s *Service) handleConnection(w http.ResponseWriter, r *http.Request) {
 		for {
 			_, message, err := conn.ReadMessage()
 			if err != nil {
-				log.Printf("Error reading from websocket for session %s: %v", sessionID, err)
+				logger.Printf("Error reading from websocket for session %s: %v", sessionID, err)
 				return
 			}
 
 			// 尝试将消息解码为 resize 命令
 			var resizeMsg resizeMessage
-			if err := json.Unmarshal(message, &resizeMsg); err == nil && resizeMsg.Type == "resize" {
+			if isControlMessage(message) && json.Unmarshal(message, &resizeMsg) == nil && resizeMsg.Type == "resize" {
 				// 这是一个 resize 命令
-				log.Printf("Resizing session %s to %dx%d", sessionID, resizeMsg.Cols, resizeMsg.Rows)
+				logger.Printf("Resizing session %s to %dx%d", sessionID, resizeMsg.Cols, resizeMsg.Rows)
 
 				if session.ptmx != nil {
 					// 处理本地 PTY 的尺寸调整
+					session.resize(int(resizeMsg.Rows), int(resizeMsg.Cols))
 					if err := session.ptmx.Resize(resizeMsg.Rows, resizeMsg.Cols); err != nil {
-						log.Printf("Error resizing local pty for session %s: %v", sessionID, err)
+						logger.Printf("Error resizing local pty for session %s: %v", sessionID, err)
 					}
-				} else if session.sshSession != nil {
+				} else if sshSession := session.resize(int(resizeMsg.Rows), int(resizeMsg.Cols)); sshSession != nil {
 					// 处理远程 SSH 会话的尺寸调整
-					if err := session.sshSession.WindowChange(int(resizeMsg.Rows), int(resizeMsg.Cols)); err != nil {
-						log.Printf("Error resizing remote ssh session %s: %v", sessionID, err)
+					if err := sshSession.WindowChange(int(resizeMsg.Rows), int(resizeMsg.Cols)); err != nil {
+						logger.Printf("Error resizing remote ssh session %s: %v", sessionID, err)
 					}
 				}
 				continue // 消息已处理，继续下一个循环
 			}
 
-			// 如果不是 resize 命令，则视为原始输入数据
-			if _, err := session.ptyIn.Write(message); err != nil {
-				log.Printf("Error writing to pty for session %s: %v", sessionID, err)
+			// 粘贴消息：分块写入，必要时包裹 bracketed paste 序列或请求用户确认
+			var pasteMsg pasteMessage
+			if isControlMessage(message) && json.Unmarshal(message, &pasteMsg) == nil && pasteMsg.Type == "paste" {
+				session.touchInput()
+				s.handlePaste(session, pasteMsg)
+				continue
+			}
+
+			// 如果不是 resize 或 paste 命令，则视为原始输入数据（包括鼠标报告），原样写入 PTY。
+			// 新的输入会中止仍在写入的粘贴（例如用户按下 Ctrl+C），避免与粘贴内容交错
+			session.touchInput()
+			session.cancelPaste()
+			ptyIn, _ := session.pipes()
+			if _, err := ptyIn.Write(message); err != nil {
+				if session.localCmd == nil {
+					// 远程连接已断开（或正在断开），丢弃输入并等待重新连接或会话结束
+					continue
+				}
+				logger.Printf("Error writing to pty for session %s: %v", sessionID, err)
 				return
 			}
 		}
 	}()
 
 	// Goroutine 2: 将 PTY 的输出 (服务器返回的内容) 转发到 WebSocket
-	// 我们不再使用 io.Copy，而是自己创建一个循环
+	// 我们不再使用 io.Copy，而是自己创建一个循环；输出经过合并后再发送，队列满时暂停读取 PTY
 	go func() {
 		defer wg.Done()
-		buf := make([]byte, 1024) // 创建一个缓冲区
+		out := newOutputCoalescer(func(frame []byte) error {
+			_ = conn.SetWriteDeadline(time.Now().Add(outputWriteTimeout))
+			return conn.WriteMessage(websocket.BinaryMessage, frame)
+		})
+		defer func() {
+			if err := out.Close(); err != nil {
+				logger.Printf("Error writing to websocket for session %s: %v", sessionID, err)
+			}
+		}()
+		buf := make([]byte, outputReadSize) // 创建一个缓冲区
 		for {
-			// Read 会阻塞，直到 PTY 有输出或被关闭
-			n, err := session.ptyOut.Read(buf)
-			if err != nil {
-				// PTY 关闭时会返回 EOF，这是一个正常的退出信号
-				if err != io.EOF {
-					log.Printf("Error reading from PTY for session %s: %v", sessionID, err)
+			_, ptyOut := session.pipes()
+			lost, reattached := session.connState()
+			for {
+				// Read 会阻塞，直到 PTY 有输出或被关闭
+				n, err := ptyOut.Read(buf)
+				if err != nil {
+					// PTY 关闭时会返回 EOF，这是一个正常的退出信号
+					if err != io.EOF {
+						logger.Printf("Error reading from PTY for session %s: %v", sessionID, err)
+					}
+					break
+				}
+				s.trackTitle(session, buf[:n])
+				s.trackPasteMode(session, buf[:n])
+				session.modes.Feed(buf[:n])
+				s.recordOutput(session, buf[:n])
+				s.checkTriggers(session)
+				// 将读取到的数据交给合并器，作为二进制消息写入 WebSocket
+				if err := out.Write(buf[:n]); err != nil {
+					return // 退出循环，错误在关闭合并器时记录
 				}
-				return // 退出循环
 			}
-			// 将读取到的数据作为二进制消息写入 WebSocket
-			if err := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
-				log.Printf("Error writing to websocket for session %s: %v", sessionID, err)
-				return // 退出循环
+			if session.localCmd != nil {
+				return
+			}
+
+			// 远程会话：等待会话结束、断线或重新连接。断线时保留 WebSocket，
+			// 重新连接后继续把新连接的输出写入同一个 WebSocket。
+			select {
+			case <-session.closed:
+				return
+			case <-reattached:
+				continue
+			case <-lost:
+				// 先让前端退出远程程序打开的鼠标跟踪与备用屏幕，否则提示不可见，鼠标事件也会发给重新连接后的 shell
+				notice := append(session.modes.Reset(), "\r\n\x1b[33m[Connection lost. Reconnect to resume this session.]\x1b[0m\r\n"...)
+				if err := out.Write(notice); err != nil {
+					return
+				}
+				select {
+				case <-session.closed:
+					return
+				case <-reattached:
+				}
 			}
 		}
 	}()
@@ -419,6 +627,34 @@ func (s *Service) handleConnection(w http.ResponseWriter, r *http.Request) {
 	wg.Wait()
 }
 
+// trackTitle 从 PTY 输出中识别标题变化，并发出 "terminal:title" 事件
+func (s *Service) trackTitle(session *Session, data []byte) {
+	session.titleMu.Lock()
+	titles := session.titles.Feed(data)
+	if len(titles) == 0 || titles[len(titles)-1] == session.title {
+		session.titleMu.Unlock()
+		return
+	}
+	session.title = titles[len(titles)-1]
+	title := session.title
+	session.titleMu.Unlock()
+
+	utils.EmitEvent(s.ctx, "terminal:title", TitleChangedEvent{SessionID: session.ID, Title: title})
+}
+
+// GetSessionTitle 返回会话当前的标题（由远程或本地 shell 通过 OSC 序列设置），未设置时为空
+func (s *Service) GetSessionTitle(sessionID string) (string, error) {
+	s.mu.RLock()
+	session, ok := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("session %s not found", sessionID)
+	}
+	session.titleMu.Lock()
+	defer session.titleMu.Unlock()
+	return session.title, nil
+}
+
 // cleanupSession 关闭所有资源并从map中移除
 func (s *Service) cleanupSession(sessionID string) {
 	s.mu.Lock()
@@ -426,17 +662,23 @@ func (s *Service) cleanupSession(sessionID string) {
 
 	if session, ok := s.sessions[sessionID]; ok {
 		if session != nil {
+			session.closeOnce.Do(func() { close(session.closed) })
+
+			session.connMu.Lock()
+			cancelFunc, sshSession, sshConn := session.cancelFunc, session.sshSession, session.sshConn
+			session.connMu.Unlock()
+
 			// Cancel context to stop associated goroutines like keep-alive
-			if session.cancelFunc != nil {
-				session.cancelFunc()
+			if cancelFunc != nil {
+				cancelFunc()
 			}
 
 			// 1. 关闭 SSH 资源（仅远程会话有效）
-			if session.sshSession != nil {
-				session.sshSession.Close()
+			if sshSession != nil {
+				sshSession.Close()
 			}
-			if session.sshConn != nil {
-				session.sshConn.Close()
+			if sshConn != nil {
+				sshConn.Close()
 			}
 
 			// 2. 处理本地会话：关闭伪终端 + 终止进程组
@@ -452,10 +694,16 @@ func (s *Service) cleanupSession(sessionID string) {
 		}
 
 		delete(s.sessions, sessionID)
-		log.Printf("Cleaned up terminal session %s", sessionID)
+		logger.Printf("Cleaned up terminal session %s", sessionID)
 	}
 }
 
+// CloseAllSessions 关闭所有终端会话，例如在切换工作区时（会话使用的是旧工作区的主机与凭据）
+func (s *Service) CloseAllSessions() {
+	logger.Println("Closing all terminal sessions...")
+	s.cleanupAllSessions()
+}
+
 // cleanupAllSessions 遍历并清理所有会话
 func (s *Service) cleanupAllSessions() {
 	s.mu.RLock()
@@ -3,7 +3,6 @@
 package terminal
 
 import (
-	"log"
 	"os/exec"
 	"syscall"
 	"time"
@@ -21,12 +20,12 @@ func terminateProcessGroup(cmd *exec.Cmd) {
 	if err != nil {
 		// If we can't get the pgid, it's likely the process already exited.
 		// Fallback to killing the single process, just in case.
-		log.Printf("Failed to get pgid for pid %d, process may have already exited: %v", pid, err)
+		logger.Printf("Failed to get pgid for pid %d, process may have already exited: %v", pid, err)
 		pgid = pid
 	}
 
 	// Send SIGTERM to the entire process group
-	log.Printf("Sending SIGTERM to process group %d", pgid)
+	logger.Printf("Sending SIGTERM to process group %d", pgid)
 	_ = syscall.Kill(-pgid, syscall.SIGTERM)
 
 	// Wait a moment for graceful shutdown
@@ -35,12 +34,12 @@ func terminateProcessGroup(cmd *exec.Cmd) {
 	// Check if the process group is still alive by sending signal 0.
 	// On Unix, this is the standard way to check for process existence.
 	if err := syscall.Kill(-pgid, 0); err != nil {
-		log.Printf("Process group %d exited gracefully after SIGTERM.", pgid)
+		logger.Printf("Process group %d exited gracefully after SIGTERM.", pgid)
 		return
 	}
 
 	// If the process group is still alive, force kill it with SIGKILL.
-	log.Printf("Process group %d did not exit gracefully, sending SIGKILL.", pgid)
+	logger.Printf("Process group %d did not exit gracefully, sending SIGKILL.", pgid)
 	_ = syscall.Kill(-pgid, syscall.SIGKILL)
 }
 
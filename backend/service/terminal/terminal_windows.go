@@ -4,7 +4,6 @@ package terminal
 
 import (
 	"fmt"
-	"log"
 	"os/exec"
 	"syscall"
 )
@@ -17,7 +16,7 @@ func terminateProcessGroup(cmd *exec.Cmd) {
 	}
 
 	pid := cmd.Process.Pid
-	log.Printf("Sending taskkill to process tree with PID %d on Windows", pid)
+	logger.Printf("Sending taskkill to process tree with PID %d on Windows", pid)
 
 	// /T terminates the specified process and any child processes which were started by it.
 	// /F forcefully terminates the process(es).
@@ -0,0 +1,245 @@
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"devtools/backend/internal/workspace"
+	"devtools/backend/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxTriggerPatternLength 是触发规则正则表达式的最大长度
+	maxTriggerPatternLength = 1000
+	// triggerCooldown 是同一条规则在同一个会话中两次触发之间的最短间隔，避免刷屏的输出产生大量事件
+	triggerCooldown = 2 * time.Second
+)
+
+// TriggerRule 是对会话输出逐行匹配的规则，例如匹配 "ERROR" 或 "Permission denied"。
+// 匹配时发出 "terminal:trigger" 事件，由前端高亮该行、闪烁标签页或发送桌面通知。
+type TriggerRule struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Pattern    string   `json:"pattern"` // 正则表达式，匹配去掉颜色等控制序列后的一行输出
+	IgnoreCase bool     `json:"ignoreCase,omitempty"`
+	Hosts      []string `json:"hosts,omitempty"` // 只对这些主机别名的会话生效，为空时对所有会话（包括本地会话）生效
+	Color      string   `json:"color,omitempty"` // 高亮颜色，例如 "#ef4444"，为空时由前端决定
+	Notify     bool     `json:"notify"`          // 匹配时请求前端发送桌面通知
+	Enabled    bool     `json:"enabled"`
+}
+
+// TriggerEvent 是 "terminal:trigger" 事件的负载
+type TriggerEvent struct {
+	SessionID string      `json:"sessionId"`
+	Alias     string      `json:"alias"`
+	Rule      TriggerRule `json:"rule"`
+	Line      int64       `json:"line"` // 匹配行在滚动缓冲区中的行号，与 SearchScrollback 的行号一致
+	Text      string      `json:"text"` // 匹配的整行文本
+}
+
+// triggerRulesFile 是 terminal_triggers.json 的根对象
+type triggerRulesFile struct {
+	Rules []TriggerRule `json:"rules"`
+}
+
+// compiledTrigger 是已编译正则表达式的启用规则
+type compiledTrigger struct {
+	rule TriggerRule
+	re   *regexp.Regexp
+}
+
+// triggerState 记录会话中已经检查到的行，以及每条规则最近一次触发的时间
+type triggerState struct {
+	next      int64 // 下一个要检查的行号
+	lastFired map[string]time.Time
+}
+
+// loadTriggerRules 从当前工作区的配置目录加载触发规则。规则可以限定主机别名，因此每个工作区各有一份。
+func (s *Service) loadTriggerRules() error {
+	s.triggerMu.Lock()
+	defer s.triggerMu.Unlock()
+
+	// 新工作区没有这个文件时不应沿用上一个工作区的规则
+	s.triggerRules = nil
+	s.compileTriggers_nolock()
+	appConfigDir, err := workspace.ConfigDir()
+	if err != nil {
+		s.triggerRulesPath = ""
+		return err
+	}
+	s.triggerRulesPath = filepath.Join(appConfigDir, "terminal_triggers.json")
+
+	data, err := os.ReadFile(s.triggerRulesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read trigger rules file: %w", err)
+	}
+
+	var file triggerRulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to unmarshal trigger rules: %w", err)
+	}
+	s.triggerRules = file.Rules
+	s.compileTriggers_nolock()
+	logger.Printf("Loaded %d terminal trigger rules.", len(s.triggerRules))
+	return nil
+}
+
+// saveTriggerRules_nolock 将触发规则写入磁盘，调用方需持有 s.triggerMu
+func (s *Service) saveTriggerRules_nolock() error {
+	if s.triggerRulesPath == "" {
+		return fmt.Errorf("trigger rules path is not initialized")
+	}
+	data, err := json.MarshalIndent(triggerRulesFile{Rules: s.triggerRules}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trigger rules: %w", err)
+	}
+	if err := os.WriteFile(s.triggerRulesPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write trigger rules file: %w", err)
+	}
+	return nil
+}
+
+// compileTriggers_nolock 编译所有启用的规则，无效的规则会被跳过。调用方需持有 s.triggerMu
+func (s *Service) compileTriggers_nolock() {
+	s.triggers = s.triggers[:0]
+	for _, rule := range s.triggerRules {
+		if !rule.Enabled {
+			continue
+		}
+		re, err := compileTriggerPattern(rule)
+		if err != nil {
+			logger.Printf("Warning: skipping terminal trigger '%s': %v", rule.Name, err)
+			continue
+		}
+		s.triggers = append(s.triggers, compiledTrigger{rule: rule, re: re})
+	}
+}
+
+func compileTriggerPattern(rule TriggerRule) (*regexp.Regexp, error) {
+	if strings.TrimSpace(rule.Pattern) == "" {
+		return nil, fmt.Errorf("pattern cannot be empty")
+	}
+	if len(rule.Pattern) > maxTriggerPatternLength {
+		return nil, fmt.Errorf("pattern is longer than %d characters", maxTriggerPatternLength)
+	}
+	pattern := rule.Pattern
+	if rule.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// GetTriggerRules 返回所有触发规则（按保存顺序）
+func (s *Service) GetTriggerRules() []TriggerRule {
+	s.triggerMu.RLock()
+	defer s.triggerMu.RUnlock()
+	return append([]TriggerRule{}, s.triggerRules...)
+}
+
+// SaveTriggerRule 新增或更新一条触发规则（ID 为空时新增），返回保存后的规则
+func (s *Service) SaveTriggerRule(rule TriggerRule) (*TriggerRule, error) {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return nil, fmt.Errorf("trigger name cannot be empty")
+	}
+	if _, err := compileTriggerPattern(rule); err != nil {
+		return nil, err
+	}
+
+	s.triggerMu.Lock()
+	defer s.triggerMu.Unlock()
+	if rule.ID == "" {
+		rule.ID = uuid.NewString()
+		s.triggerRules = append(s.triggerRules, rule)
+	} else if i := slices.IndexFunc(s.triggerRules, func(r TriggerRule) bool { return r.ID == rule.ID }); i >= 0 {
+		s.triggerRules[i] = rule
+	} else {
+		return nil, fmt.Errorf("trigger rule %s not found", rule.ID)
+	}
+	s.compileTriggers_nolock()
+	if err := s.saveTriggerRules_nolock(); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteTriggerRule 删除一条触发规则
+func (s *Service) DeleteTriggerRule(id string) error {
+	s.triggerMu.Lock()
+	defer s.triggerMu.Unlock()
+	i := slices.IndexFunc(s.triggerRules, func(r TriggerRule) bool { return r.ID == id })
+	if i < 0 {
+		return nil
+	}
+	s.triggerRules = slices.Delete(s.triggerRules, i, i+1)
+	s.compileTriggers_nolock()
+	return s.saveTriggerRules_nolock()
+}
+
+// checkTriggers 对会话中新完成的输出行执行触发规则，并发出 "terminal:trigger" 事件
+func (s *Service) checkTriggers(session *Session) {
+	for _, event := range s.matchTriggers(session, time.Now()) {
+		utils.EmitEvent(s.ctx, "terminal:trigger", event)
+	}
+}
+
+// matchTriggers 返回会话中自上次检查以来新完成的行触发的事件。
+// 只检查以换行结束的行，提示符等未结束的行在换行后才会被检查。
+func (s *Service) matchTriggers(session *Session, now time.Time) []TriggerEvent {
+	s.triggerMu.RLock()
+	var triggers []compiledTrigger
+	for _, t := range s.triggers {
+		if len(t.rule.Hosts) == 0 || slices.Contains(t.rule.Hosts, session.Alias) {
+			triggers = append(triggers, t)
+		}
+	}
+	s.triggerMu.RUnlock()
+
+	session.triggerMu.Lock()
+	defer session.triggerMu.Unlock()
+	state := &session.triggers
+	lines, first := session.scrollback.linesFrom(state.next)
+	state.next = first + int64(len(lines))
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	var events []TriggerEvent
+	for i, line := range lines {
+		for _, t := range triggers {
+			if !t.re.MatchString(line) {
+				continue
+			}
+			if last, ok := state.lastFired[t.rule.ID]; ok && now.Sub(last) < triggerCooldown {
+				continue
+			}
+			if state.lastFired == nil {
+				state.lastFired = make(map[string]time.Time)
+			}
+			state.lastFired[t.rule.ID] = now
+			events = append(events, TriggerEvent{
+				SessionID: session.ID,
+				Alias:     session.Alias,
+				Rule:      t.rule,
+				Line:      first + int64(i),
+				Text:      line,
+			})
+		}
+	}
+	return events
+}
@@ -0,0 +1,57 @@
+package terminal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMatchTriggers 测试规则只匹配完整的行、按主机过滤，并且同一规则在冷却时间内只触发一次
+func TestMatchTriggers(t *testing.T) {
+	s := NewService(nil)
+	s.triggerRulesPath = filepath.Join(t.TempDir(), "terminal_triggers.json")
+
+	errorRule, err := s.SaveTriggerRule(TriggerRule{Name: "errors", Pattern: `\berror\b`, IgnoreCase: true, Enabled: true})
+	if err != nil {
+		t.Fatalf("SaveTriggerRule failed: %v", err)
+	}
+	if _, err := s.SaveTriggerRule(TriggerRule{Name: "denied", Pattern: "Permission denied", Hosts: []string{"db"}, Enabled: true}); err != nil {
+		t.Fatalf("SaveTriggerRule failed: %v", err)
+	}
+	if _, err := s.SaveTriggerRule(TriggerRule{Name: "bad", Pattern: "(", Enabled: true}); err == nil {
+		t.Error("SaveTriggerRule should reject an invalid pattern")
+	}
+
+	session := &Session{ID: "s1", Alias: "web"}
+	now := time.Now()
+	session.scrollback.Write([]byte("build ok\r\n\x1b[31mERROR\x1b[0m: disk full\r\nPermission denied\r\nerror pending"))
+	events := s.matchTriggers(session, now)
+	if len(events) != 1 || events[0].Rule.ID != errorRule.ID || events[0].Line != 1 || events[0].Text != "ERROR: disk full" {
+		t.Fatalf("expected one match of the error rule on line 1, got %+v", events)
+	}
+
+	// 未结束的行在换行后才检查，冷却时间内不再触发
+	session.scrollback.Write([]byte("\r\n"))
+	if events := s.matchTriggers(session, now.Add(time.Second)); len(events) != 0 {
+		t.Fatalf("the rule should not fire again within the cooldown, got %+v", events)
+	}
+	session.scrollback.Write([]byte("another error\r\n"))
+	events = s.matchTriggers(session, now.Add(triggerCooldown+time.Second))
+	if len(events) != 1 || events[0].Line != 4 {
+		t.Fatalf("expected the error rule to fire on line 4 after the cooldown, got %+v", events)
+	}
+
+	// 只对 db 生效的规则
+	dbSession := &Session{ID: "s2", Alias: "db"}
+	dbSession.scrollback.Write([]byte("Permission denied (publickey)\n"))
+	if events := s.matchTriggers(dbSession, now); len(events) != 1 || events[0].Rule.Name != "denied" {
+		t.Fatalf("expected the denied rule to fire for db, got %+v", events)
+	}
+
+	if err := s.DeleteTriggerRule(errorRule.ID); err != nil {
+		t.Fatalf("DeleteTriggerRule failed: %v", err)
+	}
+	if rules := s.GetTriggerRules(); len(rules) != 1 || rules[0].Name != "denied" {
+		t.Errorf("expected only the denied rule to remain, got %+v", rules)
+	}
+}
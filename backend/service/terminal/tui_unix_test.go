@@ -0,0 +1,131 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeTUIEnv 让测试进程作为一个 TUI 程序运行，见 TestMain
+const fakeTUIEnv = "DEVTOOLS_TEST_FAKE_TUI"
+
+// TUI 程序在屏幕上打开与关闭的模式，与 htop、vim（mouse=a）相同
+const (
+	fakeTUIEnter = "\x1b[?1049h\x1b[?1003h\x1b[?1006h"
+	fakeTUIExit  = "\x1b[?1006l\x1b[?1003l\x1b[?1049l"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeTUIEnv) == "1" {
+		os.Exit(runFakeTUI())
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeTUI 把终端切换到 raw 模式，打开鼠标跟踪与备用屏幕，
+// 然后把读到的输入（直到 'q'）以十六进制回显，用于核对输入是否被原样转发
+func runFakeTUI() int {
+	stty := exec.Command("stty", "raw", "-echo")
+	stty.Stdin = os.Stdin
+	if err := stty.Run(); err != nil {
+		fmt.Printf("stty failed: %v\r\n", err)
+		return 1
+	}
+	fmt.Print(fakeTUIEnter + "READY\r\n")
+
+	var input []byte
+	buf := make([]byte, 256)
+	for !bytes.HasSuffix(input, []byte("q")) {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return 1
+		}
+		input = append(input, buf[:n]...)
+	}
+	fmt.Printf("GOT:%s\r\n", hex.EncodeToString(input[:len(input)-1]))
+	fmt.Print(fakeTUIExit + "BYE\r\n")
+	return 0
+}
+
+// TestTUIMousePassthrough 在无界面的 PTY 中运行一个 TUI 程序，经由 WebSocket 与它交互，
+// 检查鼠标报告（包括列号超过 95 时含高位字节的 X10 编码）在两个方向上都被原样转发，且模式被正确跟踪
+func TestTUIMousePassthrough(t *testing.T) {
+	if _, err := exec.LookPath("stty"); err != nil {
+		t.Skip("stty is not available")
+	}
+	s := NewService(nil)
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), fakeTUIEnv+"=1", "TERM=xterm-256color")
+	cmd.SysProcAttr = sysProcAttr()
+	session, err := s.startLocalCommand("", cmd)
+	if err != nil {
+		t.Fatalf("failed to start the TUI: %v", err)
+	}
+	defer s.cleanupSession(session.ID)
+
+	server := httptest.NewServer(http.HandlerFunc(s.handleConnection))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/terminal/" + session.ID
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("websocket dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	var output bytes.Buffer
+	readUntil := func(marker string) {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		for !strings.Contains(output.String(), marker) {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("waiting for %q: %v (output so far: %q)", marker, err, output.String())
+			}
+			output.Write(data)
+		}
+	}
+
+	readUntil("READY\r\n")
+	if !strings.Contains(output.String(), fakeTUIEnter) {
+		t.Errorf("mode sequences should reach the frontend untouched: %q", output.String())
+	}
+	if got := session.modes.Modes(); got != (TerminalModes{MouseTracking: MouseAny, MouseEncoding: MouseEncodingSGR, AlternateScreen: true}) {
+		t.Errorf("unexpected modes while the TUI runs: %+v", got)
+	}
+
+	mouse := [][]byte{
+		[]byte("\x1b[<0;12;5M"), []byte("\x1b[<0;12;5m"), // SGR 按下与释放
+		[]byte("\x1b[<35;200;40M"),              // SGR 移动
+		{0x1b, '[', 'M', 32, 32 + 120, 32 + 10}, // X10 编码，第 120 列
+		[]byte(`{"cols":`),                      // 以 { 开头但不是控制消息的输入
+	}
+	var want []byte
+	for _, m := range mouse {
+		want = append(want, m...)
+		if err := conn.WriteMessage(websocket.BinaryMessage, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte("q")); err != nil {
+		t.Fatal(err)
+	}
+
+	readUntil("BYE\r\n")
+	if got := "GOT:" + hex.EncodeToString(want) + "\r\n"; !strings.Contains(output.String(), got) {
+		t.Errorf("input should reach the TUI untouched, want %q in %q", got, output.String())
+	}
+	if got := session.modes.Modes(); got != (TerminalModes{MouseTracking: MouseOff, MouseEncoding: MouseEncodingDefault}) {
+		t.Errorf("modes should be off after the TUI exits: %+v", got)
+	}
+}
@@ -0,0 +1,12 @@
+package terminal
+
+// ReloadWorkspace 在切换工作区后从新工作区的配置目录重新加载登录命令与触发规则。
+// 调用方应先关闭属于旧工作区的会话（CloseAllSessions）。
+func (s *Service) ReloadWorkspace() {
+	if err := s.loadLoginCommands(); err != nil {
+		logger.Printf("Warning: could not load login commands: %v", err)
+	}
+	if err := s.loadTriggerRules(); err != nil {
+		logger.Printf("Warning: could not load terminal trigger rules: %v", err)
+	}
+}
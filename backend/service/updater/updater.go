@@ -0,0 +1,390 @@
+// Package updater 定期检查项目的 GitHub Release（需要在设置中开启），发现新版本时发送 "update:available" 事件。
+// 它只负责检查与下载安装包，不会自动安装。
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"devtools/backend/internal/logging"
+	"devtools/backend/internal/settings"
+	"devtools/backend/pkg/utils"
+)
+
+var logger = logging.For("updater")
+
+// DefaultFeedURL 是项目最新 Release 的 GitHub API 地址
+const DefaultFeedURL = "https://api.github.com/repos/kekexiaoai/devtools/releases/latest"
+
+// 检查与下载的限制
+const (
+	checkTimeout    = 30 * time.Second
+	maxFeedBytes    = 1 << 20
+	downloadTimeout = 30 * time.Minute
+	// firstCheckDelay 是启动后第一次检查前的等待时间，避免与其他服务的启动争抢网络
+	firstCheckDelay = 30 * time.Second
+)
+
+// UpdateInfo 是一次检查的结果
+type UpdateInfo struct {
+	CurrentVersion string  `json:"currentVersion"`
+	LatestVersion  string  `json:"latestVersion"`
+	Available      bool    `json:"available"` // LatestVersion 比 CurrentVersion 新
+	ReleaseNotes   string  `json:"releaseNotes"`
+	ReleaseURL     string  `json:"releaseUrl"`
+	PublishedAt    string  `json:"publishedAt,omitempty"`
+	Assets         []Asset `json:"assets"`
+}
+
+// Asset 是 Release 中可下载的安装包
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+// Status 是更新检查的当前状态，供设置页面展示
+type Status struct {
+	Enabled        bool        `json:"enabled"`
+	CurrentVersion string      `json:"currentVersion"`
+	LastCheck      string      `json:"lastCheck,omitempty"` // RFC3339
+	LastError      string      `json:"lastError,omitempty"`
+	Latest         *UpdateInfo `json:"latest,omitempty"`
+}
+
+// githubRelease 是 GitHub releases API 返回的字段子集
+type githubRelease struct {
+	TagName     string `json:"tag_name"`
+	Body        string `json:"body"`
+	HTMLURL     string `json:"html_url"`
+	PublishedAt string `json:"published_at"`
+	Draft       bool   `json:"draft"`
+	Prerelease  bool   `json:"prerelease"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+		Size               int64  `json:"size"`
+	} `json:"assets"`
+}
+
+// Service 按设置定期检查新版本
+type Service struct {
+	ctx            context.Context
+	currentVersion string
+	feedURL        string
+	client         *http.Client
+
+	mu        sync.Mutex
+	ready     bool
+	enabled   bool
+	interval  time.Duration
+	cancel    context.CancelFunc
+	lastCheck time.Time
+	lastError string
+	latest    *UpdateInfo
+	notified  string // 已经发送过事件的版本，同一版本每次运行只提醒一次
+}
+
+// NewService 是更新检查服务的构造函数，currentVersion 为编译时嵌入的版本号
+func NewService(currentVersion string) *Service {
+	return &Service{
+		currentVersion: currentVersion,
+		feedURL:        DefaultFeedURL,
+		client:         http.DefaultClient,
+		interval:       time.Duration(settings.DefaultUpdateCheckIntervalHours) * time.Hour,
+	}
+}
+
+// Startup 在应用启动时被调用，设置中开启了检查时启动检查循环
+func (s *Service) Startup(ctx context.Context) error {
+	s.ctx = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = true
+	s.reconcile_nolock()
+	return nil
+}
+
+// Shutdown 停止检查循环
+func (s *Service) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = false
+	s.stop_nolock()
+}
+
+// ApplySettings 在设置变化时启动、停止或重启检查循环。启动完成之前只记录设置。
+func (s *Service) ApplySettings(cfg settings.Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	interval := time.Duration(cfg.UpdateCheckIntervalHours) * time.Hour
+	if cfg.UpdateCheckEnabled == s.enabled && interval == s.interval {
+		return
+	}
+	s.enabled = cfg.UpdateCheckEnabled
+	s.interval = interval
+	s.reconcile_nolock()
+}
+
+// GetUpdateStatus 返回最近一次检查的结果
+func (s *Service) GetUpdateStatus() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{
+		Enabled:        s.enabled,
+		CurrentVersion: s.currentVersion,
+		LastError:      s.lastError,
+		Latest:         s.latest,
+	}
+	if !s.lastCheck.IsZero() {
+		status.LastCheck = s.lastCheck.Format(time.RFC3339)
+	}
+	return status
+}
+
+// CheckForUpdates 立即检查一次（不受设置开关限制），有新版本时同样发送 "update:available" 事件
+func (s *Service) CheckForUpdates() (*UpdateInfo, error) {
+	return s.check(context.Background())
+}
+
+// reconcile_nolock 按当前设置启动或停止检查循环，调用方需持有 s.mu
+func (s *Service) reconcile_nolock() {
+	s.stop_nolock()
+	if !s.ready || !s.enabled || s.interval <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.loop(ctx, s.interval)
+}
+
+// stop_nolock 停止检查循环，调用方需持有 s.mu
+func (s *Service) stop_nolock() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// loop 稍等片刻后检查一次，之后按间隔检查，直到 ctx 被取消
+func (s *Service) loop(ctx context.Context, interval time.Duration) {
+	timer := time.NewTimer(firstCheckDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if _, err := s.check(ctx); err != nil && ctx.Err() == nil {
+				logger.Printf("Warning: update check failed: %v", err)
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// check 读取最新的 Release 并与当前版本比较
+func (s *Service) check(ctx context.Context) (*UpdateInfo, error) {
+	info, err := s.fetchLatest(ctx)
+
+	s.mu.Lock()
+	s.lastCheck = time.Now()
+	if err != nil {
+		s.lastError = err.Error()
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.lastError = ""
+	s.latest = info
+	notify := info.Available && s.notified != info.LatestVersion
+	if notify {
+		s.notified = info.LatestVersion
+	}
+	s.mu.Unlock()
+
+	if notify {
+		logger.Printf("Update available: %s (current %s).", info.LatestVersion, info.CurrentVersion)
+		utils.EmitEvent(s.ctx, "update:available", info)
+	}
+	return info, nil
+}
+
+// fetchLatest 从 Release 源读取最新版本
+func (s *Service) fetchLatest(ctx context.Context) (*UpdateInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release feed URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch release feed: %s", resp.Status)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFeedBytes)).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse release feed: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release feed has no version tag")
+	}
+
+	info := &UpdateInfo{
+		CurrentVersion: s.currentVersion,
+		LatestVersion:  release.TagName,
+		Available:      !release.Draft && !release.Prerelease && compareVersions(release.TagName, s.currentVersion) > 0,
+		ReleaseNotes:   release.Body,
+		ReleaseURL:     release.HTMLURL,
+		PublishedAt:    release.PublishedAt,
+		Assets:         []Asset{},
+	}
+	for _, a := range release.Assets {
+		info.Assets = append(info.Assets, Asset{Name: a.Name, URL: a.BrowserDownloadURL, Size: a.Size})
+	}
+	return info, nil
+}
+
+// DownloadUpdate 把安装包下载到用户的下载目录并返回文件路径；同名文件已存在时在文件名后加序号。
+// 只下载，不会运行安装包。
+func (s *Service) DownloadUpdate(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("update URL must be an https URL")
+	}
+	name := path.Base(u.Path)
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("update URL does not name a file")
+	}
+	dir, err := downloadsDir()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), downloadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid update URL: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download update: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download update: %s", resp.Status)
+	}
+
+	// 先写入临时文件，完整下载后再改名，避免留下不完整的安装包
+	tmp, err := os.CreateTemp(dir, "."+name+".*.part")
+	if err != nil {
+		return "", fmt.Errorf("failed to create download file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to download update: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write download file: %w", err)
+	}
+
+	target := uniquePath(filepath.Join(dir, name))
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", fmt.Errorf("failed to save update: %w", err)
+	}
+	logger.Printf("Downloaded update %s to %s.", rawURL, target)
+	return target, nil
+}
+
+// downloadsDir 返回用户的下载目录（~/Downloads），不存在时创建
+func downloadsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	dir := filepath.Join(home, "Downloads")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create downloads directory: %w", err)
+	}
+	return dir, nil
+}
+
+// uniquePath 在 p 已存在时返回 "name (1).ext"、"name (2).ext" ……
+func uniquePath(p string) string {
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return p
+	}
+	ext := filepath.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// compareVersions 比较 "v1.2.3" 形式的版本号（"v" 可省略），返回 -1、0 或 1。
+// 缺少的部分视为 0；带预发布后缀（例如 "1.2.0-beta.1"）的版本比对应的正式版本旧。
+func compareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+	for i := range max(len(aCore), len(bCore)) {
+		var x, y int
+		if i < len(aCore) {
+			x = aCore[i]
+		}
+		if i < len(bCore) {
+			y = bCore[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// splitVersion 把版本号拆分为数字部分与预发布后缀，无法解析的数字部分视为 0
+func splitVersion(v string) ([]int, string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+") // 构建元数据不参与比较
+	core, pre, _ := strings.Cut(v, "-")
+	var parts []int
+	for _, p := range strings.Split(core, ".") {
+		n, _ := strconv.Atoi(p)
+		parts = append(parts, n)
+	}
+	return parts, pre
+}
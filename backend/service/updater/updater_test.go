@@ -0,0 +1,60 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCompareVersions 测试版本号比较，包括 "v" 前缀、缺少的部分与预发布版本
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "1.2.3", 0},
+		{"v1.10.0", "v1.9.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"v2.0.0", "0.0.0", 1},
+		{"v1.2.0-beta.1", "v1.2.0", -1},
+		{"v1.2.0-beta.2", "v1.2.0-beta.1", 1},
+		{"v1.2.0+build.5", "v1.2.0", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestCheckForUpdates 测试读取 Release 源：只有比当前版本新的正式版本才算可用
+func TestCheckForUpdates(t *testing.T) {
+	release := `{"tag_name": "v1.3.0", "body": "- Faster tunnels", "html_url": "https://example.com/r/v1.3.0",
+		"assets": [{"name": "devtools.dmg", "browser_download_url": "https://example.com/devtools.dmg", "size": 42}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(release))
+	}))
+	defer server.Close()
+
+	s := NewService("v1.2.5")
+	s.feedURL = server.URL
+	info, err := s.CheckForUpdates()
+	if err != nil {
+		t.Fatalf("CheckForUpdates failed: %v", err)
+	}
+	if !info.Available || info.LatestVersion != "v1.3.0" || info.ReleaseNotes != "- Faster tunnels" {
+		t.Errorf("unexpected update info: %+v", info)
+	}
+	if len(info.Assets) != 1 || info.Assets[0].URL != "https://example.com/devtools.dmg" {
+		t.Errorf("unexpected assets: %+v", info.Assets)
+	}
+	if status := s.GetUpdateStatus(); status.LastCheck == "" || status.Latest != info {
+		t.Errorf("status should record the check: %+v", status)
+	}
+
+	s = NewService("v1.3.0")
+	s.feedURL = server.URL
+	if info, err := s.CheckForUpdates(); err != nil || info.Available {
+		t.Errorf("the current version should not be reported as an update: %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,99 @@
+package backend
+
+import (
+	"fmt"
+
+	"devtools/backend/internal/workspace"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// 工作区把 ssh_config、隧道、同步配置与钥匙串中的密码按用途（个人、不同客户等）分开。
+// 切换工作区时关闭属于旧工作区的终端会话、隧道与同步监控，再让各服务从新工作区的配置目录重新加载。
+
+// ListWorkspaces 返回所有工作区
+func (a *App) ListWorkspaces() []workspace.Workspace {
+	return a.workspaces.List()
+}
+
+// GetActiveWorkspace 返回当前激活的工作区
+func (a *App) GetActiveWorkspace() workspace.Workspace {
+	return a.workspaces.Active()
+}
+
+// CreateWorkspace 新建一个空的工作区，不会切换到它
+func (a *App) CreateWorkspace(ws workspace.Workspace) error {
+	return a.workspaces.Create(ws)
+}
+
+// UpdateWorkspace 修改工作区的 ssh_config 路径，修改的是当前工作区时立即重新加载 SSH 配置
+func (a *App) UpdateWorkspace(ws workspace.Workspace) error {
+	a.workspaceMu.Lock()
+	defer a.workspaceMu.Unlock()
+
+	old, ok := a.workspaces.Get(ws.Name)
+	if !ok {
+		return fmt.Errorf("workspace '%s' not found", ws.Name)
+	}
+	if ws.Name == a.workspaces.Active().Name {
+		if err := a.sshManager.SetConfigPath(ws.SSHConfigPath); err != nil {
+			return err
+		}
+	}
+	if err := a.workspaces.Update(ws); err != nil {
+		if ws.Name == a.workspaces.Active().Name {
+			_ = a.sshManager.SetConfigPath(old.SSHConfigPath)
+		}
+		return err
+	}
+	runtime.EventsEmit(a.ctx, "workspace:changed", a.workspaces.Active())
+	return nil
+}
+
+// DeleteWorkspace 从列表中删除一个工作区，它的配置目录保留在磁盘上
+func (a *App) DeleteWorkspace(name string) error {
+	a.workspaceMu.Lock()
+	defer a.workspaceMu.Unlock()
+	return a.workspaces.Delete(name)
+}
+
+// SwitchWorkspace 切换到另一个工作区。新工作区的 ssh_config 无法加载时不做任何改动；
+// 否则关闭所有终端会话、隧道与同步监控后激活新工作区，各服务从它的配置目录重新加载，
+// 最后发送 "workspace:changed" 事件，前端据此刷新所有列表。
+func (a *App) SwitchWorkspace(name string) error {
+	a.workspaceMu.Lock()
+	defer a.workspaceMu.Unlock()
+
+	current := a.workspaces.Active()
+	if name == current.Name {
+		return nil
+	}
+	next, ok := a.workspaces.Get(name)
+	if !ok {
+		return fmt.Errorf("workspace '%s' not found", name)
+	}
+	logger.Printf("Switching workspace from '%s' to '%s'...", current.Name, next.Name)
+
+	if err := a.sshManager.SetConfigPath(next.SSHConfigPath); err != nil {
+		return fmt.Errorf("failed to load the ssh config of workspace '%s': %w", next.Name, err)
+	}
+	if err := a.workspaces.SetActive(next.Name); err != nil {
+		if rollbackErr := a.sshManager.SetConfigPath(current.SSHConfigPath); rollbackErr != nil {
+			logger.Printf("Warning: failed to restore the ssh config of workspace '%s': %v", current.Name, rollbackErr)
+		}
+		return err
+	}
+
+	// 会话与隧道使用的是旧工作区的主机与凭据，先全部关闭，再切换钥匙串命名空间并重新加载
+	a.TerminalService.CloseAllSessions()
+	a.sshManager.SetKeyringNamespace(workspace.KeyringNamespace(next.Name))
+	a.SSHGateService.ReloadWorkspace()
+	a.TerminalService.ReloadWorkspace()
+	if err := a.FileSyncService.ReloadWorkspace(); err != nil {
+		logger.Printf("Warning: failed to restart the file sync service: %v", err)
+	}
+
+	logger.Printf("Switched to workspace '%s'.", next.Name)
+	runtime.EventsEmit(a.ctx, "workspace:changed", next)
+	return nil
+}
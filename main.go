@@ -5,9 +5,11 @@ import (
 	"embed"
 	"fmt"
 	"log"
+	"os"
 	_runtime "runtime"
 
 	"devtools/backend"
+	"devtools/backend/cli"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/menu"
@@ -27,9 +29,14 @@ var version = "0.0.0"
 const appName = "devtools"
 
 func main() {
+	// 无界面的命令行模式：devtools cli <command>
+	if len(os.Args) > 1 && os.Args[1] == "cli" {
+		os.Exit(cli.Run(os.Args[2:]))
+	}
+
 	isMacOS := _runtime.GOOS == "darwin"
 	// 创建一个 app 的实例
-	app := backend.NewApp(IsDebug, isMacOS)
+	app := backend.NewApp(IsDebug, isMacOS, version)
 
 	// 完成所有服务的初始化和注入
 	app.Bootstrap()
@@ -81,6 +88,12 @@ func main() {
 			app.FileSyncService,
 			app.SSHGateService,
 			app.TerminalService,
+			app.SettingsService,
+			app.LogStreamService,
+			app.SnippetService,
+			app.HotkeyService,
+			app.UpdateService,
+			app.NotifyService,
 		},
 		Mac: &mac.Options{
 			TitleBar: &mac.TitleBar{